@@ -0,0 +1,173 @@
+// Command treechessctl runs maintenance operations against the TreeChess database.
+//
+// Usage:
+//
+//	treechessctl migrate
+//	treechessctl recompute-metadata
+//	treechessctl backfill-fingerprints
+//	treechessctl requeue-evals
+//	treechessctl export-user -username NAME [-o FILE]
+//
+// It reads DATABASE_URL from the environment (or ../.env, like the server).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/services"
+)
+
+const usage = `usage: treechessctl <command> [flags]
+
+commands:
+  migrate                 apply pending schema migrations
+  recompute-metadata      recalculate node and depth counts of every repertoire
+  backfill-fingerprints   record duplicate-detection fingerprints of all stored games
+  requeue-evals           put failed engine evaluations back in the queue
+  export-user             write all data of a user as JSON (-username NAME [-o FILE])
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(*repository.DB, []string) error{
+		"migrate":               func(db *repository.DB, _ []string) error { return db.Migrate() },
+		"recompute-metadata":    recomputeMetadata,
+		"backfill-fingerprints": backfillFingerprints,
+		"requeue-evals":         requeueEvals,
+		"export-user":           exportUser,
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	_ = godotenv.Load("../.env")
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+	// Only the migrate command changes the schema
+	db, err := repository.NewDB(config.Config{DatabaseURL: dbURL, AutoMigrate: false})
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	if err := run(db, os.Args[2:]); err != nil {
+		db.Close()
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func recomputeMetadata(db *repository.DB, _ []string) error {
+	ids, err := db.ListRepertoireIDs(context.Background())
+	if err != nil {
+		return err
+	}
+
+	repertoireSvc := services.NewRepertoireService(repository.NewPostgresRepertoireRepo(db.Pool))
+	updated := 0
+	for _, id := range ids {
+		changed, err := repertoireSvc.RecomputeMetadata(id)
+		if err != nil {
+			return fmt.Errorf("repertoire %s: %w", id, err)
+		}
+		if changed {
+			updated++
+		}
+	}
+	log.Printf("recomputed metadata of %d repertoires, %d were stale", len(ids), updated)
+	return nil
+}
+
+func backfillFingerprints(db *repository.DB, _ []string) error {
+	userIDs, err := db.ListUserIDs(context.Background())
+	if err != nil {
+		return err
+	}
+
+	importSvc := services.NewImportService(nil, repository.NewPostgresAnalysisRepo(db.Pool),
+		services.WithFingerprintRepo(repository.NewPostgresFingerprintRepo(db.Pool, config.DefaultFingerprintBatchSize)))
+	total := 0
+	for _, userID := range userIDs {
+		processed, err := importSvc.BackfillFingerprints(userID)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", userID, err)
+		}
+		total += processed
+	}
+	log.Printf("fingerprinted %d games of %d users", total, len(userIDs))
+	return nil
+}
+
+func requeueEvals(db *repository.DB, _ []string) error {
+	requeued, err := db.RequeueFailedEvals(context.Background())
+	if err != nil {
+		return err
+	}
+	log.Printf("requeued %d failed evals", requeued)
+	return nil
+}
+
+func exportUser(db *repository.DB, args []string) error {
+	flags := flag.NewFlagSet("export-user", flag.ContinueOnError)
+	username := flags.String("username", "", "user to export")
+	output := flags.String("o", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+
+	user, err := repository.NewPostgresUserRepo(db.Pool).GetByUsername(*username)
+	if err != nil {
+		return err
+	}
+	repertoires, err := services.NewRepertoireService(repository.NewPostgresRepertoireRepo(db.Pool)).ListRepertoires(user.ID, nil, true)
+	if err != nil {
+		return err
+	}
+	analyses, err := repository.NewPostgresAnalysisRepo(db.Pool).GetAllGamesRaw(user.ID)
+	if err != nil {
+		return err
+	}
+
+	export := models.UserDataExport{
+		ExportedAt:  time.Now().UTC(),
+		User:        *user,
+		Repertoires: repertoires,
+		Analyses:    analyses,
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -10,11 +14,32 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// Deployment profiles selected with APP_ENV
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Storage backends selected with DB_DRIVER
+const (
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite" // single-file storage for self-hosting, in binaries built with the sqlite tag
+)
+
+// minProductionJWTSecretLen is the shortest JWT secret the production profile accepts
+const minProductionJWTSecretLen = 32
+
 // Config holds application configuration
 type Config struct {
+	Environment              string
+	DBDriver                 string
 	DatabaseURL              string
+	SQLitePath               string
 	Port                     int
 	AllowedOrigins           []string
+	AllowCredentials         bool
+	TrustedProxies           []*net.IPNet
+	RateLimitExempt          []*net.IPNet
 	JWTSecret                string
 	JWTExpiry                time.Duration
 	LichessClientID          string
@@ -26,7 +51,23 @@ type Config struct {
 	SMTPUser                 string
 	SMTPPassword             string
 	SMTPFromAddress          string
+	VAPIDPrivateKey          string
+	VAPIDSubject             string
 	PasswordResetExpiryHours int
+	AdminUsernames           []string
+	EvalRetention            time.Duration
+	ImportSpoolDir           string
+	AutoMigrate              bool
+	AnalysisWorkers          int
+	FingerprintBatchSize     int
+	UsageQuotas              UsageQuotas
+}
+
+// UsageQuotas caps the calls each user may make to external services per UTC day; 0 lifts a cap
+type UsageQuotas struct {
+	Explorer      int
+	LichessExport int
+	Chesscom      int
 }
 
 // MustLoad loads configuration from environment variables
@@ -35,11 +76,33 @@ func MustLoad() Config {
 	// Load .env file if present (won't override existing env vars)
 	_ = godotenv.Load("../.env")
 
+	environment := os.Getenv("APP_ENV")
+	if environment == "" {
+		environment = EnvDevelopment
+	}
+	if environment != EnvDevelopment && environment != EnvProduction {
+		panic(fmt.Sprintf("Invalid APP_ENV value: %s", environment))
+	}
+
+	dbDriver := os.Getenv("DB_DRIVER")
+	if dbDriver == "" {
+		dbDriver = DBDriverPostgres
+	}
+	if dbDriver != DBDriverPostgres && dbDriver != DBDriverSQLite {
+		panic(fmt.Sprintf("Invalid DB_DRIVER value: %s", dbDriver))
+	}
+
 	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
+	if dbURL == "" && dbDriver == DBDriverPostgres {
 		panic("DATABASE_URL environment variable is required")
 	}
 
+	// The SQLite database file, created on first start
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "treechess.db"
+	}
+
 	portStr := os.Getenv("PORT")
 	port := 8080
 	if portStr != "" {
@@ -59,6 +122,19 @@ func MustLoad() Config {
 			allowedOrigins[i] = strings.TrimSpace(origin)
 		}
 	}
+	allowCredentials := os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+
+	// Client addresses are only read from X-Forwarded-For when the request comes from one of these
+	trustedProxies, err := parseIPNets("TRUSTED_PROXIES", os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Monitoring probes and other clients the per-IP rate limits do not apply to
+	rateLimitExempt, err := parseIPNets("RATE_LIMIT_EXEMPT_IPS", os.Getenv("RATE_LIMIT_EXEMPT_IPS"))
+	if err != nil {
+		panic(err.Error())
+	}
 
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
@@ -103,6 +179,14 @@ func MustLoad() Config {
 	smtpPassword := os.Getenv("SMTP_PASSWORD")
 	smtpFromAddress := os.Getenv("SMTP_FROM_ADDRESS")
 
+	// Web Push (optional - if no key is set, push notifications are disabled).
+	// The subject is the contact push services reach when a server misbehaves.
+	vapidPrivateKey := os.Getenv("VAPID_PRIVATE_KEY")
+	vapidSubject := os.Getenv("VAPID_SUBJECT")
+	if vapidSubject == "" {
+		vapidSubject = frontendURL
+	}
+
 	passwordResetExpiryHours := 1
 	if expiryStr := os.Getenv("PASSWORD_RESET_EXPIRY_HOURS"); expiryStr != "" {
 		hours, err := strconv.Atoi(expiryStr)
@@ -112,10 +196,70 @@ func MustLoad() Config {
 		passwordResetExpiryHours = hours
 	}
 
-	return Config{
+	// Admins curate featured templates (comma-separated usernames)
+	var adminUsernames []string
+	for _, name := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			adminUsernames = append(adminUsernames, name)
+		}
+	}
+
+	// Finished opening evals older than this are pruned; 0 keeps them forever
+	evalRetention := 90 * 24 * time.Hour
+	if daysStr := os.Getenv("EVAL_RETENTION_DAYS"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			panic(fmt.Sprintf("Invalid EVAL_RETENTION_DAYS value: %s", daysStr))
+		}
+		evalRetention = time.Duration(days) * 24 * time.Hour
+	}
+
+	// Large PGN databases are written here before being imported in the background
+	importSpoolDir := os.Getenv("IMPORT_SPOOL_DIR")
+	if importSpoolDir == "" {
+		importSpoolDir = filepath.Join(os.TempDir(), "treechess-imports")
+	}
+
+	// Pending schema migrations are applied on startup unless disabled
+	autoMigrate := os.Getenv("AUTO_MIGRATE") != "false"
+
+	// Games of an import are analyzed in parallel by this many workers
+	analysisWorkers := runtime.NumCPU()
+	if workersStr := os.Getenv("ANALYSIS_WORKERS"); workersStr != "" {
+		w, err := strconv.Atoi(workersStr)
+		if err != nil || w < 1 {
+			panic(fmt.Sprintf("Invalid ANALYSIS_WORKERS value: %s", workersStr))
+		}
+		analysisWorkers = w
+	}
+
+	// Imported games are checked for duplicates by this many fingerprints per query
+	fingerprintBatchSize := DefaultFingerprintBatchSize
+	if batchStr := os.Getenv("FINGERPRINT_BATCH_SIZE"); batchStr != "" {
+		b, err := strconv.Atoi(batchStr)
+		if err != nil || b < 1 {
+			panic(fmt.Sprintf("Invalid FINGERPRINT_BATCH_SIZE value: %s", batchStr))
+		}
+		fingerprintBatchSize = b
+	}
+
+	// Daily quotas of external service calls per user
+	usageQuotas := UsageQuotas{
+		Explorer:      parseDailyQuota("EXPLORER_DAILY_QUOTA", ExplorerDailyBudget),
+		LichessExport: parseDailyQuota("LICHESS_EXPORT_DAILY_QUOTA", DefaultLichessExportQuota),
+		Chesscom:      parseDailyQuota("CHESSCOM_DAILY_QUOTA", DefaultChesscomQuota),
+	}
+
+	cfg := Config{
+		Environment:              environment,
+		DBDriver:                 dbDriver,
 		DatabaseURL:              dbURL,
+		SQLitePath:               sqlitePath,
 		Port:                     port,
 		AllowedOrigins:           allowedOrigins,
+		AllowCredentials:         allowCredentials,
+		TrustedProxies:           trustedProxies,
+		RateLimitExempt:          rateLimitExempt,
 		JWTSecret:                jwtSecret,
 		JWTExpiry:                jwtExpiry,
 		LichessClientID:          lichessClientID,
@@ -127,6 +271,114 @@ func MustLoad() Config {
 		SMTPUser:                 smtpUser,
 		SMTPPassword:             smtpPassword,
 		SMTPFromAddress:          smtpFromAddress,
+		VAPIDPrivateKey:          vapidPrivateKey,
+		VAPIDSubject:             vapidSubject,
 		PasswordResetExpiryHours: passwordResetExpiryHours,
+		AdminUsernames:           adminUsernames,
+		EvalRetention:            evalRetention,
+		ImportSpoolDir:           importSpoolDir,
+		AutoMigrate:              autoMigrate,
+		AnalysisWorkers:          analysisWorkers,
+		FingerprintBatchSize:     fingerprintBatchSize,
+		UsageQuotas:              usageQuotas,
+	}
+	if err := cfg.validate(); err != nil {
+		panic(fmt.Sprintf("Invalid configuration: %v", err))
+	}
+	return cfg
+}
+
+// IsProduction reports whether the production profile is active
+func (c Config) IsProduction() bool {
+	return c.Environment == EnvProduction
+}
+
+// validate rejects insecure combinations of settings. Development only rejects what browsers
+// refuse anyway; production also requires HTTPS origins, secure cookies and a strong JWT secret.
+func (c Config) validate() error {
+	var errs []error
+	wildcard := false
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			wildcard = true
+		}
+	}
+	if wildcard && c.AllowCredentials {
+		errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS must not contain * when CORS_ALLOW_CREDENTIALS is true"))
+	}
+
+	if !c.IsProduction() {
+		return errors.Join(errs...)
+	}
+
+	if wildcard {
+		errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS must list explicit origins in production"))
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Errorf("CORS origin %s must use https in production", origin))
+		}
+	}
+	if !strings.HasPrefix(c.FrontendURL, "https://") {
+		errs = append(errs, errors.New("FRONTEND_URL must use https in production"))
+	}
+	if !strings.HasPrefix(c.OAuthCallbackURL, "https://") {
+		errs = append(errs, errors.New("OAUTH_CALLBACK_URL must use https in production"))
+	}
+	if !c.SecureCookies {
+		errs = append(errs, errors.New("SECURE_COOKIES must be true in production"))
+	}
+	if len(c.JWTSecret) < minProductionJWTSecretLen {
+		errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d characters in production", minProductionJWTSecretLen))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if ones, _ := proxy.Mask.Size(); ones == 0 {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES must not trust every address (%s) in production", proxy))
+		}
+	}
+	for _, exempt := range c.RateLimitExempt {
+		if ones, _ := exempt.Mask.Size(); ones == 0 {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_EXEMPT_IPS must not exempt every address (%s) in production", exempt))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseDailyQuota reads a daily quota from an environment variable, defaultValue when unset
+func parseDailyQuota(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	quota, err := strconv.Atoi(value)
+	if err != nil || quota < 0 {
+		panic(fmt.Sprintf("Invalid %s value: %s", name, value))
+	}
+	return quota
+}
+
+// parseIPNets parses the comma-separated list of IP addresses and CIDR ranges of the name
+// environment variable. A bare address stands for itself only.
+func parseIPNets(name, value string) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			ipNets = append(ipNets, ipNet)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("Invalid %s entry: %s", name, entry)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
 	}
+	return ipNets, nil
 }
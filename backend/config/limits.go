@@ -8,23 +8,188 @@ const (
 	MaxRepertoires       = 50
 	MaxRepertoireNameLen = 100
 
+	// Node edits are replayed on the fresh tree when another save got in first, this many times at most
+	MaxTreeEditAttempts = 3
+
+	// Moves of a repertoire restored from a JSON export
+	MaxImportedRepertoireNodes = 20000
+
+	// Node comments edited in bulk as a CSV file keyed by move path
+	MaxCommentsCSVSize = 2 * 1024 * 1024 // 2MB
+
+	// Revisions kept per repertoire; older ones are dropped as new saves come in
+	MaxRepertoireRevisions = 50
+
+	// Study notes: one markdown document per repertoire, with its earlier versions kept
+	MaxStudyNotesLen       = 50000
+	MaxStudyNotesRevisions = 20
+
+	// Repertoires read by imports and insights are cached per user this long
+	RepertoireCacheTTL = 30 * time.Second
+
+	// Full-text search over node comments and repertoire names
+	MaxSearchQueryLen = 200
+	MaxSearchResults  = 50
+
+	// Repertoire template limits
+	MaxTemplateDescriptionLen = 500
+	MaxTemplateTags           = 10
+
+	// Tags on templates and repertoire nodes
+	MaxTagLen   = 30
+	MaxNodeTags = 10
+
+	// Training defaults
+	DefaultTrainingPositions = 20
+	MaxTrainingPositions     = 100
+	TrainingSessionGap       = 30 * time.Minute // pause after which the next answer starts a new session
+	DefaultTrainingDays      = 365              // days of the training activity calendar
+	MaxTrainingDays          = 3 * 365
+
+	// Sparring: games against Explorer replies are dropped after this long without a move
+	SparringSessionTTL  = 30 * time.Minute
+	MaxSparringSessions = 3 // per user, the oldest is dropped when starting another
+
 	// File upload limits
 	MaxPGNFileSize = 10 * 1024 * 1024 // 10MB
 
+	// PGN database imports (Chessbase/SCID exports) run in the background, chunk by chunk
+	MaxPGNDatabaseSize = 200 * 1024 * 1024 // 200MB
+	ImportChunkSize    = 200               // games per chunk
+
+	// Fingerprints of imported games are checked against the stored ones in batches, keeping
+	// queries under the Postgres parameter limit and reporting progress between batches
+	DefaultFingerprintBatchSize = 1000 // fingerprints per query, overridden with FINGERPRINT_BATCH_SIZE
+	FingerprintProgressBatch    = 5000 // fingerprints checked between progress reports
+
+	// Lichess team imports run in the background, one member at a time to stay under the Lichess rate limit
+	MaxTeamImportMembers       = 50
+	TeamImportDefaultDays      = 30 // games looked back when the request sets no start date
+	TeamImportMemberDelay      = 2 * time.Second
+	TeamImportRateLimitBackoff = time.Minute // Lichess asks clients to wait a full minute after a 429
+
+	// Moves of each imported game matched against repertoires; later moves are marked beyond book
+	MaxAnalysisDepth = 100
+
 	// Pagination defaults
 	DefaultGamesLimit = 20
 	MaxGamesLimit     = 100
 
+	// PGN and CSV export limits
+	MaxExportGames = 500
+
+	// User notes on imported games
+	MaxGameNoteLen = 2000
+
+	// Insights defaults, overridable per user through the insight settings
+	DefaultInsightsLimit        = 2
+	MaxInsightsLimit            = 50
+	DefaultInsightsMinDrop      = 0.02
+	DefaultInsightsMinPly       = 3 // Plies 1-2 are the opening choice, not a mistake
+	MaxInsightsMinPly           = 40
+	DefaultInsightsMinFrequency = 2 // Only recurring mistakes are reported
+	MaxInsightsMinFrequency     = 20
+
+	// Classification rules: user-defined labels added to analyzed moves
+	MaxClassificationRulesPerUser = 20
+	MaxClassificationRuleNameLen  = 100
+
+	// Opponent replies: opponent moves recorded per imported game, for the opening plies only
+	OpponentRepliesMaxPly = 30
+	MaxOpponentRating     = 4000
+
+	// Linked Lichess/Chess.com accounts per user
+	MaxLinkedAccounts = 10
+
 	// Lichess API limits
 	DefaultLichessGames = 20
 	MaxLichessGames     = 100
 
+	// Outgoing HTTP client limits (Lichess, Chess.com, Explorer)
+	MaxHTTPRetries          = 3
+	HTTPRetryBaseDelay      = 500 * time.Millisecond
+	MaxHTTPRetryDelay       = 30 * time.Second
+	MaxHTTPRequestsPerHost  = 4
+	CircuitBreakerThreshold = 5
+	CircuitBreakerCooldown  = 30 * time.Second
+	// Per-host state is dropped once a host has been idle this long
+	HTTPHostIdleTimeout = 10 * time.Minute
+
+	// Engine evals that fail are retried with exponential backoff, then left failed
+	EvalMaxAttempts    = 5
+	EvalRetryBaseDelay = time.Minute
+	EvalRetryMaxDelay  = time.Hour
+
+	// Per-user budgets of expensive endpoints, per hour (bursts allow a few back-to-back requests)
+	ImportRequestsPerHour = 30
+	ImportRequestBurst    = 5
+	SyncRequestsPerHour   = 12
+	SyncRequestBurst      = 2
+	MergeRequestsPerHour  = 60
+	MergeRequestBurst     = 10
+
+	// Personal API tokens, each with its own hourly request budget
+	MaxAPITokensPerUser     = 10
+	MaxAPITokenNameLen      = 100
+	APITokenRequestsPerHour = 1000
+	APITokenRequestBurst    = 50
+
 	// Database timeouts
 	DefaultDBTimeout   = 5 * time.Second
 	MigrationDBTimeout = 30 * time.Second
 
+	// Explorer proxy: Lichess lookups a user may trigger per day (cache hits are free)
+	ExplorerDailyBudget = 300
+	ExplorerQueueSize   = 100
+
+	// Explorer responses and master games are kept in memory for this long, up to this many
+	// positions each
+	ExplorerCacheTTL  = 24 * time.Hour
+	ExplorerCacheSize = 10000
+
+	// Opponent moves played in fewer Explorer games than this share are flagged as rare when added
+	RareMoveShare = 0.01
+
+	// Daily quotas of external service calls per user, overridable from the environment
+	DefaultLichessExportQuota = 30
+	DefaultChesscomQuota      = 60 // a Chess.com sync downloads each time class separately
+
+	// Lichess tablebase: positions with at most this many pieces, kings included, have exact results
+	TablebaseMaxPieces = 7
+	TablebaseCacheSize = 10000
+
+	// Repertoire health: scores older than the refresh age are recomputed by the worker
+	HealthRefreshAge   = 24 * time.Hour
+	HealthRecallWindow = 30 * 24 * time.Hour
+	HealthStaleAfter   = 90 * 24 * time.Hour // freshness reaches 0 after this long without edits or training
+	HealthBatchSize    = 100
+
+	// Study recommendations: lines and opponent moves reached by fewer games are not worth one
+	MaxRecommendations         = 10
+	RecommendationMinGames     = 2
+	RecommendationRecallWindow = 30 * 24 * time.Hour
+
+	// Opening labels: bumping the version, e.g. after extending the ECO table, relabels every repertoire
+	OpeningLabelsVersion   = 1
+	OpeningLabelsBatchSize = 50
+
+	// Book depth: move times are reported for the opening plies only
+	BookDepthMaxPly = 40
+
+	// Node preparation: deep Explorer expansion run by the prep worker
+	DefaultPrepDepth = 4
+	MaxPrepDepth     = 12
+	PrepCandidates   = 3 // first moves explored from the prepared node
+	PrepMinGames     = 20
+	PrepClaimBatch   = 2
+
+	// Goal limits
+	MaxGoalsPerUser  = 20
+	DefaultGoalDepth = 8
+	MaxGoalDepth     = 20
+
 	// Video import limits
-	MaxVideoLengthSeconds = 3600      // 1 hour max
+	MaxVideoLengthSeconds = 3600 // 1 hour max
 	VideoProcessTimeout   = 30 * time.Minute
 	MaxVideoImports       = 50
 )
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+// CreateAPITokenHandler issues a personal API token; the response is the only time it is shown
+// POST /api/tokens
+func (h *AuthHandler) CreateAPITokenHandler(c echo.Context) error {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	// A leaked token must not be able to mint more tokens
+	if principal.TokenID != "" {
+		return ForbiddenResponse(c, "api tokens cannot manage api tokens")
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	resp, err := h.authService.CreateAPIToken(principal.ID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to create api token")
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// ListAPITokensHandler lists the personal API tokens of the user, without their secrets
+// GET /api/tokens
+func (h *AuthHandler) ListAPITokensHandler(c echo.Context) error {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	tokens, err := h.authService.ListAPITokens(principal.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list api tokens")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tokens": tokens,
+	})
+}
+
+// RevokeAPITokenHandler revokes one of the user's personal API tokens
+// DELETE /api/tokens/:id
+func (h *AuthHandler) RevokeAPITokenHandler(c echo.Context) error {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	if principal.TokenID != "" {
+		return ForbiddenResponse(c, "api tokens cannot manage api tokens")
+	}
+
+	tokenID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.authService.RevokeAPIToken(principal.ID, tokenID); err != nil {
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			return NotFoundResponse(c, "api token")
+		}
+		return InternalErrorResponse(c, "failed to revoke api token")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -35,24 +35,9 @@ func (h *AuthHandler) RegisterHandler(c echo.Context) error {
 		return nil
 	}
 
-	resp, err := h.authService.Register(req.Email, req.Username, req.Password)
+	resp, err := h.authService.Register(req.Email, req.Username, req.Password, requestDevice(c))
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidEmail) {
-			return BadRequestResponse(c, err.Error())
-		}
-		if errors.Is(err, services.ErrInvalidUsername) {
-			return BadRequestResponse(c, err.Error())
-		}
-		if errors.Is(err, services.ErrPasswordTooShort) {
-			return BadRequestResponse(c, err.Error())
-		}
-		if errors.Is(err, repository.ErrEmailExists) {
-			return ConflictResponse(c, "email already taken")
-		}
-		if errors.Is(err, repository.ErrUsernameExists) {
-			return ConflictResponse(c, "username already taken")
-		}
-		return InternalErrorResponse(c, "failed to register")
+		return ServiceErrorResponse(c, err, "failed to register")
 	}
 
 	return c.JSON(http.StatusCreated, resp)
@@ -71,24 +56,21 @@ func (h *AuthHandler) LoginHandler(c echo.Context) error {
 		return nil
 	}
 
-	resp, err := h.authService.Login(req.Email, req.Password)
+	resp, err := h.authService.Login(req.Email, req.Password, requestDevice(c))
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidCredentials) {
-			return ErrorResponse(c, http.StatusUnauthorized, "invalid credentials")
-		}
-		if errors.Is(err, services.ErrOAuthOnly) {
-			return BadRequestResponse(c, err.Error())
-		}
-		return InternalErrorResponse(c, "failed to login")
+		return ServiceErrorResponse(c, err, "failed to login")
 	}
 
 	return c.JSON(http.StatusOK, resp)
 }
 
 func (h *AuthHandler) MeHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
-	user, err := h.authService.GetUserByID(userID)
+	user, err := h.authService.GetUserByID(principal.ID)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return ErrorResponse(c, http.StatusUnauthorized, "user not found")
@@ -103,10 +85,14 @@ var validTimeFormats = map[string]bool{
 	"bullet": true,
 	"blitz":  true,
 	"rapid":  true,
+	"daily":  true,
 }
 
 func (h *AuthHandler) UpdateProfileHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
 	var req models.UpdateProfileRequest
 	if err := c.Bind(&req); err != nil {
@@ -115,21 +101,86 @@ func (h *AuthHandler) UpdateProfileHandler(c echo.Context) error {
 
 	for _, tf := range req.TimeFormatPrefs {
 		if !validTimeFormats[tf] {
-			return BadRequestResponse(c, "invalid time format: "+tf+". Allowed values: bullet, blitz, rapid")
+			return BadRequestResponse(c, "invalid time format: "+tf+". Allowed values: bullet, blitz, rapid, daily")
 		}
 	}
 
-	user, err := h.authService.UpdateProfile(userID, req)
+	user, err := h.authService.UpdateProfile(principal.ID, req)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return ErrorResponse(c, http.StatusUnauthorized, "user not found")
 		}
-		return InternalErrorResponse(c, "failed to update profile")
+		return ServiceErrorResponse(c, err, "failed to update profile")
 	}
 
 	return c.JSON(http.StatusOK, user)
 }
 
+// UpdateNotificationsHandler sets the email notifications the user receives
+// PUT /api/auth/notifications
+func (h *AuthHandler) UpdateNotificationsHandler(c echo.Context) error {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.UpdateNotificationsRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	user, err := h.authService.UpdateNotifications(principal.ID, req)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrorResponse(c, http.StatusUnauthorized, "user not found")
+		}
+		return ServiceErrorResponse(c, err, "failed to update notifications")
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// ListSessionsHandler lists the devices the user is logged in on
+// GET /api/auth/sessions
+func (h *AuthHandler) ListSessionsHandler(c echo.Context) error {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	sessions, err := h.authService.ListSessions(principal.ID, principal.SessionID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list sessions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSessionHandler logs the user out of one of their sessions
+// DELETE /api/auth/sessions/:id
+func (h *AuthHandler) RevokeSessionHandler(c echo.Context) error {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	sessionID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.authService.RevokeSession(principal.ID, sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return NotFoundResponse(c, "session")
+		}
+		return InternalErrorResponse(c, "failed to revoke session")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 func (h *AuthHandler) ForgotPasswordHandler(c echo.Context) error {
 	var req models.ForgotPasswordRequest
 	if err := c.Bind(&req); err != nil {
@@ -163,19 +214,7 @@ func (h *AuthHandler) ResetPasswordHandler(c echo.Context) error {
 
 	err := h.authService.ResetPassword(req.Token, req.NewPassword)
 	if err != nil {
-		if errors.Is(err, services.ErrResetTokenInvalid) {
-			return BadRequestResponse(c, "invalid reset token")
-		}
-		if errors.Is(err, services.ErrResetTokenExpired) {
-			return BadRequestResponse(c, "reset token has expired")
-		}
-		if errors.Is(err, services.ErrResetTokenUsed) {
-			return BadRequestResponse(c, "reset token has already been used")
-		}
-		if errors.Is(err, services.ErrPasswordTooShort) {
-			return BadRequestResponse(c, "password must be at least 8 characters")
-		}
-		return InternalErrorResponse(c, "failed to reset password")
+		return ServiceErrorResponse(c, err, "failed to reset password")
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -184,7 +223,10 @@ func (h *AuthHandler) ResetPasswordHandler(c echo.Context) error {
 }
 
 func (h *AuthHandler) ChangePasswordHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
 	var req models.ChangePasswordRequest
 	if err := c.Bind(&req); err != nil {
@@ -198,21 +240,12 @@ func (h *AuthHandler) ChangePasswordHandler(c echo.Context) error {
 		return nil
 	}
 
-	err := h.authService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	err := h.authService.ChangePassword(user.ID, req.CurrentPassword, req.NewPassword)
 	if err != nil {
-		if errors.Is(err, services.ErrIncorrectPassword) {
-			return BadRequestResponse(c, "current password is incorrect")
-		}
-		if errors.Is(err, services.ErrNoPassword) {
-			return BadRequestResponse(c, "this account does not have a password set")
-		}
-		if errors.Is(err, services.ErrPasswordTooShort) {
-			return BadRequestResponse(c, "password must be at least 8 characters")
-		}
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return ErrorResponse(c, http.StatusUnauthorized, "user not found")
 		}
-		return InternalErrorResponse(c, "failed to change password")
+		return ServiceErrorResponse(c, err, "failed to change password")
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -221,9 +254,12 @@ func (h *AuthHandler) ChangePasswordHandler(c echo.Context) error {
 }
 
 func (h *AuthHandler) HasPasswordHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
-	hasPassword, err := h.authService.HasPassword(userID)
+	hasPassword, err := h.authService.HasPassword(user.ID)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return ErrorResponse(c, http.StatusUnauthorized, "user not found")
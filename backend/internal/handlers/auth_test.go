@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/treechess/backend/internal/middleware"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 	"github.com/treechess/backend/internal/repository/mocks"
@@ -275,7 +276,7 @@ func TestMeHandler_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	err := handler.MeHandler(c)
 
@@ -299,7 +300,7 @@ func TestMeHandler_NotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "nonexistent")
+	setTestPrincipal(c, "nonexistent")
 
 	handler.MeHandler(c)
 
@@ -309,11 +310,10 @@ func TestMeHandler_NotFound(t *testing.T) {
 func TestUpdateProfileHandler_Success(t *testing.T) {
 	lichess := "lichessuser"
 	mockRepo := &mocks.MockUserRepo{
-		UpdateProfileFunc: func(userID string, l, c *string, timeFormatPrefs []string) (*models.User, error) {
+		UpdateProfileFunc: func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
 			return &models.User{
-				ID:              userID,
-				Username:        "testuser",
-				LichessUsername: l,
+				ID:       userID,
+				Username: "testuser",
 			}, nil
 		},
 	}
@@ -325,7 +325,7 @@ func TestUpdateProfileHandler_Success(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	err := handler.UpdateProfileHandler(c)
 
@@ -335,7 +335,7 @@ func TestUpdateProfileHandler_Success(t *testing.T) {
 
 func TestUpdateProfileHandler_NotFound(t *testing.T) {
 	mockRepo := &mocks.MockUserRepo{
-		UpdateProfileFunc: func(userID string, l, c *string, timeFormatPrefs []string) (*models.User, error) {
+		UpdateProfileFunc: func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
 			return nil, repository.ErrUserNotFound
 		},
 	}
@@ -347,13 +347,30 @@ func TestUpdateProfileHandler_NotFound(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "nonexistent")
+	setTestPrincipal(c, "nonexistent")
 
 	handler.UpdateProfileHandler(c)
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 }
 
+func TestUpdateProfileHandler_InvalidLinkedAccount(t *testing.T) {
+	handler := newTestAuthHandler(&mocks.MockUserRepo{})
+
+	e := echo.New()
+	body := `{"linkedAccounts":[{"provider":"fics","username":"someone"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/auth/profile", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-123")
+
+	err := handler.UpdateProfileHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
 // Helper to create auth handler with password reset support
 func newTestAuthHandlerWithPasswordReset(
 	userRepo repository.UserRepository,
@@ -671,7 +688,7 @@ func TestChangePasswordHandler_Success(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	err := handler.ChangePasswordHandler(c)
 
@@ -702,7 +719,7 @@ func TestChangePasswordHandler_IncorrectCurrent(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	handler.ChangePasswordHandler(c)
 
@@ -733,7 +750,7 @@ func TestChangePasswordHandler_NoPassword(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	handler.ChangePasswordHandler(c)
 
@@ -763,7 +780,7 @@ func TestChangePasswordHandler_ShortNewPassword(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	handler.ChangePasswordHandler(c)
 
@@ -792,7 +809,7 @@ func TestChangePasswordHandler_MissingFields(t *testing.T) {
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			c.Set("userID", "user-123")
+			setTestPrincipal(c, "user-123")
 
 			handler.ChangePasswordHandler(c)
 
@@ -815,7 +832,7 @@ func TestChangePasswordHandler_UserNotFound(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "nonexistent")
+	setTestPrincipal(c, "nonexistent")
 
 	handler.ChangePasswordHandler(c)
 
@@ -840,7 +857,7 @@ func TestHasPasswordHandler_HasPassword(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/auth/has-password", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	err := handler.HasPasswordHandler(c)
 
@@ -870,7 +887,7 @@ func TestHasPasswordHandler_NoPassword(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/auth/has-password", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-123")
+	setTestPrincipal(c, "user-123")
 
 	err := handler.HasPasswordHandler(c)
 
@@ -894,9 +911,73 @@ func TestHasPasswordHandler_UserNotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/auth/has-password", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "nonexistent")
+	setTestPrincipal(c, "nonexistent")
 
 	handler.HasPasswordHandler(c)
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 }
+
+// --- Session Handler Tests ---
+
+func TestRevokeSessionHandler_NotFound(t *testing.T) {
+	authSvc := services.NewAuthService(&mocks.MockUserRepo{}, testJWTSecret, 24*time.Hour)
+	authSvc.WithSessions(&mocks.MockSessionRepo{
+		RevokeFunc: func(id, userID string, revokedAt time.Time) error {
+			return repository.ErrSessionNotFound
+		},
+	})
+	handler := NewAuthHandler(authSvc)
+	sessionID := "123e4567-e89b-12d3-a456-426614174000"
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/api/auth/sessions/"+sessionID, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(sessionID)
+	setTestUserID(c)
+
+	err := handler.RevokeSessionHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// --- API Token Handler Tests ---
+
+func TestCreateAPITokenHandler_RejectsAPITokens(t *testing.T) {
+	handler := newTestAuthHandler(&mocks.MockUserRepo{})
+
+	e := echo.New()
+	body := `{"name":"scripts","scope":"write"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	middleware.SetPrincipal(c, middleware.Principal{ID: testUserID, TokenID: "token-1", Scopes: []string{models.ScopeRead, models.ScopeWrite, models.ScopeImport}})
+
+	err := handler.CreateAPITokenHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestCreateAPITokenHandler_InvalidScope(t *testing.T) {
+	authSvc := services.NewAuthService(&mocks.MockUserRepo{}, testJWTSecret, 24*time.Hour)
+	authSvc.WithAPITokens(&mocks.MockAPITokenRepo{})
+	handler := NewAuthHandler(authSvc)
+
+	e := echo.New()
+	body := `{"name":"scripts","scope":"admin"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	err := handler.CreateAPITokenHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+// BoardSVGHandler draws a position as an SVG image, for study sheets, emails and link previews.
+// It is public and the image only depends on the query, so responses are cached.
+// GET /api/board.svg?fen=...&lastMove=e2e4&orientation=white|black
+func BoardSVGHandler(c echo.Context) error {
+	fen := c.QueryParam("fen")
+	if fen == "" {
+		return BadRequestResponse(c, "fen is required")
+	}
+
+	opts := services.BoardOptions{LastMove: c.QueryParam("lastMove")}
+	switch c.QueryParam("orientation") {
+	case "", "white":
+	case "black":
+		opts.Flipped = true
+	default:
+		return BadRequestResponse(c, "orientation must be white or black")
+	}
+
+	svg, err := services.RenderBoardSVG(fen, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBoardMove) {
+			return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeInvalidMove, err.Error())
+		}
+		return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeInvalidFEN, "invalid FEN")
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=86400")
+	return c.Blob(http.StatusOK, "image/svg+xml", []byte(svg))
+}
@@ -15,7 +15,10 @@ import (
 // GET /api/categories?color=white|black
 func ListCategoriesHandler(svc *services.CategoryService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		colorParam := c.QueryParam("color")
 
 		var colorFilter *models.Color
@@ -27,7 +30,7 @@ func ListCategoriesHandler(svc *services.CategoryService) echo.HandlerFunc {
 			colorFilter = &color
 		}
 
-		categories, err := svc.ListCategories(userID, colorFilter)
+		categories, err := svc.ListCategories(user.ID, colorFilter)
 		if err != nil {
 			return InternalErrorResponse(c, "failed to list categories")
 		}
@@ -45,7 +48,10 @@ func ListCategoriesHandler(svc *services.CategoryService) echo.HandlerFunc {
 // POST /api/categories
 func CreateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 
 		var req models.CreateCategoryRequest
 		if err := c.Bind(&req); err != nil {
@@ -60,18 +66,9 @@ func CreateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 			return BadRequestResponse(c, "invalid color. must be 'white' or 'black'")
 		}
 
-		cat, err := svc.CreateCategory(userID, req.Name, req.Color)
+		cat, err := svc.CreateCategory(user.ID, req.Name, req.Color)
 		if err != nil {
-			if errors.Is(err, services.ErrCategoryLimit) {
-				return ConflictResponse(c, "maximum category limit reached (50)")
-			}
-			if errors.Is(err, services.ErrNameRequired) {
-				return BadRequestResponse(c, "name is required")
-			}
-			if errors.Is(err, services.ErrNameTooLong) {
-				return BadRequestResponse(c, "name must be 100 characters or less")
-			}
-			return InternalErrorResponse(c, "failed to create category")
+			return ServiceErrorResponse(c, err, "failed to create category")
 		}
 
 		return c.JSON(http.StatusCreated, cat)
@@ -82,7 +79,10 @@ func CreateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 // GET /api/categories/:id
 func GetCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate ID is a valid UUID
@@ -90,8 +90,8 @@ func GetCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return NotFoundResponse(c, "category")
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "category")
 		}
 
 		catWithReps, err := svc.GetCategoryWithRepertoires(idParam)
@@ -110,7 +110,10 @@ func GetCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 // PATCH /api/categories/:id
 func UpdateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate ID is a valid UUID
@@ -118,8 +121,8 @@ func UpdateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return NotFoundResponse(c, "category")
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "category")
 		}
 
 		var req models.UpdateCategoryRequest
@@ -132,13 +135,7 @@ func UpdateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 			if errors.Is(err, services.ErrCategoryNotFound) {
 				return NotFoundResponse(c, "category")
 			}
-			if errors.Is(err, services.ErrNameRequired) {
-				return BadRequestResponse(c, "name is required")
-			}
-			if errors.Is(err, services.ErrNameTooLong) {
-				return BadRequestResponse(c, "name must be 100 characters or less")
-			}
-			return InternalErrorResponse(c, "failed to update category")
+			return ServiceErrorResponse(c, err, "failed to update category")
 		}
 
 		return c.JSON(http.StatusOK, cat)
@@ -149,7 +146,10 @@ func UpdateCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 // DELETE /api/categories/:id
 func DeleteCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate ID is a valid UUID
@@ -157,8 +157,8 @@ func DeleteCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return NotFoundResponse(c, "category")
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "category")
 		}
 
 		err := svc.DeleteCategory(idParam)
@@ -177,7 +177,10 @@ func DeleteCategoryHandler(svc *services.CategoryService) echo.HandlerFunc {
 // PATCH /api/repertoires/:id/category
 func AssignCategoryHandler(svc *services.RepertoireService, catSvc *services.CategoryService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate repertoire ID is a valid UUID
@@ -185,8 +188,8 @@ func AssignCategoryHandler(svc *services.RepertoireService, catSvc *services.Cat
 			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return NotFoundResponse(c, "repertoire")
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		var req models.AssignCategoryRequest
@@ -199,8 +202,8 @@ func AssignCategoryHandler(svc *services.RepertoireService, catSvc *services.Cat
 			if _, err := uuid.Parse(*req.CategoryID); err != nil {
 				return BadRequestResponse(c, "categoryId must be a valid UUID")
 			}
-			if err := catSvc.CheckOwnership(*req.CategoryID, userID); err != nil {
-				return NotFoundResponse(c, "category")
+			if err := catSvc.CheckOwnership(*req.CategoryID, user.ID); err != nil {
+				return AccessErrorResponse(c, err, "category")
 			}
 		}
 
@@ -221,3 +224,82 @@ func AssignCategoryHandler(svc *services.RepertoireService, catSvc *services.Cat
 		return c.JSON(http.StatusOK, rep)
 	}
 }
+
+// SetRepertoireMatchActiveHandler sets whether imported games are matched against a repertoire
+// PATCH /api/repertoires/:id/matching
+func SetRepertoireMatchActiveHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+		idParam := c.Param("id")
+
+		// Validate repertoire ID is a valid UUID
+		if _, err := uuid.Parse(idParam); err != nil {
+			return BadRequestResponse(c, "id must be a valid UUID")
+		}
+
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		var req models.SetMatchActiveRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+		if req.Active == nil {
+			return BadRequestResponse(c, "active is required")
+		}
+
+		rep, err := svc.SetMatchActive(idParam, *req.Active)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return InternalErrorResponse(c, "failed to update repertoire matching")
+		}
+
+		return c.JSON(http.StatusOK, rep)
+	}
+}
+
+// SetCategoryMatchActiveHandler sets whether the repertoires of a category are matched against
+// imported games
+// PATCH /api/categories/:id/matching
+func SetCategoryMatchActiveHandler(svc *services.CategoryService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+		idParam := c.Param("id")
+
+		// Validate ID is a valid UUID
+		if _, err := uuid.Parse(idParam); err != nil {
+			return BadRequestResponse(c, "id must be a valid UUID")
+		}
+
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "category")
+		}
+
+		var req models.SetMatchActiveRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+		if req.Active == nil {
+			return BadRequestResponse(c, "active is required")
+		}
+
+		cat, err := svc.SetMatchActive(idParam, *req.Active)
+		if err != nil {
+			if errors.Is(err, services.ErrCategoryNotFound) {
+				return NotFoundResponse(c, "category")
+			}
+			return InternalErrorResponse(c, "failed to update category matching")
+		}
+
+		return c.JSON(http.StatusOK, cat)
+	}
+}
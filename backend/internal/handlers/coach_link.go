@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+type CoachLinkHandler struct {
+	linkService *services.CoachLinkService
+}
+
+func NewCoachLinkHandler(linkSvc *services.CoachLinkService) *CoachLinkHandler {
+	return &CoachLinkHandler{linkService: linkSvc}
+}
+
+// InviteHandler invites a user, by email or username, to become the current user's coach or student
+// POST /api/links/invite
+func (h *CoachLinkHandler) InviteHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.InviteCoachLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	link, err := h.linkService.Invite(user.ID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to invite user")
+	}
+
+	return c.JSON(http.StatusCreated, link)
+}
+
+// ListHandler returns the current user's links, as coach or student, pending ones included
+// GET /api/links
+func (h *CoachLinkHandler) ListHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	links, err := h.linkService.List(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list links")
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
+
+// AcceptHandler accepts a link the current user was invited to
+// POST /api/links/:id/accept
+func (h *CoachLinkHandler) AcceptHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	link, err := h.linkService.Accept(id, user.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			return ForbiddenResponse(c, "only the invited user can accept a link")
+		}
+		if errors.Is(err, services.ErrLinkAlreadyActive) {
+			return ErrorCodeResponse(c, http.StatusConflict, models.ErrCodeAlreadyExists, err.Error())
+		}
+		return AccessErrorResponse(c, err, "link")
+	}
+
+	return c.JSON(http.StatusOK, link)
+}
+
+// RevokeHandler ends a link, or declines it while pending; either side may do so
+// DELETE /api/links/:id
+func (h *CoachLinkHandler) RevokeHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.linkService.Revoke(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "link")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
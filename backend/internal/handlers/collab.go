@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+// CollabHandler streams the edits of a repertoire to a watching client over a WebSocket.
+// The first message is a snapshot carrying the current version; every later message is an
+// edit event. Edits themselves go through the regular REST endpoints.
+// GET /api/repertoires/:id/ws?token=...
+func CollabHandler(svc *services.RepertoireService, hub *services.CollabHub) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		websocket.Handler(func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			// Subscribe before reading the version so no edit falls between the two
+			sub := hub.Subscribe(idParam)
+			defer hub.Unsubscribe(sub)
+
+			rep, err := svc.GetRepertoire(idParam)
+			if err != nil {
+				return
+			}
+			snapshot := models.RepertoireEvent{
+				Type:         models.RepertoireEventSnapshot,
+				RepertoireID: rep.ID,
+				Version:      rep.Version,
+			}
+			if err := websocket.JSON.Send(ws, snapshot); err != nil {
+				return
+			}
+
+			// Clients only listen; reading detects when they go away
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var discard string
+				for {
+					if err := websocket.Message.Receive(ws, &discard); err != nil {
+						return
+					}
+				}
+			}()
+
+			for {
+				select {
+				case <-closed:
+					return
+				case event, ok := <-sub.Events:
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						log.Printf("collab: failed to send event to %s: %v", user.ID, err)
+						return
+					}
+				}
+			}
+		}).ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
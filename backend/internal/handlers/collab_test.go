@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+	"github.com/treechess/backend/internal/services"
+)
+
+func TestCollabHandler_StreamsEdits(t *testing.T) {
+	repID := "123e4567-e89b-12d3-a456-426614174000"
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Version: 4}, nil
+		},
+	}
+	svc := services.NewRepertoireService(mockRepo)
+	hub := services.NewCollabHub()
+
+	e := echo.New()
+	e.GET("/api/repertoires/:id/ws", CollabHandler(svc, hub), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			setTestUserID(c)
+			return next(c)
+		}
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/repertoires/" + repID + "/ws"
+	ws, err := websocket.Dial(wsURL, "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var snapshot models.RepertoireEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &snapshot))
+	assert.Equal(t, models.RepertoireEventSnapshot, snapshot.Type)
+	assert.Equal(t, 4, snapshot.Version)
+
+	hub.Publish(models.RepertoireEvent{Type: models.RepertoireEventNodeDeleted, RepertoireID: repID, Version: 5, NodeID: "n1"})
+
+	var event models.RepertoireEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, models.RepertoireEventNodeDeleted, event.Type)
+	assert.Equal(t, 5, event.Version)
+	assert.Equal(t, "n1", event.NodeID)
+}
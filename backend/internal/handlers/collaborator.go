@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/services"
+)
+
+// ListSharedRepertoiresHandler returns the repertoires other users shared with the current user
+// GET /api/repertoires/shared
+func ListSharedRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		shared, err := svc.ListSharedRepertoires(user.ID)
+		if err != nil {
+			return InternalErrorResponse(c, "failed to list shared repertoires")
+		}
+
+		return c.JSON(http.StatusOK, shared)
+	}
+}
+
+// ListCollaboratorsHandler returns the users a repertoire is shared with
+// GET /api/repertoires/:id/collaborators
+func ListCollaboratorsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		collaborators, err := svc.ListCollaborators(idParam)
+		if err != nil {
+			return InternalErrorResponse(c, "failed to list collaborators")
+		}
+
+		return c.JSON(http.StatusOK, collaborators)
+	}
+}
+
+// InviteCollaboratorHandler shares a repertoire with another user by email or username
+// POST /api/repertoires/:id/collaborators
+func InviteCollaboratorHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		var req models.InviteCollaboratorRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+
+		collaborator, err := svc.InviteCollaborator(idParam, user.ID, req)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to invite collaborator")
+		}
+
+		return c.JSON(http.StatusCreated, collaborator)
+	}
+}
+
+// RemoveCollaboratorHandler revokes a collaborator; collaborators may also remove themselves
+// DELETE /api/repertoires/:id/collaborators/:userId
+func RemoveCollaboratorHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		collaboratorID, ok := ValidateUUIDParam(c, "userId")
+		if !ok {
+			return nil
+		}
+
+		if err := svc.RemoveCollaborator(idParam, user.ID, collaboratorID); err != nil {
+			if errors.Is(err, services.ErrNotFound) || errors.Is(err, services.ErrForbidden) {
+				return AccessErrorResponse(c, err, "repertoire")
+			}
+			if errors.Is(err, repository.ErrCollaboratorNotFound) {
+				return NotFoundResponse(c, "collaborator")
+			}
+			return InternalErrorResponse(c, "failed to remove collaborator")
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
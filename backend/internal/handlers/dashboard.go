@@ -17,9 +17,12 @@ func NewDashboardHandler(importSvc *services.ImportService) *DashboardHandler {
 }
 
 func (h *DashboardHandler) GetStats(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
-	stats, err := h.importService.GetDashboardStats(userID)
+	stats, err := h.importService.GetDashboardStats(user.ID)
 	if err != nil {
 		return InternalErrorResponse(c, "failed to get dashboard stats")
 	}
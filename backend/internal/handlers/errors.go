@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/services"
+)
+
+// serviceError is how the API answers a service or repository sentinel error
+type serviceError struct {
+	err    error
+	status int
+	code   string
+}
+
+// serviceErrors maps sentinel errors to their status and stable code, checked in order with errors.Is.
+// Errors wrapping services.ErrNotFound or services.ErrForbidden fall through to the generic entries at the end.
+var serviceErrors = []serviceError{
+	// Invalid input
+	{services.ErrInvalidFEN, http.StatusBadRequest, models.ErrCodeInvalidFEN},
+	{services.ErrInvalidMove, http.StatusBadRequest, models.ErrCodeInvalidMove},
+	{services.ErrInvalidBoardMove, http.StatusBadRequest, models.ErrCodeInvalidMove},
+	{services.ErrColorMismatch, http.StatusBadRequest, models.ErrCodeColorMismatch},
+	{services.ErrMergeColorMismatch, http.StatusBadRequest, models.ErrCodeColorMismatch},
+	{services.ErrMixedColors, http.StatusBadRequest, models.ErrCodeColorMismatch},
+	{services.ErrCannotDeleteRoot, http.StatusBadRequest, models.ErrCodeRootNode},
+	{services.ErrCannotExtractRoot, http.StatusBadRequest, models.ErrCodeRootNode},
+	{services.ErrTooManyLinkedAccounts, http.StatusBadRequest, models.ErrCodeLinkedAccountLimitReached},
+	{services.ErrOAuthOnly, http.StatusBadRequest, models.ErrCodeOAuthOnly},
+	{services.ErrIncorrectPassword, http.StatusBadRequest, models.ErrCodeIncorrectPassword},
+	{services.ErrResetTokenInvalid, http.StatusBadRequest, models.ErrCodeResetTokenInvalid},
+	{services.ErrResetTokenExpired, http.StatusBadRequest, models.ErrCodeResetTokenExpired},
+	{services.ErrResetTokenUsed, http.StatusBadRequest, models.ErrCodeResetTokenUsed},
+	{services.ErrInvalidUsername, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidEmail, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrPasswordTooShort, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrNoPassword, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidLinkedAccount, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrDigestNeedsEmail, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidTimezone, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidLocale, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidAnalysisDepth, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidAPITokenName, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidAPITokenScope, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidColor, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrNameRequired, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrNameTooLong, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrDescriptionTooLong, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrTooManyTags, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrTagTooLong, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidChildOrder, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrMergeMinimumTwo, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrMergeDuplicateIDs, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidSearchQuery, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidExportFormat, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidRole, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInviteeRequired, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrCannotInviteSelf, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidLinkRole, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrCannotLinkSelf, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidGoalType, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidGoalValue, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidPrepDepth, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidInsightSettings, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidClassificationRule, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidRatingRange, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidExplorerFilter, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrGameNoteTooLong, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrStudyNotesTooLong, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrEmptyGameUpdate, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrNotTrainingPosition, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrTooManyPieces, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrCustomStartingPosition, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrGameCustomStart, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidAdoptPly, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrNoUserGames, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidSparringRating, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidNotificationEvent, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidWebhookURL, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidPushSubscription, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidRepertoireFile, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrUnsupportedRepertoireFile, http.StatusBadRequest, models.ErrCodeValidationFailed},
+	{services.ErrInvalidCommentsFile, http.StatusBadRequest, models.ErrCodeValidationFailed},
+
+	// Authentication
+	{services.ErrInvalidCredentials, http.StatusUnauthorized, models.ErrCodeInvalidCredentials},
+	{services.ErrUnauthorized, http.StatusUnauthorized, models.ErrCodeUnauthorized},
+
+	// Missing resources
+	{services.ErrNodeNotFound, http.StatusNotFound, models.ErrCodeNodeNotFound},
+	{services.ErrParentNotFound, http.StatusNotFound, models.ErrCodeNodeNotFound},
+	{services.ErrInviteeNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrTemplateNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrLichessUserNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrChesscomUserNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrLichessStudyNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrLichessBroadcastNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrLichessTeamNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrAnalysisNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrGameNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrImportJobNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrTeamImportNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrImportSummaryNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrReanalysisJobNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrRecomputeJobNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrSyncRunNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrDismissedMistakeNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrCollaboratorNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrRevisionNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrStudyNotesNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrPrepRequestNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrPrepSuggestionNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrNotificationWebhookNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{repository.ErrPushSubscriptionNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+
+	// Conflicts and limits
+	{services.ErrLimitReached, http.StatusConflict, models.ErrCodeRepertoireLimitReached},
+	{services.ErrCategoryLimit, http.StatusConflict, models.ErrCodeCategoryLimitReached},
+	{services.ErrGoalLimit, http.StatusConflict, models.ErrCodeGoalLimitReached},
+	{services.ErrClassificationRuleLimit, http.StatusConflict, models.ErrCodeClassificationRuleLimitReached},
+	{services.ErrTooManyAPITokens, http.StatusConflict, models.ErrCodeAPITokenLimitReached},
+	{services.ErrMoveExists, http.StatusConflict, models.ErrCodeMoveExists},
+	{services.ErrAllGamesDuplicate, http.StatusConflict, models.ErrCodeDuplicateGame},
+	{services.ErrCoachLinkExists, http.StatusConflict, models.ErrCodeAlreadyExists},
+	{services.ErrLinkAlreadyActive, http.StatusConflict, models.ErrCodeAlreadyExists},
+	{services.ErrSparringOver, http.StatusConflict, models.ErrCodeConflict},
+	{services.ErrConcurrentEdit, http.StatusConflict, models.ErrCodeConflict},
+	{services.ErrRepertoireArchived, http.StatusConflict, models.ErrCodeRepertoireArchived},
+	{repository.ErrEmailExists, http.StatusConflict, models.ErrCodeEmailTaken},
+	{repository.ErrUsernameExists, http.StatusConflict, models.ErrCodeUsernameTaken},
+	{repository.ErrClassificationRuleKeyExists, http.StatusConflict, models.ErrCodeAlreadyExists},
+	{services.ErrImportTooLarge, http.StatusRequestEntityTooLarge, models.ErrCodeImportTooLarge},
+
+	// Budgets, upstream services and optional features
+	{services.ErrExplorerBudgetExceeded, http.StatusTooManyRequests, models.ErrCodeExplorerBudgetExceeded},
+	{services.ErrUsageQuotaExceeded, http.StatusTooManyRequests, models.ErrCodeUsageQuotaExceeded},
+	{services.ErrExplorerBusy, http.StatusServiceUnavailable, models.ErrCodeExplorerBusy},
+	{services.ErrLichessRateLimited, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited},
+	{services.ErrChesscomRateLimited, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited},
+	{services.ErrLichessStudyForbidden, http.StatusForbidden, models.ErrCodePrivateStudy},
+	{services.ErrCircuitOpen, http.StatusServiceUnavailable, models.ErrCodeUpstreamUnavailable},
+	{services.ErrEngineUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrTablebaseUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrImportJobsUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrRecomputeUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrPushUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrTeamImportsUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrAPITokensUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrCollaboratorsUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+	{services.ErrTemplatesUnavailable, http.StatusServiceUnavailable, models.ErrCodeFeatureUnavailable},
+
+	// Generic access errors, last so the specific errors wrapping them match first
+	{services.ErrNotFound, http.StatusNotFound, models.ErrCodeNotFound},
+	{services.ErrForbidden, http.StatusForbidden, models.ErrCodeForbidden},
+}
+
+// ErrorCodeResponse sends the API error envelope with an explicit code
+func ErrorCodeResponse(c echo.Context, status int, code, message string) error {
+	return c.JSON(status, models.APIError{Code: code, Message: message})
+}
+
+// ServiceErrorResponse answers a failed service call through the central error mapping: a known
+// sentinel error is sent with its status and code, anything else as a 500 with the fallback message
+func ServiceErrorResponse(c echo.Context, err error, fallback string) error {
+	for _, se := range serviceErrors {
+		if errors.Is(err, se.err) {
+			return ErrorCodeResponse(c, se.status, se.code, err.Error())
+		}
+	}
+	return InternalErrorResponse(c, fallback)
+}
+
+// codeForStatus is the generic code of responses that do not come from a specific service error
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return models.ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return models.ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return models.ErrCodeForbidden
+	case http.StatusNotFound:
+		return models.ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return models.ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return models.ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return models.ErrCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return models.ErrCodeRateLimited
+	case http.StatusBadGateway:
+		return models.ErrCodeBadGateway
+	case http.StatusServiceUnavailable:
+		return models.ErrCodeServiceUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return models.ErrCodeInternal
+	}
+	return models.ErrCodeBadRequest
+}
+
+// HTTPErrorHandler sends the errors Echo raises itself (unknown routes, body limits, panics) in the API error envelope
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+		if msg, ok := he.Message.(string); ok {
+			message = msg
+		} else {
+			message = http.StatusText(status)
+		}
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = ErrorResponse(c, status, message)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+func decodeAPIError(t *testing.T, rec *httptest.ResponseRecorder) models.APIError {
+	t.Helper()
+	var body models.APIError
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body
+}
+
+func TestServiceErrorResponse_MapsWrappedSentinel(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+
+	err := ServiceErrorResponse(c, fmt.Errorf("seed: %w", services.ErrLimitReached), "failed")
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	body := decodeAPIError(t, rec)
+	assert.Equal(t, models.ErrCodeRepertoireLimitReached, body.Code)
+	assert.Contains(t, body.Message, "maximum repertoire limit reached")
+}
+
+func TestServiceErrorResponse_SpecificBeforeGeneric(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	// ErrGoalNotFound wraps ErrNotFound and must keep its own message
+	err := ServiceErrorResponse(c, services.ErrGoalNotFound, "failed")
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	body := decodeAPIError(t, rec)
+	assert.Equal(t, models.ErrCodeNotFound, body.Code)
+	assert.Equal(t, "goal not found", body.Message)
+}
+
+func TestServiceErrorResponse_UnknownErrorIsInternal(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	err := ServiceErrorResponse(c, fmt.Errorf("connection refused"), "failed to get goals")
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	body := decodeAPIError(t, rec)
+	assert.Equal(t, models.ErrCodeInternal, body.Code)
+	// Internal details are not leaked
+	assert.Equal(t, "failed to get goals", body.Message)
+}
+
+func TestErrorResponse_GenericCodePerStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusBadRequest, models.ErrCodeBadRequest},
+		{http.StatusUnauthorized, models.ErrCodeUnauthorized},
+		{http.StatusNotFound, models.ErrCodeNotFound},
+		{http.StatusTooManyRequests, models.ErrCodeRateLimited},
+		{http.StatusBadGateway, models.ErrCodeBadGateway},
+		{http.StatusInternalServerError, models.ErrCodeInternal},
+	}
+	for _, tt := range tests {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+		require.NoError(t, ErrorResponse(c, tt.status, "message"))
+
+		assert.Equal(t, tt.status, rec.Code)
+		assert.Equal(t, tt.code, decodeAPIError(t, rec).Code)
+	}
+}
+
+func TestHTTPErrorHandler_UnknownRoute(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	body := decodeAPIError(t, rec)
+	assert.Equal(t, models.ErrCodeNotFound, body.Code)
+	assert.Equal(t, "Not Found", body.Message)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+type GoalHandler struct {
+	goalService       *services.GoalService
+	repertoireService *services.RepertoireService
+}
+
+func NewGoalHandler(goalSvc *services.GoalService, repertoireSvc *services.RepertoireService) *GoalHandler {
+	return &GoalHandler{goalService: goalSvc, repertoireService: repertoireSvc}
+}
+
+// CreateGoalHandler adds a goal to a repertoire
+// POST /api/repertoires/:id/goals
+func (h *GoalHandler) CreateGoalHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	repertoireID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.repertoireService.CheckOwner(repertoireID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "repertoire")
+	}
+
+	var req models.CreateGoalRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	goal, err := h.goalService.CreateGoal(user.ID, repertoireID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to create goal")
+	}
+
+	return c.JSON(http.StatusCreated, goal)
+}
+
+// ListGoalsHandler returns all goals of the current user with their latest progress
+// GET /api/goals
+func (h *GoalHandler) ListGoalsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	goals, err := h.goalService.ListGoals(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list goals")
+	}
+
+	if goals == nil {
+		goals = []models.Goal{}
+	}
+
+	return c.JSON(http.StatusOK, goals)
+}
+
+// DeleteGoalHandler deletes a goal
+// DELETE /api/goals/:id
+func (h *GoalHandler) DeleteGoalHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.goalService.CheckOwnership(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "goal")
+	}
+
+	if err := h.goalService.DeleteGoal(id); err != nil {
+		if errors.Is(err, services.ErrGoalNotFound) {
+			return NotFoundResponse(c, "goal")
+		}
+		return InternalErrorResponse(c, "failed to delete goal")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -4,13 +4,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/middleware"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 	"github.com/treechess/backend/internal/repository/mocks"
@@ -25,9 +29,42 @@ func newTestRepertoireService() *services.RepertoireService {
 	return services.NewRepertoireService(mockRepo)
 }
 
-// setTestUserID sets the userID in the echo context for testing
+// setTestUserID authenticates the request as the test user
 func setTestUserID(c echo.Context) {
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
+}
+
+// setTestPrincipal authenticates the request as userID, like the JWT middleware does
+func setTestPrincipal(c echo.Context, userID string) {
+	middleware.SetPrincipal(c, middleware.Principal{ID: userID})
+}
+
+func TestCurrentUser_Unauthenticated(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := ListRepertoiresHandler(newTestRepertoireService())(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAccessErrorResponse(t *testing.T) {
+	cases := map[error]int{
+		services.ErrForbidden:        http.StatusForbidden,
+		services.ErrNotFound:         http.StatusNotFound,
+		services.ErrCategoryNotFound: http.StatusNotFound,
+		assert.AnError:               http.StatusInternalServerError,
+	}
+	for accessErr, expected := range cases {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+		require.NoError(t, AccessErrorResponse(c, accessErr, "repertoire"))
+		assert.Equal(t, expected, rec.Code, accessErr.Error())
+	}
 }
 
 func TestHealthHandler(t *testing.T) {
@@ -842,12 +879,48 @@ func TestListTemplatesHandler_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/repertoires/templates", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	setTestUserID(c)
 
-	handler := ListTemplatesHandler()
+	handler := ListTemplatesHandler(newTestRepertoireService())
 	err := handler(c)
 
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"id":"italian"`)
+}
+
+func TestPublishTemplateHandler_MissingRepertoireID(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/templates", strings.NewReader(`{"name":"Mine"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := PublishTemplateHandler(newTestRepertoireService())
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSetTemplateFeaturedHandler_NotFound(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/api/repertoires/templates/missing/featured", strings.NewReader(`{"featured":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("missing")
+	setTestUserID(c)
+
+	svc := newTestRepertoireService()
+	svc.WithTemplates(&mocks.MockTemplateRepo{})
+	handler := SetTemplateFeaturedHandler(svc)
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
 
 // --- SeedHandler tests ---
@@ -983,6 +1056,158 @@ func TestExtractSubtreeHandler_CannotExtractRoot(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
+// --- MirrorRepertoireHandler tests ---
+
+func TestMirrorRepertoireHandler_InvalidRepertoireID(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/not-a-uuid/mirror", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("not-a-uuid")
+	setTestUserID(c)
+
+	svc := newTestRepertoireService()
+	handler := MirrorRepertoireHandler(svc)
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestMirrorRepertoireHandler_NotFound(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/"+validUUID+"/mirror", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return nil, repository.ErrRepertoireNotFound
+		},
+	}
+	svc := services.NewRepertoireService(mockRepo)
+	handler := MirrorRepertoireHandler(svc)
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// --- Revision handler tests ---
+
+func TestDiffRevisionsHandler_InvalidVersion(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires/"+validUUID+"/revisions/zero/diff/2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "revA", "revB")
+	c.SetParamValues(validUUID, "zero", "2")
+	setTestUserID(c)
+
+	svc := newTestRepertoireService()
+	handler := DiffRevisionsHandler(svc)
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRestoreRevisionHandler_RevisionNotFound(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/"+validUUID+"/revisions/3/restore", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "rev")
+	c.SetParamValues(validUUID, "3")
+	setTestUserID(c)
+
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id}, nil
+		},
+	}
+	svc := services.NewRepertoireService(mockRepo)
+	handler := RestoreRevisionHandler(svc)
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// --- Study notes handler tests ---
+
+func TestSaveStudyNotesHandler(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"saved", `{"content":"# Plans"}`, http.StatusOK},
+		{"cleared", `{"content":""}`, http.StatusOK},
+		{"missing content", `{}`, http.StatusBadRequest},
+		{"too long", `{"content":"` + strings.Repeat("a", config.MaxStudyNotesLen+1) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/api/repertoires/"+validUUID+"/notes", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(validUUID)
+			setTestUserID(c)
+
+			mockRepo := &mocks.MockRepertoireRepo{
+				BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+				GetNotesFunc: func(repertoireID string, version int) (*models.StudyNotes, error) {
+					return &models.StudyNotes{RepertoireID: repertoireID, Version: 1, Content: "old"}, nil
+				},
+			}
+			err := SaveStudyNotesHandler(services.NewRepertoireService(mockRepo))(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestGetStudyNotesHandler_Revision(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires/"+validUUID+"/notes/revisions/2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "rev")
+	c.SetParamValues(validUUID, "2")
+	setTestUserID(c)
+
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+		GetNotesFunc: func(repertoireID string, version int) (*models.StudyNotes, error) {
+			assert.Equal(t, 2, version)
+			return &models.StudyNotes{RepertoireID: repertoireID, Version: version, Content: "older plans"}, nil
+		},
+	}
+	err := GetStudyNotesHandler(services.NewRepertoireService(mockRepo))(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var notes models.StudyNotes
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+	assert.Equal(t, "older plans", notes.Content)
+}
+
 // --- MergeRepertoiresHandler tests ---
 
 func TestMergeRepertoiresHandler_TooFewIDs(t *testing.T) {
@@ -1155,3 +1380,404 @@ func TestDeleteNodeHandler_CannotDeleteRoot(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, response["error"], "cannot delete root")
 }
+
+func newETagTestService(updatedAt time.Time) *services.RepertoireService {
+	return services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Name: "Test", Color: models.ColorWhite, UpdatedAt: updatedAt}, nil
+		},
+	})
+}
+
+func runGetRepertoire(t *testing.T, svc *services.RepertoireService, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	return runGetRepertoireQuery(t, svc, "", headers)
+}
+
+func runGetRepertoireQuery(t *testing.T, svc *services.RepertoireService, query string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires/"+validUUID+query, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	require.NoError(t, GetRepertoireHandler(svc)(c))
+	return rec
+}
+
+func TestGetRepertoireHandler_ETag(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := newETagTestService(updatedAt)
+
+	first := runGetRepertoire(t, svc, nil)
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, updatedAt.Format(http.TimeFormat), first.Header().Get("Last-Modified"))
+
+	second := runGetRepertoire(t, svc, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, second.Code)
+	assert.Empty(t, second.Body.Bytes())
+
+	stale := runGetRepertoire(t, svc, map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, http.StatusOK, stale.Code)
+}
+
+func TestGetRepertoireHandler_IfModifiedSince(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := newETagTestService(updatedAt)
+
+	notModified := runGetRepertoire(t, svc, map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+
+	modified := runGetRepertoire(t, svc, map[string]string{"If-Modified-Since": updatedAt.Add(-time.Hour).Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, modified.Code)
+}
+
+func TestGetRepertoireHandler_IfModifiedSinceIgnoredForVariants(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := newETagTestService(updatedAt)
+
+	slim := runGetRepertoireQuery(t, svc, "?fields=slim", nil)
+	require.Equal(t, http.StatusOK, slim.Code)
+	assert.Empty(t, slim.Header().Get("Last-Modified"))
+	notModified := runGetRepertoireQuery(t, svc, "?fields=slim", map[string]string{"If-None-Match": slim.Header().Get("ETag")})
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+
+	// A date validated against one variant must not answer 304 for another
+	sinceFull := map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)}
+	full := runGetRepertoire(t, svc, map[string]string{"If-None-Match": slim.Header().Get("ETag")})
+	assert.Equal(t, http.StatusOK, full.Code)
+	assert.Contains(t, full.Body.String(), `"treeData"`)
+	for _, query := range []string{"?fields=slim", "?depth=2"} {
+		rec := runGetRepertoireQuery(t, svc, query, sinceFull)
+		assert.Equal(t, http.StatusOK, rec.Code, query)
+		assert.NotEmpty(t, rec.Body.Bytes(), query)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`W/"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`"x", "abc"`, `"abc"`))
+	assert.True(t, etagMatches(`*`, `"abc"`))
+	assert.False(t, etagMatches(`"x"`, `"abc"`))
+}
+
+func TestUpdateNodeTagsHandler_InvalidNodeID(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/api/repertoires/123e4567-e89b-12d3-a456-426614174000/nodes/bad/tags", strings.NewReader(`{"tags":["critical"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "nodeId")
+	c.SetParamValues("123e4567-e89b-12d3-a456-426614174000", "bad")
+	setTestUserID(c)
+
+	handler := UpdateNodeTagsHandler(newTestRepertoireService())
+
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUpdateNodeTagsHandler_TagTooLong(t *testing.T) {
+	e := echo.New()
+	body := `{"tags":["` + strings.Repeat("x", 50) + `"]}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/repertoires/123e4567-e89b-12d3-a456-426614174000/nodes/223e4567-e89b-12d3-a456-426614174000/tags", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "nodeId")
+	c.SetParamValues("123e4567-e89b-12d3-a456-426614174000", "223e4567-e89b-12d3-a456-426614174000")
+	setTestUserID(c)
+
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: models.RepertoireNode{ID: "223e4567-e89b-12d3-a456-426614174000"}}, nil
+		},
+	}
+	handler := UpdateNodeTagsHandler(services.NewRepertoireService(mockRepo))
+
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestReorderChildrenHandler_InvalidOrder(t *testing.T) {
+	e := echo.New()
+	rootUUID := "223e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPatch, "/api/repertoires/123e4567-e89b-12d3-a456-426614174000/nodes/"+rootUUID+"/reorder", strings.NewReader(`{"childIds":["a"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "nodeId")
+	c.SetParamValues("123e4567-e89b-12d3-a456-426614174000", rootUUID)
+	setTestUserID(c)
+
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: models.RepertoireNode{
+				ID:       rootUUID,
+				Children: []*models.RepertoireNode{{ID: "a"}, {ID: "b"}},
+			}}, nil
+		},
+	}
+	handler := ReorderChildrenHandler(services.NewRepertoireService(mockRepo))
+
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetSubtreeHandler_Depth(t *testing.T) {
+	e := echo.New()
+	repUUID := "123e4567-e89b-12d3-a456-426614174000"
+	nodeUUID := "223e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires/"+repUUID+"/nodes/"+nodeUUID+"/subtree?depth=1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "nodeId")
+	c.SetParamValues(repUUID, nodeUUID)
+	setTestUserID(c)
+
+	mockRepo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: models.RepertoireNode{
+				ID: "root",
+				Children: []*models.RepertoireNode{{
+					ID: nodeUUID,
+					Children: []*models.RepertoireNode{{
+						ID:       "child",
+						Children: []*models.RepertoireNode{{ID: "grandchild"}},
+					}},
+				}},
+			}}, nil
+		},
+	}
+	handler := GetSubtreeHandler(services.NewRepertoireService(mockRepo))
+
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var subtree models.RepertoireNode
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &subtree))
+	require.Len(t, subtree.Children, 1)
+	assert.True(t, subtree.Children[0].Truncated)
+	assert.Empty(t, subtree.Children[0].Children)
+}
+
+func TestInviteCollaboratorHandler_InvalidRole(t *testing.T) {
+	e := echo.New()
+	body := `{"username":"student","role":"owner"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/123e4567-e89b-12d3-a456-426614174000/collaborators", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("123e4567-e89b-12d3-a456-426614174000")
+	setTestUserID(c)
+
+	svc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+	})
+	svc.WithCollaborators(&mocks.MockCollaboratorRepo{}, &mocks.MockUserRepo{})
+	handler := InviteCollaboratorHandler(svc)
+
+	err := handler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetRepertoireHandler_SharedWithViewer(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires/123e4567-e89b-12d3-a456-426614174000", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("123e4567-e89b-12d3-a456-426614174000")
+	setTestUserID(c)
+
+	svc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return false, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Name: "Coach prep"}, nil
+		},
+	})
+	svc.WithCollaborators(&mocks.MockCollaboratorRepo{
+		GetFunc: func(repertoireID, userID string) (*models.RepertoireCollaborator, error) {
+			return &models.RepertoireCollaborator{RepertoireID: repertoireID, UserID: userID, Role: models.CollaboratorViewer}, nil
+		},
+	}, nil)
+
+	err := GetRepertoireHandler(svc)(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Viewers cannot edit
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodDelete, "/", nil), rec)
+	c.SetParamNames("id", "nodeId")
+	c.SetParamValues("123e4567-e89b-12d3-a456-426614174000", "223e4567-e89b-12d3-a456-426614174000")
+	setTestUserID(c)
+	err = DeleteNodeHandler(svc)(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestExportStudySheetHandler(t *testing.T) {
+	newContext := func(format string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/repertoires/123e4567-e89b-12d3-a456-426614174000/export?format="+format, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("123e4567-e89b-12d3-a456-426614174000")
+		setTestUserID(c)
+		return c, rec
+	}
+	move := "e4"
+	svc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{
+				ID: id, Name: "e4", Color: models.ColorWhite,
+				TreeData: models.RepertoireNode{
+					ID: "root", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+					Children: []*models.RepertoireNode{{ID: "e4", Move: &move, MoveNumber: 1, ColorToMove: models.ChessColorBlack}},
+				},
+			}, nil
+		},
+	})
+
+	c, rec := newContext("html")
+	require.NoError(t, ExportStudySheetHandler(svc)(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "repertoire.html")
+	assert.Contains(t, rec.Body.String(), "1. e4")
+
+	c, rec = newContext("")
+	require.NoError(t, ExportStudySheetHandler(svc)(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/markdown")
+
+	c, rec = newContext("pdf")
+	require.NoError(t, ExportStudySheetHandler(svc)(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestBoardSVGHandler(t *testing.T) {
+	call := func(query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/board.svg?"+query, nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, BoardSVGHandler(e.NewContext(req, rec)))
+		return rec
+	}
+	fen := url.QueryEscape("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -")
+
+	rec := call("fen=" + fen + "&lastMove=e2e4&orientation=black")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/svg+xml", rec.Header().Get(echo.HeaderContentType))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "<svg "))
+
+	assert.Equal(t, http.StatusBadRequest, call("").Code)
+	assert.Equal(t, http.StatusBadRequest, call("fen=nonsense").Code)
+	assert.Equal(t, http.StatusBadRequest, call("fen="+fen+"&orientation=sideways").Code)
+	assert.Equal(t, http.StatusBadRequest, call("fen="+fen+"&lastMove=z9z9").Code)
+}
+
+func TestSearchRepertoiresHandler(t *testing.T) {
+	var gotUser, gotQuery string
+	svc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		SearchFunc: func(userID, query string, limit int) ([]models.RepertoireSearchResult, error) {
+			gotUser, gotQuery = userID, query
+			return []models.RepertoireSearchResult{
+				{RepertoireID: "rep-1", RepertoireName: "Najdorf", NodeID: "n3", MovePath: []string{"e4", "c5", "Nf3"}, Comment: "Beware the trap"},
+			}, nil
+		},
+	})
+
+	t.Run("returns the matches", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/repertoires/search?q=+trap+", nil), rec)
+		setTestUserID(c)
+
+		require.NoError(t, SearchRepertoiresHandler(svc)(c))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, testUserID, gotUser)
+		assert.Equal(t, "trap", gotQuery)
+		var results []models.RepertoireSearchResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+		require.Len(t, results, 1)
+		assert.Equal(t, []string{"e4", "c5", "Nf3"}, results[0].MovePath)
+	})
+
+	t.Run("requires a query", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/repertoires/search", nil), rec)
+		setTestUserID(c)
+
+		require.NoError(t, SearchRepertoiresHandler(svc)(c))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+// --- PrepHandler tests ---
+
+func TestRequestPrepHandler_InvalidNodeID(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/"+validUUID+"/nodes/not-a-uuid/prep", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "nodeId")
+	c.SetParamValues(validUUID, "not-a-uuid")
+	setTestUserID(c)
+
+	repertoireSvc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+	})
+	handler := NewPrepHandler(services.NewPrepService(&mocks.MockPrepRepo{}, repertoireSvc, nil), repertoireSvc)
+
+	require.NoError(t, handler.RequestPrepHandler(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDiscardSuggestionHandler_UnknownPrep(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "prepId", "suggestionId")
+	c.SetParamValues(validUUID, validUUID, validUUID)
+	setTestUserID(c)
+
+	repertoireSvc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+	})
+	handler := NewPrepHandler(services.NewPrepService(&mocks.MockPrepRepo{}, repertoireSvc, nil), repertoireSvc)
+
+	require.NoError(t, handler.DiscardSuggestionHandler(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -1,16 +1,26 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/middleware"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
 )
 
-// ErrorResponse sends a JSON error response with the given status code and message
+// ErrorResponse sends the API error envelope with the generic code of the status
 func ErrorResponse(c echo.Context, status int, message string) error {
-	return c.JSON(status, map[string]string{"error": message})
+	return ErrorCodeResponse(c, status, codeForStatus(status), message)
 }
 
 // BadRequestResponse sends a 400 Bad Request error response
@@ -28,11 +38,51 @@ func InternalErrorResponse(c echo.Context, message string) error {
 	return ErrorResponse(c, http.StatusInternalServerError, message)
 }
 
+// UnauthorizedResponse sends a 401 Unauthorized error response
+func UnauthorizedResponse(c echo.Context) error {
+	return ErrorResponse(c, http.StatusUnauthorized, "unauthorized")
+}
+
+// ForbiddenResponse sends a 403 Forbidden error response
+func ForbiddenResponse(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusForbidden, message)
+}
+
 // ConflictResponse sends a 409 Conflict error response
 func ConflictResponse(c echo.Context, message string) error {
 	return ErrorResponse(c, http.StatusConflict, message)
 }
 
+// CurrentUser returns the authenticated user of the request
+// Returns the principal and true, or sends a 401 response and returns false
+func CurrentUser(c echo.Context) (middleware.Principal, bool) {
+	principal, ok := middleware.PrincipalFrom(c)
+	if !ok {
+		UnauthorizedResponse(c)
+		return middleware.Principal{}, false
+	}
+	return principal, true
+}
+
+// requestDevice describes the client of a login request, for the session it opens
+func requestDevice(c echo.Context) models.SessionDevice {
+	return models.SessionDevice{UserAgent: c.Request().UserAgent(), IP: c.RealIP()}
+}
+
+// AccessErrorResponse answers a failed access check consistently across the API:
+// 404 when the resource does not exist or is not visible to the user, 403 when the user
+// can see it but their role does not allow the action, and 500 when the check itself failed
+func AccessErrorResponse(c echo.Context, err error, resource string) error {
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		return ForbiddenResponse(c, "you do not have permission to modify this "+resource)
+	case errors.Is(err, services.ErrNotFound):
+		return NotFoundResponse(c, resource)
+	default:
+		return InternalErrorResponse(c, "failed to check access to "+resource)
+	}
+}
+
 // ValidateUUIDParam validates a URL parameter as a valid UUID
 // Returns the UUID string and true if valid, or sends an error response and returns false
 func ValidateUUIDParam(c echo.Context, paramName string) (string, bool) {
@@ -92,3 +142,49 @@ func RequireField(c echo.Context, fieldName, value string) bool {
 	}
 	return true
 }
+
+// CachedJSONResponse sends body as JSON with ETag and Last-Modified validators.
+// It answers 304 Not Modified when If-None-Match matches the content hash or,
+// absent If-None-Match, when If-Modified-Since is not older than lastModified.
+// The date carries no variant, so responses shaped by query parameters pass a zero lastModified.
+func CachedJSONResponse(c echo.Context, body interface{}, lastModified time.Time) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to encode response")
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "private, no-cache")
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	if !lastModified.IsZero() {
+		header.Set(echo.HeaderLastModified, lastModified.Format(http.TimeFormat))
+	}
+
+	req := c.Request()
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	} else if ims := req.Header.Get(echo.HeaderIfModifiedSince); ims != "" && !lastModified.IsZero() {
+		if since, err := http.ParseTime(ims); err == nil && !lastModified.After(since) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+
+	return c.JSONBlob(http.StatusOK, data)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag (weak comparison)
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
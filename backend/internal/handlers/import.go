@@ -9,7 +9,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
 	"github.com/treechess/backend/config"
@@ -41,57 +43,294 @@ func (h *ImportHandler) UploadHandler(c echo.Context) error {
 		return nil
 	}
 
+	policy, ok := models.ParseDuplicatePolicy(c.FormValue("duplicatePolicy"))
+	if !ok {
+		return BadRequestResponse(c, invalidDuplicatePolicyMessage)
+	}
+	depth, ok := parseAnalysisDepth(c.FormValue("analysisDepth"))
+	if !ok {
+		return BadRequestResponse(c, services.ErrInvalidAnalysisDepth.Error())
+	}
+	matchRepertoireIDs, ok := parseMatchRepertoireIDs(c.FormValue("matchRepertoireIds"))
+	if !ok {
+		return BadRequestResponse(c, invalidMatchRepertoiresMessage)
+	}
+	includeVariants := c.FormValue("includeVariants") == "true"
+
+	filename, pgnData, ok := readPGNUpload(c)
+	if !ok {
+		return nil
+	}
+
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	summary, _, err := h.importService.ParseAndAnalyzeWithOptions(filename, username, user.ID, pgnData,
+		services.ImportOptions{DuplicatePolicy: policy, AnalysisDepth: depth, MatchRepertoireIDs: matchRepertoireIDs, IncludeVariants: includeVariants,
+			Provenance: models.ImportProvenance{
+				Source: models.ImportSourcePGN,
+				SourceParams: models.ImportSourceParams{
+					Username:           username,
+					Filename:           filename,
+					DuplicatePolicy:    policy,
+					AnalysisDepth:      depth,
+					MatchRepertoireIDs: matchRepertoireIDs,
+					IncludeVariants:    includeVariants,
+				},
+			}})
+	if err != nil {
+		if errors.Is(err, services.ErrAllGamesDuplicate) {
+			return ErrorCodeResponse(c, http.StatusConflict, models.ErrCodeDuplicateGame, err.Error())
+		}
+		if errors.Is(err, services.ErrOnlyVariantGames) {
+			return BadRequestResponse(c, err.Error())
+		}
+		log.Printf("PGN parse error for user %s: %v", user.ID, err)
+		return BadRequestResponse(c, "failed to parse PGN file")
+	}
+
+	return importSummaryResponse(c, summary)
+}
+
+// readPGNUpload reads the .pgn file of a multipart upload and returns its name and content.
+// It sends an error response and returns false when the file is missing, misnamed or too large.
+func readPGNUpload(c echo.Context) (string, string, bool) {
 	file, err := c.FormFile("file")
 	if err != nil {
-		return BadRequestResponse(c, "file is required")
+		BadRequestResponse(c, "file is required")
+		return "", "", false
 	}
 
 	if !strings.HasSuffix(strings.ToLower(file.Filename), ".pgn") {
-		return BadRequestResponse(c, "file must have .pgn extension")
+		BadRequestResponse(c, "file must have .pgn extension")
+		return "", "", false
 	}
 
 	if file.Size > config.MaxPGNFileSize {
-		return ErrorResponse(c, http.StatusRequestEntityTooLarge, "file exceeds maximum allowed size")
+		ErrorResponse(c, http.StatusRequestEntityTooLarge, "file exceeds maximum allowed size")
+		return "", "", false
 	}
 
 	src, err := file.Open()
 	if err != nil {
-		return InternalErrorResponse(c, "failed to read file")
+		InternalErrorResponse(c, "failed to read file")
+		return "", "", false
 	}
 	defer src.Close()
 
 	limitedReader := io.LimitReader(src, config.MaxPGNFileSize+1)
 	pgnData, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return InternalErrorResponse(c, "failed to read file content")
+		InternalErrorResponse(c, "failed to read file content")
+		return "", "", false
 	}
 
 	if len(pgnData) > config.MaxPGNFileSize {
-		return ErrorResponse(c, http.StatusRequestEntityTooLarge, "file exceeds maximum allowed size")
+		ErrorResponse(c, http.StatusRequestEntityTooLarge, "file exceeds maximum allowed size")
+		return "", "", false
 	}
+	return file.Filename, string(pgnData), true
+}
 
-	userID := c.Get("userID").(string)
-	summary, _, err := h.importService.ParseAndAnalyze(file.Filename, username, userID, string(pgnData))
+// ImportDatabaseHandler accepts a large PGN database, such as a Chessbase or SCID export, and
+// imports it in the background. The file is streamed to disk instead of being held in memory.
+func (h *ImportHandler) ImportDatabaseHandler(c echo.Context) error {
+	reader, err := c.Request().MultipartReader()
 	if err != nil {
-		if errors.Is(err, services.ErrAllGamesDuplicate) {
-			return ErrorResponse(c, http.StatusConflict, "all games have already been imported")
+		return BadRequestResponse(c, "multipart form data is required")
+	}
+
+	var filename, path, username, policyValue string
+	discard := func() {
+		if path != "" {
+			h.importService.DiscardSpooledPGN(path)
 		}
-		log.Printf("PGN parse error for user %s: %v", userID, err)
-		return BadRequestResponse(c, "failed to parse PGN file")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			discard()
+			return BadRequestResponse(c, "invalid multipart body")
+		}
+
+		switch part.FormName() {
+		case "file":
+			if path != "" {
+				break
+			}
+			filename = part.FileName()
+			if !strings.HasSuffix(strings.ToLower(filename), ".pgn") {
+				part.Close()
+				return BadRequestResponse(c, "file must have .pgn extension")
+			}
+			path, err = h.importService.SpoolPGN(part)
+			if err != nil {
+				part.Close()
+				return ServiceErrorResponse(c, err, "failed to store file")
+			}
+		case "username":
+			value, _ := io.ReadAll(io.LimitReader(part, 256))
+			username = string(value)
+		case "duplicatePolicy":
+			value, _ := io.ReadAll(io.LimitReader(part, 32))
+			policyValue = string(value)
+		}
+		part.Close()
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":                summary.ID,
-		"username":          summary.Username,
-		"filename":          summary.Filename,
-		"gameCount":         summary.GameCount,
-		"skippedDuplicates": summary.SkippedDuplicates,
-	})
+	if !RequireField(c, "username", username) {
+		discard()
+		return nil
+	}
+	policy, ok := models.ParseDuplicatePolicy(policyValue)
+	if !ok {
+		discard()
+		return BadRequestResponse(c, invalidDuplicatePolicyMessage)
+	}
+	if path == "" {
+		return BadRequestResponse(c, "file is required")
+	}
+
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	job, err := h.importService.QueueDatabaseImport(user.ID, username, filename, policy, path)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to queue import")
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJobHandler reports the progress of a database import, including unparseable games
+func (h *ImportHandler) GetImportJobHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	job, err := h.importService.GetImportJob(id, user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get import job")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// ImportStatsHandler returns the user's imports aggregated per source and month
+func (h *ImportHandler) ImportStatsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	stats, err := h.importService.GetImportStats(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to get import stats")
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+const invalidDuplicatePolicyMessage = "duplicatePolicy must be one of: skip, replace, keep-both"
+
+// parseAnalysisDepth parses an optional analysisDepth form value; nil means the user's setting applies
+func parseAnalysisDepth(value string) (*int, bool) {
+	if value == "" {
+		return nil, true
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil || !services.ValidAnalysisDepth(depth) {
+		return nil, false
+	}
+	return &depth, true
+}
+
+const invalidMatchRepertoiresMessage = "matchRepertoireIds must be repertoire UUIDs"
+
+// validMatchRepertoireIDs checks the repertoires an import restricts matching to. IDs of
+// repertoires the user does not own match no game, so only their format is checked.
+func validMatchRepertoireIDs(ids []string) bool {
+	if len(ids) > config.MaxRepertoires {
+		return false
+	}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMatchRepertoireIDs parses an optional comma-separated matchRepertoireIds form value
+func parseMatchRepertoireIDs(value string) ([]string, bool) {
+	if value == "" {
+		return nil, true
+	}
+	ids := strings.Split(value, ",")
+	for i := range ids {
+		ids[i] = strings.TrimSpace(ids[i])
+	}
+	return ids, validMatchRepertoireIDs(ids)
+}
+
+// importSummaryResponse reports an import, including which duplicates were skipped, replaced or kept.
+// Imports that only replaced existing games create no analysis and answer 200 instead of 201.
+func importSummaryResponse(c echo.Context, summary *models.AnalysisSummary) error {
+	replaced := 0
+	for _, d := range summary.Duplicates {
+		if d.Action == "replaced" {
+			replaced++
+		}
+	}
+	duplicates := summary.Duplicates
+	if duplicates == nil {
+		duplicates = []models.DuplicateGame{}
+	}
+	warnings := summary.Warnings
+	if warnings == nil {
+		warnings = []models.ImportWarning{}
+	}
+
+	body := map[string]interface{}{
+		"id":                 summary.ID,
+		"username":           summary.Username,
+		"filename":           summary.Filename,
+		"gameCount":          summary.GameCount,
+		"skippedDuplicates":  summary.SkippedDuplicates,
+		"replacedDuplicates": replaced,
+		"duplicates":         duplicates,
+		"warnings":           warnings,
+		"source":             summary.Source,
+		"sourceParams":       summary.SourceParams,
+	}
+
+	status := http.StatusCreated
+	if summary.ID == "" {
+		status = http.StatusOK
+	}
+	return c.JSON(status, body)
 }
 
 func (h *ImportHandler) ListAnalysesHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
-	analyses, err := h.importService.GetAnalyses(userID)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	source := c.QueryParam("source")
+	if source != "" && !models.ValidImportSource(source) {
+		return BadRequestResponse(c, "source must be pgn, lichess, chesscom or broadcast")
+	}
+	analyses, err := h.importService.GetAnalyses(user.ID, source)
 	if err != nil {
 		return InternalErrorResponse(c, "failed to list analyses")
 	}
@@ -99,11 +338,13 @@ func (h *ImportHandler) ListAnalysesHandler(c echo.Context) error {
 	result := make([]map[string]interface{}, len(analyses))
 	for i, a := range analyses {
 		result[i] = map[string]interface{}{
-			"id":         a.ID,
-			"username":   a.Username,
-			"filename":   a.Filename,
-			"gameCount":  a.GameCount,
-			"uploadedAt": a.UploadedAt.Format("2006-01-02T15:04:05Z07:00"),
+			"id":           a.ID,
+			"username":     a.Username,
+			"filename":     a.Filename,
+			"gameCount":    a.GameCount,
+			"uploadedAt":   a.UploadedAt.Format("2006-01-02T15:04:05Z07:00"),
+			"source":       a.Source,
+			"sourceParams": a.SourceParams,
 		}
 	}
 
@@ -111,22 +352,22 @@ func (h *ImportHandler) ListAnalysesHandler(c echo.Context) error {
 }
 
 func (h *ImportHandler) GetAnalysisHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 	id, ok := ValidateUUIDParam(c, "id")
 	if !ok {
 		return nil
 	}
 
-	if err := h.importService.CheckOwnership(id, userID); err != nil {
-		return NotFoundResponse(c, "analysis")
+	if err := h.importService.CheckOwnership(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
 	}
 
 	detail, err := h.importService.GetAnalysisByID(id)
 	if err != nil {
-		if errors.Is(err, repository.ErrAnalysisNotFound) {
-			return NotFoundResponse(c, "analysis")
-		}
-		return InternalErrorResponse(c, "failed to get analysis")
+		return ServiceErrorResponse(c, err, "failed to get analysis")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -140,27 +381,123 @@ func (h *ImportHandler) GetAnalysisHandler(c echo.Context) error {
 }
 
 func (h *ImportHandler) DeleteAnalysisHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 	id, ok := ValidateUUIDParam(c, "id")
 	if !ok {
 		return nil
 	}
 
-	if err := h.importService.CheckOwnership(id, userID); err != nil {
-		return NotFoundResponse(c, "analysis")
+	if err := h.importService.CheckOwnership(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
 	}
 
 	err := h.importService.DeleteAnalysis(id)
 	if err != nil {
-		if errors.Is(err, repository.ErrAnalysisNotFound) {
-			return NotFoundResponse(c, "analysis")
-		}
-		return InternalErrorResponse(c, "failed to delete analysis")
+		return ServiceErrorResponse(c, err, "failed to delete analysis")
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// RecomputeEvalsHandler discards an analysis' engine evals and queues its games for re-evaluation
+// POST /api/analyses/:id/recompute-evals
+func (h *ImportHandler) RecomputeEvalsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+
+	queued, err := h.importService.RecomputeEvals(user.ID, id)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to recompute evaluations")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"analysisId": id,
+		"queued":     queued,
+	})
+}
+
+// RetryEvalsHandler requeues the engine evals of an analysis that failed after every retry
+// POST /api/analyses/:id/evals/retry
+func (h *ImportHandler) RetryEvalsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+
+	queued, err := h.importService.RetryFailedEvals(id)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to retry evaluations")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"analysisId": id,
+		"queued":     queued,
+	})
+}
+
+// RerunImportHandler runs the import that created an analysis again with the same parameters,
+// creating a new analysis for the games not imported yet. Uploaded files are not kept and cannot be rerun.
+// POST /api/analyses/:id/rerun
+func (h *ImportHandler) RerunImportHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(id, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+	analysis, err := h.importService.GetAnalysisByID(id)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get analysis")
+	}
+
+	params := analysis.SourceParams
+	params.Sync = false
+	if (analysis.Source == models.ImportSourceLichess || analysis.Source == models.ImportSourceChesscom) && params.Username == "" ||
+		analysis.Source == models.ImportSourceBroadcast && params.RoundID == "" ||
+		analysis.Source == models.ImportSourceTeam && (params.Username == "" || params.TeamID == "") {
+		return ConflictResponse(c, "the analysis does not record what was imported")
+	}
+	switch analysis.Source {
+	case models.ImportSourceLichess:
+		return h.importLichess(c, user.ID, params)
+	case models.ImportSourceChesscom:
+		return h.importChesscom(c, user.ID, params)
+	case models.ImportSourceBroadcast:
+		return h.importBroadcast(c, user.ID, params)
+	case models.ImportSourceTeam:
+		return h.importTeamMember(c, user.ID, params)
+	default:
+		return ConflictResponse(c, "uploaded PGN files are not kept and cannot be imported again")
+	}
+}
+
 func (h *ImportHandler) ValidatePGNHandler(c echo.Context) error {
 	limitedReader := io.LimitReader(c.Request().Body, config.MaxPGNFileSize+1)
 	pgnData, err := io.ReadAll(limitedReader)
@@ -200,13 +537,14 @@ func (h *ImportHandler) ValidateMoveHandler(c echo.Context) error {
 		return nil
 	}
 
-	err := h.importService.ValidateMove(req.FEN, req.SAN)
+	san, err := h.importService.NormalizeMove(req.FEN, req.SAN)
 	if err != nil {
 		return BadRequestResponse(c, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"valid": true,
+		"san":   san,
 	})
 }
 
@@ -228,9 +566,12 @@ func (h *ImportHandler) GetLegalMovesHandler(c echo.Context) error {
 }
 
 func (h *ImportHandler) GetDistinctRepertoiresHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
-	repertoires, err := h.importService.GetDistinctRepertoires(userID)
+	repertoires, err := h.importService.GetDistinctRepertoires(user.ID)
 	if err != nil {
 		return InternalErrorResponse(c, "failed to get repertoires")
 	}
@@ -245,14 +586,19 @@ func (h *ImportHandler) GetDistinctRepertoiresHandler(c echo.Context) error {
 }
 
 func (h *ImportHandler) GetGamesHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 	limit := ParseIntQueryParam(c, "limit", config.DefaultGamesLimit, 1, config.MaxGamesLimit)
 	offset := ParseIntQueryParam(c, "offset", 0, 0, 1000000)
 	timeClass := c.QueryParam("timeClass")
 	repertoire := c.QueryParam("repertoire")
 	source := c.QueryParam("source")
+	classification := c.QueryParam("classification")
+	starred := c.QueryParam("starred") == "true"
 
-	response, err := h.importService.GetAllGames(userID, limit, offset, timeClass, repertoire, source)
+	response, err := h.importService.GetAllGames(user.ID, limit, offset, timeClass, repertoire, source, classification, starred)
 	if err != nil {
 		return InternalErrorResponse(c, "failed to get games")
 	}
@@ -260,15 +606,100 @@ func (h *ImportHandler) GetGamesHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// pgnAttachment sends PGN text as a downloadable file
+func pgnAttachment(c echo.Context, filename, pgn string) error {
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "application/x-chess-pgn", []byte(pgn))
+}
+
+// ExportGamePGNHandler downloads a single analyzed game as annotated PGN
+func (h *ImportHandler) ExportGamePGNHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	analysisID, ok := ValidateUUIDParam(c, "analysisId")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+
+	gameIndex, err := strconv.Atoi(c.Param("gameIndex"))
+	if err != nil || gameIndex < 0 {
+		return BadRequestResponse(c, "gameIndex must be a non-negative integer")
+	}
+
+	pgn, err := h.importService.ExportGamePGN(analysisID, gameIndex)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to export game")
+	}
+
+	return pgnAttachment(c, fmt.Sprintf("game-%s-%d.pgn", analysisID, gameIndex), pgn)
+}
+
+// ExportGamesPGNHandler downloads every game matching the games list filters as annotated PGN
+func (h *ImportHandler) ExportGamesPGNHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	timeClass := c.QueryParam("timeClass")
+	repertoire := c.QueryParam("repertoire")
+	source := c.QueryParam("source")
+	classification := c.QueryParam("classification")
+	starred := c.QueryParam("starred") == "true"
+
+	pgn, count, err := h.importService.ExportGamesPGN(user.ID, timeClass, repertoire, source, classification, starred)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to export games")
+	}
+
+	c.Response().Header().Set("X-Game-Count", strconv.Itoa(count))
+	return pgnAttachment(c, "games.pgn", pgn)
+}
+
+// csvAttachment sends CSV data as a downloadable file
+func csvAttachment(c echo.Context, filename string, data []byte) error {
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// ExportGamesCSVHandler downloads the games matching the games list filters as a spreadsheet-friendly CSV
+func (h *ImportHandler) ExportGamesCSVHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	timeClass := c.QueryParam("timeClass")
+	repertoire := c.QueryParam("repertoire")
+	source := c.QueryParam("source")
+	classification := c.QueryParam("classification")
+	starred := c.QueryParam("starred") == "true"
+
+	data, count, err := h.importService.ExportGamesCSV(user.ID, timeClass, repertoire, source, classification, starred)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to export games")
+	}
+
+	c.Response().Header().Set("X-Game-Count", strconv.Itoa(count))
+	return csvAttachment(c, "games.csv", data)
+}
+
 func (h *ImportHandler) DeleteGameHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 	analysisID, ok := ValidateUUIDParam(c, "analysisId")
 	if !ok {
 		return nil
 	}
 
-	if err := h.importService.CheckOwnership(analysisID, userID); err != nil {
-		return NotFoundResponse(c, "analysis")
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
 	}
 
 	gameIndexStr := c.Param("gameIndex")
@@ -279,20 +710,50 @@ func (h *ImportHandler) DeleteGameHandler(c echo.Context) error {
 
 	err = h.importService.DeleteGame(analysisID, gameIndex)
 	if err != nil {
-		if errors.Is(err, repository.ErrAnalysisNotFound) {
-			return NotFoundResponse(c, "analysis")
-		}
-		if errors.Is(err, repository.ErrGameNotFound) {
-			return NotFoundResponse(c, "game")
-		}
-		return InternalErrorResponse(c, "failed to delete game")
+		return ServiceErrorResponse(c, err, "failed to delete game")
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// UpdateGameHandler edits the user's note and starred flag on a game
+func (h *ImportHandler) UpdateGameHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	analysisID, ok := ValidateUUIDParam(c, "analysisId")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+
+	gameIndex, err := strconv.Atoi(c.Param("gameIndex"))
+	if err != nil || gameIndex < 0 {
+		return BadRequestResponse(c, "gameIndex must be a non-negative integer")
+	}
+
+	var req models.UpdateGameRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	notes, err := h.importService.UpdateGameNotes(analysisID, gameIndex, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to update game")
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
+
 func (h *ImportHandler) BulkDeleteGamesHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
 	var req struct {
 		Games []struct {
@@ -313,7 +774,7 @@ func (h *ImportHandler) BulkDeleteGamesHandler(c echo.Context) error {
 
 	deleted := 0
 	for _, g := range req.Games {
-		if err := h.importService.CheckOwnership(g.AnalysisID, userID); err != nil {
+		if err := h.importService.CheckOwnership(g.AnalysisID, user.ID); err != nil {
 			continue
 		}
 		if err := h.importService.DeleteGame(g.AnalysisID, g.GameIndex); err != nil {
@@ -328,14 +789,17 @@ func (h *ImportHandler) BulkDeleteGamesHandler(c echo.Context) error {
 }
 
 func (h *ImportHandler) ReanalyzeGameHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 	analysisID, ok := ValidateUUIDParam(c, "analysisId")
 	if !ok {
 		return nil
 	}
 
-	if err := h.importService.CheckOwnership(analysisID, userID); err != nil {
-		return NotFoundResponse(c, "analysis")
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
 	}
 
 	gameIndexStr := c.Param("gameIndex")
@@ -358,54 +822,282 @@ func (h *ImportHandler) ReanalyzeGameHandler(c echo.Context) error {
 		return nil
 	}
 
-	reanalyzed, err := h.importService.ReanalyzeGame(analysisID, gameIndex, req.RepertoireID)
+	reanalyzed, err := h.importService.ReanalyzeGame(user.ID, analysisID, gameIndex, req.RepertoireID)
 	if err != nil {
-		if errors.Is(err, repository.ErrAnalysisNotFound) {
-			return NotFoundResponse(c, "analysis")
-		}
-		if errors.Is(err, repository.ErrGameNotFound) {
-			return NotFoundResponse(c, "game")
-		}
 		if errors.Is(err, services.ErrRepertoireNotFound) {
 			return NotFoundResponse(c, "repertoire")
 		}
-		if errors.Is(err, services.ErrColorMismatch) {
-			return BadRequestResponse(c, err.Error())
-		}
-		return InternalErrorResponse(c, "failed to reanalyze game")
+		return ServiceErrorResponse(c, err, "failed to reanalyze game")
 	}
 
 	return c.JSON(http.StatusOK, reanalyzed)
 }
 
-func (h *ImportHandler) MarkGameViewedHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+// GameRepertoireSnapshotHandler returns a game with the repertoire tree it was analyzed against
+// GET /api/games/:analysisId/:gameIndex/repertoire-snapshot
+func (h *ImportHandler) GameRepertoireSnapshotHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 	analysisID, ok := ValidateUUIDParam(c, "analysisId")
 	if !ok {
 		return nil
 	}
 
-	if err := h.importService.CheckOwnership(analysisID, userID); err != nil {
-		return NotFoundResponse(c, "analysis")
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
 	}
 
-	gameIndexStr := c.Param("gameIndex")
-	gameIndex, err := strconv.Atoi(gameIndexStr)
+	gameIndex, err := strconv.Atoi(c.Param("gameIndex"))
 	if err != nil || gameIndex < 0 {
 		return BadRequestResponse(c, "gameIndex must be a non-negative integer")
 	}
 
-	if err := h.importService.MarkGameViewed(userID, analysisID, gameIndex); err != nil {
-		return InternalErrorResponse(c, "failed to mark game as viewed")
+	snapshot, err := h.importService.GetGameRepertoireSnapshot(analysisID, gameIndex)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get repertoire snapshot")
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, snapshot)
 }
 
-func (h *ImportHandler) GetInsightsHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+// AdoptGameLineHandler grafts the first plies of a game into a repertoire
+// POST /api/games/:analysisId/:gameIndex/adopt?toPly=N&repertoireId=...
+func (h *ImportHandler) AdoptGameLineHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	analysisID, ok := ValidateUUIDParam(c, "analysisId")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+
+	gameIndex, ok := ParseIntParam(c, "gameIndex", 0)
+	if !ok {
+		return nil
+	}
+	toPly, err := strconv.Atoi(c.QueryParam("toPly"))
+	if err != nil || toPly < 1 {
+		return BadRequestResponse(c, "toPly must be a positive integer")
+	}
+	repertoireID := c.QueryParam("repertoireId")
+	if !RequireField(c, "repertoireId", repertoireID) {
+		return nil
+	}
+	if !ValidateUUIDField(c, "repertoireId", repertoireID) {
+		return nil
+	}
+
+	adopted, err := h.importService.AdoptGameLine(user.ID, analysisID, gameIndex, toPly, repertoireID)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+		if errors.Is(err, services.ErrNotFound) {
+			return NotFoundResponse(c, "repertoire")
+		}
+		return ServiceErrorResponse(c, err, "failed to adopt game line")
+	}
+
+	return c.JSON(http.StatusOK, adopted)
+}
+
+// ReanalyzeRepertoireGamesHandler queues a background re-analysis of every game matched to a repertoire
+func (h *ImportHandler) ReanalyzeRepertoireGamesHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	repertoireID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	job, err := h.importService.EnqueueRepertoireReanalysis(user.ID, repertoireID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, services.ErrForbidden) {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+		return InternalErrorResponse(c, "failed to queue reanalysis")
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GetReanalysisJobHandler reports the progress of a repertoire re-analysis job
+func (h *ImportHandler) GetReanalysisJobHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	job, err := h.importService.GetReanalysisJob(id, user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get reanalysis job")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// RecomputeHandler queues a job recomputing stored analyses from the raw game archive, for one
+// user or, without userId, for every user
+// POST /api/admin/recompute
+func (h *ImportHandler) RecomputeHandler(c echo.Context) error {
+	var req models.RecomputeRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if req.UserID != "" && !ValidateUUIDField(c, "userId", req.UserID) {
+		return nil
+	}
+
+	job, err := h.importService.QueueRecompute(req.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return NotFoundResponse(c, "user")
+	}
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to queue recompute")
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GetRecomputeJobHandler reports the progress of a recompute job
+// GET /api/admin/recompute/:id
+func (h *ImportHandler) GetRecomputeJobHandler(c echo.Context) error {
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	job, err := h.importService.GetRecomputeJob(id)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get recompute job")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// ResultsOverlayHandler returns the user's score per repertoire node across their imported games
+func (h *ImportHandler) ResultsOverlayHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	repertoireID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	overlay, err := h.importService.ResultsOverlay(user.ID, repertoireID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return NotFoundResponse(c, "repertoire")
+		}
+		return InternalErrorResponse(c, "failed to get results overlay")
+	}
+
+	return c.JSON(http.StatusOK, overlay)
+}
+
+// OpponentRepliesHandler returns what the user's opponents played in a position across their imported games
+// GET /api/positions/opponent-replies?fen=...&minRating=1600&maxRating=2000
+func (h *ImportHandler) OpponentRepliesHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	fen := c.QueryParam("fen")
+	if fen == "" {
+		return BadRequestResponse(c, "fen parameter is required")
+	}
+	minRating := ParseIntQueryParam(c, "minRating", 0, 0, config.MaxOpponentRating)
+	maxRating := ParseIntQueryParam(c, "maxRating", 0, 0, config.MaxOpponentRating)
+
+	replies, err := h.importService.OpponentReplies(user.ID, fen, minRating, maxRating)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get opponent replies")
+	}
+
+	return c.JSON(http.StatusOK, replies)
+}
+
+// BookDepthHandler returns where the user's games in a repertoire leave the book and their opening move times
+func (h *ImportHandler) BookDepthHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	repertoireID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	depth, err := h.importService.BookDepth(user.ID, repertoireID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return NotFoundResponse(c, "repertoire")
+		}
+		return InternalErrorResponse(c, "failed to get book depth")
+	}
+
+	return c.JSON(http.StatusOK, depth)
+}
+
+func (h *ImportHandler) MarkGameViewedHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	analysisID, ok := ValidateUUIDParam(c, "analysisId")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.CheckOwnership(analysisID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "analysis")
+	}
+
+	gameIndexStr := c.Param("gameIndex")
+	gameIndex, err := strconv.Atoi(gameIndexStr)
+	if err != nil || gameIndex < 0 {
+		return BadRequestResponse(c, "gameIndex must be a non-negative integer")
+	}
+
+	if err := h.importService.MarkGameViewed(user.ID, analysisID, gameIndex); err != nil {
+		return InternalErrorResponse(c, "failed to mark game as viewed")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *ImportHandler) GetInsightsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	base, err := h.importService.InsightsFilterFor(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to get insight settings")
+	}
+	filter, err := parseInsightsFilter(c, base)
+	if err != nil {
+		return BadRequestResponse(c, err.Error())
+	}
 
-	insights, err := h.importService.GetInsights(userID)
+	insights, err := h.importService.GetInsights(user.ID, filter)
 	if err != nil {
 		return InternalErrorResponse(c, "failed to get insights")
 	}
@@ -413,6 +1105,220 @@ func (h *ImportHandler) GetInsightsHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, insights)
 }
 
+// ExportMistakesCSVHandler downloads the insight mistakes, with the same filters as the insights, as CSV.
+// Without a limit every mistake up to config.MaxInsightsLimit is exported.
+func (h *ImportHandler) ExportMistakesCSVHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	base, err := h.importService.InsightsFilterFor(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to get insight settings")
+	}
+	base.Limit = config.MaxInsightsLimit
+	filter, err := parseInsightsFilter(c, base)
+	if err != nil {
+		return BadRequestResponse(c, err.Error())
+	}
+
+	data, err := h.importService.ExportMistakesCSV(user.ID, filter)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to export mistakes")
+	}
+
+	return csvAttachment(c, "mistakes.csv", data)
+}
+
+// parseInsightsFilter narrows base, built from the user's insight settings, with the query filters:
+// limit, repertoireId, timeClass, minDrop and since
+func parseInsightsFilter(c echo.Context, base models.InsightsFilter) (models.InsightsFilter, error) {
+	filter := base
+	filter.Limit = ParseIntQueryParam(c, "limit", base.Limit, 1, config.MaxInsightsLimit)
+	filter.RepertoireID = c.QueryParam("repertoireId")
+
+	if timeClass := c.QueryParam("timeClass"); timeClass != "" {
+		if !validTimeFormats[timeClass] {
+			return filter, errors.New("timeClass must be one of: " + strings.Join(models.TimeClasses, ", "))
+		}
+		filter.TimeClass = timeClass
+	}
+
+	if minDropStr := c.QueryParam("minDrop"); minDropStr != "" {
+		minDrop, err := strconv.ParseFloat(minDropStr, 64)
+		if err != nil || minDrop < 0 || minDrop > 1 {
+			return filter, errors.New("minDrop must be a number between 0 and 1")
+		}
+		filter.MinDrop = minDrop
+	}
+
+	if sinceStr := c.QueryParam("since"); sinceStr != "" {
+		since, err := parseSinceParam(sinceStr, filter.Location)
+		if err != nil {
+			return filter, errors.New("since must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		filter.Since = since
+	}
+
+	return filter, nil
+}
+
+// GetInsightSettingsHandler returns the user's mistake thresholds, the defaults when never saved
+// GET /api/settings/insights
+func (h *ImportHandler) GetInsightSettingsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	settings, err := h.importService.GetInsightSettings(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to get insight settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateInsightSettingsHandler replaces the user's mistake thresholds
+// PUT /api/settings/insights
+func (h *ImportHandler) UpdateInsightSettingsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.InsightSettings
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	settings, err := h.importService.UpdateInsightSettings(user.ID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to update insight settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// ListClassificationRulesHandler returns the user's classification rules
+// GET /api/settings/classification-rules
+func (h *ImportHandler) ListClassificationRulesHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	rules, err := h.importService.ListClassificationRules(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list classification rules")
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// CreateClassificationRuleHandler adds a classification rule, applied to the games analyzed from then on
+// POST /api/settings/classification-rules
+func (h *ImportHandler) CreateClassificationRuleHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.ClassificationRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	rule, err := h.importService.CreateClassificationRule(user.ID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to create classification rule")
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateClassificationRuleHandler replaces the key, name and conditions of a classification rule
+// PUT /api/settings/classification-rules/:id
+func (h *ImportHandler) UpdateClassificationRuleHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	var req models.ClassificationRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	rule, err := h.importService.UpdateClassificationRule(user.ID, id, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to update classification rule")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// DeleteClassificationRuleHandler deletes a classification rule; analyzed games keep its key until re-analyzed
+// DELETE /api/settings/classification-rules/:id
+func (h *ImportHandler) DeleteClassificationRuleHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.importService.DeleteClassificationRule(user.ID, id); err != nil {
+		return ServiceErrorResponse(c, err, "failed to delete classification rule")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ExplainMistakeHandler gathers the repertoire, Explorer and master game context of an insight mistake.
+// While the Explorer stats are being fetched it answers 202 with a pending status; clients poll again.
+// GET /api/insights/explain?fen=...&played=Nf3
+func (h *ImportHandler) ExplainMistakeHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	fen := c.QueryParam("fen")
+	played := c.QueryParam("played")
+	if fen == "" || played == "" {
+		return BadRequestResponse(c, "fen and played are required")
+	}
+
+	explanation, err := h.importService.ExplainMistake(user.ID, fen, played)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to explain mistake")
+	}
+
+	if explanation.ExplorerStatus == models.ExplorerPending {
+		return c.JSON(http.StatusAccepted, explanation)
+	}
+	return c.JSON(http.StatusOK, explanation)
+}
+
+// parseSinceParam accepts either a full RFC 3339 timestamp or a plain date, which starts at
+// midnight in loc, UTC when nil
+func parseSinceParam(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
 // DismissMistakeRequest is the request body for dismissing a mistake
 type DismissMistakeRequest struct {
 	FEN        string `json:"fen"`
@@ -420,7 +1326,10 @@ type DismissMistakeRequest struct {
 }
 
 func (h *ImportHandler) DismissMistakeHandler(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
 	var req DismissMistakeRequest
 	if err := c.Bind(&req); err != nil {
@@ -431,13 +1340,51 @@ func (h *ImportHandler) DismissMistakeHandler(c echo.Context) error {
 		return BadRequestResponse(c, "fen and playedMove are required")
 	}
 
-	if err := h.importService.DismissMistake(userID, req.FEN, req.PlayedMove); err != nil {
+	if err := h.importService.DismissMistake(user.ID, req.FEN, req.PlayedMove); err != nil {
 		return InternalErrorResponse(c, "failed to dismiss mistake")
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *ImportHandler) ListDismissedMistakesHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	mistakes, err := h.importService.ListDismissedMistakes(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list dismissed mistakes")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"mistakes": mistakes,
+	})
+}
+
+func (h *ImportHandler) RestoreMistakeHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	fen := c.QueryParam("fen")
+	playedMove := c.QueryParam("playedMove")
+
+	if fen == "" || playedMove == "" {
+		return BadRequestResponse(c, "fen and playedMove are required")
+	}
+
+	if err := h.importService.RestoreMistake(user.ID, fen, playedMove); err != nil {
+		if errors.Is(err, repository.ErrDismissedMistakeNotFound) {
+			return NotFoundResponse(c, "dismissed mistake")
+		}
+		return InternalErrorResponse(c, "failed to restore mistake")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 func (h *ImportHandler) LichessImportHandler(c echo.Context) error {
 	var req models.LichessImportRequest
 	if err := c.Bind(&req); err != nil {
@@ -450,43 +1397,59 @@ func (h *ImportHandler) LichessImportHandler(c echo.Context) error {
 	if !validChessUsername.MatchString(req.Username) {
 		return BadRequestResponse(c, "invalid username format")
 	}
-
-	pgnData, err := h.lichessService.FetchGames(req.Username, req.Options)
-	if err != nil {
-		if errors.Is(err, services.ErrLichessUserNotFound) {
-			return NotFoundResponse(c, "Lichess user")
-		}
-		if errors.Is(err, services.ErrLichessRateLimited) {
-			return ErrorResponse(c, http.StatusTooManyRequests, "Lichess rate limit exceeded, try again later")
-		}
-		log.Printf("Lichess fetch error for %s: %v", req.Username, err)
-		return BadRequestResponse(c, "failed to fetch games from Lichess")
+	policy, ok := models.ParseDuplicatePolicy(req.DuplicatePolicy)
+	if !ok {
+		return BadRequestResponse(c, invalidDuplicatePolicyMessage)
+	}
+	if req.AnalysisDepth != nil && !services.ValidAnalysisDepth(*req.AnalysisDepth) {
+		return BadRequestResponse(c, services.ErrInvalidAnalysisDepth.Error())
+	}
+	if !validMatchRepertoireIDs(req.MatchRepertoireIDs) {
+		return BadRequestResponse(c, invalidMatchRepertoiresMessage)
 	}
 
-	if len(pgnData) > config.MaxPGNFileSize {
-		return ErrorResponse(c, http.StatusRequestEntityTooLarge, "PGN exceeds maximum allowed size")
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
 	}
+	return h.importLichess(c, user.ID, models.ImportSourceParams{
+		Username:           req.Username,
+		DuplicatePolicy:    policy,
+		AnalysisDepth:      req.AnalysisDepth,
+		Lichess:            &req.Options,
+		MatchRepertoireIDs: req.MatchRepertoireIDs,
+		IncludeVariants:    req.IncludeVariants,
+	})
+}
 
-	filename := fmt.Sprintf("lichess_%s.pgn", req.Username)
+// importLichess fetches and imports the games of a Lichess account as described by params
+func (h *ImportHandler) importLichess(c echo.Context, userID string, params models.ImportSourceParams) error {
+	var opts models.LichessImportOptions
+	if params.Lichess != nil {
+		opts = *params.Lichess
+	}
+	pgnData, ok := h.fetchLichessPGN(c, userID, params.Username, opts)
+	if !ok {
+		return nil
+	}
 
-	userID := c.Get("userID").(string)
-	summary, _, err := h.importService.ParseAndAnalyze(filename, req.Username, userID, pgnData)
+	filename := fmt.Sprintf("lichess_%s.pgn", params.Username)
+	summary, _, err := h.importService.ParseAndAnalyzeWithOptions(filename, params.Username, userID, pgnData,
+		services.ImportOptions{DuplicatePolicy: params.DuplicatePolicy, AnalysisDepth: params.AnalysisDepth,
+			MatchRepertoireIDs: params.MatchRepertoireIDs, IncludeVariants: params.IncludeVariants,
+			Provenance: models.ImportProvenance{Source: models.ImportSourceLichess, SourceParams: params}})
 	if err != nil {
 		if errors.Is(err, services.ErrAllGamesDuplicate) {
-			return ErrorResponse(c, http.StatusConflict, "all games have already been imported")
+			return ErrorCodeResponse(c, http.StatusConflict, models.ErrCodeDuplicateGame, err.Error())
+		}
+		if errors.Is(err, services.ErrOnlyVariantGames) {
+			return BadRequestResponse(c, err.Error())
 		}
 		log.Printf("Lichess import parse error for user %s: %v", userID, err)
 		return BadRequestResponse(c, "failed to parse imported games")
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":                summary.ID,
-		"username":          summary.Username,
-		"filename":          summary.Filename,
-		"gameCount":         summary.GameCount,
-		"skippedDuplicates": summary.SkippedDuplicates,
-		"source":            "lichess",
-	})
+	return importSummaryResponse(c, summary)
 }
 
 func (h *ImportHandler) ChesscomImportHandler(c echo.Context) error {
@@ -501,41 +1464,316 @@ func (h *ImportHandler) ChesscomImportHandler(c echo.Context) error {
 	if !validChessUsername.MatchString(req.Username) {
 		return BadRequestResponse(c, "invalid username format")
 	}
+	policy, ok := models.ParseDuplicatePolicy(req.DuplicatePolicy)
+	if !ok {
+		return BadRequestResponse(c, invalidDuplicatePolicyMessage)
+	}
+	if req.AnalysisDepth != nil && !services.ValidAnalysisDepth(*req.AnalysisDepth) {
+		return BadRequestResponse(c, services.ErrInvalidAnalysisDepth.Error())
+	}
+	if !validMatchRepertoireIDs(req.MatchRepertoireIDs) {
+		return BadRequestResponse(c, invalidMatchRepertoiresMessage)
+	}
+
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	return h.importChesscom(c, user.ID, models.ImportSourceParams{
+		Username:           req.Username,
+		DuplicatePolicy:    policy,
+		AnalysisDepth:      req.AnalysisDepth,
+		Chesscom:           &req.Options,
+		MatchRepertoireIDs: req.MatchRepertoireIDs,
+		IncludeVariants:    req.IncludeVariants,
+	})
+}
+
+// importChesscom fetches and imports the games of a Chess.com account as described by params
+func (h *ImportHandler) importChesscom(c echo.Context, userID string, params models.ImportSourceParams) error {
+	var opts models.ChesscomImportOptions
+	if params.Chesscom != nil {
+		opts = *params.Chesscom
+	}
+	pgnData, ok := h.fetchChesscomPGN(c, userID, params.Username, opts)
+	if !ok {
+		return nil
+	}
 
-	pgnData, err := h.chesscomService.FetchGames(req.Username, req.Options)
+	filename := fmt.Sprintf("chesscom_%s.pgn", params.Username)
+	summary, _, err := h.importService.ParseAndAnalyzeWithOptions(filename, params.Username, userID, pgnData,
+		services.ImportOptions{DuplicatePolicy: params.DuplicatePolicy, AnalysisDepth: params.AnalysisDepth,
+			MatchRepertoireIDs: params.MatchRepertoireIDs, IncludeVariants: params.IncludeVariants,
+			Provenance: models.ImportProvenance{Source: models.ImportSourceChesscom, SourceParams: params}})
 	if err != nil {
-		if errors.Is(err, services.ErrChesscomUserNotFound) {
-			return NotFoundResponse(c, "Chess.com user")
+		if errors.Is(err, services.ErrAllGamesDuplicate) {
+			return ErrorCodeResponse(c, http.StatusConflict, models.ErrCodeDuplicateGame, err.Error())
 		}
-		if errors.Is(err, services.ErrChesscomRateLimited) {
-			return ErrorResponse(c, http.StatusTooManyRequests, "Chess.com rate limit exceeded, try again later")
+		if errors.Is(err, services.ErrOnlyVariantGames) {
+			return BadRequestResponse(c, err.Error())
 		}
-		log.Printf("Chess.com fetch error for %s: %v", req.Username, err)
-		return BadRequestResponse(c, "failed to fetch games from Chess.com")
+		log.Printf("Chess.com import parse error for user %s: %v", userID, err)
+		return BadRequestResponse(c, "failed to parse imported games")
 	}
 
-	if len(pgnData) > config.MaxPGNFileSize {
-		return ErrorResponse(c, http.StatusRequestEntityTooLarge, "PGN exceeds maximum allowed size")
+	return importSummaryResponse(c, summary)
+}
+
+// LichessBroadcastImportHandler imports the games of a Lichess broadcast round for reference.
+// The games are matched against the repertoires of both colors and never count as duplicates of the user's own.
+func (h *ImportHandler) LichessBroadcastImportHandler(c echo.Context) error {
+	var req models.LichessBroadcastImportRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	if !RequireField(c, "url", req.URL) {
+		return nil
+	}
+	roundID, err := services.ParseBroadcastRoundURL(req.URL)
+	if err != nil {
+		return BadRequestResponse(c, "invalid Lichess broadcast round URL")
+	}
+	if req.AnalysisDepth != nil && !services.ValidAnalysisDepth(*req.AnalysisDepth) {
+		return BadRequestResponse(c, services.ErrInvalidAnalysisDepth.Error())
 	}
 
-	filename := fmt.Sprintf("chesscom_%s.pgn", req.Username)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	return h.importBroadcast(c, user.ID, models.ImportSourceParams{RoundID: roundID, AnalysisDepth: req.AnalysisDepth})
+}
 
-	userID := c.Get("userID").(string)
-	summary, _, err := h.importService.ParseAndAnalyze(filename, req.Username, userID, pgnData)
+// importBroadcast fetches and imports the games of a Lichess broadcast round as described by params
+func (h *ImportHandler) importBroadcast(c echo.Context, userID string, params models.ImportSourceParams) error {
+	roundID := params.RoundID
+	pgnData, err := h.lichessService.FetchBroadcastRoundPGN(roundID)
 	if err != nil {
-		if errors.Is(err, services.ErrAllGamesDuplicate) {
-			return ErrorResponse(c, http.StatusConflict, "all games have already been imported")
+		switch {
+		case errors.Is(err, services.ErrLichessBroadcastNotFound):
+			return NotFoundResponse(c, "Lichess broadcast round")
+		case errors.Is(err, services.ErrLichessRateLimited):
+			return ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Lichess rate limit exceeded, try again later")
+		default:
+			log.Printf("Lichess broadcast fetch error for round %s: %v", roundID, err)
+			return BadRequestResponse(c, "failed to fetch broadcast round from Lichess")
 		}
-		log.Printf("Chess.com import parse error for user %s: %v", userID, err)
+	}
+	if len(pgnData) > config.MaxPGNFileSize {
+		return ErrorCodeResponse(c, http.StatusRequestEntityTooLarge, models.ErrCodeImportTooLarge, "PGN exceeds maximum allowed size")
+	}
+
+	// No player of the round is the user; the analysis is listed under the broadcast
+	summary, _, err := h.importService.ParseAndAnalyzeWithOptions(services.BroadcastFilename(roundID), "broadcast", userID, pgnData,
+		services.ImportOptions{AnalysisDepth: params.AnalysisDepth, Reference: true,
+			Provenance: models.ImportProvenance{Source: models.ImportSourceBroadcast, SourceParams: params}})
+	if err != nil {
+		log.Printf("Lichess broadcast import parse error for user %s: %v", userID, err)
 		return BadRequestResponse(c, "failed to parse imported games")
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"id":                summary.ID,
-		"username":          summary.Username,
-		"filename":          summary.Filename,
-		"gameCount":         summary.GameCount,
-		"skippedDuplicates": summary.SkippedDuplicates,
-		"source":            "chesscom",
-	})
+	return importSummaryResponse(c, summary)
+}
+
+// LichessTeamImportHandler queues a background import of the recent games of every member of a Lichess team.
+// Each member's games become a reference analysis; the returned team import lists them as they complete.
+func (h *ImportHandler) LichessTeamImportHandler(c echo.Context) error {
+	var req models.LichessTeamImportRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	if !RequireField(c, "team", req.Team) {
+		return nil
+	}
+	teamID, err := services.ParseLichessTeamID(req.Team)
+	if err != nil {
+		return BadRequestResponse(c, "invalid Lichess team URL")
+	}
+	if req.Options.Since > 0 && req.Options.Until > 0 && req.Options.Until < req.Options.Since {
+		return BadRequestResponse(c, "options.until must not be before options.since")
+	}
+
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	teamImport, err := h.importService.QueueTeamImport(user.ID, teamID, req.Options, req.AnalysisDepth)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to queue team import")
+	}
+
+	return c.JSON(http.StatusAccepted, teamImport)
+}
+
+// GetTeamImportHandler returns the progress of a team import
+func (h *ImportHandler) GetTeamImportHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	teamImport, err := h.importService.GetTeamImport(id, user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get team import")
+	}
+
+	return c.JSON(http.StatusOK, teamImport)
+}
+
+// ImportSummaryHandler returns what an import run found, including the games it dropped
+// or could not match to a repertoire. The ID is the one of the analysis the run created.
+func (h *ImportHandler) ImportSummaryHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	summary, err := h.importService.GetImportSummary(id, user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get import summary")
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+// importTeamMember fetches and imports the games of one team member as described by params
+func (h *ImportHandler) importTeamMember(c echo.Context, userID string, params models.ImportSourceParams) error {
+	var opts models.LichessImportOptions
+	if params.Lichess != nil {
+		opts = *params.Lichess
+	}
+	pgnData, ok := h.fetchLichessPGN(c, userID, params.Username, opts)
+	if !ok {
+		return nil
+	}
+
+	summary, err := h.importService.ImportTeamMemberGames(userID, params, pgnData)
+	if err != nil {
+		log.Printf("Lichess team member import parse error for user %s: %v", userID, err)
+		return BadRequestResponse(c, "failed to parse imported games")
+	}
+
+	return importSummaryResponse(c, summary)
+}
+
+// fetchLichessPGN fetches the games of a Lichess account, charged to the user's daily quota.
+// It sends an error response and returns false when they cannot be fetched or are too large.
+func (h *ImportHandler) fetchLichessPGN(c echo.Context, userID, username string, opts models.LichessImportOptions) (string, bool) {
+	if err := h.importService.ChargeUsage(userID, models.UsageLichessExport); err != nil {
+		ServiceErrorResponse(c, err, "failed to fetch games from Lichess")
+		return "", false
+	}
+	pgnData, err := h.lichessService.FetchGames(username, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLichessUserNotFound):
+			NotFoundResponse(c, "Lichess user")
+		case errors.Is(err, services.ErrLichessRateLimited):
+			ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Lichess rate limit exceeded, try again later")
+		default:
+			log.Printf("Lichess fetch error for %s: %v", username, err)
+			BadRequestResponse(c, "failed to fetch games from Lichess")
+		}
+		return "", false
+	}
+
+	if len(pgnData) > config.MaxPGNFileSize {
+		ErrorCodeResponse(c, http.StatusRequestEntityTooLarge, models.ErrCodeImportTooLarge, "PGN exceeds maximum allowed size")
+		return "", false
+	}
+	return pgnData, true
+}
+
+// fetchChesscomPGN fetches the games of a Chess.com account, charged to the user's daily quota.
+// It sends an error response and returns false when they cannot be fetched or are too large.
+func (h *ImportHandler) fetchChesscomPGN(c echo.Context, userID, username string, opts models.ChesscomImportOptions) (string, bool) {
+	if err := h.importService.ChargeUsage(userID, models.UsageChesscom); err != nil {
+		ServiceErrorResponse(c, err, "failed to fetch games from Chess.com")
+		return "", false
+	}
+	pgnData, err := h.chesscomService.FetchGames(username, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrChesscomUserNotFound):
+			NotFoundResponse(c, "Chess.com user")
+		case errors.Is(err, services.ErrChesscomRateLimited):
+			ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Chess.com rate limit exceeded, try again later")
+		default:
+			log.Printf("Chess.com fetch error for %s: %v", username, err)
+			BadRequestResponse(c, "failed to fetch games from Chess.com")
+		}
+		return "", false
+	}
+
+	if len(pgnData) > config.MaxPGNFileSize {
+		ErrorCodeResponse(c, http.StatusRequestEntityTooLarge, models.ErrCodeImportTooLarge, "PGN exceeds maximum allowed size")
+		return "", false
+	}
+	return pgnData, true
+}
+
+// ImportPreviewHandler reports what an import would do without saving anything, so users can
+// confirm a large import first. It takes the multipart upload of UploadHandler, or a JSON body
+// naming a Lichess or Chess.com account whose games are fetched.
+// POST /api/imports/preview
+func (h *ImportHandler) ImportPreviewHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var username, pgnData string
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		username = c.FormValue("username")
+		if !RequireField(c, "username", username) {
+			return nil
+		}
+		var ok bool
+		if _, pgnData, ok = readPGNUpload(c); !ok {
+			return nil
+		}
+	} else {
+		var req models.ImportPreviewRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+		if !RequireField(c, "username", req.Username) {
+			return nil
+		}
+		if !validChessUsername.MatchString(req.Username) {
+			return BadRequestResponse(c, "invalid username format")
+		}
+		username = req.Username
+
+		var ok bool
+		switch req.Source {
+		case "lichess":
+			pgnData, ok = h.fetchLichessPGN(c, user.ID, req.Username, req.LichessOptions)
+		case "chesscom":
+			pgnData, ok = h.fetchChesscomPGN(c, user.ID, req.Username, req.ChesscomOptions)
+		default:
+			return BadRequestResponse(c, "source must be lichess or chesscom, or upload a PGN file")
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	preview, err := h.importService.PreviewImport(user.ID, username, pgnData)
+	if err != nil {
+		log.Printf("Import preview error for user %s: %v", user.ID, err)
+		return BadRequestResponse(c, "failed to parse PGN")
+	}
+
+	return c.JSON(http.StatusOK, preview)
 }
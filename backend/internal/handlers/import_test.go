@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -237,7 +239,7 @@ func TestListAnalysesHandler_Empty(t *testing.T) {
 	setTestUserID(c)
 
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
-		GetAllFunc: func(userID string) ([]models.AnalysisSummary, error) {
+		GetAllFunc: func(userID, source string) ([]models.AnalysisSummary, error) {
 			return []models.AnalysisSummary{}, nil
 		},
 	}
@@ -264,7 +266,7 @@ func TestListAnalysesHandler_WithResults(t *testing.T) {
 	setTestUserID(c)
 
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
-		GetAllFunc: func(userID string) ([]models.AnalysisSummary, error) {
+		GetAllFunc: func(userID, source string) ([]models.AnalysisSummary, error) {
 			return []models.AnalysisSummary{
 				{ID: "uuid-1", Username: "player1", Filename: "game1.pgn", GameCount: 5, UploadedAt: time.Now()},
 				{ID: "uuid-2", Username: "player2", Filename: "game2.pgn", GameCount: 10, UploadedAt: time.Now()},
@@ -286,6 +288,73 @@ func TestListAnalysesHandler_WithResults(t *testing.T) {
 	assert.Equal(t, "player1", response[0]["username"])
 }
 
+func TestListAnalysesHandler_SourceFilter(t *testing.T) {
+	var gotSource string
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		GetAllFunc: func(userID, source string) ([]models.AnalysisSummary, error) {
+			gotSource = source
+			return []models.AnalysisSummary{{
+				ID: "uuid-1", Username: "player1", Filename: "lichess_player1.pgn", GameCount: 5, UploadedAt: time.Now(),
+				ImportProvenance: models.ImportProvenance{
+					Source:       models.ImportSourceLichess,
+					SourceParams: models.ImportSourceParams{Username: "player1"},
+				},
+			}}, nil
+		},
+	}
+	handler := NewImportHandler(services.NewImportService(nil, mockAnalysisRepo), nil, nil)
+
+	t.Run("filters and returns the provenance", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/analyses?source=lichess", nil), rec)
+		setTestUserID(c)
+
+		require.NoError(t, handler.ListAnalysesHandler(c))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "lichess", gotSource)
+		var response []map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Len(t, response, 1)
+		assert.Equal(t, "lichess", response[0]["source"])
+		assert.Equal(t, map[string]interface{}{"username": "player1"}, response[0]["sourceParams"])
+	})
+
+	t.Run("rejects an unknown source", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/analyses?source=fide", nil), rec)
+		setTestUserID(c)
+
+		require.NoError(t, handler.ListAnalysesHandler(c))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestRerunImportHandler_UploadedFile(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.AnalysisDetail, error) {
+			return &models.AnalysisDetail{ID: id, ImportProvenance: models.ImportProvenance{
+				Source:       models.ImportSourcePGN,
+				SourceParams: models.ImportSourceParams{Filename: "games.pgn"},
+			}}, nil
+		},
+	}
+	handler := NewImportHandler(services.NewImportService(nil, mockAnalysisRepo), nil, nil)
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/api/analyses/"+validUUID+"/rerun", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	require.NoError(t, handler.RerunImportHandler(c))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
 func TestGetAnalysisHandler_NotFound(t *testing.T) {
 	e := echo.New()
 	validUUID := "123e4567-e89b-12d3-a456-426614174000"
@@ -733,6 +802,116 @@ func TestDeleteGameHandler_NegativeGameIndex(t *testing.T) {
 	assert.Equal(t, "gameIndex must be a non-negative integer", response["error"])
 }
 
+func TestUpdateGameHandler_Success(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPatch, "/api/games/"+validUUID+"/2", strings.NewReader(`{"starred":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("analysisId", "gameIndex")
+	c.SetParamValues(validUUID, "2")
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+		UpdateGameNotesFunc: func(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error) {
+			assert.Nil(t, note)
+			require.NotNil(t, starred)
+			return &models.GameNotes{AnalysisID: analysisID, GameIndex: gameIndex, Note: "kept", Starred: *starred}, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.UpdateGameHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response models.GameNotes
+	err = json.Unmarshal(rec.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.GameIndex)
+	assert.Equal(t, "kept", response.Note)
+	assert.True(t, response.Starred)
+}
+
+func TestUpdateGameHandler_GameNotFound(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPatch, "/api/games/"+validUUID+"/9", strings.NewReader(`{"note":"sharp line"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("analysisId", "gameIndex")
+	c.SetParamValues(validUUID, "9")
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.UpdateGameHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetGamesHandler_StarredFilter(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/games?starred=true", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	var gotStarred bool
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
+			gotStarred = starred
+			return &models.GamesResponse{Games: []models.GameSummary{}}, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.GetGamesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, gotStarred)
+}
+
+func TestGetGamesHandler_ClassificationFilter(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/games?classification=inaccuracy-in-book", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	var gotClassification string
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
+			gotClassification = classification
+			return &models.GamesResponse{Games: []models.GameSummary{}}, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.GetGamesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "inaccuracy-in-book", gotClassification)
+}
+
 func TestLichessImportHandler_MissingUsername(t *testing.T) {
 	e := echo.New()
 	body := `{"options":{}}`
@@ -775,6 +954,105 @@ func TestLichessImportHandler_InvalidJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
+func TestLichessBroadcastImportHandler_InvalidURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"missing url", `{}`, "url is required"},
+		{"study url", `{"url":"https://lichess.org/study/abcdef12"}`, "invalid Lichess broadcast round URL"},
+		{"invalid depth", `{"url":"abcdef12","analysisDepth":-1}`, services.ErrInvalidAnalysisDepth.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/imports/lichess-broadcast", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			setTestUserID(c)
+
+			handler := NewImportHandler(services.NewImportService(nil, nil), services.NewLichessService(), nil)
+
+			err := handler.LichessBroadcastImportHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var response map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.message, response["error"])
+		})
+	}
+}
+
+func TestLichessTeamImportHandler_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"missing team", `{}`, http.StatusBadRequest, "team is required"},
+		{"broadcast url", `{"team":"https://lichess.org/broadcast/tata-steel-2025/round-1/abcdef12"}`, http.StatusBadRequest, "invalid Lichess team URL"},
+		{"invalid depth", `{"team":"my-club","analysisDepth":-1}`, http.StatusBadRequest, services.ErrInvalidAnalysisDepth.Error()},
+		{"not configured", `{"team":"my-club"}`, http.StatusServiceUnavailable, services.ErrTeamImportsUnavailable.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/imports/lichess-team", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			setTestUserID(c)
+
+			handler := NewImportHandler(services.NewImportService(nil, nil), services.NewLichessService(), nil)
+
+			err := handler.LichessTeamImportHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, rec.Code)
+			var response map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.message, response["error"])
+		})
+	}
+}
+
+func TestExplainMistakeHandler_MissingParams(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/insights/explain?fen=rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR+w+KQkq+-", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.ExplainMistakeHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestExplainMistakeHandler_InvalidFEN(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/insights/explain?fen=garbage&played=e5", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	repSvc := services.NewRepertoireService(&mocks.MockRepertoireRepo{})
+	handler := NewImportHandler(services.NewImportService(repSvc, nil), nil, nil)
+
+	err := handler.ExplainMistakeHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
 func TestGetGamesHandler_DefaultPagination(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/games", nil)
@@ -783,7 +1061,7 @@ func TestGetGamesHandler_DefaultPagination(t *testing.T) {
 	setTestUserID(c)
 
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
-		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source string) (*models.GamesResponse, error) {
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
 			return &models.GamesResponse{
 				Games:  []models.GameSummary{},
 				Total:  0,
@@ -814,7 +1092,7 @@ func TestGetGamesHandler_WithGames(t *testing.T) {
 	setTestUserID(c)
 
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
-		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source string) (*models.GamesResponse, error) {
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
 			return &models.GamesResponse{
 				Games: []models.GameSummary{
 					{
@@ -859,7 +1137,7 @@ func TestGetGamesHandler_CustomPagination(t *testing.T) {
 
 	var capturedLimit, capturedOffset int
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
-		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source string) (*models.GamesResponse, error) {
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
 			capturedLimit = limit
 			capturedOffset = offset
 			return &models.GamesResponse{
@@ -986,7 +1264,7 @@ func TestUploadHandler_CaseInsensitivePGNExtension(t *testing.T) {
 		},
 	}
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
-		SaveFunc: func(userID string, username, filename string, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
 			return &models.AnalysisSummary{
 				ID:        "new-analysis-id",
 				Username:  username,
@@ -1040,3 +1318,709 @@ func TestValidateMoveHandler_QueensideCastling(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
+
+func TestGetInsightsHandler_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"minDrop not a number", "minDrop=abc"},
+		{"minDrop above 1", "minDrop=1.5"},
+		{"since malformed", "since=last-week"},
+		{"timeClass unknown", "timeClass=hyperbullet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/games/insights?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			setTestUserID(c)
+
+			handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+			err := handler.GetInsightsHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestGetInsightsHandler_ValidParams(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/games/insights?limit=10&minDrop=0.05&since=2024-01-01&repertoireId=rep-1&timeClass=blitz", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.GetInsightsHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestExportMistakesCSVHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/insights/mistakes/export.csv?repertoireId=rep-1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.ExportMistakesCSVHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/csv")
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "mistakes.csv")
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "date,white,black"))
+}
+
+func TestExportMistakesCSVHandler_InvalidMinDrop(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/insights/mistakes/export.csv?minDrop=2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.ExportMistakesCSVHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListDismissedMistakesHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/insights/dismissed", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	repo := &mocks.MockDismissedMistakeRepo{
+		ListFunc: func(userID string) ([]models.DismissedMistake, error) {
+			return []models.DismissedMistake{{FEN: "fen-1", PlayedMove: "Bf4"}}, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, nil, services.WithDismissedMistakeRepo(repo))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.ListDismissedMistakesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Mistakes []models.DismissedMistake `json:"mistakes"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Mistakes, 1)
+	assert.Equal(t, "Bf4", response.Mistakes[0].PlayedMove)
+}
+
+func TestRestoreMistakeHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		restoreErr error
+		wantStatus int
+	}{
+		{"restored", "fen=fen-1&playedMove=Bf4", nil, http.StatusNoContent},
+		{"missing move", "fen=fen-1", nil, http.StatusBadRequest},
+		{"not dismissed", "fen=fen-1&playedMove=Bf4", repository.ErrDismissedMistakeNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/api/insights/dismissed?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			setTestUserID(c)
+
+			var restored string
+			repo := &mocks.MockDismissedMistakeRepo{
+				RestoreFunc: func(userID, fen, playedMove string) error {
+					restored = fen + "|" + playedMove
+					return tt.restoreErr
+				},
+			}
+			importSvc := services.NewImportService(nil, nil, services.WithDismissedMistakeRepo(repo))
+			handler := NewImportHandler(importSvc, nil, nil)
+
+			err := handler.RestoreMistakeHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusNoContent {
+				assert.Equal(t, "fen-1|Bf4", restored)
+			}
+		})
+	}
+}
+
+func TestUploadHandler_InvalidDuplicatePolicy(t *testing.T) {
+	e := echo.New()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("username", "testuser")
+	writer.WriteField("duplicatePolicy", "overwrite")
+	part, _ := writer.CreateFormFile("file", "test.pgn")
+	part.Write([]byte("1. e4 e5 1-0"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/imports", body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.UploadHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "duplicatePolicy")
+}
+
+func TestReanalyzeRepertoireGamesHandler_Accepted(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPost, "/api/repertoires/"+validUUID+"/reanalyze-games", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	repSvc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+	})
+	jobRepo := &mocks.MockReanalysisJobRepo{
+		CreateFunc: func(userID, repertoireID string) (*models.ReanalysisJob, error) {
+			return &models.ReanalysisJob{ID: "job-1", UserID: userID, RepertoireID: repertoireID, Status: "pending"}, nil
+		},
+	}
+	importSvc := services.NewImportService(repSvc, &mocks.MockAnalysisRepo{}, services.WithReanalysisJobRepo(jobRepo))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.ReanalyzeRepertoireGamesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	var job models.ReanalysisJob
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, "job-1", job.ID)
+	assert.Equal(t, validUUID, job.RepertoireID)
+	assert.Equal(t, "pending", job.Status)
+}
+
+func TestGetReanalysisJobHandler_NotFound(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodGet, "/api/analyses/reanalysis-jobs/"+validUUID, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	importSvc := services.NewImportService(nil, &mocks.MockAnalysisRepo{}, services.WithReanalysisJobRepo(&mocks.MockReanalysisJobRepo{}))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.GetReanalysisJobHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestExportGamePGNHandler(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodGet, "/api/games/"+validUUID+"/0/pgn", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("analysisId", "gameIndex")
+	c.SetParamValues(validUUID, "0")
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return &models.GameAnalysis{
+				Headers: models.PGNHeaders{"White": "me", "Black": "you", "Result": "0-1"},
+				Moves: []models.MoveAnalysis{
+					{SAN: "f3", Status: "out-of-repertoire", ExpectedMove: "e4", IsUserMove: true},
+				},
+			}, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.ExportGamePGNHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-chess-pgn", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+	assert.Contains(t, rec.Body.String(), "1. f3 {out of repertoire, expected e4} 0-1")
+}
+
+func TestExportGamePGNHandler_GameNotFound(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodGet, "/api/games/"+validUUID+"/3/pgn", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("analysisId", "gameIndex")
+	c.SetParamValues(validUUID, "3")
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return nil, repository.ErrGameNotFound
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.ExportGamePGNHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRecomputeEvalsHandler_EngineUnavailable(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPost, "/api/analyses/"+validUUID+"/recompute-evals", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.RecomputeEvalsHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestRecomputeEvalsHandler_Success(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPost, "/api/analyses/"+validUUID+"/recompute-evals", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+		GetByIDFunc: func(id string) (*models.AnalysisDetail, error) {
+			return &models.AnalysisDetail{ID: id, Results: []models.GameAnalysis{{GameIndex: 0}, {GameIndex: 1}}}, nil
+		},
+	}
+	engineSvc := services.NewEngineService(&mocks.MockEngineEvalRepo{}, mockAnalysisRepo)
+	importSvc := services.NewImportService(nil, mockAnalysisRepo, services.WithEngineService(engineSvc))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.RecomputeEvalsHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"queued":2`)
+}
+
+func TestRetryEvalsHandler_Success(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPost, "/api/analyses/"+validUUID+"/evals/retry", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return true, nil
+		},
+	}
+	evalRepo := &mocks.MockEngineEvalRepo{
+		RetryFailedFunc: func(analysisID string) (int64, error) {
+			assert.Equal(t, validUUID, analysisID)
+			return 4, nil
+		},
+	}
+	engineSvc := services.NewEngineService(evalRepo, mockAnalysisRepo)
+	importSvc := services.NewImportService(nil, mockAnalysisRepo, services.WithEngineService(engineSvc))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.RetryEvalsHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"queued":4`)
+}
+
+func TestRetryEvalsHandler_NotOwner(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodPost, "/api/analyses/"+validUUID+"/evals/retry", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return false, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, mockAnalysisRepo)
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.RetryEvalsHandler(c)
+
+	require.NoError(t, err)
+	assert.NotEqual(t, http.StatusAccepted, rec.Code)
+}
+
+func newDatabaseImportRequest(t *testing.T, username string) (*http.Request, *bytes.Buffer) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("username", username)
+	part, err := writer.CreateFormFile("file", "database.pgn")
+	require.NoError(t, err)
+	part.Write([]byte("[White \"testuser\"]\n\n1. e4 e5 1-0\n"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/imports/database", body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	return req, body
+}
+
+func TestImportDatabaseHandler_Accepted(t *testing.T) {
+	e := echo.New()
+	req, _ := newDatabaseImportRequest(t, "testuser")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	var queued models.ImportJob
+	jobRepo := &mocks.MockImportJobRepo{
+		CreateFunc: func(job models.ImportJob) (*models.ImportJob, error) {
+			queued = job
+			job.ID = "job-1"
+			job.Status = "pending"
+			return &job, nil
+		},
+	}
+	importSvc := services.NewImportService(nil, nil, services.WithImportJobRepo(jobRepo, t.TempDir()))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.ImportDatabaseHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "testuser", queued.Username)
+	assert.Equal(t, "database.pgn", queued.Filename)
+	assert.Equal(t, models.DuplicatePolicySkip, queued.DuplicatePolicy)
+	assert.FileExists(t, queued.FilePath)
+
+	var job models.ImportJob
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, "job-1", job.ID)
+	assert.Equal(t, "pending", job.Status)
+}
+
+func TestImportDatabaseHandler_MissingUsernameDiscardsFile(t *testing.T) {
+	e := echo.New()
+	req, _ := newDatabaseImportRequest(t, "")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	spoolDir := t.TempDir()
+	importSvc := services.NewImportService(nil, nil, services.WithImportJobRepo(&mocks.MockImportJobRepo{}, spoolDir))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.ImportDatabaseHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	entries, err := os.ReadDir(spoolDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGetImportJobHandler_NotFound(t *testing.T) {
+	e := echo.New()
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	req := httptest.NewRequest(http.MethodGet, "/api/imports/jobs/"+validUUID, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(validUUID)
+	setTestUserID(c)
+
+	importSvc := services.NewImportService(nil, nil, services.WithImportJobRepo(&mocks.MockImportJobRepo{}, t.TempDir()))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.GetImportJobHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestImportSummaryHandler(t *testing.T) {
+	validUUID := "123e4567-e89b-12d3-a456-426614174000"
+	newContext := func() (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/imports/"+validUUID+"/summary", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(validUUID)
+		setTestUserID(c)
+		return c, rec
+	}
+
+	t.Run("found", func(t *testing.T) {
+		c, rec := newContext()
+		summaryRepo := &mocks.MockImportSummaryRepo{
+			GetByAnalysisIDFunc: func(analysisID, userID string) (*models.ImportSummary, error) {
+				assert.Equal(t, validUUID, analysisID)
+				assert.Equal(t, testUserID, userID)
+				return &models.ImportSummary{
+					AnalysisID:    analysisID,
+					GamesFound:    3,
+					GamesImported: 2,
+					Warnings:      []models.ImportWarning{{Kind: models.ImportWarningUserNotFound, Game: 2}},
+				}, nil
+			},
+		}
+		handler := NewImportHandler(services.NewImportService(nil, nil, services.WithImportSummaries(summaryRepo)), nil, nil)
+
+		err := handler.ImportSummaryHandler(c)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var summary models.ImportSummary
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+		assert.Equal(t, 3, summary.GamesFound)
+		require.Len(t, summary.Warnings, 1)
+		assert.Equal(t, models.ImportWarningUserNotFound, summary.Warnings[0].Kind)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c, rec := newContext()
+		handler := NewImportHandler(services.NewImportService(nil, nil, services.WithImportSummaries(&mocks.MockImportSummaryRepo{})), nil, nil)
+
+		err := handler.ImportSummaryHandler(c)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestImportStatsHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/imports/stats", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetImportStatsFunc: func(userID string) ([]models.ImportSourceStats, error) {
+			assert.Equal(t, testUserID, userID)
+			return []models.ImportSourceStats{
+				{Source: "lichess", Month: "2026-09", GamesImported: 40, DuplicatesSkipped: 3, OutOfRepertoireRate: 0.25, EvalCompletion: 1},
+			}, nil
+		},
+	}
+	handler := NewImportHandler(services.NewImportService(nil, analysisRepo), nil, nil)
+
+	err := handler.ImportStatsHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stats []models.ImportSourceStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Len(t, stats, 1)
+	assert.Equal(t, "lichess", stats[0].Source)
+	assert.Equal(t, 3, stats[0].DuplicatesSkipped)
+}
+
+func TestImportPreviewHandler_InvalidSource(t *testing.T) {
+	e := echo.New()
+	body := `{"source":"fics","username":"testuser"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/imports/preview", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.ImportPreviewHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestImportPreviewHandler_Upload(t *testing.T) {
+	e := echo.New()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("username", "testuser")
+	part, _ := writer.CreateFormFile("file", "games.pgn")
+	part.Write([]byte("[White \"testuser\"]\n[Black \"opponent\"]\n[Result \"1-0\"]\n\n1. e4 e5 1-0\n"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/imports/preview", body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.ImportPreviewHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var preview models.ImportPreview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &preview))
+	assert.Equal(t, 1, preview.TotalGames)
+	assert.Equal(t, 1, preview.NewGames)
+	assert.Equal(t, 1, preview.AsWhite)
+}
+
+func TestOpponentRepliesHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/positions/opponent-replies?fen="+url.QueryEscape("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -")+"&minRating=1500", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	importSvc := services.NewImportService(nil, nil, services.WithOpponentReplyRepo(&mocks.MockOpponentReplyRepo{
+		AggregateFunc: func(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error) {
+			assert.Equal(t, 1500, minRating)
+			assert.Zero(t, maxRating)
+			return []models.OpponentReply{{Move: "e4", Games: 2, Wins: 1, Losses: 1}}, nil
+		},
+	}))
+	handler := NewImportHandler(importSvc, nil, nil)
+
+	err := handler.OpponentRepliesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"move":"e4"`)
+	assert.Contains(t, rec.Body.String(), `"games":2`)
+}
+
+func TestOpponentRepliesHandler_MissingFEN(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/positions/opponent-replies", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+	err := handler.OpponentRepliesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRecomputeHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"invalid user ID", `{"userId":"not-a-uuid"}`, http.StatusBadRequest},
+		{"archive not configured", `{}`, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/recompute", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			handler := NewImportHandler(services.NewImportService(nil, nil), nil, nil)
+
+			require.NoError(t, handler.RecomputeHandler(c))
+			assert.Equal(t, tt.expected, rec.Code)
+		})
+	}
+}
+
+func TestParseMatchRepertoireIDs(t *testing.T) {
+	id1, id2 := "3f1c2a7e-8b4d-4c1e-9a3b-2d5e6f7a8b9c", "7a9b8c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+
+	ids, ok := parseMatchRepertoireIDs("")
+	assert.True(t, ok)
+	assert.Nil(t, ids)
+
+	ids, ok = parseMatchRepertoireIDs(id1 + ", " + id2)
+	assert.True(t, ok)
+	assert.Equal(t, []string{id1, id2}, ids)
+
+	_, ok = parseMatchRepertoireIDs(id1 + ",not-a-uuid")
+	assert.False(t, ok)
+}
+
+func TestParseSinceParam_UserTimezone(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	since, err := parseSinceParam("2026-03-10", paris)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC), since.UTC())
+
+	since, err = parseSinceParam("2026-03-10", nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), since)
+
+	// Timestamps carry their own offset
+	since, err = parseSinceParam("2026-03-10T12:00:00Z", paris)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), since)
+}
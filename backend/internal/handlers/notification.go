@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationSvc *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationSvc}
+}
+
+// SettingsHandler returns the user's notification preferences and channels
+// GET /api/notifications/settings
+func (h *NotificationHandler) SettingsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	settings, err := h.notificationService.Settings(user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get notification settings")
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePreferencesHandler sets the channels the user receives each event on
+// PUT /api/notifications/preferences
+func (h *NotificationHandler) UpdatePreferencesHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	settings, err := h.notificationService.UpdatePreferences(user.ID, req.Preferences)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to update notification preferences")
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// SetWebhookHandler sets the URL the user's notifications are posted to. The response holds
+// the signing secret, which is not shown again.
+// PUT /api/notifications/webhook
+func (h *NotificationHandler) SetWebhookHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.SetNotificationWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if !RequireField(c, "url", req.URL) {
+		return nil
+	}
+
+	webhook, err := h.notificationService.SetWebhook(user.ID, req.URL)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to set notification webhook")
+	}
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhookHandler stops posting the user's notifications to their webhook
+// DELETE /api/notifications/webhook
+func (h *NotificationHandler) DeleteWebhookHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.notificationService.DeleteWebhook(user.ID); err != nil {
+		return ServiceErrorResponse(c, err, "failed to delete notification webhook")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SubscribePushHandler registers a browser for push notifications
+// POST /api/notifications/push
+func (h *NotificationHandler) SubscribePushHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var sub models.PushSubscription
+	if err := c.Bind(&sub); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if !RequireField(c, "endpoint", sub.Endpoint) {
+		return nil
+	}
+
+	if err := h.notificationService.SubscribePush(user.ID, sub); err != nil {
+		return ServiceErrorResponse(c, err, "failed to subscribe to push notifications")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UnsubscribePushHandler removes a browser's push subscription
+// DELETE /api/notifications/push
+func (h *NotificationHandler) UnsubscribePushHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.UnsubscribePushRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if !RequireField(c, "endpoint", req.Endpoint) {
+		return nil
+	}
+
+	if err := h.notificationService.UnsubscribePush(user.ID, req.Endpoint); err != nil {
+		return ServiceErrorResponse(c, err, "failed to unsubscribe from push notifications")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -111,7 +111,7 @@ func (h *OAuthHandler) Callback(c echo.Context) error {
 		return h.redirectWithError(c, "failed to authenticate with Lichess")
 	}
 
-	resp, isNew, err := h.oauthService.FindOrCreateUser("lichess", lichessID, username)
+	resp, isNew, err := h.oauthService.FindOrCreateUser("lichess", lichessID, username, requestDevice(c))
 	if err != nil {
 		return h.redirectWithError(c, "failed to create account")
 	}
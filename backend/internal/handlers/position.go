@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+type PositionHandler struct {
+	engineService     *services.EngineService
+	repertoireService *services.RepertoireService
+}
+
+func NewPositionHandler(engineSvc *services.EngineService, repertoireSvc *services.RepertoireService) *PositionHandler {
+	return &PositionHandler{engineService: engineSvc, repertoireService: repertoireSvc}
+}
+
+// GetModelGamesHandler returns master games reaching a position
+// GET /api/positions/model-games?fen=...
+func (h *PositionHandler) GetModelGamesHandler(c echo.Context) error {
+	fen := c.QueryParam("fen")
+	if fen == "" {
+		return BadRequestResponse(c, "fen parameter is required")
+	}
+
+	games, err := h.engineService.GetModelGames(fen)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFEN) {
+			return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeInvalidFEN, err.Error())
+		}
+		return ErrorResponse(c, http.StatusBadGateway, "failed to fetch model games")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"fen":   fen,
+		"games": games,
+	})
+}
+
+// LookupPositionHandler returns the user's repertoire nodes reaching a position
+// GET /api/positions/lookup?fen=...
+func (h *PositionHandler) LookupPositionHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	fen := c.QueryParam("fen")
+	if fen == "" {
+		return BadRequestResponse(c, "fen parameter is required")
+	}
+
+	matches, err := h.repertoireService.LookupPosition(user.ID, fen)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to look up position")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"fen":     services.NormalizeFEN(fen),
+		"matches": matches,
+	})
+}
+
+// ExplorerHandler returns Lichess Explorer stats for a position from the shared cache.
+// Cache misses are queued and answered with 202 and a pending status; clients poll again.
+// GET /api/explorer?fen=...&speeds=blitz,rapid&ratings=1800,2000
+func (h *PositionHandler) ExplorerHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	fen := c.QueryParam("fen")
+	if fen == "" {
+		return BadRequestResponse(c, "fen parameter is required")
+	}
+
+	position, err := h.engineService.ExplorerPosition(user.ID, fen, c.QueryParam("speeds"), c.QueryParam("ratings"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to look up explorer stats")
+	}
+
+	if position.Status == models.ExplorerPending {
+		return c.JSON(http.StatusAccepted, position)
+	}
+	return c.JSON(http.StatusOK, position)
+}
+
+// TablebaseHandler returns the Lichess tablebase verdict on a position with few pieces
+// GET /api/tablebase?fen=...
+func (h *PositionHandler) TablebaseHandler(c echo.Context) error {
+	fen := c.QueryParam("fen")
+	if fen == "" {
+		return BadRequestResponse(c, "fen parameter is required")
+	}
+
+	position, err := h.engineService.TablebasePosition(fen)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFEN), errors.Is(err, services.ErrTooManyPieces),
+			errors.Is(err, services.ErrTablebaseUnavailable):
+			return ServiceErrorResponse(c, err, "failed to fetch tablebase verdict")
+		}
+		return ErrorResponse(c, http.StatusBadGateway, "failed to fetch tablebase verdict")
+	}
+
+	return c.JSON(http.StatusOK, position)
+}
+
+// NormalizeFENHandler validates a FEN and returns its normalized and full forms with the side
+// to move, the number of legal moves and whether the game is over
+// POST /api/chess/normalize-fen
+func (h *PositionHandler) NormalizeFENHandler(c echo.Context) error {
+	var req struct {
+		FEN string `json:"fen"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if !RequireField(c, "fen", req.FEN) {
+		return nil
+	}
+
+	details, err := services.DescribeFEN(req.FEN)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to normalize FEN")
+	}
+
+	return c.JSON(http.StatusOK, details)
+}
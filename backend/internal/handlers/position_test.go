@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+	"github.com/treechess/backend/internal/services"
+)
+
+func TestGetModelGamesHandler_MissingFEN(t *testing.T) {
+	handler := NewPositionHandler(services.NewEngineService(nil, nil), nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/positions/model-games", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.GetModelGamesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetModelGamesHandler_InvalidFEN(t *testing.T) {
+	handler := NewPositionHandler(services.NewEngineService(nil, nil), nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/positions/model-games?fen=garbage", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.GetModelGamesHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid FEN")
+}
+
+func TestLookupPositionHandler(t *testing.T) {
+	e4 := "e4"
+	rep := models.Repertoire{
+		ID:    "rep-1",
+		Name:  "White",
+		Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID:  "root",
+			FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+			Children: []*models.RepertoireNode{{
+				ID:   "n1",
+				FEN:  "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+				Move: &e4,
+			}},
+		},
+	}
+	repo := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) {
+			assert.Equal(t, "user-1", userID)
+			return []models.Repertoire{rep}, nil
+		},
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &rep, nil
+		},
+	}
+	handler := NewPositionHandler(nil, services.NewRepertoireService(repo))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/positions/lookup?fen=rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR+b+KQkq+e3+0+1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.LookupPositionHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"nodeId":"n1"`)
+	assert.Contains(t, rec.Body.String(), `"path":["e4"]`)
+}
+
+func TestLookupPositionHandler_InvalidFEN(t *testing.T) {
+	handler := NewPositionHandler(nil, services.NewRepertoireService(&mocks.MockRepertoireRepo{}))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/positions/lookup?fen=garbage", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.LookupPositionHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestExplorerHandler_PendingOnCacheMiss(t *testing.T) {
+	handler := NewPositionHandler(services.NewEngineService(nil, nil), nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/explorer?fen=rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR%20b%20KQkq%20-%200%201", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.ExplorerHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"pending"`)
+}
+
+func TestExplorerHandler_InvalidSpeeds(t *testing.T) {
+	handler := NewPositionHandler(services.NewEngineService(nil, nil), nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/explorer?fen=rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR%20b%20KQkq%20-%200%201&speeds=turbo", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.ExplorerHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTablebaseHandler_Errors(t *testing.T) {
+	withTablebase := services.NewEngineService(nil, nil)
+	withTablebase.WithTablebase(services.NewTablebaseService())
+
+	tests := []struct {
+		name   string
+		engine *services.EngineService
+		query  string
+		status int
+	}{
+		{"missing fen", withTablebase, "", http.StatusBadRequest},
+		{"too many pieces", withTablebase, "?fen=rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR%20b%20KQkq%20-%200%201", http.StatusBadRequest},
+		{"not configured", services.NewEngineService(nil, nil), "?fen=8/8/8/8/8/4k3/4P3/4K3%20w%20-%20-%200%201", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPositionHandler(tt.engine, nil)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/tablebase"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			setTestPrincipal(c, "user-1")
+
+			err := handler.TablebaseHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestNormalizeFENHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"valid", `{"fen":"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"}`, http.StatusOK},
+		{"missing fen", `{}`, http.StatusBadRequest},
+		{"invalid fen", `{"fen":"garbage"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPositionHandler(nil, nil)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/chess/normalize-fen", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			setTestPrincipal(c, "user-1")
+
+			err := handler.NormalizeFENHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				var details models.FENDetails
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, models.ColorBlack, details.SideToMove)
+				assert.Equal(t, 20, details.LegalMoves)
+				assert.Equal(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", details.FullFEN)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/services"
+)
+
+type PrepHandler struct {
+	prepService       *services.PrepService
+	repertoireService *services.RepertoireService
+}
+
+func NewPrepHandler(prepSvc *services.PrepService, repertoireSvc *services.RepertoireService) *PrepHandler {
+	return &PrepHandler{prepService: prepSvc, repertoireService: repertoireSvc}
+}
+
+// checkRepertoire validates the repertoire ID and that the current user owns it
+func (h *PrepHandler) checkRepertoire(c echo.Context) (string, bool) {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return "", false
+	}
+	repertoireID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return "", false
+	}
+	if err := h.repertoireService.CheckOwner(repertoireID, user.ID); err != nil {
+		AccessErrorResponse(c, err, "repertoire")
+		return "", false
+	}
+	return repertoireID, true
+}
+
+// RequestPrepHandler queues a deep expansion of a node
+// POST /api/repertoires/:id/nodes/:nodeId/prep?depth=4
+func (h *PrepHandler) RequestPrepHandler(c echo.Context) error {
+	repertoireID, ok := h.checkRepertoire(c)
+	if !ok {
+		return nil
+	}
+	nodeID, ok := ValidateUUIDParam(c, "nodeId")
+	if !ok {
+		return nil
+	}
+	user, _ := CurrentUser(c)
+
+	depth := ParseIntQueryParam(c, "depth", config.DefaultPrepDepth, 1, config.MaxPrepDepth)
+	req, err := h.prepService.RequestPrep(user.ID, repertoireID, nodeID, depth)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to queue node preparation")
+	}
+
+	return c.JSON(http.StatusAccepted, req)
+}
+
+// ListPrepHandler returns the preparations of a node with their remaining suggestions
+// GET /api/repertoires/:id/nodes/:nodeId/prep
+func (h *PrepHandler) ListPrepHandler(c echo.Context) error {
+	repertoireID, ok := h.checkRepertoire(c)
+	if !ok {
+		return nil
+	}
+	nodeID, ok := ValidateUUIDParam(c, "nodeId")
+	if !ok {
+		return nil
+	}
+
+	requests, err := h.prepService.ListPrep(repertoireID, nodeID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list node preparations")
+	}
+
+	return c.JSON(http.StatusOK, requests)
+}
+
+// AcceptSuggestionHandler adds a suggested line to the tree
+// POST /api/repertoires/:id/prep/:prepId/suggestions/:suggestionId/accept
+func (h *PrepHandler) AcceptSuggestionHandler(c echo.Context) error {
+	repertoireID, ok := h.checkRepertoire(c)
+	if !ok {
+		return nil
+	}
+	prepID, ok := ValidateUUIDParam(c, "prepId")
+	if !ok {
+		return nil
+	}
+	suggestionID, ok := ValidateUUIDParam(c, "suggestionId")
+	if !ok {
+		return nil
+	}
+
+	rep, err := h.prepService.AcceptSuggestion(repertoireID, prepID, suggestionID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to accept suggestion")
+	}
+
+	return c.JSON(http.StatusOK, rep)
+}
+
+// DiscardSuggestionHandler drops a suggestion
+// DELETE /api/repertoires/:id/prep/:prepId/suggestions/:suggestionId
+func (h *PrepHandler) DiscardSuggestionHandler(c echo.Context) error {
+	repertoireID, ok := h.checkRepertoire(c)
+	if !ok {
+		return nil
+	}
+	prepID, ok := ValidateUUIDParam(c, "prepId")
+	if !ok {
+		return nil
+	}
+	suggestionID, ok := ValidateUUIDParam(c, "suggestionId")
+	if !ok {
+		return nil
+	}
+
+	if err := h.prepService.DiscardSuggestion(repertoireID, prepID, suggestionID); err != nil {
+		return ServiceErrorResponse(c, err, "failed to discard suggestion")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
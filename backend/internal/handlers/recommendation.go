@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/services"
+)
+
+type RecommendationHandler struct {
+	recommendationService *services.RecommendationService
+}
+
+func NewRecommendationHandler(recommendationSvc *services.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{recommendationService: recommendationSvc}
+}
+
+// ListHandler returns what the user should study next, most valuable first
+// GET /api/recommendations
+func (h *RecommendationHandler) ListHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	recommendations, err := h.recommendationService.Recommend(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to get recommendations")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"recommendations": recommendations,
+	})
+}
@@ -2,38 +2,46 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/services"
 )
 
-// ListRepertoiresHandler returns all repertoires, optionally filtered by color
-// GET /api/repertoires?color=white|black
+// ListRepertoiresHandler returns all repertoires, optionally filtered by color; archived ones
+// only with includeArchived=true
+// GET /api/repertoires?color=white|black&fields=slim&includeArchived=true
 func ListRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		colorParam := c.QueryParam("color")
 
 		var colorFilter *models.Color
 		if colorParam != "" {
 			color := models.Color(colorParam)
 			if color != models.ColorWhite && color != models.ColorBlack {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "invalid color. must be 'white' or 'black'",
-				})
+				return BadRequestResponse(c, "invalid color. must be 'white' or 'black'")
 			}
 			colorFilter = &color
 		}
 
-		repertoires, err := svc.ListRepertoires(userID, colorFilter)
+		repertoires, err := svc.ListRepertoires(user.ID, colorFilter, c.QueryParam("includeArchived") == "true")
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to list repertoires",
-			})
+			return InternalErrorResponse(c, "failed to list repertoires")
 		}
 
 		// Return empty array instead of null
@@ -41,7 +49,18 @@ func ListRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
 			repertoires = []models.Repertoire{}
 		}
 
-		return c.JSON(http.StatusOK, repertoires)
+		var body interface{} = repertoires
+		if slimFieldsRequested(c) {
+			slim := make([]models.SlimRepertoire, len(repertoires))
+			for i, rep := range repertoires {
+				slim[i] = models.NewSlimRepertoire(rep)
+			}
+			body = slim
+		}
+
+		// ETag only: deleting a repertoire does not advance any updated_at,
+		// so If-Modified-Since cannot be trusted for the list
+		return CachedJSONResponse(c, body, time.Time{})
 	}
 }
 
@@ -49,47 +68,27 @@ func ListRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
 // POST /api/repertoires
 func CreateRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 
 		var req models.CreateRepertoireRequest
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		if req.Name == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "name is required",
-			})
+			return BadRequestResponse(c, "name is required")
 		}
 
 		if req.Color != models.ColorWhite && req.Color != models.ColorBlack {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid color. must be 'white' or 'black'",
-			})
+			return BadRequestResponse(c, "invalid color. must be 'white' or 'black'")
 		}
 
-		rep, err := svc.CreateRepertoire(userID, req.Name, req.Color)
+		rep, err := svc.CreateRepertoire(user.ID, req.Name, req.Color)
 		if err != nil {
-			if errors.Is(err, services.ErrLimitReached) {
-				return c.JSON(http.StatusConflict, map[string]string{
-					"error": "maximum repertoire limit reached (50)",
-				})
-			}
-			if errors.Is(err, services.ErrNameRequired) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name is required",
-				})
-			}
-			if errors.Is(err, services.ErrNameTooLong) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name must be 100 characters or less",
-				})
-			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to create repertoire",
-			})
+			return ServiceErrorResponse(c, err, "failed to create repertoire")
 		}
 
 		return c.JSON(http.StatusCreated, rep)
@@ -97,84 +96,124 @@ func CreateRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
 }
 
 // GetRepertoireHandler returns a single repertoire by ID
-// GET /api/repertoire/:id
+// GET /api/repertoire/:id?depth=N&fields=slim
 func GetRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
-		rep, err := svc.GetRepertoire(idParam)
+		depth := parseDepthParam(c)
+		rep, err := svc.GetRepertoireToDepth(idParam, depth)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to get repertoire",
-			})
+			return InternalErrorResponse(c, "failed to get repertoire")
 		}
 
-		return c.JSON(http.StatusOK, rep)
+		// If-Modified-Since does not say which variant the client holds, so only the full
+		// tree is validated by date; depth-limited and slim trees rely on their ETag
+		lastModified := rep.UpdatedAt
+		if depth >= 0 || slimFieldsRequested(c) {
+			lastModified = time.Time{}
+		}
+		if slimFieldsRequested(c) {
+			return CachedJSONResponse(c, models.NewSlimRepertoire(*rep), lastModified)
+		}
+		return CachedJSONResponse(c, rep, lastModified)
+	}
+}
+
+// GetSubtreeHandler returns the subtree below a node, for clients loading large trees progressively
+// GET /api/repertoires/:id/nodes/:nodeId/subtree?depth=N&fields=slim
+func GetSubtreeHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		nodeID, ok := ValidateUUIDParam(c, "nodeId")
+		if !ok {
+			return nil
+		}
+
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		subtree, err := svc.GetSubtree(idParam, nodeID, parseDepthParam(c))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrNotFound):
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to get subtree")
+		}
+
+		if slimFieldsRequested(c) {
+			return c.JSON(http.StatusOK, models.NewSlimTree(subtree))
+		}
+		return c.JSON(http.StatusOK, subtree)
 	}
 }
 
+// slimFieldsRequested reports whether the client asked for compact trees with ?fields=slim
+func slimFieldsRequested(c echo.Context) bool {
+	return c.QueryParam("fields") == "slim"
+}
+
+// parseDepthParam reads the optional depth query parameter; -1 (the default) means the whole tree
+func parseDepthParam(c echo.Context) int {
+	return ParseIntQueryParam(c, "depth", -1, 0, math.MaxInt32)
+}
+
 // UpdateRepertoireHandler renames a repertoire
 // PATCH /api/repertoire/:id
 func UpdateRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		var req models.UpdateRepertoireRequest
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		rep, err := svc.RenameRepertoire(idParam, req.Name)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
-			}
-			if errors.Is(err, services.ErrNameRequired) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name is required",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			if errors.Is(err, services.ErrNameTooLong) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name must be 100 characters or less",
-				})
-			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to update repertoire",
-			})
+			return ServiceErrorResponse(c, err, "failed to update repertoire")
 		}
 
 		return c.JSON(http.StatusOK, rep)
@@ -185,151 +224,221 @@ func UpdateRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
 // DELETE /api/repertoire/:id
 func DeleteRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		err := svc.DeleteRepertoire(idParam)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to delete repertoire",
-			})
+			return InternalErrorResponse(c, "failed to delete repertoire")
 		}
 
 		return c.NoContent(http.StatusNoContent)
 	}
 }
 
-// AddNodeHandler adds a node to a repertoire
-// POST /api/repertoire/:id/node
+// SetRepertoireArchivedHandler archives or unarchives a repertoire
+// PATCH /api/repertoires/:id/archive
+func SetRepertoireArchivedHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+		idParam := c.Param("id")
+
+		// Validate ID is a valid UUID
+		if _, err := uuid.Parse(idParam); err != nil {
+			return BadRequestResponse(c, "id must be a valid UUID")
+		}
+
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		var req models.SetArchivedRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+		if req.Archived == nil {
+			return BadRequestResponse(c, "archived is required")
+		}
+
+		rep, err := svc.SetArchived(idParam, *req.Archived)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to update repertoire archive state")
+		}
+
+		return c.JSON(http.StatusOK, rep)
+	}
+}
+
+// AddNodeHandler adds a node to a repertoire. With includeStats=true, an opponent move comes back
+// with its Explorer popularity.
+// POST /api/repertoire/:id/node?includeStats=true
 func AddNodeHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		var req models.AddNodeRequest
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		if req.ParentID == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "parentId is required",
-			})
+			return BadRequestResponse(c, "parentId is required")
 		}
 
 		// Validate parentId is a valid UUID
 		if _, err := uuid.Parse(req.ParentID); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "parentId must be a valid UUID",
-			})
+			return BadRequestResponse(c, "parentId must be a valid UUID")
 		}
 
 		if req.Move == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "move is required",
-			})
+			return BadRequestResponse(c, "move is required")
 		}
 
 		rep, err := svc.AddNode(idParam, req)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
-			}
-			if errors.Is(err, services.ErrParentNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "parent node not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			if errors.Is(err, services.ErrInvalidMove) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": err.Error(),
-				})
-			}
-			if errors.Is(err, services.ErrMoveExists) {
-				return c.JSON(http.StatusConflict, map[string]string{
-					"error": "move already exists in repertoire",
-				})
-			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to add node",
-			})
+			return ServiceErrorResponse(c, err, "failed to add node")
 		}
 
-		return c.JSON(http.StatusOK, rep)
+		if c.QueryParam("includeStats") != "true" {
+			return c.JSON(http.StatusOK, rep)
+		}
+		// The node is saved whatever happens to the lookup, so a failed one only leaves the stats out
+		popularity, err := svc.OpponentMovePopularity(user.ID, rep, req.ParentID, req.Move)
+		if err != nil {
+			log.Printf("add node: explorer popularity of %s failed: %v", req.Move, err)
+		}
+		return c.JSON(http.StatusOK, models.AddNodeResponse{Repertoire: *rep, Popularity: popularity})
 	}
 }
 
-// ListTemplatesHandler returns available starter repertoire templates
+// ListTemplatesHandler returns the featured templates and the user's own templates
 // GET /api/repertoires/templates
-func ListTemplatesHandler() echo.HandlerFunc {
+func ListTemplatesHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		templates := services.ListTemplates()
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		templates, err := svc.ListTemplates(user.ID)
+		if err != nil {
+			return InternalErrorResponse(c, "failed to list templates")
+		}
 		return c.JSON(http.StatusOK, templates)
 	}
 }
 
+// PublishTemplateHandler publishes one of the user's repertoires as a template
+// POST /api/repertoires/templates
+func PublishTemplateHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		var req models.PublishTemplateRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+		if !RequireField(c, "repertoireId", req.RepertoireID) {
+			return nil
+		}
+		if !ValidateUUIDField(c, "repertoireId", req.RepertoireID) {
+			return nil
+		}
+
+		tmpl, err := svc.PublishTemplate(user.ID, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrNotFound), errors.Is(err, services.ErrForbidden):
+				return AccessErrorResponse(c, err, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to publish template")
+		}
+		return c.JSON(http.StatusCreated, tmpl)
+	}
+}
+
+// SetTemplateFeaturedHandler features or unfeatures a template (admins only)
+// PUT /api/repertoires/templates/:id/featured
+func SetTemplateFeaturedHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req struct {
+			Featured bool `json:"featured"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+
+		tmpl, err := svc.SetTemplateFeatured(c.Param("id"), req.Featured)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to update template")
+		}
+		return c.JSON(http.StatusOK, tmpl)
+	}
+}
+
 // SeedHandler creates starter repertoires from templates
 // POST /api/repertoires/seed
 func SeedHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 
 		var req struct {
 			TemplateIDs []string `json:"templateIds"`
 		}
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		if len(req.TemplateIDs) == 0 {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "templateIds is required",
-			})
+			return BadRequestResponse(c, "templateIds is required")
 		}
 
-		repertoires, err := svc.SeedRepertoires(userID, req.TemplateIDs)
+		repertoires, err := svc.SeedRepertoires(user.ID, req.TemplateIDs)
 		if err != nil {
 			if errors.Is(err, services.ErrLimitReached) {
-				return c.JSON(http.StatusConflict, map[string]string{
-					"error": "maximum repertoire limit reached (50)",
-				})
+				return ErrorCodeResponse(c, http.StatusConflict, models.ErrCodeRepertoireLimitReached, err.Error())
 			}
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": err.Error(),
-			})
+			return BadRequestResponse(c, err.Error())
 		}
 
 		return c.JSON(http.StatusCreated, repertoires)
@@ -340,150 +449,120 @@ func SeedHandler(svc *services.RepertoireService) echo.HandlerFunc {
 // POST /api/repertoires/:id/extract
 func ExtractSubtreeHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		var req models.ExtractSubtreeRequest
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		if req.NodeID == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "nodeId is required",
-			})
+			return BadRequestResponse(c, "nodeId is required")
 		}
 
 		// Validate nodeId is a valid UUID
 		if _, err := uuid.Parse(req.NodeID); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "nodeId must be a valid UUID",
-			})
+			return BadRequestResponse(c, "nodeId must be a valid UUID")
 		}
 
-		result, err := svc.ExtractSubtree(userID, idParam, req.NodeID, req.Name)
+		result, err := svc.ExtractSubtree(user.ID, idParam, req.NodeID, req.Name)
 		if err != nil {
-			if errors.Is(err, services.ErrCannotExtractRoot) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "cannot extract root node",
-				})
-			}
-			if errors.Is(err, services.ErrNodeNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "node not found",
-				})
-			}
-			if errors.Is(err, services.ErrLimitReached) {
-				return c.JSON(http.StatusConflict, map[string]string{
-					"error": "maximum repertoire limit reached (50)",
-				})
-			}
-			if errors.Is(err, services.ErrNameTooLong) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name must be 100 characters or less",
-				})
-			}
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to extract subtree",
-			})
+			return ServiceErrorResponse(c, err, "failed to extract subtree")
 		}
 
 		return c.JSON(http.StatusCreated, result)
 	}
 }
 
+// MirrorRepertoireHandler creates a copy of a repertoire for the opposite color
+// POST /api/repertoires/:id/mirror
+func MirrorRepertoireHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+		idParam := c.Param("id")
+
+		if _, err := uuid.Parse(idParam); err != nil {
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
+		}
+
+		if err := svc.CheckOwner(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		var req models.MirrorRepertoireRequest
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+
+		mirrored, err := svc.MirrorRepertoire(user.ID, idParam, req.Name)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to mirror repertoire")
+		}
+
+		return c.JSON(http.StatusCreated, mirrored)
+	}
+}
+
 // MergeRepertoiresHandler creates a new repertoire by merging multiple source repertoires
 // POST /api/repertoires/merge
 func MergeRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 
 		var req models.MergeRepertoiresRequest
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		if len(req.IDs) < 2 {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "at least two repertoire IDs are required",
-			})
+			return BadRequestResponse(c, "at least two repertoire IDs are required")
 		}
 
 		if req.Name == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "name is required",
-			})
+			return BadRequestResponse(c, "name is required")
 		}
 
 		// Validate all IDs are valid UUIDs and check ownership
 		for _, id := range req.IDs {
 			if _, err := uuid.Parse(id); err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "all IDs must be valid UUIDs",
-				})
+				return BadRequestResponse(c, "all IDs must be valid UUIDs")
 			}
-			if err := svc.CheckOwnership(id, userID); err != nil {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+			if err := svc.CheckOwner(id, user.ID); err != nil {
+				return AccessErrorResponse(c, err, "repertoire")
 			}
 		}
 
-		result, err := svc.MergeRepertoires(userID, req.IDs, req.Name)
+		result, err := svc.MergeRepertoires(user.ID, req.IDs, req.Name)
 		if err != nil {
-			if errors.Is(err, services.ErrMergeMinimumTwo) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": err.Error(),
-				})
-			}
-			if errors.Is(err, services.ErrMergeColorMismatch) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "cannot merge repertoires of different colors",
-				})
-			}
-			if errors.Is(err, services.ErrMergeDuplicateIDs) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "duplicate repertoire IDs",
-				})
-			}
-			if errors.Is(err, services.ErrNameRequired) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name is required",
-				})
-			}
-			if errors.Is(err, services.ErrNameTooLong) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "name must be 100 characters or less",
-				})
-			}
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to merge repertoires",
-			})
+			return ServiceErrorResponse(c, err, "failed to merge repertoires")
 		}
 
 		return c.JSON(http.StatusCreated, result)
@@ -494,30 +573,24 @@ func MergeRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
 // POST /api/repertoires/:id/merge-transpositions
 func MergeTranspositionsHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		rep, err := svc.MergeTranspositions(idParam)
 		if err != nil {
-			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
-			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to merge transpositions",
-			})
+			return ServiceErrorResponse(c, err, "failed to merge transpositions")
 		}
 
 		return c.JSON(http.StatusOK, rep)
@@ -528,52 +601,40 @@ func MergeTranspositionsHandler(svc *services.RepertoireService) echo.HandlerFun
 // PATCH /api/repertoires/:id/nodes/:nodeId/comment
 func UpdateNodeCommentHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 		nodeID := c.Param("nodeId")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
 		// Validate nodeId is a valid UUID
 		if _, err := uuid.Parse(nodeID); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "node id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "node id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		var req struct {
 			Comment string `json:"comment"`
 		}
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		rep, err := svc.UpdateNodeComment(idParam, nodeID, req.Comment)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			if errors.Is(err, services.ErrNodeNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "node not found",
-				})
-			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to update comment",
-			})
+			return ServiceErrorResponse(c, err, "failed to update comment")
 		}
 
 		return c.JSON(http.StatusOK, rep)
@@ -584,52 +645,40 @@ func UpdateNodeCommentHandler(svc *services.RepertoireService) echo.HandlerFunc
 // PATCH /api/repertoires/:id/nodes/:nodeId/branch-name
 func UpdateNodeBranchNameHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 		nodeID := c.Param("nodeId")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
 		// Validate nodeId is a valid UUID
 		if _, err := uuid.Parse(nodeID); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "node id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "node id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		var req struct {
 			BranchName string `json:"branchName"`
 		}
 		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "invalid request body",
-			})
+			return BadRequestResponse(c, "invalid request body")
 		}
 
 		rep, err := svc.UpdateNodeBranchName(idParam, nodeID, req.BranchName)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
-			}
-			if errors.Is(err, services.ErrNodeNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "node not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to update branch name",
-			})
+			return ServiceErrorResponse(c, err, "failed to update branch name")
 		}
 
 		return c.JSON(http.StatusOK, rep)
@@ -640,43 +689,33 @@ func UpdateNodeBranchNameHandler(svc *services.RepertoireService) echo.HandlerFu
 // POST /api/repertoires/:id/nodes/:nodeId/toggle-collapsed
 func ToggleNodeCollapsedHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 		nodeID := c.Param("nodeId")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
 		// Validate nodeId is a valid UUID
 		if _, err := uuid.Parse(nodeID); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "node id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "node id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		rep, err := svc.ToggleNodeCollapsed(idParam, nodeID)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
-			}
-			if errors.Is(err, services.ErrNodeNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "node not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to toggle collapsed state",
-			})
+			return ServiceErrorResponse(c, err, "failed to toggle collapsed state")
 		}
 
 		return c.JSON(http.StatusOK, rep)
@@ -687,50 +726,607 @@ func ToggleNodeCollapsedHandler(svc *services.RepertoireService) echo.HandlerFun
 // DELETE /api/repertoire/:id/node/:nodeId
 func DeleteNodeHandler(svc *services.RepertoireService) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userID := c.Get("userID").(string)
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
 		idParam := c.Param("id")
 		nodeID := c.Param("nodeId")
 
 		// Validate repertoire ID is a valid UUID
 		if _, err := uuid.Parse(idParam); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "repertoire id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "repertoire id must be a valid UUID")
 		}
 
 		// Validate nodeId is a valid UUID
 		if _, err := uuid.Parse(nodeID); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "node id must be a valid UUID",
-			})
+			return BadRequestResponse(c, "node id must be a valid UUID")
 		}
 
-		if err := svc.CheckOwnership(idParam, userID); err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "repertoire not found"})
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
 		}
 
 		rep, err := svc.DeleteNode(idParam, nodeID)
 		if err != nil {
 			if errors.Is(err, services.ErrNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "repertoire not found",
-				})
+				return NotFoundResponse(c, "repertoire")
 			}
-			if errors.Is(err, services.ErrCannotDeleteRoot) {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "cannot delete root node",
-				})
+			return ServiceErrorResponse(c, err, "failed to delete node")
+		}
+
+		return c.JSON(http.StatusOK, rep)
+	}
+}
+
+// UpdateNodeTagsHandler replaces the tags of a specific node
+// PATCH /api/repertoires/:id/nodes/:nodeId/tags
+func UpdateNodeTagsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		nodeID, ok := ValidateUUIDParam(c, "nodeId")
+		if !ok {
+			return nil
+		}
+
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		var req struct {
+			Tags []string `json:"tags"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+
+		rep, err := svc.UpdateNodeTags(idParam, nodeID, req.Tags)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrNotFound):
+				return NotFoundResponse(c, "repertoire")
 			}
-			if errors.Is(err, services.ErrNodeNotFound) {
-				return c.JSON(http.StatusNotFound, map[string]string{
-					"error": "node not found",
-				})
+			return ServiceErrorResponse(c, err, "failed to update tags")
+		}
+
+		return c.JSON(http.StatusOK, rep)
+	}
+}
+
+// ReorderChildrenHandler sets the order of a node's children
+// PATCH /api/repertoires/:id/nodes/:nodeId/reorder
+func ReorderChildrenHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		nodeID, ok := ValidateUUIDParam(c, "nodeId")
+		if !ok {
+			return nil
+		}
+
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		var req struct {
+			ChildIDs []string `json:"childIds"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+
+		rep, err := svc.ReorderChildren(idParam, nodeID, req.ChildIDs)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrNotFound):
+				return NotFoundResponse(c, "repertoire")
 			}
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "failed to delete node",
-			})
+			return ServiceErrorResponse(c, err, "failed to reorder moves")
 		}
 
 		return c.JSON(http.StatusOK, rep)
 	}
 }
+
+// ListLinesHandler enumerates the lines of a repertoire, optionally only those with given tags
+// GET /api/repertoires/:id/lines?tags=critical,gambit
+func ListLinesHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		lines, err := svc.ListLines(idParam, services.ParseTagsParam(c.QueryParam("tags")))
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return InternalErrorResponse(c, "failed to list lines")
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"lines": lines,
+		})
+	}
+}
+
+// ExportStudySheetHandler downloads a repertoire as a printable study sheet
+// GET /api/repertoires/:id/export?format=markdown|html
+func ExportStudySheetHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		format := c.QueryParam("format")
+		if format == "" {
+			format = services.StudySheetMarkdown
+		}
+		sheet, err := svc.ExportStudySheet(idParam, format)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrNotFound):
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to export repertoire")
+		}
+
+		filename, contentType := "repertoire.md", "text/markdown; charset=utf-8"
+		if format == services.StudySheetHTML {
+			filename, contentType = "repertoire.html", echo.MIMETextHTMLCharsetUTF8
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+		return c.Blob(http.StatusOK, contentType, []byte(sheet))
+	}
+}
+
+// ExportJSONHandler downloads a repertoire in the versioned JSON format, for backups and for
+// moving it to another instance
+// GET /api/repertoires/:id/export.json
+func ExportJSONHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		export, err := svc.ExportJSON(idParam)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to export repertoire")
+		}
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="repertoire.json"`)
+		return c.JSON(http.StatusOK, export)
+	}
+}
+
+// ExportCommentsCSVHandler downloads the comments of a repertoire as CSV keyed by move path,
+// for editing them in bulk in a spreadsheet
+// GET /api/repertoires/:id/comments.csv
+func ExportCommentsCSVHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		data, err := svc.ExportCommentsCSV(idParam)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return InternalErrorResponse(c, "failed to export comments")
+		}
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="comments.csv"`)
+		return c.Blob(http.StatusOK, "text/csv; charset=utf-8", data)
+	}
+}
+
+// ImportCommentsHandler sets the comments of a repertoire from an uploaded CSV in the export format
+// POST /api/repertoires/:id/comments
+func ImportCommentsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return BadRequestResponse(c, "file is required")
+		}
+		if !strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
+			return BadRequestResponse(c, "file must have .csv extension")
+		}
+		if file.Size > config.MaxCommentsCSVSize {
+			return ErrorResponse(c, http.StatusRequestEntityTooLarge, "file exceeds maximum allowed size")
+		}
+		src, err := file.Open()
+		if err != nil {
+			return InternalErrorResponse(c, "failed to read file")
+		}
+		defer src.Close()
+		data, err := io.ReadAll(io.LimitReader(src, config.MaxCommentsCSVSize+1))
+		if err != nil {
+			return InternalErrorResponse(c, "failed to read file content")
+		}
+		if len(data) > config.MaxCommentsCSVSize {
+			return ErrorResponse(c, http.StatusRequestEntityTooLarge, "file exceeds maximum allowed size")
+		}
+
+		result, err := svc.ImportCommentsCSV(idParam, data)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to import comments")
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// ImportJSONHandler creates a repertoire from a JSON export
+// POST /api/repertoires/import-json
+func ImportJSONHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		var export models.RepertoireExport
+		if err := c.Bind(&export); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+
+		rep, err := svc.ImportJSON(user.ID, &export)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to import repertoire")
+		}
+
+		return c.JSON(http.StatusCreated, rep)
+	}
+}
+
+// TrainingPositionsHandler selects positions to drill, optionally only tagged ones
+// GET /api/repertoires/:id/training?tags=critical&limit=20
+func TrainingPositionsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		limit := ParseIntQueryParam(c, "limit", config.DefaultTrainingPositions, 1, config.MaxTrainingPositions)
+		positions, err := svc.TrainingPositions(idParam, services.ParseTagsParam(c.QueryParam("tags")), limit)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return InternalErrorResponse(c, "failed to select training positions")
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"positions": positions,
+		})
+	}
+}
+
+// RepertoireMetricsHandler returns depth, branching and annotation statistics for a repertoire
+// GET /api/repertoires/:id/metrics
+func RepertoireMetricsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		metrics, err := svc.Metrics(idParam)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return InternalErrorResponse(c, "failed to compute repertoire metrics")
+		}
+
+		return c.JSON(http.StatusOK, metrics)
+	}
+}
+
+// SearchRepertoiresHandler searches the user's node comments and repertoire names
+// GET /api/repertoires/search?q=trap
+func SearchRepertoiresHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		results, err := svc.Search(user.ID, c.QueryParam("q"))
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to search repertoires")
+		}
+
+		return c.JSON(http.StatusOK, results)
+	}
+}
+
+// ListRevisionsHandler lists the kept revisions of a repertoire, newest first
+// GET /api/repertoires/:id/revisions
+func ListRevisionsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		revisions, err := svc.ListRevisions(idParam)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to list revisions")
+		}
+
+		return c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// DiffRevisionsHandler compares two revisions of a repertoire
+// GET /api/repertoires/:id/revisions/:revA/diff/:revB
+func DiffRevisionsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		from, ok := parseVersionParam(c, "revA")
+		if !ok {
+			return nil
+		}
+		to, ok := parseVersionParam(c, "revB")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		diff, err := svc.DiffRevisions(idParam, from, to)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to compare revisions")
+		}
+
+		return c.JSON(http.StatusOK, diff)
+	}
+}
+
+// RestoreRevisionHandler makes an earlier revision of a repertoire the current tree
+// POST /api/repertoires/:id/revisions/:rev/restore
+func RestoreRevisionHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		version, ok := parseVersionParam(c, "rev")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		rep, err := svc.RestoreRevision(idParam, version)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to restore revision")
+		}
+
+		return c.JSON(http.StatusOK, rep)
+	}
+}
+
+// parseVersionParam reads a revision version from the path
+// Returns false after sending an error response when it is not a positive integer
+func parseVersionParam(c echo.Context, paramName string) (int, bool) {
+	version, err := strconv.Atoi(c.Param(paramName))
+	if err != nil || version < 1 {
+		BadRequestResponse(c, paramName+" must be a positive integer")
+		return 0, false
+	}
+	return version, true
+}
+
+// GetStudyNotesHandler returns the study notes of a repertoire, or one of their earlier versions
+// GET /api/repertoires/:id/notes
+// GET /api/repertoires/:id/notes/revisions/:rev
+func GetStudyNotesHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		version := 0
+		if c.Param("rev") != "" {
+			if version, ok = parseVersionParam(c, "rev"); !ok {
+				return nil
+			}
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		notes, err := svc.GetStudyNotes(idParam, version)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to get study notes")
+		}
+
+		return c.JSON(http.StatusOK, notes)
+	}
+}
+
+// SaveStudyNotesHandler replaces the study notes of a repertoire
+// PUT /api/repertoires/:id/notes
+func SaveStudyNotesHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+
+		var req struct {
+			Content *string `json:"content"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return BadRequestResponse(c, "invalid request body")
+		}
+		if req.Content == nil {
+			return BadRequestResponse(c, "content is required")
+		}
+		if err := svc.CheckOwnership(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		notes, err := svc.SaveStudyNotes(idParam, user.ID, *req.Content)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return NotFoundResponse(c, "repertoire")
+			}
+			return ServiceErrorResponse(c, err, "failed to save study notes")
+		}
+
+		return c.JSON(http.StatusOK, notes)
+	}
+}
+
+// ListStudyNotesRevisionsHandler lists the kept versions of the study notes of a repertoire
+// GET /api/repertoires/:id/notes/revisions
+func ListStudyNotesRevisionsHandler(svc *services.RepertoireService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := CurrentUser(c)
+		if !ok {
+			return nil
+		}
+
+		idParam, ok := ValidateUUIDParam(c, "id")
+		if !ok {
+			return nil
+		}
+		if err := svc.CheckReadAccess(idParam, user.ID); err != nil {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
+
+		revisions, err := svc.ListStudyNotesRevisions(idParam)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "failed to list study notes revisions")
+		}
+
+		return c.JSON(http.StatusOK, revisions)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+type SparringHandler struct {
+	sparringService   *services.SparringService
+	repertoireService *services.RepertoireService
+}
+
+func NewSparringHandler(sparringSvc *services.SparringService, repertoireSvc *services.RepertoireService) *SparringHandler {
+	return &SparringHandler{sparringService: sparringSvc, repertoireService: repertoireSvc}
+}
+
+// StartHandler starts a sparring game where the server plays the opponent's replies
+// POST /api/sparring/start
+func (h *SparringHandler) StartHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.SparringStartRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if !RequireField(c, "repertoireId", req.RepertoireID) || !ValidateUUIDField(c, "repertoireId", req.RepertoireID) {
+		return nil
+	}
+	if err := h.repertoireService.CheckReadAccess(req.RepertoireID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "repertoire")
+	}
+
+	session, err := h.sparringService.Start(user.ID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to start sparring")
+	}
+	return c.JSON(http.StatusCreated, session)
+}
+
+// MoveHandler plays the user's move in a sparring game and answers with the opponent's reply
+// POST /api/sparring/move
+func (h *SparringHandler) MoveHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.SparringMoveRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+	if !RequireField(c, "sessionId", req.SessionID) {
+		return nil
+	}
+
+	session, err := h.sparringService.Move(user.ID, req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to play sparring move")
+	}
+	return c.JSON(http.StatusOK, session)
+}
@@ -23,7 +23,8 @@ func NewStudyImportHandler(studyImportSvc *services.StudyImportService) *StudyIm
 	}
 }
 
-// PreviewStudyHandler handles GET /api/studies/preview?url={lichessStudyUrl}
+// PreviewStudyHandler handles GET /api/studies/preview?url={lichessStudyUrl}&targetRepertoireId={id}
+// With a target repertoire, each chapter reports the moves it would add to it.
 func (h *StudyImportHandler) PreviewStudyHandler(c echo.Context) error {
 	rawURL := c.QueryParam("url")
 	if !RequireField(c, "url", rawURL) {
@@ -35,21 +36,32 @@ func (h *StudyImportHandler) PreviewStudyHandler(c echo.Context) error {
 		return BadRequestResponse(c, "invalid Lichess study URL")
 	}
 
-	userID := c.Get("userID").(string)
-	authToken := h.studyImportService.GetLichessTokenForUser(userID)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	authToken := h.studyImportService.GetLichessTokenForUser(user.ID)
 
-	info, err := h.studyImportService.PreviewStudy(studyID, authToken)
+	var info *models.StudyInfo
+	if targetID := c.QueryParam("targetRepertoireId"); targetID != "" {
+		info, err = h.studyImportService.PreviewStudyInto(user.ID, studyID, authToken, targetID)
+	} else {
+		info, err = h.studyImportService.PreviewStudy(studyID, authToken)
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, services.ErrForbidden) {
+			return AccessErrorResponse(c, err, "repertoire")
+		}
 		if errors.Is(err, services.ErrLichessStudyNotFound) {
 			return NotFoundResponse(c, "Lichess study")
 		}
 		if errors.Is(err, services.ErrLichessStudyForbidden) {
-			return ErrorResponse(c, http.StatusForbidden, "this study is private; link your Lichess account to access it")
+			return ErrorCodeResponse(c, http.StatusForbidden, models.ErrCodePrivateStudy, "this study is private; link your Lichess account to access it")
 		}
 		if errors.Is(err, services.ErrLichessRateLimited) {
-			return ErrorResponse(c, http.StatusTooManyRequests, "Lichess rate limit exceeded, try again later")
+			return ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Lichess rate limit exceeded, try again later")
 		}
-		log.Printf("Study preview error for user %s: %v", userID, err)
+		log.Printf("Study preview error for user %s: %v", user.ID, err)
 		return BadRequestResponse(c, "failed to fetch study from Lichess")
 	}
 
@@ -75,53 +87,94 @@ func (h *StudyImportHandler) ImportStudyHandler(c echo.Context) error {
 	if len(req.ChapterIndices) == 0 {
 		return BadRequestResponse(c, "at least one chapter must be selected")
 	}
+	if req.TargetRepertoireID != "" && (req.MergeAsOne || req.CreateCategory) {
+		return BadRequestResponse(c, "targetRepertoireId cannot be combined with mergeAsOne or createCategory")
+	}
 
-	userID := c.Get("userID").(string)
-	authToken := h.studyImportService.GetLichessTokenForUser(userID)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	authToken := h.studyImportService.GetLichessTokenForUser(user.ID)
+
+	if req.TargetRepertoireID != "" {
+		merged, err := h.studyImportService.ImportStudyChaptersInto(user.ID, studyID, authToken, req.ChapterIndices, req.TargetRepertoireID)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) || errors.Is(err, services.ErrForbidden) {
+				return AccessErrorResponse(c, err, "repertoire")
+			}
+			if errors.Is(err, services.ErrLichessStudyNotFound) {
+				return NotFoundResponse(c, "Lichess study")
+			}
+			if errors.Is(err, services.ErrLichessStudyForbidden) {
+				return ErrorCodeResponse(c, http.StatusForbidden, models.ErrCodePrivateStudy, "this study is private; link your Lichess account to access it")
+			}
+			if errors.Is(err, services.ErrLichessRateLimited) {
+				return ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Lichess rate limit exceeded, try again later")
+			}
+			if errors.Is(err, services.ErrMixedColors) {
+				return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeColorMismatch, "chapters must be played from the color of the target repertoire")
+			}
+			if errors.Is(err, services.ErrRepertoireArchived) {
+				return ServiceErrorResponse(c, err, "failed to import study")
+			}
+			log.Printf("Study import into repertoire %s error for user %s: %v", req.TargetRepertoireID, user.ID, err)
+			return BadRequestResponse(c, "failed to import study")
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"repertoires": []models.Repertoire{*merged},
+			"count":       1,
+		})
+	}
 
 	if req.MergeAsOne {
-		merged, err := h.studyImportService.ImportStudyChaptersMerged(userID, studyID, authToken, req.ChapterIndices, req.MergeName)
+		result, err := h.studyImportService.ImportStudyChaptersMergedWithCategory(user.ID, studyID, authToken, req.ChapterIndices, req.MergeName, req.CreateCategory, req.CategoryName)
 		if err != nil {
 			if errors.Is(err, services.ErrLichessStudyNotFound) {
 				return NotFoundResponse(c, "Lichess study")
 			}
 			if errors.Is(err, services.ErrLichessStudyForbidden) {
-				return ErrorResponse(c, http.StatusForbidden, "this study is private; link your Lichess account to access it")
+				return ErrorCodeResponse(c, http.StatusForbidden, models.ErrCodePrivateStudy, "this study is private; link your Lichess account to access it")
 			}
 			if errors.Is(err, services.ErrLichessRateLimited) {
-				return ErrorResponse(c, http.StatusTooManyRequests, "Lichess rate limit exceeded, try again later")
+				return ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Lichess rate limit exceeded, try again later")
 			}
 			if errors.Is(err, services.ErrLimitReached) {
-				return BadRequestResponse(c, "maximum repertoire limit reached")
+				return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeRepertoireLimitReached, "maximum repertoire limit reached")
 			}
 			if errors.Is(err, services.ErrMixedColors) {
-				return BadRequestResponse(c, "cannot merge chapters with different colors (white/black)")
+				return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeColorMismatch, "cannot merge chapters with different colors (white/black)")
 			}
-			log.Printf("Study merged import error for user %s: %v", userID, err)
+			log.Printf("Study merged import error for user %s: %v", user.ID, err)
 			return BadRequestResponse(c, "failed to import study")
 		}
 
-		return c.JSON(http.StatusCreated, map[string]interface{}{
-			"repertoires": []models.Repertoire{*merged},
+		response := map[string]interface{}{
+			"repertoires": result.Repertoires,
 			"count":       1,
-		})
+		}
+		if result.Category != nil {
+			response["category"] = result.Category
+		}
+		return c.JSON(http.StatusCreated, response)
 	}
 
-	result, err := h.studyImportService.ImportStudyChaptersWithCategory(userID, studyID, authToken, req.ChapterIndices, req.CreateCategory, req.CategoryName)
+	result, err := h.studyImportService.ImportStudyChaptersWithCategory(user.ID, studyID, authToken, req.ChapterIndices, req.CreateCategory, req.CategoryName)
 	if err != nil {
 		if errors.Is(err, services.ErrLichessStudyNotFound) {
 			return NotFoundResponse(c, "Lichess study")
 		}
 		if errors.Is(err, services.ErrLichessStudyForbidden) {
-			return ErrorResponse(c, http.StatusForbidden, "this study is private; link your Lichess account to access it")
+			return ErrorCodeResponse(c, http.StatusForbidden, models.ErrCodePrivateStudy, "this study is private; link your Lichess account to access it")
 		}
 		if errors.Is(err, services.ErrLichessRateLimited) {
-			return ErrorResponse(c, http.StatusTooManyRequests, "Lichess rate limit exceeded, try again later")
+			return ErrorCodeResponse(c, http.StatusTooManyRequests, models.ErrCodeUpstreamRateLimited, "Lichess rate limit exceeded, try again later")
 		}
 		if errors.Is(err, services.ErrLimitReached) {
-			return BadRequestResponse(c, "maximum repertoire limit reached")
+			return ErrorCodeResponse(c, http.StatusBadRequest, models.ErrCodeRepertoireLimitReached, "maximum repertoire limit reached")
 		}
-		log.Printf("Study import error for user %s: %v", userID, err)
+		log.Printf("Study import error for user %s: %v", user.ID, err)
 		return BadRequestResponse(c, "failed to import study")
 	}
 
@@ -39,7 +39,7 @@ func TestPreviewStudyHandler_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/studies/preview?url=https://lichess.org/study/abcdefgh", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	err := handler.PreviewStudyHandler(c)
 
@@ -59,7 +59,7 @@ func TestPreviewStudyHandler_MissingURL(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/studies/preview", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.PreviewStudyHandler(c)
 
@@ -73,7 +73,7 @@ func TestPreviewStudyHandler_InvalidURL(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/studies/preview?url=not-a-valid-url", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.PreviewStudyHandler(c)
 
@@ -92,7 +92,7 @@ func TestPreviewStudyHandler_NotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/studies/preview?url=https://lichess.org/study/abcdefgh", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.PreviewStudyHandler(c)
 
@@ -111,7 +111,7 @@ func TestPreviewStudyHandler_Forbidden(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/studies/preview?url=https://lichess.org/study/abcdefgh", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.PreviewStudyHandler(c)
 
@@ -130,7 +130,7 @@ func TestPreviewStudyHandler_RateLimited(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/studies/preview?url=https://lichess.org/study/abcdefgh", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.PreviewStudyHandler(c)
 
@@ -164,7 +164,7 @@ func TestImportStudyHandler_Success(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	err := handler.ImportStudyHandler(c)
 
@@ -181,7 +181,7 @@ func TestImportStudyHandler_MissingURL(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.ImportStudyHandler(c)
 
@@ -197,7 +197,7 @@ func TestImportStudyHandler_NoChapters(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.ImportStudyHandler(c)
 
@@ -228,9 +228,52 @@ func TestImportStudyHandler_LimitReached(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", testUserID)
+	setTestPrincipal(c, testUserID)
 
 	handler.ImportStudyHandler(c)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
+
+func TestImportStudyHandler_TargetRepertoire(t *testing.T) {
+	pgnData := `[Event "Study: Chapter 1"]
+[Orientation "White"]
+
+1. e4 e5 *
+`
+	mockLichess := &mocks.MockLichessService{
+		FetchStudyPGNFunc: func(studyID, authToken string) (string, error) {
+			return pgnData, nil
+		},
+	}
+	mockRepSvc := &mocks.MockRepertoireService{
+		CheckOwnershipFunc: func(id, userID string) error {
+			if id != "rep-1" {
+				return services.ErrNotFound
+			}
+			return nil
+		},
+		GetRepertoireFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, TreeData: models.RepertoireNode{ID: "root", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"}}, nil
+		},
+		SaveTreeFunc: func(repertoireID string, treeData models.RepertoireNode) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: repertoireID, TreeData: treeData}, nil
+		},
+	}
+	handler := newTestStudyImportHandler(mockLichess, mockRepSvc, &mocks.MockUserRepo{})
+
+	call := func(body string) int {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/studies/import", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		setTestPrincipal(c, testUserID)
+		require.NoError(t, handler.ImportStudyHandler(c))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(`{"studyUrl":"abcdefgh","chapters":[0],"targetRepertoireId":"rep-1"}`))
+	assert.Equal(t, http.StatusNotFound, call(`{"studyUrl":"abcdefgh","chapters":[0],"targetRepertoireId":"rep-2"}`))
+	assert.Equal(t, http.StatusBadRequest, call(`{"studyUrl":"abcdefgh","chapters":[0],"targetRepertoireId":"rep-1","mergeAsOne":true}`))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/services"
 )
 
@@ -16,13 +17,54 @@ func NewSyncHandler(syncSvc *services.SyncService) *SyncHandler {
 	return &SyncHandler{syncService: syncSvc}
 }
 
+// HandleSync starts a sync in the background and returns the run to poll
 func (h *SyncHandler) HandleSync(c echo.Context) error {
-	userID := c.Get("userID").(string)
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
 
-	result, err := h.syncService.Sync(userID)
+	run, err := h.syncService.StartSync(user.ID)
 	if err != nil {
 		return InternalErrorResponse(c, "failed to sync games")
 	}
 
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusAccepted, run)
+}
+
+// ListRunsHandler returns the user's recent sync runs
+func (h *SyncHandler) ListRunsHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	runs, err := h.syncService.ListRuns(user.ID)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to list sync runs")
+	}
+	if runs == nil {
+		runs = []models.SyncRun{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"runs": runs})
+}
+
+// GetRunHandler returns a single sync run with its per-account results
+func (h *SyncHandler) GetRunHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	id, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+
+	run, err := h.syncService.GetRun(id, user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get sync run")
+	}
+
+	return c.JSON(http.StatusOK, run)
 }
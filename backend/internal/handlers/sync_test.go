@@ -19,10 +19,8 @@ import (
 
 func TestHandleSync_Success(t *testing.T) {
 	lichessUser := "lichessplayer"
-	user := &models.User{
-		ID:             "user-1",
-		LichessUsername: &lichessUser,
-	}
+	user := &models.User{ID: "user-1"}
+	user.SetLinkedAccounts([]models.LinkedAccount{{ID: "acc-1", Provider: models.ProviderLichess, Username: lichessUser}})
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -39,23 +37,41 @@ func TestHandleSync_Success(t *testing.T) {
 		},
 	}
 
+	finished := make(chan *models.SyncRun, 1)
+	mockRunRepo := &mocks.MockSyncRunRepo{
+		FinishFunc: func(run *models.SyncRun) error {
+			finished <- run
+			return nil
+		},
+	}
+
 	syncSvc := services.NewSyncService(mockUserRepo, mockImport, mockLichess, &mocks.MockChesscomService{})
+	syncSvc.WithRunHistory(mockRunRepo)
 	handler := NewSyncHandler(syncSvc)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/api/sync", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-1")
+	setTestPrincipal(c, "user-1")
 
 	err := handler.HandleSync(c)
 
 	require.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	var run models.SyncRun
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, "run-1", run.ID)
+	assert.Equal(t, models.SyncRunRunning, run.Status)
 
-	var result models.SyncResult
-	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
-	assert.Equal(t, 5, result.LichessGamesImported)
+	select {
+	case done := <-finished:
+		assert.Equal(t, models.SyncRunDone, done.Status)
+		assert.Equal(t, 5, done.LichessGamesImported)
+	case <-time.After(time.Second):
+		t.Fatal("sync run was not finished")
+	}
 }
 
 func TestHandleSync_Error(t *testing.T) {
@@ -66,15 +82,59 @@ func TestHandleSync_Error(t *testing.T) {
 	}
 
 	syncSvc := services.NewSyncService(mockUserRepo, &mocks.MockImportService{}, &mocks.MockLichessService{}, &mocks.MockChesscomService{})
+	syncSvc.WithRunHistory(&mocks.MockSyncRunRepo{})
 	handler := NewSyncHandler(syncSvc)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/api/sync", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("userID", "user-1")
+	setTestPrincipal(c, "user-1")
 
 	handler.HandleSync(c)
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 }
+
+func TestGetRunHandler_OtherUser(t *testing.T) {
+	runID := "123e4567-e89b-12d3-a456-426614174000"
+	mockRunRepo := &mocks.MockSyncRunRepo{
+		GetByIDFunc: func(id string) (*models.SyncRun, error) {
+			return &models.SyncRun{ID: id, UserID: "someone-else", Status: models.SyncRunDone}, nil
+		},
+	}
+	syncSvc := services.NewSyncService(&mocks.MockUserRepo{}, &mocks.MockImportService{}, &mocks.MockLichessService{}, &mocks.MockChesscomService{})
+	syncSvc.WithRunHistory(mockRunRepo)
+	handler := NewSyncHandler(syncSvc)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/runs/"+runID, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(runID)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.GetRunHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestListRunsHandler_Empty(t *testing.T) {
+	syncSvc := services.NewSyncService(&mocks.MockUserRepo{}, &mocks.MockImportService{}, &mocks.MockLichessService{}, &mocks.MockChesscomService{})
+	syncSvc.WithRunHistory(&mocks.MockSyncRunRepo{})
+	handler := NewSyncHandler(syncSvc)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/runs", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestPrincipal(c, "user-1")
+
+	err := handler.ListRunsHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"runs":[]}`, rec.Body.String())
+}
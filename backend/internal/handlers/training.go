@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+type TrainingHandler struct {
+	trainingService   *services.TrainingService
+	repertoireService *services.RepertoireService
+}
+
+func NewTrainingHandler(trainingSvc *services.TrainingService, repertoireSvc *services.RepertoireService) *TrainingHandler {
+	return &TrainingHandler{trainingService: trainingSvc, repertoireService: repertoireSvc}
+}
+
+// AnswerHandler checks the move played in a training position and records it in the user's activity
+// POST /api/repertoires/:id/training/answers
+func (h *TrainingHandler) AnswerHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	repertoireID, ok := ValidateUUIDParam(c, "id")
+	if !ok {
+		return nil
+	}
+	if err := h.repertoireService.CheckReadAccess(repertoireID, user.ID); err != nil {
+		return AccessErrorResponse(c, err, "repertoire")
+	}
+
+	var req models.TrainingAnswerRequest
+	if err := c.Bind(&req); err != nil {
+		return BadRequestResponse(c, "invalid request body")
+	}
+
+	result, err := h.trainingService.Answer(user.ID, repertoireID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			return NotFoundResponse(c, "repertoire")
+		}
+		return ServiceErrorResponse(c, err, "failed to record training answer")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ActivityHandler returns the user's training calendar and streaks
+// GET /api/training/activity?days=365
+func (h *TrainingHandler) ActivityHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	days := ParseIntQueryParam(c, "days", config.DefaultTrainingDays, 1, config.MaxTrainingDays)
+	activity, err := h.trainingService.Activity(user.ID, days)
+	if err != nil {
+		return InternalErrorResponse(c, "failed to get training activity")
+	}
+
+	return c.JSON(http.StatusOK, activity)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+	"github.com/treechess/backend/internal/services"
+)
+
+const trainingRepertoireID = "123e4567-e89b-12d3-a456-426614174000"
+
+func newTrainingHandler() *TrainingHandler {
+	e4 := "e4"
+	repertoireSvc := services.NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return true, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, TreeData: models.RepertoireNode{
+				ID: "root", ColorToMove: models.ChessColorWhite,
+				Children: []*models.RepertoireNode{{ID: "e4", Move: &e4, ColorToMove: models.ChessColorBlack}},
+			}}, nil
+		},
+	})
+	return NewTrainingHandler(services.NewTrainingService(&mocks.MockTrainingRepo{}, repertoireSvc), repertoireSvc)
+}
+
+func TestTrainingAnswerHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		correct bool
+	}{
+		{"correct", `{"nodeId":"root","move":"e4"}`, http.StatusOK, true},
+		{"wrong", `{"nodeId":"root","move":"d4"}`, http.StatusOK, false},
+		{"opponent to move", `{"nodeId":"e4","move":"e5"}`, http.StatusBadRequest, false},
+		{"unknown node", `{"nodeId":"missing","move":"e4"}`, http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/repertoires/"+trainingRepertoireID+"/training/answers", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(trainingRepertoireID)
+			setTestUserID(c)
+
+			err := newTrainingHandler().AnswerHandler(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				var result models.TrainingAnswerResult
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				assert.Equal(t, tt.correct, result.Correct)
+				assert.Equal(t, []string{"e4"}, result.ExpectedMoves)
+			}
+		})
+	}
+}
+
+func TestTrainingActivityHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/training/activity?days=30", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	setTestUserID(c)
+
+	err := newTrainingHandler().ActivityHandler(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"days":[]`)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/services"
+)
+
+type UsageHandler struct {
+	usageService *services.UsageService
+}
+
+func NewUsageHandler(usageSvc *services.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageSvc}
+}
+
+// GetHandler returns the user's consumption of external services today against their quotas
+// GET /api/usage
+func (h *UsageHandler) GetHandler(c echo.Context) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+
+	report, err := h.usageService.Usage(user.ID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get usage")
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// TotalsHandler returns the consumption of external services by all users today
+// GET /api/admin/usage
+func (h *UsageHandler) TotalsHandler(c echo.Context) error {
+	totals, err := h.usageService.Totals()
+	if err != nil {
+		return ServiceErrorResponse(c, err, "failed to get usage totals")
+	}
+	return c.JSON(http.StatusOK, totals)
+}
@@ -6,10 +6,66 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
 	"github.com/treechess/backend/internal/services"
 )
 
-func JWTAuth(authSvc *services.AuthService) echo.MiddlewareFunc {
+// Principal is the authenticated user of a request
+type Principal struct {
+	ID        string
+	SessionID string   // Session of the token, empty for tokens issued without sessions
+	TokenID   string   // Personal API token of the request, empty for login tokens
+	Scopes    []string // Scopes granted by the token of the request
+	CoachID   string   // Coach reading the data of the student ID on their behalf, empty otherwise
+}
+
+// HasScope reports whether the token of the principal grants scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteScopes lists the routes needing another scope than the default of their method, read
+// for GET, HEAD and OPTIONS and write otherwise, keyed by method and route path such as
+// "POST /api/imports"
+type RouteScopes map[string]string
+
+// Required returns the scope the route of a request needs
+func (s RouteScopes) Required(c echo.Context) string {
+	method := c.Request().Method
+	if scope, ok := s[method+" "+c.Path()]; ok {
+		return scope
+	}
+	if isReadMethod(method) {
+		return models.ScopeRead
+	}
+	return models.ScopeWrite
+}
+
+const principalKey = "principal"
+
+// SetPrincipal stores the authenticated user in the request context
+func SetPrincipal(c echo.Context, p Principal) {
+	c.Set(principalKey, p)
+}
+
+// PrincipalFrom returns the authenticated user stored by JWTAuth, if any
+func PrincipalFrom(c echo.Context) (Principal, bool) {
+	p, ok := c.Get(principalKey).(Principal)
+	if !ok || p.ID == "" {
+		return Principal{}, false
+	}
+	return p, true
+}
+
+// JWTAuth rejects requests without a valid token or whose token lacks the scope their route
+// needs, and stores the token's user as the request Principal
+func JWTAuth(authSvc *services.AuthService, scopes RouteScopes) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			var tokenStr string
@@ -26,16 +82,56 @@ func JWTAuth(authSvc *services.AuthService) echo.MiddlewareFunc {
 			}
 
 			if tokenStr == "" {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return c.JSON(http.StatusUnauthorized, models.APIError{Code: models.ErrCodeUnauthorized, Message: "unauthorized"})
 			}
 
-			userID, err := authSvc.ValidateToken(tokenStr)
+			subject, err := authSvc.ValidateToken(tokenStr)
 			if err != nil {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return c.JSON(http.StatusUnauthorized, models.APIError{Code: models.ErrCodeUnauthorized, Message: "unauthorized"})
 			}
 
-			c.Set("userID", userID)
+			principal := Principal{
+				ID:        subject.UserID,
+				SessionID: subject.SessionID,
+				TokenID:   subject.TokenID,
+				Scopes:    subject.Scopes,
+			}
+			if required := scopes.Required(c); !principal.HasScope(required) {
+				return c.JSON(http.StatusForbidden, models.APIError{Code: models.ErrCodeForbidden, Message: "token lacks the " + required + " scope"})
+			}
+
+			SetPrincipal(c, principal)
 			return next(c)
 		}
 	}
 }
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// RequireAdmin only lets through users whose username is listed in admins, with a token
+// granting the admin scope. It must run after JWTAuth.
+func RequireAdmin(userRepo repository.UserRepository, admins []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := PrincipalFrom(c)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, models.APIError{Code: models.ErrCodeUnauthorized, Message: "unauthorized"})
+			}
+			if !principal.HasScope(models.ScopeAdmin) {
+				return c.JSON(http.StatusForbidden, models.APIError{Code: models.ErrCodeForbidden, Message: "token lacks the " + models.ScopeAdmin + " scope"})
+			}
+			user, err := userRepo.GetByID(principal.ID)
+			if err != nil {
+				return c.JSON(http.StatusForbidden, models.APIError{Code: models.ErrCodeForbidden, Message: "forbidden"})
+			}
+			for _, admin := range admins {
+				if strings.EqualFold(admin, user.Username) {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusForbidden, models.APIError{Code: models.ErrCodeForbidden, Message: "forbidden"})
+		}
+	}
+}
@@ -30,7 +30,7 @@ func generateTestToken(t *testing.T) string {
 		},
 	}
 	svc := services.NewAuthService(mockRepo, testJWTSecret, 24*time.Hour)
-	resp, err := svc.Register("test@example.com", "testuser", "password123")
+	resp, err := svc.Register("test@example.com", "testuser", "password123", models.SessionDevice{})
 	require.NoError(t, err)
 	_ = authSvc
 	return resp.Token
@@ -46,10 +46,11 @@ func TestJWTAuth_ValidToken(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	middleware := JWTAuth(authSvc)
+	middleware := JWTAuth(authSvc, nil)
 	handler := middleware(func(c echo.Context) error {
-		userID := c.Get("userID").(string)
-		assert.Equal(t, "user-123", userID)
+		principal, ok := PrincipalFrom(c)
+		assert.True(t, ok)
+		assert.Equal(t, "user-123", principal.ID)
 		return c.String(http.StatusOK, "ok")
 	})
 
@@ -67,7 +68,7 @@ func TestJWTAuth_MissingToken(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	middleware := JWTAuth(authSvc)
+	middleware := JWTAuth(authSvc, nil)
 	handler := middleware(func(c echo.Context) error {
 		t.Fatal("should not reach handler")
 		return nil
@@ -88,7 +89,7 @@ func TestJWTAuth_InvalidToken(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	middleware := JWTAuth(authSvc)
+	middleware := JWTAuth(authSvc, nil)
 	handler := middleware(func(c echo.Context) error {
 		t.Fatal("should not reach handler")
 		return nil
@@ -109,10 +110,11 @@ func TestJWTAuth_QueryParamFallback(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	middleware := JWTAuth(authSvc)
+	middleware := JWTAuth(authSvc, nil)
 	handler := middleware(func(c echo.Context) error {
-		userID := c.Get("userID").(string)
-		assert.Equal(t, "user-123", userID)
+		principal, ok := PrincipalFrom(c)
+		assert.True(t, ok)
+		assert.Equal(t, "user-123", principal.ID)
 		return c.String(http.StatusOK, "ok")
 	})
 
@@ -132,7 +134,7 @@ func TestJWTAuth_BearerPrefixStripping(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	mw := JWTAuth(authSvc)
+	mw := JWTAuth(authSvc, nil)
 	handler := mw(func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -142,3 +144,129 @@ func TestJWTAuth_BearerPrefixStripping(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
+
+func TestRequireAdmin(t *testing.T) {
+	userRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			return &models.User{ID: id, Username: map[string]string{"u1": "Alice", "u2": "bob"}[id]}, nil
+		},
+	}
+	mw := RequireAdmin(userRepo, []string{"alice"})
+	handler := mw(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	for userID, expected := range map[string]int{"u1": http.StatusOK, "u2": http.StatusForbidden} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/api/repertoires/templates/x/featured", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		SetPrincipal(c, Principal{ID: userID, Scopes: []string{models.ScopeRead, models.ScopeWrite, models.ScopeAdmin}})
+
+		require.NoError(t, handler(c))
+		assert.Equal(t, expected, rec.Code, userID)
+	}
+}
+
+func TestRequireAdmin_NoAdminScope(t *testing.T) {
+	userRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			return &models.User{ID: id, Username: "alice"}, nil
+		},
+	}
+	mw := RequireAdmin(userRepo, []string{"alice"})
+	handler := mw(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/api/repertoires/templates/x/featured", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	SetPrincipal(c, Principal{ID: "u1", TokenID: "token-1", Scopes: []string{models.ScopeRead, models.ScopeWrite}})
+
+	require.NoError(t, handler(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestRequireAdmin_NoPrincipal(t *testing.T) {
+	mw := RequireAdmin(&mocks.MockUserRepo{}, []string{"alice"})
+	handler := mw(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/api/repertoires/templates/x/featured", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	require.NoError(t, handler(c))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestJWTAuth_ReadOnlyAPIToken(t *testing.T) {
+	authSvc := newTestAuthService()
+	authSvc.WithAPITokens(&mocks.MockAPITokenRepo{
+		UseFunc: func(tokenHash string, usedAt time.Time) (*models.APIToken, error) {
+			return &models.APIToken{ID: "token-1", UserID: "user-123", Scope: models.APITokenScopeRead}, nil
+		},
+	})
+	handler := JWTAuth(authSvc, nil)(func(c echo.Context) error {
+		principal, ok := PrincipalFrom(c)
+		assert.True(t, ok)
+		assert.Equal(t, "token-1", principal.TokenID)
+		return c.String(http.StatusOK, "ok")
+	})
+
+	call := func(method string) int {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/api/repertoires", nil)
+		req.Header.Set("Authorization", "Bearer tc_0123456789abcdef")
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler(e.NewContext(req, rec)))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete))
+}
+
+func TestJWTAuth_RouteScopes(t *testing.T) {
+	authSvc := newTestAuthService()
+	authSvc.WithAPITokens(&mocks.MockAPITokenRepo{
+		UseFunc: func(tokenHash string, usedAt time.Time) (*models.APIToken, error) {
+			return &models.APIToken{ID: "token-1", UserID: "user-123", Scope: models.APITokenScopeImport}, nil
+		},
+	})
+	scopes := RouteScopes{
+		"POST /api/imports":              models.ScopeImport,
+		"POST /api/imports/validate-pgn": models.ScopeRead,
+	}
+	handler := JWTAuth(authSvc, scopes)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	call := func(token, method, path string) int {
+		e := echo.New()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath(path)
+		require.NoError(t, handler(c))
+		return rec.Code
+	}
+
+	apiToken := "tc_0123456789abcdef"
+	assert.Equal(t, http.StatusOK, call(apiToken, http.MethodGet, "/api/repertoires"))
+	assert.Equal(t, http.StatusOK, call(apiToken, http.MethodPost, "/api/imports"))
+	assert.Equal(t, http.StatusOK, call(apiToken, http.MethodPost, "/api/imports/validate-pgn"))
+	assert.Equal(t, http.StatusForbidden, call(apiToken, http.MethodPost, "/api/repertoires"))
+	assert.Equal(t, http.StatusForbidden, call(apiToken, http.MethodDelete, "/api/repertoires/:id"))
+
+	// Login tokens grant every scope
+	loginToken := generateTestToken(t)
+	assert.Equal(t, http.StatusOK, call(loginToken, http.MethodPost, "/api/imports"))
+	assert.Equal(t, http.StatusOK, call(loginToken, http.MethodDelete, "/api/repertoires/:id"))
+}
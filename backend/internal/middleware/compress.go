@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+const zstdScheme = "zstd"
+
+// Compress encodes responses with zstd when the client accepts it and with gzip otherwise.
+// WebSocket upgrades are passed through untouched.
+func Compress() echo.MiddlewareFunc {
+	gzip := echomw.GzipWithConfig(echomw.GzipConfig{Skipper: skipCompression})
+	encoders := sync.Pool{
+		New: func() interface{} {
+			// One encoder goroutine per response; requests already run concurrently
+			encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			if err != nil {
+				return err
+			}
+			return encoder
+		},
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		gzipNext := gzip(next)
+		return func(c echo.Context) error {
+			if skipCompression(c) {
+				return next(c)
+			}
+			if !acceptsEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding), zstdScheme) {
+				return gzipNext(c)
+			}
+
+			encoder, ok := encoders.Get().(*zstd.Encoder)
+			if !ok {
+				return gzipNext(c)
+			}
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			rw := res.Writer
+			encoder.Reset(rw)
+			zrw := &zstdResponseWriter{ResponseWriter: rw, encoder: encoder}
+			res.Writer = zrw
+			defer func() {
+				zrw.finish()
+				res.Writer = rw
+				encoder.Reset(io.Discard)
+				encoders.Put(encoder)
+			}()
+			return next(c)
+		}
+	}
+}
+
+// skipCompression leaves WebSocket upgrades alone, their connection is hijacked
+func skipCompression(c echo.Context) bool {
+	return strings.EqualFold(c.Request().Header.Get(echo.HeaderUpgrade), "websocket")
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows the coding, ignoring q=0 entries
+func acceptsEncoding(header, coding string) bool {
+	for _, entry := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// zstdResponseWriter compresses the body with zstd. The status line is held back until the
+// first body write so bodiless responses (204, 304, redirects) go out without Content-Encoding.
+type zstdResponseWriter struct {
+	http.ResponseWriter
+	encoder   *zstd.Encoder
+	code      int
+	wroteBody bool
+}
+
+func (w *zstdResponseWriter) WriteHeader(code int) {
+	w.Header().Del(echo.HeaderContentLength)
+	w.code = code
+}
+
+func (w *zstdResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteBody {
+		w.wroteBody = true
+		if w.Header().Get(echo.HeaderContentType) == "" {
+			w.Header().Set(echo.HeaderContentType, http.DetectContentType(b))
+		}
+		w.Header().Del(echo.HeaderContentLength)
+		w.Header().Set(echo.HeaderContentEncoding, zstdScheme)
+		w.ResponseWriter.WriteHeader(w.status())
+	}
+	return w.encoder.Write(b)
+}
+
+func (w *zstdResponseWriter) Flush() {
+	if w.wroteBody {
+		_ = w.encoder.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *zstdResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *zstdResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// finish ends the compressed stream, or sends the held back status of a bodiless response
+func (w *zstdResponseWriter) finish() {
+	if w.wroteBody {
+		_ = w.encoder.Close()
+		return
+	}
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+func (w *zstdResponseWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCompressed(t *testing.T, acceptEncoding string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/repertoires", nil)
+	if acceptEncoding != "" {
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	require.NoError(t, Compress()(handler)(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestCompress_PrefersZstd(t *testing.T) {
+	body := strings.Repeat(`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"}`, 50)
+
+	rec := serveCompressed(t, "gzip, deflate, br, zstd", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(body))
+	})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "zstd", rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Less(t, rec.Body.Len(), len(body))
+	decoder, err := zstd.NewReader(rec.Body)
+	require.NoError(t, err)
+	defer decoder.Close()
+	decoded, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+}
+
+func TestCompress_FallsBackToGzip(t *testing.T) {
+	rec := serveCompressed(t, "gzip, zstd;q=0", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello")
+	})
+
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(decoded))
+}
+
+func TestCompress_BodilessResponse(t *testing.T) {
+	rec := serveCompressed(t, "zstd", func(c echo.Context) error {
+		return c.NoContent(http.StatusNotModified)
+	})
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Zero(t, rec.Body.Len())
+}
+
+func TestCompress_Identity(t *testing.T) {
+	rec := serveCompressed(t, "", func(c echo.Context) error {
+		return c.String(http.StatusOK, "plain")
+	})
+
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, "plain", rec.Body.String())
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	assert.True(t, acceptsEncoding("gzip, zstd", "zstd"))
+	assert.True(t, acceptsEncoding("ZSTD;q=0.5", "zstd"))
+	assert.False(t, acceptsEncoding("zstd;q=0", "zstd"))
+	assert.False(t, acceptsEncoding("zstd; q=0.000", "zstd"))
+	assert.False(t, acceptsEncoding("gzip", "zstd"))
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPExtractor(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(remoteAddr, forwardedFor string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		req.Header.Set(echo.HeaderXRealIP, "198.51.100.9")
+		return req
+	}
+
+	// Without trusted proxies forwarding headers are ignored
+	direct := IPExtractor(nil)
+	assert.Equal(t, "203.0.113.7", direct(request("203.0.113.7:4000", "198.51.100.1")))
+
+	// A trusted proxy's forwarded address is used
+	behindProxy := IPExtractor([]*net.IPNet{proxies})
+	assert.Equal(t, "198.51.100.1", behindProxy(request("10.1.2.3:4000", "198.51.100.1")))
+
+	// An untrusted peer cannot spoof its address
+	assert.Equal(t, "203.0.113.7", behindProxy(request("203.0.113.7:4000", "198.51.100.1")))
+
+	// Private addresses other than the trusted range are not trusted implicitly
+	assert.Equal(t, "192.168.1.5", behindProxy(request("192.168.1.5:4000", "198.51.100.1")))
+}
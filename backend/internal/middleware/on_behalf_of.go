@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/services"
+)
+
+// OnBehalfOfParam is the query parameter with which a coach reads the data of a linked student
+const OnBehalfOfParam = "onBehalfOf"
+
+// OnBehalfOf lets a coach call a read endpoint for a student they have an accepted link with:
+// when the onBehalfOf query parameter names the student, the request runs as the student, with
+// the coach recorded in Principal.CoachID. Only read requests are allowed. It must run after JWTAuth.
+// Without a link service no student can be read.
+func OnBehalfOf(links *services.CoachLinkService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			studentID := c.QueryParam(OnBehalfOfParam)
+			if studentID == "" {
+				return next(c)
+			}
+
+			principal, ok := PrincipalFrom(c)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, models.APIError{Code: models.ErrCodeUnauthorized, Message: "unauthorized"})
+			}
+			if !isReadMethod(c.Request().Method) {
+				return c.JSON(http.StatusForbidden, models.APIError{Code: models.ErrCodeForbidden, Message: "coaches can only read student data"})
+			}
+			if _, err := uuid.Parse(studentID); err != nil {
+				return c.JSON(http.StatusBadRequest, models.APIError{Code: models.ErrCodeBadRequest, Message: OnBehalfOfParam + " must be a valid UUID"})
+			}
+			if studentID == principal.ID {
+				return next(c)
+			}
+
+			if links == nil {
+				return c.JSON(http.StatusNotFound, models.APIError{Code: models.ErrCodeNotFound, Message: "student not found"})
+			}
+			if err := links.CheckCoachAccess(principal.ID, studentID); err != nil {
+				if errors.Is(err, services.ErrNotFound) {
+					return c.JSON(http.StatusNotFound, models.APIError{Code: models.ErrCodeNotFound, Message: "student not found"})
+				}
+				return c.JSON(http.StatusInternalServerError, models.APIError{Code: models.ErrCodeInternal, Message: "failed to check coach link"})
+			}
+
+			principal.CoachID = principal.ID
+			principal.ID = studentID
+			SetPrincipal(c, principal)
+			return next(c)
+		}
+	}
+}
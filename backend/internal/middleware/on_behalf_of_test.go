@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/treechess/backend/internal/repository/mocks"
+	"github.com/treechess/backend/internal/services"
+)
+
+const (
+	testCoachID   = "11111111-1111-1111-1111-111111111111"
+	testStudentID = "22222222-2222-2222-2222-222222222222"
+)
+
+func TestOnBehalfOf(t *testing.T) {
+	links := services.NewCoachLinkService(&mocks.MockCoachLinkRepo{
+		IsActiveFunc: func(coachID, studentID string) (bool, error) {
+			return coachID == testCoachID && studentID == testStudentID, nil
+		},
+	}, &mocks.MockUserRepo{})
+	handler := OnBehalfOf(links)(func(c echo.Context) error {
+		p, _ := PrincipalFrom(c)
+		return c.String(http.StatusOK, p.ID+"|"+p.CoachID)
+	})
+
+	call := func(method, query, userID string) *httptest.ResponseRecorder {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(method, "/api/games?"+query, nil), rec)
+		SetPrincipal(c, Principal{ID: userID})
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	rec := call(http.MethodGet, "", testCoachID)
+	assert.Equal(t, testCoachID+"|", rec.Body.String())
+
+	rec = call(http.MethodGet, "onBehalfOf="+testStudentID, testCoachID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, testStudentID+"|"+testCoachID, rec.Body.String())
+
+	// No link the other way round
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "onBehalfOf="+testCoachID, testStudentID).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "onBehalfOf=nope", testCoachID).Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete, "onBehalfOf="+testStudentID, testCoachID).Code)
+}
+
+func TestOnBehalfOf_WithoutLinks(t *testing.T) {
+	handler := OnBehalfOf(nil)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	call := func(query string) int {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/games?"+query, nil), rec)
+		SetPrincipal(c, Principal{ID: testCoachID})
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("onBehalfOf="+testCoachID))
+	assert.Equal(t, http.StatusNotFound, call("onBehalfOf="+testStudentID))
+}
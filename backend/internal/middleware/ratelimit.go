@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// Headers describing the request budget of the caller. When several limiters apply to a request,
+// the one with the fewest requests left is reported.
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"     // requests allowed back-to-back with a full budget
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining" // requests left right now
+	HeaderRateLimitReset     = "X-RateLimit-Reset"     // seconds until the budget is full again
+	HeaderRetryAfter         = "Retry-After"           // seconds until the next request is allowed, on 429 only
+)
+
+// RateLimitHeaders are the headers rate limiters set, for CORS to expose them to the frontend
+var RateLimitHeaders = []string{HeaderRateLimitLimit, HeaderRateLimitRemaining, HeaderRateLimitReset, HeaderRetryAfter}
+
+var errNoPrincipal = errors.New("no authenticated user")
+
+// IPRateLimit limits each client address to limit requests per period, allowing bursts of up to
+// burst requests. Addresses in exempt, such as monitoring probes, are not limited. Rejected
+// requests get message as their error.
+func IPRateLimit(limit int, period time.Duration, burst int, exempt []*net.IPNet, message string) echo.MiddlewareFunc {
+	return rateLimiter{
+		store: newBudgetStore(limit, period, burst),
+		skipper: func(c echo.Context) bool {
+			ip := net.ParseIP(c.RealIP())
+			for _, ipNet := range exempt {
+				if ip != nil && ipNet.Contains(ip) {
+					return true
+				}
+			}
+			return false
+		},
+		identifier: func(c echo.Context) (string, error) {
+			return c.RealIP(), nil
+		},
+		message: message,
+	}.middleware()
+}
+
+// UserRateLimit limits each authenticated user to limit requests per period, allowing bursts of
+// up to burst requests. Unlike the global IP limiter it follows a user across addresses and does
+// not throttle users sharing one. It must run after JWTAuth. Each call keeps its own budgets, so
+// routes given the same middleware value share them.
+func UserRateLimit(limit int, period time.Duration, burst int) echo.MiddlewareFunc {
+	return rateLimiter{
+		store: newBudgetStore(limit, period, burst),
+		identifier: func(c echo.Context) (string, error) {
+			principal, ok := PrincipalFrom(c)
+			if !ok {
+				return "", errNoPrincipal
+			}
+			return principal.ID, nil
+		},
+		message: "rate limit exceeded",
+	}.middleware()
+}
+
+// APITokenRateLimit limits each personal API token to limit requests per period, allowing bursts
+// of up to burst requests. Requests authenticated with a login token are not counted. It must
+// run after JWTAuth.
+func APITokenRateLimit(limit int, period time.Duration, burst int) echo.MiddlewareFunc {
+	return rateLimiter{
+		store: newBudgetStore(limit, period, burst),
+		skipper: func(c echo.Context) bool {
+			principal, ok := PrincipalFrom(c)
+			return !ok || principal.TokenID == ""
+		},
+		identifier: func(c echo.Context) (string, error) {
+			principal, _ := PrincipalFrom(c)
+			return principal.TokenID, nil
+		},
+		message: "rate limit exceeded",
+	}.middleware()
+}
+
+type rateLimiter struct {
+	store      *budgetStore
+	skipper    func(echo.Context) bool
+	identifier func(echo.Context) (string, error)
+	message    string
+}
+
+func (l rateLimiter) middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if l.skipper != nil && l.skipper(c) {
+				return next(c)
+			}
+			identifier, err := l.identifier(c)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, models.APIError{Code: models.ErrCodeUnauthorized, Message: "unauthorized"})
+			}
+
+			q := l.store.take(identifier)
+			header := c.Response().Header()
+			if !q.allowed {
+				writeQuota(header, q)
+				header.Set(HeaderRetryAfter, strconv.Itoa(ceilSeconds(q.retryAfter)))
+				return c.JSON(http.StatusTooManyRequests, models.APIError{Code: models.ErrCodeRateLimited, Message: l.message})
+			}
+			// An earlier limiter may already have reported a tighter budget
+			if current, err := strconv.Atoi(header.Get(HeaderRateLimitRemaining)); err != nil || q.remaining < current {
+				writeQuota(header, q)
+			}
+			return next(c)
+		}
+	}
+}
+
+func writeQuota(header http.Header, q quota) {
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(q.limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(q.remaining))
+	header.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(q.reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// quota is the state of one budget after a request was counted against it
+type quota struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration // until the budget is full again
+	retryAfter time.Duration // until the next request is allowed
+}
+
+type budget struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// budgetStore keeps a token bucket per identifier. Buckets unused for expiresIn are dropped;
+// by then they have refilled, so dropping them loses nothing.
+type budgetStore struct {
+	mu          sync.Mutex
+	rate        rate.Limit
+	burst       int
+	expiresIn   time.Duration
+	budgets     map[string]*budget
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func newBudgetStore(limit int, period time.Duration, burst int) *budgetStore {
+	return &budgetStore{
+		rate:      rate.Limit(float64(limit) / period.Seconds()),
+		burst:     burst,
+		expiresIn: period,
+		budgets:   make(map[string]*budget),
+		now:       time.Now,
+	}
+}
+
+func (s *budgetStore) take(identifier string) quota {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastCleanup) > s.expiresIn {
+		for id, b := range s.budgets {
+			if now.Sub(b.lastSeen) > s.expiresIn {
+				delete(s.budgets, id)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	b, ok := s.budgets[identifier]
+	if !ok {
+		b = &budget{limiter: rate.NewLimiter(s.rate, s.burst)}
+		s.budgets[identifier] = b
+	}
+	b.lastSeen = now
+
+	allowed := b.limiter.AllowN(now, 1)
+	tokens := b.limiter.TokensAt(now)
+	q := quota{
+		allowed:   allowed,
+		limit:     s.burst,
+		remaining: max(int(math.Floor(tokens)), 0),
+		reset:     s.refillTime(float64(s.burst) - tokens),
+	}
+	if !allowed {
+		q.retryAfter = s.refillTime(1 - tokens)
+	}
+	return q
+}
+
+// refillTime is how long the bucket takes to gain tokens
+func (s *budgetStore) refillTime(tokens float64) time.Duration {
+	if tokens <= 0 || s.rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / float64(s.rate) * float64(time.Second))
+}
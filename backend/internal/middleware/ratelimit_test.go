@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRateLimit(t *testing.T) {
+	limit := UserRateLimit(2, time.Hour, 2)
+	handler := limit(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	call := func(userID, ip string) int {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/sync", nil)
+		req.Header.Set(echo.HeaderXRealIP, ip)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if userID != "" {
+			SetPrincipal(c, Principal{ID: userID})
+		}
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+
+	// The budget follows the user across addresses
+	assert.Equal(t, http.StatusOK, call("user-1", "10.0.0.1"))
+	assert.Equal(t, http.StatusOK, call("user-1", "10.0.0.2"))
+	assert.Equal(t, http.StatusTooManyRequests, call("user-1", "10.0.0.3"))
+
+	// Another user behind the same address keeps their own budget
+	assert.Equal(t, http.StatusOK, call("user-2", "10.0.0.1"))
+
+	assert.Equal(t, http.StatusUnauthorized, call("", "10.0.0.1"))
+}
+
+func TestAPITokenRateLimit(t *testing.T) {
+	limit := APITokenRateLimit(1, time.Hour, 1)
+	handler := limit(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	call := func(principal Principal) int {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/repertoires", nil), rec)
+		SetPrincipal(c, principal)
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(Principal{ID: "user-1", TokenID: "token-1"}))
+	assert.Equal(t, http.StatusTooManyRequests, call(Principal{ID: "user-1", TokenID: "token-1"}))
+
+	// Each token has its own budget, and login tokens are not counted
+	assert.Equal(t, http.StatusOK, call(Principal{ID: "user-1", TokenID: "token-2"}))
+	assert.Equal(t, http.StatusOK, call(Principal{ID: "user-1"}))
+	assert.Equal(t, http.StatusOK, call(Principal{ID: "user-1"}))
+}
+
+func TestIPRateLimit_Headers(t *testing.T) {
+	_, probes, _ := net.ParseCIDR("192.0.2.0/24")
+	limit := IPRateLimit(2, time.Hour, 2, []*net.IPNet{probes}, "rate limit exceeded")
+	handler := limit(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	call := func(ip string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.IPExtractor = echo.ExtractIPDirect()
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		if err := handler(e.NewContext(req, rec)); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	rec := call("10.0.0.1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "1", rec.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "1800", rec.Header().Get(HeaderRateLimitReset))
+
+	call("10.0.0.1")
+	rec = call("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "1800", rec.Header().Get(HeaderRetryAfter))
+
+	// Exempt probes are neither limited nor told about a budget
+	for i := 0; i < 3; i++ {
+		rec = call("192.0.2.10")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get(HeaderRateLimitRemaining))
+	}
+}
+
+func TestRateLimit_ReportsTightestBudget(t *testing.T) {
+	wide := UserRateLimit(100, time.Hour, 100)
+	narrow := UserRateLimit(2, time.Hour, 2)
+	handler := narrow(wide(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}))
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/sync", nil), rec)
+	SetPrincipal(c, Principal{ID: "user-1"})
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "2", rec.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "1", rec.Header().Get(HeaderRateLimitRemaining))
+}
@@ -0,0 +1,59 @@
+package models
+
+// APIError is the body of every error response. Code is stable for clients to branch on;
+// Message is meant for humans and may change.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+}
+
+// Generic error codes, one per HTTP status the API answers with
+const (
+	ErrCodeBadRequest         = "BAD_REQUEST"
+	ErrCodeUnauthorized       = "UNAUTHORIZED"
+	ErrCodeForbidden          = "FORBIDDEN"
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	ErrCodeConflict           = "CONFLICT"
+	ErrCodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	ErrCodeRateLimited        = "RATE_LIMITED"
+	ErrCodeInternal           = "INTERNAL_ERROR"
+	ErrCodeBadGateway         = "BAD_GATEWAY"
+	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+)
+
+// Error codes of specific failures, mapped from service errors
+const (
+	ErrCodeValidationFailed               = "VALIDATION_FAILED"
+	ErrCodeInvalidFEN                     = "INVALID_FEN"
+	ErrCodeInvalidMove                    = "INVALID_MOVE"
+	ErrCodeColorMismatch                  = "COLOR_MISMATCH"
+	ErrCodeRepertoireLimitReached         = "REPERTOIRE_LIMIT_REACHED"
+	ErrCodeCategoryLimitReached           = "CATEGORY_LIMIT_REACHED"
+	ErrCodeGoalLimitReached               = "GOAL_LIMIT_REACHED"
+	ErrCodeAPITokenLimitReached           = "API_TOKEN_LIMIT_REACHED"
+	ErrCodeLinkedAccountLimitReached      = "LINKED_ACCOUNT_LIMIT_REACHED"
+	ErrCodeClassificationRuleLimitReached = "CLASSIFICATION_RULE_LIMIT_REACHED"
+	ErrCodeMoveExists                     = "MOVE_EXISTS"
+	ErrCodeRepertoireArchived             = "REPERTOIRE_ARCHIVED"
+	ErrCodeRootNode                       = "ROOT_NODE"
+	ErrCodeNodeNotFound                   = "NODE_NOT_FOUND"
+	ErrCodeDuplicateGame                  = "DUPLICATE_GAME"
+	ErrCodeImportTooLarge                 = "IMPORT_TOO_LARGE"
+	ErrCodeEmailTaken                     = "EMAIL_TAKEN"
+	ErrCodeUsernameTaken                  = "USERNAME_TAKEN"
+	ErrCodeInvalidCredentials             = "INVALID_CREDENTIALS"
+	ErrCodeOAuthOnly                      = "OAUTH_ONLY"
+	ErrCodeIncorrectPassword              = "INCORRECT_PASSWORD"
+	ErrCodeResetTokenInvalid              = "RESET_TOKEN_INVALID"
+	ErrCodeResetTokenExpired              = "RESET_TOKEN_EXPIRED"
+	ErrCodeResetTokenUsed                 = "RESET_TOKEN_USED"
+	ErrCodeAlreadyExists                  = "ALREADY_EXISTS"
+	ErrCodeFeatureUnavailable             = "FEATURE_UNAVAILABLE"
+	ErrCodeUpstreamRateLimited            = "UPSTREAM_RATE_LIMITED"
+	ErrCodeUpstreamUnavailable            = "UPSTREAM_UNAVAILABLE"
+	ErrCodeExplorerBudgetExceeded         = "EXPLORER_BUDGET_EXCEEDED"
+	ErrCodeExplorerBusy                   = "EXPLORER_BUSY"
+	ErrCodeUsageQuotaExceeded             = "USAGE_QUOTA_EXCEEDED"
+	ErrCodePrivateStudy                   = "PRIVATE_STUDY"
+)
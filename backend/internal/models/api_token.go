@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Scopes of personal API tokens
+const (
+	APITokenScopeRead   = "read"   // Reading data only
+	APITokenScopeImport = "import" // Reading data, importing and syncing games
+	APITokenScopeWrite  = "write"  // Every request a logged-in user can make, admin routes excepted
+)
+
+// Scopes granted by tokens, checked against the scope each protected route needs
+const (
+	ScopeRead   = "read"   // Reading data, needed by GET routes unless set otherwise
+	ScopeWrite  = "write"  // Changing data, needed by other routes unless set otherwise
+	ScopeImport = "import" // Importing and syncing games
+	ScopeAdmin  = "admin"  // Admin routes, on top of the user being an admin
+)
+
+// APIToken is a personal access token a user scripts the API with. Only its hash is stored.
+type APIToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	Prefix     string     `json:"prefix"` // First characters of the token, to tell tokens apart
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type CreateAPITokenRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// CreateAPITokenResponse carries the raw token, which is only shown once
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// BookDepth tells how deep games stay in a repertoire before someone leaves it.
+// Plies are numbered like MoveAnalysis.PlyNumber: 0 is White's first move.
+type BookDepth struct {
+	RepertoireID         string            `json:"repertoireId"`
+	Games                int               `json:"games"`
+	UserDeviations       int               `json:"userDeviations"`       // Games the user left the repertoire first
+	UserLeaveBookPly     *float64          `json:"userLeaveBookPly"`     // Average ply of the user's deviations
+	OpponentDeviations   int               `json:"opponentDeviations"`   // Games the opponent left the repertoire first
+	OpponentLeaveBookPly *float64          `json:"opponentLeaveBookPly"` // Average ply of the opponents' deviations
+	Trend                []BookDepthPeriod `json:"trend"`                // Per import month, oldest first
+	MoveTimes            []PlyMoveTime     `json:"moveTimes"`            // Time the user spends per ply, for games with clock data
+}
+
+// BookDepthPeriod is the book depth of the games imported during one month
+type BookDepthPeriod struct {
+	Month                string   `json:"month"` // YYYY-MM
+	Games                int      `json:"games"`
+	UserDeviations       int      `json:"userDeviations"`
+	UserLeaveBookPly     *float64 `json:"userLeaveBookPly"`
+	OpponentDeviations   int      `json:"opponentDeviations"`
+	OpponentLeaveBookPly *float64 `json:"opponentLeaveBookPly"`
+}
+
+// PlyMoveTime is the average time the user spends on their move at one ply
+type PlyMoveTime struct {
+	Ply          int     `json:"ply"`
+	Moves        int     `json:"moves"`
+	InBook       int     `json:"inBook"`       // Moves that followed the repertoire
+	AvgTimeSpent float64 `json:"avgTimeSpent"` // Seconds
+}
@@ -4,11 +4,12 @@ import "time"
 
 // Category represents a group of repertoires
 type Category struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Color     Color     `json:"color"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Color       Color     `json:"color"`
+	MatchActive bool      `json:"matchActive"` // its repertoires can be matched against imported games
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
 // CategoryWithRepertoires includes the category with its repertoires
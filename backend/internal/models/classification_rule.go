@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Movers a classification rule can be restricted to
+const (
+	ClassificationMoverUser     = "user"
+	ClassificationMoverOpponent = "opponent"
+)
+
+// ClassificationRule is a user-defined classification of analyzed moves, e.g. "inaccuracy in book"
+// for repertoire moves losing winrate. A move gets the rule's key when it meets every condition
+// that is set; the games containing such a move can be filtered by the key.
+type ClassificationRule struct {
+	ID             string    `json:"id"`
+	Key            string    `json:"key"`                      // Slug surfaced on the moves and games, unique per user
+	Name           string    `json:"name"`                     // Label shown to the user
+	MoveStatus     string    `json:"moveStatus,omitempty"`     // Analysis status the move must have, any when empty
+	Mover          string    `json:"mover,omitempty"`          // "user" or "opponent", any when empty
+	MinWinrateDrop *float64  `json:"minWinrateDrop,omitempty"` // Minimum winrate drop (0-1); only matches evaluated moves
+	MinTimeSpent   *float64  `json:"minTimeSpent,omitempty"`   // Minimum seconds spent on the move; only matches timed moves
+	MaxPly         *int      `json:"maxPly,omitempty"`         // Only the first MaxPly plies of the game
+	CreatedAt      time.Time `json:"createdAt"`
+	UserID         string    `json:"-"`
+}
+
+// ClassificationRuleRequest creates or replaces a classification rule
+type ClassificationRuleRequest struct {
+	Key            string   `json:"key"`
+	Name           string   `json:"name"`
+	MoveStatus     string   `json:"moveStatus"`
+	Mover          string   `json:"mover"`
+	MinWinrateDrop *float64 `json:"minWinrateDrop"`
+	MinTimeSpent   *float64 `json:"minTimeSpent"`
+	MaxPly         *int     `json:"maxPly"`
+}
+
+// Matches reports whether an analyzed move meets every condition of the rule
+func (r ClassificationRule) Matches(move MoveAnalysis) bool {
+	if r.MoveStatus != "" && move.Status != r.MoveStatus {
+		return false
+	}
+	if r.Mover == ClassificationMoverUser && !move.IsUserMove || r.Mover == ClassificationMoverOpponent && move.IsUserMove {
+		return false
+	}
+	if r.MinWinrateDrop != nil && (move.WinrateDrop == nil || *move.WinrateDrop < *r.MinWinrateDrop) {
+		return false
+	}
+	if r.MinTimeSpent != nil && (move.TimeSpent == nil || *move.TimeSpent < *r.MinTimeSpent) {
+		return false
+	}
+	if r.MaxPly != nil && move.PlyNumber >= *r.MaxPly {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// CoachLinkStatus is the state of a coach-student link
+type CoachLinkStatus string
+
+const (
+	CoachLinkPending CoachLinkStatus = "pending" // Waiting for the invitee to accept
+	CoachLinkActive  CoachLinkStatus = "active"
+)
+
+// CoachLinkRole is the side of a coach-student link a user is on
+type CoachLinkRole string
+
+const (
+	CoachLinkRoleCoach   CoachLinkRole = "coach"
+	CoachLinkRoleStudent CoachLinkRole = "student"
+)
+
+// CoachLink grants a coach read access to a student's games, insights and repertoires once active
+type CoachLink struct {
+	ID              string          `json:"id"`
+	CoachID         string          `json:"coachId"`
+	CoachUsername   string          `json:"coachUsername"`
+	StudentID       string          `json:"studentId"`
+	StudentUsername string          `json:"studentUsername"`
+	InvitedBy       string          `json:"invitedBy"`
+	Status          CoachLinkStatus `json:"status"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	AcceptedAt      *time.Time      `json:"acceptedAt,omitempty"`
+}
+
+// InviteCoachLinkRequest invites the user matching email or username to a link, Role being the
+// side the invitee takes: a coach invites a "student", a student invites a "coach"
+type InviteCoachLinkRequest struct {
+	Email    string        `json:"email"`
+	Username string        `json:"username"`
+	Role     CoachLinkRole `json:"role"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// FENDetails describes a position given as a FEN, so clients need no chess logic of their own
+type FENDetails struct {
+	// FEN keeps board, side to move, castling and en passant, the form repertoire nodes store
+	FEN        string `json:"fen"`
+	FullFEN    string `json:"fullFen"`
+	SideToMove Color  `json:"sideToMove"`
+	LegalMoves int    `json:"legalMoves"`
+	GameOver   bool   `json:"gameOver"`
+	// Reason says why the game is over: checkmate, stalemate, insufficient_material or seventy_five_move_rule
+	Reason string `json:"reason,omitempty"`
+}
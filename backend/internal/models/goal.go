@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// GoalType identifies how a goal's progress is measured
+type GoalType string
+
+const (
+	// GoalTypeExplorerCoverage tracks the percentage of Lichess Explorer opponent replies
+	// covered by the repertoire, up to a given depth in plies
+	GoalTypeExplorerCoverage GoalType = "explorer_coverage"
+	// GoalTypeWeeklyReviews tracks how many repertoire positions the user reviewed in training in the last 7 days
+	GoalTypeWeeklyReviews GoalType = "weekly_reviews"
+)
+
+// Goal represents a training goal attached to a repertoire
+type Goal struct {
+	ID                string     `json:"id"`
+	RepertoireID      string     `json:"repertoireId"`
+	RepertoireName    string     `json:"repertoireName"`
+	Type              GoalType   `json:"type"`
+	Target            float64    `json:"target"`
+	Depth             int        `json:"depth,omitempty"`
+	Progress          float64    `json:"progress"`
+	Achieved          bool       `json:"achieved"`
+	ProgressUpdatedAt *time.Time `json:"progressUpdatedAt,omitempty"`
+	LastSummaryAt     *time.Time `json:"-"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UserID            string     `json:"-"`
+}
+
+// CreateGoalRequest represents a request to add a goal to a repertoire
+type CreateGoalRequest struct {
+	Type   GoalType `json:"type"`
+	Target float64  `json:"target"`
+	Depth  int      `json:"depth,omitempty"`
+}
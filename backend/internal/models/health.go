@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RepertoireHealth is a 0-100 score of how well a repertoire is maintained, with its components.
+// Each component is on a 0-100 scale; components without data are nil and do not count.
+type RepertoireHealth struct {
+	Score      int       `json:"score"`
+	Coverage   *float64  `json:"coverage"`  // Share of Explorer replies the tree answers
+	Results    *float64  `json:"results"`   // The user's score in games reaching the repertoire
+	Recall     *float64  `json:"recall"`    // Share of correct training answers over the recall window
+	Freshness  float64   `json:"freshness"` // Falls from 100 to 0 as the last edit or training gets older
+	ComputedAt time.Time `json:"computedAt"`
+}
+
+// TrainingRecall sums the training on a repertoire
+type TrainingRecall struct {
+	Reviews      int        // Within the requested window
+	Correct      int        // Within the requested window
+	LastAnswerAt *time.Time // Last answer ever, nil when the repertoire was never trained
+}
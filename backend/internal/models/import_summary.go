@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ImportWarningKind says why a game of an import needs attention
+type ImportWarningKind string
+
+const (
+	ImportWarningParseFailed  ImportWarningKind = "parse_failed"   // the game could not be read
+	ImportWarningUserNotFound ImportWarningKind = "user_not_found" // none of the user's names played the game
+	ImportWarningDuplicate    ImportWarningKind = "duplicate"      // the game was already imported; Message holds what was done
+	ImportWarningNoRepertoire ImportWarningKind = "no_repertoire"  // the game matched none of the user's repertoires
+	ImportWarningVariant      ImportWarningKind = "variant"        // the game is a chess variant; Message says whether it was kept
+)
+
+// ImportWarning is one game of an import that was dropped or needs attention.
+// Game is the 1-based position of the game in the imported PGN.
+type ImportWarning struct {
+	Kind    ImportWarningKind `json:"kind"`
+	Game    int               `json:"game"`
+	White   string            `json:"white,omitempty"`
+	Black   string            `json:"black,omitempty"`
+	Date    string            `json:"date,omitempty"`
+	Message string            `json:"message,omitempty"`
+}
+
+// ImportSummary records what an import run found, kept for auditing after the fact
+type ImportSummary struct {
+	AnalysisID    string          `json:"analysisId"`
+	GamesFound    int             `json:"gamesFound"`
+	GamesImported int             `json:"gamesImported"`
+	Warnings      []ImportWarning `json:"warnings"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
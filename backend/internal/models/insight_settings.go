@@ -0,0 +1,9 @@
+package models
+
+// InsightSettings are a user's thresholds deciding which moves the insights report as mistakes
+type InsightSettings struct {
+	MinDrop      float64 `json:"minDrop"`      // Minimum winrate drop (0-1) for a move to count as a mistake
+	MinPly       int     `json:"minPly"`       // First ply that can count as a mistake; earlier plies are the opening choice
+	MinFrequency int     `json:"minFrequency"` // Games a mistake must recur in before it is reported
+	MaxMistakes  int     `json:"maxMistakes"`  // Mistakes shown by the insights unless a limit is requested
+}
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// NotificationEvent is a kind of event users can be notified of
+type NotificationEvent string
+
+const (
+	NotificationImportCompleted   NotificationEvent = "import_completed"   // a background import finished
+	NotificationAnalysisCompleted NotificationEvent = "analysis_completed" // the engine analyzed every game of an import
+	NotificationWeeklyDigest      NotificationEvent = "weekly_digest"
+)
+
+// NotificationEvents lists the events in the order settings show them
+var NotificationEvents = []NotificationEvent{NotificationImportCompleted, NotificationAnalysisCompleted, NotificationWeeklyDigest}
+
+// NotificationChannel is a way of delivering notifications
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail   NotificationChannel = "email"
+	NotificationChannelWebhook NotificationChannel = "webhook"
+	NotificationChannelPush    NotificationChannel = "push"
+)
+
+// Notification is an event delivered to a user
+type Notification struct {
+	Event NotificationEvent `json:"event"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	// URL is the page of the app the notification leads to, relative to the frontend
+	URL    string    `json:"url,omitempty"`
+	Data   any       `json:"data,omitempty"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// NotificationPreference tells on which channels a user receives an event
+type NotificationPreference struct {
+	Event   NotificationEvent `json:"event"`
+	Email   bool              `json:"email"`
+	Webhook bool              `json:"webhook"`
+	Push    bool              `json:"push"`
+}
+
+// Enabled reports whether the preference delivers on a channel
+func (p NotificationPreference) Enabled(channel NotificationChannel) bool {
+	switch channel {
+	case NotificationChannelEmail:
+		return p.Email
+	case NotificationChannelWebhook:
+		return p.Webhook
+	case NotificationChannelPush:
+		return p.Push
+	default:
+		return false
+	}
+}
+
+// NotificationWebhook is the URL a user's notifications are posted to. Secret signs the
+// payloads and is only returned when the webhook is set.
+type NotificationWebhook struct {
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PushSubscription is a browser's Web Push subscription, as returned by PushManager.subscribe
+type PushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// NotificationSettings gathers a user's notification preferences and channels
+type NotificationSettings struct {
+	Preferences []NotificationPreference `json:"preferences"`
+	Webhook     *NotificationWebhook     `json:"webhook,omitempty"`
+	// PushSubscriptions counts the user's browsers subscribed to push notifications
+	PushSubscriptions int `json:"pushSubscriptions"`
+	// PushPublicKey is the server key browsers subscribe with, empty when push is not configured
+	PushPublicKey string `json:"pushPublicKey,omitempty"`
+	EmailEnabled  bool   `json:"emailEnabled"`
+}
+
+// UpdateNotificationPreferencesRequest replaces the preferences of the listed events
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// SetNotificationWebhookRequest sets the URL notifications are posted to
+type SetNotificationWebhookRequest struct {
+	URL string `json:"url"`
+}
+
+// UnsubscribePushRequest removes a browser's push subscription
+type UnsubscribePushRequest struct {
+	Endpoint string `json:"endpoint"`
+}
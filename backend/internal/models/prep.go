@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Prep request statuses
+const (
+	PrepPending = "pending"
+	PrepRunning = "running"
+	PrepDone    = "done"
+	PrepFailed  = "failed"
+)
+
+// PrepRequest is a queued deep expansion of a repertoire node. Once done, Suggestions holds
+// the candidate lines found below the node that the user has neither accepted nor discarded.
+type PrepRequest struct {
+	ID           string           `json:"id"`
+	RepertoireID string           `json:"repertoireId"`
+	NodeID       string           `json:"nodeId"`
+	FEN          string           `json:"fen"`
+	Depth        int              `json:"depth"`
+	Status       string           `json:"status"`
+	Suggestions  []PrepSuggestion `json:"suggestions"`
+	Error        string           `json:"error,omitempty"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	UpdatedAt    time.Time        `json:"updatedAt"`
+	UserID       string           `json:"-"`
+}
+
+// PrepSuggestion is a candidate line starting at the prepared node, with the Explorer
+// stats of its first move
+type PrepSuggestion struct {
+	ID    string   `json:"id"`
+	Moves []string `json:"moves"`
+	Games int      `json:"games"`
+	White int      `json:"white"`
+	Draws int      `json:"draws"`
+	Black int      `json:"black"`
+}
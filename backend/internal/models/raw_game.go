@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// RawGame is the PGN of an imported game as it was received, archived once per user and
+// fingerprint. Stored analyses are derived from it.
+type RawGame struct {
+	UserID      string    `json:"userId"`
+	Fingerprint string    `json:"fingerprint"`
+	PGN         string    `json:"pgn"`
+	Source      string    `json:"source"`
+	ImportedAt  time.Time `json:"importedAt"`
+}
+
+// RecomputeJob tracks a background recomputation of stored analyses from the raw game archive
+type RecomputeJob struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId,omitempty"` // Empty when the job covers every user
+	Status     string    `json:"status"`           // pending, processing, done, failed
+	Total      int       `json:"total"`            // Archived games to go through
+	Processed  int       `json:"processed"`
+	Recomputed int       `json:"recomputed"` // Processed games whose stored analysis was replaced
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// RecomputeRequest is the request body of POST /api/admin/recompute
+type RecomputeRequest struct {
+	UserID string `json:"userId"` // Only this user's games; empty means every user
+}
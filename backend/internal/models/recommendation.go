@@ -0,0 +1,26 @@
+package models
+
+// RecommendationKind names what a recommendation asks the user to study
+type RecommendationKind string
+
+const (
+	RecommendationDeepen RecommendationKind = "deepen" // games reach the end of a line and are lost past it
+	RecommendationCover  RecommendationKind = "cover"  // opponents play a move the repertoire does not answer
+	RecommendationReview RecommendationKind = "review" // the user leaves a line they prepared, or forgets the repertoire
+)
+
+// Recommendation is one thing worth studying next, with the evidence behind it
+type Recommendation struct {
+	Kind           RecommendationKind `json:"kind"`
+	RepertoireID   string             `json:"repertoireId"`
+	RepertoireName string             `json:"repertoireName"`
+	NodeID         string             `json:"nodeId,omitempty"` // position to open, empty when the whole repertoire is concerned
+	Line           string             `json:"line,omitempty"`   // moves to the position in move-number notation
+	Move           string             `json:"move,omitempty"`   // opponent move to prepare an answer to, for cover
+	Games          int                `json:"games"`
+	Losses         int                `json:"losses"`
+	CoveredUntil   int                `json:"coveredUntil,omitempty"` // last move number the repertoire reaches, for deepen
+	Recall         *float64           `json:"recall,omitempty"`       // share of correct training answers on the repertoire
+	Priority       float64            `json:"priority"`
+	Summary        string             `json:"summary"`
+}
@@ -34,7 +34,18 @@ type RepertoireNode struct {
 	BranchName      *string           `json:"branchName,omitempty"`
 	Collapsed       bool              `json:"collapsed,omitempty"`
 	TranspositionOf *string           `json:"transpositionOf,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	EditedAt        *time.Time        `json:"editedAt,omitempty"`
+	Opening         *OpeningLabel     `json:"opening,omitempty"` // set on the first node of first- and second-level branches
 	Children        []*RepertoireNode `json:"children"`
+	// Truncated is set in depth-limited responses on nodes whose children were left out
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// OpeningLabel names the opening a branch of a repertoire reaches, from the ECO table
+type OpeningLabel struct {
+	ECO  string `json:"eco"`
+	Name string `json:"name"`
 }
 
 type Metadata struct {
@@ -44,14 +55,28 @@ type Metadata struct {
 }
 
 type Repertoire struct {
-	ID         string         `json:"id"`
-	Name       string         `json:"name"`
-	Color      Color          `json:"color"`
-	CategoryID *string        `json:"categoryId,omitempty"`
-	TreeData   RepertoireNode `json:"treeData"`
-	Metadata   Metadata       `json:"metadata"`
-	CreatedAt  time.Time      `json:"createdAt"`
-	UpdatedAt  time.Time      `json:"updatedAt"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Color       Color             `json:"color"`
+	CategoryID  *string           `json:"categoryId,omitempty"`
+	TreeData    RepertoireNode    `json:"treeData"`
+	Metadata    Metadata          `json:"metadata"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	Version     int               `json:"version"`          // incremented on every tree save
+	MatchActive bool              `json:"matchActive"`      // imported games are matched against it
+	Archived    bool              `json:"archived"`         // read-only and left out of matching
+	Health      *RepertoireHealth `json:"health,omitempty"` // set when listing repertoires, once computed
+}
+
+// SetMatchActiveRequest marks a repertoire or a category as active for game matching
+type SetMatchActiveRequest struct {
+	Active *bool `json:"active"`
+}
+
+// SetArchivedRequest archives or unarchives a repertoire
+type SetArchivedRequest struct {
+	Archived *bool `json:"archived"`
 }
 
 // CreateRepertoireRequest represents a request to create a new repertoire
@@ -69,6 +94,10 @@ type UpdateRepertoireRequest struct {
 type RepertoireRef struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// Snapshot of the repertoire a game was analyzed against, unset for games analyzed before
+	// snapshots were recorded
+	Version  int    `json:"version,omitempty"`
+	TreeHash string `json:"treeHash,omitempty"`
 }
 
 // MergeRepertoiresRequest represents a request to merge multiple repertoires into a new one
@@ -94,6 +123,11 @@ type ExtractSubtreeResponse struct {
 	Extracted *Repertoire `json:"extracted"`
 }
 
+// MirrorRepertoireRequest names the mirrored copy; an empty name derives one from the source
+type MirrorRepertoireRequest struct {
+	Name string `json:"name"`
+}
+
 type AddNodeRequest struct {
 	ParentID   string `json:"parentId"`
 	Move       string `json:"move"`
@@ -102,15 +136,40 @@ type AddNodeRequest struct {
 	// They are optional in the request and will be overridden
 }
 
+// AddNodeResponse is the repertoire after adding a node, with the popularity of the move when
+// requested and the move is the opponent's
+type AddNodeResponse struct {
+	Repertoire
+	Popularity *MovePopularity `json:"popularity,omitempty"`
+}
+
+// MovePopularity is how often a move is played from its position in the Explorer games
+type MovePopularity struct {
+	Move       string  `json:"move"`
+	Games      int     `json:"games"`
+	TotalGames int     `json:"totalGames"` // games from the position, all moves together
+	Share      float64 `json:"share"`      // Games / TotalGames, 0 when the position has no games
+	// Rare is set when the share is below config.RareMoveShare, so the user may be preparing
+	// for a move they will seldom meet
+	Rare bool `json:"rare"`
+}
+
 type PGNHeaders map[string]string
 
 type MoveAnalysis struct {
-	PlyNumber    int    `json:"plyNumber"`
-	SAN          string `json:"san"`
-	FEN          string `json:"fen"`
-	Status       string `json:"status"`
-	ExpectedMove string `json:"expectedMove,omitempty"`
-	IsUserMove   bool   `json:"isUserMove"`
+	PlyNumber    int      `json:"plyNumber"`
+	SAN          string   `json:"san"`
+	FEN          string   `json:"fen"`
+	Status       string   `json:"status"`
+	ExpectedMove string   `json:"expectedMove,omitempty"`
+	IsUserMove   bool     `json:"isUserMove"`
+	Comment      string   `json:"comment,omitempty"`   // PGN comment following the move
+	NAGs         []string `json:"nags,omitempty"`      // Numeric annotation glyphs, e.g. "$1" for "!"
+	Clock        *float64 `json:"clock,omitempty"`     // Seconds left on the mover's clock after the move (from %clk)
+	TimeSpent    *float64 `json:"timeSpent,omitempty"` // Seconds spent on the move, increment included
+
+	WinrateDrop     *float64 `json:"winrateDrop,omitempty"`     // Winrate lost against the best move, once the game is evaluated
+	Classifications []string `json:"classifications,omitempty"` // Keys of the user's classification rules the move matches
 }
 
 type GameAnalysis struct {
@@ -120,24 +179,102 @@ type GameAnalysis struct {
 	UserColor         Color          `json:"userColor"`         // Which color the user played as in this game
 	MatchedRepertoire *RepertoireRef `json:"matchedRepertoire"` // Which repertoire was matched (nil if no match)
 	MatchScore        int            `json:"matchScore"`        // Number of moves that matched the repertoire
+	Note              string         `json:"note,omitempty"`
+	Starred           bool           `json:"starred,omitempty"`
+}
+
+// Import sources recorded on analyses
+const (
+	ImportSourcePGN       = "pgn" // Uploaded PGN file or database
+	ImportSourceLichess   = "lichess"
+	ImportSourceChesscom  = "chesscom"
+	ImportSourceBroadcast = "broadcast"    // Lichess broadcast round, imported for reference
+	ImportSourceTeam      = "lichess_team" // Games of one member of a Lichess team, imported for reference
+)
+
+// ValidImportSource reports whether source is one of the import sources
+func ValidImportSource(source string) bool {
+	switch source {
+	case ImportSourcePGN, ImportSourceLichess, ImportSourceChesscom, ImportSourceBroadcast, ImportSourceTeam:
+		return true
+	}
+	return false
+}
+
+// ImportSourceParams records how an import was requested so it can be run again
+type ImportSourceParams struct {
+	Username        string                 `json:"username,omitempty"`
+	Filename        string                 `json:"filename,omitempty"` // Uploaded file
+	RoundID         string                 `json:"roundId,omitempty"`  // Lichess broadcast round
+	TeamID          string                 `json:"teamId,omitempty"`   // Lichess team of a team import
+	BatchID         string                 `json:"batchId,omitempty"`  // Team import the analysis belongs to
+	Sync            bool                   `json:"sync,omitempty"`     // Imported by the automatic sync
+	DuplicatePolicy DuplicatePolicy        `json:"duplicatePolicy,omitempty"`
+	AnalysisDepth   *int                   `json:"analysisDepth,omitempty"`
+	Lichess         *LichessImportOptions  `json:"lichess,omitempty"`
+	Chesscom        *ChesscomImportOptions `json:"chesscom,omitempty"`
+	// MatchRepertoireIDs restricts matching to these repertoires instead of the active ones
+	MatchRepertoireIDs []string `json:"matchRepertoireIds,omitempty"`
+	// IncludeVariants keeps chess variant games, unmatched, instead of skipping them
+	IncludeVariants bool `json:"includeVariants,omitempty"`
+}
+
+// ImportProvenance is where the games of an analysis came from and how the import was requested
+type ImportProvenance struct {
+	Source       string             `json:"source"`
+	SourceParams ImportSourceParams `json:"sourceParams"`
 }
 
 type AnalysisSummary struct {
-	ID                string    `json:"id"`
-	Username          string    `json:"username"`
-	Filename          string    `json:"filename"`
-	GameCount         int       `json:"gameCount"`
-	UploadedAt        time.Time `json:"uploadedAt"`
-	SkippedDuplicates int       `json:"-"` // not persisted, set after save
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	Filename   string    `json:"filename"`
+	GameCount  int       `json:"gameCount"`
+	UploadedAt time.Time `json:"uploadedAt"`
+	ImportProvenance
+	SkippedDuplicates int             `json:"-"` // not persisted, set after save
+	Duplicates        []DuplicateGame `json:"-"` // not persisted, set after save
+	Warnings          []ImportWarning `json:"-"` // persisted in the import summary, set after save
+}
+
+// DuplicatePolicy controls what an import does with games that were already imported
+type DuplicatePolicy string
+
+const (
+	DuplicatePolicySkip     DuplicatePolicy = "skip"      // Ignore the new copy (default)
+	DuplicatePolicyReplace  DuplicatePolicy = "replace"   // Re-analyze the stored game in place
+	DuplicatePolicyKeepBoth DuplicatePolicy = "keep-both" // Store the new copy alongside the old one
+)
+
+// ParseDuplicatePolicy validates a duplicate policy, defaulting an empty value to skip
+func ParseDuplicatePolicy(value string) (DuplicatePolicy, bool) {
+	switch DuplicatePolicy(value) {
+	case "", DuplicatePolicySkip:
+		return DuplicatePolicySkip, true
+	case DuplicatePolicyReplace, DuplicatePolicyKeepBoth:
+		return DuplicatePolicy(value), true
+	}
+	return "", false
+}
+
+// DuplicateGame reports a game of an import that was already imported, and what was done with it
+type DuplicateGame struct {
+	Action     string `json:"action"` // skipped, replaced, kept
+	White      string `json:"white"`
+	Black      string `json:"black"`
+	Date       string `json:"date,omitempty"`
+	AnalysisID string `json:"analysisId"` // Analysis holding the previously imported copy
+	GameIndex  int    `json:"gameIndex"`
 }
 
 type AnalysisDetail struct {
-	ID         string         `json:"id"`
-	Username   string         `json:"username"`
-	Filename   string         `json:"filename"`
-	GameCount  int            `json:"gameCount"`
-	UploadedAt time.Time      `json:"uploadedAt"`
-	Results    []GameAnalysis `json:"results"`
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	Filename   string    `json:"filename"`
+	GameCount  int       `json:"gameCount"`
+	UploadedAt time.Time `json:"uploadedAt"`
+	ImportProvenance
+	Results []GameAnalysis `json:"results"`
 }
 
 // LichessImportOptions represents options for importing games from Lichess
@@ -151,8 +288,20 @@ type LichessImportOptions struct {
 
 // LichessImportRequest represents a request to import games from Lichess
 type LichessImportRequest struct {
-	Username string               `json:"username"`
-	Options  LichessImportOptions `json:"options"`
+	Username        string               `json:"username"`
+	DuplicatePolicy string               `json:"duplicatePolicy,omitempty"`
+	AnalysisDepth   *int                 `json:"analysisDepth,omitempty"` // Moves matched per game; overrides the user's setting
+	Options         LichessImportOptions `json:"options"`
+	// MatchRepertoireIDs restricts matching to these repertoires instead of the active ones
+	MatchRepertoireIDs []string `json:"matchRepertoireIds,omitempty"`
+	// IncludeVariants keeps Antichess, Atomic, Crazyhouse and other variant games, unmatched
+	IncludeVariants bool `json:"includeVariants,omitempty"`
+}
+
+// LichessBroadcastImportRequest represents a request to import the games of a Lichess broadcast round for reference
+type LichessBroadcastImportRequest struct {
+	URL           string `json:"url"`                     // Round URL or round ID
+	AnalysisDepth *int   `json:"analysisDepth,omitempty"` // Moves matched per game; overrides the user's setting
 }
 
 // ChesscomImportOptions represents options for importing games from Chess.com
@@ -161,12 +310,38 @@ type ChesscomImportOptions struct {
 	Since     int64  `json:"since,omitempty"`     // Timestamp Unix ms (start date)
 	Until     int64  `json:"until,omitempty"`     // Timestamp Unix ms (end date)
 	TimeClass string `json:"timeClass,omitempty"` // Game type: daily, rapid, blitz, bullet
+	// ExcludeDaily leaves out daily (correspondence) games when no time class is given
+	ExcludeDaily bool `json:"excludeDaily,omitempty"`
 }
 
 // ChesscomImportRequest represents a request to import games from Chess.com
 type ChesscomImportRequest struct {
-	Username string               `json:"username"`
-	Options  ChesscomImportOptions `json:"options"`
+	Username        string                `json:"username"`
+	DuplicatePolicy string                `json:"duplicatePolicy,omitempty"`
+	AnalysisDepth   *int                  `json:"analysisDepth,omitempty"` // Moves matched per game; overrides the user's setting
+	Options         ChesscomImportOptions `json:"options"`
+	// MatchRepertoireIDs restricts matching to these repertoires instead of the active ones
+	MatchRepertoireIDs []string `json:"matchRepertoireIds,omitempty"`
+	// IncludeVariants keeps variant games, e.g. Chess960, unmatched
+	IncludeVariants bool `json:"includeVariants,omitempty"`
+}
+
+// ImportPreviewRequest asks what importing the games of a Lichess or Chess.com account would do
+type ImportPreviewRequest struct {
+	Source          string                `json:"source"` // lichess or chesscom
+	Username        string                `json:"username"`
+	LichessOptions  LichessImportOptions  `json:"lichessOptions"`
+	ChesscomOptions ChesscomImportOptions `json:"chesscomOptions"`
+}
+
+// ImportPreview counts what an import would do, before anything is saved
+type ImportPreview struct {
+	TotalGames      int `json:"totalGames"`
+	NewGames        int `json:"newGames"`
+	Duplicates      int `json:"duplicates"`      // Already imported
+	NotPlayedByUser int `json:"notPlayedByUser"` // The username was not found in the game
+	AsWhite         int `json:"asWhite"`
+	AsBlack         int `json:"asBlack"`
 }
 
 // StudyChapterInfo represents metadata about a single Lichess study chapter
@@ -175,6 +350,11 @@ type StudyChapterInfo struct {
 	Name        string `json:"name"`
 	Orientation string `json:"orientation"`
 	MoveCount   int    `json:"moveCount"`
+	NodeCount   int    `json:"nodeCount"` // moves of the chapter tree, variations included
+	// Moves the chapter alone would add to the repertoire given as merge target
+	NewNodeCount *int `json:"newNodeCount,omitempty"`
+	// Chapters starting from a custom position cannot be imported and are skipped
+	Unsupported bool `json:"unsupported,omitempty"`
 }
 
 // StudyInfo represents metadata about a Lichess study
@@ -192,6 +372,8 @@ type StudyImportRequest struct {
 	MergeName      string `json:"mergeName,omitempty"`
 	CreateCategory bool   `json:"createCategory,omitempty"`
 	CategoryName   string `json:"categoryName,omitempty"`
+	// Merges the chapters into this existing repertoire instead of creating new ones
+	TargetRepertoireID string `json:"targetRepertoireId,omitempty"`
 }
 
 // GameSummary represents a single game for the games list
@@ -209,14 +391,60 @@ type GameSummary struct {
 	ImportedAt     time.Time `json:"importedAt"`
 	RepertoireName string    `json:"repertoireName,omitempty"`
 	RepertoireID   string    `json:"repertoireId,omitempty"`
-	Source         string    `json:"source"` // "lichess", "chesscom", "pgn"
+	Source         string    `json:"source"` // "lichess", "chesscom", "broadcast", "pgn"
 	Synced         bool      `json:"synced"`
+	Note           string    `json:"note,omitempty"`
+	Starred        bool      `json:"starred"`
+
+	Classifications []string `json:"classifications,omitempty"` // Classification rule keys matched by a move of the game
+}
+
+// UpdateGameRequest edits the user's annotations of a game; omitted fields are left unchanged
+type UpdateGameRequest struct {
+	Note    *string `json:"note"`
+	Starred *bool   `json:"starred"`
+}
+
+// GameNotes is the user's annotations of a game
+type GameNotes struct {
+	AnalysisID string `json:"analysisId"`
+	GameIndex  int    `json:"gameIndex"`
+	Note       string `json:"note"`
+	Starred    bool   `json:"starred"`
+}
+
+// AdoptLineResponse is the repertoire a game line was adopted into and the game re-analyzed against it
+type AdoptLineResponse struct {
+	Repertoire *Repertoire   `json:"repertoire"`
+	Game       *GameAnalysis `json:"game"`
+	AddedNodes int           `json:"addedNodes"` // Plies of the line that were not in the repertoire yet
+}
+
+// ParseTimeControl splits a TimeControl PGN header value into base and increment seconds.
+// It returns ok=false for correspondence ("-" or Chess.com's "1/86400") or malformed values.
+func ParseTimeControl(tc string) (base, increment int, ok bool) {
+	if isCorrespondenceTimeControl(tc) {
+		return 0, 0, false
+	}
+	parts := strings.Split(tc, "+")
+	if _, err := fmt.Sscanf(parts[0], "%d", &base); err != nil {
+		return 0, 0, false
+	}
+	if len(parts) > 1 {
+		if _, err := fmt.Sscanf(parts[1], "%d", &increment); err != nil {
+			return 0, 0, false
+		}
+	}
+	return base, increment, true
 }
 
+// TimeClasses are the time classes returned by ClassifyTimeControl, fastest first
+var TimeClasses = []string{"bullet", "blitz", "rapid", "daily"}
+
 // ClassifyTimeControl maps a TimeControl PGN header value to a time class.
-// Format: "seconds" or "seconds+increment"
+// Format: "seconds" or "seconds+increment"; correspondence games are "daily"
 func ClassifyTimeControl(tc string) string {
-	if tc == "-" || tc == "" {
+	if tc == "" || isCorrespondenceTimeControl(tc) {
 		return "daily"
 	}
 
@@ -248,6 +476,12 @@ func ClassifyTimeControl(tc string) string {
 	}
 }
 
+// isCorrespondenceTimeControl reports whether a TimeControl header describes a correspondence game:
+// "-" on Lichess, "moves/seconds" per move (e.g. "1/86400") on Chess.com daily games
+func isCorrespondenceTimeControl(tc string) bool {
+	return tc == "-" || strings.Contains(tc, "/")
+}
+
 // GameRef is a lightweight reference to a game within an analysis
 type GameRef struct {
 	AnalysisID string `json:"analysisId"`
@@ -257,6 +491,8 @@ type GameRef struct {
 	Black      string `json:"black"`
 	Result     string `json:"result"`
 	Date       string `json:"date"`
+	UserColor  Color  `json:"userColor,omitempty"`
+	Opening    string `json:"opening,omitempty"`
 }
 
 // ExplorerMoveStats represents opening explorer data for a single user move
@@ -269,37 +505,241 @@ type ExplorerMoveStats struct {
 	BestWinrate   float64 `json:"bestWinrate"`
 	WinrateDrop   float64 `json:"winrateDrop"`
 	TotalGames    int     `json:"totalGames"`
+	Tablebase     bool    `json:"tablebase,omitempty"` // Winrates are exact tablebase results (1, 0.5 or 0) rather than Explorer stats
+}
+
+// ExplorerStatus tells whether explorer data is available or still being fetched
+type ExplorerStatus string
+
+const (
+	ExplorerReady   ExplorerStatus = "ready"
+	ExplorerPending ExplorerStatus = "pending"
+)
+
+// ExplorerPosition is the Lichess Explorer summary of a position. While Status is
+// pending the lookup is queued and the counts are empty; clients poll again.
+type ExplorerPosition struct {
+	FEN     string         `json:"fen"`
+	Speeds  string         `json:"speeds"`
+	Ratings string         `json:"ratings"`
+	Status  ExplorerStatus `json:"status"`
+	White   int            `json:"white"`
+	Draws   int            `json:"draws"`
+	Black   int            `json:"black"`
+	Moves   []ExplorerMove `json:"moves"`
+}
+
+// ExplorerMove is the Explorer record of one move played from a position
+type ExplorerMove struct {
+	SAN           string `json:"san"`
+	UCI           string `json:"uci"`
+	White         int    `json:"white"`
+	Draws         int    `json:"draws"`
+	Black         int    `json:"black"`
+	AverageRating int    `json:"averageRating"`
+}
+
+// TablebasePosition is the Lichess tablebase verdict on a position with few pieces.
+// Category is win, cursed-win, draw, blessed-loss, loss, or maybe-win, maybe-loss and unknown
+// when the distance to zeroing is uncertain, all from the side to move's point of view.
+type TablebasePosition struct {
+	FEN       string          `json:"fen"`
+	Category  string          `json:"category"`
+	DTZ       *int            `json:"dtz"` // Distance to zeroing, in plies
+	DTM       *int            `json:"dtm"` // Distance to mate, in plies, when known
+	Checkmate bool            `json:"checkmate"`
+	Stalemate bool            `json:"stalemate"`
+	Moves     []TablebaseMove `json:"moves"` // Best first
+}
+
+// TablebaseMove is a legal move of a tablebase position. Its category is from the point of view
+// of the side to move after it, so a move into a "loss" wins.
+type TablebaseMove struct {
+	UCI       string `json:"uci"`
+	SAN       string `json:"san"`
+	Category  string `json:"category"`
+	DTZ       *int   `json:"dtz"`
+	DTM       *int   `json:"dtm"`
+	Zeroing   bool   `json:"zeroing"`
+	Checkmate bool   `json:"checkmate"`
+	Stalemate bool   `json:"stalemate"`
+}
+
+// ModelGame represents a master game reaching a position, as reported by the Lichess Explorer
+type ModelGame struct {
+	ID          string `json:"id"`
+	White       string `json:"white"`
+	WhiteRating int    `json:"whiteRating"`
+	Black       string `json:"black"`
+	BlackRating int    `json:"blackRating"`
+	Year        int    `json:"year"`
+	Result      string `json:"result"` // 1-0, 0-1, 1/2-1/2
+	URL         string `json:"url"`
 }
 
 // EngineEval represents a pending/completed opening analysis for a game
 type EngineEval struct {
-	ID         string              `json:"id"`
-	UserID     string              `json:"userId"`
-	AnalysisID string              `json:"analysisId"`
-	GameIndex  int                 `json:"gameIndex"`
-	Status     string              `json:"status"` // pending, processing, done, failed
-	Evals      []ExplorerMoveStats `json:"evals,omitempty"`
-	CreatedAt  time.Time           `json:"createdAt"`
-	UpdatedAt  time.Time           `json:"updatedAt"`
+	ID            string              `json:"id"`
+	UserID        string              `json:"userId"`
+	AnalysisID    string              `json:"analysisId"`
+	GameIndex     int                 `json:"gameIndex"`
+	Status        string              `json:"status"` // pending, processing, done, failed
+	Evals         []ExplorerMoveStats `json:"evals,omitempty"`
+	Attempts      int                 `json:"attempts"`                // failed attempts so far
+	NextAttemptAt *time.Time          `json:"nextAttemptAt,omitempty"` // set while a failed eval waits for its retry
+	CreatedAt     time.Time           `json:"createdAt"`
+	UpdatedAt     time.Time           `json:"updatedAt"`
+}
+
+// ReanalysisJob tracks a background re-analysis of every game matched to a repertoire
+type ReanalysisJob struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"userId"`
+	RepertoireID string    `json:"repertoireId"`
+	Status       string    `json:"status"` // pending, processing, done, failed
+	Total        int       `json:"total"`
+	Processed    int       `json:"processed"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ImportJob tracks a background import of a large PGN database, processed in chunks of games
+type ImportJob struct {
+	ID              string          `json:"id"`
+	UserID          string          `json:"userId"`
+	Username        string          `json:"username"`
+	Filename        string          `json:"filename"`
+	DuplicatePolicy DuplicatePolicy `json:"duplicatePolicy"`
+	FilePath        string          `json:"-"`
+	Status          string          `json:"status"` // pending, processing, done, failed
+	TotalGames      int             `json:"totalGames"`
+	TotalChunks     int             `json:"totalChunks"`
+	ProcessedChunks int             `json:"processedChunks"`
+	ImportedGames   int             `json:"importedGames"`
+	SkippedGames    int             `json:"skippedGames"` // duplicates and games the user did not play
+	CheckedGames    int             `json:"checkedGames"` // games checked against the already imported ones
+	FailedGames     []ImportFailure `json:"failedGames"`
+	AnalysisIDs     []string        `json:"analysisIds"`
+	Error           string          `json:"error,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
+
+// ImportFailure reports a game of an imported database that could not be imported.
+// GameIndex is the 0-based position of the game in the uploaded file.
+type ImportFailure struct {
+	GameIndex int    `json:"gameIndex"`
+	Error     string `json:"error"`
+}
+
+// ImportChunkResult is the outcome of importing one chunk of an import job
+type ImportChunkResult struct {
+	AnalysisID string
+	Imported   int
+	Skipped    int
+	Failed     []ImportFailure
+}
+
+// LichessTeamImportRequest represents a request to import the recent games of every member of a Lichess team
+type LichessTeamImportRequest struct {
+	Team          string               `json:"team"` // Team URL or ID
+	AnalysisDepth *int                 `json:"analysisDepth,omitempty"`
+	Options       LichessImportOptions `json:"options"`
+}
+
+// TeamImport tracks a background import of a Lichess team, one analysis per member.
+// Members is filled once the team roster has been fetched; Results grows as members are imported.
+type TeamImport struct {
+	ID            string               `json:"id"`
+	UserID        string               `json:"-"`
+	TeamID        string               `json:"teamId"`
+	Options       LichessImportOptions `json:"options"`
+	AnalysisDepth *int                 `json:"analysisDepth,omitempty"`
+	Status        string               `json:"status"` // pending, processing, done, failed
+	Members       []string             `json:"members"`
+	Results       []TeamMemberImport   `json:"results"`
+	Error         string               `json:"error,omitempty"`
+	NextAttemptAt *time.Time           `json:"nextAttemptAt,omitempty"` // Set while waiting out a Lichess rate limit
+	CreatedAt     time.Time            `json:"createdAt"`
+	UpdatedAt     time.Time            `json:"updatedAt"`
+}
+
+// TeamMemberImport is the outcome of importing the games of one team member
+type TeamMemberImport struct {
+	Username   string `json:"username"`
+	AnalysisID string `json:"analysisId,omitempty"`
+	GameCount  int    `json:"gameCount"`
+	Error      string `json:"error,omitempty"`
 }
 
 // OpeningMistake represents a recurring opening mistake detected via explorer stats
 type OpeningMistake struct {
+	FEN         string         `json:"fen"`
+	PlayedMove  string         `json:"playedMove"`
+	BestMove    string         `json:"bestMove"`
+	WinrateDrop float64        `json:"winrateDrop"`
+	Frequency   int            `json:"frequency"`
+	Score       float64        `json:"score"`
+	Games       []GameRef      `json:"games"`
+	TimeClasses map[string]int `json:"timeClasses"` // Games of the mistake per time class
+}
+
+// MistakeExplanation gathers the context of a move the user played, for an explanation card of an insight mistake
+type MistakeExplanation struct {
+	FEN            string                  `json:"fen"`
+	PlayedMove     string                  `json:"playedMove"`
+	Repertoires    []RepertoireExpectation `json:"repertoires"`              // Repertoires reaching the position with their side to move
+	ExplorerStatus ExplorerStatus          `json:"explorerStatus,omitempty"` // Empty when the Explorer is not available
+	TotalGames     int                     `json:"totalGames"`               // Explorer games reaching the position
+	Played         *ExplainedMove          `json:"played,omitempty"`         // Nil when the Explorer has no game with the played move
+	Best           *ExplainedMove          `json:"best,omitempty"`
+	ModelGames     []ModelGame             `json:"modelGames"`
+}
+
+// ExplainedMove is the Explorer record of a move with its expected score for the side to move
+type ExplainedMove struct {
+	ExplorerMove
+	Winrate float64 `json:"winrate"`
+}
+
+// TimeTroubleSignal flags a repertoire where the user burns a large share of their clock right after leaving book
+type TimeTroubleSignal struct {
+	RepertoireID     string  `json:"repertoireId"`
+	RepertoireName   string  `json:"repertoireName"`
+	Games            int     `json:"games"`            // Games with clock data that left book
+	AvgLeaveBookMove float64 `json:"avgLeaveBookMove"` // Average full-move number of the first move out of book
+	AvgTimeSpent     float64 `json:"avgTimeSpent"`     // Average seconds spent on the user's first moves out of book
+	AvgClockShare    float64 `json:"avgClockShare"`    // AvgTimeSpent as a fraction of the base time
+}
+
+// DismissedMistake is a mistake the user chose to hide from insights
+type DismissedMistake struct {
 	FEN         string    `json:"fen"`
 	PlayedMove  string    `json:"playedMove"`
-	BestMove    string    `json:"bestMove"`
-	WinrateDrop float64   `json:"winrateDrop"`
-	Frequency   int       `json:"frequency"`
-	Score       float64   `json:"score"`
-	Games       []GameRef `json:"games"`
+	DismissedAt time.Time `json:"dismissedAt"`
+}
+
+// InsightsFilter narrows the games scanned by GetInsights and sizes its result
+type InsightsFilter struct {
+	Limit        int       // Maximum number of mistakes returned
+	MinDrop      float64   // Minimum winrate drop (0-1) for a move to count as a mistake
+	MinPly       int       // First ply that can count as a mistake; zero means config.DefaultInsightsMinPly
+	MinFrequency int       // Games a mistake must recur in; zero means config.DefaultInsightsMinFrequency
+	Since        time.Time // Only games uploaded at or after this time; zero means no bound
+	RepertoireID string    // Only games matched to this repertoire; empty means all
+	TimeClass    string    // Only games of this time class, as classified by ClassifyTimeControl; empty means all
+	// Time zone plain dates given as filters are read in, the user's; nil means UTC
+	Location *time.Location
 }
 
 // InsightsResponse is the response for the GET /api/games/insights endpoint
 type InsightsResponse struct {
-	WorstMistakes           []OpeningMistake `json:"worstMistakes"`
-	EngineAnalysisDone      bool             `json:"engineAnalysisDone"`
-	EngineAnalysisTotal     int              `json:"engineAnalysisTotal"`
-	EngineAnalysisCompleted int              `json:"engineAnalysisCompleted"`
+	WorstMistakes           []OpeningMistake    `json:"worstMistakes"`
+	TimeTrouble             []TimeTroubleSignal `json:"timeTrouble"`
+	EngineAnalysisDone      bool                `json:"engineAnalysisDone"`
+	EngineAnalysisTotal     int                 `json:"engineAnalysisTotal"`
+	EngineAnalysisCompleted int                 `json:"engineAnalysisCompleted"`
 }
 
 // RawAnalysis represents a full analysis with all game data, used for insights computation
@@ -318,6 +758,16 @@ type GamesResponse struct {
 	Offset int           `json:"offset"`
 }
 
+// ImportSourceStats summarizes the games a user imported from one source during one month
+type ImportSourceStats struct {
+	Source              string  `json:"source"` // "lichess", "chesscom", "broadcast", "pgn"
+	Month               string  `json:"month"`  // YYYY-MM
+	GamesImported       int     `json:"gamesImported"`
+	DuplicatesSkipped   int     `json:"duplicatesSkipped"`
+	OutOfRepertoireRate float64 `json:"outOfRepertoireRate"` // Share of imported games that left the repertoire
+	EvalCompletion      float64 `json:"evalCompletion"`      // Share of imported games with a finished engine evaluation
+}
+
 // RepertoireStats holds per-repertoire dashboard metrics
 type RepertoireStats struct {
 	RepertoireID   string  `json:"repertoireId"`
@@ -345,4 +795,211 @@ type DashboardStatsResponse struct {
 	InRepCount      int               `json:"inRepCount"`
 	OutRepCount     int               `json:"outRepCount"`
 	Repertoires     []RepertoireStats `json:"repertoires"`
+	TimeClasses     []TimeClassStats  `json:"timeClasses"` // In TimeClasses order, only the time classes played
+}
+
+// TimeClassStats are the out-of-book statistics of the games of one time class
+type TimeClassStats struct {
+	TimeClass        string  `json:"timeClass"`
+	GameCount        int     `json:"gameCount"`
+	InRepCount       int     `json:"inRepCount"`
+	OutRepCount      int     `json:"outRepCount"`
+	CoveragePercent  float64 `json:"coveragePercent"`
+	WinRate          float64 `json:"winRate"`
+	LeftBookCount    int     `json:"leftBookCount"`    // Games where one of the user's moves left book
+	AvgLeaveBookMove float64 `json:"avgLeaveBookMove"` // Average full-move number of the first move out of book
+}
+
+// PositionMatch is a repertoire node reaching a looked-up position
+type PositionMatch struct {
+	RepertoireID   string   `json:"repertoireId"`
+	RepertoireName string   `json:"repertoireName"`
+	Color          Color    `json:"color"`
+	NodeID         string   `json:"nodeId"`
+	Path           []string `json:"path"`
+}
+
+// RepertoireExpectation is what a repertoire prepares in a position where its side is to move
+type RepertoireExpectation struct {
+	RepertoireID       string   `json:"repertoireId"`
+	RepertoireName     string   `json:"repertoireName"`
+	NodeID             string   `json:"nodeId"`
+	Path               []string `json:"path"`
+	ExpectedMove       string   `json:"expectedMove,omitempty"` // Main move prepared here, empty when the line ends
+	Comment            string   `json:"comment,omitempty"`      // Comment of the expected move
+	PlayedInRepertoire bool     `json:"playedInRepertoire"`     // The played move is one of the prepared moves
+}
+
+// RepertoirePosition is an entry of the position index: a repertoire node and its position
+type RepertoirePosition struct {
+	RepertoireID string `json:"repertoireId"`
+	NodeID       string `json:"nodeId"`
+	FEN          string `json:"fen"`
+}
+
+// RepertoireSearchResult is a node comment or repertoire name matching a search. Name matches
+// point at the root node with an empty move path.
+type RepertoireSearchResult struct {
+	RepertoireID   string   `json:"repertoireId"`
+	RepertoireName string   `json:"repertoireName"`
+	NodeID         string   `json:"nodeId"`
+	MovePath       []string `json:"movePath"` // SAN moves from the root to the node
+	Comment        string   `json:"comment,omitempty"`
+	Rank           float64  `json:"rank"`
+}
+
+// RepertoireTemplate is a repertoire users can seed from. Built-in templates have no owner;
+// featured templates are listed to every user, the others only to their owner.
+type RepertoireTemplate struct {
+	ID          string         `json:"id"`
+	UserID      *string        `json:"userId,omitempty"`
+	Name        string         `json:"name"`
+	Color       Color          `json:"color"`
+	Description string         `json:"description"`
+	Tags        []string       `json:"tags"`
+	Featured    bool           `json:"featured"`
+	TreeData    RepertoireNode `json:"-"`
+	CreatedAt   time.Time      `json:"createdAt"`
+}
+
+// PublishTemplateRequest publishes one of the user's repertoires as a template
+type PublishTemplateRequest struct {
+	RepertoireID string   `json:"repertoireId"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Tags         []string `json:"tags"`
+}
+
+// RepertoireLine is a line of a repertoire, from the starting position to a leaf
+type RepertoireLine struct {
+	LeafID  string        `json:"leafId"`
+	Moves   []string      `json:"moves"`
+	Tags    []string      `json:"tags"`
+	Opening *OpeningLabel `json:"opening,omitempty"` // the deepest branch label on the line
+}
+
+// TrainingPosition is a position where the user must find their repertoire move
+type TrainingPosition struct {
+	NodeID        string   `json:"nodeId"`
+	FEN           string   `json:"fen"`
+	Path          []string `json:"path"`
+	ExpectedMoves []string `json:"expectedMoves"`
+	Tags          []string `json:"tags"`
+}
+
+// RepertoireMetrics describes the shape of a repertoire tree
+type RepertoireMetrics struct {
+	RepertoireID      string          `json:"repertoireId"`
+	TotalNodes        int             `json:"totalNodes"`
+	LeafCount         int             `json:"leafCount"`
+	MaxDepth          int             `json:"maxDepth"`
+	NodesPerDepth     []int           `json:"nodesPerDepth"` // index is the ply, 0 being the starting position
+	MyBranching       float64         `json:"myBranching"`   // average replies prepared per position where the user moves
+	OpponentBranching float64         `json:"opponentBranching"`
+	AnnotatedPercent  float64         `json:"annotatedPercent"`
+	Branches          []BranchMetrics `json:"branches"`
+}
+
+// BranchMetrics summarizes one major branch, i.e. a first move of the repertoire
+type BranchMetrics struct {
+	NodeID       string     `json:"nodeId"`
+	Move         string     `json:"move"`
+	BranchName   *string    `json:"branchName,omitempty"`
+	Nodes        int        `json:"nodes"`
+	LastEditedAt *time.Time `json:"lastEditedAt"`
+}
+
+// RepertoireEventType identifies a collaborative edit broadcast to watchers of a repertoire
+type RepertoireEventType string
+
+const (
+	RepertoireEventSnapshot          RepertoireEventType = "snapshot"
+	RepertoireEventNodeAdded         RepertoireEventType = "node_added"
+	RepertoireEventNodeDeleted       RepertoireEventType = "node_deleted"
+	RepertoireEventCommentUpdated    RepertoireEventType = "comment_updated"
+	RepertoireEventChildrenReordered RepertoireEventType = "children_reordered"
+	RepertoireEventRevisionRestored  RepertoireEventType = "revision_restored"
+	RepertoireEventCommentsImported  RepertoireEventType = "comments_imported"
+)
+
+// RepertoireEvent describes one edit of a repertoire. Version is the repertoire version
+// after the edit; a client seeing a gap in versions has missed events and should reload.
+// Concurrent edits are resolved last-writer-wins.
+type RepertoireEvent struct {
+	Type         RepertoireEventType `json:"type"`
+	RepertoireID string              `json:"repertoireId"`
+	Version      int                 `json:"version"`
+	NodeID       string              `json:"nodeId,omitempty"`
+	ParentID     *string             `json:"parentId,omitempty"`
+	Node         *RepertoireNode     `json:"node,omitempty"`
+	Comment      *string             `json:"comment,omitempty"`
+	ChildIDs     []string            `json:"childIds,omitempty"`
+}
+
+// CollaboratorRole is the access a collaborator has on a shared repertoire
+type CollaboratorRole string
+
+const (
+	CollaboratorViewer CollaboratorRole = "viewer"
+	CollaboratorEditor CollaboratorRole = "editor"
+)
+
+// RepertoireCollaborator is a user a repertoire is shared with
+type RepertoireCollaborator struct {
+	RepertoireID string           `json:"repertoireId"`
+	UserID       string           `json:"userId"`
+	Username     string           `json:"username"`
+	Role         CollaboratorRole `json:"role"`
+	CreatedAt    time.Time        `json:"createdAt"`
+}
+
+// InviteCollaboratorRequest shares a repertoire with the user matching email or username
+type InviteCollaboratorRequest struct {
+	Email    string           `json:"email"`
+	Username string           `json:"username"`
+	Role     CollaboratorRole `json:"role"`
+}
+
+// SharedRepertoire is a repertoire another user shared with the current user
+type SharedRepertoire struct {
+	Repertoire
+	Role CollaboratorRole `json:"role"`
+}
+
+// NodeResult is the user's score in the imported games that reached a repertoire node
+type NodeResult struct {
+	NodeID string  `json:"nodeId"`
+	Games  int     `json:"games"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+	Score  float64 `json:"score"` // (wins + draws/2) / games
+}
+
+// ResultsOverlay maps the user's game results onto a repertoire tree.
+// Nodes no game reached are omitted.
+type ResultsOverlay struct {
+	RepertoireID string       `json:"repertoireId"`
+	Nodes        []NodeResult `json:"nodes"`
+}
+
+// OpponentReply is a move opponents played against the user in a position, with the user's score after it
+type OpponentReply struct {
+	Move          string  `json:"move"`
+	Games         int     `json:"games"`
+	Frequency     float64 `json:"frequency"` // share of the games reaching the position
+	Wins          int     `json:"wins"`
+	Draws         int     `json:"draws"`
+	Losses        int     `json:"losses"`
+	Score         float64 `json:"score"`         // (wins + draws/2) / finished games
+	AverageRating int     `json:"averageRating"` // opponents' average rating, 0 when none is known
+}
+
+// OpponentReplies aggregates what the user's opponents played in a position, most frequent first
+type OpponentReplies struct {
+	FEN       string          `json:"fen"`
+	Games     int             `json:"games"`
+	MinRating int             `json:"minRating,omitempty"`
+	MaxRating int             `json:"maxRating,omitempty"`
+	Replies   []OpponentReply `json:"replies"`
 }
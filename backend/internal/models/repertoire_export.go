@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// RepertoireExportFormat identifies the JSON files written by repertoire exports
+const RepertoireExportFormat = "treechess-repertoire"
+
+// RepertoireExportVersion is the version of the export schema written by this server. Imports
+// accept files up to this version; it is bumped whenever a field changes meaning.
+const RepertoireExportVersion = 1
+
+// RepertoireExport is a repertoire as a self-contained JSON document, for backups and for moving
+// repertoires between instances. Nodes carry no IDs or positions: on import every move is
+// replayed from the root position and the nodes get fresh IDs.
+//
+// Schema version 1:
+//   - format: always "treechess-repertoire"
+//   - version: 1
+//   - name, color ("white" or "black")
+//   - metadata: node and depth counts, informational only and recomputed on import
+//   - notes: the markdown study notes, if any
+//   - tree: the root node, with the starting position in fen and the move number it starts
+//     from; every other node has a SAN move and optional comment, branchName, tags and
+//     collapsed, and children in display order
+type RepertoireExport struct {
+	Format     string       `json:"format"`
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exportedAt"`
+	Name       string       `json:"name"`
+	Color      Color        `json:"color"`
+	Metadata   Metadata     `json:"metadata"`
+	Notes      string       `json:"notes,omitempty"`
+	Tree       ExportedNode `json:"tree"`
+}
+
+// ExportedNode is a node of an exported repertoire tree. FEN and MoveNumber are only set on
+// the root; Move on every other node.
+type ExportedNode struct {
+	FEN        string         `json:"fen,omitempty"`
+	MoveNumber int            `json:"moveNumber,omitempty"`
+	Move       string         `json:"move,omitempty"` // SAN
+	Comment    string         `json:"comment,omitempty"`
+	BranchName string         `json:"branchName,omitempty"`
+	Tags       []string       `json:"tags,omitempty"`
+	Collapsed  bool           `json:"collapsed,omitempty"`
+	Children   []ExportedNode `json:"children,omitempty"`
+}
+
+// CommentsImportResult reports what a bulk comment import changed. Rows are matched to nodes by
+// their move path; rows whose path is not in the tree are listed and otherwise ignored.
+type CommentsImportResult struct {
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	NotFound  []string `json:"notFound"`
+}
@@ -125,3 +125,44 @@ func TestGameAnalysis_MultipleMoves(t *testing.T) {
 	assert.Len(t, ga.Moves, 2)
 	assert.Equal(t, "Player1", ga.Headers["White"])
 }
+
+func TestNewSlimRepertoire(t *testing.T) {
+	e4 := "e4"
+	rootID := "root"
+	rep := Repertoire{
+		ID: "rep-1",
+		TreeData: RepertoireNode{
+			ID: rootID, FEN: "start-fen", ColorToMove: ChessColorWhite,
+			Children: []*RepertoireNode{
+				{ID: "n1", FEN: "after-e4", Move: &e4, MoveNumber: 1, ParentID: &rootID, Children: []*RepertoireNode{}},
+			},
+		},
+	}
+
+	data, err := json.Marshal(NewSlimRepertoire(rep))
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "rep-1", decoded["id"])
+	tree := decoded["treeData"].(map[string]interface{})
+	assert.Equal(t, "start-fen", tree["fen"])
+	child := tree["children"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, child, "fen")
+	assert.NotContains(t, child, "children")
+	assert.Equal(t, "e4", child["move"])
+	// The full tree is left untouched
+	assert.Equal(t, "after-e4", rep.TreeData.Children[0].FEN)
+}
+
+func TestClassifyTimeControl(t *testing.T) {
+	assert.Equal(t, "bullet", ClassifyTimeControl("60"))
+	assert.Equal(t, "blitz", ClassifyTimeControl("180+2"))
+	assert.Equal(t, "rapid", ClassifyTimeControl("600+5"))
+	assert.Equal(t, "daily", ClassifyTimeControl("-"))
+	assert.Equal(t, "daily", ClassifyTimeControl("1/86400"))
+	assert.Equal(t, "daily", ClassifyTimeControl("1/259200"))
+
+	_, _, ok := ParseTimeControl("1/86400")
+	assert.False(t, ok)
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// RepertoireRevision is the tree of a repertoire as it was saved at one version.
+// TreeData is left out of revision lists.
+type RepertoireRevision struct {
+	Version   int             `json:"version"`
+	Metadata  Metadata        `json:"metadata"`
+	CreatedAt time.Time       `json:"createdAt"`
+	TreeData  *RepertoireNode `json:"treeData,omitempty"`
+}
+
+// RevisionDiff lists what changed in a repertoire from one revision to another. Nodes are
+// matched by the moves leading to them, so a line re-imported with fresh node IDs is not
+// reported as changed. Added and Removed only hold the first node of each added or removed
+// line; Nodes counts the whole line.
+type RevisionDiff struct {
+	RepertoireID string       `json:"repertoireId"`
+	From         int          `json:"from"`
+	To           int          `json:"to"`
+	Added        []DiffNode   `json:"added"`
+	Removed      []DiffNode   `json:"removed"`
+	Changed      []NodeChange `json:"changed"`
+}
+
+// DiffNode is the first node of a line added or removed between two revisions
+type DiffNode struct {
+	NodeID string   `json:"nodeId"`
+	Path   []string `json:"path"` // moves from the root
+	FEN    string   `json:"fen"`
+	Nodes  int      `json:"nodes"` // nodes in the line, this one included
+}
+
+// NodeChange is a node present in both revisions whose annotations differ
+type NodeChange struct {
+	NodeID  string        `json:"nodeId"` // ID in the newer revision
+	Path    []string      `json:"path"`
+	FEN     string        `json:"fen"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is one annotation of a node before and after, empty when unset
+type FieldChange struct {
+	Field  string `json:"field"` // comment, branchName or tags
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// GameRepertoireSnapshot is a game with the repertoire tree it was analyzed against, so its
+// statuses can be explained after the repertoire changed. Changed reports whether the moves of
+// the repertoire differ now; CurrentVersion is 0 once the repertoire is deleted.
+type GameRepertoireSnapshot struct {
+	Game           GameAnalysis    `json:"game"`
+	Repertoire     RepertoireRef   `json:"repertoire"`
+	TreeData       *RepertoireNode `json:"treeData"`
+	CurrentVersion int             `json:"currentVersion"`
+	Changed        bool            `json:"changed"`
+}
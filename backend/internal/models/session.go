@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SessionDevice describes the client a user logged in from
+type SessionDevice struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is a login of a user on one device, valid until it expires or is revoked
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"` // The session of the request listing sessions
+}
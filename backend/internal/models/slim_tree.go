@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// SlimNode is the compact form of a RepertoireNode returned for fields=slim. Only the top node
+// keeps its FEN, clients replay the moves to recompute the others, and empty fields are omitted.
+type SlimNode struct {
+	ID              string        `json:"id"`
+	FEN             string        `json:"fen,omitempty"`
+	Move            *string       `json:"move,omitempty"`
+	MoveNumber      int           `json:"moveNumber,omitempty"`
+	ColorToMove     ChessColor    `json:"colorToMove,omitempty"`
+	ParentID        *string       `json:"parentId,omitempty"`
+	Comment         *string       `json:"comment,omitempty"`
+	BranchName      *string       `json:"branchName,omitempty"`
+	Collapsed       bool          `json:"collapsed,omitempty"`
+	TranspositionOf *string       `json:"transpositionOf,omitempty"`
+	Tags            []string      `json:"tags,omitempty"`
+	EditedAt        *time.Time    `json:"editedAt,omitempty"`
+	Opening         *OpeningLabel `json:"opening,omitempty"`
+	Truncated       bool          `json:"truncated,omitempty"`
+	Children        []*SlimNode   `json:"children,omitempty"`
+}
+
+// SlimRepertoire is a Repertoire whose tree is sent as SlimNodes
+type SlimRepertoire struct {
+	Repertoire
+	TreeData *SlimNode `json:"treeData"`
+}
+
+// NewSlimTree converts a tree to its compact form, keeping the FEN of the top node only
+func NewSlimTree(root *RepertoireNode) *SlimNode {
+	slim := newSlimNode(root)
+	slim.FEN = root.FEN
+	return slim
+}
+
+// NewSlimRepertoire converts a repertoire's tree to its compact form
+func NewSlimRepertoire(rep Repertoire) SlimRepertoire {
+	return SlimRepertoire{Repertoire: rep, TreeData: NewSlimTree(&rep.TreeData)}
+}
+
+func newSlimNode(node *RepertoireNode) *SlimNode {
+	slim := &SlimNode{
+		ID:              node.ID,
+		Move:            node.Move,
+		MoveNumber:      node.MoveNumber,
+		ColorToMove:     node.ColorToMove,
+		ParentID:        node.ParentID,
+		Comment:         node.Comment,
+		BranchName:      node.BranchName,
+		Collapsed:       node.Collapsed,
+		TranspositionOf: node.TranspositionOf,
+		Tags:            node.Tags,
+		EditedAt:        node.EditedAt,
+		Opening:         node.Opening,
+		Truncated:       node.Truncated,
+	}
+	for _, child := range node.Children {
+		slim.Children = append(slim.Children, newSlimNode(child))
+	}
+	return slim
+}
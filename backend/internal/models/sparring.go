@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// SparringStatus tells whether a sparring game goes on and, once over, why it ended
+type SparringStatus string
+
+const (
+	SparringActive SparringStatus = "active"
+	// SparringCompleted: the user played their repertoire to the end of the line
+	SparringCompleted SparringStatus = "completed"
+	// SparringDeviated: the user played a move their repertoire does not have
+	SparringDeviated SparringStatus = "deviated"
+	// SparringOutOfBook: the opponent played a move the repertoire does not answer
+	SparringOutOfBook SparringStatus = "out_of_book"
+)
+
+// SparringStartRequest starts a sparring game on a repertoire. The opponent plays the moves
+// of players rated around Rating, or of the Explorer's default bands when it is 0.
+type SparringStartRequest struct {
+	RepertoireID string `json:"repertoireId"`
+	Rating       int    `json:"rating"`
+}
+
+// SparringMoveRequest is the move the user plays in a sparring game
+type SparringMoveRequest struct {
+	SessionID string `json:"sessionId"`
+	Move      string `json:"move"` // SAN
+}
+
+// SparringSession is the state of a sparring game after the last move
+type SparringSession struct {
+	ID           string         `json:"id"`
+	RepertoireID string         `json:"repertoireId"`
+	Color        Color          `json:"color"`
+	Ratings      string         `json:"ratings"` // Explorer rating bands the opponent is sampled from
+	FEN          string         `json:"fen"`
+	Moves        []string       `json:"moves"`
+	Status       SparringStatus `json:"status"`
+	// OpponentMove is the reply the server played after the user's last move, if any
+	OpponentMove *string `json:"opponentMove,omitempty"`
+	// ExpectedMoves are the repertoire moves the user should have played, set once they deviate
+	ExpectedMoves []string      `json:"expectedMoves,omitempty"`
+	Score         SparringScore `json:"score"`
+	ExpiresAt     time.Time     `json:"expiresAt"`
+}
+
+// SparringScore counts the user's moves in a sparring game
+type SparringScore struct {
+	RepertoireMoves int `json:"repertoireMoves"` // moves played as the repertoire prescribes
+	Deviations      int `json:"deviations"`
+	// Percent of the user's moves that followed the repertoire
+	Accuracy int `json:"accuracy"`
+}
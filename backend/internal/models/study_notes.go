@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// StudyNotes is the markdown document of a repertoire, for plans and model games that do not
+// map to a single node. Version 0 means the repertoire has no notes yet.
+type StudyNotes struct {
+	RepertoireID string     `json:"repertoireId"`
+	Version      int        `json:"version"`
+	Content      string     `json:"content"`
+	UpdatedBy    *string    `json:"updatedBy,omitempty"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+}
+
+// StudyNotesRevision is one kept version of the study notes of a repertoire, without its content
+type StudyNotesRevision struct {
+	Version   int       `json:"version"`
+	Length    int       `json:"length"` // in characters
+	UpdatedBy *string   `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// TrainingAnswerRequest is the move a user played in a training position
+type TrainingAnswerRequest struct {
+	NodeID string `json:"nodeId"`
+	Move   string `json:"move"` // SAN
+}
+
+// TrainingAnswerResult tells whether a training answer was a repertoire move
+type TrainingAnswerResult struct {
+	Correct       bool            `json:"correct"`
+	ExpectedMoves []string        `json:"expectedMoves"`
+	Session       TrainingSession `json:"session"`
+}
+
+// TrainingSession groups the answers given on a repertoire without a long pause
+type TrainingSession struct {
+	ID           string    `json:"id"`
+	RepertoireID string    `json:"repertoireId"`
+	StartedAt    time.Time `json:"startedAt"`
+	LastAnswerAt time.Time `json:"lastAnswerAt"`
+	Reviews      int       `json:"reviews"`
+	Correct      int       `json:"correct"`
+}
+
+// TrainingDay is the training a user did on one day (UTC)
+type TrainingDay struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Reviews int    `json:"reviews"`
+	Correct int    `json:"correct"`
+}
+
+// TrainingActivity is a user's training calendar with their streaks
+type TrainingActivity struct {
+	Days          []TrainingDay `json:"days"`          // Days with training in the requested range, oldest first
+	CurrentStreak int           `json:"currentStreak"` // Consecutive training days ending today, or yesterday until today's training
+	LongestStreak int           `json:"longestStreak"`
+	TotalReviews  int           `json:"totalReviews"` // Within the requested range
+	Accuracy      float64       `json:"accuracy"`     // Share of correct answers within the requested range
+}
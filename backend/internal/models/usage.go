@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// UsageKind is an external service whose calls are counted against a daily quota
+type UsageKind string
+
+const (
+	UsageExplorer      UsageKind = "explorer"       // Lichess opening explorer lookups missing the cache
+	UsageLichessExport UsageKind = "lichess_export" // Lichess game exports
+	UsageChesscom      UsageKind = "chesscom"       // Chess.com game archive downloads
+)
+
+// UsageKinds lists the counted services in the order reports show them
+var UsageKinds = []UsageKind{UsageExplorer, UsageLichessExport, UsageChesscom}
+
+// UsageCounter is a user's consumption of one service today
+type UsageCounter struct {
+	Kind UsageKind `json:"kind"`
+	Used int       `json:"used"`
+	// Limit is the daily quota, 0 when the service is not capped
+	Limit int `json:"limit"`
+}
+
+// UsageReport is a user's consumption of external services on the current UTC day
+type UsageReport struct {
+	Day      string         `json:"day"`
+	ResetsAt time.Time      `json:"resetsAt"`
+	Counters []UsageCounter `json:"counters"`
+}
+
+// UsageTotal sums the consumption of one service by all users on a day
+type UsageTotal struct {
+	Kind  UsageKind `json:"kind"`
+	Used  int       `json:"used"`
+	Users int       `json:"users"`
+	// MaxUsed is the consumption of the heaviest user, to compare with Limit
+	MaxUsed int `json:"maxUsed"`
+	Limit   int `json:"limit"`
+}
+
+// UsageTotals is the operators' view of external service consumption on the current UTC day
+type UsageTotals struct {
+	Day    string       `json:"day"`
+	Totals []UsageTotal `json:"totals"`
+}
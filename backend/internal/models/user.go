@@ -1,21 +1,81 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type User struct {
-	ID                 string     `json:"id"`
-	Username           string     `json:"username"`
-	Email              *string    `json:"email,omitempty"`
-	PasswordHash       string     `json:"-"`
-	OAuthProvider      *string    `json:"oauthProvider,omitempty"`
-	OAuthID            *string    `json:"-"`
-	LichessUsername    *string    `json:"lichessUsername,omitempty"`
-	ChesscomUsername   *string    `json:"chesscomUsername,omitempty"`
-	LichessAccessToken *string    `json:"-"`
-	LastLichessSyncAt  *time.Time `json:"lastLichessSyncAt,omitempty"`
-	LastChesscomSyncAt *time.Time `json:"lastChesscomSyncAt,omitempty"`
-	TimeFormatPrefs    []string   `json:"timeFormatPrefs,omitempty"`
-	CreatedAt          time.Time  `json:"createdAt"`
+	ID                 string          `json:"id"`
+	Username           string          `json:"username"`
+	Email              *string         `json:"email,omitempty"`
+	PasswordHash       string          `json:"-"`
+	OAuthProvider      *string         `json:"oauthProvider,omitempty"`
+	OAuthID            *string         `json:"-"`
+	LinkedAccounts     []LinkedAccount `json:"linkedAccounts"`
+	LichessUsername    *string         `json:"lichessUsername,omitempty"`  // First linked Lichess account, kept for older clients
+	ChesscomUsername   *string         `json:"chesscomUsername,omitempty"` // First linked Chess.com account, kept for older clients
+	LichessAccessToken *string         `json:"-"`
+	LastLichessSyncAt  *time.Time      `json:"lastLichessSyncAt,omitempty"`
+	LastChesscomSyncAt *time.Time      `json:"lastChesscomSyncAt,omitempty"`
+	TimeFormatPrefs    []string        `json:"timeFormatPrefs,omitempty"`
+	AnalysisDepth      int             `json:"analysisDepth"` // Moves of each imported game matched against repertoires, 0 for whole games
+	WeeklyDigest       bool            `json:"weeklyDigest"`  // Opted in to the weekly email digest
+	Timezone           string          `json:"timezone"`      // IANA time zone the user's days are counted in
+	Locale             string          `json:"locale"`        // BCP 47 tag dates are formatted for
+	CreatedAt          time.Time       `json:"createdAt"`
+}
+
+// Location returns the user's time zone, UTC when it is unset or unknown
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Linked account providers
+const (
+	ProviderLichess  = "lichess"
+	ProviderChesscom = "chesscom"
+)
+
+// LinkedAccount is a Lichess or Chess.com username whose games belong to a user
+type LinkedAccount struct {
+	ID         string     `json:"id"`
+	Provider   string     `json:"provider"` // lichess, chesscom
+	Username   string     `json:"username"`
+	LastSyncAt *time.Time `json:"lastSyncAt,omitempty"`
+}
+
+// AccountsFor returns the user's linked accounts for a provider
+func (u *User) AccountsFor(provider string) []LinkedAccount {
+	var accounts []LinkedAccount
+	for _, a := range u.LinkedAccounts {
+		if a.Provider == provider {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts
+}
+
+// SetLinkedAccounts replaces the user's linked accounts and derives the legacy single-username fields
+func (u *User) SetLinkedAccounts(accounts []LinkedAccount) {
+	u.LinkedAccounts = accounts
+	u.LichessUsername = nil
+	u.ChesscomUsername = nil
+	for i := range accounts {
+		switch {
+		case accounts[i].Provider == ProviderLichess && u.LichessUsername == nil:
+			u.LichessUsername = &accounts[i].Username
+		case accounts[i].Provider == ProviderChesscom && u.ChesscomUsername == nil:
+			u.ChesscomUsername = &accounts[i].Username
+		}
+	}
 }
 
 type SyncResult struct {
@@ -25,10 +85,76 @@ type SyncResult struct {
 	ChesscomError         string `json:"chesscomError,omitempty"`
 }
 
+// Sync run statuses
+const (
+	SyncRunRunning = "running"
+	SyncRunDone    = "done"
+	SyncRunFailed  = "failed"
+)
+
+// SyncRun records one execution of a sync with the outcome of every linked account.
+// The embedded SyncResult holds the per-provider totals derived from Accounts.
+type SyncRun struct {
+	SyncResult
+	ID         string              `json:"id"`
+	UserID     string              `json:"userId"`
+	Status     string              `json:"status"`
+	Accounts   []SyncAccountResult `json:"accounts"`
+	Error      string              `json:"error,omitempty"`
+	StartedAt  time.Time           `json:"startedAt"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+}
+
+// SyncAccountResult is the outcome of syncing a single linked account
+type SyncAccountResult struct {
+	Provider      string `json:"provider"`
+	Username      string `json:"username"`
+	GamesFetched  int    `json:"gamesFetched"`
+	GamesImported int    `json:"gamesImported"`
+	GamesSkipped  int    `json:"gamesSkipped"`
+	Error         string `json:"error,omitempty"`
+}
+
+// SetAccounts stores the account results and recomputes the per-provider totals
+func (r *SyncRun) SetAccounts(accounts []SyncAccountResult) {
+	r.Accounts = accounts
+	r.SyncResult = SyncResult{}
+
+	var lichessErrs, chesscomErrs []string
+	for _, a := range accounts {
+		switch a.Provider {
+		case ProviderLichess:
+			r.LichessGamesImported += a.GamesImported
+			if a.Error != "" {
+				lichessErrs = append(lichessErrs, a.Username+": "+a.Error)
+			}
+		case ProviderChesscom:
+			r.ChesscomGamesImported += a.GamesImported
+			if a.Error != "" {
+				chesscomErrs = append(chesscomErrs, a.Username+": "+a.Error)
+			}
+		}
+	}
+	r.LichessError = strings.Join(lichessErrs, "; ")
+	r.ChesscomError = strings.Join(chesscomErrs, "; ")
+}
+
 type UpdateProfileRequest struct {
-	LichessUsername  *string  `json:"lichessUsername"`
-	ChesscomUsername *string  `json:"chesscomUsername"`
-	TimeFormatPrefs  []string `json:"timeFormatPrefs,omitempty"`
+	// LinkedAccounts replaces every linked account when present.
+	// When absent, LichessUsername and ChesscomUsername set a single account per provider.
+	LinkedAccounts   []LinkedAccountInput `json:"linkedAccounts,omitempty"`
+	LichessUsername  *string              `json:"lichessUsername"`
+	ChesscomUsername *string              `json:"chesscomUsername"`
+	TimeFormatPrefs  []string             `json:"timeFormatPrefs,omitempty"`
+	AnalysisDepth    *int                 `json:"analysisDepth,omitempty"`
+	Timezone         *string              `json:"timezone,omitempty"`
+	Locale           *string              `json:"locale,omitempty"`
+}
+
+// LinkedAccountInput is a provider/username pair submitted with a profile update
+type LinkedAccountInput struct {
+	Provider string `json:"provider"`
+	Username string `json:"username"`
 }
 
 type RegisterRequest struct {
@@ -46,3 +172,36 @@ type AuthResponse struct {
 	Token string `json:"token"`
 	User  User   `json:"user"`
 }
+
+// UserDataExport is everything stored for a user, as written by the maintenance export
+type UserDataExport struct {
+	ExportedAt  time.Time     `json:"exportedAt"`
+	User        User          `json:"user"`
+	Repertoires []Repertoire  `json:"repertoires"`
+	Analyses    []RawAnalysis `json:"analyses"`
+}
+
+// UpdateNotificationsRequest sets the email notifications a user receives
+type UpdateNotificationsRequest struct {
+	WeeklyDigest bool `json:"weeklyDigest"`
+}
+
+// WeeklyDigest summarizes a user's week for the digest email
+type WeeklyDigest struct {
+	Since           time.Time
+	GamesImported   int
+	UnreviewedGames int
+	NewMistakes     []OpeningMistake
+	WorstBranches   []BranchResult
+	TrainingDue     int
+	Timezone        string // of the recipient, for the dates of the email
+	Locale          string
+}
+
+// BranchResult is the user's record in the games reaching a repertoire position
+type BranchResult struct {
+	RepertoireName string
+	Moves          []string
+	Games          int
+	Score          float64 // (wins + draws/2) / games
+}
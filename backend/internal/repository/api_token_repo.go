@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	apiTokenColumns = `id, user_id, name, scope, prefix, created_at, last_used_at`
+
+	createAPITokenSQL = `
+		INSERT INTO api_tokens (user_id, name, scope, prefix, token_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + apiTokenColumns
+	useAPITokenSQL = `
+		UPDATE api_tokens SET last_used_at = $2
+		WHERE token_hash = $1 AND revoked_at IS NULL
+		RETURNING ` + apiTokenColumns
+	listAPITokensSQL = `
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+	countAPITokensSQL = `
+		SELECT COUNT(*) FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	revokeAPITokenSQL = `
+		UPDATE api_tokens SET revoked_at = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+)
+
+// PostgresAPITokenRepo implements APITokenRepository using PostgreSQL
+type PostgresAPITokenRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAPITokenRepo creates a new PostgresAPITokenRepo
+func NewPostgresAPITokenRepo(pool *pgxpool.Pool) *PostgresAPITokenRepo {
+	return &PostgresAPITokenRepo{pool: pool}
+}
+
+func scanAPIToken(scan func(dest ...any) error) (*models.APIToken, error) {
+	var t models.APIToken
+	if err := scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.Prefix, &t.CreatedAt, &t.LastUsedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create stores a new token of a user by the hash of its secret
+func (r *PostgresAPITokenRepo) Create(userID, name, scope, prefix, tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	token, err := scanAPIToken(r.pool.QueryRow(ctx, createAPITokenSQL, userID, name, scope, prefix, tokenHash).Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api token: %w", err)
+	}
+	return token, nil
+}
+
+// Use records that a token was used and returns it, or ErrAPITokenNotFound if it is unknown or revoked
+func (r *PostgresAPITokenRepo) Use(tokenHash string, usedAt time.Time) (*models.APIToken, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	token, err := scanAPIToken(r.pool.QueryRow(ctx, useAPITokenSQL, tokenHash, usedAt).Scan)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, fmt.Errorf("failed to use api token: %w", err)
+	}
+	return token, nil
+}
+
+// ListByUser returns the active tokens of a user, newest first
+func (r *PostgresAPITokenRepo) ListByUser(userID string) ([]models.APIToken, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listAPITokensSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// CountByUser returns the number of active tokens of a user
+func (r *PostgresAPITokenRepo) CountByUser(userID string) (int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	if err := r.pool.QueryRow(ctx, countAPITokensSQL, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count api tokens: %w", err)
+	}
+	return count, nil
+}
+
+// Revoke disables a token of a user; ErrAPITokenNotFound if the user has no such active token
+func (r *PostgresAPITokenRepo) Revoke(id, userID string, revokedAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, revokeAPITokenSQL, id, userID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
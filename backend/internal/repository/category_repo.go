@@ -13,18 +13,18 @@ import (
 
 const (
 	getCategoryByIDSQL = `
-		SELECT id, name, color, created_at, updated_at
+		SELECT id, name, color, match_active, created_at, updated_at
 		FROM categories
 		WHERE id = $1
 	`
 	getCategoriesByUserAndColorSQL = `
-		SELECT id, name, color, created_at, updated_at
+		SELECT id, name, color, match_active, created_at, updated_at
 		FROM categories
 		WHERE user_id = $1 AND color = $2
 		ORDER BY name ASC
 	`
 	getAllCategoriesByUserSQL = `
-		SELECT id, name, color, created_at, updated_at
+		SELECT id, name, color, match_active, created_at, updated_at
 		FROM categories
 		WHERE user_id = $1
 		ORDER BY color, name ASC
@@ -32,13 +32,19 @@ const (
 	createCategorySQL = `
 		INSERT INTO categories (id, user_id, name, color)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, color, created_at, updated_at
+		RETURNING id, name, color, match_active, created_at, updated_at
 	`
 	updateCategoryNameSQL = `
 		UPDATE categories
 		SET name = $2, updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, name, color, created_at, updated_at
+		RETURNING id, name, color, match_active, created_at, updated_at
+	`
+	updateCategoryMatchActiveSQL = `
+		UPDATE categories
+		SET match_active = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, name, color, match_active, created_at, updated_at
 	`
 	deleteCategorySQL = `
 		DELETE FROM categories WHERE id = $1
@@ -61,6 +67,7 @@ type CategoryRepository interface {
 	GetAll(userID string) ([]models.Category, error)
 	Create(userID, name string, color models.Color) (*models.Category, error)
 	UpdateName(id, name string) (*models.Category, error)
+	SetMatchActive(id string, active bool) (*models.Category, error)
 	Delete(id string) error
 	BelongsToUser(id, userID string) (bool, error)
 	Exists(id string) (bool, error)
@@ -87,6 +94,7 @@ func (r *PostgresCategoryRepo) GetByID(id string) (*models.Category, error) {
 		&cat.ID,
 		&cat.Name,
 		&cat.Color,
+		&cat.MatchActive,
 		&cat.CreatedAt,
 		&cat.UpdatedAt,
 	)
@@ -140,6 +148,7 @@ func (r *PostgresCategoryRepo) Create(userID, name string, color models.Color) (
 		&cat.ID,
 		&cat.Name,
 		&cat.Color,
+		&cat.MatchActive,
 		&cat.CreatedAt,
 		&cat.UpdatedAt,
 	)
@@ -160,6 +169,7 @@ func (r *PostgresCategoryRepo) UpdateName(id, name string) (*models.Category, er
 		&cat.ID,
 		&cat.Name,
 		&cat.Color,
+		&cat.MatchActive,
 		&cat.CreatedAt,
 		&cat.UpdatedAt,
 	)
@@ -173,6 +183,30 @@ func (r *PostgresCategoryRepo) UpdateName(id, name string) (*models.Category, er
 	return &cat, nil
 }
 
+// SetMatchActive sets whether the repertoires of a category are matched against imported games
+func (r *PostgresCategoryRepo) SetMatchActive(id string, active bool) (*models.Category, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var cat models.Category
+	err := r.pool.QueryRow(ctx, updateCategoryMatchActiveSQL, id, active).Scan(
+		&cat.ID,
+		&cat.Name,
+		&cat.Color,
+		&cat.MatchActive,
+		&cat.CreatedAt,
+		&cat.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to update category matching: %w", err)
+	}
+
+	return &cat, nil
+}
+
 // Delete deletes a category by ID (repertoires will cascade delete)
 func (r *PostgresCategoryRepo) Delete(id string) error {
 	ctx, cancel := dbContext()
@@ -243,6 +277,7 @@ func (r *PostgresCategoryRepo) scanCategories(rows interface {
 			&cat.ID,
 			&cat.Name,
 			&cat.Color,
+			&cat.MatchActive,
 			&cat.CreatedAt,
 			&cat.UpdatedAt,
 		)
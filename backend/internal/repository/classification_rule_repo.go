@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	classificationRuleColumnsSQL = `
+		id, user_id, key, name, move_status, mover, min_winrate_drop, min_time_spent, max_ply, created_at
+	`
+	createClassificationRuleSQL = `
+		INSERT INTO classification_rules (user_id, key, name, move_status, mover, min_winrate_drop, min_time_spent, max_ply)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + classificationRuleColumnsSQL
+	getClassificationRulesByUserSQL = `
+		SELECT ` + classificationRuleColumnsSQL + `
+		FROM classification_rules
+		WHERE user_id = $1
+		ORDER BY created_at, key
+	`
+	countClassificationRulesSQL = `
+		SELECT COUNT(*) FROM classification_rules WHERE user_id = $1
+	`
+	updateClassificationRuleSQL = `
+		UPDATE classification_rules
+		SET key = $2, name = $3, move_status = $4, mover = $5, min_winrate_drop = $6, min_time_spent = $7, max_ply = $8
+		WHERE id = $1
+		RETURNING ` + classificationRuleColumnsSQL
+	deleteClassificationRuleSQL = `
+		DELETE FROM classification_rules WHERE id = $1
+	`
+	belongsToUserClassificationRuleSQL = `
+		SELECT EXISTS(SELECT 1 FROM classification_rules WHERE id = $1 AND user_id = $2)
+	`
+)
+
+// PostgresClassificationRuleRepo implements ClassificationRuleRepository using PostgreSQL
+type PostgresClassificationRuleRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresClassificationRuleRepo creates a new PostgreSQL classification rule repository
+func NewPostgresClassificationRuleRepo(pool *pgxpool.Pool) *PostgresClassificationRuleRepo {
+	return &PostgresClassificationRuleRepo{pool: pool}
+}
+
+func scanClassificationRule(row pgx.Row) (*models.ClassificationRule, error) {
+	var rule models.ClassificationRule
+	err := row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.Key,
+		&rule.Name,
+		&rule.MoveStatus,
+		&rule.Mover,
+		&rule.MinWinrateDrop,
+		&rule.MinTimeSpent,
+		&rule.MaxPly,
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Create stores a new classification rule of a user
+func (r *PostgresClassificationRuleRepo) Create(userID string, rule models.ClassificationRule) (*models.ClassificationRule, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	created, err := scanClassificationRule(r.pool.QueryRow(ctx, createClassificationRuleSQL,
+		userID, rule.Key, rule.Name, rule.MoveStatus, rule.Mover, rule.MinWinrateDrop, rule.MinTimeSpent, rule.MaxPly))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrClassificationRuleKeyExists
+		}
+		return nil, fmt.Errorf("failed to create classification rule: %w", err)
+	}
+	return created, nil
+}
+
+// GetByUser returns the classification rules of a user, oldest first
+func (r *PostgresClassificationRuleRepo) GetByUser(userID string) ([]models.ClassificationRule, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getClassificationRulesByUserSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query classification rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.ClassificationRule
+	for rows.Next() {
+		rule, err := scanClassificationRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan classification rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating classification rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// CountByUser returns the number of classification rules a user has
+func (r *PostgresClassificationRuleRepo) CountByUser(userID string) (int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	if err := r.pool.QueryRow(ctx, countClassificationRulesSQL, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count classification rules: %w", err)
+	}
+	return count, nil
+}
+
+// Update replaces the key, name and conditions of a classification rule
+func (r *PostgresClassificationRuleRepo) Update(id string, rule models.ClassificationRule) (*models.ClassificationRule, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	updated, err := scanClassificationRule(r.pool.QueryRow(ctx, updateClassificationRuleSQL,
+		id, rule.Key, rule.Name, rule.MoveStatus, rule.Mover, rule.MinWinrateDrop, rule.MinTimeSpent, rule.MaxPly))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrClassificationRuleNotFound
+		}
+		if isDuplicateKeyError(err) {
+			return nil, ErrClassificationRuleKeyExists
+		}
+		return nil, fmt.Errorf("failed to update classification rule: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete deletes a classification rule by ID
+func (r *PostgresClassificationRuleRepo) Delete(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, deleteClassificationRuleSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete classification rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrClassificationRuleNotFound
+	}
+	return nil
+}
+
+// BelongsToUser checks if a classification rule belongs to a specific user
+func (r *PostgresClassificationRuleRepo) BelongsToUser(id, userID string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var belongs bool
+	if err := r.pool.QueryRow(ctx, belongsToUserClassificationRuleSQL, id, userID).Scan(&belongs); err != nil {
+		return false, fmt.Errorf("failed to check classification rule ownership: %w", err)
+	}
+	return belongs, nil
+}
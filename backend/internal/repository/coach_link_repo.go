@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	coachLinkSelectSQL = `
+		SELECT l.id, l.coach_id, coach.username, l.student_id, student.username, l.invited_by,
+			l.status, l.created_at, l.accepted_at
+		FROM coach_links l
+		JOIN users coach ON coach.id = l.coach_id
+		JOIN users student ON student.id = l.student_id
+	`
+	createCoachLinkSQL = `
+		INSERT INTO coach_links (coach_id, student_id, invited_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (coach_id, student_id) DO NOTHING
+		RETURNING id
+	`
+	getCoachLinkSQL      = coachLinkSelectSQL + `WHERE l.id = $1`
+	listCoachLinksSQL    = coachLinkSelectSQL + `WHERE l.coach_id = $1 OR l.student_id = $1 ORDER BY l.created_at DESC`
+	acceptCoachLinkSQL   = `UPDATE coach_links SET status = 'active', accepted_at = $2 WHERE id = $1`
+	deleteCoachLinkSQL   = `DELETE FROM coach_links WHERE id = $1`
+	isActiveCoachLinkSQL = `SELECT EXISTS(SELECT 1 FROM coach_links WHERE coach_id = $1 AND student_id = $2 AND status = 'active')`
+)
+
+// PostgresCoachLinkRepo implements CoachLinkRepository using PostgreSQL
+type PostgresCoachLinkRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCoachLinkRepo creates a new PostgresCoachLinkRepo
+func NewPostgresCoachLinkRepo(pool *pgxpool.Pool) *PostgresCoachLinkRepo {
+	return &PostgresCoachLinkRepo{pool: pool}
+}
+
+func scanCoachLink(row pgx.Row) (*models.CoachLink, error) {
+	var l models.CoachLink
+	if err := row.Scan(&l.ID, &l.CoachID, &l.CoachUsername, &l.StudentID, &l.StudentUsername, &l.InvitedBy,
+		&l.Status, &l.CreatedAt, &l.AcceptedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Create records a pending link. ErrCoachLinkExists is returned when the pair is already linked or invited.
+func (r *PostgresCoachLinkRepo) Create(coachID, studentID, invitedBy string) (*models.CoachLink, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var id string
+	if err := r.pool.QueryRow(ctx, createCoachLinkSQL, coachID, studentID, invitedBy).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCoachLinkExists
+		}
+		return nil, fmt.Errorf("failed to create coach link: %w", err)
+	}
+	return r.GetByID(id)
+}
+
+// GetByID returns a link with the usernames of both sides
+func (r *PostgresCoachLinkRepo) GetByID(id string) (*models.CoachLink, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	l, err := scanCoachLink(r.pool.QueryRow(ctx, getCoachLinkSQL, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCoachLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get coach link: %w", err)
+	}
+	return l, nil
+}
+
+// ListByUser returns the links a user is on either side of, most recent first
+func (r *PostgresCoachLinkRepo) ListByUser(userID string) ([]models.CoachLink, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listCoachLinksSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coach links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.CoachLink{}
+	for rows.Next() {
+		l, err := scanCoachLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coach link: %w", err)
+		}
+		links = append(links, *l)
+	}
+	return links, rows.Err()
+}
+
+// Accept activates a link
+func (r *PostgresCoachLinkRepo) Accept(id string, at time.Time) (*models.CoachLink, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, acceptCoachLinkSQL, id, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept coach link: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrCoachLinkNotFound
+	}
+	return r.GetByID(id)
+}
+
+// Delete removes a link, pending or active
+func (r *PostgresCoachLinkRepo) Delete(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, deleteCoachLinkSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete coach link: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCoachLinkNotFound
+	}
+	return nil
+}
+
+// IsActive reports whether the coach has an accepted link with the student
+func (r *PostgresCoachLinkRepo) IsActive(coachID, studentID string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var active bool
+	if err := r.pool.QueryRow(ctx, isActiveCoachLinkSQL, coachID, studentID).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check coach link: %w", err)
+	}
+	return active, nil
+}
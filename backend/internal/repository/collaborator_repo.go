@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	upsertCollaboratorSQL = `
+		INSERT INTO repertoire_collaborators (repertoire_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (repertoire_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+	getCollaboratorSQL = `
+		SELECT c.repertoire_id, c.user_id, u.username, c.role, c.created_at
+		FROM repertoire_collaborators c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.repertoire_id = $1 AND c.user_id = $2
+	`
+	listCollaboratorsSQL = `
+		SELECT c.repertoire_id, c.user_id, u.username, c.role, c.created_at
+		FROM repertoire_collaborators c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.repertoire_id = $1
+		ORDER BY c.created_at
+	`
+	listCollaborationsByUserSQL = `
+		SELECT c.repertoire_id, c.user_id, u.username, c.role, c.created_at
+		FROM repertoire_collaborators c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at DESC
+	`
+	deleteCollaboratorSQL = `
+		DELETE FROM repertoire_collaborators WHERE repertoire_id = $1 AND user_id = $2
+	`
+)
+
+// PostgresCollaboratorRepo implements CollaboratorRepository using PostgreSQL
+type PostgresCollaboratorRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCollaboratorRepo creates a new PostgresCollaboratorRepo
+func NewPostgresCollaboratorRepo(pool *pgxpool.Pool) *PostgresCollaboratorRepo {
+	return &PostgresCollaboratorRepo{pool: pool}
+}
+
+func scanCollaborator(row pgx.Row) (*models.RepertoireCollaborator, error) {
+	var c models.RepertoireCollaborator
+	if err := row.Scan(&c.RepertoireID, &c.UserID, &c.Username, &c.Role, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Upsert grants a user a role on a repertoire, replacing any role they already had
+func (r *PostgresCollaboratorRepo) Upsert(repertoireID, userID string, role models.CollaboratorRole, invitedBy string) (*models.RepertoireCollaborator, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, upsertCollaboratorSQL, repertoireID, userID, string(role), invitedBy); err != nil {
+		return nil, fmt.Errorf("failed to save collaborator: %w", err)
+	}
+
+	c, err := scanCollaborator(r.pool.QueryRow(ctx, getCollaboratorSQL, repertoireID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collaborator: %w", err)
+	}
+	return c, nil
+}
+
+// Get returns a user's collaboration on a repertoire
+func (r *PostgresCollaboratorRepo) Get(repertoireID, userID string) (*models.RepertoireCollaborator, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	c, err := scanCollaborator(r.pool.QueryRow(ctx, getCollaboratorSQL, repertoireID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCollaboratorNotFound
+		}
+		return nil, fmt.Errorf("failed to get collaborator: %w", err)
+	}
+	return c, nil
+}
+
+// ListByRepertoire returns the collaborators of a repertoire, oldest invitation first
+func (r *PostgresCollaboratorRepo) ListByRepertoire(repertoireID string) ([]models.RepertoireCollaborator, error) {
+	return r.list(listCollaboratorsSQL, repertoireID)
+}
+
+// ListByUser returns the repertoires shared with a user, most recent first
+func (r *PostgresCollaboratorRepo) ListByUser(userID string) ([]models.RepertoireCollaborator, error) {
+	return r.list(listCollaborationsByUserSQL, userID)
+}
+
+func (r *PostgresCollaboratorRepo) list(query, arg string) ([]models.RepertoireCollaborator, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collaborators: %w", err)
+	}
+	defer rows.Close()
+
+	collaborators := []models.RepertoireCollaborator{}
+	for rows.Next() {
+		c, err := scanCollaborator(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collaborator: %w", err)
+		}
+		collaborators = append(collaborators, *c)
+	}
+	return collaborators, rows.Err()
+}
+
+// Delete revokes a user's access to a repertoire
+func (r *PostgresCollaboratorRepo) Delete(repertoireID, userID string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, deleteCollaboratorSQL, repertoireID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete collaborator: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCollaboratorNotFound
+	}
+	return nil
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
 )
 
 // DB wraps the database connection pool
@@ -15,7 +17,7 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
-// NewDB creates a new database connection and runs migrations
+// NewDB creates a new database connection and, when cfg.AutoMigrate is set, applies pending migrations
 func NewDB(cfg config.Config) (*DB, error) {
 	pool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
 	if err != nil {
@@ -32,7 +34,11 @@ func NewDB(cfg config.Config) (*DB, error) {
 
 	db := &DB{Pool: pool}
 
-	if err := db.runMigrations(); err != nil {
+	if !cfg.AutoMigrate {
+		log.Println("Skipping database migrations (AUTO_MIGRATE=false)")
+		return db, nil
+	}
+	if err := db.Migrate(); err != nil {
 		pool.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
@@ -52,158 +58,105 @@ func dbContext() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), config.DefaultDBTimeout)
 }
 
-func (db *DB) runMigrations() error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.MigrationDBTimeout)
-	defer cancel()
+// backfillGameSummaries fills the derived time_class and status columns for games
+// migrated from the legacy results blobs, which cannot be computed in SQL.
+func (db *DB) backfillGameSummaries(ctx context.Context) error {
+	rows, err := db.Pool.Query(ctx, `SELECT analysis_id, game_index, headers, moves FROM games WHERE status IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to query games: %w", err)
+	}
+
+	type pending struct {
+		analysisID string
+		gameIndex  int
+		timeClass  string
+		status     string
+	}
+	var updates []pending
+	for rows.Next() {
+		var p pending
+		var game models.GameAnalysis
+		var headersJSON, movesJSON []byte
+		if err := rows.Scan(&p.analysisID, &p.gameIndex, &headersJSON, &movesJSON); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan game: %w", err)
+		}
+		if err := json.Unmarshal(headersJSON, &game.Headers); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to unmarshal headers: %w", err)
+		}
+		if err := json.Unmarshal(movesJSON, &game.Moves); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to unmarshal moves: %w", err)
+		}
+		p.timeClass = models.ClassifyTimeControl(game.Headers["TimeControl"])
+		p.status = computeGameStatus(game)
+		updates = append(updates, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating games: %w", err)
+	}
+
+	for _, p := range updates {
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE games SET time_class = $3, status = $4 WHERE analysis_id = $1 AND game_index = $2`,
+			p.analysisID, p.gameIndex, p.timeClass, p.status,
+		); err != nil {
+			return fmt.Errorf("failed to update game: %w", err)
+		}
+	}
+
+	return nil
+}
 
-	schema := `
-		-- Create users table
-		CREATE TABLE IF NOT EXISTS users (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			username VARCHAR(50) NOT NULL UNIQUE,
-			password_hash VARCHAR(255),
-			oauth_provider VARCHAR(20),
-			oauth_id VARCHAR(255),
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			UNIQUE(oauth_provider, oauth_id)
-		);
-
-		-- Create repertoires table
-		CREATE TABLE IF NOT EXISTS repertoires (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			user_id UUID NOT NULL REFERENCES users(id),
-			name VARCHAR(100) NOT NULL DEFAULT 'Main Repertoire',
-			color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
-			tree_data JSONB NOT NULL,
-			metadata JSONB NOT NULL DEFAULT '{"totalNodes": 0, "totalMoves": 0, "deepestDepth": 0}',
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		);
-
-		-- Create analyses table
-		CREATE TABLE IF NOT EXISTS analyses (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			user_id UUID NOT NULL REFERENCES users(id),
-			username VARCHAR(255) NOT NULL,
-			filename VARCHAR(255) NOT NULL,
-			game_count INTEGER NOT NULL,
-			results JSONB NOT NULL,
-			uploaded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		);
-
-		-- Create indexes
-		CREATE INDEX IF NOT EXISTS idx_repertoires_user_id ON repertoires(user_id);
-		CREATE INDEX IF NOT EXISTS idx_repertoires_color ON repertoires(color);
-		CREATE INDEX IF NOT EXISTS idx_repertoires_updated ON repertoires(updated_at DESC);
-		CREATE INDEX IF NOT EXISTS idx_repertoires_name ON repertoires(name);
-		CREATE INDEX IF NOT EXISTS idx_analyses_user_id ON analyses(user_id);
-		CREATE INDEX IF NOT EXISTS idx_analyses_username ON analyses(username);
-		CREATE INDEX IF NOT EXISTS idx_analyses_uploaded ON analyses(uploaded_at DESC);
-		-- Create function to enforce max 50 repertoires per user
-		CREATE OR REPLACE FUNCTION check_repertoire_limit()
-		RETURNS TRIGGER AS $$
-		BEGIN
-			IF (SELECT COUNT(*) FROM repertoires WHERE user_id = NEW.user_id) >= 50 THEN
-				RAISE EXCEPTION 'Maximum of 50 repertoires allowed';
-			END IF;
-			RETURN NEW;
-		END;
-		$$ LANGUAGE plpgsql;
-
-		-- Drop trigger if it exists (for idempotency)
-		DROP TRIGGER IF EXISTS repertoire_limit_trigger ON repertoires;
-
-		-- Create trigger to enforce limit
-		CREATE TRIGGER repertoire_limit_trigger
-			BEFORE INSERT ON repertoires
-			FOR EACH ROW EXECUTE FUNCTION check_repertoire_limit();
-	`
-
-	_, err := db.Pool.Exec(ctx, schema)
+// backfillRepertoirePositions indexes the positions of repertoires saved before the
+// repertoire_positions table existed. Every repertoire has at least its root node indexed.
+func (db *DB) backfillRepertoirePositions(ctx context.Context) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT r.id, r.user_id, r.tree_data FROM repertoires r
+		WHERE NOT EXISTS (SELECT 1 FROM repertoire_positions p WHERE p.repertoire_id = r.id)`)
 	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+		return fmt.Errorf("failed to query repertoires: %w", err)
 	}
 
-	migrations := []string{
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS lichess_username VARCHAR(50)`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS chesscom_username VARCHAR(50)`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_lichess_sync_at TIMESTAMP WITH TIME ZONE`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_chesscom_sync_at TIMESTAMP WITH TIME ZONE`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS lichess_access_token TEXT`,
-		`CREATE TABLE IF NOT EXISTS game_fingerprints (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			user_id UUID NOT NULL REFERENCES users(id),
-			fingerprint VARCHAR(512) NOT NULL,
-			analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
-			game_index INTEGER NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			UNIQUE(user_id, fingerprint)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_game_fingerprints_user ON game_fingerprints(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_game_fingerprints_analysis ON game_fingerprints(analysis_id)`,
-		`CREATE TABLE IF NOT EXISTS viewed_games (
-			user_id UUID NOT NULL REFERENCES users(id),
-			analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
-			game_index INTEGER NOT NULL,
-			viewed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			PRIMARY KEY (user_id, analysis_id, game_index)
-		)`,
-		`CREATE TABLE IF NOT EXISTS engine_evals (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			user_id UUID NOT NULL REFERENCES users(id),
-			analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
-			game_index INTEGER NOT NULL,
-			status VARCHAR(20) NOT NULL DEFAULT 'pending',
-			evals JSONB,
-			created_at TIMESTAMPTZ DEFAULT NOW(),
-			updated_at TIMESTAMPTZ DEFAULT NOW(),
-			UNIQUE(analysis_id, game_index)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_engine_evals_user ON engine_evals(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_engine_evals_status ON engine_evals(status)`,
-		`CREATE TABLE IF NOT EXISTS dismissed_mistakes (
-			user_id UUID NOT NULL REFERENCES users(id),
-			fen TEXT NOT NULL,
-			played_move VARCHAR(10) NOT NULL,
-			dismissed_at TIMESTAMPTZ DEFAULT NOW(),
-			PRIMARY KEY (user_id, fen, played_move)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_dismissed_mistakes_user ON dismissed_mistakes(user_id)`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS time_format_prefs TEXT[] DEFAULT '{}'`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255)`,
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL`,
-		`CREATE TABLE IF NOT EXISTS password_reset_tokens (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-			token_hash VARCHAR(64) NOT NULL UNIQUE,
-			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
-			used_at TIMESTAMP WITH TIME ZONE,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_hash ON password_reset_tokens(token_hash)`,
-		// Categories table for grouping repertoires
-		`CREATE TABLE IF NOT EXISTS categories (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			user_id UUID NOT NULL REFERENCES users(id),
-			name VARCHAR(100) NOT NULL,
-			color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			UNIQUE(user_id, name, color)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_categories_color ON categories(color)`,
-		// Add category_id to repertoires with cascade delete
-		`ALTER TABLE repertoires ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id) ON DELETE CASCADE`,
-		`CREATE INDEX IF NOT EXISTS idx_repertoires_category ON repertoires(category_id)`,
+	type pending struct {
+		id       string
+		userID   string
+		treeData models.RepertoireNode
+	}
+	var repertoires []pending
+	for rows.Next() {
+		var p pending
+		var treeDataJSON []byte
+		if err := rows.Scan(&p.id, &p.userID, &treeDataJSON); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan repertoire: %w", err)
+		}
+		if err := json.Unmarshal(treeDataJSON, &p.treeData); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to unmarshal tree_data: %w", err)
+		}
+		repertoires = append(repertoires, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating repertoires: %w", err)
 	}
-	for _, m := range migrations {
-		if _, err := db.Pool.Exec(ctx, m); err != nil {
-			return fmt.Errorf("failed to run migration: %w", err)
+
+	for _, p := range repertoires {
+		tx, err := db.Pool.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if err := reindexPositions(ctx, tx, p.id, p.userID, p.treeData); err != nil {
+			tx.Rollback(ctx)
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit repertoire positions: %w", err)
 		}
 	}
 
-	log.Println("Database migrations completed successfully")
 	return nil
 }
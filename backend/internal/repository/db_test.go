@@ -2,8 +2,10 @@ package repository
 
 import (
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/treechess/backend/config"
 )
@@ -26,3 +28,43 @@ func TestDB_Close_NilPool(t *testing.T) {
 	db := &DB{Pool: nil}
 	db.Close() // Should not panic
 }
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].version)
+	assert.Equal(t, "baseline", migrations[0].name)
+	for i := 1; i < len(migrations); i++ {
+		assert.Greater(t, migrations[i].version, migrations[i-1].version)
+	}
+}
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_add_index.sql": {Data: []byte("CREATE INDEX a ON b(c);")},
+		"migrations/0002_add_table.sql": {Data: []byte("CREATE TABLE b (c INT);")},
+	}
+
+	migrations, err := loadMigrations(fsys)
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, 2, migrations[0].version)
+	assert.Equal(t, "add_table", migrations[0].name)
+	assert.Equal(t, 10, migrations[1].version)
+}
+
+func TestLoadMigrations_RejectsInvalidFiles(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{
+		"migrations/2_missing_padding.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "invalid migration filename")
+
+	_, err = loadMigrations(fstest.MapFS{
+		"migrations/0002_first.sql":  {Data: []byte("SELECT 1;")},
+		"migrations/0002_second.sql": {Data: []byte("SELECT 2;")},
+	})
+	assert.ErrorContains(t, err, "share version 2")
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
 )
 
 // DismissedMistakeRepo implements DismissedMistakeRepository
@@ -58,3 +60,51 @@ func (r *DismissedMistakeRepo) GetDismissed(userID string) (map[string]bool, err
 
 	return dismissed, nil
 }
+
+// List returns a user's dismissed mistakes, most recently dismissed first
+func (r *DismissedMistakeRepo) List(userID string) ([]models.DismissedMistake, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	query := `
+		SELECT fen, played_move, dismissed_at
+		FROM dismissed_mistakes
+		WHERE user_id = $1
+		ORDER BY dismissed_at DESC
+	`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dismissed mistakes: %w", err)
+	}
+	defer rows.Close()
+
+	mistakes := []models.DismissedMistake{}
+	for rows.Next() {
+		var m models.DismissedMistake
+		if err := rows.Scan(&m.FEN, &m.PlayedMove, &m.DismissedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dismissed mistake: %w", err)
+		}
+		mistakes = append(mistakes, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dismissed mistakes: %w", err)
+	}
+
+	return mistakes, nil
+}
+
+// Restore removes a dismissal so the mistake shows up in insights again
+func (r *DismissedMistakeRepo) Restore(userID, fen, playedMove string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	query := `DELETE FROM dismissed_mistakes WHERE user_id = $1 AND fen = $2 AND played_move = $3`
+	result, err := r.pool.Exec(ctx, query, userID, fen, playedMove)
+	if err != nil {
+		return fmt.Errorf("failed to restore mistake: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrDismissedMistakeNotFound
+	}
+	return nil
+}
@@ -2,14 +2,51 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/treechess/backend/internal/models"
 )
 
+// Lease expiry is compared against the database clock, so replicas with skewed clocks agree on it.
+// Processing rows without a lease were left behind by a crashed worker and are claimable too.
+const claimEngineEvalsSQL = `
+	UPDATE engine_evals SET
+		status = 'processing',
+		lease_owner = $1,
+		lease_expires_at = NOW() + make_interval(secs => $3),
+		updated_at = NOW()
+	WHERE id IN (
+		SELECT id FROM engine_evals
+		WHERE (status = 'pending' AND (next_attempt_at IS NULL OR next_attempt_at <= NOW()))
+		   OR (status = 'processing' AND (lease_expires_at IS NULL OR lease_expires_at < NOW()))
+		ORDER BY created_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, user_id, analysis_id, game_index, status, attempts, created_at, updated_at
+`
+
+// The SET expressions see the attempts count before the increment, so the delay before the
+// n-th retry is base * 2^(n-1), capped at the maximum delay.
+const failEngineEvalSQL = `
+	UPDATE engine_evals SET
+		attempts = attempts + 1,
+		status = CASE WHEN attempts + 1 < $3 THEN 'pending' ELSE 'failed' END,
+		next_attempt_at = CASE WHEN attempts + 1 < $3
+			THEN NOW() + make_interval(secs => LEAST($4 * power(2, attempts), $5))
+			ELSE NULL END,
+		lease_owner = NULL,
+		lease_expires_at = NULL,
+		updated_at = NOW()
+	WHERE id = $1 AND lease_owner = $2 AND status = 'processing'
+	RETURNING status
+`
+
 // PostgresEngineEvalRepo implements EngineEvalRepository using PostgreSQL
 type PostgresEngineEvalRepo struct {
 	pool *pgxpool.Pool
@@ -39,49 +76,49 @@ func (r *PostgresEngineEvalRepo) CreatePendingBatch(userID, analysisID string, g
 	return nil
 }
 
-// GetPending returns up to limit pending engine evals
-func (r *PostgresEngineEvalRepo) GetPending(limit int) ([]models.EngineEval, error) {
+// ClaimPending leases up to limit claimable evals to workerID for the lease duration and marks
+// them as processing. Claimable evals are pending ones and processing ones whose lease expired.
+// Rows locked by a concurrent claim are skipped, so workers never claim the same eval.
+func (r *PostgresEngineEvalRepo) ClaimPending(workerID string, limit int, lease time.Duration) ([]models.EngineEval, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	rows, err := r.pool.Query(ctx,
-		`SELECT id, user_id, analysis_id, game_index, status, created_at, updated_at
-		 FROM engine_evals
-		 WHERE status = 'pending'
-		 ORDER BY created_at ASC
-		 LIMIT $1`,
-		limit,
-	)
+	rows, err := r.pool.Query(ctx, claimEngineEvalsSQL, workerID, limit, lease.Seconds())
 	if err != nil {
-		return nil, fmt.Errorf("failed to query pending evals: %w", err)
+		return nil, fmt.Errorf("failed to claim pending evals: %w", err)
 	}
 	defer rows.Close()
 
 	var evals []models.EngineEval
 	for rows.Next() {
 		var e models.EngineEval
-		if err := rows.Scan(&e.ID, &e.UserID, &e.AnalysisID, &e.GameIndex, &e.Status, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AnalysisID, &e.GameIndex, &e.Status, &e.Attempts, &e.CreatedAt, &e.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan eval: %w", err)
 		}
 		evals = append(evals, e)
 	}
-	return evals, nil
+	return evals, rows.Err()
 }
 
-// MarkProcessing marks an engine eval as processing
-func (r *PostgresEngineEvalRepo) MarkProcessing(id string) error {
+// ExtendLease renews the leases workerID still holds on the given evals and returns how many it renewed
+func (r *PostgresEngineEvalRepo) ExtendLease(workerID string, ids []string, lease time.Duration) (int64, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	_, err := r.pool.Exec(ctx,
-		`UPDATE engine_evals SET status = 'processing', updated_at = $2 WHERE id = $1`,
-		id, time.Now(),
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE engine_evals SET lease_expires_at = NOW() + make_interval(secs => $3)
+		 WHERE id = ANY($2) AND lease_owner = $1 AND status = 'processing'`,
+		workerID, ids, lease.Seconds(),
 	)
-	return err
+	if err != nil {
+		return 0, fmt.Errorf("failed to extend eval leases: %w", err)
+	}
+	return tag.RowsAffected(), nil
 }
 
-// SaveEvals saves completed evaluations for an engine eval
-func (r *PostgresEngineEvalRepo) SaveEvals(id string, evals []models.ExplorerMoveStats) error {
+// SaveEvals saves completed evaluations for an engine eval leased to workerID.
+// Returns ErrEvalLeaseLost if the lease expired and another worker claimed the eval.
+func (r *PostgresEngineEvalRepo) SaveEvals(id, workerID string, evals []models.ExplorerMoveStats) error {
 	ctx, cancel := dbContext()
 	defer cancel()
 
@@ -90,23 +127,69 @@ func (r *PostgresEngineEvalRepo) SaveEvals(id string, evals []models.ExplorerMov
 		return fmt.Errorf("failed to marshal evals: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx,
-		`UPDATE engine_evals SET status = 'done', evals = $2, updated_at = $3 WHERE id = $1`,
-		id, evalsJSON, time.Now(),
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE engine_evals SET status = 'done', evals = $3, lease_owner = NULL, lease_expires_at = NULL, updated_at = $4
+		 WHERE id = $1 AND lease_owner = $2 AND status = 'processing'`,
+		id, workerID, evalsJSON, time.Now(),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEvalLeaseLost
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt on an engine eval leased to workerID. Until the eval has
+// failed maxAttempts times it goes back to pending, claimable again after an exponential backoff
+// starting at baseDelay and capped at maxDelay; then it is marked failed. Reports whether it will be retried.
+// Returns ErrEvalLeaseLost if the lease expired and another worker claimed the eval.
+func (r *PostgresEngineEvalRepo) MarkFailed(id, workerID string, maxAttempts int, baseDelay, maxDelay time.Duration) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var status string
+	err := r.pool.QueryRow(ctx, failEngineEvalSQL, id, workerID, maxAttempts, baseDelay.Seconds(), maxDelay.Seconds()).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrEvalLeaseLost
+		}
+		return false, fmt.Errorf("failed to mark eval as failed: %w", err)
+	}
+	return status == "pending", nil
 }
 
-// MarkFailed marks an engine eval as failed
-func (r *PostgresEngineEvalRepo) MarkFailed(id string) error {
+// RetryFailed queues the failed evals of an analysis again with a fresh attempt budget and returns how many it queued
+func (r *PostgresEngineEvalRepo) RetryFailed(analysisID string) (int64, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	_, err := r.pool.Exec(ctx,
-		`UPDATE engine_evals SET status = 'failed', updated_at = $2 WHERE id = $1`,
-		id, time.Now(),
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE engine_evals SET status = 'pending', attempts = 0, next_attempt_at = NULL, updated_at = NOW()
+		 WHERE analysis_id = $1 AND status = 'failed'`,
+		analysisID,
 	)
-	return err
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue failed evals: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CountUnfinished returns how many evals of an analysis are still pending or processing
+func (r *PostgresEngineEvalRepo) CountUnfinished(analysisID string) (int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM engine_evals WHERE analysis_id = $1 AND status IN ('pending', 'processing')`,
+		analysisID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unfinished evals: %w", err)
+	}
+	return count, nil
 }
 
 // GetByUser returns all engine evals for a user
@@ -115,7 +198,7 @@ func (r *PostgresEngineEvalRepo) GetByUser(userID string) ([]models.EngineEval,
 	defer cancel()
 
 	rows, err := r.pool.Query(ctx,
-		`SELECT id, user_id, analysis_id, game_index, status, evals, created_at, updated_at
+		`SELECT id, user_id, analysis_id, game_index, status, evals, attempts, next_attempt_at, created_at, updated_at
 		 FROM engine_evals
 		 WHERE user_id = $1
 		 ORDER BY created_at DESC`,
@@ -130,7 +213,7 @@ func (r *PostgresEngineEvalRepo) GetByUser(userID string) ([]models.EngineEval,
 	for rows.Next() {
 		var e models.EngineEval
 		var evalsJSON []byte
-		if err := rows.Scan(&e.ID, &e.UserID, &e.AnalysisID, &e.GameIndex, &e.Status, &evalsJSON, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AnalysisID, &e.GameIndex, &e.Status, &evalsJSON, &e.Attempts, &e.NextAttemptAt, &e.CreatedAt, &e.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan eval: %w", err)
 		}
 		if evalsJSON != nil {
@@ -142,3 +225,68 @@ func (r *PostgresEngineEvalRepo) GetByUser(userID string) ([]models.EngineEval,
 	}
 	return evals, nil
 }
+
+// DeleteOlderThan removes finished evals (done or failed) last updated before the cutoff
+func (r *PostgresEngineEvalRepo) DeleteOlderThan(before time.Time) (int64, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM engine_evals
+		 WHERE status IN ('done', 'failed') AND updated_at < $1`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale evals: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteByUserOlderThan removes a single user's finished evals last updated before the cutoff
+func (r *PostgresEngineEvalRepo) DeleteByUserOlderThan(userID string, before time.Time) (int64, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM engine_evals
+		 WHERE user_id = $1 AND status IN ('done', 'failed') AND updated_at < $2`,
+		userID, before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale evals for user: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ResetAnalysis discards every eval of an analysis and queues the given games for re-evaluation
+func (r *PostgresEngineEvalRepo) ResetAnalysis(userID, analysisID string, gameIndices []int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM engine_evals WHERE analysis_id = $1`, analysisID); err != nil {
+		return fmt.Errorf("failed to delete evals: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, idx := range gameIndices {
+		batch.Queue(
+			`INSERT INTO engine_evals (user_id, analysis_id, game_index, status)
+			 VALUES ($1, $2, $3, 'pending')`,
+			userID, analysisID, idx,
+		)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to queue evals: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -8,17 +8,75 @@ var (
 	ErrCategoryNotFound = fmt.Errorf("category not found")
 
 	// Repertoire errors
-	ErrRepertoireNotFound = fmt.Errorf("repertoire not found")
+	ErrRepertoireNotFound   = fmt.Errorf("repertoire not found")
+	ErrTemplateNotFound     = fmt.Errorf("template not found")
+	ErrCollaboratorNotFound = fmt.Errorf("collaborator not found")
+	ErrRevisionNotFound     = fmt.Errorf("revision not found")
+	ErrStudyNotesNotFound   = fmt.Errorf("study notes not found")
+	// ErrRepertoireVersionConflict: the tree was saved by another edit since it was read
+	ErrRepertoireVersionConflict = fmt.Errorf("repertoire was modified concurrently")
 
 	// Analysis errors
 	ErrAnalysisNotFound = fmt.Errorf("analysis not found")
 	ErrGameNotFound     = fmt.Errorf("game not found")
 
+	// Dismissed mistake errors
+	ErrDismissedMistakeNotFound = fmt.Errorf("dismissed mistake not found")
+
 	// User errors
 	ErrUserNotFound   = fmt.Errorf("user not found")
 	ErrUsernameExists = fmt.Errorf("username already exists")
 	ErrEmailExists    = fmt.Errorf("email already exists")
 
+	// Goal errors
+	ErrGoalNotFound = fmt.Errorf("goal not found")
+
+	// Engine eval errors
+	ErrEvalLeaseLost = fmt.Errorf("engine eval lease lost")
+
+	// Reanalysis job errors
+	ErrReanalysisJobNotFound = fmt.Errorf("reanalysis job not found")
+
+	// Recompute job errors
+	ErrRecomputeJobNotFound = fmt.Errorf("recompute job not found")
+
+	// Import job errors
+	ErrImportJobNotFound = fmt.Errorf("import job not found")
+
+	// Import summary errors
+	ErrImportSummaryNotFound = fmt.Errorf("import summary not found")
+
+	// Team import errors
+	ErrTeamImportNotFound = fmt.Errorf("team import not found")
+
+	// Sync run errors
+	ErrSyncRunNotFound = fmt.Errorf("sync run not found")
+
 	// Password reset errors
 	ErrResetTokenNotFound = fmt.Errorf("reset token not found")
+
+	// Session errors
+	ErrSessionNotFound = fmt.Errorf("session not found")
+
+	// API token errors
+	ErrAPITokenNotFound = fmt.Errorf("api token not found")
+
+	// Coach link errors
+	ErrCoachLinkNotFound = fmt.Errorf("coach link not found")
+	ErrCoachLinkExists   = fmt.Errorf("coach link already exists")
+
+	// Prep request errors
+	ErrPrepRequestNotFound    = fmt.Errorf("prep request not found")
+	ErrPrepSuggestionNotFound = fmt.Errorf("prep suggestion not found")
+
+	// Insight settings errors
+	ErrInsightSettingsNotFound = fmt.Errorf("insight settings not found")
+
+	// Classification rule errors
+	ErrClassificationRuleNotFound  = fmt.Errorf("classification rule not found")
+	ErrClassificationRuleKeyExists = fmt.Errorf("a classification rule with this key already exists")
+
+	// Notification errors
+	ErrNotificationWebhookNotFound = fmt.Errorf("notification webhook not found")
+	ErrPushSubscriptionNotFound    = fmt.Errorf("push subscription not found")
 )
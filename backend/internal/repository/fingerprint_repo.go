@@ -5,24 +5,40 @@ import (
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/config"
 )
 
 // PostgresFingerprintRepo implements GameFingerprintRepository using PostgreSQL
 type PostgresFingerprintRepo struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	batchSize int // Fingerprints per query
 }
 
-// NewPostgresFingerprintRepo creates a new PostgreSQL fingerprint repository
-func NewPostgresFingerprintRepo(pool *pgxpool.Pool) *PostgresFingerprintRepo {
-	return &PostgresFingerprintRepo{pool: pool}
+// NewPostgresFingerprintRepo creates a new PostgreSQL fingerprint repository querying batchSize
+// fingerprints at a time, config.DefaultFingerprintBatchSize when not positive
+func NewPostgresFingerprintRepo(pool *pgxpool.Pool, batchSize int) *PostgresFingerprintRepo {
+	if batchSize < 1 {
+		batchSize = config.DefaultFingerprintBatchSize
+	}
+	return &PostgresFingerprintRepo{pool: pool, batchSize: batchSize}
 }
 
-// CheckExisting returns which fingerprints already exist for the given user
-func (r *PostgresFingerprintRepo) CheckExisting(userID string, fingerprints []string) (map[string]bool, error) {
-	if len(fingerprints) == 0 {
-		return map[string]bool{}, nil
+// CheckExisting returns which fingerprints already exist for the given user, and where the
+// matching games are stored. Fingerprints are looked up batchSize at a time.
+func (r *PostgresFingerprintRepo) CheckExisting(userID string, fingerprints []string) (map[string]GameLocation, error) {
+	existing := make(map[string]GameLocation)
+	for start := 0; start < len(fingerprints); start += r.batchSize {
+		end := min(start+r.batchSize, len(fingerprints))
+		if err := r.checkBatch(userID, fingerprints[start:end], existing); err != nil {
+			return nil, err
+		}
 	}
+	return existing, nil
+}
 
+// checkBatch adds the locations of the stored games matching fingerprints to existing
+func (r *PostgresFingerprintRepo) checkBatch(userID string, fingerprints []string, existing map[string]GameLocation) error {
 	ctx, cancel := dbContext()
 	defer cancel()
 
@@ -36,38 +52,44 @@ func (r *PostgresFingerprintRepo) CheckExisting(userID string, fingerprints []st
 	}
 
 	query := fmt.Sprintf(
-		"SELECT fingerprint FROM game_fingerprints WHERE user_id = $1 AND fingerprint IN (%s)",
+		"SELECT fingerprint, analysis_id, game_index FROM game_fingerprints WHERE user_id = $1 AND fingerprint IN (%s)",
 		strings.Join(placeholders, ", "),
 	)
 
 	rows, err := r.pool.Query(ctx, query, params...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing fingerprints: %w", err)
+		return fmt.Errorf("failed to check existing fingerprints: %w", err)
 	}
 	defer rows.Close()
 
-	existing := make(map[string]bool)
 	for rows.Next() {
 		var fp string
-		if err := rows.Scan(&fp); err != nil {
-			return nil, fmt.Errorf("failed to scan fingerprint: %w", err)
+		var loc GameLocation
+		if err := rows.Scan(&fp, &loc.AnalysisID, &loc.GameIndex); err != nil {
+			return fmt.Errorf("failed to scan fingerprint: %w", err)
 		}
-		existing[fp] = true
+		existing[fp] = loc
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating fingerprints: %w", err)
+		return fmt.Errorf("error iterating fingerprints: %w", err)
 	}
 
-	return existing, nil
+	return nil
 }
 
-// SaveBatch inserts multiple fingerprints in a single query
+// SaveBatch inserts multiple fingerprints, batchSize per query
 func (r *PostgresFingerprintRepo) SaveBatch(userID, analysisID string, entries []FingerprintEntry) error {
-	if len(entries) == 0 {
-		return nil
+	for start := 0; start < len(entries); start += r.batchSize {
+		end := min(start+r.batchSize, len(entries))
+		if err := r.saveBatch(userID, analysisID, entries[start:end]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+func (r *PostgresFingerprintRepo) saveBatch(userID, analysisID string, entries []FingerprintEntry) error {
 	ctx, cancel := dbContext()
 	defer cancel()
 
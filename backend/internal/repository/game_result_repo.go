@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	insertGameNodeResultSQL = `
+		INSERT INTO game_node_results (analysis_id, game_index, user_id, repertoire_id, node_id, outcome)
+		SELECT analysis_id, game_index, user_id, $3, $4, $5
+		FROM games
+		WHERE analysis_id = $1 AND game_index = $2
+		ON CONFLICT (analysis_id, game_index, node_id) DO NOTHING
+	`
+	deleteGameNodeResultsSQL = `
+		DELETE FROM game_node_results WHERE analysis_id = $1 AND game_index = $2
+	`
+	resultsOverlaySQL = `
+		SELECT node_id,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE outcome = 'win'),
+			COUNT(*) FILTER (WHERE outcome = 'draw'),
+			COUNT(*) FILTER (WHERE outcome = 'loss')
+		FROM game_node_results
+		WHERE repertoire_id = $1 AND user_id = $2
+		GROUP BY node_id
+		ORDER BY COUNT(*) DESC, node_id
+	`
+)
+
+// PostgresGameResultRepo implements GameResultRepository using PostgreSQL
+type PostgresGameResultRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresGameResultRepo creates a new PostgresGameResultRepo
+func NewPostgresGameResultRepo(pool *pgxpool.Pool) *PostgresGameResultRepo {
+	return &PostgresGameResultRepo{pool: pool}
+}
+
+// SaveBatch records the repertoire nodes reached by newly saved games
+func (r *PostgresGameResultRepo) SaveBatch(analysisID string, entries []GameResultEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	queueGameResults(batch, analysisID, entries)
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save game results: %w", err)
+	}
+	return nil
+}
+
+// ReplaceGame swaps the recorded nodes of one game, e.g. after it was re-analyzed against another repertoire
+func (r *PostgresGameResultRepo) ReplaceGame(analysisID string, gameIndex int, entries []GameResultEntry) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, deleteGameNodeResultsSQL, analysisID, gameIndex); err != nil {
+		return fmt.Errorf("failed to delete game results: %w", err)
+	}
+
+	if len(entries) > 0 {
+		batch := &pgx.Batch{}
+		queueGameResults(batch, analysisID, entries)
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("failed to save game results: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit game results: %w", err)
+	}
+	return nil
+}
+
+func queueGameResults(batch *pgx.Batch, analysisID string, entries []GameResultEntry) {
+	for _, e := range entries {
+		batch.Queue(insertGameNodeResultSQL, analysisID, e.GameIndex, e.RepertoireID, e.NodeID, e.Outcome)
+	}
+}
+
+// Overlay aggregates the user's results per node of a repertoire
+func (r *PostgresGameResultRepo) Overlay(repertoireID, userID string) ([]models.NodeResult, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, resultsOverlaySQL, repertoireID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results overlay: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.NodeResult{}
+	for rows.Next() {
+		var n models.NodeResult
+		if err := rows.Scan(&n.NodeID, &n.Games, &n.Wins, &n.Draws, &n.Losses); err != nil {
+			return nil, fmt.Errorf("failed to scan node result: %w", err)
+		}
+		results = append(results, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating node results: %w", err)
+	}
+	return results, nil
+}
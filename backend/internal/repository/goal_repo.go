@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	goalColumnsSQL = `
+		g.id, g.user_id, g.repertoire_id, r.name, g.goal_type, g.target, g.depth,
+		g.progress, g.achieved, g.progress_updated_at, g.last_summary_at, g.created_at
+	`
+	createGoalSQL = `
+		INSERT INTO goals (user_id, repertoire_id, goal_type, target, depth)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	getGoalByIDSQL = `
+		SELECT ` + goalColumnsSQL + `
+		FROM goals g
+		JOIN repertoires r ON r.id = g.repertoire_id
+		WHERE g.id = $1
+	`
+	getGoalsByUserSQL = `
+		SELECT ` + goalColumnsSQL + `
+		FROM goals g
+		JOIN repertoires r ON r.id = g.repertoire_id
+		WHERE g.user_id = $1
+		ORDER BY g.created_at
+	`
+	getAllGoalsSQL = `
+		SELECT ` + goalColumnsSQL + `
+		FROM goals g
+		JOIN repertoires r ON r.id = g.repertoire_id
+		ORDER BY g.user_id, g.created_at
+	`
+	countGoalsSQL = `
+		SELECT COUNT(*) FROM goals WHERE user_id = $1
+	`
+	updateGoalProgressSQL = `
+		UPDATE goals
+		SET progress = $2, achieved = $3, progress_updated_at = NOW()
+		WHERE id = $1
+	`
+	markGoalSummarySentSQL = `
+		UPDATE goals
+		SET last_summary_at = $2
+		WHERE user_id = $1
+	`
+	deleteGoalSQL = `
+		DELETE FROM goals WHERE id = $1
+	`
+	belongsToUserGoalSQL = `
+		SELECT EXISTS(SELECT 1 FROM goals WHERE id = $1 AND user_id = $2)
+	`
+)
+
+// PostgresGoalRepo implements GoalRepository using PostgreSQL
+type PostgresGoalRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresGoalRepo creates a new PostgreSQL goal repository
+func NewPostgresGoalRepo(pool *pgxpool.Pool) *PostgresGoalRepo {
+	return &PostgresGoalRepo{pool: pool}
+}
+
+func scanGoal(row pgx.Row) (*models.Goal, error) {
+	var g models.Goal
+	err := row.Scan(
+		&g.ID,
+		&g.UserID,
+		&g.RepertoireID,
+		&g.RepertoireName,
+		&g.Type,
+		&g.Target,
+		&g.Depth,
+		&g.Progress,
+		&g.Achieved,
+		&g.ProgressUpdatedAt,
+		&g.LastSummaryAt,
+		&g.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Create creates a new goal for a repertoire
+func (r *PostgresGoalRepo) Create(userID, repertoireID string, goalType models.GoalType, target float64, depth int) (*models.Goal, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var id string
+	err := r.pool.QueryRow(ctx, createGoalSQL, userID, repertoireID, goalType, target, depth).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+
+	return r.GetByID(id)
+}
+
+// GetByID retrieves a goal by its ID
+func (r *PostgresGoalRepo) GetByID(id string) (*models.Goal, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	goal, err := scanGoal(r.pool.QueryRow(ctx, getGoalByIDSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrGoalNotFound
+		}
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	return goal, nil
+}
+
+// GetByUser returns all goals of a user
+func (r *PostgresGoalRepo) GetByUser(userID string) ([]models.Goal, error) {
+	return r.query(getGoalsByUserSQL, userID)
+}
+
+// GetAll returns every goal, ordered by user, for the progress worker
+func (r *PostgresGoalRepo) GetAll() ([]models.Goal, error) {
+	return r.query(getAllGoalsSQL)
+}
+
+func (r *PostgresGoalRepo) query(sql string, args ...interface{}) ([]models.Goal, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query goals: %w", err)
+	}
+	defer rows.Close()
+
+	var goals []models.Goal
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goals = append(goals, *goal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating goals: %w", err)
+	}
+
+	return goals, nil
+}
+
+// CountByUser returns the number of goals a user has
+func (r *PostgresGoalRepo) CountByUser(userID string) (int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	if err := r.pool.QueryRow(ctx, countGoalsSQL, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count goals: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateProgress stores the latest computed progress of a goal
+func (r *PostgresGoalRepo) UpdateProgress(id string, progress float64, achieved bool) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, updateGoalProgressSQL, id, progress, achieved)
+	if err != nil {
+		return fmt.Errorf("failed to update goal progress: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// MarkSummarySent records when the weekly goal summary was last sent to a user
+func (r *PostgresGoalRepo) MarkSummarySent(userID string, sentAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, markGoalSummarySentSQL, userID, sentAt); err != nil {
+		return fmt.Errorf("failed to mark goal summary sent: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes a goal by ID
+func (r *PostgresGoalRepo) Delete(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, deleteGoalSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// BelongsToUser checks if a goal belongs to a specific user
+func (r *PostgresGoalRepo) BelongsToUser(id, userID string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var belongs bool
+	if err := r.pool.QueryRow(ctx, belongsToUserGoalSQL, id, userID).Scan(&belongs); err != nil {
+		return false, fmt.Errorf("failed to check goal ownership: %w", err)
+	}
+	return belongs, nil
+}
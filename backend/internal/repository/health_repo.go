@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	listStaleHealthSQL = `
+		SELECT r.id, r.user_id
+		FROM repertoires r
+		LEFT JOIN repertoire_health h ON h.repertoire_id = r.id
+		WHERE NOT r.archived AND (h.computed_at IS NULL OR h.computed_at < $1)
+		ORDER BY h.computed_at NULLS FIRST
+		LIMIT $2
+	`
+	saveHealthSQL = `
+		INSERT INTO repertoire_health (repertoire_id, score, coverage, results, recall, freshness, computed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (repertoire_id) DO UPDATE
+		SET score = EXCLUDED.score, coverage = EXCLUDED.coverage, results = EXCLUDED.results,
+			recall = EXCLUDED.recall, freshness = EXCLUDED.freshness, computed_at = EXCLUDED.computed_at
+	`
+	getHealthByUserSQL = `
+		SELECT h.repertoire_id, h.score, h.coverage, h.results, h.recall, h.freshness, h.computed_at
+		FROM repertoire_health h
+		JOIN repertoires r ON r.id = h.repertoire_id
+		WHERE r.user_id = $1
+	`
+)
+
+// PostgresHealthRepo implements HealthRepository using PostgreSQL
+type PostgresHealthRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresHealthRepo creates a new PostgreSQL repertoire health repository
+func NewPostgresHealthRepo(pool *pgxpool.Pool) *PostgresHealthRepo {
+	return &PostgresHealthRepo{pool: pool}
+}
+
+// ListStale returns the repertoires whose health was never computed or was computed before the cutoff, oldest first
+func (r *PostgresHealthRepo) ListStale(before time.Time, limit int) ([]RepertoireOwner, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listStaleHealthSQL, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale repertoire health: %w", err)
+	}
+	defer rows.Close()
+
+	var owners []RepertoireOwner
+	for rows.Next() {
+		var owner RepertoireOwner
+		if err := rows.Scan(&owner.RepertoireID, &owner.UserID); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire owner: %w", err)
+		}
+		owners = append(owners, owner)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating repertoire owners: %w", err)
+	}
+
+	return owners, nil
+}
+
+// Save stores the health of a repertoire, replacing the previous one
+func (r *PostgresHealthRepo) Save(repertoireID string, health models.RepertoireHealth) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, saveHealthSQL, repertoireID, health.Score, health.Coverage, health.Results, health.Recall, health.Freshness, health.ComputedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save repertoire health: %w", err)
+	}
+	return nil
+}
+
+// GetByUser returns the health of the user's repertoires, keyed by repertoire ID
+func (r *PostgresHealthRepo) GetByUser(userID string) (map[string]models.RepertoireHealth, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getHealthByUserSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repertoire health: %w", err)
+	}
+	defer rows.Close()
+
+	health := make(map[string]models.RepertoireHealth)
+	for rows.Next() {
+		var repertoireID string
+		var h models.RepertoireHealth
+		if err := rows.Scan(&repertoireID, &h.Score, &h.Coverage, &h.Results, &h.Recall, &h.Freshness, &h.ComputedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire health: %w", err)
+		}
+		health[repertoireID] = h
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating repertoire health: %w", err)
+	}
+
+	return health, nil
+}
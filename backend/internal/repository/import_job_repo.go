@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	importJobColumns = `id, user_id, username, filename, duplicate_policy, file_path, status,
+		total_games, total_chunks, processed_chunks, imported_games, skipped_games,
+		checked_games, failed_games, analysis_ids, COALESCE(error, ''), created_at, updated_at`
+
+	createImportJobSQL = `
+		INSERT INTO import_jobs (user_id, username, filename, duplicate_policy, file_path, status)
+		VALUES ($1, $2, $3, $4, $5, 'pending')
+		RETURNING ` + importJobColumns
+	getImportJobSQL = `
+		SELECT ` + importJobColumns + `
+		FROM import_jobs
+		WHERE id = $1
+	`
+	getPendingImportJobsSQL = `
+		SELECT ` + importJobColumns + `
+		FROM import_jobs
+		WHERE status = 'pending'
+		ORDER BY created_at ASC
+		LIMIT $1
+	`
+	recordImportChunkSQL = `
+		UPDATE import_jobs SET
+			processed_chunks = processed_chunks + 1,
+			imported_games = imported_games + $2,
+			skipped_games = skipped_games + $3,
+			failed_games = failed_games || $4::jsonb,
+			analysis_ids = analysis_ids || $5::jsonb,
+			updated_at = $6
+		WHERE id = $1
+	`
+)
+
+// PostgresImportJobRepo implements ImportJobRepository using PostgreSQL
+type PostgresImportJobRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresImportJobRepo creates a new PostgresImportJobRepo
+func NewPostgresImportJobRepo(pool *pgxpool.Pool) *PostgresImportJobRepo {
+	return &PostgresImportJobRepo{pool: pool}
+}
+
+func scanImportJob(row pgx.Row) (*models.ImportJob, error) {
+	var j models.ImportJob
+	var failedJSON, analysisIDsJSON []byte
+	if err := row.Scan(&j.ID, &j.UserID, &j.Username, &j.Filename, &j.DuplicatePolicy, &j.FilePath, &j.Status,
+		&j.TotalGames, &j.TotalChunks, &j.ProcessedChunks, &j.ImportedGames, &j.SkippedGames,
+		&j.CheckedGames, &failedJSON, &analysisIDsJSON, &j.Error, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(failedJSON, &j.FailedGames); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal failed games: %w", err)
+	}
+	if err := json.Unmarshal(analysisIDsJSON, &j.AnalysisIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal analysis ids: %w", err)
+	}
+	return &j, nil
+}
+
+// Create queues a pending import of a spooled PGN file
+func (r *PostgresImportJobRepo) Create(job models.ImportJob) (*models.ImportJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	created, err := scanImportJob(r.pool.QueryRow(ctx, createImportJobSQL,
+		job.UserID, job.Username, job.Filename, job.DuplicatePolicy, job.FilePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+	return created, nil
+}
+
+// GetByID returns an import job by ID
+func (r *PostgresImportJobRepo) GetByID(id string) (*models.ImportJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	job, err := scanImportJob(r.pool.QueryRow(ctx, getImportJobSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrImportJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	return job, nil
+}
+
+// GetPending returns up to limit pending import jobs, oldest first
+func (r *PostgresImportJobRepo) GetPending(limit int) ([]models.ImportJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getPendingImportJobsSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.ImportJob
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// MarkProcessing marks a job as processing and records the size of the file
+func (r *PostgresImportJobRepo) MarkProcessing(id string, totalGames, totalChunks int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE import_jobs SET status = 'processing', total_games = $2, total_chunks = $3, processed_chunks = 0, checked_games = 0, updated_at = $4 WHERE id = $1`,
+		id, totalGames, totalChunks, time.Now(),
+	)
+	return err
+}
+
+// RecordChunk adds the outcome of one processed chunk to the job's progress
+func (r *PostgresImportJobRepo) RecordChunk(id string, result models.ImportChunkResult) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	failed := result.Failed
+	if failed == nil {
+		failed = []models.ImportFailure{}
+	}
+	failedJSON, err := json.Marshal(failed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal failed games: %w", err)
+	}
+	analysisIDs := []string{}
+	if result.AnalysisID != "" {
+		analysisIDs = append(analysisIDs, result.AnalysisID)
+	}
+	analysisIDsJSON, err := json.Marshal(analysisIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis ids: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, recordImportChunkSQL,
+		id, result.Imported, result.Skipped, failedJSON, analysisIDsJSON, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record import chunk: %w", err)
+	}
+	return nil
+}
+
+// RecordDedupProgress records how many games of the job were checked against the already imported ones
+func (r *PostgresImportJobRepo) RecordDedupProgress(id string, checkedGames int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE import_jobs SET checked_games = $2, updated_at = $3 WHERE id = $1`,
+		id, checkedGames, time.Now(),
+	)
+	return err
+}
+
+// MarkDone marks a job as done
+func (r *PostgresImportJobRepo) MarkDone(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE import_jobs SET status = 'done', updated_at = $2 WHERE id = $1`,
+		id, time.Now(),
+	)
+	return err
+}
+
+// MarkFailed marks a job as failed with the reason
+func (r *PostgresImportJobRepo) MarkFailed(id string, message string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE import_jobs SET status = 'failed', error = $2, updated_at = $3 WHERE id = $1`,
+		id, message, time.Now(),
+	)
+	return err
+}
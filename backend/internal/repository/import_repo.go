@@ -3,7 +3,6 @@ package repository
 import (
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 
@@ -16,42 +15,206 @@ import (
 
 const (
 	saveAnalysisSQL = `
-		INSERT INTO analyses (id, user_id, username, filename, game_count, results, uploaded_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, username, filename, game_count, uploaded_at
+		INSERT INTO analyses (id, user_id, username, filename, game_count, uploaded_at, source, source_params)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, username, filename, game_count, uploaded_at, source, source_params
+	`
+	saveGameSQL = `
+		INSERT INTO games (analysis_id, game_index, user_id, headers, moves, user_color,
+			repertoire_id, repertoire_name, match_score, time_class, status, classifications,
+			repertoire_version, repertoire_tree_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 	getAnalysesSQL = `
-		SELECT id, username, filename, game_count, uploaded_at
+		SELECT id, username, filename, game_count, uploaded_at, source, source_params
 		FROM analyses
-		WHERE user_id = $1
+		WHERE user_id = $1 AND ($2 = '' OR source = $2)
 		ORDER BY uploaded_at DESC
 	`
 	getAnalysisByIDSQL = `
-		SELECT id, username, filename, game_count, results, uploaded_at
+		SELECT id, username, filename, game_count, uploaded_at, source, source_params
 		FROM analyses
 		WHERE id = $1
 	`
+	getGamesByAnalysisSQL = `
+		SELECT analysis_id, game_index, headers, moves, user_color, repertoire_id, repertoire_name, match_score, note, starred,
+			repertoire_version, repertoire_tree_hash
+		FROM games
+		WHERE analysis_id = $1
+		ORDER BY game_index
+	`
+	getGameSQL = `
+		SELECT analysis_id, game_index, headers, moves, user_color, repertoire_id, repertoire_name, match_score, note, starred,
+			repertoire_version, repertoire_tree_hash
+		FROM games
+		WHERE analysis_id = $1 AND game_index = $2
+	`
+	getGamesByUserSQL = `
+		SELECT g.analysis_id, g.game_index, g.headers, g.moves, g.user_color, g.repertoire_id, g.repertoire_name, g.match_score,
+			g.note, g.starred, g.repertoire_version, g.repertoire_tree_hash
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		WHERE g.user_id = $1
+		ORDER BY a.uploaded_at DESC, g.game_index
+	`
 	deleteAnalysisSQL = `
 		DELETE FROM analyses
 		WHERE id = $1
 	`
+	// importSourceSQL derives the import source from a filename column, for the skipped duplicates
+	// which only record the filename of their import
+	importSourceSQL = `CASE
+				WHEN filename LIKE 'sync\_lichess\_%' OR filename LIKE 'lichess\_%' THEN 'lichess'
+				WHEN filename LIKE 'sync\_chesscom\_%' OR filename LIKE 'chesscom\_%' THEN 'chesscom'
+				WHEN filename LIKE 'broadcast\_%' THEN 'broadcast'
+				WHEN filename LIKE 'team\_%' THEN 'lichess_team'
+				ELSE 'pgn'
+			END`
+	// gameFiltersSQL is shared by the games list and count queries
+	gameFiltersSQL = `
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		LEFT JOIN viewed_games v
+			ON v.user_id = g.user_id AND v.analysis_id = g.analysis_id AND v.game_index = g.game_index
+		WHERE g.user_id = $1
+			AND ($2 = '' OR g.time_class = $2)
+			AND ($3 = '' OR g.repertoire_name = $3)
+			AND ($4 = '' OR a.source = $4)
+			AND (NOT $5 OR g.starred)
+			AND ($6 = '' OR $6 = ANY(g.classifications))
+	`
+	countGamesSQL  = `SELECT COUNT(*) ` + gameFiltersSQL
 	getAllGamesSQL = `
-		SELECT id, filename, results, uploaded_at
-		FROM analyses
-		WHERE user_id = $1
-		ORDER BY uploaded_at DESC
+		SELECT g.analysis_id, g.game_index,
+			COALESCE(g.headers->>'White', ''), COALESCE(g.headers->>'Black', ''),
+			COALESCE(g.headers->>'Result', ''), COALESCE(g.headers->>'Date', ''),
+			COALESCE(g.headers->>'Opening', ''),
+			g.user_color, g.repertoire_id, g.repertoire_name,
+			COALESCE(g.time_class, ''), COALESCE(g.status, 'ok'),
+			a.filename, a.source, a.uploaded_at, v.user_id IS NOT NULL, g.note, g.starred, g.classifications
+		` + gameFiltersSQL + `
+		ORDER BY a.uploaded_at DESC, g.game_index
+		LIMIT $7 OFFSET $8
 	`
-	belongsToUserAnalysisSQL = `
-		SELECT EXISTS(SELECT 1 FROM analyses WHERE id = $1 AND user_id = $2)
+	deleteGameSQL = `
+		DELETE FROM games
+		WHERE analysis_id = $1 AND game_index = $2
 	`
-	updateAnalysisResultsSQL = `
+	decrementGameCountSQL = `
 		UPDATE analyses
-		SET results = $2, game_count = $3
+		SET game_count = game_count - 1
 		WHERE id = $1
+		RETURNING game_count
+	`
+	updateGameSQL = `
+		UPDATE games
+		SET headers = $3, moves = $4, user_color = $5, repertoire_id = $6, repertoire_name = $7,
+			match_score = $8, time_class = $9, status = $10, classifications = $11,
+			repertoire_version = $12, repertoire_tree_hash = $13
+		WHERE analysis_id = $1 AND game_index = $2
+	`
+	updateGameNotesSQL = `
+		UPDATE games
+		SET note = COALESCE($3, note), starred = COALESCE($4, starred)
+		WHERE analysis_id = $1 AND game_index = $2
+		RETURNING note, starred
+	`
+	analysisExistsSQL = `
+		SELECT EXISTS(SELECT 1 FROM analyses WHERE id = $1)
+	`
+	belongsToUserAnalysisSQL = `
+		SELECT EXISTS(SELECT 1 FROM analyses WHERE id = $1 AND user_id = $2)
+	`
+	getDistinctRepertoiresSQL = `
+		SELECT DISTINCT repertoire_name
+		FROM games
+		WHERE user_id = $1 AND repertoire_name IS NOT NULL AND repertoire_name <> ''
+		ORDER BY repertoire_name
+	`
+	getGameLocationsByRepertoireSQL = `
+		SELECT analysis_id, game_index
+		FROM games
+		WHERE user_id = $1 AND repertoire_id = $2
+		ORDER BY analysis_id, game_index
+	`
+	recordSkippedDuplicatesSQL = `
+		INSERT INTO skipped_duplicates (user_id, filename, game_count)
+		VALUES ($1, $2, $3)
+	`
+	// getImportStatsSQL aggregates imported games and skipped duplicates per source and month
+	getImportStatsSQL = `
+		WITH imported AS (
+			SELECT a.source,
+				date_trunc('month', a.uploaded_at) AS month,
+				COUNT(*) AS games,
+				COUNT(*) FILTER (WHERE g.status = 'error') AS out_of_repertoire,
+				COUNT(*) FILTER (WHERE e.status = 'done') AS evaluated
+			FROM games g
+			JOIN analyses a ON a.id = g.analysis_id
+			LEFT JOIN engine_evals e ON e.analysis_id = g.analysis_id AND e.game_index = g.game_index
+			WHERE g.user_id = $1
+			GROUP BY 1, 2
+		), skipped AS (
+			SELECT ` + importSourceSQL + ` AS source,
+				date_trunc('month', skipped_at) AS month,
+				SUM(game_count) AS games
+			FROM skipped_duplicates
+			WHERE user_id = $1
+			GROUP BY 1, 2
+		)
+		SELECT COALESCE(i.source, s.source), COALESCE(i.month, s.month),
+			COALESCE(i.games, 0), COALESCE(s.games, 0),
+			COALESCE(i.out_of_repertoire, 0), COALESCE(i.evaluated, 0)
+		FROM imported i
+		FULL OUTER JOIN skipped s ON s.source = i.source AND s.month = i.month
+		ORDER BY 2 DESC, 1
+	`
+	// The first move that is not a repertoire move tells who left the book: "out-of-repertoire" is the
+	// user, "opponent-new" the opponent; games that reach the end of the tree leave it to nobody.
+	// Reference imports are not the user's games and are left out.
+	getBookDepthSQL = `
+		SELECT date_trunc('month', a.uploaded_at) AS month,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE exit.status = 'out-of-repertoire'),
+			AVG(exit.ply) FILTER (WHERE exit.status = 'out-of-repertoire'),
+			COUNT(*) FILTER (WHERE exit.status = 'opponent-new'),
+			AVG(exit.ply) FILTER (WHERE exit.status = 'opponent-new')
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		LEFT JOIN LATERAL (
+			SELECT (m->>'plyNumber')::int AS ply, m->>'status' AS status
+			FROM jsonb_array_elements(g.moves) m
+			WHERE m->>'status' <> 'in-repertoire'
+			ORDER BY (m->>'plyNumber')::int
+			LIMIT 1
+		) exit ON TRUE
+		WHERE g.user_id = $1 AND g.repertoire_id = $2 AND a.source NOT IN ('broadcast', 'lichess_team')
+		GROUP BY 1
+		ORDER BY 1
+	`
+	getMoveTimesSQL = `
+		SELECT (m->>'plyNumber')::int AS ply,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE m->>'status' = 'in-repertoire'),
+			AVG((m->>'timeSpent')::float8)
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		CROSS JOIN LATERAL jsonb_array_elements(g.moves) m
+		WHERE g.user_id = $1 AND g.repertoire_id = $2 AND a.source NOT IN ('broadcast', 'lichess_team')
+			AND (m->>'isUserMove')::boolean AND m ? 'timeSpent' AND (m->>'plyNumber')::int < $3
+		GROUP BY 1
+		ORDER BY 1
+	`
+	getRawAnalysesSQL = `
+		SELECT id, filename, uploaded_at
+		FROM analyses
+		WHERE user_id = $1
+		ORDER BY uploaded_at DESC
 	`
 )
 
-// PostgresAnalysisRepo implements AnalysisRepository using PostgreSQL
+// PostgresAnalysisRepo implements AnalysisRepository using PostgreSQL.
+// Analysis metadata lives in the analyses table; each game is a row in the games table.
 type PostgresAnalysisRepo struct {
 	pool *pgxpool.Pool
 }
@@ -61,48 +224,193 @@ func NewPostgresAnalysisRepo(pool *pgxpool.Pool) *PostgresAnalysisRepo {
 	return &PostgresAnalysisRepo{pool: pool}
 }
 
-// Save saves a new analysis
-func (r *PostgresAnalysisRepo) Save(userID string, username, filename string, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+// gameRow holds the column values derived from a GameAnalysis for insert/update
+type gameRow struct {
+	headers         []byte
+	moves           []byte
+	repertoireID    *string
+	repertoireName  *string
+	timeClass       string
+	status          string
+	classifications []string
+	// Snapshot of the matched repertoire, NULL when unmatched or not recorded
+	repertoireVersion  *int
+	repertoireTreeHash *string
+}
+
+func toGameRow(game models.GameAnalysis) (*gameRow, error) {
+	headers := game.Headers
+	if headers == nil {
+		headers = models.PGNHeaders{}
+	}
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal headers: %w", err)
+	}
+
+	moves := game.Moves
+	if moves == nil {
+		moves = []models.MoveAnalysis{}
+	}
+	movesJSON, err := json.Marshal(moves)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moves: %w", err)
+	}
+
+	row := &gameRow{
+		headers:         headersJSON,
+		moves:           movesJSON,
+		timeClass:       models.ClassifyTimeControl(game.Headers["TimeControl"]),
+		status:          computeGameStatus(game),
+		classifications: gameClassifications(game),
+	}
+	if game.MatchedRepertoire != nil {
+		row.repertoireID = &game.MatchedRepertoire.ID
+		row.repertoireName = &game.MatchedRepertoire.Name
+		if game.MatchedRepertoire.Version > 0 {
+			row.repertoireVersion = &game.MatchedRepertoire.Version
+			row.repertoireTreeHash = &game.MatchedRepertoire.TreeHash
+		}
+	}
+	return row, nil
+}
+
+// scanGame scans a row selected with the column list used by getGameSQL
+func scanGame(row pgx.Row) (string, *models.GameAnalysis, error) {
+	var analysisID string
+	var game models.GameAnalysis
+	var headersJSON, movesJSON []byte
+	var repertoireID, repertoireName, repertoireTreeHash *string
+	var repertoireVersion *int
+
+	if err := row.Scan(
+		&analysisID,
+		&game.GameIndex,
+		&headersJSON,
+		&movesJSON,
+		&game.UserColor,
+		&repertoireID,
+		&repertoireName,
+		&game.MatchScore,
+		&game.Note,
+		&game.Starred,
+		&repertoireVersion,
+		&repertoireTreeHash,
+	); err != nil {
+		return "", nil, err
+	}
+
+	if err := json.Unmarshal(headersJSON, &game.Headers); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal headers: %w", err)
+	}
+	if err := json.Unmarshal(movesJSON, &game.Moves); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal moves: %w", err)
+	}
+	if repertoireID != nil {
+		ref := &models.RepertoireRef{ID: *repertoireID}
+		if repertoireName != nil {
+			ref.Name = *repertoireName
+		}
+		if repertoireVersion != nil && repertoireTreeHash != nil {
+			ref.Version = *repertoireVersion
+			ref.TreeHash = *repertoireTreeHash
+		}
+		game.MatchedRepertoire = ref
+	}
+
+	return analysisID, &game, nil
+}
+
+// Save saves a new analysis and its games in a single transaction
+func (r *PostgresAnalysisRepo) Save(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	resultsJSON, err := json.Marshal(results)
+	if provenance.Source == "" {
+		provenance.Source = models.ImportSourcePGN
+	}
+	paramsJSON, err := json.Marshal(provenance.SourceParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal results: %w", err)
+		return nil, fmt.Errorf("failed to marshal source_params: %w", err)
 	}
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	id := uuid.New()
 	uploadedAt := time.Now()
 
 	var summary models.AnalysisSummary
-	err = r.pool.QueryRow(ctx, saveAnalysisSQL,
+	err = tx.QueryRow(ctx, saveAnalysisSQL,
 		id,
 		userID,
 		username,
 		filename,
 		gameCount,
-		resultsJSON,
 		uploadedAt,
+		provenance.Source,
+		paramsJSON,
 	).Scan(
 		&summary.ID,
 		&summary.Username,
 		&summary.Filename,
 		&summary.GameCount,
 		&summary.UploadedAt,
+		&summary.Source,
+		&paramsJSON,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save analysis: %w", err)
 	}
+	if err := json.Unmarshal(paramsJSON, &summary.SourceParams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal source_params: %w", err)
+	}
+
+	if len(results) > 0 {
+		batch := &pgx.Batch{}
+		for _, game := range results {
+			row, err := toGameRow(game)
+			if err != nil {
+				return nil, err
+			}
+			batch.Queue(saveGameSQL,
+				id,
+				game.GameIndex,
+				userID,
+				row.headers,
+				row.moves,
+				game.UserColor,
+				row.repertoireID,
+				row.repertoireName,
+				game.MatchScore,
+				row.timeClass,
+				row.status,
+				row.classifications,
+				row.repertoireVersion,
+				row.repertoireTreeHash,
+			)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, fmt.Errorf("failed to save games: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit analysis: %w", err)
+	}
 
 	return &summary, nil
 }
 
-// GetAll returns all analysis summaries for a user
-func (r *PostgresAnalysisRepo) GetAll(userID string) ([]models.AnalysisSummary, error) {
+// GetAll returns all analysis summaries for a user, only those of one import source when source is set
+func (r *PostgresAnalysisRepo) GetAll(userID, source string) ([]models.AnalysisSummary, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	rows, err := r.pool.Query(ctx, getAnalysesSQL, userID)
+	rows, err := r.pool.Query(ctx, getAnalysesSQL, userID, source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query analyses: %w", err)
 	}
@@ -111,10 +419,14 @@ func (r *PostgresAnalysisRepo) GetAll(userID string) ([]models.AnalysisSummary,
 	var analyses []models.AnalysisSummary
 	for rows.Next() {
 		var a models.AnalysisSummary
-		err := rows.Scan(&a.ID, &a.Username, &a.Filename, &a.GameCount, &a.UploadedAt)
+		var paramsJSON []byte
+		err := rows.Scan(&a.ID, &a.Username, &a.Filename, &a.GameCount, &a.UploadedAt, &a.Source, &paramsJSON)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan analysis: %w", err)
 		}
+		if err := json.Unmarshal(paramsJSON, &a.SourceParams); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal source_params: %w", err)
+		}
 		analyses = append(analyses, a)
 	}
 
@@ -131,15 +443,16 @@ func (r *PostgresAnalysisRepo) GetByID(id string) (*models.AnalysisDetail, error
 	defer cancel()
 
 	var detail models.AnalysisDetail
-	var resultsJSON []byte
+	var paramsJSON []byte
 
 	err := r.pool.QueryRow(ctx, getAnalysisByIDSQL, id).Scan(
 		&detail.ID,
 		&detail.Username,
 		&detail.Filename,
 		&detail.GameCount,
-		&resultsJSON,
 		&detail.UploadedAt,
+		&detail.Source,
+		&paramsJSON,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -147,14 +460,48 @@ func (r *PostgresAnalysisRepo) GetByID(id string) (*models.AnalysisDetail, error
 		}
 		return nil, fmt.Errorf("failed to get analysis: %w", err)
 	}
+	if err := json.Unmarshal(paramsJSON, &detail.SourceParams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal source_params: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, getGamesByAnalysisSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games: %w", err)
+	}
+	defer rows.Close()
+
+	detail.Results = []models.GameAnalysis{}
+	for rows.Next() {
+		_, game, err := scanGame(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
+		}
+		detail.Results = append(detail.Results, *game)
+	}
 
-	if err := json.Unmarshal(resultsJSON, &detail.Results); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal results: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
 	}
 
 	return &detail, nil
 }
 
+// GetGame returns a single game of an analysis
+func (r *PostgresAnalysisRepo) GetGame(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, game, err := scanGame(r.pool.QueryRow(ctx, getGameSQL, analysisID, gameIndex))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrGameNotFound
+		}
+		return nil, fmt.Errorf("failed to get game: %w", err)
+	}
+
+	return game, nil
+}
+
 // Delete deletes an analysis by ID
 func (r *PostgresAnalysisRepo) Delete(id string) error {
 	ctx, cancel := dbContext()
@@ -173,182 +520,155 @@ func (r *PostgresAnalysisRepo) Delete(id string) error {
 }
 
 // GetAllGames returns all games from all analyses with pagination for a user
-func (r *PostgresAnalysisRepo) GetAllGames(userID string, limit, offset int, timeClass, repertoire, source string) (*models.GamesResponse, error) {
+func (r *PostgresAnalysisRepo) GetAllGames(userID string, limit, offset int, timeClass, repertoire, source, classification string, starred bool) (*models.GamesResponse, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	rows, err := r.pool.Query(ctx, getAllGamesSQL, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	var total int
+	if err := r.pool.QueryRow(ctx, countGamesSQL, userID, timeClass, repertoire, source, starred, classification).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count games: %w", err)
 	}
-	defer rows.Close()
 
-	viewedGames, err := r.GetViewedGames(userID)
+	rows, err := r.pool.Query(ctx, getAllGamesSQL, userID, timeClass, repertoire, source, starred, classification, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get viewed games: %w", err)
+		return nil, fmt.Errorf("failed to query games: %w", err)
 	}
+	defer rows.Close()
 
-	var allGames []models.GameSummary
-
+	games := []models.GameSummary{}
 	for rows.Next() {
-		var analysisID string
+		var summary models.GameSummary
+		var repertoireID, repertoireName *string
 		var filename string
-		var resultsJSON []byte
-		var uploadedAt time.Time
-
-		if err := rows.Scan(&analysisID, &filename, &resultsJSON, &uploadedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan analysis: %w", err)
+		var viewed bool
+
+		if err := rows.Scan(
+			&summary.AnalysisID,
+			&summary.GameIndex,
+			&summary.White,
+			&summary.Black,
+			&summary.Result,
+			&summary.Date,
+			&summary.Opening,
+			&summary.UserColor,
+			&repertoireID,
+			&repertoireName,
+			&summary.TimeClass,
+			&summary.Status,
+			&filename,
+			&summary.Source,
+			&summary.ImportedAt,
+			&viewed,
+			&summary.Note,
+			&summary.Starred,
+			&summary.Classifications,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
 		}
 
-		analysisSource := classifySource(filename)
-		analysisSynced := isSynced(filename)
-
-		if source != "" && analysisSource != source {
-			continue
+		summary.Synced = isSynced(filename) && !viewed
+		if repertoireID != nil {
+			summary.RepertoireID = *repertoireID
 		}
-
-		var games []models.GameAnalysis
-		if err := json.Unmarshal(resultsJSON, &games); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal results: %w", err)
-		}
-
-		for _, game := range games {
-			status := computeGameStatus(game)
-			tc := models.ClassifyTimeControl(game.Headers["TimeControl"])
-			if timeClass != "" && tc != timeClass {
-				continue
-			}
-			gameOpening := game.Headers["Opening"]
-			gameRepertoire := ""
-			if game.MatchedRepertoire != nil {
-				gameRepertoire = game.MatchedRepertoire.Name
-			}
-			if repertoire != "" && gameRepertoire != repertoire {
-				continue
-			}
-			summary := models.GameSummary{
-				AnalysisID: analysisID,
-				GameIndex:  game.GameIndex,
-				White:      game.Headers["White"],
-				Black:      game.Headers["Black"],
-				Result:     game.Headers["Result"],
-				Date:       game.Headers["Date"],
-				UserColor:  game.UserColor,
-				Status:     status,
-				TimeClass:  tc,
-				Opening:    gameOpening,
-				ImportedAt: uploadedAt,
-				Source:     analysisSource,
-			Synced:     analysisSynced && !viewedGames[fmt.Sprintf("%s-%d", analysisID, game.GameIndex)],
-			}
-			if game.MatchedRepertoire != nil {
-				summary.RepertoireName = game.MatchedRepertoire.Name
-				summary.RepertoireID = game.MatchedRepertoire.ID
-			}
-			allGames = append(allGames, summary)
+		if repertoireName != nil {
+			summary.RepertoireName = *repertoireName
 		}
+		games = append(games, summary)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating analyses: %w", err)
-	}
-
-	total := len(allGames)
-
-	// Apply pagination
-	start := offset
-	if start > total {
-		start = total
-	}
-	end := start + limit
-	if end > total {
-		end = total
-	}
-
-	paginatedGames := allGames[start:end]
-	if paginatedGames == nil {
-		paginatedGames = []models.GameSummary{}
+		return nil, fmt.Errorf("error iterating games: %w", err)
 	}
 
 	return &models.GamesResponse{
-		Games:  paginatedGames,
+		Games:  games,
 		Total:  total,
 		Limit:  limit,
 		Offset: offset,
 	}, nil
 }
 
-// DeleteGame removes a single game from an analysis
-func (r *PostgresAnalysisRepo) DeleteGame(analysisID string, gameIndex int) error {
+// UpdateGameNotes sets the note and/or the starred flag of a game; nil values are left unchanged
+func (r *PostgresAnalysisRepo) UpdateGameNotes(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	// First, get the current analysis
-	var resultsJSON []byte
-	err := r.pool.QueryRow(ctx, "SELECT results FROM analyses WHERE id = $1", analysisID).Scan(&resultsJSON)
+	notes := models.GameNotes{AnalysisID: analysisID, GameIndex: gameIndex}
+	err := r.pool.QueryRow(ctx, updateGameNotesSQL, analysisID, gameIndex, note, starred).Scan(&notes.Note, &notes.Starred)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrAnalysisNotFound
+			return nil, ErrGameNotFound
 		}
-		return fmt.Errorf("failed to get analysis: %w", err)
+		return nil, fmt.Errorf("failed to update game notes: %w", err)
 	}
+	return &notes, nil
+}
 
-	var games []models.GameAnalysis
-	if err := json.Unmarshal(resultsJSON, &games); err != nil {
-		return fmt.Errorf("failed to unmarshal results: %w", err)
+// DeleteGame removes a single game from an analysis
+func (r *PostgresAnalysisRepo) DeleteGame(analysisID string, gameIndex int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, deleteGameSQL, analysisID, gameIndex)
+	if err != nil {
+		return fmt.Errorf("failed to delete game: %w", err)
 	}
 
-	// Find and remove the game with the given index
-	found := false
-	var updatedGames []models.GameAnalysis
-	for _, game := range games {
-		if game.GameIndex == gameIndex {
-			found = true
-		} else {
-			updatedGames = append(updatedGames, game)
+	if result.RowsAffected() == 0 {
+		var exists bool
+		if err := r.pool.QueryRow(ctx, analysisExistsSQL, analysisID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to get analysis: %w", err)
+		}
+		if !exists {
+			return ErrAnalysisNotFound
 		}
+		return ErrGameNotFound
 	}
 
-	if !found {
-		return ErrGameNotFound
+	var remaining int
+	if err := r.pool.QueryRow(ctx, decrementGameCountSQL, analysisID).Scan(&remaining); err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
 	}
 
 	// If no games left, delete the entire analysis
-	if len(updatedGames) == 0 {
+	if remaining <= 0 {
 		return r.Delete(analysisID)
 	}
 
-	// Update the analysis with remaining games
-	updatedJSON, err := json.Marshal(updatedGames)
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated results: %w", err)
-	}
-
-	_, err = r.pool.Exec(ctx, updateAnalysisResultsSQL, analysisID, updatedJSON, len(updatedGames))
-	if err != nil {
-		return fmt.Errorf("failed to update analysis: %w", err)
-	}
-
 	return nil
 }
 
-// UpdateResults updates the results array of an existing analysis
-func (r *PostgresAnalysisRepo) UpdateResults(analysisID string, results []models.GameAnalysis) error {
+// UpdateGame replaces the stored data of a single game
+func (r *PostgresAnalysisRepo) UpdateGame(analysisID string, game models.GameAnalysis) error {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	resultsJSON, err := json.Marshal(results)
+	row, err := toGameRow(game)
 	if err != nil {
-		return fmt.Errorf("failed to marshal results: %w", err)
-	}
-
-	result, err := r.pool.Exec(ctx, updateAnalysisResultsSQL, analysisID, resultsJSON, len(results))
+		return err
+	}
+
+	result, err := r.pool.Exec(ctx, updateGameSQL,
+		analysisID,
+		game.GameIndex,
+		row.headers,
+		row.moves,
+		game.UserColor,
+		row.repertoireID,
+		row.repertoireName,
+		game.MatchScore,
+		row.timeClass,
+		row.status,
+		row.classifications,
+		row.repertoireVersion,
+		row.repertoireTreeHash,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to update analysis results: %w", err)
+		return fmt.Errorf("failed to update game: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return ErrAnalysisNotFound
+		return ErrGameNotFound
 	}
 
 	return nil
@@ -372,45 +692,24 @@ func (r *PostgresAnalysisRepo) GetDistinctRepertoires(userID string) ([]string,
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	rows, err := r.pool.Query(ctx, getAllGamesSQL, userID)
+	rows, err := r.pool.Query(ctx, getDistinctRepertoiresSQL, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query analyses: %w", err)
+		return nil, fmt.Errorf("failed to query repertoires: %w", err)
 	}
 	defer rows.Close()
 
-	seen := make(map[string]struct{})
-
+	repertoires := []string{}
 	for rows.Next() {
-		var analysisID string
-		var filename string
-		var resultsJSON []byte
-		var uploadedAt time.Time
-
-		if err := rows.Scan(&analysisID, &filename, &resultsJSON, &uploadedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan analysis: %w", err)
-		}
-
-		var games []models.GameAnalysis
-		if err := json.Unmarshal(resultsJSON, &games); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal results: %w", err)
-		}
-
-		for _, game := range games {
-			if game.MatchedRepertoire != nil && game.MatchedRepertoire.Name != "" {
-				seen[game.MatchedRepertoire.Name] = struct{}{}
-			}
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire: %w", err)
 		}
+		repertoires = append(repertoires, name)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating analyses: %w", err)
-	}
-
-	repertoires := make([]string, 0, len(seen))
-	for r := range seen {
-		repertoires = append(repertoires, r)
+		return nil, fmt.Errorf("error iterating repertoires: %w", err)
 	}
-	sort.Strings(repertoires)
 
 	return repertoires, nil
 }
@@ -461,25 +760,20 @@ func (r *PostgresAnalysisRepo) GetAllGamesRaw(userID string) ([]models.RawAnalys
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	rows, err := r.pool.Query(ctx, getAllGamesSQL, userID)
+	rows, err := r.pool.Query(ctx, getRawAnalysesSQL, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query analyses: %w", err)
 	}
 	defer rows.Close()
 
 	var analyses []models.RawAnalysis
+	byID := make(map[string]int)
 	for rows.Next() {
 		var a models.RawAnalysis
-		var resultsJSON []byte
-
-		if err := rows.Scan(&a.ID, &a.Filename, &resultsJSON, &a.UploadedAt); err != nil {
+		if err := rows.Scan(&a.ID, &a.Filename, &a.UploadedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan analysis: %w", err)
 		}
-
-		if err := json.Unmarshal(resultsJSON, &a.Results); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal results: %w", err)
-		}
-
+		byID[a.ID] = len(analyses)
 		analyses = append(analyses, a)
 	}
 
@@ -487,18 +781,148 @@ func (r *PostgresAnalysisRepo) GetAllGamesRaw(userID string) ([]models.RawAnalys
 		return nil, fmt.Errorf("error iterating analyses: %w", err)
 	}
 
+	gameRows, err := r.pool.Query(ctx, getGamesByUserSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games: %w", err)
+	}
+	defer gameRows.Close()
+
+	for gameRows.Next() {
+		analysisID, game, err := scanGame(gameRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
+		}
+		if idx, ok := byID[analysisID]; ok {
+			analyses[idx].Results = append(analyses[idx].Results, *game)
+		}
+	}
+
+	if err := gameRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
+	}
+
 	return analyses, nil
 }
 
-// classifySource derives the import source from the analysis filename
-func classifySource(filename string) string {
-	if strings.HasPrefix(filename, "sync_lichess_") || strings.HasPrefix(filename, "lichess_") {
-		return "lichess"
+// GetGameLocationsByRepertoire returns where every game of a user matched to a repertoire is stored
+func (r *PostgresAnalysisRepo) GetGameLocationsByRepertoire(userID, repertoireID string) ([]GameLocation, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getGameLocationsByRepertoireSQL, userID, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games by repertoire: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []GameLocation
+	for rows.Next() {
+		var loc GameLocation
+		if err := rows.Scan(&loc.AnalysisID, &loc.GameIndex); err != nil {
+			return nil, fmt.Errorf("failed to scan game location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
+	}
+	return locations, nil
+}
+
+// RecordSkippedDuplicates records how many duplicate games an import skipped
+func (r *PostgresAnalysisRepo) RecordSkippedDuplicates(userID, filename string, count int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, recordSkippedDuplicatesSQL, userID, filename, count); err != nil {
+		return fmt.Errorf("failed to record skipped duplicates: %w", err)
+	}
+	return nil
+}
+
+// GetImportStats aggregates a user's imports per source and month, newest month first
+func (r *PostgresAnalysisRepo) GetImportStats(userID string) ([]models.ImportSourceStats, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getImportStatsSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.ImportSourceStats{}
+	for rows.Next() {
+		var st models.ImportSourceStats
+		var month time.Time
+		var outOfRepertoire, evaluated int
+		if err := rows.Scan(&st.Source, &month, &st.GamesImported, &st.DuplicatesSkipped, &outOfRepertoire, &evaluated); err != nil {
+			return nil, fmt.Errorf("failed to scan import stats: %w", err)
+		}
+		st.Month = month.Format("2006-01")
+		if st.GamesImported > 0 {
+			st.OutOfRepertoireRate = float64(outOfRepertoire) / float64(st.GamesImported)
+			st.EvalCompletion = float64(evaluated) / float64(st.GamesImported)
+		}
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import stats: %w", err)
+	}
+	return stats, nil
+}
+
+// GetBookDepth returns, per import month, where the user's games in a repertoire left the book
+func (r *PostgresAnalysisRepo) GetBookDepth(userID, repertoireID string) ([]models.BookDepthPeriod, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getBookDepthSQL, userID, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query book depth: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.BookDepthPeriod{}
+	for rows.Next() {
+		var p models.BookDepthPeriod
+		var month time.Time
+		if err := rows.Scan(&month, &p.Games, &p.UserDeviations, &p.UserLeaveBookPly, &p.OpponentDeviations, &p.OpponentLeaveBookPly); err != nil {
+			return nil, fmt.Errorf("failed to scan book depth: %w", err)
+		}
+		p.Month = month.Format("2006-01")
+		periods = append(periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book depth: %w", err)
+	}
+	return periods, nil
+}
+
+// GetMoveTimes returns the average time the user spent on their moves at each ply below maxPly
+// in a repertoire's games, counting only moves with clock data
+func (r *PostgresAnalysisRepo) GetMoveTimes(userID, repertoireID string, maxPly int) ([]models.PlyMoveTime, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getMoveTimesSQL, userID, repertoireID, maxPly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query move times: %w", err)
+	}
+	defer rows.Close()
+
+	times := []models.PlyMoveTime{}
+	for rows.Next() {
+		var t models.PlyMoveTime
+		if err := rows.Scan(&t.Ply, &t.Moves, &t.InBook, &t.AvgTimeSpent); err != nil {
+			return nil, fmt.Errorf("failed to scan move times: %w", err)
+		}
+		times = append(times, t)
 	}
-	if strings.HasPrefix(filename, "sync_chesscom_") || strings.HasPrefix(filename, "chesscom_") {
-		return "chesscom"
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating move times: %w", err)
 	}
-	return "pgn"
+	return times, nil
 }
 
 // isSynced returns true if the analysis was imported via automatic sync
@@ -518,3 +942,19 @@ func computeGameStatus(game models.GameAnalysis) string {
 	}
 	return "ok"
 }
+
+// gameClassifications returns the classification rule keys matched by the moves of a game, in
+// the order they first appear
+func gameClassifications(game models.GameAnalysis) []string {
+	keys := []string{}
+	seen := make(map[string]bool)
+	for _, move := range game.Moves {
+		for _, key := range move.Classifications {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
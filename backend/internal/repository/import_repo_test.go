@@ -377,3 +377,37 @@ func TestGamesResponse_EmptyGames(t *testing.T) {
 	assert.Empty(t, decoded.Games)
 	assert.Equal(t, 0, decoded.Total)
 }
+
+func TestToGameRow_DerivedColumns(t *testing.T) {
+	game := models.GameAnalysis{
+		GameIndex: 2,
+		Headers:   models.PGNHeaders{"TimeControl": "300+0", "White": "me"},
+		Moves: []models.MoveAnalysis{
+			{PlyNumber: 0, SAN: "e4", Status: "in-repertoire", IsUserMove: true},
+			{PlyNumber: 1, SAN: "a6", Status: "opponent-new", IsUserMove: false},
+		},
+		UserColor:         models.ColorWhite,
+		MatchedRepertoire: &models.RepertoireRef{ID: "rep-1", Name: "Main"},
+	}
+
+	row, err := toGameRow(game)
+	require.NoError(t, err)
+
+	assert.Equal(t, "blitz", row.timeClass)
+	assert.Equal(t, "new-line", row.status)
+	require.NotNil(t, row.repertoireID)
+	assert.Equal(t, "rep-1", *row.repertoireID)
+	assert.Equal(t, "Main", *row.repertoireName)
+	assert.JSONEq(t, `{"TimeControl":"300+0","White":"me"}`, string(row.headers))
+}
+
+func TestToGameRow_NilFields(t *testing.T) {
+	row, err := toGameRow(models.GameAnalysis{UserColor: models.ColorBlack})
+	require.NoError(t, err)
+
+	assert.Equal(t, "{}", string(row.headers))
+	assert.Equal(t, "[]", string(row.moves))
+	assert.Nil(t, row.repertoireID)
+	assert.Nil(t, row.repertoireName)
+	assert.Equal(t, "ok", row.status)
+}
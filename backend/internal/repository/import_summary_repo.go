@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	saveImportSummarySQL = `
+		INSERT INTO import_summaries (analysis_id, user_id, games_found, games_imported, warnings)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (analysis_id) DO UPDATE SET
+			games_found = EXCLUDED.games_found,
+			games_imported = EXCLUDED.games_imported,
+			warnings = EXCLUDED.warnings
+	`
+	getImportSummarySQL = `
+		SELECT analysis_id, games_found, games_imported, warnings, created_at
+		FROM import_summaries
+		WHERE analysis_id = $1 AND user_id = $2
+	`
+)
+
+// PostgresImportSummaryRepo implements ImportSummaryRepository using PostgreSQL
+type PostgresImportSummaryRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresImportSummaryRepo creates a new PostgreSQL import summary repository
+func NewPostgresImportSummaryRepo(pool *pgxpool.Pool) *PostgresImportSummaryRepo {
+	return &PostgresImportSummaryRepo{pool: pool}
+}
+
+// Save stores the summary of an import run
+func (r *PostgresImportSummaryRepo) Save(userID string, summary models.ImportSummary) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	warnings := summary.Warnings
+	if warnings == nil {
+		warnings = []models.ImportWarning{}
+	}
+	warningsJSON, err := json.Marshal(warnings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal import warnings: %w", err)
+	}
+
+	if _, err := r.pool.Exec(ctx, saveImportSummarySQL, summary.AnalysisID, userID,
+		summary.GamesFound, summary.GamesImported, warningsJSON); err != nil {
+		return fmt.Errorf("failed to save import summary: %w", err)
+	}
+	return nil
+}
+
+// GetByAnalysisID returns the summary of a user's import run
+func (r *PostgresImportSummaryRepo) GetByAnalysisID(analysisID, userID string) (*models.ImportSummary, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var s models.ImportSummary
+	var warningsJSON []byte
+	err := r.pool.QueryRow(ctx, getImportSummarySQL, analysisID, userID).Scan(
+		&s.AnalysisID, &s.GamesFound, &s.GamesImported, &warningsJSON, &s.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrImportSummaryNotFound
+		}
+		return nil, fmt.Errorf("failed to get import summary: %w", err)
+	}
+	if err := json.Unmarshal(warningsJSON, &s.Warnings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal import warnings: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	getInsightSettingsSQL = `
+		SELECT min_drop, min_ply, min_frequency, max_mistakes
+		FROM insight_settings
+		WHERE user_id = $1
+	`
+	saveInsightSettingsSQL = `
+		INSERT INTO insight_settings (user_id, min_drop, min_ply, min_frequency, max_mistakes, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET min_drop = EXCLUDED.min_drop, min_ply = EXCLUDED.min_ply, min_frequency = EXCLUDED.min_frequency,
+			max_mistakes = EXCLUDED.max_mistakes, updated_at = EXCLUDED.updated_at
+	`
+)
+
+// PostgresInsightSettingsRepo implements InsightSettingsRepository using PostgreSQL
+type PostgresInsightSettingsRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresInsightSettingsRepo creates a new PostgreSQL insight settings repository
+func NewPostgresInsightSettingsRepo(pool *pgxpool.Pool) *PostgresInsightSettingsRepo {
+	return &PostgresInsightSettingsRepo{pool: pool}
+}
+
+// Get returns the insight settings saved by a user
+func (r *PostgresInsightSettingsRepo) Get(userID string) (*models.InsightSettings, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var s models.InsightSettings
+	err := r.pool.QueryRow(ctx, getInsightSettingsSQL, userID).Scan(&s.MinDrop, &s.MinPly, &s.MinFrequency, &s.MaxMistakes)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInsightSettingsNotFound
+		}
+		return nil, fmt.Errorf("failed to get insight settings: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Save stores the insight settings of a user, replacing the previous ones
+func (r *PostgresInsightSettingsRepo) Save(userID string, settings models.InsightSettings) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, saveInsightSettingsSQL, userID, settings.MinDrop, settings.MinPly, settings.MinFrequency, settings.MaxMistakes)
+	if err != nil {
+		return fmt.Errorf("failed to save insight settings: %w", err)
+	}
+
+	return nil
+}
@@ -12,6 +12,48 @@ type FingerprintEntry struct {
 	GameIndex   int
 }
 
+// RawGameEntry is the PGN of one imported game, to be archived under its fingerprint
+type RawGameEntry struct {
+	Fingerprint string
+	PGN         string
+}
+
+// RawGameKey locates an archived game, for paging through the archive
+type RawGameKey struct {
+	UserID      string
+	Fingerprint string
+}
+
+// GameResultEntry records that a game reached a repertoire node, with the user's outcome
+type GameResultEntry struct {
+	GameIndex    int
+	RepertoireID string
+	NodeID       string
+	Outcome      string // "win", "draw" or "loss"
+}
+
+// OpponentReplyEntry records a move an opponent played against the user in an imported game
+type OpponentReplyEntry struct {
+	GameIndex      int
+	Ply            int
+	FEN            string // normalized position the move was played from
+	Move           string // SAN
+	OpponentRating int    // 0 when unknown
+	Outcome        string // "win", "draw" or "loss" for the user, "" for unfinished games
+}
+
+// RepertoireOwner pairs a repertoire with the user who owns it
+type RepertoireOwner struct {
+	RepertoireID string
+	UserID       string
+}
+
+// GameLocation identifies a stored game by its analysis and index
+type GameLocation struct {
+	AnalysisID string
+	GameIndex  int
+}
+
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
 	Create(email, username, passwordHash string) (*models.User, error)
@@ -22,10 +64,14 @@ type UserRepository interface {
 	EmailExists(email string) (bool, error)
 	FindByOAuth(provider, oauthID string) (*models.User, error)
 	CreateOAuth(provider, oauthID, username string) (*models.User, error)
-	UpdateProfile(userID string, lichess, chesscom *string, timeFormatPrefs []string) (*models.User, error)
+	UpdateProfile(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error)
 	UpdateSyncTimestamps(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error
+	UpdateLinkedAccountSync(accountID string, syncedAt time.Time) error
 	UpdateLichessToken(userID, token string) error
 	UpdatePassword(userID, passwordHash string) error
+	UpdateNotifications(userID string, weeklyDigest bool) (*models.User, error)
+	ListDigestRecipients(sentBefore time.Time) ([]models.User, error)
+	MarkDigestSent(userID string, sentAt time.Time) error
 }
 
 // RepertoireRepository defines the interface for repertoire data operations
@@ -35,50 +81,280 @@ type RepertoireRepository interface {
 	GetAll(userID string) ([]models.Repertoire, error)
 	Create(userID string, name string, color models.Color) (*models.Repertoire, error)
 	Save(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error)
+	// SaveNodes writes only the nodes of an edited tree listed in changes, provided the stored
+	// tree is still at version, or returns ErrRepertoireVersionConflict
+	SaveNodes(id string, version int, tree models.RepertoireNode, changes NodeChanges, metadata models.Metadata) (*models.Repertoire, error)
 	UpdateName(id string, name string) (*models.Repertoire, error)
 	Delete(id string) error
 	Count(userID string) (int, error)
 	Exists(id string) (bool, error)
 	BelongsToUser(id string, userID string) (bool, error)
+	FindPositions(userID string, fens []string) ([]models.RepertoirePosition, error)
+	Search(userID, query string, limit int) ([]models.RepertoireSearchResult, error)
+	ListRevisions(repertoireID string) ([]models.RepertoireRevision, error)
+	GetRevision(repertoireID string, version int) (*models.RepertoireRevision, error)
+	// GetNotes returns a version of the study notes of a repertoire, the newest one when version is 0
+	GetNotes(repertoireID string, version int) (*models.StudyNotes, error)
+	SaveNotes(repertoireID, userID, content string) (*models.StudyNotes, error)
+	ListNoteRevisions(repertoireID string) ([]models.StudyNotesRevision, error)
+	// ListUnlabeled returns the IDs of unarchived repertoires whose opening labels predate labelsVersion
+	ListUnlabeled(labelsVersion, limit int) ([]string, error)
+	MarkOpeningsLabeled(id string, labelsVersion int) error
+}
+
+// TemplateRepository defines the interface for repertoire template operations
+type TemplateRepository interface {
+	List(userID string) ([]models.RepertoireTemplate, error)
+	GetByID(id string) (*models.RepertoireTemplate, error)
+	Create(tmpl models.RepertoireTemplate) (*models.RepertoireTemplate, error)
+	SetFeatured(id string, featured bool) (*models.RepertoireTemplate, error)
+	EnsureBuiltin(templates []models.RepertoireTemplate) error
+}
+
+// CollaboratorRepository defines the interface for repertoire sharing operations
+type CollaboratorRepository interface {
+	Upsert(repertoireID, userID string, role models.CollaboratorRole, invitedBy string) (*models.RepertoireCollaborator, error)
+	Get(repertoireID, userID string) (*models.RepertoireCollaborator, error)
+	ListByRepertoire(repertoireID string) ([]models.RepertoireCollaborator, error)
+	ListByUser(userID string) ([]models.RepertoireCollaborator, error)
+	Delete(repertoireID, userID string) error
+}
+
+// CoachLinkRepository defines the interface for coach-student link operations
+type CoachLinkRepository interface {
+	Create(coachID, studentID, invitedBy string) (*models.CoachLink, error)
+	GetByID(id string) (*models.CoachLink, error)
+	ListByUser(userID string) ([]models.CoachLink, error)
+	Accept(id string, at time.Time) (*models.CoachLink, error)
+	Delete(id string) error
+	IsActive(coachID, studentID string) (bool, error)
 }
 
 // GameFingerprintRepository defines the interface for game fingerprint operations
 type GameFingerprintRepository interface {
-	CheckExisting(userID string, fingerprints []string) (map[string]bool, error)
+	CheckExisting(userID string, fingerprints []string) (map[string]GameLocation, error)
 	SaveBatch(userID, analysisID string, entries []FingerprintEntry) error
 	DeleteByAnalysisAndIndex(analysisID string, gameIndex int) error
 }
 
+// GameResultRepository defines the interface for the per-node game results overlay
+type GameResultRepository interface {
+	SaveBatch(analysisID string, entries []GameResultEntry) error
+	ReplaceGame(analysisID string, gameIndex int, entries []GameResultEntry) error
+	Overlay(repertoireID, userID string) ([]models.NodeResult, error)
+}
+
+// OpponentReplyRepository defines the interface for the opponents' replies recorded from imported games
+type OpponentReplyRepository interface {
+	SaveBatch(analysisID string, entries []OpponentReplyEntry) error
+	Aggregate(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error)
+}
+
 // EngineEvalRepository defines the interface for engine evaluation operations
 type EngineEvalRepository interface {
 	CreatePendingBatch(userID, analysisID string, gameCount int) error
-	GetPending(limit int) ([]models.EngineEval, error)
-	MarkProcessing(id string) error
-	SaveEvals(id string, evals []models.ExplorerMoveStats) error
-	MarkFailed(id string) error
+	ClaimPending(workerID string, limit int, lease time.Duration) ([]models.EngineEval, error)
+	ExtendLease(workerID string, ids []string, lease time.Duration) (int64, error)
+	SaveEvals(id, workerID string, evals []models.ExplorerMoveStats) error
+	MarkFailed(id, workerID string, maxAttempts int, baseDelay, maxDelay time.Duration) (bool, error)
+	RetryFailed(analysisID string) (int64, error)
+	CountUnfinished(analysisID string) (int, error)
 	GetByUser(userID string) ([]models.EngineEval, error)
+	DeleteOlderThan(before time.Time) (int64, error)
+	DeleteByUserOlderThan(userID string, before time.Time) (int64, error)
+	ResetAnalysis(userID, analysisID string, gameIndices []int) error
+}
+
+// ReanalysisJobRepository defines the interface for repertoire re-analysis job operations
+type ReanalysisJobRepository interface {
+	Create(userID, repertoireID string) (*models.ReanalysisJob, error)
+	GetByID(id string) (*models.ReanalysisJob, error)
+	GetPending(limit int) ([]models.ReanalysisJob, error)
+	MarkProcessing(id string, total int) error
+	UpdateProgress(id string, processed int) error
+	MarkDone(id string) error
+	MarkFailed(id string, message string) error
+}
+
+// RawGameRepository defines the interface for the append-only archive of imported games
+type RawGameRepository interface {
+	Append(userID, source string, games []RawGameEntry) error
+	// Count and ListAfter cover every user when userID is empty
+	Count(userID string) (int, error)
+	ListAfter(userID string, after RawGameKey, limit int) ([]models.RawGame, error)
+}
+
+// RecomputeJobRepository defines the interface for archive recompute job operations
+type RecomputeJobRepository interface {
+	Create(userID string) (*models.RecomputeJob, error)
+	GetByID(id string) (*models.RecomputeJob, error)
+	GetPending(limit int) ([]models.RecomputeJob, error)
+	MarkProcessing(id string, total int) error
+	UpdateProgress(id string, processed, recomputed int) error
+	MarkDone(id string) error
+	MarkFailed(id string, message string) error
+}
+
+// ImportJobRepository defines the interface for background PGN database imports
+type ImportJobRepository interface {
+	Create(job models.ImportJob) (*models.ImportJob, error)
+	GetByID(id string) (*models.ImportJob, error)
+	GetPending(limit int) ([]models.ImportJob, error)
+	MarkProcessing(id string, totalGames, totalChunks int) error
+	RecordChunk(id string, result models.ImportChunkResult) error
+	RecordDedupProgress(id string, checkedGames int) error
+	MarkDone(id string) error
+	MarkFailed(id string, message string) error
+}
+
+// TeamImportRepository defines the interface for background imports of Lichess teams
+type TeamImportRepository interface {
+	Create(userID, teamID string, options models.LichessImportOptions, analysisDepth *int) (*models.TeamImport, error)
+	GetByID(id string) (*models.TeamImport, error)
+	ClaimPending(limit int) ([]models.TeamImport, error)
+	SetMembers(id string, members []string) error
+	RecordMember(id string, result models.TeamMemberImport) error
+	Defer(id string, until time.Time) error
+	MarkDone(id string) error
+	MarkFailed(id string, message string) error
+}
+
+// SyncRunRepository defines the interface for sync run history operations
+type SyncRunRepository interface {
+	Create(userID string) (*models.SyncRun, error)
+	Finish(run *models.SyncRun) error
+	GetByID(id string) (*models.SyncRun, error)
+	ListByUser(userID string, limit int) ([]models.SyncRun, error)
 }
 
 // DismissedMistakeRepository defines the interface for dismissed mistake operations
 type DismissedMistakeRepository interface {
 	Dismiss(userID, fen, playedMove string) error
 	GetDismissed(userID string) (map[string]bool, error)
+	List(userID string) ([]models.DismissedMistake, error)
+	Restore(userID, fen, playedMove string) error
 }
 
 // AnalysisRepository defines the interface for analysis data operations
 type AnalysisRepository interface {
-	Save(userID string, username, filename string, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error)
-	GetAll(userID string) ([]models.AnalysisSummary, error)
+	Save(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error)
+	GetAll(userID, source string) ([]models.AnalysisSummary, error)
 	GetByID(id string) (*models.AnalysisDetail, error)
+	GetGame(analysisID string, gameIndex int) (*models.GameAnalysis, error)
 	Delete(id string) error
-	GetAllGames(userID string, limit, offset int, timeClass, repertoire, source string) (*models.GamesResponse, error)
+	GetAllGames(userID string, limit, offset int, timeClass, repertoire, source, classification string, starred bool) (*models.GamesResponse, error)
+	UpdateGameNotes(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error)
 	DeleteGame(analysisID string, gameIndex int) error
-	UpdateResults(analysisID string, results []models.GameAnalysis) error
+	UpdateGame(analysisID string, game models.GameAnalysis) error
 	BelongsToUser(id string, userID string) (bool, error)
 	GetDistinctRepertoires(userID string) ([]string, error)
 	MarkGameViewed(userID, analysisID string, gameIndex int) error
 	GetViewedGames(userID string) (map[string]bool, error)
 	GetAllGamesRaw(userID string) ([]models.RawAnalysis, error)
+	GetGameLocationsByRepertoire(userID, repertoireID string) ([]GameLocation, error)
+	RecordSkippedDuplicates(userID, filename string, count int) error
+	GetImportStats(userID string) ([]models.ImportSourceStats, error)
+	GetBookDepth(userID, repertoireID string) ([]models.BookDepthPeriod, error)
+	GetMoveTimes(userID, repertoireID string, maxPly int) ([]models.PlyMoveTime, error)
+}
+
+// GoalRepository defines the interface for repertoire goal operations
+type GoalRepository interface {
+	Create(userID, repertoireID string, goalType models.GoalType, target float64, depth int) (*models.Goal, error)
+	GetByID(id string) (*models.Goal, error)
+	GetByUser(userID string) ([]models.Goal, error)
+	GetAll() ([]models.Goal, error)
+	CountByUser(userID string) (int, error)
+	UpdateProgress(id string, progress float64, achieved bool) error
+	MarkSummarySent(userID string, sentAt time.Time) error
+	Delete(id string) error
+	BelongsToUser(id, userID string) (bool, error)
+}
+
+// ImportSummaryRepository defines the interface for the summaries of import runs
+type ImportSummaryRepository interface {
+	Save(userID string, summary models.ImportSummary) error
+	GetByAnalysisID(analysisID, userID string) (*models.ImportSummary, error)
+}
+
+// UsageRepository defines the interface for the daily counters of external service calls
+type UsageRepository interface {
+	Increment(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error)
+	GetDay(userID string, day time.Time) (map[models.UsageKind]int, error)
+	Totals(day time.Time) ([]models.UsageTotal, error)
+}
+
+// NotificationRepository defines the interface for notification preferences and channels
+type NotificationRepository interface {
+	GetPreferences(userID string) ([]models.NotificationPreference, error)
+	SetPreferences(userID string, prefs []models.NotificationPreference) error
+	GetWebhook(userID string) (*models.NotificationWebhook, error)
+	SetWebhook(userID, url, secret string) (*models.NotificationWebhook, error)
+	DeleteWebhook(userID string) error
+	AddPushSubscription(userID string, sub models.PushSubscription) error
+	ListPushSubscriptions(userID string) ([]models.PushSubscription, error)
+	DeletePushSubscription(userID, endpoint string) error
+}
+
+// PrepRepository defines the interface for queued node preparations and their suggestions
+type PrepRepository interface {
+	Create(userID, repertoireID, nodeID, fen string, depth int) (*models.PrepRequest, error)
+	GetByID(id string) (*models.PrepRequest, error)
+	ListByNode(repertoireID, nodeID string) ([]models.PrepRequest, error)
+	ClaimPending(limit int) ([]models.PrepRequest, error)
+	Complete(id string, suggestions []models.PrepSuggestion) error
+	MarkFailed(id, message string) error
+	RemoveSuggestion(id, suggestionID string) error
+}
+
+// TrainingRepository defines the interface for training session and activity operations
+type TrainingRepository interface {
+	// RecordAnswer adds an answer to the user's session on the repertoire, starting a new session
+	// when the last answer is older than the session gap, and to the day's totals
+	RecordAnswer(userID, repertoireID string, correct bool, at time.Time, sessionGap time.Duration) (*models.TrainingSession, error)
+	GetDays(userID string) ([]models.TrainingDay, error)
+	// GetRecall sums the user's answers on the repertoire since the given time
+	GetRecall(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error)
+}
+
+// HealthRepository defines the interface for repertoire health scores
+type HealthRepository interface {
+	ListStale(before time.Time, limit int) ([]RepertoireOwner, error)
+	Save(repertoireID string, health models.RepertoireHealth) error
+	GetByUser(userID string) (map[string]models.RepertoireHealth, error)
+}
+
+// InsightSettingsRepository defines the interface for per-user insight thresholds
+type InsightSettingsRepository interface {
+	Get(userID string) (*models.InsightSettings, error)
+	Save(userID string, settings models.InsightSettings) error
+}
+
+// ClassificationRuleRepository defines the interface for user-defined move classification rules
+type ClassificationRuleRepository interface {
+	Create(userID string, rule models.ClassificationRule) (*models.ClassificationRule, error)
+	GetByUser(userID string) ([]models.ClassificationRule, error)
+	CountByUser(userID string) (int, error)
+	Update(id string, rule models.ClassificationRule) (*models.ClassificationRule, error)
+	Delete(id string) error
+	BelongsToUser(id, userID string) (bool, error)
+}
+
+// SessionRepository defines the interface for login session operations
+type SessionRepository interface {
+	Create(userID string, device models.SessionDevice, expiresAt time.Time) (*models.Session, error)
+	Touch(id string, seenAt time.Time) error
+	ListActive(userID string, now time.Time) ([]models.Session, error)
+	Revoke(id, userID string, revokedAt time.Time) error
+}
+
+// APITokenRepository defines the interface for personal API token operations
+type APITokenRepository interface {
+	Create(userID, name, scope, prefix, tokenHash string) (*models.APIToken, error)
+	Use(tokenHash string, usedAt time.Time) (*models.APIToken, error)
+	ListByUser(userID string) ([]models.APIToken, error)
+	CountByUser(userID string) (int, error)
+	Revoke(id, userID string, revokedAt time.Time) error
 }
 
 // PasswordResetRepository defines the interface for password reset token operations
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListUserIDs returns the ID of every user, for maintenance commands that process all accounts
+func (db *DB) ListUserIDs(ctx context.Context) ([]string, error) {
+	return db.listIDs(ctx, `SELECT id FROM users ORDER BY created_at`)
+}
+
+// ListRepertoireIDs returns the ID of every repertoire of every user
+func (db *DB) ListRepertoireIDs(ctx context.Context) ([]string, error) {
+	return db.listIDs(ctx, `SELECT id FROM repertoires ORDER BY created_at`)
+}
+
+func (db *DB) listIDs(ctx context.Context, query string) ([]string, error) {
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ids: %w", err)
+	}
+	return ids, nil
+}
+
+// RequeueFailedEvals puts failed engine evaluations back in the queue and returns how many were requeued
+func (db *DB) RequeueFailedEvals(ctx context.Context) (int64, error) {
+	tag, err := db.Pool.Exec(ctx, `UPDATE engine_evals SET status = 'pending', updated_at = NOW() WHERE status = 'failed'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue evals: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,355 @@
+-- Baseline schema: everything the server created before versioned migrations existed.
+-- Every statement is idempotent, so databases created by older releases are adopted as-is.
+
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username VARCHAR(50) NOT NULL UNIQUE,
+    password_hash VARCHAR(255),
+    oauth_provider VARCHAR(20),
+    oauth_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(oauth_provider, oauth_id)
+);
+
+-- Create repertoires table
+CREATE TABLE IF NOT EXISTS repertoires (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL DEFAULT 'Main Repertoire',
+    color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
+    tree_data JSONB NOT NULL,
+    metadata JSONB NOT NULL DEFAULT '{"totalNodes": 0, "totalMoves": 0, "deepestDepth": 0}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create analyses table
+CREATE TABLE IF NOT EXISTS analyses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    username VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    game_count INTEGER NOT NULL,
+    uploaded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_repertoires_user_id ON repertoires(user_id);
+CREATE INDEX IF NOT EXISTS idx_repertoires_color ON repertoires(color);
+CREATE INDEX IF NOT EXISTS idx_repertoires_updated ON repertoires(updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_repertoires_name ON repertoires(name);
+CREATE INDEX IF NOT EXISTS idx_analyses_user_id ON analyses(user_id);
+CREATE INDEX IF NOT EXISTS idx_analyses_username ON analyses(username);
+CREATE INDEX IF NOT EXISTS idx_analyses_uploaded ON analyses(uploaded_at DESC);
+-- Create function to enforce max 50 repertoires per user
+CREATE OR REPLACE FUNCTION check_repertoire_limit()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT COUNT(*) FROM repertoires WHERE user_id = NEW.user_id) >= 50 THEN
+        RAISE EXCEPTION 'Maximum of 50 repertoires allowed';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Drop trigger if it exists (for idempotency)
+DROP TRIGGER IF EXISTS repertoire_limit_trigger ON repertoires;
+
+-- Create trigger to enforce limit
+CREATE TRIGGER repertoire_limit_trigger
+    BEFORE INSERT ON repertoires
+    FOR EACH ROW EXECUTE FUNCTION check_repertoire_limit();
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_lichess_sync_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_chesscom_sync_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lichess_access_token TEXT;
+
+CREATE TABLE IF NOT EXISTS game_fingerprints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    fingerprint VARCHAR(512) NOT NULL,
+    analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    game_index INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, fingerprint)
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_fingerprints_user ON game_fingerprints(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_game_fingerprints_analysis ON game_fingerprints(analysis_id);
+
+CREATE TABLE IF NOT EXISTS viewed_games (
+    user_id UUID NOT NULL REFERENCES users(id),
+    analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    game_index INTEGER NOT NULL,
+    viewed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, analysis_id, game_index)
+);
+
+CREATE TABLE IF NOT EXISTS engine_evals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    game_index INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    evals JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(analysis_id, game_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_engine_evals_user ON engine_evals(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_engine_evals_status ON engine_evals(status);
+
+CREATE TABLE IF NOT EXISTS dismissed_mistakes (
+    user_id UUID NOT NULL REFERENCES users(id),
+    fen TEXT NOT NULL,
+    played_move VARCHAR(10) NOT NULL,
+    dismissed_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, fen, played_move)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dismissed_mistakes_user ON dismissed_mistakes(user_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS time_format_prefs TEXT[] DEFAULT '{}';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_hash ON password_reset_tokens(token_hash);
+
+-- Categories table for grouping repertoires
+CREATE TABLE IF NOT EXISTS categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, name, color)
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_categories_color ON categories(color);
+
+-- Add category_id to repertoires with cascade delete
+ALTER TABLE repertoires ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_repertoires_category ON repertoires(category_id);
+
+-- Normalized per-game storage replacing the analyses.results JSON array
+CREATE TABLE IF NOT EXISTS games (
+    analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    game_index INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id),
+    headers JSONB NOT NULL DEFAULT '{}',
+    moves JSONB NOT NULL DEFAULT '[]',
+    user_color VARCHAR(5) NOT NULL,
+    repertoire_id UUID,
+    repertoire_name VARCHAR(100),
+    match_score INTEGER NOT NULL DEFAULT 0,
+    time_class VARCHAR(10),
+    status VARCHAR(10),
+    PRIMARY KEY (analysis_id, game_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_games_user ON games(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_games_user_color ON games(user_id, user_color);
+
+CREATE INDEX IF NOT EXISTS idx_games_user_repertoire ON games(user_id, repertoire_name);
+
+CREATE INDEX IF NOT EXISTS idx_games_user_time_class ON games(user_id, time_class);
+
+CREATE INDEX IF NOT EXISTS idx_games_headers ON games USING GIN (headers);
+
+-- Move existing results blobs into the games table, then drop the column
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'analyses' AND column_name = 'results') THEN
+        INSERT INTO games (analysis_id, game_index, user_id, headers, moves, user_color, repertoire_id, repertoire_name, match_score)
+        SELECT a.id,
+            (g->>'gameIndex')::int,
+            a.user_id,
+            COALESCE(g->'headers', '{}'),
+            COALESCE(g->'moves', '[]'),
+            g->>'userColor',
+            NULLIF(g->'matchedRepertoire'->>'id', '')::uuid,
+            g->'matchedRepertoire'->>'name',
+            COALESCE((g->>'matchScore')::int, 0)
+        FROM analyses a, jsonb_array_elements(a.results) g
+        WHERE jsonb_typeof(a.results) = 'array'
+        ON CONFLICT DO NOTHING;
+        ALTER TABLE analyses DROP COLUMN results;
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    goal_type VARCHAR(30) NOT NULL,
+    target DOUBLE PRECISION NOT NULL,
+    depth INTEGER NOT NULL DEFAULT 0,
+    progress DOUBLE PRECISION NOT NULL DEFAULT 0,
+    achieved BOOLEAN NOT NULL DEFAULT FALSE,
+    progress_updated_at TIMESTAMPTZ,
+    last_summary_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user ON goals(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_goals_repertoire ON goals(repertoire_id);
+
+-- Multiple Lichess/Chess.com accounts per user, replacing users.lichess_username/chesscom_username
+CREATE TABLE IF NOT EXISTS linked_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    last_sync_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_linked_accounts_unique ON linked_accounts(user_id, provider, (LOWER(username)));
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'lichess_username') THEN
+        INSERT INTO linked_accounts (user_id, provider, username, last_sync_at)
+        SELECT id, 'lichess', lichess_username, last_lichess_sync_at FROM users
+        WHERE lichess_username IS NOT NULL AND lichess_username <> ''
+        ON CONFLICT DO NOTHING;
+        INSERT INTO linked_accounts (user_id, provider, username, last_sync_at)
+        SELECT id, 'chesscom', chesscom_username, last_chesscom_sync_at FROM users
+        WHERE chesscom_username IS NOT NULL AND chesscom_username <> ''
+        ON CONFLICT DO NOTHING;
+        ALTER TABLE users DROP COLUMN lichess_username;
+        ALTER TABLE users DROP COLUMN chesscom_username;
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS reanalysis_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reanalysis_jobs_user ON reanalysis_jobs(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_reanalysis_jobs_status ON reanalysis_jobs(status);
+
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    accounts JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    started_at TIMESTAMPTZ DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_user ON sync_runs(user_id, started_at DESC);
+
+CREATE TABLE IF NOT EXISTS repertoire_positions (
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    node_id VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id),
+    normalized_fen TEXT NOT NULL,
+    PRIMARY KEY (repertoire_id, node_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_positions_user_fen ON repertoire_positions(user_id, normalized_fen);
+
+CREATE TABLE IF NOT EXISTS repertoire_templates (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
+    description TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    featured BOOLEAN NOT NULL DEFAULT FALSE,
+    tree_data JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_templates_user ON repertoire_templates(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_templates_featured ON repertoire_templates(featured) WHERE featured;
+
+CREATE INDEX IF NOT EXISTS idx_engine_evals_updated ON engine_evals(updated_at);
+
+ALTER TABLE repertoires ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS repertoire_collaborators (
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (repertoire_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_collaborators_user ON repertoire_collaborators(user_id);
+
+-- One row per repertoire node a game passed through, removed with the game
+CREATE TABLE IF NOT EXISTS game_node_results (
+    analysis_id UUID NOT NULL,
+    game_index INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    node_id VARCHAR(64) NOT NULL,
+    outcome VARCHAR(4) NOT NULL CHECK (outcome IN ('win', 'draw', 'loss')),
+    PRIMARY KEY (analysis_id, game_index, node_id),
+    FOREIGN KEY (analysis_id, game_index) REFERENCES games(analysis_id, game_index) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_node_results_repertoire ON game_node_results(repertoire_id, user_id);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    duplicate_policy VARCHAR(10) NOT NULL DEFAULT 'skip',
+    file_path TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total_games INTEGER NOT NULL DEFAULT 0,
+    total_chunks INTEGER NOT NULL DEFAULT 0,
+    processed_chunks INTEGER NOT NULL DEFAULT 0,
+    imported_games INTEGER NOT NULL DEFAULT 0,
+    skipped_games INTEGER NOT NULL DEFAULT 0,
+    failed_games JSONB NOT NULL DEFAULT '[]',
+    analysis_ids JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user ON import_jobs(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs(status);
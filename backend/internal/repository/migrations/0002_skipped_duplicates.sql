@@ -0,0 +1,11 @@
+-- Duplicate games skipped by each import, kept for the import statistics.
+-- Imports whose games were all duplicates store no analysis, so skips are recorded separately.
+CREATE TABLE IF NOT EXISTS skipped_duplicates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    game_count INTEGER NOT NULL,
+    skipped_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_skipped_duplicates_user ON skipped_duplicates(user_id);
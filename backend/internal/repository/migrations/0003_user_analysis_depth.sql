@@ -0,0 +1,2 @@
+-- Number of moves of each game matched against the repertoire on import; 0 analyzes whole games
+ALTER TABLE users ADD COLUMN IF NOT EXISTS analysis_depth INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Weekly email digest of new mistakes and unreviewed games, sent to users who opt in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS weekly_digest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Workers lease the evals they process, so several backend instances can share the queue.
+-- A lease that is not renewed expires and the eval goes back to whichever worker claims it next.
+ALTER TABLE engine_evals ADD COLUMN IF NOT EXISTS lease_owner VARCHAR(100);
+ALTER TABLE engine_evals ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_engine_evals_claimable ON engine_evals(created_at)
+    WHERE status IN ('pending', 'processing');
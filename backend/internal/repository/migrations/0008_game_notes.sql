@@ -0,0 +1,5 @@
+-- Users annotate games and star the ones they want to study later
+ALTER TABLE games ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+ALTER TABLE games ADD COLUMN IF NOT EXISTS starred BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_games_starred ON games(user_id) WHERE starred;
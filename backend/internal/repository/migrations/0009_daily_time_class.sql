@@ -0,0 +1,3 @@
+-- Chess.com daily games have a "1/86400" (seconds per move) TimeControl and were stored as bullet
+UPDATE games SET time_class = 'daily'
+WHERE headers->>'TimeControl' LIKE '%/%' AND time_class IS DISTINCT FROM 'daily';
@@ -0,0 +1,21 @@
+-- Training answers are grouped in sessions: answers on a repertoire without a long pause
+CREATE TABLE IF NOT EXISTS training_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    last_answer_at TIMESTAMPTZ NOT NULL,
+    reviews INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_sessions_user ON training_sessions(user_id, repertoire_id, last_answer_at DESC);
+
+-- Daily totals of training answers, for the activity calendar and streaks
+CREATE TABLE IF NOT EXISTS training_activity (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    reviews INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
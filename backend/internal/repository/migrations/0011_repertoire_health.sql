@@ -0,0 +1,13 @@
+-- Repertoire health score, recomputed daily from coverage, game results, training recall and staleness.
+-- Components without data (no games, no training) are NULL and left out of the score.
+CREATE TABLE IF NOT EXISTS repertoire_health (
+    repertoire_id UUID PRIMARY KEY REFERENCES repertoires(id) ON DELETE CASCADE,
+    score INTEGER NOT NULL,
+    coverage DOUBLE PRECISION,
+    results DOUBLE PRECISION,
+    recall DOUBLE PRECISION,
+    freshness DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_health_computed ON repertoire_health(computed_at);
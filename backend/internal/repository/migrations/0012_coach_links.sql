@@ -0,0 +1,15 @@
+-- Coach-student links: once the invitee accepts, the coach may read the student's games,
+-- insights and repertoires. Either side can invite; either side can revoke.
+CREATE TABLE IF NOT EXISTS coach_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    coach_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    student_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    accepted_at TIMESTAMPTZ,
+    UNIQUE (coach_id, student_id),
+    CHECK (coach_id <> student_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_links_student ON coach_links(student_id);
@@ -0,0 +1,10 @@
+-- Per-user thresholds deciding which moves the insights report as mistakes.
+-- Users without a row get the defaults from config.
+CREATE TABLE IF NOT EXISTS insight_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    min_drop DOUBLE PRECISION NOT NULL,
+    min_ply INTEGER NOT NULL,
+    min_frequency INTEGER NOT NULL,
+    max_mistakes INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,31 @@
+-- Full-text index of node comments, rebuilt by the repertoire repository whenever a tree is saved.
+-- The 'simple' configuration is used because comments are written in any language.
+CREATE TABLE IF NOT EXISTS repertoire_comments (
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    node_id VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    move_path TEXT[] NOT NULL,
+    comment TEXT NOT NULL,
+    search TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', comment)) STORED,
+    PRIMARY KEY (repertoire_id, node_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_comments_search ON repertoire_comments USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_repertoire_comments_user ON repertoire_comments(user_id);
+CREATE INDEX IF NOT EXISTS idx_repertoires_name_search ON repertoires USING GIN (to_tsvector('simple', name));
+
+-- Index the comments of the trees saved before this migration
+WITH RECURSIVE nodes AS (
+    SELECT r.id AS repertoire_id, r.user_id, r.tree_data AS node, ARRAY[]::TEXT[] AS move_path
+    FROM repertoires r
+    UNION ALL
+    SELECT n.repertoire_id, n.user_id, child, n.move_path || (child->>'move')
+    FROM nodes n,
+        jsonb_array_elements(CASE WHEN jsonb_typeof(n.node->'children') = 'array' THEN n.node->'children' ELSE '[]'::jsonb END) AS child
+    WHERE jsonb_typeof(child) = 'object'
+)
+INSERT INTO repertoire_comments (repertoire_id, node_id, user_id, move_path, comment)
+SELECT repertoire_id, node->>'id', user_id, move_path, node->>'comment'
+FROM nodes
+WHERE COALESCE(node->>'comment', '') <> '' AND node->>'id' IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,24 @@
+-- Where the games of an analysis came from and how the import was requested, so it can be
+-- filtered on and run again. Older analyses get the source their filename prefix implies.
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS source VARCHAR(20);
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS source_params JSONB NOT NULL DEFAULT '{}';
+
+UPDATE analyses SET
+    source = CASE
+        WHEN filename LIKE 'sync\_lichess\_%' OR filename LIKE 'lichess\_%' THEN 'lichess'
+        WHEN filename LIKE 'sync\_chesscom\_%' OR filename LIKE 'chesscom\_%' THEN 'chesscom'
+        WHEN filename LIKE 'broadcast\_%' THEN 'broadcast'
+        ELSE 'pgn'
+    END,
+    source_params = CASE
+        WHEN filename LIKE 'broadcast\_%' THEN jsonb_build_object('roundId', substring(filename FROM 'broadcast_(.*)\.pgn$'))
+        WHEN filename LIKE 'sync\_%' THEN jsonb_build_object('username', username, 'sync', true)
+        WHEN filename LIKE 'lichess\_%' OR filename LIKE 'chesscom\_%' THEN jsonb_build_object('username', username)
+        ELSE jsonb_build_object('username', username, 'filename', filename)
+    END
+WHERE source IS NULL;
+
+ALTER TABLE analyses ALTER COLUMN source SET DEFAULT 'pgn';
+ALTER TABLE analyses ALTER COLUMN source SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_analyses_user_source ON analyses(user_id, source);
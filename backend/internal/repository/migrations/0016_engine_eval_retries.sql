@@ -0,0 +1,7 @@
+-- Failed evals are retried with exponential backoff until they run out of attempts.
+-- A pending eval is not claimable before next_attempt_at; only exhausted evals stay failed.
+ALTER TABLE engine_evals ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE engine_evals ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_engine_evals_failed ON engine_evals(analysis_id)
+    WHERE status = 'failed';
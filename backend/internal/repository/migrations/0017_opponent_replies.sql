@@ -0,0 +1,33 @@
+-- The moves opponents played against the user in their imported games, one row per opponent move
+-- within the opening plies, so replies can be aggregated per position at the user's level.
+CREATE TABLE IF NOT EXISTS opponent_replies (
+    analysis_id UUID NOT NULL,
+    game_index INTEGER NOT NULL,
+    ply INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fen VARCHAR(100) NOT NULL,
+    move VARCHAR(10) NOT NULL,
+    opponent_rating INTEGER,
+    outcome VARCHAR(4) CHECK (outcome IN ('win', 'draw', 'loss')),
+    PRIMARY KEY (analysis_id, game_index, ply),
+    FOREIGN KEY (analysis_id, game_index) REFERENCES games(analysis_id, game_index) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_opponent_replies_position ON opponent_replies(user_id, fen);
+
+-- Backfill from the games already imported; broadcast games are reference material, not the user's
+INSERT INTO opponent_replies (analysis_id, game_index, ply, user_id, fen, move, opponent_rating, outcome)
+SELECT g.analysis_id, g.game_index, (m->>'plyNumber')::int, g.user_id, m->>'fen', m->>'san',
+    NULLIF(substring(g.headers->>(CASE WHEN g.user_color = 'white' THEN 'BlackElo' ELSE 'WhiteElo' END) FROM '^[0-9]{1,4}$'), '')::int,
+    CASE
+        WHEN g.headers->>'Result' = '1/2-1/2' THEN 'draw'
+        WHEN (g.headers->>'Result' = '1-0') = (g.user_color = 'white') AND g.headers->>'Result' IN ('1-0', '0-1') THEN 'win'
+        WHEN g.headers->>'Result' IN ('1-0', '0-1') THEN 'loss'
+    END
+FROM games g
+JOIN analyses a ON a.id = g.analysis_id
+CROSS JOIN LATERAL jsonb_array_elements(g.moves) m
+WHERE a.source <> 'broadcast'
+  AND NOT COALESCE((m->>'isUserMove')::boolean, false)
+  AND (m->>'plyNumber')::int < 30
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+-- Prep requests: deep Explorer expansion of one repertoire node, run by a background worker.
+-- The candidate lines it finds stay detached in suggestions until the user accepts or discards them.
+CREATE TABLE IF NOT EXISTS prep_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    node_id TEXT NOT NULL,
+    fen TEXT NOT NULL,
+    depth INTEGER NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    suggestions JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_prep_requests_node ON prep_requests(repertoire_id, node_id);
+CREATE INDEX IF NOT EXISTS idx_prep_requests_pending ON prep_requests(created_at) WHERE status = 'pending';
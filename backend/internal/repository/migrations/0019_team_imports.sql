@@ -0,0 +1,19 @@
+-- Team imports: the recent games of every member of a Lichess team, imported in the background
+-- as one reference analysis per member. A rate-limited import waits until next_attempt_at and
+-- resumes after the members already in results.
+CREATE TABLE IF NOT EXISTS team_imports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    team_id VARCHAR(100) NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}',
+    analysis_depth INTEGER,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'done', 'failed')),
+    members JSONB NOT NULL DEFAULT '[]',
+    results JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_imports_pending ON team_imports(created_at) WHERE status = 'pending';
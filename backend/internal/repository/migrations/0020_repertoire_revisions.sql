@@ -0,0 +1,14 @@
+-- Repertoire revisions: the tree as it was after each save, so edits can be compared and undone.
+-- Existing repertoires start their history with their current version.
+CREATE TABLE IF NOT EXISTS repertoire_revisions (
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    tree_data JSONB NOT NULL,
+    metadata JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (repertoire_id, version)
+);
+
+INSERT INTO repertoire_revisions (repertoire_id, version, tree_data, metadata, created_at)
+SELECT id, version, tree_data, metadata, updated_at FROM repertoires
+ON CONFLICT DO NOTHING;
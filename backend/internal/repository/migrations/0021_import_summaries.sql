@@ -0,0 +1,10 @@
+-- Import summaries: what an import run found besides the games it kept, so it can be audited later.
+-- One row per analysis; warnings lists the games that were skipped or need attention.
+CREATE TABLE IF NOT EXISTS import_summaries (
+    analysis_id UUID PRIMARY KEY REFERENCES analyses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    games_found INTEGER NOT NULL,
+    games_imported INTEGER NOT NULL,
+    warnings JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+-- Study notes: a markdown document per repertoire for plans and model games that do not fit on one node.
+-- Every save adds a version; the newest one is the current document.
+CREATE TABLE IF NOT EXISTS repertoire_notes (
+    repertoire_id UUID NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (repertoire_id, version)
+);
@@ -0,0 +1,3 @@
+-- Opening labels: the version of the ECO labeling last applied to the tree of a repertoire.
+-- The labeling worker relabels repertoires below the current version.
+ALTER TABLE repertoires ADD COLUMN IF NOT EXISTS opening_labels_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Usage counters: calls to external services per user and UTC day, checked against daily quotas
+CREATE TABLE IF NOT EXISTS usage_counters (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_counters_day ON usage_counters(day);
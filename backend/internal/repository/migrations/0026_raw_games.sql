@@ -0,0 +1,38 @@
+-- Archive of the PGN of every imported game, keyed by its fingerprint. Rows are only ever
+-- inserted: analyses are derived from it and can be recomputed without re-importing.
+CREATE TABLE IF NOT EXISTS raw_games (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL,
+    pgn TEXT NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT '',
+    imported_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, fingerprint)
+);
+
+CREATE OR REPLACE FUNCTION reject_raw_game_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'raw_games is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS raw_games_append_only_trigger ON raw_games;
+
+CREATE TRIGGER raw_games_append_only_trigger
+    BEFORE UPDATE ON raw_games
+    FOR EACH ROW EXECUTE FUNCTION reject_raw_game_update();
+
+-- Recompute jobs re-derive the stored analyses of one user, or of every user, from raw_games
+CREATE TABLE IF NOT EXISTS recompute_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    recomputed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recompute_jobs_status ON recompute_jobs(status);
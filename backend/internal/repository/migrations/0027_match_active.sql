@@ -0,0 +1,4 @@
+-- Repertoires and categories can be left out of game matching, e.g. a second system the user
+-- is still building. A repertoire is matched when both it and its category, if any, are active.
+ALTER TABLE repertoires ADD COLUMN IF NOT EXISTS match_active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS match_active BOOLEAN NOT NULL DEFAULT TRUE;
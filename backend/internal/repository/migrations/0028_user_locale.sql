@@ -0,0 +1,4 @@
+-- Time zone (IANA name) and locale (BCP 47 tag) of each user. Training days, digests and date
+-- filters follow the user's day instead of the server's UTC one.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en';
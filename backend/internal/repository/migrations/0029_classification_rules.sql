@@ -0,0 +1,19 @@
+-- User-defined classifications of analyzed moves, applied on top of the fixed statuses.
+-- A move matches a rule when it meets every condition that is set (non-NULL).
+CREATE TABLE IF NOT EXISTS classification_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(40) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    move_status VARCHAR(20) NOT NULL DEFAULT '',
+    mover VARCHAR(10) NOT NULL DEFAULT '',
+    min_winrate_drop DOUBLE PRECISION,
+    min_time_spent DOUBLE PRECISION,
+    max_ply INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, key)
+);
+
+-- Keys of the rules matched by a move of the game, derived from the moves for the games filter
+ALTER TABLE games ADD COLUMN IF NOT EXISTS classifications TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_games_classifications ON games USING GIN (classifications);
@@ -0,0 +1,4 @@
+-- Version and tree hash of the matched repertoire when the game was analyzed, so a match result
+-- can be explained against the tree as it was. NULL for games analyzed before this migration.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS repertoire_version INTEGER;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS repertoire_tree_hash VARCHAR(71);
@@ -0,0 +1,3 @@
+-- Archived repertoires are kept read-only, out of game matching and health refreshes, and hidden
+-- from default lists, e.g. the prep of a finished season
+ALTER TABLE repertoires ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- Import tokens may read data and import or sync games, e.g. for a nightly sync script
+ALTER TABLE api_tokens DROP CONSTRAINT IF EXISTS api_tokens_scope_check;
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_scope_check CHECK (scope IN ('read', 'import', 'write'));
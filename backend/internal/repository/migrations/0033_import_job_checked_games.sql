@@ -0,0 +1,2 @@
+-- Background imports report how many games were checked for duplicates, the slow stage of large imports
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS checked_games INT NOT NULL DEFAULT 0;
@@ -12,6 +12,9 @@ import (
 // MockEmailService implements services.EmailSender for testing
 type MockEmailService struct {
 	SendPasswordResetEmailFunc func(toEmail, token string) error
+	SendGoalSummaryEmailFunc   func(toEmail string, goals []models.Goal) error
+	SendWeeklyDigestEmailFunc  func(toEmail string, digest models.WeeklyDigest) error
+	SendNotificationEmailFunc  func(toEmail string, n models.Notification) error
 	EnabledFunc                func() bool
 }
 
@@ -22,6 +25,27 @@ func (m *MockEmailService) SendPasswordResetEmail(toEmail, token string) error {
 	return nil
 }
 
+func (m *MockEmailService) SendGoalSummaryEmail(toEmail string, goals []models.Goal) error {
+	if m.SendGoalSummaryEmailFunc != nil {
+		return m.SendGoalSummaryEmailFunc(toEmail, goals)
+	}
+	return nil
+}
+
+func (m *MockEmailService) SendWeeklyDigestEmail(toEmail string, digest models.WeeklyDigest) error {
+	if m.SendWeeklyDigestEmailFunc != nil {
+		return m.SendWeeklyDigestEmailFunc(toEmail, digest)
+	}
+	return nil
+}
+
+func (m *MockEmailService) SendNotificationEmail(toEmail string, n models.Notification) error {
+	if m.SendNotificationEmailFunc != nil {
+		return m.SendNotificationEmailFunc(toEmail, n)
+	}
+	return nil
+}
+
 func (m *MockEmailService) Enabled() bool {
 	if m.EnabledFunc != nil {
 		return m.EnabledFunc()
@@ -80,6 +104,8 @@ type MockRepertoireService struct {
 	CreateRepertoireFunc             func(userID, name string, color models.Color) (*models.Repertoire, error)
 	CreateRepertoireWithCategoryFunc func(userID, name string, color models.Color, categoryID *string) (*models.Repertoire, error)
 	SaveTreeFunc                     func(repertoireID string, treeData models.RepertoireNode) (*models.Repertoire, error)
+	GetRepertoireFunc                func(id string) (*models.Repertoire, error)
+	CheckOwnershipFunc               func(id string, userID string) error
 }
 
 func (m *MockRepertoireService) CreateRepertoire(userID, name string, color models.Color) (*models.Repertoire, error) {
@@ -107,20 +133,34 @@ func (m *MockRepertoireService) SaveTree(repertoireID string, treeData models.Re
 	return nil, nil
 }
 
+func (m *MockRepertoireService) GetRepertoire(id string) (*models.Repertoire, error) {
+	if m.GetRepertoireFunc != nil {
+		return m.GetRepertoireFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *MockRepertoireService) CheckOwnership(id string, userID string) error {
+	if m.CheckOwnershipFunc != nil {
+		return m.CheckOwnershipFunc(id, userID)
+	}
+	return nil
+}
+
 // --- Repository mocks ---
 
 // MockFingerprintRepo is a mock implementation of GameFingerprintRepository for testing
 type MockFingerprintRepo struct {
-	CheckExistingFunc           func(userID string, fingerprints []string) (map[string]bool, error)
+	CheckExistingFunc           func(userID string, fingerprints []string) (map[string]repository.GameLocation, error)
 	SaveBatchFunc               func(userID, analysisID string, entries []repository.FingerprintEntry) error
 	DeleteByAnalysisAndIndexFunc func(analysisID string, gameIndex int) error
 }
 
-func (m *MockFingerprintRepo) CheckExisting(userID string, fingerprints []string) (map[string]bool, error) {
+func (m *MockFingerprintRepo) CheckExisting(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
 	if m.CheckExistingFunc != nil {
 		return m.CheckExistingFunc(userID, fingerprints)
 	}
-	return map[string]bool{}, nil
+	return map[string]repository.GameLocation{}, nil
 }
 
 func (m *MockFingerprintRepo) SaveBatch(userID, analysisID string, entries []repository.FingerprintEntry) error {
@@ -137,14 +177,67 @@ func (m *MockFingerprintRepo) DeleteByAnalysisAndIndex(analysisID string, gameIn
 	return nil
 }
 
+// MockGameResultRepo is a mock implementation of GameResultRepository for testing
+type MockGameResultRepo struct {
+	SaveBatchFunc   func(analysisID string, entries []repository.GameResultEntry) error
+	ReplaceGameFunc func(analysisID string, gameIndex int, entries []repository.GameResultEntry) error
+	OverlayFunc     func(repertoireID, userID string) ([]models.NodeResult, error)
+}
+
+func (m *MockGameResultRepo) SaveBatch(analysisID string, entries []repository.GameResultEntry) error {
+	if m.SaveBatchFunc != nil {
+		return m.SaveBatchFunc(analysisID, entries)
+	}
+	return nil
+}
+
+func (m *MockGameResultRepo) ReplaceGame(analysisID string, gameIndex int, entries []repository.GameResultEntry) error {
+	if m.ReplaceGameFunc != nil {
+		return m.ReplaceGameFunc(analysisID, gameIndex, entries)
+	}
+	return nil
+}
+
+func (m *MockGameResultRepo) Overlay(repertoireID, userID string) ([]models.NodeResult, error) {
+	if m.OverlayFunc != nil {
+		return m.OverlayFunc(repertoireID, userID)
+	}
+	return []models.NodeResult{}, nil
+}
+
+// MockOpponentReplyRepo is a mock implementation of OpponentReplyRepository for testing
+type MockOpponentReplyRepo struct {
+	SaveBatchFunc func(analysisID string, entries []repository.OpponentReplyEntry) error
+	AggregateFunc func(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error)
+}
+
+func (m *MockOpponentReplyRepo) SaveBatch(analysisID string, entries []repository.OpponentReplyEntry) error {
+	if m.SaveBatchFunc != nil {
+		return m.SaveBatchFunc(analysisID, entries)
+	}
+	return nil
+}
+
+func (m *MockOpponentReplyRepo) Aggregate(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error) {
+	if m.AggregateFunc != nil {
+		return m.AggregateFunc(userID, fen, minRating, maxRating)
+	}
+	return []models.OpponentReply{}, nil
+}
+
 // MockEngineEvalRepo is a mock implementation of EngineEvalRepository for testing
 type MockEngineEvalRepo struct {
-	CreatePendingBatchFunc func(userID, analysisID string, gameCount int) error
-	GetPendingFunc         func(limit int) ([]models.EngineEval, error)
-	MarkProcessingFunc     func(id string) error
-	SaveEvalsFunc          func(id string, evals []models.ExplorerMoveStats) error
-	MarkFailedFunc         func(id string) error
-	GetByUserFunc          func(userID string) ([]models.EngineEval, error)
+	CreatePendingBatchFunc    func(userID, analysisID string, gameCount int) error
+	ClaimPendingFunc          func(workerID string, limit int, lease time.Duration) ([]models.EngineEval, error)
+	ExtendLeaseFunc           func(workerID string, ids []string, lease time.Duration) (int64, error)
+	SaveEvalsFunc             func(id, workerID string, evals []models.ExplorerMoveStats) error
+	MarkFailedFunc            func(id, workerID string, maxAttempts int, baseDelay, maxDelay time.Duration) (bool, error)
+	RetryFailedFunc           func(analysisID string) (int64, error)
+	CountUnfinishedFunc       func(analysisID string) (int, error)
+	GetByUserFunc             func(userID string) ([]models.EngineEval, error)
+	DeleteOlderThanFunc       func(before time.Time) (int64, error)
+	DeleteByUserOlderThanFunc func(userID string, before time.Time) (int64, error)
+	ResetAnalysisFunc         func(userID, analysisID string, gameIndices []int) error
 }
 
 func (m *MockEngineEvalRepo) CreatePendingBatch(userID, analysisID string, gameCount int) error {
@@ -154,32 +247,46 @@ func (m *MockEngineEvalRepo) CreatePendingBatch(userID, analysisID string, gameC
 	return nil
 }
 
-func (m *MockEngineEvalRepo) GetPending(limit int) ([]models.EngineEval, error) {
-	if m.GetPendingFunc != nil {
-		return m.GetPendingFunc(limit)
+func (m *MockEngineEvalRepo) ClaimPending(workerID string, limit int, lease time.Duration) ([]models.EngineEval, error) {
+	if m.ClaimPendingFunc != nil {
+		return m.ClaimPendingFunc(workerID, limit, lease)
 	}
 	return nil, nil
 }
 
-func (m *MockEngineEvalRepo) MarkProcessing(id string) error {
-	if m.MarkProcessingFunc != nil {
-		return m.MarkProcessingFunc(id)
+func (m *MockEngineEvalRepo) ExtendLease(workerID string, ids []string, lease time.Duration) (int64, error) {
+	if m.ExtendLeaseFunc != nil {
+		return m.ExtendLeaseFunc(workerID, ids, lease)
 	}
-	return nil
+	return int64(len(ids)), nil
 }
 
-func (m *MockEngineEvalRepo) SaveEvals(id string, evals []models.ExplorerMoveStats) error {
+func (m *MockEngineEvalRepo) SaveEvals(id, workerID string, evals []models.ExplorerMoveStats) error {
 	if m.SaveEvalsFunc != nil {
-		return m.SaveEvalsFunc(id, evals)
+		return m.SaveEvalsFunc(id, workerID, evals)
 	}
 	return nil
 }
 
-func (m *MockEngineEvalRepo) MarkFailed(id string) error {
+func (m *MockEngineEvalRepo) MarkFailed(id, workerID string, maxAttempts int, baseDelay, maxDelay time.Duration) (bool, error) {
 	if m.MarkFailedFunc != nil {
-		return m.MarkFailedFunc(id)
+		return m.MarkFailedFunc(id, workerID, maxAttempts, baseDelay, maxDelay)
 	}
-	return nil
+	return false, nil
+}
+
+func (m *MockEngineEvalRepo) RetryFailed(analysisID string) (int64, error) {
+	if m.RetryFailedFunc != nil {
+		return m.RetryFailedFunc(analysisID)
+	}
+	return 0, nil
+}
+
+func (m *MockEngineEvalRepo) CountUnfinished(analysisID string) (int, error) {
+	if m.CountUnfinishedFunc != nil {
+		return m.CountUnfinishedFunc(analysisID)
+	}
+	return 0, nil
 }
 
 func (m *MockEngineEvalRepo) GetByUser(userID string) ([]models.EngineEval, error) {
@@ -189,6 +296,27 @@ func (m *MockEngineEvalRepo) GetByUser(userID string) ([]models.EngineEval, erro
 	return nil, nil
 }
 
+func (m *MockEngineEvalRepo) DeleteOlderThan(before time.Time) (int64, error) {
+	if m.DeleteOlderThanFunc != nil {
+		return m.DeleteOlderThanFunc(before)
+	}
+	return 0, nil
+}
+
+func (m *MockEngineEvalRepo) DeleteByUserOlderThan(userID string, before time.Time) (int64, error) {
+	if m.DeleteByUserOlderThanFunc != nil {
+		return m.DeleteByUserOlderThanFunc(userID, before)
+	}
+	return 0, nil
+}
+
+func (m *MockEngineEvalRepo) ResetAnalysis(userID, analysisID string, gameIndices []int) error {
+	if m.ResetAnalysisFunc != nil {
+		return m.ResetAnalysisFunc(userID, analysisID, gameIndices)
+	}
+	return nil
+}
+
 // MockRepertoireRepo is a mock implementation of RepertoireRepository for testing
 type MockRepertoireRepo struct {
 	GetByIDFunc             func(id string) (*models.Repertoire, error)
@@ -197,14 +325,27 @@ type MockRepertoireRepo struct {
 	CreateFunc              func(userID string, name string, color models.Color) (*models.Repertoire, error)
 	CreateWithCategoryFunc  func(userID, name string, color models.Color, categoryID *string) (*models.Repertoire, error)
 	SaveFunc                func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error)
+	SaveNodesFunc           func(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error)
 	UpdateNameFunc          func(id string, name string) (*models.Repertoire, error)
 	UpdateCategoryFunc      func(id string, categoryID *string) (*models.Repertoire, error)
+	SetMatchActiveFunc      func(id string, active bool) (*models.Repertoire, error)
+	SetArchivedFunc         func(id string, archived bool) (*models.Repertoire, error)
+	GetMatchExcludedFunc    func(userID string) (map[string]bool, error)
 	DeleteFunc              func(id string) error
 	CountFunc               func(userID string) (int, error)
 	ExistsFunc              func(id string) (bool, error)
 	BelongsToUserFunc       func(id string, userID string) (bool, error)
 	GetByCategoryFunc       func(categoryID string) ([]models.Repertoire, error)
 	GetUncategorizedFunc    func(userID string, color models.Color) ([]models.Repertoire, error)
+	FindPositionsFunc       func(userID string, fens []string) ([]models.RepertoirePosition, error)
+	SearchFunc              func(userID, query string, limit int) ([]models.RepertoireSearchResult, error)
+	ListRevisionsFunc       func(repertoireID string) ([]models.RepertoireRevision, error)
+	GetRevisionFunc         func(repertoireID string, version int) (*models.RepertoireRevision, error)
+	GetNotesFunc            func(repertoireID string, version int) (*models.StudyNotes, error)
+	SaveNotesFunc           func(repertoireID, userID, content string) (*models.StudyNotes, error)
+	ListNoteRevisionsFunc   func(repertoireID string) ([]models.StudyNotesRevision, error)
+	ListUnlabeledFunc       func(labelsVersion, limit int) ([]string, error)
+	MarkOpeningsLabeledFunc func(id string, labelsVersion int) error
 }
 
 func (m *MockRepertoireRepo) GetByID(id string) (*models.Repertoire, error) {
@@ -253,6 +394,14 @@ func (m *MockRepertoireRepo) Save(id string, treeData models.RepertoireNode, met
 	return nil, nil
 }
 
+// SaveNodes falls back to Save, so tests checking the saved tree cover both ways of saving
+func (m *MockRepertoireRepo) SaveNodes(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+	if m.SaveNodesFunc != nil {
+		return m.SaveNodesFunc(id, version, tree, changes, metadata)
+	}
+	return m.Save(id, tree, metadata)
+}
+
 func (m *MockRepertoireRepo) UpdateName(id string, name string) (*models.Repertoire, error) {
 	if m.UpdateNameFunc != nil {
 		return m.UpdateNameFunc(id, name)
@@ -267,6 +416,27 @@ func (m *MockRepertoireRepo) UpdateCategory(id string, categoryID *string) (*mod
 	return nil, nil
 }
 
+func (m *MockRepertoireRepo) SetMatchActive(id string, active bool) (*models.Repertoire, error) {
+	if m.SetMatchActiveFunc != nil {
+		return m.SetMatchActiveFunc(id, active)
+	}
+	return nil, nil
+}
+
+func (m *MockRepertoireRepo) SetArchived(id string, archived bool) (*models.Repertoire, error) {
+	if m.SetArchivedFunc != nil {
+		return m.SetArchivedFunc(id, archived)
+	}
+	return nil, nil
+}
+
+func (m *MockRepertoireRepo) GetMatchExcluded(userID string) (map[string]bool, error) {
+	if m.GetMatchExcludedFunc != nil {
+		return m.GetMatchExcludedFunc(userID)
+	}
+	return nil, nil
+}
+
 func (m *MockRepertoireRepo) Delete(id string) error {
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(id)
@@ -295,6 +465,94 @@ func (m *MockRepertoireRepo) BelongsToUser(id string, userID string) (bool, erro
 	return true, nil
 }
 
+// FindPositions defaults to indexing the trees returned by GetAllFunc, like the position table would
+func (m *MockRepertoireRepo) FindPositions(userID string, fens []string) ([]models.RepertoirePosition, error) {
+	if m.FindPositionsFunc != nil {
+		return m.FindPositionsFunc(userID, fens)
+	}
+	if m.GetAllFunc == nil {
+		return nil, nil
+	}
+	reps, err := m.GetAllFunc(userID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool)
+	for _, fen := range fens {
+		wanted[repository.PositionIndexKey(fen)] = true
+	}
+	var positions []models.RepertoirePosition
+	var walk func(repertoireID string, node *models.RepertoireNode)
+	walk = func(repertoireID string, node *models.RepertoireNode) {
+		if key := repository.PositionIndexKey(node.FEN); wanted[key] {
+			positions = append(positions, models.RepertoirePosition{RepertoireID: repertoireID, NodeID: node.ID, FEN: key})
+		}
+		for _, child := range node.Children {
+			walk(repertoireID, child)
+		}
+	}
+	for i := range reps {
+		walk(reps[i].ID, &reps[i].TreeData)
+	}
+	return positions, nil
+}
+
+func (m *MockRepertoireRepo) Search(userID, query string, limit int) ([]models.RepertoireSearchResult, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(userID, query, limit)
+	}
+	return []models.RepertoireSearchResult{}, nil
+}
+
+func (m *MockRepertoireRepo) ListRevisions(repertoireID string) ([]models.RepertoireRevision, error) {
+	if m.ListRevisionsFunc != nil {
+		return m.ListRevisionsFunc(repertoireID)
+	}
+	return []models.RepertoireRevision{}, nil
+}
+
+func (m *MockRepertoireRepo) GetRevision(repertoireID string, version int) (*models.RepertoireRevision, error) {
+	if m.GetRevisionFunc != nil {
+		return m.GetRevisionFunc(repertoireID, version)
+	}
+	return nil, repository.ErrRevisionNotFound
+}
+
+func (m *MockRepertoireRepo) GetNotes(repertoireID string, version int) (*models.StudyNotes, error) {
+	if m.GetNotesFunc != nil {
+		return m.GetNotesFunc(repertoireID, version)
+	}
+	return nil, repository.ErrStudyNotesNotFound
+}
+
+func (m *MockRepertoireRepo) SaveNotes(repertoireID, userID, content string) (*models.StudyNotes, error) {
+	if m.SaveNotesFunc != nil {
+		return m.SaveNotesFunc(repertoireID, userID, content)
+	}
+	return &models.StudyNotes{RepertoireID: repertoireID, Version: 1, Content: content, UpdatedBy: &userID}, nil
+}
+
+func (m *MockRepertoireRepo) ListNoteRevisions(repertoireID string) ([]models.StudyNotesRevision, error) {
+	if m.ListNoteRevisionsFunc != nil {
+		return m.ListNoteRevisionsFunc(repertoireID)
+	}
+	return []models.StudyNotesRevision{}, nil
+}
+
+func (m *MockRepertoireRepo) ListUnlabeled(labelsVersion, limit int) ([]string, error) {
+	if m.ListUnlabeledFunc != nil {
+		return m.ListUnlabeledFunc(labelsVersion, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockRepertoireRepo) MarkOpeningsLabeled(id string, labelsVersion int) error {
+	if m.MarkOpeningsLabeledFunc != nil {
+		return m.MarkOpeningsLabeledFunc(id, labelsVersion)
+	}
+	return nil
+}
+
 func (m *MockRepertoireRepo) GetByCategory(categoryID string) ([]models.Repertoire, error) {
 	if m.GetByCategoryFunc != nil {
 		return m.GetByCategoryFunc(categoryID)
@@ -311,30 +569,37 @@ func (m *MockRepertoireRepo) GetUncategorized(userID string, color models.Color)
 
 // MockAnalysisRepo is a mock implementation of AnalysisRepository for testing
 type MockAnalysisRepo struct {
-	SaveFunc               func(userID string, username, filename string, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error)
-	GetAllFunc             func(userID string) ([]models.AnalysisSummary, error)
+	SaveFunc               func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error)
+	GetAllFunc             func(userID, source string) ([]models.AnalysisSummary, error)
 	GetByIDFunc            func(id string) (*models.AnalysisDetail, error)
+	GetGameFunc            func(analysisID string, gameIndex int) (*models.GameAnalysis, error)
 	DeleteFunc             func(id string) error
-	GetAllGamesFunc        func(userID string, limit, offset int, timeClass, opening, source string) (*models.GamesResponse, error)
+	GetAllGamesFunc        func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error)
+	UpdateGameNotesFunc    func(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error)
 	DeleteGameFunc         func(analysisID string, gameIndex int) error
-	UpdateResultsFunc      func(analysisID string, results []models.GameAnalysis) error
+	UpdateGameFunc         func(analysisID string, game models.GameAnalysis) error
 	BelongsToUserFunc      func(id string, userID string) (bool, error)
 	GetDistinctRepertoiresFunc func(userID string) ([]string, error)
 	MarkGameViewedFunc         func(userID, analysisID string, gameIndex int) error
 	GetViewedGamesFunc         func(userID string) (map[string]bool, error)
 	GetAllGamesRawFunc         func(userID string) ([]models.RawAnalysis, error)
+	GetGameLocationsByRepertoireFunc func(userID, repertoireID string) ([]repository.GameLocation, error)
+	RecordSkippedDuplicatesFunc      func(userID, filename string, count int) error
+	GetImportStatsFunc               func(userID string) ([]models.ImportSourceStats, error)
+	GetBookDepthFunc                 func(userID, repertoireID string) ([]models.BookDepthPeriod, error)
+	GetMoveTimesFunc                 func(userID, repertoireID string, maxPly int) ([]models.PlyMoveTime, error)
 }
 
-func (m *MockAnalysisRepo) Save(userID string, username, filename string, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+func (m *MockAnalysisRepo) Save(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
 	if m.SaveFunc != nil {
-		return m.SaveFunc(userID, username, filename, gameCount, results)
+		return m.SaveFunc(userID, username, filename, provenance, gameCount, results)
 	}
 	return nil, nil
 }
 
-func (m *MockAnalysisRepo) GetAll(userID string) ([]models.AnalysisSummary, error) {
+func (m *MockAnalysisRepo) GetAll(userID, source string) ([]models.AnalysisSummary, error) {
 	if m.GetAllFunc != nil {
-		return m.GetAllFunc(userID)
+		return m.GetAllFunc(userID, source)
 	}
 	return nil, nil
 }
@@ -346,6 +611,13 @@ func (m *MockAnalysisRepo) GetByID(id string) (*models.AnalysisDetail, error) {
 	return nil, nil
 }
 
+func (m *MockAnalysisRepo) GetGame(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+	if m.GetGameFunc != nil {
+		return m.GetGameFunc(analysisID, gameIndex)
+	}
+	return nil, nil
+}
+
 func (m *MockAnalysisRepo) Delete(id string) error {
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(id)
@@ -353,9 +625,9 @@ func (m *MockAnalysisRepo) Delete(id string) error {
 	return nil
 }
 
-func (m *MockAnalysisRepo) GetAllGames(userID string, limit, offset int, timeClass, opening, source string) (*models.GamesResponse, error) {
+func (m *MockAnalysisRepo) GetAllGames(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
 	if m.GetAllGamesFunc != nil {
-		return m.GetAllGamesFunc(userID, limit, offset, timeClass, opening, source)
+		return m.GetAllGamesFunc(userID, limit, offset, timeClass, opening, source, classification, starred)
 	}
 	return nil, nil
 }
@@ -367,9 +639,16 @@ func (m *MockAnalysisRepo) DeleteGame(analysisID string, gameIndex int) error {
 	return nil
 }
 
-func (m *MockAnalysisRepo) UpdateResults(analysisID string, results []models.GameAnalysis) error {
-	if m.UpdateResultsFunc != nil {
-		return m.UpdateResultsFunc(analysisID, results)
+func (m *MockAnalysisRepo) UpdateGameNotes(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error) {
+	if m.UpdateGameNotesFunc != nil {
+		return m.UpdateGameNotesFunc(analysisID, gameIndex, note, starred)
+	}
+	return nil, repository.ErrGameNotFound
+}
+
+func (m *MockAnalysisRepo) UpdateGame(analysisID string, game models.GameAnalysis) error {
+	if m.UpdateGameFunc != nil {
+		return m.UpdateGameFunc(analysisID, game)
 	}
 	return nil
 }
@@ -409,227 +688,1284 @@ func (m *MockAnalysisRepo) GetAllGamesRaw(userID string) ([]models.RawAnalysis,
 	return nil, nil
 }
 
-// MockUserRepo is a mock implementation of UserRepository for testing
-type MockUserRepo struct {
-	CreateFunc               func(email, username, passwordHash string) (*models.User, error)
-	GetByUsernameFunc        func(username string) (*models.User, error)
-	GetByEmailFunc           func(email string) (*models.User, error)
-	GetByIDFunc              func(id string) (*models.User, error)
-	ExistsFunc               func(username string) (bool, error)
-	EmailExistsFunc          func(email string) (bool, error)
-	FindByOAuthFunc          func(provider, oauthID string) (*models.User, error)
-	CreateOAuthFunc          func(provider, oauthID, username string) (*models.User, error)
-	UpdateProfileFunc        func(userID string, lichess, chesscom *string, timeFormatPrefs []string) (*models.User, error)
-	UpdateSyncTimestampsFunc func(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error
-	UpdateLichessTokenFunc   func(userID, token string) error
-	UpdatePasswordFunc       func(userID, passwordHash string) error
-}
-
-func (m *MockUserRepo) Create(email, username, passwordHash string) (*models.User, error) {
-	if m.CreateFunc != nil {
-		return m.CreateFunc(email, username, passwordHash)
+func (m *MockAnalysisRepo) GetGameLocationsByRepertoire(userID, repertoireID string) ([]repository.GameLocation, error) {
+	if m.GetGameLocationsByRepertoireFunc != nil {
+		return m.GetGameLocationsByRepertoireFunc(userID, repertoireID)
 	}
 	return nil, nil
 }
 
-func (m *MockUserRepo) GetByUsername(username string) (*models.User, error) {
-	if m.GetByUsernameFunc != nil {
-		return m.GetByUsernameFunc(username)
+func (m *MockAnalysisRepo) RecordSkippedDuplicates(userID, filename string, count int) error {
+	if m.RecordSkippedDuplicatesFunc != nil {
+		return m.RecordSkippedDuplicatesFunc(userID, filename, count)
 	}
-	return nil, nil
+	return nil
 }
 
-func (m *MockUserRepo) GetByEmail(email string) (*models.User, error) {
-	if m.GetByEmailFunc != nil {
-		return m.GetByEmailFunc(email)
+func (m *MockAnalysisRepo) GetImportStats(userID string) ([]models.ImportSourceStats, error) {
+	if m.GetImportStatsFunc != nil {
+		return m.GetImportStatsFunc(userID)
 	}
-	return nil, nil
+	return []models.ImportSourceStats{}, nil
 }
 
-func (m *MockUserRepo) GetByID(id string) (*models.User, error) {
-	if m.GetByIDFunc != nil {
-		return m.GetByIDFunc(id)
+func (m *MockAnalysisRepo) GetBookDepth(userID, repertoireID string) ([]models.BookDepthPeriod, error) {
+	if m.GetBookDepthFunc != nil {
+		return m.GetBookDepthFunc(userID, repertoireID)
 	}
-	return nil, nil
+	return []models.BookDepthPeriod{}, nil
 }
 
-func (m *MockUserRepo) Exists(username string) (bool, error) {
-	if m.ExistsFunc != nil {
-		return m.ExistsFunc(username)
+func (m *MockAnalysisRepo) GetMoveTimes(userID, repertoireID string, maxPly int) ([]models.PlyMoveTime, error) {
+	if m.GetMoveTimesFunc != nil {
+		return m.GetMoveTimesFunc(userID, repertoireID, maxPly)
 	}
-	return false, nil
+	return []models.PlyMoveTime{}, nil
 }
 
-func (m *MockUserRepo) EmailExists(email string) (bool, error) {
-	if m.EmailExistsFunc != nil {
-		return m.EmailExistsFunc(email)
-	}
-	return false, nil
+// MockReanalysisJobRepo is a mock implementation of ReanalysisJobRepository for testing
+type MockReanalysisJobRepo struct {
+	CreateFunc         func(userID, repertoireID string) (*models.ReanalysisJob, error)
+	GetByIDFunc        func(id string) (*models.ReanalysisJob, error)
+	GetPendingFunc     func(limit int) ([]models.ReanalysisJob, error)
+	MarkProcessingFunc func(id string, total int) error
+	UpdateProgressFunc func(id string, processed int) error
+	MarkDoneFunc       func(id string) error
+	MarkFailedFunc     func(id string, message string) error
 }
 
-func (m *MockUserRepo) FindByOAuth(provider, oauthID string) (*models.User, error) {
-	if m.FindByOAuthFunc != nil {
-		return m.FindByOAuthFunc(provider, oauthID)
+func (m *MockReanalysisJobRepo) Create(userID, repertoireID string) (*models.ReanalysisJob, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, repertoireID)
 	}
 	return nil, nil
 }
 
-func (m *MockUserRepo) CreateOAuth(provider, oauthID, username string) (*models.User, error) {
-	if m.CreateOAuthFunc != nil {
-		return m.CreateOAuthFunc(provider, oauthID, username)
+func (m *MockReanalysisJobRepo) GetByID(id string) (*models.ReanalysisJob, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
 	}
-	return nil, nil
+	return nil, repository.ErrReanalysisJobNotFound
 }
 
-func (m *MockUserRepo) UpdateProfile(userID string, lichess, chesscom *string, timeFormatPrefs []string) (*models.User, error) {
-	if m.UpdateProfileFunc != nil {
-		return m.UpdateProfileFunc(userID, lichess, chesscom, timeFormatPrefs)
+func (m *MockReanalysisJobRepo) GetPending(limit int) ([]models.ReanalysisJob, error) {
+	if m.GetPendingFunc != nil {
+		return m.GetPendingFunc(limit)
 	}
 	return nil, nil
 }
 
-func (m *MockUserRepo) UpdateSyncTimestamps(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error {
-	if m.UpdateSyncTimestampsFunc != nil {
-		return m.UpdateSyncTimestampsFunc(userID, lichessSyncAt, chesscomSyncAt)
+func (m *MockReanalysisJobRepo) MarkProcessing(id string, total int) error {
+	if m.MarkProcessingFunc != nil {
+		return m.MarkProcessingFunc(id, total)
 	}
 	return nil
 }
 
-func (m *MockUserRepo) UpdateLichessToken(userID, token string) error {
-	if m.UpdateLichessTokenFunc != nil {
-		return m.UpdateLichessTokenFunc(userID, token)
+func (m *MockReanalysisJobRepo) UpdateProgress(id string, processed int) error {
+	if m.UpdateProgressFunc != nil {
+		return m.UpdateProgressFunc(id, processed)
 	}
 	return nil
 }
 
-func (m *MockUserRepo) UpdatePassword(userID, passwordHash string) error {
-	if m.UpdatePasswordFunc != nil {
-		return m.UpdatePasswordFunc(userID, passwordHash)
+func (m *MockReanalysisJobRepo) MarkDone(id string) error {
+	if m.MarkDoneFunc != nil {
+		return m.MarkDoneFunc(id)
 	}
 	return nil
 }
 
-// MockCategoryRepo is a mock implementation of CategoryRepository for testing
-type MockCategoryRepo struct {
-	GetByIDFunc            func(id string) (*models.Category, error)
-	GetByUserAndColorFunc  func(userID string, color models.Color) ([]models.Category, error)
-	GetAllFunc             func(userID string) ([]models.Category, error)
-	CreateFunc             func(userID, name string, color models.Color) (*models.Category, error)
-	UpdateNameFunc         func(id, name string) (*models.Category, error)
-	DeleteFunc             func(id string) error
-	BelongsToUserFunc      func(id, userID string) (bool, error)
-	ExistsFunc             func(id string) (bool, error)
-	CountFunc              func(userID string) (int, error)
+func (m *MockReanalysisJobRepo) MarkFailed(id string, message string) error {
+	if m.MarkFailedFunc != nil {
+		return m.MarkFailedFunc(id, message)
+	}
+	return nil
 }
 
-func (m *MockCategoryRepo) GetByID(id string) (*models.Category, error) {
-	if m.GetByIDFunc != nil {
-		return m.GetByIDFunc(id)
+// MockRawGameRepo is a mock implementation of RawGameRepository for testing
+type MockRawGameRepo struct {
+	AppendFunc    func(userID, source string, games []repository.RawGameEntry) error
+	CountFunc     func(userID string) (int, error)
+	ListAfterFunc func(userID string, after repository.RawGameKey, limit int) ([]models.RawGame, error)
+}
+
+func (m *MockRawGameRepo) Append(userID, source string, games []repository.RawGameEntry) error {
+	if m.AppendFunc != nil {
+		return m.AppendFunc(userID, source, games)
 	}
-	return nil, nil
+	return nil
 }
 
-func (m *MockCategoryRepo) GetByUserAndColor(userID string, color models.Color) ([]models.Category, error) {
-	if m.GetByUserAndColorFunc != nil {
-		return m.GetByUserAndColorFunc(userID, color)
+func (m *MockRawGameRepo) Count(userID string) (int, error) {
+	if m.CountFunc != nil {
+		return m.CountFunc(userID)
 	}
-	return nil, nil
+	return 0, nil
 }
 
-func (m *MockCategoryRepo) GetAll(userID string) ([]models.Category, error) {
-	if m.GetAllFunc != nil {
-		return m.GetAllFunc(userID)
+func (m *MockRawGameRepo) ListAfter(userID string, after repository.RawGameKey, limit int) ([]models.RawGame, error) {
+	if m.ListAfterFunc != nil {
+		return m.ListAfterFunc(userID, after, limit)
 	}
 	return nil, nil
 }
 
-func (m *MockCategoryRepo) Create(userID, name string, color models.Color) (*models.Category, error) {
+// MockRecomputeJobRepo is a mock implementation of RecomputeJobRepository for testing
+type MockRecomputeJobRepo struct {
+	CreateFunc         func(userID string) (*models.RecomputeJob, error)
+	GetByIDFunc        func(id string) (*models.RecomputeJob, error)
+	GetPendingFunc     func(limit int) ([]models.RecomputeJob, error)
+	MarkProcessingFunc func(id string, total int) error
+	UpdateProgressFunc func(id string, processed, recomputed int) error
+	MarkDoneFunc       func(id string) error
+	MarkFailedFunc     func(id string, message string) error
+}
+
+func (m *MockRecomputeJobRepo) Create(userID string) (*models.RecomputeJob, error) {
 	if m.CreateFunc != nil {
-		return m.CreateFunc(userID, name, color)
+		return m.CreateFunc(userID)
 	}
 	return nil, nil
 }
 
-func (m *MockCategoryRepo) UpdateName(id, name string) (*models.Category, error) {
-	if m.UpdateNameFunc != nil {
-		return m.UpdateNameFunc(id, name)
+func (m *MockRecomputeJobRepo) GetByID(id string) (*models.RecomputeJob, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrRecomputeJobNotFound
+}
+
+func (m *MockRecomputeJobRepo) GetPending(limit int) ([]models.RecomputeJob, error) {
+	if m.GetPendingFunc != nil {
+		return m.GetPendingFunc(limit)
 	}
 	return nil, nil
 }
 
-func (m *MockCategoryRepo) Delete(id string) error {
-	if m.DeleteFunc != nil {
-		return m.DeleteFunc(id)
+func (m *MockRecomputeJobRepo) MarkProcessing(id string, total int) error {
+	if m.MarkProcessingFunc != nil {
+		return m.MarkProcessingFunc(id, total)
 	}
 	return nil
 }
 
-func (m *MockCategoryRepo) BelongsToUser(id, userID string) (bool, error) {
-	if m.BelongsToUserFunc != nil {
-		return m.BelongsToUserFunc(id, userID)
+func (m *MockRecomputeJobRepo) UpdateProgress(id string, processed, recomputed int) error {
+	if m.UpdateProgressFunc != nil {
+		return m.UpdateProgressFunc(id, processed, recomputed)
 	}
-	return true, nil
+	return nil
 }
 
-func (m *MockCategoryRepo) Exists(id string) (bool, error) {
-	if m.ExistsFunc != nil {
-		return m.ExistsFunc(id)
+func (m *MockRecomputeJobRepo) MarkDone(id string) error {
+	if m.MarkDoneFunc != nil {
+		return m.MarkDoneFunc(id)
 	}
-	return false, nil
+	return nil
 }
 
-func (m *MockCategoryRepo) Count(userID string) (int, error) {
-	if m.CountFunc != nil {
-		return m.CountFunc(userID)
+func (m *MockRecomputeJobRepo) MarkFailed(id string, message string) error {
+	if m.MarkFailedFunc != nil {
+		return m.MarkFailedFunc(id, message)
 	}
-	return 0, nil
+	return nil
 }
 
-// MockPasswordResetRepo is a mock implementation of PasswordResetRepository for testing
-type MockPasswordResetRepo struct {
-	CreateFunc              func(userID, tokenHash string, expiresAt time.Time) (*models.PasswordResetToken, error)
-	GetByTokenHashFunc      func(tokenHash string) (*models.PasswordResetToken, error)
-	MarkUsedFunc            func(id string) error
-	DeleteByUserIDFunc      func(userID string) error
-	CountRecentByUserIDFunc func(userID string, since time.Time) (int, error)
+// MockImportJobRepo is a mock implementation of ImportJobRepository for testing
+type MockImportJobRepo struct {
+	CreateFunc              func(job models.ImportJob) (*models.ImportJob, error)
+	GetByIDFunc             func(id string) (*models.ImportJob, error)
+	GetPendingFunc          func(limit int) ([]models.ImportJob, error)
+	MarkProcessingFunc      func(id string, totalGames, totalChunks int) error
+	RecordChunkFunc         func(id string, result models.ImportChunkResult) error
+	RecordDedupProgressFunc func(id string, checkedGames int) error
+	MarkDoneFunc            func(id string) error
+	MarkFailedFunc          func(id string, message string) error
 }
 
-func (m *MockPasswordResetRepo) Create(userID, tokenHash string, expiresAt time.Time) (*models.PasswordResetToken, error) {
+func (m *MockImportJobRepo) Create(job models.ImportJob) (*models.ImportJob, error) {
 	if m.CreateFunc != nil {
-		return m.CreateFunc(userID, tokenHash, expiresAt)
+		return m.CreateFunc(job)
 	}
-	return &models.PasswordResetToken{
-		ID:        "reset-123",
+	job.ID = "import-job-1"
+	job.Status = "pending"
+	return &job, nil
+}
+
+func (m *MockImportJobRepo) GetByID(id string) (*models.ImportJob, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrImportJobNotFound
+}
+
+func (m *MockImportJobRepo) GetPending(limit int) ([]models.ImportJob, error) {
+	if m.GetPendingFunc != nil {
+		return m.GetPendingFunc(limit)
+	}
+	return nil, nil
+}
+
+func (m *MockImportJobRepo) MarkProcessing(id string, totalGames, totalChunks int) error {
+	if m.MarkProcessingFunc != nil {
+		return m.MarkProcessingFunc(id, totalGames, totalChunks)
+	}
+	return nil
+}
+
+func (m *MockImportJobRepo) RecordChunk(id string, result models.ImportChunkResult) error {
+	if m.RecordChunkFunc != nil {
+		return m.RecordChunkFunc(id, result)
+	}
+	return nil
+}
+
+func (m *MockImportJobRepo) RecordDedupProgress(id string, checkedGames int) error {
+	if m.RecordDedupProgressFunc != nil {
+		return m.RecordDedupProgressFunc(id, checkedGames)
+	}
+	return nil
+}
+
+func (m *MockImportJobRepo) MarkDone(id string) error {
+	if m.MarkDoneFunc != nil {
+		return m.MarkDoneFunc(id)
+	}
+	return nil
+}
+
+func (m *MockImportJobRepo) MarkFailed(id string, message string) error {
+	if m.MarkFailedFunc != nil {
+		return m.MarkFailedFunc(id, message)
+	}
+	return nil
+}
+
+// MockTeamImportRepo is a mock implementation of TeamImportRepository for testing
+type MockTeamImportRepo struct {
+	CreateFunc       func(userID, teamID string, options models.LichessImportOptions, analysisDepth *int) (*models.TeamImport, error)
+	GetByIDFunc      func(id string) (*models.TeamImport, error)
+	ClaimPendingFunc func(limit int) ([]models.TeamImport, error)
+	SetMembersFunc   func(id string, members []string) error
+	RecordMemberFunc func(id string, result models.TeamMemberImport) error
+	DeferFunc        func(id string, until time.Time) error
+	MarkDoneFunc     func(id string) error
+	MarkFailedFunc   func(id string, message string) error
+}
+
+func (m *MockTeamImportRepo) Create(userID, teamID string, options models.LichessImportOptions, analysisDepth *int) (*models.TeamImport, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, teamID, options, analysisDepth)
+	}
+	return &models.TeamImport{ID: "team-import-123", UserID: userID, TeamID: teamID, Options: options,
+		AnalysisDepth: analysisDepth, Status: "pending", Members: []string{}, Results: []models.TeamMemberImport{}}, nil
+}
+
+func (m *MockTeamImportRepo) GetByID(id string) (*models.TeamImport, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrTeamImportNotFound
+}
+
+func (m *MockTeamImportRepo) ClaimPending(limit int) ([]models.TeamImport, error) {
+	if m.ClaimPendingFunc != nil {
+		return m.ClaimPendingFunc(limit)
+	}
+	return nil, nil
+}
+
+func (m *MockTeamImportRepo) SetMembers(id string, members []string) error {
+	if m.SetMembersFunc != nil {
+		return m.SetMembersFunc(id, members)
+	}
+	return nil
+}
+
+func (m *MockTeamImportRepo) RecordMember(id string, result models.TeamMemberImport) error {
+	if m.RecordMemberFunc != nil {
+		return m.RecordMemberFunc(id, result)
+	}
+	return nil
+}
+
+func (m *MockTeamImportRepo) Defer(id string, until time.Time) error {
+	if m.DeferFunc != nil {
+		return m.DeferFunc(id, until)
+	}
+	return nil
+}
+
+func (m *MockTeamImportRepo) MarkDone(id string) error {
+	if m.MarkDoneFunc != nil {
+		return m.MarkDoneFunc(id)
+	}
+	return nil
+}
+
+func (m *MockTeamImportRepo) MarkFailed(id string, message string) error {
+	if m.MarkFailedFunc != nil {
+		return m.MarkFailedFunc(id, message)
+	}
+	return nil
+}
+
+// MockSyncRunRepo is a mock implementation of SyncRunRepository for testing
+type MockSyncRunRepo struct {
+	CreateFunc     func(userID string) (*models.SyncRun, error)
+	FinishFunc     func(run *models.SyncRun) error
+	GetByIDFunc    func(id string) (*models.SyncRun, error)
+	ListByUserFunc func(userID string, limit int) ([]models.SyncRun, error)
+}
+
+func (m *MockSyncRunRepo) Create(userID string) (*models.SyncRun, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID)
+	}
+	return &models.SyncRun{ID: "run-1", UserID: userID, Status: models.SyncRunRunning}, nil
+}
+
+func (m *MockSyncRunRepo) Finish(run *models.SyncRun) error {
+	if m.FinishFunc != nil {
+		return m.FinishFunc(run)
+	}
+	return nil
+}
+
+func (m *MockSyncRunRepo) GetByID(id string) (*models.SyncRun, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrSyncRunNotFound
+}
+
+func (m *MockSyncRunRepo) ListByUser(userID string, limit int) ([]models.SyncRun, error) {
+	if m.ListByUserFunc != nil {
+		return m.ListByUserFunc(userID, limit)
+	}
+	return nil, nil
+}
+
+// MockUserRepo is a mock implementation of UserRepository for testing
+type MockUserRepo struct {
+	CreateFunc                  func(email, username, passwordHash string) (*models.User, error)
+	GetByUsernameFunc           func(username string) (*models.User, error)
+	GetByEmailFunc              func(email string) (*models.User, error)
+	GetByIDFunc                 func(id string) (*models.User, error)
+	ExistsFunc                  func(username string) (bool, error)
+	EmailExistsFunc             func(email string) (bool, error)
+	FindByOAuthFunc             func(provider, oauthID string) (*models.User, error)
+	CreateOAuthFunc             func(provider, oauthID, username string) (*models.User, error)
+	UpdateProfileFunc           func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error)
+	UpdateSyncTimestampsFunc    func(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error
+	UpdateLinkedAccountSyncFunc func(accountID string, syncedAt time.Time) error
+	UpdateLichessTokenFunc      func(userID, token string) error
+	UpdatePasswordFunc          func(userID, passwordHash string) error
+	UpdateNotificationsFunc     func(userID string, weeklyDigest bool) (*models.User, error)
+	ListDigestRecipientsFunc    func(sentBefore time.Time) ([]models.User, error)
+	MarkDigestSentFunc          func(userID string, sentAt time.Time) error
+}
+
+func (m *MockUserRepo) Create(email, username, passwordHash string) (*models.User, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(email, username, passwordHash)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) GetByUsername(username string) (*models.User, error) {
+	if m.GetByUsernameFunc != nil {
+		return m.GetByUsernameFunc(username)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) GetByEmail(email string) (*models.User, error) {
+	if m.GetByEmailFunc != nil {
+		return m.GetByEmailFunc(email)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) GetByID(id string) (*models.User, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) Exists(username string) (bool, error) {
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(username)
+	}
+	return false, nil
+}
+
+func (m *MockUserRepo) EmailExists(email string) (bool, error) {
+	if m.EmailExistsFunc != nil {
+		return m.EmailExistsFunc(email)
+	}
+	return false, nil
+}
+
+func (m *MockUserRepo) FindByOAuth(provider, oauthID string) (*models.User, error) {
+	if m.FindByOAuthFunc != nil {
+		return m.FindByOAuthFunc(provider, oauthID)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) CreateOAuth(provider, oauthID, username string) (*models.User, error) {
+	if m.CreateOAuthFunc != nil {
+		return m.CreateOAuthFunc(provider, oauthID, username)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) UpdateProfile(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
+	if m.UpdateProfileFunc != nil {
+		return m.UpdateProfileFunc(userID, accounts, timeFormatPrefs, analysisDepth, timezone, locale)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) UpdateLinkedAccountSync(accountID string, syncedAt time.Time) error {
+	if m.UpdateLinkedAccountSyncFunc != nil {
+		return m.UpdateLinkedAccountSyncFunc(accountID, syncedAt)
+	}
+	return nil
+}
+
+func (m *MockUserRepo) UpdateSyncTimestamps(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error {
+	if m.UpdateSyncTimestampsFunc != nil {
+		return m.UpdateSyncTimestampsFunc(userID, lichessSyncAt, chesscomSyncAt)
+	}
+	return nil
+}
+
+func (m *MockUserRepo) UpdateLichessToken(userID, token string) error {
+	if m.UpdateLichessTokenFunc != nil {
+		return m.UpdateLichessTokenFunc(userID, token)
+	}
+	return nil
+}
+
+func (m *MockUserRepo) UpdatePassword(userID, passwordHash string) error {
+	if m.UpdatePasswordFunc != nil {
+		return m.UpdatePasswordFunc(userID, passwordHash)
+	}
+	return nil
+}
+
+func (m *MockUserRepo) UpdateNotifications(userID string, weeklyDigest bool) (*models.User, error) {
+	if m.UpdateNotificationsFunc != nil {
+		return m.UpdateNotificationsFunc(userID, weeklyDigest)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) ListDigestRecipients(sentBefore time.Time) ([]models.User, error) {
+	if m.ListDigestRecipientsFunc != nil {
+		return m.ListDigestRecipientsFunc(sentBefore)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepo) MarkDigestSent(userID string, sentAt time.Time) error {
+	if m.MarkDigestSentFunc != nil {
+		return m.MarkDigestSentFunc(userID, sentAt)
+	}
+	return nil
+}
+
+// MockCategoryRepo is a mock implementation of CategoryRepository for testing
+type MockCategoryRepo struct {
+	GetByIDFunc            func(id string) (*models.Category, error)
+	GetByUserAndColorFunc  func(userID string, color models.Color) ([]models.Category, error)
+	GetAllFunc             func(userID string) ([]models.Category, error)
+	CreateFunc             func(userID, name string, color models.Color) (*models.Category, error)
+	UpdateNameFunc         func(id, name string) (*models.Category, error)
+	SetMatchActiveFunc     func(id string, active bool) (*models.Category, error)
+	DeleteFunc             func(id string) error
+	BelongsToUserFunc      func(id, userID string) (bool, error)
+	ExistsFunc             func(id string) (bool, error)
+	CountFunc              func(userID string) (int, error)
+}
+
+func (m *MockCategoryRepo) GetByID(id string) (*models.Category, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *MockCategoryRepo) GetByUserAndColor(userID string, color models.Color) ([]models.Category, error) {
+	if m.GetByUserAndColorFunc != nil {
+		return m.GetByUserAndColorFunc(userID, color)
+	}
+	return nil, nil
+}
+
+func (m *MockCategoryRepo) GetAll(userID string) ([]models.Category, error) {
+	if m.GetAllFunc != nil {
+		return m.GetAllFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockCategoryRepo) Create(userID, name string, color models.Color) (*models.Category, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, name, color)
+	}
+	return nil, nil
+}
+
+func (m *MockCategoryRepo) UpdateName(id, name string) (*models.Category, error) {
+	if m.UpdateNameFunc != nil {
+		return m.UpdateNameFunc(id, name)
+	}
+	return nil, nil
+}
+
+func (m *MockCategoryRepo) SetMatchActive(id string, active bool) (*models.Category, error) {
+	if m.SetMatchActiveFunc != nil {
+		return m.SetMatchActiveFunc(id, active)
+	}
+	return nil, nil
+}
+
+func (m *MockCategoryRepo) Delete(id string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return nil
+}
+
+func (m *MockCategoryRepo) BelongsToUser(id, userID string) (bool, error) {
+	if m.BelongsToUserFunc != nil {
+		return m.BelongsToUserFunc(id, userID)
+	}
+	return true, nil
+}
+
+func (m *MockCategoryRepo) Exists(id string) (bool, error) {
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(id)
+	}
+	return false, nil
+}
+
+func (m *MockCategoryRepo) Count(userID string) (int, error) {
+	if m.CountFunc != nil {
+		return m.CountFunc(userID)
+	}
+	return 0, nil
+}
+
+// MockPasswordResetRepo is a mock implementation of PasswordResetRepository for testing
+type MockPasswordResetRepo struct {
+	CreateFunc              func(userID, tokenHash string, expiresAt time.Time) (*models.PasswordResetToken, error)
+	GetByTokenHashFunc      func(tokenHash string) (*models.PasswordResetToken, error)
+	MarkUsedFunc            func(id string) error
+	DeleteByUserIDFunc      func(userID string) error
+	CountRecentByUserIDFunc func(userID string, since time.Time) (int, error)
+}
+
+func (m *MockPasswordResetRepo) Create(userID, tokenHash string, expiresAt time.Time) (*models.PasswordResetToken, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, tokenHash, expiresAt)
+	}
+	return &models.PasswordResetToken{
+		ID:        "reset-123",
 		UserID:    userID,
 		TokenHash: tokenHash,
 		ExpiresAt: expiresAt,
 	}, nil
 }
 
-func (m *MockPasswordResetRepo) GetByTokenHash(tokenHash string) (*models.PasswordResetToken, error) {
-	if m.GetByTokenHashFunc != nil {
-		return m.GetByTokenHashFunc(tokenHash)
+func (m *MockPasswordResetRepo) GetByTokenHash(tokenHash string) (*models.PasswordResetToken, error) {
+	if m.GetByTokenHashFunc != nil {
+		return m.GetByTokenHashFunc(tokenHash)
+	}
+	return nil, repository.ErrResetTokenNotFound
+}
+
+func (m *MockPasswordResetRepo) MarkUsed(id string) error {
+	if m.MarkUsedFunc != nil {
+		return m.MarkUsedFunc(id)
+	}
+	return nil
+}
+
+func (m *MockPasswordResetRepo) DeleteByUserID(userID string) error {
+	if m.DeleteByUserIDFunc != nil {
+		return m.DeleteByUserIDFunc(userID)
+	}
+	return nil
+}
+
+func (m *MockPasswordResetRepo) CountRecentByUserID(userID string, since time.Time) (int, error) {
+	if m.CountRecentByUserIDFunc != nil {
+		return m.CountRecentByUserIDFunc(userID, since)
+	}
+	return 0, nil
+}
+
+// MockGoalRepo is a mock implementation of GoalRepository for testing
+type MockGoalRepo struct {
+	CreateFunc          func(userID, repertoireID string, goalType models.GoalType, target float64, depth int) (*models.Goal, error)
+	GetByIDFunc         func(id string) (*models.Goal, error)
+	GetByUserFunc       func(userID string) ([]models.Goal, error)
+	GetAllFunc          func() ([]models.Goal, error)
+	CountByUserFunc     func(userID string) (int, error)
+	UpdateProgressFunc  func(id string, progress float64, achieved bool) error
+	MarkSummarySentFunc func(userID string, sentAt time.Time) error
+	DeleteFunc          func(id string) error
+	BelongsToUserFunc   func(id, userID string) (bool, error)
+}
+
+func (m *MockGoalRepo) Create(userID, repertoireID string, goalType models.GoalType, target float64, depth int) (*models.Goal, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, repertoireID, goalType, target, depth)
+	}
+	return &models.Goal{
+		ID:           "goal-123",
+		UserID:       userID,
+		RepertoireID: repertoireID,
+		Type:         goalType,
+		Target:       target,
+		Depth:        depth,
+	}, nil
+}
+
+func (m *MockGoalRepo) GetByID(id string) (*models.Goal, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrGoalNotFound
+}
+
+func (m *MockGoalRepo) GetByUser(userID string) ([]models.Goal, error) {
+	if m.GetByUserFunc != nil {
+		return m.GetByUserFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockGoalRepo) GetAll() ([]models.Goal, error) {
+	if m.GetAllFunc != nil {
+		return m.GetAllFunc()
+	}
+	return nil, nil
+}
+
+func (m *MockGoalRepo) CountByUser(userID string) (int, error) {
+	if m.CountByUserFunc != nil {
+		return m.CountByUserFunc(userID)
+	}
+	return 0, nil
+}
+
+func (m *MockGoalRepo) UpdateProgress(id string, progress float64, achieved bool) error {
+	if m.UpdateProgressFunc != nil {
+		return m.UpdateProgressFunc(id, progress, achieved)
+	}
+	return nil
+}
+
+func (m *MockGoalRepo) MarkSummarySent(userID string, sentAt time.Time) error {
+	if m.MarkSummarySentFunc != nil {
+		return m.MarkSummarySentFunc(userID, sentAt)
+	}
+	return nil
+}
+
+func (m *MockGoalRepo) Delete(id string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return nil
+}
+
+func (m *MockGoalRepo) BelongsToUser(id, userID string) (bool, error) {
+	if m.BelongsToUserFunc != nil {
+		return m.BelongsToUserFunc(id, userID)
+	}
+	return true, nil
+}
+
+// MockPrepRepo is a mock implementation of PrepRepository for testing
+type MockPrepRepo struct {
+	CreateFunc           func(userID, repertoireID, nodeID, fen string, depth int) (*models.PrepRequest, error)
+	GetByIDFunc          func(id string) (*models.PrepRequest, error)
+	ListByNodeFunc       func(repertoireID, nodeID string) ([]models.PrepRequest, error)
+	ClaimPendingFunc     func(limit int) ([]models.PrepRequest, error)
+	CompleteFunc         func(id string, suggestions []models.PrepSuggestion) error
+	MarkFailedFunc       func(id, message string) error
+	RemoveSuggestionFunc func(id, suggestionID string) error
+}
+
+func (m *MockPrepRepo) Create(userID, repertoireID, nodeID, fen string, depth int) (*models.PrepRequest, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, repertoireID, nodeID, fen, depth)
+	}
+	return &models.PrepRequest{
+		ID:           "prep-123",
+		UserID:       userID,
+		RepertoireID: repertoireID,
+		NodeID:       nodeID,
+		FEN:          fen,
+		Depth:        depth,
+		Status:       models.PrepPending,
+		Suggestions:  []models.PrepSuggestion{},
+	}, nil
+}
+
+func (m *MockPrepRepo) GetByID(id string) (*models.PrepRequest, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrPrepRequestNotFound
+}
+
+func (m *MockPrepRepo) ListByNode(repertoireID, nodeID string) ([]models.PrepRequest, error) {
+	if m.ListByNodeFunc != nil {
+		return m.ListByNodeFunc(repertoireID, nodeID)
+	}
+	return nil, nil
+}
+
+func (m *MockPrepRepo) ClaimPending(limit int) ([]models.PrepRequest, error) {
+	if m.ClaimPendingFunc != nil {
+		return m.ClaimPendingFunc(limit)
+	}
+	return nil, nil
+}
+
+func (m *MockPrepRepo) Complete(id string, suggestions []models.PrepSuggestion) error {
+	if m.CompleteFunc != nil {
+		return m.CompleteFunc(id, suggestions)
+	}
+	return nil
+}
+
+func (m *MockPrepRepo) MarkFailed(id, message string) error {
+	if m.MarkFailedFunc != nil {
+		return m.MarkFailedFunc(id, message)
 	}
-	return nil, repository.ErrResetTokenNotFound
+	return nil
 }
 
-func (m *MockPasswordResetRepo) MarkUsed(id string) error {
-	if m.MarkUsedFunc != nil {
-		return m.MarkUsedFunc(id)
+func (m *MockPrepRepo) RemoveSuggestion(id, suggestionID string) error {
+	if m.RemoveSuggestionFunc != nil {
+		return m.RemoveSuggestionFunc(id, suggestionID)
 	}
 	return nil
 }
 
-func (m *MockPasswordResetRepo) DeleteByUserID(userID string) error {
-	if m.DeleteByUserIDFunc != nil {
-		return m.DeleteByUserIDFunc(userID)
+// MockImportSummaryRepo is a mock implementation of ImportSummaryRepository for testing
+type MockImportSummaryRepo struct {
+	SaveFunc            func(userID string, summary models.ImportSummary) error
+	GetByAnalysisIDFunc func(analysisID, userID string) (*models.ImportSummary, error)
+}
+
+func (m *MockImportSummaryRepo) Save(userID string, summary models.ImportSummary) error {
+	if m.SaveFunc != nil {
+		return m.SaveFunc(userID, summary)
 	}
 	return nil
 }
 
-func (m *MockPasswordResetRepo) CountRecentByUserID(userID string, since time.Time) (int, error) {
-	if m.CountRecentByUserIDFunc != nil {
-		return m.CountRecentByUserIDFunc(userID, since)
+func (m *MockImportSummaryRepo) GetByAnalysisID(analysisID, userID string) (*models.ImportSummary, error) {
+	if m.GetByAnalysisIDFunc != nil {
+		return m.GetByAnalysisIDFunc(analysisID, userID)
+	}
+	return nil, repository.ErrImportSummaryNotFound
+}
+
+// MockUsageRepo is a mock implementation of UsageRepository for testing
+type MockUsageRepo struct {
+	IncrementFunc func(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error)
+	GetDayFunc    func(userID string, day time.Time) (map[models.UsageKind]int, error)
+	TotalsFunc    func(day time.Time) ([]models.UsageTotal, error)
+}
+
+func (m *MockUsageRepo) Increment(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error) {
+	if m.IncrementFunc != nil {
+		return m.IncrementFunc(userID, kind, day, limit)
+	}
+	return true, nil
+}
+
+func (m *MockUsageRepo) GetDay(userID string, day time.Time) (map[models.UsageKind]int, error) {
+	if m.GetDayFunc != nil {
+		return m.GetDayFunc(userID, day)
+	}
+	return map[models.UsageKind]int{}, nil
+}
+
+func (m *MockUsageRepo) Totals(day time.Time) ([]models.UsageTotal, error) {
+	if m.TotalsFunc != nil {
+		return m.TotalsFunc(day)
+	}
+	return nil, nil
+}
+
+// MockNotificationRepo is a mock implementation of NotificationRepository for testing
+type MockNotificationRepo struct {
+	GetPreferencesFunc         func(userID string) ([]models.NotificationPreference, error)
+	SetPreferencesFunc         func(userID string, prefs []models.NotificationPreference) error
+	GetWebhookFunc             func(userID string) (*models.NotificationWebhook, error)
+	SetWebhookFunc             func(userID, url, secret string) (*models.NotificationWebhook, error)
+	DeleteWebhookFunc          func(userID string) error
+	AddPushSubscriptionFunc    func(userID string, sub models.PushSubscription) error
+	ListPushSubscriptionsFunc  func(userID string) ([]models.PushSubscription, error)
+	DeletePushSubscriptionFunc func(userID, endpoint string) error
+}
+
+func (m *MockNotificationRepo) GetPreferences(userID string) ([]models.NotificationPreference, error) {
+	if m.GetPreferencesFunc != nil {
+		return m.GetPreferencesFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockNotificationRepo) SetPreferences(userID string, prefs []models.NotificationPreference) error {
+	if m.SetPreferencesFunc != nil {
+		return m.SetPreferencesFunc(userID, prefs)
+	}
+	return nil
+}
+
+func (m *MockNotificationRepo) GetWebhook(userID string) (*models.NotificationWebhook, error) {
+	if m.GetWebhookFunc != nil {
+		return m.GetWebhookFunc(userID)
+	}
+	return nil, repository.ErrNotificationWebhookNotFound
+}
+
+func (m *MockNotificationRepo) SetWebhook(userID, url, secret string) (*models.NotificationWebhook, error) {
+	if m.SetWebhookFunc != nil {
+		return m.SetWebhookFunc(userID, url, secret)
+	}
+	return &models.NotificationWebhook{URL: url, Secret: secret, CreatedAt: time.Now()}, nil
+}
+
+func (m *MockNotificationRepo) DeleteWebhook(userID string) error {
+	if m.DeleteWebhookFunc != nil {
+		return m.DeleteWebhookFunc(userID)
+	}
+	return nil
+}
+
+func (m *MockNotificationRepo) AddPushSubscription(userID string, sub models.PushSubscription) error {
+	if m.AddPushSubscriptionFunc != nil {
+		return m.AddPushSubscriptionFunc(userID, sub)
+	}
+	return nil
+}
+
+func (m *MockNotificationRepo) ListPushSubscriptions(userID string) ([]models.PushSubscription, error) {
+	if m.ListPushSubscriptionsFunc != nil {
+		return m.ListPushSubscriptionsFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockNotificationRepo) DeletePushSubscription(userID, endpoint string) error {
+	if m.DeletePushSubscriptionFunc != nil {
+		return m.DeletePushSubscriptionFunc(userID, endpoint)
+	}
+	return nil
+}
+
+// MockDismissedMistakeRepo is a mock implementation of DismissedMistakeRepository for testing
+type MockDismissedMistakeRepo struct {
+	DismissFunc      func(userID, fen, playedMove string) error
+	GetDismissedFunc func(userID string) (map[string]bool, error)
+	ListFunc         func(userID string) ([]models.DismissedMistake, error)
+	RestoreFunc      func(userID, fen, playedMove string) error
+}
+
+func (m *MockDismissedMistakeRepo) Dismiss(userID, fen, playedMove string) error {
+	if m.DismissFunc != nil {
+		return m.DismissFunc(userID, fen, playedMove)
+	}
+	return nil
+}
+
+func (m *MockDismissedMistakeRepo) GetDismissed(userID string) (map[string]bool, error) {
+	if m.GetDismissedFunc != nil {
+		return m.GetDismissedFunc(userID)
+	}
+	return map[string]bool{}, nil
+}
+
+func (m *MockDismissedMistakeRepo) List(userID string) ([]models.DismissedMistake, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(userID)
+	}
+	return []models.DismissedMistake{}, nil
+}
+
+func (m *MockDismissedMistakeRepo) Restore(userID, fen, playedMove string) error {
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(userID, fen, playedMove)
+	}
+	return nil
+}
+
+// MockTemplateRepo is a mock implementation of TemplateRepository for testing
+type MockTemplateRepo struct {
+	ListFunc          func(userID string) ([]models.RepertoireTemplate, error)
+	GetByIDFunc       func(id string) (*models.RepertoireTemplate, error)
+	CreateFunc        func(tmpl models.RepertoireTemplate) (*models.RepertoireTemplate, error)
+	SetFeaturedFunc   func(id string, featured bool) (*models.RepertoireTemplate, error)
+	EnsureBuiltinFunc func(templates []models.RepertoireTemplate) error
+}
+
+func (m *MockTemplateRepo) List(userID string) ([]models.RepertoireTemplate, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(userID)
+	}
+	return []models.RepertoireTemplate{}, nil
+}
+
+func (m *MockTemplateRepo) GetByID(id string) (*models.RepertoireTemplate, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrTemplateNotFound
+}
+
+func (m *MockTemplateRepo) Create(tmpl models.RepertoireTemplate) (*models.RepertoireTemplate, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(tmpl)
+	}
+	return &tmpl, nil
+}
+
+func (m *MockTemplateRepo) SetFeatured(id string, featured bool) (*models.RepertoireTemplate, error) {
+	if m.SetFeaturedFunc != nil {
+		return m.SetFeaturedFunc(id, featured)
+	}
+	return nil, repository.ErrTemplateNotFound
+}
+
+func (m *MockTemplateRepo) EnsureBuiltin(templates []models.RepertoireTemplate) error {
+	if m.EnsureBuiltinFunc != nil {
+		return m.EnsureBuiltinFunc(templates)
+	}
+	return nil
+}
+
+// MockCollaboratorRepo is a mock implementation of CollaboratorRepository for testing
+type MockCollaboratorRepo struct {
+	UpsertFunc           func(repertoireID, userID string, role models.CollaboratorRole, invitedBy string) (*models.RepertoireCollaborator, error)
+	GetFunc              func(repertoireID, userID string) (*models.RepertoireCollaborator, error)
+	ListByRepertoireFunc func(repertoireID string) ([]models.RepertoireCollaborator, error)
+	ListByUserFunc       func(userID string) ([]models.RepertoireCollaborator, error)
+	DeleteFunc           func(repertoireID, userID string) error
+}
+
+func (m *MockCollaboratorRepo) Upsert(repertoireID, userID string, role models.CollaboratorRole, invitedBy string) (*models.RepertoireCollaborator, error) {
+	if m.UpsertFunc != nil {
+		return m.UpsertFunc(repertoireID, userID, role, invitedBy)
+	}
+	return &models.RepertoireCollaborator{RepertoireID: repertoireID, UserID: userID, Role: role}, nil
+}
+
+func (m *MockCollaboratorRepo) Get(repertoireID, userID string) (*models.RepertoireCollaborator, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(repertoireID, userID)
+	}
+	return nil, repository.ErrCollaboratorNotFound
+}
+
+func (m *MockCollaboratorRepo) ListByRepertoire(repertoireID string) ([]models.RepertoireCollaborator, error) {
+	if m.ListByRepertoireFunc != nil {
+		return m.ListByRepertoireFunc(repertoireID)
+	}
+	return []models.RepertoireCollaborator{}, nil
+}
+
+func (m *MockCollaboratorRepo) ListByUser(userID string) ([]models.RepertoireCollaborator, error) {
+	if m.ListByUserFunc != nil {
+		return m.ListByUserFunc(userID)
+	}
+	return []models.RepertoireCollaborator{}, nil
+}
+
+func (m *MockCollaboratorRepo) Delete(repertoireID, userID string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(repertoireID, userID)
+	}
+	return nil
+}
+
+// MockSessionRepo is a mock implementation of SessionRepository for testing
+type MockSessionRepo struct {
+	CreateFunc     func(userID string, device models.SessionDevice, expiresAt time.Time) (*models.Session, error)
+	TouchFunc      func(id string, seenAt time.Time) error
+	ListActiveFunc func(userID string, now time.Time) ([]models.Session, error)
+	RevokeFunc     func(id, userID string, revokedAt time.Time) error
+}
+
+func (m *MockSessionRepo) Create(userID string, device models.SessionDevice, expiresAt time.Time) (*models.Session, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, device, expiresAt)
+	}
+	return &models.Session{ID: "session-1", UserID: userID, UserAgent: device.UserAgent, IP: device.IP, ExpiresAt: expiresAt}, nil
+}
+
+func (m *MockSessionRepo) Touch(id string, seenAt time.Time) error {
+	if m.TouchFunc != nil {
+		return m.TouchFunc(id, seenAt)
+	}
+	return nil
+}
+
+func (m *MockSessionRepo) ListActive(userID string, now time.Time) ([]models.Session, error) {
+	if m.ListActiveFunc != nil {
+		return m.ListActiveFunc(userID, now)
+	}
+	return []models.Session{}, nil
+}
+
+func (m *MockSessionRepo) Revoke(id, userID string, revokedAt time.Time) error {
+	if m.RevokeFunc != nil {
+		return m.RevokeFunc(id, userID, revokedAt)
+	}
+	return nil
+}
+
+// MockAPITokenRepo is a mock implementation of APITokenRepository for testing
+type MockAPITokenRepo struct {
+	CreateFunc      func(userID, name, scope, prefix, tokenHash string) (*models.APIToken, error)
+	UseFunc         func(tokenHash string, usedAt time.Time) (*models.APIToken, error)
+	ListByUserFunc  func(userID string) ([]models.APIToken, error)
+	CountByUserFunc func(userID string) (int, error)
+	RevokeFunc      func(id, userID string, revokedAt time.Time) error
+}
+
+func (m *MockAPITokenRepo) Create(userID, name, scope, prefix, tokenHash string) (*models.APIToken, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, name, scope, prefix, tokenHash)
+	}
+	return &models.APIToken{ID: "token-1", UserID: userID, Name: name, Scope: scope, Prefix: prefix}, nil
+}
+
+func (m *MockAPITokenRepo) Use(tokenHash string, usedAt time.Time) (*models.APIToken, error) {
+	if m.UseFunc != nil {
+		return m.UseFunc(tokenHash, usedAt)
+	}
+	return nil, repository.ErrAPITokenNotFound
+}
+
+func (m *MockAPITokenRepo) ListByUser(userID string) ([]models.APIToken, error) {
+	if m.ListByUserFunc != nil {
+		return m.ListByUserFunc(userID)
+	}
+	return []models.APIToken{}, nil
+}
+
+func (m *MockAPITokenRepo) CountByUser(userID string) (int, error) {
+	if m.CountByUserFunc != nil {
+		return m.CountByUserFunc(userID)
+	}
+	return 0, nil
+}
+
+func (m *MockAPITokenRepo) Revoke(id, userID string, revokedAt time.Time) error {
+	if m.RevokeFunc != nil {
+		return m.RevokeFunc(id, userID, revokedAt)
+	}
+	return nil
+}
+
+// MockTrainingRepo is a mock implementation of TrainingRepository for testing
+type MockTrainingRepo struct {
+	RecordAnswerFunc func(userID, repertoireID string, correct bool, at time.Time, sessionGap time.Duration) (*models.TrainingSession, error)
+	GetDaysFunc      func(userID string) ([]models.TrainingDay, error)
+	GetRecallFunc    func(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error)
+}
+
+func (m *MockTrainingRepo) RecordAnswer(userID, repertoireID string, correct bool, at time.Time, sessionGap time.Duration) (*models.TrainingSession, error) {
+	if m.RecordAnswerFunc != nil {
+		return m.RecordAnswerFunc(userID, repertoireID, correct, at, sessionGap)
+	}
+	session := &models.TrainingSession{ID: "session-123", RepertoireID: repertoireID, StartedAt: at, LastAnswerAt: at, Reviews: 1}
+	if correct {
+		session.Correct = 1
+	}
+	return session, nil
+}
+
+func (m *MockTrainingRepo) GetDays(userID string) ([]models.TrainingDay, error) {
+	if m.GetDaysFunc != nil {
+		return m.GetDaysFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockTrainingRepo) GetRecall(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error) {
+	if m.GetRecallFunc != nil {
+		return m.GetRecallFunc(userID, repertoireID, since)
+	}
+	return &models.TrainingRecall{}, nil
+}
+
+// MockHealthRepo is a mock implementation of HealthRepository for testing
+type MockHealthRepo struct {
+	ListStaleFunc func(before time.Time, limit int) ([]repository.RepertoireOwner, error)
+	SaveFunc      func(repertoireID string, health models.RepertoireHealth) error
+	GetByUserFunc func(userID string) (map[string]models.RepertoireHealth, error)
+}
+
+func (m *MockHealthRepo) ListStale(before time.Time, limit int) ([]repository.RepertoireOwner, error) {
+	if m.ListStaleFunc != nil {
+		return m.ListStaleFunc(before, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockHealthRepo) Save(repertoireID string, health models.RepertoireHealth) error {
+	if m.SaveFunc != nil {
+		return m.SaveFunc(repertoireID, health)
+	}
+	return nil
+}
+
+func (m *MockHealthRepo) GetByUser(userID string) (map[string]models.RepertoireHealth, error) {
+	if m.GetByUserFunc != nil {
+		return m.GetByUserFunc(userID)
+	}
+	return map[string]models.RepertoireHealth{}, nil
+}
+
+// MockInsightSettingsRepo is a mock implementation of InsightSettingsRepository for testing
+type MockInsightSettingsRepo struct {
+	GetFunc  func(userID string) (*models.InsightSettings, error)
+	SaveFunc func(userID string, settings models.InsightSettings) error
+}
+
+func (m *MockInsightSettingsRepo) Get(userID string) (*models.InsightSettings, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(userID)
+	}
+	return nil, repository.ErrInsightSettingsNotFound
+}
+
+func (m *MockInsightSettingsRepo) Save(userID string, settings models.InsightSettings) error {
+	if m.SaveFunc != nil {
+		return m.SaveFunc(userID, settings)
+	}
+	return nil
+}
+
+// MockClassificationRuleRepo is a mock implementation of ClassificationRuleRepository for testing
+type MockClassificationRuleRepo struct {
+	CreateFunc        func(userID string, rule models.ClassificationRule) (*models.ClassificationRule, error)
+	GetByUserFunc     func(userID string) ([]models.ClassificationRule, error)
+	CountByUserFunc   func(userID string) (int, error)
+	UpdateFunc        func(id string, rule models.ClassificationRule) (*models.ClassificationRule, error)
+	DeleteFunc        func(id string) error
+	BelongsToUserFunc func(id, userID string) (bool, error)
+}
+
+func (m *MockClassificationRuleRepo) Create(userID string, rule models.ClassificationRule) (*models.ClassificationRule, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(userID, rule)
+	}
+	rule.ID = "rule-1"
+	rule.UserID = userID
+	return &rule, nil
+}
+
+func (m *MockClassificationRuleRepo) GetByUser(userID string) ([]models.ClassificationRule, error) {
+	if m.GetByUserFunc != nil {
+		return m.GetByUserFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockClassificationRuleRepo) CountByUser(userID string) (int, error) {
+	if m.CountByUserFunc != nil {
+		return m.CountByUserFunc(userID)
 	}
 	return 0, nil
 }
+
+func (m *MockClassificationRuleRepo) Update(id string, rule models.ClassificationRule) (*models.ClassificationRule, error) {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(id, rule)
+	}
+	rule.ID = id
+	return &rule, nil
+}
+
+func (m *MockClassificationRuleRepo) Delete(id string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return nil
+}
+
+func (m *MockClassificationRuleRepo) BelongsToUser(id, userID string) (bool, error) {
+	if m.BelongsToUserFunc != nil {
+		return m.BelongsToUserFunc(id, userID)
+	}
+	return true, nil
+}
+
+// MockCoachLinkRepo is a mock implementation of CoachLinkRepository for testing
+type MockCoachLinkRepo struct {
+	CreateFunc     func(coachID, studentID, invitedBy string) (*models.CoachLink, error)
+	GetByIDFunc    func(id string) (*models.CoachLink, error)
+	ListByUserFunc func(userID string) ([]models.CoachLink, error)
+	AcceptFunc     func(id string, at time.Time) (*models.CoachLink, error)
+	DeleteFunc     func(id string) error
+	IsActiveFunc   func(coachID, studentID string) (bool, error)
+}
+
+func (m *MockCoachLinkRepo) Create(coachID, studentID, invitedBy string) (*models.CoachLink, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(coachID, studentID, invitedBy)
+	}
+	return &models.CoachLink{ID: "link-123", CoachID: coachID, StudentID: studentID, InvitedBy: invitedBy, Status: models.CoachLinkPending}, nil
+}
+
+func (m *MockCoachLinkRepo) GetByID(id string) (*models.CoachLink, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(id)
+	}
+	return nil, repository.ErrCoachLinkNotFound
+}
+
+func (m *MockCoachLinkRepo) ListByUser(userID string) ([]models.CoachLink, error) {
+	if m.ListByUserFunc != nil {
+		return m.ListByUserFunc(userID)
+	}
+	return []models.CoachLink{}, nil
+}
+
+func (m *MockCoachLinkRepo) Accept(id string, at time.Time) (*models.CoachLink, error) {
+	if m.AcceptFunc != nil {
+		return m.AcceptFunc(id, at)
+	}
+	return &models.CoachLink{ID: id, Status: models.CoachLinkActive, AcceptedAt: &at}, nil
+}
+
+func (m *MockCoachLinkRepo) Delete(id string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return nil
+}
+
+func (m *MockCoachLinkRepo) IsActive(coachID, studentID string) (bool, error) {
+	if m.IsActiveFunc != nil {
+		return m.IsActiveFunc(coachID, studentID)
+	}
+	return false, nil
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	getNotificationPreferencesSQL = `
+		SELECT event, email, webhook, push
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+	upsertNotificationPreferenceSQL = `
+		INSERT INTO notification_preferences (user_id, event, email, webhook, push)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, event) DO UPDATE SET email = $3, webhook = $4, push = $5
+	`
+	getNotificationWebhookSQL = `
+		SELECT url, secret, created_at
+		FROM notification_webhooks
+		WHERE user_id = $1
+	`
+	setNotificationWebhookSQL = `
+		INSERT INTO notification_webhooks (user_id, url, secret)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET url = $2, secret = $3, created_at = NOW()
+		RETURNING url, secret, created_at
+	`
+	deleteNotificationWebhookSQL = `DELETE FROM notification_webhooks WHERE user_id = $1`
+	// A browser subscribing again under another account moves its subscription over
+	addPushSubscriptionSQL = `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (endpoint) DO UPDATE SET user_id = $1, p256dh = $3, auth = $4
+	`
+	listPushSubscriptionsSQL = `
+		SELECT endpoint, p256dh, auth
+		FROM push_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	deletePushSubscriptionSQL = `DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`
+)
+
+// PostgresNotificationRepo implements NotificationRepository using PostgreSQL
+type PostgresNotificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresNotificationRepo creates a new PostgreSQL notification repository
+func NewPostgresNotificationRepo(pool *pgxpool.Pool) *PostgresNotificationRepo {
+	return &PostgresNotificationRepo{pool: pool}
+}
+
+// GetPreferences returns the preferences a user saved; events they never set are left out
+func (r *PostgresNotificationRepo) GetPreferences(userID string) ([]models.NotificationPreference, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getNotificationPreferencesSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	var prefs []models.NotificationPreference
+	for rows.Next() {
+		var p models.NotificationPreference
+		var event string
+		if err := rows.Scan(&event, &p.Email, &p.Webhook, &p.Push); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		p.Event = models.NotificationEvent(event)
+		prefs = append(prefs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// SetPreferences saves the preferences of the given events, leaving the other events as they are
+func (r *PostgresNotificationRepo) SetPreferences(userID string, prefs []models.NotificationPreference) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, p := range prefs {
+		if _, err := tx.Exec(ctx, upsertNotificationPreferenceSQL, userID, string(p.Event), p.Email, p.Webhook, p.Push); err != nil {
+			return fmt.Errorf("failed to save notification preference: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit notification preferences: %w", err)
+	}
+	return nil
+}
+
+// GetWebhook returns the webhook of a user, or ErrNotificationWebhookNotFound
+func (r *PostgresNotificationRepo) GetWebhook(userID string) (*models.NotificationWebhook, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var w models.NotificationWebhook
+	err := r.pool.QueryRow(ctx, getNotificationWebhookSQL, userID).Scan(&w.URL, &w.Secret, &w.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotificationWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification webhook: %w", err)
+	}
+	return &w, nil
+}
+
+// SetWebhook sets or replaces the webhook of a user
+func (r *PostgresNotificationRepo) SetWebhook(userID, url, secret string) (*models.NotificationWebhook, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var w models.NotificationWebhook
+	err := r.pool.QueryRow(ctx, setNotificationWebhookSQL, userID, url, secret).Scan(&w.URL, &w.Secret, &w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set notification webhook: %w", err)
+	}
+	return &w, nil
+}
+
+// DeleteWebhook removes the webhook of a user, or returns ErrNotificationWebhookNotFound
+func (r *PostgresNotificationRepo) DeleteWebhook(userID string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, deleteNotificationWebhookSQL, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotificationWebhookNotFound
+	}
+	return nil
+}
+
+// AddPushSubscription stores a browser's push subscription for a user
+func (r *PostgresNotificationRepo) AddPushSubscription(userID string, sub models.PushSubscription) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, addPushSubscriptionSQL, userID, sub.Endpoint, sub.Keys.P256dh, sub.Keys.Auth); err != nil {
+		return fmt.Errorf("failed to add push subscription: %w", err)
+	}
+	return nil
+}
+
+// ListPushSubscriptions returns the push subscriptions of a user, oldest first
+func (r *PostgresNotificationRepo) ListPushSubscriptions(userID string) ([]models.PushSubscription, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listPushSubscriptionsSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []models.PushSubscription
+	for rows.Next() {
+		var sub models.PushSubscription
+		if err := rows.Scan(&sub.Endpoint, &sub.Keys.P256dh, &sub.Keys.Auth); err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating push subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// DeletePushSubscription removes a push subscription of a user, or returns ErrPushSubscriptionNotFound
+func (r *PostgresNotificationRepo) DeletePushSubscription(userID, endpoint string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, deletePushSubscriptionSQL, userID, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import "fmt"
+
+const (
+	listUnlabeledRepertoiresSQL = `
+		SELECT id
+		FROM repertoires
+		WHERE opening_labels_version < $1 AND NOT archived
+		ORDER BY updated_at
+		LIMIT $2
+	`
+	markOpeningsLabeledSQL = `
+		UPDATE repertoires
+		SET opening_labels_version = $2
+		WHERE id = $1
+	`
+)
+
+// ListUnlabeled returns the IDs of unarchived repertoires whose opening labels predate labelsVersion,
+// least recently updated first
+func (r *PostgresRepertoireRepo) ListUnlabeled(labelsVersion, limit int) ([]string, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listUnlabeledRepertoiresSQL, labelsVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unlabeled repertoires: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unlabeled repertoires: %w", err)
+	}
+	return ids, nil
+}
+
+// MarkOpeningsLabeled records the version of the labeling applied to the tree of a repertoire
+func (r *PostgresRepertoireRepo) MarkOpeningsLabeled(id string, labelsVersion int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, markOpeningsLabeledSQL, id, labelsVersion); err != nil {
+		return fmt.Errorf("failed to mark repertoire labeled: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	insertOpponentReplySQL = `
+		INSERT INTO opponent_replies (analysis_id, game_index, ply, user_id, fen, move, opponent_rating, outcome)
+		SELECT analysis_id, game_index, $3, user_id, $4, $5, NULLIF($6, 0), NULLIF($7, '')
+		FROM games
+		WHERE analysis_id = $1 AND game_index = $2
+		ON CONFLICT (analysis_id, game_index, ply) DO NOTHING
+	`
+	// A bound of 0 disables it; games whose opponent rating is unknown only count without bounds
+	aggregateOpponentRepliesSQL = `
+		SELECT move,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE outcome = 'win'),
+			COUNT(*) FILTER (WHERE outcome = 'draw'),
+			COUNT(*) FILTER (WHERE outcome = 'loss'),
+			COALESCE(ROUND(AVG(opponent_rating)), 0)::int
+		FROM opponent_replies
+		WHERE user_id = $1 AND fen = $2
+		  AND ($3 = 0 OR opponent_rating >= $3)
+		  AND ($4 = 0 OR opponent_rating <= $4)
+		GROUP BY move
+		ORDER BY COUNT(*) DESC, move
+	`
+)
+
+// PostgresOpponentReplyRepo implements OpponentReplyRepository using PostgreSQL
+type PostgresOpponentReplyRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresOpponentReplyRepo creates a new PostgresOpponentReplyRepo
+func NewPostgresOpponentReplyRepo(pool *pgxpool.Pool) *PostgresOpponentReplyRepo {
+	return &PostgresOpponentReplyRepo{pool: pool}
+}
+
+// SaveBatch records the opponents' moves of newly saved games
+func (r *PostgresOpponentReplyRepo) SaveBatch(analysisID string, entries []OpponentReplyEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	for _, e := range entries {
+		batch.Queue(insertOpponentReplySQL, analysisID, e.GameIndex, e.Ply, e.FEN, e.Move, e.OpponentRating, e.Outcome)
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save opponent replies: %w", err)
+	}
+	return nil
+}
+
+// Aggregate counts the opponents' replies in a position across the user's games, within the rating bounds
+func (r *PostgresOpponentReplyRepo) Aggregate(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, aggregateOpponentRepliesSQL, userID, fen, minRating, maxRating)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query opponent replies: %w", err)
+	}
+	defer rows.Close()
+
+	replies := []models.OpponentReply{}
+	for rows.Next() {
+		var reply models.OpponentReply
+		if err := rows.Scan(&reply.Move, &reply.Games, &reply.Wins, &reply.Draws, &reply.Losses, &reply.AverageRating); err != nil {
+			return nil, fmt.Errorf("failed to scan opponent reply: %w", err)
+		}
+		replies = append(replies, reply)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating opponent replies: %w", err)
+	}
+	return replies, nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	prepRequestColumns = `id, repertoire_id, user_id, node_id, fen, depth, status, suggestions,
+		COALESCE(error, ''), created_at, updated_at`
+
+	createPrepRequestSQL = `
+		INSERT INTO prep_requests (user_id, repertoire_id, node_id, fen, depth)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + prepRequestColumns
+	getPrepRequestSQL = `
+		SELECT ` + prepRequestColumns + `
+		FROM prep_requests
+		WHERE id = $1
+	`
+	listPrepRequestsByNodeSQL = `
+		SELECT ` + prepRequestColumns + `
+		FROM prep_requests
+		WHERE repertoire_id = $1 AND node_id = $2
+		ORDER BY created_at DESC
+	`
+	// Claimed requests are skipped by other workers; a request left running by a crashed
+	// worker stays running until the user asks again
+	claimPrepRequestsSQL = `
+		UPDATE prep_requests SET status = 'running', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM prep_requests
+			WHERE status = 'pending'
+			ORDER BY created_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + prepRequestColumns
+	completePrepRequestSQL = `
+		UPDATE prep_requests SET status = 'done', suggestions = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	failPrepRequestSQL = `
+		UPDATE prep_requests SET status = 'failed', error = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	removePrepSuggestionSQL = `
+		UPDATE prep_requests SET
+			suggestions = (
+				SELECT COALESCE(jsonb_agg(s), '[]'::jsonb)
+				FROM jsonb_array_elements(suggestions) s
+				WHERE s->>'id' <> $2
+			),
+			updated_at = NOW()
+		WHERE id = $1 AND suggestions @> jsonb_build_array(jsonb_build_object('id', $2::text))
+	`
+)
+
+// PostgresPrepRepo implements PrepRepository using PostgreSQL
+type PostgresPrepRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresPrepRepo creates a new PostgreSQL prep request repository
+func NewPostgresPrepRepo(pool *pgxpool.Pool) *PostgresPrepRepo {
+	return &PostgresPrepRepo{pool: pool}
+}
+
+func scanPrepRequest(row pgx.Row) (*models.PrepRequest, error) {
+	var p models.PrepRequest
+	var suggestionsJSON []byte
+	if err := row.Scan(&p.ID, &p.RepertoireID, &p.UserID, &p.NodeID, &p.FEN, &p.Depth, &p.Status,
+		&suggestionsJSON, &p.Error, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(suggestionsJSON, &p.Suggestions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal prep suggestions: %w", err)
+	}
+	return &p, nil
+}
+
+func scanPrepRequests(rows pgx.Rows) ([]models.PrepRequest, error) {
+	defer rows.Close()
+
+	var requests []models.PrepRequest
+	for rows.Next() {
+		p, err := scanPrepRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prep request: %w", err)
+		}
+		requests = append(requests, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating prep requests: %w", err)
+	}
+	return requests, nil
+}
+
+// Create queues a pending preparation of a node
+func (r *PostgresPrepRepo) Create(userID, repertoireID, nodeID, fen string, depth int) (*models.PrepRequest, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	p, err := scanPrepRequest(r.pool.QueryRow(ctx, createPrepRequestSQL, userID, repertoireID, nodeID, fen, depth))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prep request: %w", err)
+	}
+	return p, nil
+}
+
+// GetByID returns a prep request by ID
+func (r *PostgresPrepRepo) GetByID(id string) (*models.PrepRequest, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	p, err := scanPrepRequest(r.pool.QueryRow(ctx, getPrepRequestSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPrepRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to get prep request: %w", err)
+	}
+	return p, nil
+}
+
+// ListByNode returns the prep requests of a node, newest first
+func (r *PostgresPrepRepo) ListByNode(repertoireID, nodeID string) ([]models.PrepRequest, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listPrepRequestsByNodeSQL, repertoireID, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prep requests: %w", err)
+	}
+	return scanPrepRequests(rows)
+}
+
+// ClaimPending marks up to limit pending requests as running and returns them, oldest first
+func (r *PostgresPrepRepo) ClaimPending(limit int) ([]models.PrepRequest, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, claimPrepRequestsSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim prep requests: %w", err)
+	}
+	return scanPrepRequests(rows)
+}
+
+// Complete stores the suggestions of a finished request
+func (r *PostgresPrepRepo) Complete(id string, suggestions []models.PrepSuggestion) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if suggestions == nil {
+		suggestions = []models.PrepSuggestion{}
+	}
+	suggestionsJSON, err := json.Marshal(suggestions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prep suggestions: %w", err)
+	}
+
+	if _, err := r.pool.Exec(ctx, completePrepRequestSQL, id, suggestionsJSON); err != nil {
+		return fmt.Errorf("failed to complete prep request: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed marks a request as failed with the reason
+func (r *PostgresPrepRepo) MarkFailed(id, message string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, failPrepRequestSQL, id, message); err != nil {
+		return fmt.Errorf("failed to mark prep request failed: %w", err)
+	}
+	return nil
+}
+
+// RemoveSuggestion drops one suggestion from a request, once accepted or discarded
+func (r *PostgresPrepRepo) RemoveSuggestion(id, suggestionID string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, removePrepSuggestionSQL, id, suggestionID)
+	if err != nil {
+		return fmt.Errorf("failed to remove prep suggestion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPrepSuggestionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	// Games archived before keep their first PGN: the archive is append-only
+	appendRawGameSQL = `
+		INSERT INTO raw_games (user_id, fingerprint, pgn, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, fingerprint) DO NOTHING
+	`
+	countRawGamesSQL = `
+		SELECT COUNT(*) FROM raw_games WHERE $1 = '' OR user_id::TEXT = $1
+	`
+	listRawGamesSQL = `
+		SELECT user_id, fingerprint, pgn, source, imported_at
+		FROM raw_games
+		WHERE ($1 = '' OR user_id::TEXT = $1)
+			AND (user_id::TEXT, fingerprint) > ($2, $3)
+		ORDER BY user_id::TEXT, fingerprint
+		LIMIT $4
+	`
+)
+
+// PostgresRawGameRepo implements RawGameRepository using PostgreSQL
+type PostgresRawGameRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRawGameRepo creates a new PostgreSQL raw game archive repository
+func NewPostgresRawGameRepo(pool *pgxpool.Pool) *PostgresRawGameRepo {
+	return &PostgresRawGameRepo{pool: pool}
+}
+
+// Append archives the PGN of imported games. Games already archived for the user are left unchanged.
+func (r *PostgresRawGameRepo) Append(userID, source string, games []RawGameEntry) error {
+	if len(games) == 0 {
+		return nil
+	}
+
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	for _, game := range games {
+		batch.Queue(appendRawGameSQL, userID, game.Fingerprint, game.PGN, source)
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to archive raw games: %w", err)
+	}
+	return nil
+}
+
+// Count returns how many games are archived for a user, or for every user when userID is empty
+func (r *PostgresRawGameRepo) Count(userID string) (int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	if err := r.pool.QueryRow(ctx, countRawGamesSQL, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count raw games: %w", err)
+	}
+	return count, nil
+}
+
+// ListAfter returns up to limit archived games following after, ordered by user and fingerprint.
+// A zero key starts from the beginning.
+func (r *PostgresRawGameRepo) ListAfter(userID string, after RawGameKey, limit int) ([]models.RawGame, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listRawGamesSQL, userID, after.UserID, after.Fingerprint, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raw games: %w", err)
+	}
+	defer rows.Close()
+
+	var games []models.RawGame
+	for rows.Next() {
+		var game models.RawGame
+		if err := rows.Scan(&game.UserID, &game.Fingerprint, &game.PGN, &game.Source, &game.ImportedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan raw game: %w", err)
+		}
+		games = append(games, game)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating raw games: %w", err)
+	}
+	return games, nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	reanalysisJobColumns = `id, user_id, repertoire_id, status, total, processed, COALESCE(error, ''), created_at, updated_at`
+
+	createReanalysisJobSQL = `
+		INSERT INTO reanalysis_jobs (user_id, repertoire_id, status)
+		VALUES ($1, $2, 'pending')
+		RETURNING ` + reanalysisJobColumns
+	getReanalysisJobSQL = `
+		SELECT ` + reanalysisJobColumns + `
+		FROM reanalysis_jobs
+		WHERE id = $1
+	`
+	getPendingReanalysisJobsSQL = `
+		SELECT ` + reanalysisJobColumns + `
+		FROM reanalysis_jobs
+		WHERE status = 'pending'
+		ORDER BY created_at ASC
+		LIMIT $1
+	`
+)
+
+// PostgresReanalysisJobRepo implements ReanalysisJobRepository using PostgreSQL
+type PostgresReanalysisJobRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresReanalysisJobRepo creates a new PostgresReanalysisJobRepo
+func NewPostgresReanalysisJobRepo(pool *pgxpool.Pool) *PostgresReanalysisJobRepo {
+	return &PostgresReanalysisJobRepo{pool: pool}
+}
+
+func scanReanalysisJob(row pgx.Row) (*models.ReanalysisJob, error) {
+	var j models.ReanalysisJob
+	if err := row.Scan(&j.ID, &j.UserID, &j.RepertoireID, &j.Status, &j.Total, &j.Processed, &j.Error, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Create queues a pending re-analysis job for a repertoire
+func (r *PostgresReanalysisJobRepo) Create(userID, repertoireID string) (*models.ReanalysisJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	job, err := scanReanalysisJob(r.pool.QueryRow(ctx, createReanalysisJobSQL, userID, repertoireID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reanalysis job: %w", err)
+	}
+	return job, nil
+}
+
+// GetByID returns a re-analysis job by ID
+func (r *PostgresReanalysisJobRepo) GetByID(id string) (*models.ReanalysisJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	job, err := scanReanalysisJob(r.pool.QueryRow(ctx, getReanalysisJobSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrReanalysisJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get reanalysis job: %w", err)
+	}
+	return job, nil
+}
+
+// GetPending returns up to limit pending re-analysis jobs, oldest first
+func (r *PostgresReanalysisJobRepo) GetPending(limit int) ([]models.ReanalysisJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getPendingReanalysisJobsSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending reanalysis jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.ReanalysisJob
+	for rows.Next() {
+		job, err := scanReanalysisJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reanalysis job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// MarkProcessing marks a job as processing and records how many games it covers
+func (r *PostgresReanalysisJobRepo) MarkProcessing(id string, total int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE reanalysis_jobs SET status = 'processing', total = $2, processed = 0, updated_at = $3 WHERE id = $1`,
+		id, total, time.Now(),
+	)
+	return err
+}
+
+// UpdateProgress records how many games of a job have been re-analyzed
+func (r *PostgresReanalysisJobRepo) UpdateProgress(id string, processed int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE reanalysis_jobs SET processed = $2, updated_at = $3 WHERE id = $1`,
+		id, processed, time.Now(),
+	)
+	return err
+}
+
+// MarkDone marks a job as done
+func (r *PostgresReanalysisJobRepo) MarkDone(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE reanalysis_jobs SET status = 'done', updated_at = $2 WHERE id = $1`,
+		id, time.Now(),
+	)
+	return err
+}
+
+// MarkFailed marks a job as failed with the reason
+func (r *PostgresReanalysisJobRepo) MarkFailed(id string, message string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE reanalysis_jobs SET status = 'failed', error = $2, updated_at = $3 WHERE id = $1`,
+		id, message, time.Now(),
+	)
+	return err
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	recomputeJobColumns = `id, COALESCE(user_id::TEXT, ''), status, total, processed, recomputed, COALESCE(error, ''), created_at, updated_at`
+
+	createRecomputeJobSQL = `
+		INSERT INTO recompute_jobs (user_id, status)
+		VALUES (NULLIF($1, '')::UUID, 'pending')
+		RETURNING ` + recomputeJobColumns
+	getRecomputeJobSQL = `
+		SELECT ` + recomputeJobColumns + `
+		FROM recompute_jobs
+		WHERE id = $1
+	`
+	getPendingRecomputeJobsSQL = `
+		SELECT ` + recomputeJobColumns + `
+		FROM recompute_jobs
+		WHERE status = 'pending'
+		ORDER BY created_at ASC
+		LIMIT $1
+	`
+)
+
+// PostgresRecomputeJobRepo implements RecomputeJobRepository using PostgreSQL
+type PostgresRecomputeJobRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRecomputeJobRepo creates a new PostgresRecomputeJobRepo
+func NewPostgresRecomputeJobRepo(pool *pgxpool.Pool) *PostgresRecomputeJobRepo {
+	return &PostgresRecomputeJobRepo{pool: pool}
+}
+
+func scanRecomputeJob(row pgx.Row) (*models.RecomputeJob, error) {
+	var j models.RecomputeJob
+	if err := row.Scan(&j.ID, &j.UserID, &j.Status, &j.Total, &j.Processed, &j.Recomputed, &j.Error, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Create queues a pending recompute job for a user, or for every user when userID is empty
+func (r *PostgresRecomputeJobRepo) Create(userID string) (*models.RecomputeJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	job, err := scanRecomputeJob(r.pool.QueryRow(ctx, createRecomputeJobSQL, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recompute job: %w", err)
+	}
+	return job, nil
+}
+
+// GetByID returns a recompute job by ID
+func (r *PostgresRecomputeJobRepo) GetByID(id string) (*models.RecomputeJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	job, err := scanRecomputeJob(r.pool.QueryRow(ctx, getRecomputeJobSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrRecomputeJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get recompute job: %w", err)
+	}
+	return job, nil
+}
+
+// GetPending returns up to limit pending recompute jobs, oldest first
+func (r *PostgresRecomputeJobRepo) GetPending(limit int) ([]models.RecomputeJob, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getPendingRecomputeJobsSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending recompute jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.RecomputeJob
+	for rows.Next() {
+		job, err := scanRecomputeJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recompute job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// MarkProcessing marks a job as processing and records how many archived games it covers
+func (r *PostgresRecomputeJobRepo) MarkProcessing(id string, total int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE recompute_jobs SET status = 'processing', total = $2, processed = 0, recomputed = 0, updated_at = $3 WHERE id = $1`,
+		id, total, time.Now(),
+	)
+	return err
+}
+
+// UpdateProgress records how many archived games of a job have been processed and recomputed
+func (r *PostgresRecomputeJobRepo) UpdateProgress(id string, processed, recomputed int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE recompute_jobs SET processed = $2, recomputed = $3, updated_at = $4 WHERE id = $1`,
+		id, processed, recomputed, time.Now(),
+	)
+	return err
+}
+
+// MarkDone marks a job as done
+func (r *PostgresRecomputeJobRepo) MarkDone(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE recompute_jobs SET status = 'done', updated_at = $2 WHERE id = $1`,
+		id, time.Now(),
+	)
+	return err
+}
+
+// MarkFailed marks a job as failed with the reason
+func (r *PostgresRecomputeJobRepo) MarkFailed(id string, message string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE recompute_jobs SET status = 'failed', error = $2, updated_at = $3 WHERE id = $1`,
+		id, message, time.Now(),
+	)
+	return err
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	bumpRepertoireVersionSQL = `
+		UPDATE repertoires
+		SET metadata = $3, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING id, name, color, category_id, created_at, updated_at, version, match_active, archived, user_id
+	`
+	deleteTreeNodeSQL = `
+		UPDATE repertoires SET tree_data = tree_data #- $2::TEXT[] WHERE id = $1
+	`
+	// The children of the parent are set to an empty array first, as trees saved with a nil
+	// slice store null there
+	insertTreeNodeSQL = `
+		UPDATE repertoires
+		SET tree_data = jsonb_insert(
+			jsonb_set(tree_data, $2::TEXT[], COALESCE(NULLIF(tree_data #> $2::TEXT[], 'null'::JSONB), '[]'::JSONB)),
+			$3::TEXT[], $4::JSONB)
+		WHERE id = $1
+	`
+	// The node is replaced but keeps its stored children; jsonb_set cannot replace the root
+	updateTreeNodeSQL = `
+		UPDATE repertoires
+		SET tree_data = CASE
+			WHEN cardinality($2::TEXT[]) = 0 THEN $3::JSONB || jsonb_build_object('children', tree_data -> 'children')
+			ELSE jsonb_set(tree_data, $2::TEXT[], $3::JSONB || jsonb_build_object('children', tree_data #> ($2::TEXT[] || '{children}'::TEXT[])))
+		END
+		WHERE id = $1
+	`
+	deleteNodePositionsSQL = `
+		DELETE FROM repertoire_positions WHERE repertoire_id = $1 AND node_id = ANY($2)
+	`
+	deleteNodeCommentsSQL = `
+		DELETE FROM repertoire_comments WHERE repertoire_id = $1 AND node_id = ANY($2)
+	`
+)
+
+// NodeChanges lists the nodes an edit changed in a repertoire tree, so that SaveNodes writes
+// only those instead of the whole tree
+type NodeChanges struct {
+	// Added are the IDs of nodes added to the tree, each written with its subtree
+	Added []string
+	// Updated are the IDs of nodes whose own fields changed; their children are left as stored
+	Updated []string
+	// Deleted is the node removed from the tree, if any
+	Deleted *DeletedNode
+}
+
+// DeletedNode locates a node removed from a tree: the child of ParentID at Index, before it
+// was removed
+type DeletedNode struct {
+	ParentID string
+	Index    int
+	// NodeIDs are the IDs of the removed node and of its descendants
+	NodeIDs []string
+}
+
+// IsEmpty reports whether the edit changed nothing
+func (c NodeChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && c.Deleted == nil
+}
+
+// treeNodeWrite is one statement of SaveNodes, located by its JSON path in the stored tree
+type treeNodeWrite struct {
+	path []string
+	node *models.RepertoireNode
+}
+
+// SaveNodes writes the changed nodes of a tree, provided the stored tree is still at version.
+// The removal is applied first, then the added subtrees and the updated nodes, each located by
+// its path in tree. ErrRepertoireVersionConflict is returned when the tree was saved since it
+// was read, as the stored paths may no longer match.
+func (r *PostgresRepertoireRepo) SaveNodes(id string, version int, tree models.RepertoireNode, changes NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	added, updated, err := planNodeWrites(&tree, changes)
+	if err != nil {
+		return nil, err
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rep := models.Repertoire{TreeData: tree, Metadata: metadata}
+	var userID string
+	err = tx.QueryRow(ctx, bumpRepertoireVersionSQL, id, version, metadataJSON).Scan(
+		&rep.ID,
+		&rep.Name,
+		&rep.Color,
+		&rep.CategoryID,
+		&rep.CreatedAt,
+		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
+		&userID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRow(ctx, checkRepertoireExistsByIDSQL, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check repertoire existence: %w", err)
+		}
+		if !exists {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, ErrRepertoireVersionConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save repertoire: %w", err)
+	}
+
+	if changes.Deleted != nil {
+		if err := deleteTreeNode(ctx, tx, id, &tree, changes.Deleted); err != nil {
+			return nil, err
+		}
+	}
+	for _, write := range added {
+		nodeJSON, err := json.Marshal(write.node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal node: %w", err)
+		}
+		parentChildren := write.path[:len(write.path)-1]
+		if _, err := tx.Exec(ctx, insertTreeNodeSQL, id, parentChildren, write.path, nodeJSON); err != nil {
+			return nil, fmt.Errorf("failed to add node: %w", err)
+		}
+	}
+	for _, write := range updated {
+		fields := *write.node
+		fields.Children = nil
+		nodeJSON, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal node: %w", err)
+		}
+		if _, err := tx.Exec(ctx, updateTreeNodeSQL, id, write.path, nodeJSON); err != nil {
+			return nil, fmt.Errorf("failed to update node: %w", err)
+		}
+	}
+
+	if err := reindexNodes(ctx, tx, id, userID, &tree, changes.Deleted, added, updated); err != nil {
+		return nil, err
+	}
+	if err := recordStoredRevision(ctx, tx, id, rep.Version); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+	return &rep, nil
+}
+
+// planNodeWrites locates the added and updated nodes in the edited tree. An added node is
+// written with its subtree, so the changes listed inside it need no statement of their own.
+func planNodeWrites(root *models.RepertoireNode, changes NodeChanges) (added, updated []treeNodeWrite, err error) {
+	addedIDs := make(map[string]bool, len(changes.Added))
+	for _, nodeID := range changes.Added {
+		addedIDs[nodeID] = true
+	}
+	updatedIDs := make(map[string]bool, len(changes.Updated))
+	for _, nodeID := range changes.Updated {
+		updatedIDs[nodeID] = true
+	}
+	for _, ids := range [][]string{changes.Added, changes.Updated} {
+		for _, nodeID := range ids {
+			if findTreeNode(root, nodeID) == nil {
+				return nil, nil, fmt.Errorf("changed node %s missing from the tree", nodeID)
+			}
+		}
+	}
+
+	var walk func(node *models.RepertoireNode, path []string)
+	walk = func(node *models.RepertoireNode, path []string) {
+		if addedIDs[node.ID] {
+			added = append(added, treeNodeWrite{path: path, node: node})
+			return
+		}
+		if updatedIDs[node.ID] {
+			updated = append(updated, treeNodeWrite{path: path, node: node})
+		}
+		for i, child := range node.Children {
+			if child != nil {
+				walk(child, append(path[:len(path):len(path)], "children", strconv.Itoa(i)))
+			}
+		}
+	}
+	walk(root, []string{})
+	return added, updated, nil
+}
+
+func deleteTreeNode(ctx context.Context, tx pgx.Tx, repertoireID string, root *models.RepertoireNode, deleted *DeletedNode) error {
+	parentPath, ok := treeNodePath(root, deleted.ParentID)
+	if !ok {
+		return fmt.Errorf("parent of deleted node %s missing from the tree", deleted.ParentID)
+	}
+	path := append(parentPath, "children", strconv.Itoa(deleted.Index))
+	if _, err := tx.Exec(ctx, deleteTreeNodeSQL, repertoireID, path); err != nil {
+		return fmt.Errorf("failed to delete node: %w", err)
+	}
+	return nil
+}
+
+// reindexNodes updates the position and comment indexes for the changed nodes only
+func reindexNodes(ctx context.Context, tx pgx.Tx, repertoireID, userID string, root *models.RepertoireNode, deleted *DeletedNode, added, updated []treeNodeWrite) error {
+	var removedIDs []string
+	if deleted != nil {
+		removedIDs = deleted.NodeIDs
+	}
+	var positions, comments [][]interface{}
+	var commentIDs []string
+	for _, write := range added {
+		parentMoves := treeMovePath(root, write.path[:len(write.path)-2])
+		positions = append(positions, positionRows(repertoireID, userID, write.node)...)
+		comments = append(comments, commentRows(repertoireID, userID, write.node, parentMoves)...)
+	}
+	for _, write := range updated {
+		commentIDs = append(commentIDs, write.node.ID)
+		if write.node.Comment != nil && *write.node.Comment != "" {
+			comments = append(comments, []interface{}{repertoireID, write.node.ID, userID, treeMovePath(root, write.path), *write.node.Comment})
+		}
+	}
+
+	if len(removedIDs) > 0 {
+		if _, err := tx.Exec(ctx, deleteNodePositionsSQL, repertoireID, removedIDs); err != nil {
+			return fmt.Errorf("failed to clear node positions: %w", err)
+		}
+	}
+	if ids := append(removedIDs[:len(removedIDs):len(removedIDs)], commentIDs...); len(ids) > 0 {
+		if _, err := tx.Exec(ctx, deleteNodeCommentsSQL, repertoireID, ids); err != nil {
+			return fmt.Errorf("failed to clear node comments: %w", err)
+		}
+	}
+	if len(positions) > 0 {
+		if _, err := tx.CopyFrom(ctx,
+			pgx.Identifier{"repertoire_positions"},
+			[]string{"repertoire_id", "node_id", "user_id", "normalized_fen"},
+			pgx.CopyFromRows(positions),
+		); err != nil {
+			return fmt.Errorf("failed to index repertoire positions: %w", err)
+		}
+	}
+	if len(comments) > 0 {
+		if _, err := tx.CopyFrom(ctx,
+			pgx.Identifier{"repertoire_comments"},
+			[]string{"repertoire_id", "node_id", "user_id", "move_path", "comment"},
+			pgx.CopyFromRows(comments),
+		); err != nil {
+			return fmt.Errorf("failed to index repertoire comments: %w", err)
+		}
+	}
+	return nil
+}
+
+// treeNodePath returns the JSON path of a node in a tree
+func treeNodePath(root *models.RepertoireNode, nodeID string) ([]string, bool) {
+	if root.ID == nodeID {
+		return []string{}, true
+	}
+	for i, child := range root.Children {
+		if child == nil {
+			continue
+		}
+		if path, ok := treeNodePath(child, nodeID); ok {
+			return append([]string{"children", strconv.Itoa(i)}, path...), true
+		}
+	}
+	return nil, false
+}
+
+// treeMovePath returns the moves leading to the node at a JSON path
+func treeMovePath(root *models.RepertoireNode, path []string) []string {
+	moves := []string{}
+	node := root
+	for i := 1; i < len(path); i += 2 {
+		index, _ := strconv.Atoi(path[i])
+		node = node.Children[index]
+		if node.Move != nil {
+			moves = append(moves, *node.Move)
+		}
+	}
+	return moves
+}
+
+func findTreeNode(root *models.RepertoireNode, nodeID string) *models.RepertoireNode {
+	if root.ID == nodeID {
+		return root
+	}
+	for _, child := range root.Children {
+		if child == nil {
+			continue
+		}
+		if found := findTreeNode(child, nodeID); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+func nodeEditTestTree() models.RepertoireNode {
+	e4, e5, c5, nf3 := "e4", "e5", "c5", "Nf3"
+	return models.RepertoireNode{
+		ID: "root",
+		Children: []*models.RepertoireNode{
+			{ID: "e4", Move: &e4, Children: []*models.RepertoireNode{
+				{ID: "e5", Move: &e5, Children: []*models.RepertoireNode{
+					{ID: "nf3", Move: &nf3},
+				}},
+				{ID: "c5", Move: &c5},
+			}},
+		},
+	}
+}
+
+func TestPlanNodeWrites(t *testing.T) {
+	tree := nodeEditTestTree()
+
+	added, updated, err := planNodeWrites(&tree, NodeChanges{
+		Added:   []string{"e5", "nf3"},
+		Updated: []string{"root", "c5", "nf3"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, added, 1, "nodes inside an added subtree are written with it")
+	assert.Equal(t, "e5", added[0].node.ID)
+	assert.Equal(t, []string{"children", "0", "children", "0"}, added[0].path)
+
+	require.Len(t, updated, 2)
+	assert.Equal(t, []string{}, updated[0].path)
+	assert.Equal(t, []string{"children", "0", "children", "1"}, updated[1].path)
+}
+
+func TestPlanNodeWrites_UnknownNode(t *testing.T) {
+	tree := nodeEditTestTree()
+
+	_, _, err := planNodeWrites(&tree, NodeChanges{Updated: []string{"missing"}})
+
+	assert.Error(t, err)
+}
+
+func TestTreeNodePathAndMoves(t *testing.T) {
+	tree := nodeEditTestTree()
+
+	path, ok := treeNodePath(&tree, "nf3")
+	require.True(t, ok)
+	assert.Equal(t, []string{"children", "0", "children", "0", "children", "0"}, path)
+	assert.Equal(t, []string{"e4", "e5", "Nf3"}, treeMovePath(&tree, path))
+	assert.Equal(t, []string{}, treeMovePath(&tree, []string{}))
+
+	_, ok = treeNodePath(&tree, "missing")
+	assert.False(t, ok)
+}
+
+func TestCommentRows_StartFromParentPath(t *testing.T) {
+	tree := nodeEditTestTree()
+	comment := "Open game"
+	e5 := tree.Children[0].Children[0]
+	e5.Comment = &comment
+
+	rows := commentRows("rep-1", "user-1", e5, []string{"e4"})
+
+	require.Len(t, rows, 1)
+	assert.Equal(t, []interface{}{"rep-1", "e5", "user-1", []string{"e4", "e5"}, "Open game"}, rows[0])
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	studyNotesColumns = `repertoire_id, version, content, updated_by, created_at`
+
+	// Locking the repertoire serializes concurrent saves of its notes
+	lockRepertoireForNotesSQL = `
+		SELECT 1 FROM repertoires WHERE id = $1 FOR UPDATE
+	`
+	insertStudyNotesSQL = `
+		INSERT INTO repertoire_notes (repertoire_id, version, content, updated_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3
+		FROM repertoire_notes
+		WHERE repertoire_id = $1
+		RETURNING ` + studyNotesColumns
+	pruneStudyNotesSQL = `
+		DELETE FROM repertoire_notes
+		WHERE repertoire_id = $1 AND version <= $2
+	`
+	getLatestStudyNotesSQL = `
+		SELECT ` + studyNotesColumns + `
+		FROM repertoire_notes
+		WHERE repertoire_id = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	getStudyNotesSQL = `
+		SELECT ` + studyNotesColumns + `
+		FROM repertoire_notes
+		WHERE repertoire_id = $1 AND version = $2
+	`
+	listStudyNotesRevisionsSQL = `
+		SELECT version, char_length(content), updated_by, created_at
+		FROM repertoire_notes
+		WHERE repertoire_id = $1
+		ORDER BY version DESC
+	`
+)
+
+func scanStudyNotes(row pgx.Row) (*models.StudyNotes, error) {
+	var n models.StudyNotes
+	var updatedAt time.Time
+	if err := row.Scan(&n.RepertoireID, &n.Version, &n.Content, &n.UpdatedBy, &updatedAt); err != nil {
+		return nil, err
+	}
+	n.UpdatedAt = &updatedAt
+	return &n, nil
+}
+
+// GetNotes returns a version of the study notes of a repertoire, the newest one when version is 0
+func (r *PostgresRepertoireRepo) GetNotes(repertoireID string, version int) (*models.StudyNotes, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var row pgx.Row
+	if version == 0 {
+		row = r.pool.QueryRow(ctx, getLatestStudyNotesSQL, repertoireID)
+	} else {
+		row = r.pool.QueryRow(ctx, getStudyNotesSQL, repertoireID, version)
+	}
+	notes, err := scanStudyNotes(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStudyNotesNotFound
+		}
+		return nil, fmt.Errorf("failed to get study notes: %w", err)
+	}
+	return notes, nil
+}
+
+// SaveNotes stores the study notes of a repertoire as a new version, dropping the versions
+// beyond config.MaxStudyNotesRevisions
+func (r *PostgresRepertoireRepo) SaveNotes(repertoireID, userID, content string) (*models.StudyNotes, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int
+	if err := tx.QueryRow(ctx, lockRepertoireForNotesSQL, repertoireID).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to lock repertoire: %w", err)
+	}
+
+	notes, err := scanStudyNotes(tx.QueryRow(ctx, insertStudyNotesSQL, repertoireID, content, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save study notes: %w", err)
+	}
+	if _, err := tx.Exec(ctx, pruneStudyNotesSQL, repertoireID, notes.Version-config.MaxStudyNotesRevisions); err != nil {
+		return nil, fmt.Errorf("failed to prune study notes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return notes, nil
+}
+
+// ListNoteRevisions returns the kept versions of the study notes of a repertoire, newest first
+func (r *PostgresRepertoireRepo) ListNoteRevisions(repertoireID string) ([]models.StudyNotesRevision, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listStudyNotesRevisionsSQL, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list study notes revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.StudyNotesRevision{}
+	for rows.Next() {
+		var rev models.StudyNotesRevision
+		if err := rows.Scan(&rev.Version, &rev.Length, &rev.UpdatedBy, &rev.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan study notes revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating study notes revisions: %w", err)
+	}
+	return revisions, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	deleteRepertoirePositionsSQL = `
+		DELETE FROM repertoire_positions WHERE repertoire_id = $1
+	`
+	findRepertoirePositionsSQL = `
+		SELECT repertoire_id, node_id, normalized_fen
+		FROM repertoire_positions
+		WHERE user_id = $1 AND normalized_fen = ANY($2)
+	`
+)
+
+// PositionIndexKey is the form under which positions are stored in repertoire_positions:
+// piece placement, side to move and castling rights. The en passant square is left out
+// because move generators disagree on whether to record it after every double pawn push.
+func PositionIndexKey(fen string) string {
+	parts := strings.Fields(fen)
+	if len(parts) < 3 {
+		return fen
+	}
+	return strings.Join(parts[:3], " ") + " -"
+}
+
+// reindexPositions replaces the indexed positions of a repertoire with the nodes of its tree
+func reindexPositions(ctx context.Context, tx pgx.Tx, repertoireID, userID string, root models.RepertoireNode) error {
+	if _, err := tx.Exec(ctx, deleteRepertoirePositionsSQL, repertoireID); err != nil {
+		return fmt.Errorf("failed to clear repertoire positions: %w", err)
+	}
+
+	rows := positionRows(repertoireID, userID, &root)
+
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"repertoire_positions"},
+		[]string{"repertoire_id", "node_id", "user_id", "normalized_fen"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to index repertoire positions: %w", err)
+	}
+	return nil
+}
+
+// positionRows returns the position index rows of the nodes of a subtree
+func positionRows(repertoireID, userID string, root *models.RepertoireNode) [][]interface{} {
+	var rows [][]interface{}
+	var walk func(node *models.RepertoireNode)
+	walk = func(node *models.RepertoireNode) {
+		rows = append(rows, []interface{}{repertoireID, node.ID, userID, PositionIndexKey(node.FEN)})
+		for _, child := range node.Children {
+			if child != nil {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	return rows
+}
+
+// FindPositions returns the nodes of the user's repertoires reaching any of the given positions
+func (r *PostgresRepertoireRepo) FindPositions(userID string, fens []string) ([]models.RepertoirePosition, error) {
+	if len(fens) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	keys := make([]string, len(fens))
+	for i, fen := range fens {
+		keys[i] = PositionIndexKey(fen)
+	}
+
+	rows, err := r.pool.Query(ctx, findRepertoirePositionsSQL, userID, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repertoire positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []models.RepertoirePosition
+	for rows.Next() {
+		var p models.RepertoirePosition
+		if err := rows.Scan(&p.RepertoireID, &p.NodeID, &p.FEN); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating repertoire positions: %w", err)
+	}
+	return positions, nil
+}
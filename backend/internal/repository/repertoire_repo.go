@@ -15,30 +15,30 @@ import (
 
 const (
 	getRepertoireByIDSQL = `
-		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 		FROM repertoires
 		WHERE id = $1
 	`
 	getRepertoiresByColorSQL = `
-		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 		FROM repertoires
 		WHERE user_id = $1 AND color = $2
 		ORDER BY updated_at DESC
 	`
 	getAllRepertoiresSQL = `
-		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 		FROM repertoires
 		WHERE user_id = $1
 		ORDER BY color, updated_at DESC
 	`
 	getRepertoiresByCategorySQL = `
-		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 		FROM repertoires
 		WHERE category_id = $1
 		ORDER BY updated_at DESC
 	`
 	getUncategorizedRepertoiresSQL = `
-		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		SELECT id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 		FROM repertoires
 		WHERE user_id = $1 AND color = $2 AND category_id IS NULL
 		ORDER BY updated_at DESC
@@ -46,30 +46,49 @@ const (
 	createRepertoireSQL = `
 		INSERT INTO repertoires (id, user_id, name, color, tree_data, metadata)
 		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 	`
 	createRepertoireWithCategorySQL = `
 		INSERT INTO repertoires (id, user_id, name, color, category_id, tree_data, metadata)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 	`
 	updateRepertoireByIDSQL = `
 		UPDATE repertoires
-		SET tree_data = $2, metadata = $3, updated_at = NOW()
+		SET tree_data = $2, metadata = $3, updated_at = NOW(), version = version + 1
 		WHERE id = $1
-		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived, user_id
 	`
 	updateRepertoireNameSQL = `
 		UPDATE repertoires
 		SET name = $2, updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
 	`
 	updateRepertoireCategorySQL = `
 		UPDATE repertoires
 		SET category_id = $2, updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
+	`
+	updateRepertoireMatchActiveSQL = `
+		UPDATE repertoires
+		SET match_active = $2
+		WHERE id = $1
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
+	`
+	updateRepertoireArchivedSQL = `
+		UPDATE repertoires
+		SET archived = $2
+		WHERE id = $1
+		RETURNING id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived
+	`
+	// A repertoire is left out of matching when it, or its category, is inactive
+	getMatchExcludedRepertoiresSQL = `
+		SELECT r.id
+		FROM repertoires r
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.user_id = $1 AND (NOT r.match_active OR c.match_active IS FALSE)
 	`
 	deleteRepertoireSQL = `
 		DELETE FROM repertoires WHERE id = $1
@@ -112,6 +131,9 @@ func (r *PostgresRepertoireRepo) GetByID(id string) (*models.Repertoire, error)
 		&metadataJSON,
 		&rep.CreatedAt,
 		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -217,7 +239,13 @@ func (r *PostgresRepertoireRepo) CreateWithCategory(userID string, name string,
 		args = []interface{}{rep.ID, userID, rep.Name, string(rep.Color), treeDataJSON, metadataJSON}
 	}
 
-	err = r.pool.QueryRow(ctx, query, args...).Scan(
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(
 		&rep.ID,
 		&rep.Name,
 		&rep.Color,
@@ -226,11 +254,28 @@ func (r *PostgresRepertoireRepo) CreateWithCategory(userID string, name string,
 		&metadataJSON,
 		&rep.CreatedAt,
 		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repertoire: %w", err)
 	}
 
+	if err := reindexPositions(ctx, tx, rep.ID, userID, rootNode); err != nil {
+		return nil, err
+	}
+	if err := reindexComments(ctx, tx, rep.ID, userID, rootNode); err != nil {
+		return nil, err
+	}
+	if err := recordRevision(ctx, tx, rep.ID, rep.Version, treeDataJSON, metadataJSON); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+
 	if err := json.Unmarshal(treeDataJSON, &rep.TreeData); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
 	}
@@ -257,10 +302,17 @@ func (r *PostgresRepertoireRepo) Save(id string, treeData models.RepertoireNode,
 		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var rep models.Repertoire
+	var userID string
 	var newTreeDataJSON, newMetadataJSON []byte
 
-	err = r.pool.QueryRow(ctx, updateRepertoireByIDSQL,
+	err = tx.QueryRow(ctx, updateRepertoireByIDSQL,
 		id,
 		treeDataJSON,
 		metadataJSON,
@@ -273,11 +325,30 @@ func (r *PostgresRepertoireRepo) Save(id string, treeData models.RepertoireNode,
 		&newMetadataJSON,
 		&rep.CreatedAt,
 		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
+		&userID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save repertoire: %w", err)
 	}
 
+	// The position and comment indexes are rebuilt with the tree so lookups never see a stale tree
+	if err := reindexPositions(ctx, tx, id, userID, treeData); err != nil {
+		return nil, err
+	}
+	if err := reindexComments(ctx, tx, id, userID, treeData); err != nil {
+		return nil, err
+	}
+	if err := recordRevision(ctx, tx, id, rep.Version, newTreeDataJSON, newMetadataJSON); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+
 	if err := json.Unmarshal(newTreeDataJSON, &rep.TreeData); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
 	}
@@ -306,6 +377,9 @@ func (r *PostgresRepertoireRepo) UpdateName(id string, name string) (*models.Rep
 		&metadataJSON,
 		&rep.CreatedAt,
 		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update repertoire name: %w", err)
@@ -339,6 +413,9 @@ func (r *PostgresRepertoireRepo) UpdateCategory(id string, categoryID *string) (
 		&metadataJSON,
 		&rep.CreatedAt,
 		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -358,6 +435,110 @@ func (r *PostgresRepertoireRepo) UpdateCategory(id string, categoryID *string) (
 	return &rep, nil
 }
 
+// SetMatchActive sets whether imported games are matched against a repertoire
+func (r *PostgresRepertoireRepo) SetMatchActive(id string, active bool) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var rep models.Repertoire
+	var treeDataJSON, metadataJSON []byte
+
+	err := r.pool.QueryRow(ctx, updateRepertoireMatchActiveSQL, id, active).Scan(
+		&rep.ID,
+		&rep.Name,
+		&rep.Color,
+		&rep.CategoryID,
+		&treeDataJSON,
+		&metadataJSON,
+		&rep.CreatedAt,
+		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to update repertoire matching: %w", err)
+	}
+
+	if err := json.Unmarshal(treeDataJSON, &rep.TreeData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
+	}
+
+	if err := json.Unmarshal(metadataJSON, &rep.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+
+	return &rep, nil
+}
+
+// SetArchived archives or unarchives a repertoire
+func (r *PostgresRepertoireRepo) SetArchived(id string, archived bool) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var rep models.Repertoire
+	var treeDataJSON, metadataJSON []byte
+
+	err := r.pool.QueryRow(ctx, updateRepertoireArchivedSQL, id, archived).Scan(
+		&rep.ID,
+		&rep.Name,
+		&rep.Color,
+		&rep.CategoryID,
+		&treeDataJSON,
+		&metadataJSON,
+		&rep.CreatedAt,
+		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to update repertoire archive state: %w", err)
+	}
+
+	if err := json.Unmarshal(treeDataJSON, &rep.TreeData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
+	}
+
+	if err := json.Unmarshal(metadataJSON, &rep.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+
+	return &rep, nil
+}
+
+// GetMatchExcluded returns the IDs of the repertoires of a user that are left out of game
+// matching, either themselves or through their category
+func (r *PostgresRepertoireRepo) GetMatchExcluded(userID string) (map[string]bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getMatchExcludedRepertoiresSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repertoires excluded from matching: %w", err)
+	}
+	defer rows.Close()
+
+	excluded := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire id: %w", err)
+		}
+		excluded[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate repertoires excluded from matching: %w", err)
+	}
+	return excluded, nil
+}
+
 // GetByCategory retrieves all repertoires in a specific category
 func (r *PostgresRepertoireRepo) GetByCategory(categoryID string) ([]models.Repertoire, error) {
 	ctx, cancel := dbContext()
@@ -464,6 +645,9 @@ func (r *PostgresRepertoireRepo) scanRepertoires(rows interface {
 			&metadataJSON,
 			&rep.CreatedAt,
 			&rep.UpdatedAt,
+			&rep.Version,
+			&rep.MatchActive,
+			&rep.Archived,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan repertoire: %w", err)
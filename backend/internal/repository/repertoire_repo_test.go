@@ -129,3 +129,12 @@ func TestRepertoireNode_NilParentID(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestPositionIndexKey(t *testing.T) {
+	assert.Equal(t,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+		PositionIndexKey("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"))
+	assert.Equal(t,
+		PositionIndexKey("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -"),
+		PositionIndexKey("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"))
+}
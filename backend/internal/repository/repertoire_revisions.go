@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	insertRevisionSQL = `
+		INSERT INTO repertoire_revisions (repertoire_id, version, tree_data, metadata)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (repertoire_id, version) DO UPDATE
+		SET tree_data = EXCLUDED.tree_data, metadata = EXCLUDED.metadata, created_at = NOW()
+	`
+	copyRevisionSQL = `
+		INSERT INTO repertoire_revisions (repertoire_id, version, tree_data, metadata)
+		SELECT id, version, tree_data, metadata FROM repertoires WHERE id = $1
+		ON CONFLICT (repertoire_id, version) DO UPDATE
+		SET tree_data = EXCLUDED.tree_data, metadata = EXCLUDED.metadata, created_at = NOW()
+	`
+	pruneRevisionsSQL = `
+		DELETE FROM repertoire_revisions
+		WHERE repertoire_id = $1 AND version <= $2
+	`
+	listRevisionsSQL = `
+		SELECT version, metadata, created_at
+		FROM repertoire_revisions
+		WHERE repertoire_id = $1
+		ORDER BY version DESC
+	`
+	getRevisionSQL = `
+		SELECT version, tree_data, metadata, created_at
+		FROM repertoire_revisions
+		WHERE repertoire_id = $1 AND version = $2
+	`
+)
+
+// recordRevision keeps the tree saved at version, dropping the revisions beyond config.MaxRepertoireRevisions
+func recordRevision(ctx context.Context, tx pgx.Tx, repertoireID string, version int, treeDataJSON, metadataJSON []byte) error {
+	if _, err := tx.Exec(ctx, insertRevisionSQL, repertoireID, version, treeDataJSON, metadataJSON); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return pruneRevisions(ctx, tx, repertoireID, version)
+}
+
+// recordStoredRevision keeps the tree stored at version, as written by node-level saves, without
+// sending it back to the database
+func recordStoredRevision(ctx context.Context, tx pgx.Tx, repertoireID string, version int) error {
+	if _, err := tx.Exec(ctx, copyRevisionSQL, repertoireID); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return pruneRevisions(ctx, tx, repertoireID, version)
+}
+
+func pruneRevisions(ctx context.Context, tx pgx.Tx, repertoireID string, version int) error {
+	if _, err := tx.Exec(ctx, pruneRevisionsSQL, repertoireID, version-config.MaxRepertoireRevisions); err != nil {
+		return fmt.Errorf("failed to prune revisions: %w", err)
+	}
+	return nil
+}
+
+// ListRevisions returns the kept revisions of a repertoire without their trees, newest first
+func (r *PostgresRepertoireRepo) ListRevisions(repertoireID string) ([]models.RepertoireRevision, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listRevisionsSQL, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.RepertoireRevision{}
+	for rows.Next() {
+		var rev models.RepertoireRevision
+		var metadataJSON []byte
+		if err := rows.Scan(&rev.Version, &metadataJSON, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		if err := json.Unmarshal(metadataJSON, &rev.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetRevision returns one revision of a repertoire with its tree
+func (r *PostgresRepertoireRepo) GetRevision(repertoireID string, version int) (*models.RepertoireRevision, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var rev models.RepertoireRevision
+	var treeDataJSON, metadataJSON []byte
+	err := r.pool.QueryRow(ctx, getRevisionSQL, repertoireID, version).Scan(&rev.Version, &treeDataJSON, &metadataJSON, &rev.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+
+	rev.TreeData = &models.RepertoireNode{}
+	if err := json.Unmarshal(treeDataJSON, rev.TreeData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
+	}
+	if err := json.Unmarshal(metadataJSON, &rev.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	return &rev, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	deleteRepertoireCommentsSQL = `
+		DELETE FROM repertoire_comments WHERE repertoire_id = $1
+	`
+	searchRepertoiresSQL = `
+		SELECT repertoire_id, name, node_id, move_path, comment, rank FROM (
+			SELECT r.id AS repertoire_id, r.name, c.node_id, c.move_path, c.comment, ts_rank(c.search, q) AS rank
+			FROM repertoire_comments c
+			JOIN repertoires r ON r.id = c.repertoire_id,
+				websearch_to_tsquery('simple', $2) q
+			WHERE c.user_id = $1 AND c.search @@ q
+			UNION ALL
+			SELECT r.id, r.name, r.tree_data->>'id', '{}'::TEXT[], '', ts_rank(to_tsvector('simple', r.name), q)
+			FROM repertoires r, websearch_to_tsquery('simple', $2) q
+			WHERE r.user_id = $1 AND to_tsvector('simple', r.name) @@ q
+		) matches
+		ORDER BY rank DESC, name, node_id
+		LIMIT $3
+	`
+)
+
+// reindexComments replaces the indexed node comments of a repertoire with the comments of its tree
+func reindexComments(ctx context.Context, tx pgx.Tx, repertoireID, userID string, root models.RepertoireNode) error {
+	if _, err := tx.Exec(ctx, deleteRepertoireCommentsSQL, repertoireID); err != nil {
+		return fmt.Errorf("failed to clear repertoire comments: %w", err)
+	}
+
+	rows := commentRows(repertoireID, userID, &root, []string{})
+	if len(rows) == 0 {
+		return nil
+	}
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"repertoire_comments"},
+		[]string{"repertoire_id", "node_id", "user_id", "move_path", "comment"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to index repertoire comments: %w", err)
+	}
+	return nil
+}
+
+// commentRows returns the comment index rows of a subtree, whose parent is reached by path
+func commentRows(repertoireID, userID string, root *models.RepertoireNode, path []string) [][]interface{} {
+	var rows [][]interface{}
+	var walk func(node *models.RepertoireNode, path []string)
+	walk = func(node *models.RepertoireNode, path []string) {
+		if node.Move != nil {
+			path = append(path[:len(path):len(path)], *node.Move)
+		}
+		if node.Comment != nil && *node.Comment != "" {
+			rows = append(rows, []interface{}{repertoireID, node.ID, userID, path, *node.Comment})
+		}
+		for _, child := range node.Children {
+			if child != nil {
+				walk(child, path)
+			}
+		}
+	}
+	walk(root, path)
+	return rows
+}
+
+// Search returns the node comments and repertoire names of the user matching a web-search style query, best first
+func (r *PostgresRepertoireRepo) Search(userID, query string, limit int) ([]models.RepertoireSearchResult, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, searchRepertoiresSQL, userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search repertoires: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.RepertoireSearchResult{}
+	for rows.Next() {
+		var m models.RepertoireSearchResult
+		var rank float32
+		if err := rows.Scan(&m.RepertoireID, &m.RepertoireName, &m.NodeID, &m.MovePath, &m.Comment, &rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		m.Rank = float64(rank)
+		results = append(results, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+	return results, nil
+}
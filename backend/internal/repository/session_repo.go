@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	sessionColumns = `id, user_id, user_agent, ip, created_at, last_seen_at, expires_at`
+
+	createSessionSQL = `
+		INSERT INTO sessions (user_id, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + sessionColumns
+	touchSessionSQL = `
+		UPDATE sessions SET last_seen_at = $2
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2
+	`
+	listActiveSessionsSQL = `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC
+	`
+	revokeSessionSQL = `
+		UPDATE sessions SET revoked_at = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+)
+
+// PostgresSessionRepo implements SessionRepository using PostgreSQL
+type PostgresSessionRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresSessionRepo creates a new PostgresSessionRepo
+func NewPostgresSessionRepo(pool *pgxpool.Pool) *PostgresSessionRepo {
+	return &PostgresSessionRepo{pool: pool}
+}
+
+func scanSession(scan func(dest ...any) error) (*models.Session, error) {
+	var s models.Session
+	if err := scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Create opens a session for a user on a device
+func (r *PostgresSessionRepo) Create(userID string, device models.SessionDevice, expiresAt time.Time) (*models.Session, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	session, err := scanSession(r.pool.QueryRow(ctx, createSessionSQL, userID, device.UserAgent, device.IP, expiresAt).Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, nil
+}
+
+// Touch records activity on a session, or returns ErrSessionNotFound if it was revoked or expired
+func (r *PostgresSessionRepo) Touch(id string, seenAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, touchSessionSQL, id, seenAt)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// ListActive returns the sessions of a user that are neither revoked nor expired, most recently used first
+func (r *PostgresSessionRepo) ListActive(userID string, now time.Time) ([]models.Session, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listActiveSessionsSQL, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends a session of a user; ErrSessionNotFound if the user has no such active session
+func (r *PostgresSessionRepo) Revoke(id, userID string, revokedAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx, revokeSessionSQL, id, userID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
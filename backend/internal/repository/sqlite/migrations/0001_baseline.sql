@@ -0,0 +1,173 @@
+-- SQLite schema of the stores with a SQLite backend: users, repertoires and analyses.
+-- It mirrors the PostgreSQL schema after its migration 0028. JSONB columns are JSON text,
+-- UUIDs are text generated by the application, and timestamps are written by the driver in
+-- SQLite's own format so the date functions can read them.
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    email VARCHAR(255),
+    password_hash VARCHAR(255),
+    oauth_provider VARCHAR(20),
+    oauth_id VARCHAR(255),
+    lichess_access_token TEXT,
+    last_lichess_sync_at TIMESTAMP,
+    last_chesscom_sync_at TIMESTAMP,
+    time_format_prefs TEXT NOT NULL DEFAULT '[]',
+    analysis_depth INTEGER NOT NULL DEFAULT 0,
+    weekly_digest BOOLEAN NOT NULL DEFAULT FALSE,
+    last_digest_at TIMESTAMP,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    locale TEXT NOT NULL DEFAULT 'en',
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (oauth_provider, oauth_id)
+);
+
+CREATE UNIQUE INDEX idx_users_email ON users(email) WHERE email IS NOT NULL;
+
+CREATE TABLE linked_accounts (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    last_sync_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_linked_accounts_unique ON linked_accounts(user_id, provider, LOWER(username));
+
+-- Categories are not stored by the SQLite backend yet; the table is kept so repertoires
+-- reference the same columns as in PostgreSQL
+CREATE TABLE categories (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
+    match_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name, color)
+);
+
+CREATE TABLE repertoires (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(5) NOT NULL CHECK (color IN ('white', 'black')),
+    category_id TEXT REFERENCES categories(id) ON DELETE CASCADE,
+    tree_data TEXT NOT NULL,
+    metadata TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    match_active BOOLEAN NOT NULL DEFAULT TRUE,
+    opening_labels_version INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_repertoires_user_id ON repertoires(user_id);
+CREATE INDEX idx_repertoires_category ON repertoires(category_id);
+
+CREATE TRIGGER repertoire_limit_trigger
+    BEFORE INSERT ON repertoires
+    WHEN (SELECT COUNT(*) FROM repertoires WHERE user_id = NEW.user_id) >= 50
+BEGIN
+    SELECT RAISE(ABORT, 'Maximum of 50 repertoires allowed');
+END;
+
+CREATE TABLE repertoire_positions (
+    repertoire_id TEXT NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    node_id VARCHAR(64) NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    normalized_fen TEXT NOT NULL,
+    PRIMARY KEY (repertoire_id, node_id)
+);
+
+CREATE INDEX idx_repertoire_positions_user_fen ON repertoire_positions(user_id, normalized_fen);
+
+-- Full-text index of node comments and repertoire names, the counterpart of the PostgreSQL
+-- repertoire_comments table and name index. A repertoire name is indexed on the row of its
+-- root node, with an empty comment.
+CREATE VIRTUAL TABLE repertoire_search USING fts5(
+    repertoire_id UNINDEXED,
+    node_id UNINDEXED,
+    user_id UNINDEXED,
+    move_path UNINDEXED,
+    comment,
+    name
+);
+
+-- Virtual tables take no foreign keys
+CREATE TRIGGER repertoire_search_cleanup
+    AFTER DELETE ON repertoires
+BEGIN
+    DELETE FROM repertoire_search WHERE repertoire_id = OLD.id;
+END;
+
+CREATE TABLE repertoire_revisions (
+    repertoire_id TEXT NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    tree_data TEXT NOT NULL,
+    metadata TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (repertoire_id, version)
+);
+
+CREATE TABLE repertoire_notes (
+    repertoire_id TEXT NOT NULL REFERENCES repertoires(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    updated_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (repertoire_id, version)
+);
+
+CREATE TABLE analyses (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    username VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    game_count INTEGER NOT NULL,
+    uploaded_at TIMESTAMP NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'pgn',
+    source_params TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_analyses_user_source ON analyses(user_id, source);
+
+CREATE TABLE games (
+    analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    game_index INTEGER NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    headers TEXT NOT NULL DEFAULT '{}',
+    moves TEXT NOT NULL DEFAULT '[]',
+    user_color VARCHAR(5) NOT NULL,
+    repertoire_id TEXT,
+    repertoire_name VARCHAR(100),
+    match_score INTEGER NOT NULL DEFAULT 0,
+    time_class VARCHAR(10),
+    status VARCHAR(10),
+    note TEXT NOT NULL DEFAULT '',
+    starred BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (analysis_id, game_index)
+);
+
+CREATE INDEX idx_games_user ON games(user_id);
+CREATE INDEX idx_games_user_repertoire ON games(user_id, repertoire_id);
+
+CREATE TABLE viewed_games (
+    user_id TEXT NOT NULL REFERENCES users(id),
+    analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    game_index INTEGER NOT NULL,
+    viewed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, analysis_id, game_index)
+);
+
+CREATE TABLE skipped_duplicates (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    game_count INTEGER NOT NULL,
+    skipped_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_skipped_duplicates_user ON skipped_duplicates(user_id);
@@ -0,0 +1,2 @@
+-- Keys of the classification rules matched by a move of the game, as a JSON array
+ALTER TABLE games ADD COLUMN classifications TEXT NOT NULL DEFAULT '[]';
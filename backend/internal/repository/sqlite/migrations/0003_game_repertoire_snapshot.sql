@@ -0,0 +1,3 @@
+-- Version and tree hash of the matched repertoire when the game was analyzed
+ALTER TABLE games ADD COLUMN repertoire_version INTEGER;
+ALTER TABLE games ADD COLUMN repertoire_tree_hash TEXT;
@@ -0,0 +1,2 @@
+-- Archived repertoires are read-only, left out of matching and hidden from default lists
+ALTER TABLE repertoires ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,142 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/treechess/backend/config"
+)
+
+// SQLite migrations follow the same naming and ordering rules as the PostgreSQL ones.
+//
+//go:embed sqlite/migrations/*.sql
+var sqliteMigrationFiles embed.FS
+
+// SQLiteDB is a SQLite database file, the storage of self-hosted deployments without
+// PostgreSQL. Only the user, repertoire and analysis repositories have a SQLite implementation.
+type SQLiteDB struct {
+	DB *sql.DB
+}
+
+// NewSQLiteDB opens, creating it if needed, the SQLite database at path and applies pending migrations
+func NewSQLiteDB(path string) (*SQLiteDB, error) {
+	// Write transactions take the lock up front, so concurrent writers wait for each other
+	// through busy_timeout instead of failing when they upgrade a read lock
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Set("_txlock", "immediate")
+	params.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.DefaultDBTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	sqliteDB := &SQLiteDB{DB: db}
+	if err := sqliteDB.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return sqliteDB, nil
+}
+
+// Close closes the database
+func (db *SQLiteDB) Close() {
+	if db.DB != nil {
+		db.DB.Close()
+	}
+}
+
+// Migrate applies the embedded SQLite migrations not yet recorded in schema_migrations, each in its own transaction
+func (db *SQLiteDB) Migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.MigrationDBTimeout)
+	defer cancel()
+
+	fsys, err := fs.Sub(sqliteMigrationFiles, "sqlite")
+	if err != nil {
+		return fmt.Errorf("failed to open migrations: %w", err)
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := db.DB.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		tx, err := db.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %04d_%s: %w", m.version, m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`, m.version, m.name, sqliteNow()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %04d_%s: %w", m.version, m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %04d_%s: %w", m.version, m.name, err)
+		}
+		log.Printf("Applied SQLite migration %04d_%s", m.version, m.name)
+	}
+	return nil
+}
+
+// sqliteNow is the current time as timestamps are stored: in UTC, so that they compare as text
+func sqliteNow() time.Time {
+	return time.Now().UTC()
+}
+
+// isSQLiteUniqueError checks if the error is a SQLite unique constraint violation
+func isSQLiteUniqueError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
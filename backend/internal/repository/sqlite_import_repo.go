@@ -0,0 +1,830 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	sqliteAnalysisColumns = `id, username, filename, game_count, uploaded_at, source, source_params`
+	sqliteGameColumns     = `analysis_id, game_index, headers, moves, user_color, repertoire_id, repertoire_name, match_score, note, starred,
+		repertoire_version, repertoire_tree_hash`
+
+	sqliteSaveAnalysisSQL = `
+		INSERT INTO analyses (id, user_id, username, filename, game_count, uploaded_at, source, source_params)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+		RETURNING ` + sqliteAnalysisColumns + `
+	`
+	sqliteSaveGameSQL = `
+		INSERT INTO games (analysis_id, game_index, user_id, headers, moves, user_color,
+			repertoire_id, repertoire_name, match_score, time_class, status, classifications,
+			repertoire_version, repertoire_tree_hash)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14)
+	`
+	sqliteGetAnalysesSQL = `
+		SELECT ` + sqliteAnalysisColumns + `
+		FROM analyses
+		WHERE user_id = ?1 AND (?2 = '' OR source = ?2)
+		ORDER BY uploaded_at DESC
+	`
+	sqliteGetAnalysisByIDSQL = `
+		SELECT ` + sqliteAnalysisColumns + `
+		FROM analyses
+		WHERE id = ?1
+	`
+	sqliteGetGamesByAnalysisSQL = `
+		SELECT ` + sqliteGameColumns + `
+		FROM games
+		WHERE analysis_id = ?1
+		ORDER BY game_index
+	`
+	sqliteGetGameSQL = `
+		SELECT ` + sqliteGameColumns + `
+		FROM games
+		WHERE analysis_id = ?1 AND game_index = ?2
+	`
+	sqliteGetGamesByUserSQL = `
+		SELECT g.analysis_id, g.game_index, g.headers, g.moves, g.user_color, g.repertoire_id, g.repertoire_name, g.match_score,
+			g.note, g.starred, g.repertoire_version, g.repertoire_tree_hash
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		WHERE g.user_id = ?1
+		ORDER BY a.uploaded_at DESC, g.game_index
+	`
+	sqliteDeleteAnalysisSQL = `
+		DELETE FROM analyses
+		WHERE id = ?1
+	`
+	// sqliteImportSourceSQL is importSourceSQL for SQLite, whose LIKE has no default escape character
+	sqliteImportSourceSQL = `CASE
+				WHEN filename LIKE 'sync\_lichess\_%' ESCAPE '\' OR filename LIKE 'lichess\_%' ESCAPE '\' THEN 'lichess'
+				WHEN filename LIKE 'sync\_chesscom\_%' ESCAPE '\' OR filename LIKE 'chesscom\_%' ESCAPE '\' THEN 'chesscom'
+				WHEN filename LIKE 'broadcast\_%' ESCAPE '\' THEN 'broadcast'
+				WHEN filename LIKE 'team\_%' ESCAPE '\' THEN 'lichess_team'
+				ELSE 'pgn'
+			END`
+	sqliteGameFiltersSQL = `
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		LEFT JOIN viewed_games v
+			ON v.user_id = g.user_id AND v.analysis_id = g.analysis_id AND v.game_index = g.game_index
+		WHERE g.user_id = ?1
+			AND (?2 = '' OR g.time_class = ?2)
+			AND (?3 = '' OR g.repertoire_name = ?3)
+			AND (?4 = '' OR a.source = ?4)
+			AND (NOT ?5 OR g.starred)
+			AND (?6 = '' OR EXISTS (SELECT 1 FROM json_each(g.classifications) WHERE value = ?6))
+	`
+	sqliteCountGamesSQL  = `SELECT COUNT(*) ` + sqliteGameFiltersSQL
+	sqliteGetAllGamesSQL = `
+		SELECT g.analysis_id, g.game_index,
+			COALESCE(g.headers ->> 'White', ''), COALESCE(g.headers ->> 'Black', ''),
+			COALESCE(g.headers ->> 'Result', ''), COALESCE(g.headers ->> 'Date', ''),
+			COALESCE(g.headers ->> 'Opening', ''),
+			g.user_color, g.repertoire_id, g.repertoire_name,
+			COALESCE(g.time_class, ''), COALESCE(g.status, 'ok'),
+			a.filename, a.source, a.uploaded_at, v.user_id IS NOT NULL, g.note, g.starred, g.classifications
+		` + sqliteGameFiltersSQL + `
+		ORDER BY a.uploaded_at DESC, g.game_index
+		LIMIT ?7 OFFSET ?8
+	`
+	sqliteDeleteGameSQL = `
+		DELETE FROM games
+		WHERE analysis_id = ?1 AND game_index = ?2
+	`
+	sqliteDecrementGameCountSQL = `
+		UPDATE analyses
+		SET game_count = game_count - 1
+		WHERE id = ?1
+		RETURNING game_count
+	`
+	sqliteUpdateGameSQL = `
+		UPDATE games
+		SET headers = ?3, moves = ?4, user_color = ?5, repertoire_id = ?6, repertoire_name = ?7,
+			match_score = ?8, time_class = ?9, status = ?10, classifications = ?11,
+			repertoire_version = ?12, repertoire_tree_hash = ?13
+		WHERE analysis_id = ?1 AND game_index = ?2
+	`
+	sqliteUpdateGameNotesSQL = `
+		UPDATE games
+		SET note = COALESCE(?3, note), starred = COALESCE(?4, starred)
+		WHERE analysis_id = ?1 AND game_index = ?2
+		RETURNING note, starred
+	`
+	sqliteAnalysisExistsSQL = `
+		SELECT EXISTS(SELECT 1 FROM analyses WHERE id = ?1)
+	`
+	sqliteBelongsToUserAnalysisSQL = `
+		SELECT EXISTS(SELECT 1 FROM analyses WHERE id = ?1 AND user_id = ?2)
+	`
+	sqliteGetDistinctRepertoiresSQL = `
+		SELECT DISTINCT repertoire_name
+		FROM games
+		WHERE user_id = ?1 AND repertoire_name IS NOT NULL AND repertoire_name <> ''
+		ORDER BY repertoire_name
+	`
+	sqliteMarkGameViewedSQL = `
+		INSERT INTO viewed_games (user_id, analysis_id, game_index, viewed_at)
+		VALUES (?1, ?2, ?3, ?4)
+		ON CONFLICT DO NOTHING
+	`
+	sqliteGetViewedGamesSQL = `
+		SELECT analysis_id, game_index FROM viewed_games WHERE user_id = ?1
+	`
+	sqliteGetGameLocationsByRepertoireSQL = `
+		SELECT analysis_id, game_index
+		FROM games
+		WHERE user_id = ?1 AND repertoire_id = ?2
+		ORDER BY analysis_id, game_index
+	`
+	sqliteRecordSkippedDuplicatesSQL = `
+		INSERT INTO skipped_duplicates (id, user_id, filename, game_count, skipped_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+	`
+	// Engine evaluations are not stored by the SQLite backend, so no game counts as evaluated
+	sqliteGetImportStatsSQL = `
+		WITH imported AS (
+			SELECT a.source,
+				strftime('%Y-%m', a.uploaded_at) AS month,
+				COUNT(*) AS games,
+				COUNT(*) FILTER (WHERE g.status = 'error') AS out_of_repertoire
+			FROM games g
+			JOIN analyses a ON a.id = g.analysis_id
+			WHERE g.user_id = ?1
+			GROUP BY 1, 2
+		), skipped AS (
+			SELECT ` + sqliteImportSourceSQL + ` AS source,
+				strftime('%Y-%m', skipped_at) AS month,
+				SUM(game_count) AS games
+			FROM skipped_duplicates
+			WHERE user_id = ?1
+			GROUP BY 1, 2
+		)
+		SELECT COALESCE(i.source, s.source), COALESCE(i.month, s.month),
+			COALESCE(i.games, 0), COALESCE(s.games, 0),
+			COALESCE(i.out_of_repertoire, 0)
+		FROM imported i
+		FULL OUTER JOIN skipped s ON s.source = i.source AND s.month = i.month
+		ORDER BY 2 DESC, 1
+	`
+	// See getBookDepthSQL; the first move out of the book is picked by a correlated subquery
+	sqliteGetBookDepthSQL = `
+		WITH exits AS (
+			SELECT strftime('%Y-%m', a.uploaded_at) AS month,
+				(
+					SELECT m.value
+					FROM json_each(g.moves) m
+					WHERE m.value ->> 'status' <> 'in-repertoire'
+					ORDER BY m.value ->> 'plyNumber'
+					LIMIT 1
+				) AS exit
+			FROM games g
+			JOIN analyses a ON a.id = g.analysis_id
+			WHERE g.user_id = ?1 AND g.repertoire_id = ?2 AND a.source NOT IN ('broadcast', 'lichess_team')
+		)
+		SELECT month,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE exit ->> 'status' = 'out-of-repertoire'),
+			AVG(exit ->> 'plyNumber') FILTER (WHERE exit ->> 'status' = 'out-of-repertoire'),
+			COUNT(*) FILTER (WHERE exit ->> 'status' = 'opponent-new'),
+			AVG(exit ->> 'plyNumber') FILTER (WHERE exit ->> 'status' = 'opponent-new')
+		FROM exits
+		GROUP BY 1
+		ORDER BY 1
+	`
+	sqliteGetMoveTimesSQL = `
+		SELECT m.value ->> 'plyNumber' AS ply,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE m.value ->> 'status' = 'in-repertoire'),
+			AVG(m.value ->> 'timeSpent')
+		FROM games g
+		JOIN analyses a ON a.id = g.analysis_id
+		JOIN json_each(g.moves) m
+		WHERE g.user_id = ?1 AND g.repertoire_id = ?2 AND a.source NOT IN ('broadcast', 'lichess_team')
+			AND m.value ->> 'isUserMove' AND json_type(m.value, '$.timeSpent') IS NOT NULL
+			AND m.value ->> 'plyNumber' < ?3
+		GROUP BY 1
+		ORDER BY 1
+	`
+	sqliteGetRawAnalysesSQL = `
+		SELECT id, filename, uploaded_at
+		FROM analyses
+		WHERE user_id = ?1
+		ORDER BY uploaded_at DESC
+	`
+)
+
+// SQLiteAnalysisRepo implements AnalysisRepository using SQLite, with the schema of the
+// PostgreSQL backend: analysis metadata in analyses, one games row per game.
+type SQLiteAnalysisRepo struct {
+	db *sql.DB
+}
+
+// NewSQLiteAnalysisRepo creates a new SQLite analysis repository
+func NewSQLiteAnalysisRepo(db *sql.DB) *SQLiteAnalysisRepo {
+	return &SQLiteAnalysisRepo{db: db}
+}
+
+// scanSQLiteAnalysis scans a row selected with sqliteAnalysisColumns
+func scanSQLiteAnalysis(scan func(dest ...any) error, summary *models.AnalysisSummary) error {
+	var paramsJSON string
+	if err := scan(&summary.ID, &summary.Username, &summary.Filename, &summary.GameCount, &summary.UploadedAt, &summary.Source, &paramsJSON); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(paramsJSON), &summary.SourceParams); err != nil {
+		return fmt.Errorf("failed to unmarshal source_params: %w", err)
+	}
+	return nil
+}
+
+// Save saves a new analysis and its games in a single transaction
+func (r *SQLiteAnalysisRepo) Save(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if provenance.Source == "" {
+		provenance.Source = models.ImportSourcePGN
+	}
+	paramsJSON, err := json.Marshal(provenance.SourceParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal source_params: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id := uuid.New().String()
+	var summary models.AnalysisSummary
+	err = scanSQLiteAnalysis(tx.QueryRowContext(ctx, sqliteSaveAnalysisSQL,
+		id, userID, username, filename, gameCount, sqliteNow(), provenance.Source, string(paramsJSON),
+	).Scan, &summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save analysis: %w", err)
+	}
+
+	for _, game := range results {
+		row, err := toGameRow(game)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, sqliteSaveGameSQL,
+			id,
+			game.GameIndex,
+			userID,
+			string(row.headers),
+			string(row.moves),
+			game.UserColor,
+			row.repertoireID,
+			row.repertoireName,
+			game.MatchScore,
+			row.timeClass,
+			row.status,
+			sqliteStringArray(row.classifications),
+			row.repertoireVersion,
+			row.repertoireTreeHash,
+		); err != nil {
+			return nil, fmt.Errorf("failed to save games: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit analysis: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// GetAll returns all analysis summaries for a user, only those of one import source when source is set
+func (r *SQLiteAnalysisRepo) GetAll(userID, source string) ([]models.AnalysisSummary, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetAnalysesSQL, userID, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []models.AnalysisSummary
+	for rows.Next() {
+		var a models.AnalysisSummary
+		if err := scanSQLiteAnalysis(rows.Scan, &a); err != nil {
+			return nil, fmt.Errorf("failed to scan analysis: %w", err)
+		}
+		analyses = append(analyses, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating analyses: %w", err)
+	}
+
+	return analyses, nil
+}
+
+// GetByID returns analysis details by ID
+func (r *SQLiteAnalysisRepo) GetByID(id string) (*models.AnalysisDetail, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var detail models.AnalysisDetail
+	var paramsJSON string
+	err := r.db.QueryRowContext(ctx, sqliteGetAnalysisByIDSQL, id).Scan(
+		&detail.ID,
+		&detail.Username,
+		&detail.Filename,
+		&detail.GameCount,
+		&detail.UploadedAt,
+		&detail.Source,
+		&paramsJSON,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAnalysisNotFound
+		}
+		return nil, fmt.Errorf("failed to get analysis: %w", err)
+	}
+	if err := json.Unmarshal([]byte(paramsJSON), &detail.SourceParams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal source_params: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetGamesByAnalysisSQL, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games: %w", err)
+	}
+	defer rows.Close()
+
+	detail.Results = []models.GameAnalysis{}
+	for rows.Next() {
+		_, game, err := scanGame(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
+		}
+		detail.Results = append(detail.Results, *game)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
+	}
+
+	return &detail, nil
+}
+
+// GetGame returns a single game of an analysis
+func (r *SQLiteAnalysisRepo) GetGame(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, game, err := scanGame(r.db.QueryRowContext(ctx, sqliteGetGameSQL, analysisID, gameIndex))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
+		return nil, fmt.Errorf("failed to get game: %w", err)
+	}
+
+	return game, nil
+}
+
+// Delete deletes an analysis by ID
+func (r *SQLiteAnalysisRepo) Delete(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, sqliteDeleteAnalysisSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis: %w", err)
+	}
+	if affected == 0 {
+		return ErrAnalysisNotFound
+	}
+
+	return nil
+}
+
+// GetAllGames returns all games from all analyses with pagination for a user
+func (r *SQLiteAnalysisRepo) GetAllGames(userID string, limit, offset int, timeClass, repertoire, source, classification string, starred bool) (*models.GamesResponse, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, sqliteCountGamesSQL, userID, timeClass, repertoire, source, starred, classification).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count games: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetAllGamesSQL, userID, timeClass, repertoire, source, starred, classification, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games: %w", err)
+	}
+	defer rows.Close()
+
+	games := []models.GameSummary{}
+	for rows.Next() {
+		var summary models.GameSummary
+		var repertoireID, repertoireName *string
+		var filename, classifications string
+		var viewed bool
+
+		if err := rows.Scan(
+			&summary.AnalysisID,
+			&summary.GameIndex,
+			&summary.White,
+			&summary.Black,
+			&summary.Result,
+			&summary.Date,
+			&summary.Opening,
+			&summary.UserColor,
+			&repertoireID,
+			&repertoireName,
+			&summary.TimeClass,
+			&summary.Status,
+			&filename,
+			&summary.Source,
+			&summary.ImportedAt,
+			&viewed,
+			&summary.Note,
+			&summary.Starred,
+			&classifications,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
+		}
+		if err := json.Unmarshal([]byte(classifications), &summary.Classifications); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal classifications: %w", err)
+		}
+
+		summary.Synced = isSynced(filename) && !viewed
+		if repertoireID != nil {
+			summary.RepertoireID = *repertoireID
+		}
+		if repertoireName != nil {
+			summary.RepertoireName = *repertoireName
+		}
+		games = append(games, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
+	}
+
+	return &models.GamesResponse{
+		Games:  games,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// UpdateGameNotes sets the note and/or the starred flag of a game; nil values are left unchanged
+func (r *SQLiteAnalysisRepo) UpdateGameNotes(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	notes := models.GameNotes{AnalysisID: analysisID, GameIndex: gameIndex}
+	err := r.db.QueryRowContext(ctx, sqliteUpdateGameNotesSQL, analysisID, gameIndex, note, starred).Scan(&notes.Note, &notes.Starred)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
+		return nil, fmt.Errorf("failed to update game notes: %w", err)
+	}
+	return &notes, nil
+}
+
+// DeleteGame removes a single game from an analysis
+func (r *SQLiteAnalysisRepo) DeleteGame(analysisID string, gameIndex int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, sqliteDeleteGameSQL, analysisID, gameIndex)
+	if err != nil {
+		return fmt.Errorf("failed to delete game: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete game: %w", err)
+	}
+
+	if affected == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, sqliteAnalysisExistsSQL, analysisID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to get analysis: %w", err)
+		}
+		if !exists {
+			return ErrAnalysisNotFound
+		}
+		return ErrGameNotFound
+	}
+
+	var remaining int
+	if err := r.db.QueryRowContext(ctx, sqliteDecrementGameCountSQL, analysisID).Scan(&remaining); err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
+	}
+
+	// If no games left, delete the entire analysis
+	if remaining <= 0 {
+		return r.Delete(analysisID)
+	}
+
+	return nil
+}
+
+// UpdateGame replaces the stored data of a single game
+func (r *SQLiteAnalysisRepo) UpdateGame(analysisID string, game models.GameAnalysis) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	row, err := toGameRow(game)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, sqliteUpdateGameSQL,
+		analysisID,
+		game.GameIndex,
+		string(row.headers),
+		string(row.moves),
+		game.UserColor,
+		row.repertoireID,
+		row.repertoireName,
+		game.MatchScore,
+		row.timeClass,
+		row.status,
+		sqliteStringArray(row.classifications),
+		row.repertoireVersion,
+		row.repertoireTreeHash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update game: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update game: %w", err)
+	}
+	if affected == 0 {
+		return ErrGameNotFound
+	}
+
+	return nil
+}
+
+// BelongsToUser checks if an analysis belongs to a specific user
+func (r *SQLiteAnalysisRepo) BelongsToUser(id string, userID string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var belongs bool
+	if err := r.db.QueryRowContext(ctx, sqliteBelongsToUserAnalysisSQL, id, userID).Scan(&belongs); err != nil {
+		return false, fmt.Errorf("failed to check analysis ownership: %w", err)
+	}
+	return belongs, nil
+}
+
+// GetDistinctRepertoires returns a sorted list of distinct repertoire names for a user
+func (r *SQLiteAnalysisRepo) GetDistinctRepertoires(userID string) ([]string, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetDistinctRepertoiresSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repertoires: %w", err)
+	}
+	defer rows.Close()
+
+	repertoires := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire: %w", err)
+		}
+		repertoires = append(repertoires, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating repertoires: %w", err)
+	}
+
+	return repertoires, nil
+}
+
+// MarkGameViewed marks a specific game as viewed by the user
+func (r *SQLiteAnalysisRepo) MarkGameViewed(userID, analysisID string, gameIndex int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteMarkGameViewedSQL, userID, analysisID, gameIndex, sqliteNow()); err != nil {
+		return fmt.Errorf("failed to mark game as viewed: %w", err)
+	}
+	return nil
+}
+
+// GetViewedGames returns a set of "analysisID-gameIndex" keys for all viewed games of a user
+func (r *SQLiteAnalysisRepo) GetViewedGames(userID string) (map[string]bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetViewedGamesSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query viewed games: %w", err)
+	}
+	defer rows.Close()
+
+	viewed := make(map[string]bool)
+	for rows.Next() {
+		var analysisID string
+		var gameIndex int
+		if err := rows.Scan(&analysisID, &gameIndex); err != nil {
+			return nil, fmt.Errorf("failed to scan viewed game: %w", err)
+		}
+		viewed[fmt.Sprintf("%s-%d", analysisID, gameIndex)] = true
+	}
+	return viewed, rows.Err()
+}
+
+// GetAllGamesRaw returns all analyses with full game data for a user
+func (r *SQLiteAnalysisRepo) GetAllGamesRaw(userID string) ([]models.RawAnalysis, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetRawAnalysesSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []models.RawAnalysis
+	byID := make(map[string]int)
+	for rows.Next() {
+		var a models.RawAnalysis
+		if err := rows.Scan(&a.ID, &a.Filename, &a.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan analysis: %w", err)
+		}
+		byID[a.ID] = len(analyses)
+		analyses = append(analyses, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating analyses: %w", err)
+	}
+
+	gameRows, err := r.db.QueryContext(ctx, sqliteGetGamesByUserSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games: %w", err)
+	}
+	defer gameRows.Close()
+
+	for gameRows.Next() {
+		analysisID, game, err := scanGame(gameRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
+		}
+		if idx, ok := byID[analysisID]; ok {
+			analyses[idx].Results = append(analyses[idx].Results, *game)
+		}
+	}
+
+	if err := gameRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
+	}
+
+	return analyses, nil
+}
+
+// GetGameLocationsByRepertoire returns where every game of a user matched to a repertoire is stored
+func (r *SQLiteAnalysisRepo) GetGameLocationsByRepertoire(userID, repertoireID string) ([]GameLocation, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetGameLocationsByRepertoireSQL, userID, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query games by repertoire: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []GameLocation
+	for rows.Next() {
+		var loc GameLocation
+		if err := rows.Scan(&loc.AnalysisID, &loc.GameIndex); err != nil {
+			return nil, fmt.Errorf("failed to scan game location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating games: %w", err)
+	}
+	return locations, nil
+}
+
+// RecordSkippedDuplicates records how many duplicate games an import skipped
+func (r *SQLiteAnalysisRepo) RecordSkippedDuplicates(userID, filename string, count int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteRecordSkippedDuplicatesSQL, uuid.New().String(), userID, filename, count, sqliteNow()); err != nil {
+		return fmt.Errorf("failed to record skipped duplicates: %w", err)
+	}
+	return nil
+}
+
+// GetImportStats aggregates a user's imports per source and month, newest month first.
+// The evaluation completion is always 0: engine evaluations have no SQLite backend.
+func (r *SQLiteAnalysisRepo) GetImportStats(userID string) ([]models.ImportSourceStats, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetImportStatsSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.ImportSourceStats{}
+	for rows.Next() {
+		var st models.ImportSourceStats
+		var outOfRepertoire int
+		if err := rows.Scan(&st.Source, &st.Month, &st.GamesImported, &st.DuplicatesSkipped, &outOfRepertoire); err != nil {
+			return nil, fmt.Errorf("failed to scan import stats: %w", err)
+		}
+		if st.GamesImported > 0 {
+			st.OutOfRepertoireRate = float64(outOfRepertoire) / float64(st.GamesImported)
+		}
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import stats: %w", err)
+	}
+	return stats, nil
+}
+
+// GetBookDepth returns, per import month, where the user's games in a repertoire left the book
+func (r *SQLiteAnalysisRepo) GetBookDepth(userID, repertoireID string) ([]models.BookDepthPeriod, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetBookDepthSQL, userID, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query book depth: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.BookDepthPeriod{}
+	for rows.Next() {
+		var p models.BookDepthPeriod
+		if err := rows.Scan(&p.Month, &p.Games, &p.UserDeviations, &p.UserLeaveBookPly, &p.OpponentDeviations, &p.OpponentLeaveBookPly); err != nil {
+			return nil, fmt.Errorf("failed to scan book depth: %w", err)
+		}
+		periods = append(periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating book depth: %w", err)
+	}
+	return periods, nil
+}
+
+// GetMoveTimes returns the average time the user spent on their moves at each ply below maxPly
+// in a repertoire's games, counting only moves with clock data
+func (r *SQLiteAnalysisRepo) GetMoveTimes(userID, repertoireID string, maxPly int) ([]models.PlyMoveTime, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetMoveTimesSQL, userID, repertoireID, maxPly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query move times: %w", err)
+	}
+	defer rows.Close()
+
+	times := []models.PlyMoveTime{}
+	for rows.Next() {
+		var t models.PlyMoveTime
+		if err := rows.Scan(&t.Ply, &t.Moves, &t.InBook, &t.AvgTimeSpent); err != nil {
+			return nil, fmt.Errorf("failed to scan move times: %w", err)
+		}
+		times = append(times, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating move times: %w", err)
+	}
+	return times, nil
+}
+
+// sqliteStringArray encodes a PostgreSQL TEXT[] value as the JSON array stored by SQLite
+func sqliteStringArray(values []string) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
@@ -0,0 +1,894 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	sqliteRepertoireColumns = `id, name, color, category_id, tree_data, metadata, created_at, updated_at, version, match_active, archived`
+
+	sqliteGetRepertoireByIDSQL = `
+		SELECT ` + sqliteRepertoireColumns + `
+		FROM repertoires
+		WHERE id = ?1
+	`
+	sqliteGetRepertoiresByColorSQL = `
+		SELECT ` + sqliteRepertoireColumns + `
+		FROM repertoires
+		WHERE user_id = ?1 AND color = ?2
+		ORDER BY updated_at DESC
+	`
+	sqliteGetAllRepertoiresSQL = `
+		SELECT ` + sqliteRepertoireColumns + `
+		FROM repertoires
+		WHERE user_id = ?1
+		ORDER BY color DESC, updated_at DESC
+	`
+	sqliteGetRepertoiresByCategorySQL = `
+		SELECT ` + sqliteRepertoireColumns + `
+		FROM repertoires
+		WHERE category_id = ?1
+		ORDER BY updated_at DESC
+	`
+	sqliteGetUncategorizedRepertoiresSQL = `
+		SELECT ` + sqliteRepertoireColumns + `
+		FROM repertoires
+		WHERE user_id = ?1 AND color = ?2 AND category_id IS NULL
+		ORDER BY updated_at DESC
+	`
+	sqliteCreateRepertoireSQL = `
+		INSERT INTO repertoires (id, user_id, name, color, category_id, tree_data, metadata, created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?8)
+		RETURNING ` + sqliteRepertoireColumns + `
+	`
+	sqliteUpdateRepertoireByIDSQL = `
+		UPDATE repertoires
+		SET tree_data = ?2, metadata = ?3, updated_at = ?4, version = version + 1
+		WHERE id = ?1
+		RETURNING ` + sqliteRepertoireColumns + `, user_id
+	`
+	// A node-level save writes the whole edited tree: the version check guarantees it is the
+	// stored tree with the changes applied
+	sqliteSaveRepertoireNodesSQL = `
+		UPDATE repertoires
+		SET tree_data = ?3, metadata = ?4, updated_at = ?5, version = version + 1
+		WHERE id = ?1 AND version = ?2
+		RETURNING ` + sqliteRepertoireColumns + `, user_id
+	`
+	sqliteUpdateRepertoireNameSQL = `
+		UPDATE repertoires
+		SET name = ?2, updated_at = ?3
+		WHERE id = ?1
+		RETURNING ` + sqliteRepertoireColumns + `
+	`
+	sqliteUpdateRepertoireCategorySQL = `
+		UPDATE repertoires
+		SET category_id = ?2, updated_at = ?3
+		WHERE id = ?1
+		RETURNING ` + sqliteRepertoireColumns + `
+	`
+	sqliteUpdateRepertoireMatchActiveSQL = `
+		UPDATE repertoires
+		SET match_active = ?2
+		WHERE id = ?1
+		RETURNING ` + sqliteRepertoireColumns + `
+	`
+	sqliteUpdateRepertoireArchivedSQL = `
+		UPDATE repertoires
+		SET archived = ?2
+		WHERE id = ?1
+		RETURNING ` + sqliteRepertoireColumns + `
+	`
+	// A repertoire is left out of matching when it, or its category, is inactive
+	sqliteGetMatchExcludedRepertoiresSQL = `
+		SELECT r.id
+		FROM repertoires r
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.user_id = ?1 AND (NOT r.match_active OR c.match_active IS FALSE)
+	`
+	sqliteDeleteRepertoireSQL = `
+		DELETE FROM repertoires WHERE id = ?1
+	`
+	sqliteCountRepertoiresSQL = `
+		SELECT COUNT(*) FROM repertoires WHERE user_id = ?1
+	`
+	sqliteBelongsToUserRepertoireSQL = `
+		SELECT EXISTS(SELECT 1 FROM repertoires WHERE id = ?1 AND user_id = ?2)
+	`
+	sqliteCheckRepertoireExistsByIDSQL = `
+		SELECT EXISTS(SELECT 1 FROM repertoires WHERE id = ?1)
+	`
+
+	sqliteDeleteRepertoirePositionsSQL = `
+		DELETE FROM repertoire_positions WHERE repertoire_id = ?1
+	`
+	sqliteInsertRepertoirePositionSQL = `
+		INSERT INTO repertoire_positions (repertoire_id, node_id, user_id, normalized_fen)
+		VALUES (?1, ?2, ?3, ?4)
+	`
+	sqliteFindRepertoirePositionsSQL = `
+		SELECT repertoire_id, node_id, normalized_fen
+		FROM repertoire_positions
+		WHERE user_id = ?1 AND normalized_fen IN (SELECT value FROM json_each(?2))
+	`
+
+	sqliteDeleteRepertoireSearchSQL = `
+		DELETE FROM repertoire_search WHERE repertoire_id = ?1
+	`
+	sqliteInsertRepertoireSearchSQL = `
+		INSERT INTO repertoire_search (repertoire_id, node_id, user_id, move_path, comment, name)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	`
+	sqliteRenameRepertoireSearchSQL = `
+		UPDATE repertoire_search SET name = ?2 WHERE repertoire_id = ?1 AND comment = ''
+	`
+	sqliteSearchRepertoiresSQL = `
+		SELECT r.id, r.name, repertoire_search.node_id, repertoire_search.move_path, repertoire_search.comment,
+			-bm25(repertoire_search) AS rank
+		FROM repertoire_search
+		JOIN repertoires r ON r.id = repertoire_search.repertoire_id
+		WHERE repertoire_search MATCH ?2 AND repertoire_search.user_id = ?1
+		ORDER BY rank DESC, r.name, repertoire_search.node_id
+		LIMIT ?3
+	`
+
+	sqliteInsertRevisionSQL = `
+		INSERT INTO repertoire_revisions (repertoire_id, version, tree_data, metadata, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		ON CONFLICT (repertoire_id, version) DO UPDATE
+		SET tree_data = excluded.tree_data, metadata = excluded.metadata, created_at = excluded.created_at
+	`
+	sqlitePruneRevisionsSQL = `
+		DELETE FROM repertoire_revisions
+		WHERE repertoire_id = ?1 AND version <= ?2
+	`
+	sqliteListRevisionsSQL = `
+		SELECT version, metadata, created_at
+		FROM repertoire_revisions
+		WHERE repertoire_id = ?1
+		ORDER BY version DESC
+	`
+	sqliteGetRevisionSQL = `
+		SELECT version, tree_data, metadata, created_at
+		FROM repertoire_revisions
+		WHERE repertoire_id = ?1 AND version = ?2
+	`
+
+	sqliteStudyNotesColumns = `repertoire_id, version, content, updated_by, created_at`
+
+	sqliteInsertStudyNotesSQL = `
+		INSERT INTO repertoire_notes (repertoire_id, version, content, updated_by, created_at)
+		SELECT ?1, COALESCE(MAX(version), 0) + 1, ?2, ?3, ?4
+		FROM repertoire_notes
+		WHERE repertoire_id = ?1
+		RETURNING ` + sqliteStudyNotesColumns
+	sqlitePruneStudyNotesSQL = `
+		DELETE FROM repertoire_notes
+		WHERE repertoire_id = ?1 AND version <= ?2
+	`
+	sqliteGetLatestStudyNotesSQL = `
+		SELECT ` + sqliteStudyNotesColumns + `
+		FROM repertoire_notes
+		WHERE repertoire_id = ?1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	sqliteGetStudyNotesSQL = `
+		SELECT ` + sqliteStudyNotesColumns + `
+		FROM repertoire_notes
+		WHERE repertoire_id = ?1 AND version = ?2
+	`
+	sqliteListStudyNotesRevisionsSQL = `
+		SELECT version, length(content), updated_by, created_at
+		FROM repertoire_notes
+		WHERE repertoire_id = ?1
+		ORDER BY version DESC
+	`
+
+	sqliteListUnlabeledRepertoiresSQL = `
+		SELECT id
+		FROM repertoires
+		WHERE opening_labels_version < ?1 AND NOT archived
+		ORDER BY updated_at
+		LIMIT ?2
+	`
+	sqliteMarkOpeningsLabeledSQL = `
+		UPDATE repertoires
+		SET opening_labels_version = ?2
+		WHERE id = ?1
+	`
+)
+
+// SQLiteRepertoireRepo implements RepertoireRepository using SQLite. Trees are stored as
+// JSON text and always written whole; the position and search indexes are rebuilt with them.
+type SQLiteRepertoireRepo struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepertoireRepo creates a new SQLite repertoire repository
+func NewSQLiteRepertoireRepo(db *sql.DB) *SQLiteRepertoireRepo {
+	return &SQLiteRepertoireRepo{db: db}
+}
+
+// scanSQLiteRepertoire scans a row selected with sqliteRepertoireColumns, followed by extra
+func scanSQLiteRepertoire(scan func(dest ...any) error, extra ...any) (*models.Repertoire, error) {
+	var rep models.Repertoire
+	var treeDataJSON, metadataJSON string
+	dest := append([]any{
+		&rep.ID,
+		&rep.Name,
+		&rep.Color,
+		&rep.CategoryID,
+		&treeDataJSON,
+		&metadataJSON,
+		&rep.CreatedAt,
+		&rep.UpdatedAt,
+		&rep.Version,
+		&rep.MatchActive,
+		&rep.Archived,
+	}, extra...)
+	if err := scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(treeDataJSON), &rep.TreeData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadataJSON), &rep.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	return &rep, nil
+}
+
+// GetByID retrieves a repertoire by its UUID
+func (r *SQLiteRepertoireRepo) GetByID(id string) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rep, err := scanSQLiteRepertoire(r.db.QueryRowContext(ctx, sqliteGetRepertoireByIDSQL, id).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to get repertoire: %w", err)
+	}
+	return rep, nil
+}
+
+// GetByColor retrieves all repertoires of a given color for a user
+func (r *SQLiteRepertoireRepo) GetByColor(userID string, color models.Color) ([]models.Repertoire, error) {
+	return r.list("failed to query repertoires", sqliteGetRepertoiresByColorSQL, userID, string(color))
+}
+
+// GetAll retrieves all repertoires for a user, white ones first
+func (r *SQLiteRepertoireRepo) GetAll(userID string) ([]models.Repertoire, error) {
+	return r.list("failed to query repertoires", sqliteGetAllRepertoiresSQL, userID)
+}
+
+// GetByCategory retrieves all repertoires in a specific category
+func (r *SQLiteRepertoireRepo) GetByCategory(categoryID string) ([]models.Repertoire, error) {
+	return r.list("failed to query repertoires by category", sqliteGetRepertoiresByCategorySQL, categoryID)
+}
+
+// GetUncategorized retrieves all repertoires without a category for a user and color
+func (r *SQLiteRepertoireRepo) GetUncategorized(userID string, color models.Color) ([]models.Repertoire, error) {
+	return r.list("failed to query uncategorized repertoires", sqliteGetUncategorizedRepertoiresSQL, userID, string(color))
+}
+
+// list returns the repertoires selected by query
+func (r *SQLiteRepertoireRepo) list(failure, query string, args ...any) ([]models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", failure, err)
+	}
+	defer rows.Close()
+
+	var repertoires []models.Repertoire
+	for rows.Next() {
+		rep, err := scanSQLiteRepertoire(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire: %w", err)
+		}
+		repertoires = append(repertoires, *rep)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating repertoires: %w", err)
+	}
+	return repertoires, nil
+}
+
+// Create creates a new repertoire with a name and color for a user
+func (r *SQLiteRepertoireRepo) Create(userID string, name string, color models.Color) (*models.Repertoire, error) {
+	return r.CreateWithCategory(userID, name, color, nil)
+}
+
+// CreateWithCategory creates a new repertoire with a name, color, and optional category for a user
+func (r *SQLiteRepertoireRepo) CreateWithCategory(userID string, name string, color models.Color, categoryID *string) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rootNode := models.RepertoireNode{
+		ID:          uuid.New().String(),
+		FEN:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		MoveNumber:  0,
+		ColorToMove: models.ChessColorWhite,
+		Children:    []*models.RepertoireNode{},
+	}
+	metadata := models.Metadata{TotalNodes: 1}
+
+	treeDataJSON, err := json.Marshal(rootNode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree_data: %w", err)
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rep, err := scanSQLiteRepertoire(tx.QueryRowContext(ctx, sqliteCreateRepertoireSQL,
+		uuid.New().String(), userID, name, string(color), categoryID, string(treeDataJSON), string(metadataJSON), sqliteNow(),
+	).Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repertoire: %w", err)
+	}
+
+	if err := r.reindex(ctx, tx, rep, userID, string(treeDataJSON), string(metadataJSON)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+	return rep, nil
+}
+
+// Save saves tree data and metadata for a repertoire by ID
+func (r *SQLiteRepertoireRepo) Save(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	treeDataJSON, err := json.Marshal(treeData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree_data: %w", err)
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	rep, err := scanSQLiteRepertoire(tx.QueryRowContext(ctx, sqliteUpdateRepertoireByIDSQL,
+		id, string(treeDataJSON), string(metadataJSON), sqliteNow(),
+	).Scan, &userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save repertoire: %w", err)
+	}
+
+	if err := r.reindex(ctx, tx, rep, userID, string(treeDataJSON), string(metadataJSON)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+	return rep, nil
+}
+
+// SaveNodes saves an edited tree, provided the stored tree is still at version. Unlike the
+// PostgreSQL backend, which patches the changed nodes in place, the whole tree is written:
+// the database is local, so there is no payload to save. ErrRepertoireVersionConflict is
+// returned when the tree was saved since it was read.
+func (r *SQLiteRepertoireRepo) SaveNodes(id string, version int, tree models.RepertoireNode, changes NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	// The changes are only checked against the tree, as the PostgreSQL backend does
+	if _, _, err := planNodeWrites(&tree, changes); err != nil {
+		return nil, err
+	}
+	treeDataJSON, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree_data: %w", err)
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	rep, err := scanSQLiteRepertoire(tx.QueryRowContext(ctx, sqliteSaveRepertoireNodesSQL,
+		id, version, string(treeDataJSON), string(metadataJSON), sqliteNow(),
+	).Scan, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, sqliteCheckRepertoireExistsByIDSQL, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check repertoire existence: %w", err)
+		}
+		if !exists {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, ErrRepertoireVersionConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save repertoire: %w", err)
+	}
+
+	if err := r.reindex(ctx, tx, rep, userID, string(treeDataJSON), string(metadataJSON)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+	return rep, nil
+}
+
+// reindex rebuilds the position and search indexes of a saved tree and records its revision
+func (r *SQLiteRepertoireRepo) reindex(ctx context.Context, tx *sql.Tx, rep *models.Repertoire, userID, treeDataJSON, metadataJSON string) error {
+	if _, err := tx.ExecContext(ctx, sqliteDeleteRepertoirePositionsSQL, rep.ID); err != nil {
+		return fmt.Errorf("failed to clear repertoire positions: %w", err)
+	}
+	for _, row := range positionRows(rep.ID, userID, &rep.TreeData) {
+		if _, err := tx.ExecContext(ctx, sqliteInsertRepertoirePositionSQL, row...); err != nil {
+			return fmt.Errorf("failed to index repertoire positions: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, sqliteDeleteRepertoireSearchSQL, rep.ID); err != nil {
+		return fmt.Errorf("failed to clear repertoire comments: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqliteInsertRepertoireSearchSQL, rep.ID, rep.TreeData.ID, userID, "[]", "", rep.Name); err != nil {
+		return fmt.Errorf("failed to index repertoire name: %w", err)
+	}
+	for _, row := range commentRows(rep.ID, userID, &rep.TreeData, []string{}) {
+		movePath, err := json.Marshal(row[3])
+		if err != nil {
+			return fmt.Errorf("failed to marshal move path: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, sqliteInsertRepertoireSearchSQL, row[0], row[1], row[2], string(movePath), row[4], ""); err != nil {
+			return fmt.Errorf("failed to index repertoire comments: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, sqliteInsertRevisionSQL, rep.ID, rep.Version, treeDataJSON, metadataJSON, sqliteNow()); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlitePruneRevisionsSQL, rep.ID, rep.Version-config.MaxRepertoireRevisions); err != nil {
+		return fmt.Errorf("failed to prune revisions: %w", err)
+	}
+	return nil
+}
+
+// UpdateName updates the name of a repertoire
+func (r *SQLiteRepertoireRepo) UpdateName(id string, name string) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rep, err := scanSQLiteRepertoire(tx.QueryRowContext(ctx, sqliteUpdateRepertoireNameSQL, id, name, sqliteNow()).Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update repertoire name: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqliteRenameRepertoireSearchSQL, id, name); err != nil {
+		return nil, fmt.Errorf("failed to index repertoire name: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit repertoire: %w", err)
+	}
+	return rep, nil
+}
+
+// UpdateCategory updates the category of a repertoire
+func (r *SQLiteRepertoireRepo) UpdateCategory(id string, categoryID *string) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rep, err := scanSQLiteRepertoire(r.db.QueryRowContext(ctx, sqliteUpdateRepertoireCategorySQL, id, categoryID, sqliteNow()).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to update repertoire category: %w", err)
+	}
+	return rep, nil
+}
+
+// SetMatchActive sets whether imported games are matched against a repertoire
+func (r *SQLiteRepertoireRepo) SetMatchActive(id string, active bool) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rep, err := scanSQLiteRepertoire(r.db.QueryRowContext(ctx, sqliteUpdateRepertoireMatchActiveSQL, id, active).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to update repertoire matching: %w", err)
+	}
+	return rep, nil
+}
+
+// SetArchived archives or unarchives a repertoire
+func (r *SQLiteRepertoireRepo) SetArchived(id string, archived bool) (*models.Repertoire, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rep, err := scanSQLiteRepertoire(r.db.QueryRowContext(ctx, sqliteUpdateRepertoireArchivedSQL, id, archived).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, fmt.Errorf("failed to update repertoire archive state: %w", err)
+	}
+	return rep, nil
+}
+
+// GetMatchExcluded returns the IDs of the repertoires of a user that are left out of game
+// matching, either themselves or through their category
+func (r *SQLiteRepertoireRepo) GetMatchExcluded(userID string) (map[string]bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetMatchExcludedRepertoiresSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repertoires excluded from matching: %w", err)
+	}
+	defer rows.Close()
+
+	excluded := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire id: %w", err)
+		}
+		excluded[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate repertoires excluded from matching: %w", err)
+	}
+	return excluded, nil
+}
+
+// Delete deletes a repertoire by ID
+func (r *SQLiteRepertoireRepo) Delete(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, sqliteDeleteRepertoireSQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete repertoire: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete repertoire: %w", err)
+	}
+	if affected == 0 {
+		return ErrRepertoireNotFound
+	}
+	return nil
+}
+
+// Count returns the total number of repertoires for a user
+func (r *SQLiteRepertoireRepo) Count(userID string) (int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, sqliteCountRepertoiresSQL, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count repertoires: %w", err)
+	}
+	return count, nil
+}
+
+// Exists checks if a repertoire exists by ID
+func (r *SQLiteRepertoireRepo) Exists(id string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, sqliteCheckRepertoireExistsByIDSQL, id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check repertoire existence: %w", err)
+	}
+	return exists, nil
+}
+
+// BelongsToUser checks if a repertoire belongs to a specific user
+func (r *SQLiteRepertoireRepo) BelongsToUser(id string, userID string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var belongs bool
+	if err := r.db.QueryRowContext(ctx, sqliteBelongsToUserRepertoireSQL, id, userID).Scan(&belongs); err != nil {
+		return false, fmt.Errorf("failed to check repertoire ownership: %w", err)
+	}
+	return belongs, nil
+}
+
+// FindPositions returns the nodes of the user's repertoires reaching any of the given positions
+func (r *SQLiteRepertoireRepo) FindPositions(userID string, fens []string) ([]models.RepertoirePosition, error) {
+	if len(fens) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	keys := make([]string, len(fens))
+	for i, fen := range fens {
+		keys[i] = PositionIndexKey(fen)
+	}
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal positions: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqliteFindRepertoirePositionsSQL, userID, string(keysJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repertoire positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []models.RepertoirePosition
+	for rows.Next() {
+		var p models.RepertoirePosition
+		if err := rows.Scan(&p.RepertoireID, &p.NodeID, &p.FEN); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating repertoire positions: %w", err)
+	}
+	return positions, nil
+}
+
+// Search returns the node comments and repertoire names of the user matching a web-search style query, best first
+func (r *SQLiteRepertoireRepo) Search(userID, query string, limit int) ([]models.RepertoireSearchResult, error) {
+	results := []models.RepertoireSearchResult{}
+	match := ftsQuery(query)
+	if match == "" {
+		return results, nil
+	}
+
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteSearchRepertoiresSQL, userID, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search repertoires: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.RepertoireSearchResult
+		var movePath string
+		if err := rows.Scan(&m.RepertoireID, &m.RepertoireName, &m.NodeID, &movePath, &m.Comment, &m.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		if err := json.Unmarshal([]byte(movePath), &m.MovePath); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal move path: %w", err)
+		}
+		results = append(results, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+	return results, nil
+}
+
+// ListRevisions returns the kept revisions of a repertoire without their trees, newest first
+func (r *SQLiteRepertoireRepo) ListRevisions(repertoireID string) ([]models.RepertoireRevision, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteListRevisionsSQL, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.RepertoireRevision{}
+	for rows.Next() {
+		var rev models.RepertoireRevision
+		var metadataJSON string
+		if err := rows.Scan(&rev.Version, &metadataJSON, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		if err := json.Unmarshal([]byte(metadataJSON), &rev.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetRevision returns one revision of a repertoire with its tree
+func (r *SQLiteRepertoireRepo) GetRevision(repertoireID string, version int) (*models.RepertoireRevision, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var rev models.RepertoireRevision
+	var treeDataJSON, metadataJSON string
+	err := r.db.QueryRowContext(ctx, sqliteGetRevisionSQL, repertoireID, version).Scan(&rev.Version, &treeDataJSON, &metadataJSON, &rev.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+
+	rev.TreeData = &models.RepertoireNode{}
+	if err := json.Unmarshal([]byte(treeDataJSON), rev.TreeData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadataJSON), &rev.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	return &rev, nil
+}
+
+func scanSQLiteStudyNotes(row *sql.Row) (*models.StudyNotes, error) {
+	var n models.StudyNotes
+	var updatedAt sql.NullTime
+	if err := row.Scan(&n.RepertoireID, &n.Version, &n.Content, &n.UpdatedBy, &updatedAt); err != nil {
+		return nil, err
+	}
+	n.UpdatedAt = &updatedAt.Time
+	return &n, nil
+}
+
+// GetNotes returns a version of the study notes of a repertoire, the newest one when version is 0
+func (r *SQLiteRepertoireRepo) GetNotes(repertoireID string, version int) (*models.StudyNotes, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var row *sql.Row
+	if version == 0 {
+		row = r.db.QueryRowContext(ctx, sqliteGetLatestStudyNotesSQL, repertoireID)
+	} else {
+		row = r.db.QueryRowContext(ctx, sqliteGetStudyNotesSQL, repertoireID, version)
+	}
+	notes, err := scanSQLiteStudyNotes(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStudyNotesNotFound
+		}
+		return nil, fmt.Errorf("failed to get study notes: %w", err)
+	}
+	return notes, nil
+}
+
+// SaveNotes stores the study notes of a repertoire as a new version, dropping the versions
+// beyond config.MaxStudyNotesRevisions. Write transactions are serialized by SQLite, so
+// concurrent saves need no lock of their own.
+func (r *SQLiteRepertoireRepo) SaveNotes(repertoireID, userID, content string) (*models.StudyNotes, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, sqliteCheckRepertoireExistsByIDSQL, repertoireID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check repertoire existence: %w", err)
+	}
+	if !exists {
+		return nil, ErrRepertoireNotFound
+	}
+
+	notes, err := scanSQLiteStudyNotes(tx.QueryRowContext(ctx, sqliteInsertStudyNotesSQL, repertoireID, content, userID, sqliteNow()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save study notes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlitePruneStudyNotesSQL, repertoireID, notes.Version-config.MaxStudyNotesRevisions); err != nil {
+		return nil, fmt.Errorf("failed to prune study notes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return notes, nil
+}
+
+// ListNoteRevisions returns the kept versions of the study notes of a repertoire, newest first
+func (r *SQLiteRepertoireRepo) ListNoteRevisions(repertoireID string) ([]models.StudyNotesRevision, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteListStudyNotesRevisionsSQL, repertoireID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list study notes revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.StudyNotesRevision{}
+	for rows.Next() {
+		var rev models.StudyNotesRevision
+		if err := rows.Scan(&rev.Version, &rev.Length, &rev.UpdatedBy, &rev.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan study notes revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating study notes revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// ListUnlabeled returns the IDs of unarchived repertoires whose opening labels predate labelsVersion,
+// least recently updated first
+func (r *SQLiteRepertoireRepo) ListUnlabeled(labelsVersion, limit int) ([]string, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteListUnlabeledRepertoiresSQL, labelsVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unlabeled repertoires: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan repertoire id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unlabeled repertoires: %w", err)
+	}
+	return ids, nil
+}
+
+// MarkOpeningsLabeled records the version of the labeling applied to the tree of a repertoire
+func (r *SQLiteRepertoireRepo) MarkOpeningsLabeled(id string, labelsVersion int) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteMarkOpeningsLabeledSQL, id, labelsVersion); err != nil {
+		return fmt.Errorf("failed to mark repertoire labeled: %w", err)
+	}
+	return nil
+}
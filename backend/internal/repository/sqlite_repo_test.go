@@ -0,0 +1,256 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+func openTestSQLite(t *testing.T) *SQLiteDB {
+	t.Helper()
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "treechess.db"))
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	return db
+}
+
+func TestSQLiteDB_MigrateIsIdempotent(t *testing.T) {
+	db := openTestSQLite(t)
+	require.NoError(t, db.Migrate())
+
+	var applied int
+	require.NoError(t, db.DB.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, 4, applied)
+}
+
+func TestSQLiteUserRepo_CreateAndConflicts(t *testing.T) {
+	repo := NewSQLiteUserRepo(openTestSQLite(t).DB)
+
+	user, err := repo.Create("Alice@Example.com", "alice", "hash")
+	require.NoError(t, err)
+	require.NotNil(t, user.Email)
+	assert.Equal(t, "alice@example.com", *user.Email)
+
+	_, err = repo.Create("alice@example.com", "alice2", "hash")
+	assert.ErrorIs(t, err, ErrEmailExists)
+	_, err = repo.Create("other@example.com", "alice", "hash")
+	assert.ErrorIs(t, err, ErrUsernameExists)
+
+	found, err := repo.GetByEmail("ALICE@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = repo.GetByID("00000000-0000-0000-0000-000000000000")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestSQLiteRepertoireRepo_SaveIndexesTree(t *testing.T) {
+	db := openTestSQLite(t)
+	user, err := NewSQLiteUserRepo(db.DB).Create("bob@example.com", "bob", "hash")
+	require.NoError(t, err)
+	repo := NewSQLiteRepertoireRepo(db.DB)
+
+	rep, err := repo.Create(user.ID, "Italian Game", models.ColorWhite)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rep.Version)
+
+	e4, comment := "e4", "Best by test"
+	tree := rep.TreeData
+	tree.Children = []*models.RepertoireNode{{
+		ID:          "n1",
+		FEN:         "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+		Move:        &e4,
+		MoveNumber:  1,
+		ColorToMove: models.ChessColorBlack,
+		ParentID:    &tree.ID,
+		Comment:     &comment,
+		Children:    []*models.RepertoireNode{},
+	}}
+	saved, err := repo.SaveNodes(rep.ID, rep.Version, tree, NodeChanges{Added: []string{"n1"}}, models.Metadata{TotalNodes: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, saved.Version)
+
+	_, err = repo.SaveNodes(rep.ID, rep.Version, tree, NodeChanges{}, models.Metadata{TotalNodes: 2})
+	assert.ErrorIs(t, err, ErrRepertoireVersionConflict)
+
+	positions, err := repo.FindPositions(user.ID, []string{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"})
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "n1", positions[0].NodeID)
+
+	results, err := repo.Search(user.ID, "test -gambit", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "n1", results[0].NodeID)
+	assert.Equal(t, []string{"e4"}, results[0].MovePath)
+
+	_, err = repo.UpdateName(rep.ID, "Ruy Lopez")
+	require.NoError(t, err)
+	results, err = repo.Search(user.ID, "ruy", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, tree.ID, results[0].NodeID)
+	assert.Empty(t, results[0].MovePath)
+
+	revisions, err := repo.ListRevisions(rep.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Version)
+
+	archived, err := repo.SetArchived(rep.ID, true)
+	require.NoError(t, err)
+	assert.True(t, archived.Archived)
+	unlabeled, err := repo.ListUnlabeled(1000, 10)
+	require.NoError(t, err)
+	assert.Empty(t, unlabeled)
+	_, err = repo.SetArchived("00000000-0000-0000-0000-000000000000", true)
+	assert.ErrorIs(t, err, ErrRepertoireNotFound)
+
+	require.NoError(t, repo.Delete(rep.ID))
+	results, err = repo.Search(user.ID, "test", 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestSQLiteRepertoireRepo_Notes(t *testing.T) {
+	db := openTestSQLite(t)
+	user, err := NewSQLiteUserRepo(db.DB).Create("carol@example.com", "carol", "hash")
+	require.NoError(t, err)
+	repo := NewSQLiteRepertoireRepo(db.DB)
+	rep, err := repo.Create(user.ID, "Sicilian", models.ColorBlack)
+	require.NoError(t, err)
+
+	_, err = repo.GetNotes(rep.ID, 0)
+	assert.ErrorIs(t, err, ErrStudyNotesNotFound)
+
+	_, err = repo.SaveNotes(rep.ID, user.ID, "first")
+	require.NoError(t, err)
+	notes, err := repo.SaveNotes(rep.ID, user.ID, "second draft")
+	require.NoError(t, err)
+	assert.Equal(t, 2, notes.Version)
+
+	latest, err := repo.GetNotes(rep.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "second draft", latest.Content)
+
+	revisions, err := repo.ListNoteRevisions(rep.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, len("second draft"), revisions[0].Length)
+
+	_, err = repo.SaveNotes("00000000-0000-0000-0000-000000000000", user.ID, "orphan")
+	assert.ErrorIs(t, err, ErrRepertoireNotFound)
+}
+
+func TestSQLiteAnalysisRepo_GamesAndStats(t *testing.T) {
+	db := openTestSQLite(t)
+	user, err := NewSQLiteUserRepo(db.DB).Create("dave@example.com", "dave", "hash")
+	require.NoError(t, err)
+	rep, err := NewSQLiteRepertoireRepo(db.DB).Create(user.ID, "London", models.ColorWhite)
+	require.NoError(t, err)
+	repo := NewSQLiteAnalysisRepo(db.DB)
+
+	spent := 4.5
+	games := []models.GameAnalysis{
+		{
+			GameIndex: 0,
+			Headers:   models.PGNHeaders{"White": "dave", "Black": "eve", "TimeControl": "180+2"},
+			UserColor: models.ColorWhite,
+			Moves: []models.MoveAnalysis{
+				{PlyNumber: 0, SAN: "d4", Status: "in-repertoire", IsUserMove: true, TimeSpent: &spent},
+				{PlyNumber: 1, SAN: "e5", Status: "opponent-new", Classifications: []string{"surprise"}},
+			},
+			MatchedRepertoire: &models.RepertoireRef{ID: rep.ID, Name: rep.Name, Version: 3, TreeHash: "sha256:abc"},
+		},
+		{
+			GameIndex: 1,
+			Headers:   models.PGNHeaders{"White": "dave", "Black": "frank"},
+			UserColor: models.ColorWhite,
+			Moves: []models.MoveAnalysis{
+				{PlyNumber: 0, SAN: "e4", Status: "out-of-repertoire", IsUserMove: true},
+			},
+			MatchedRepertoire: &models.RepertoireRef{ID: rep.ID, Name: rep.Name},
+		},
+	}
+	summary, err := repo.Save(user.ID, "dave", "sync_lichess_dave.pgn", models.ImportProvenance{Source: models.ImportSourceLichess}, 2, games)
+	require.NoError(t, err)
+	require.NoError(t, repo.RecordSkippedDuplicates(user.ID, "sync_lichess_dave.pgn", 3))
+
+	page, err := repo.GetAllGames(user.ID, 10, 0, "", "", "", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, "eve", page.Games[0].Black)
+	assert.True(t, page.Games[0].Synced)
+	assert.Equal(t, []string{"surprise"}, page.Games[0].Classifications)
+
+	classified, err := repo.GetAllGames(user.ID, 10, 0, "", "", "", "surprise", false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, classified.Total)
+	require.Len(t, classified.Games, 1)
+	assert.Equal(t, 0, classified.Games[0].GameIndex)
+
+	stored, err := repo.GetGame(summary.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, &models.RepertoireRef{ID: rep.ID, Name: rep.Name, Version: 3, TreeHash: "sha256:abc"}, stored.MatchedRepertoire)
+	unstamped, err := repo.GetGame(summary.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &models.RepertoireRef{ID: rep.ID, Name: rep.Name}, unstamped.MatchedRepertoire)
+
+	require.NoError(t, repo.MarkGameViewed(user.ID, summary.ID, 0))
+	viewed, err := repo.GetViewedGames(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, viewed, 1)
+
+	stats, err := repo.GetImportStats(user.ID)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, models.ImportSourceLichess, stats[0].Source)
+	assert.Equal(t, time.Now().UTC().Format("2006-01"), stats[0].Month)
+	assert.Equal(t, 2, stats[0].GamesImported)
+	assert.Equal(t, 3, stats[0].DuplicatesSkipped)
+	assert.InDelta(t, 0.5, stats[0].OutOfRepertoireRate, 1e-9)
+
+	depth, err := repo.GetBookDepth(user.ID, rep.ID)
+	require.NoError(t, err)
+	require.Len(t, depth, 1)
+	assert.Equal(t, 1, depth[0].UserDeviations)
+	assert.Equal(t, 1, depth[0].OpponentDeviations)
+	require.NotNil(t, depth[0].OpponentLeaveBookPly)
+	assert.Equal(t, 1.0, *depth[0].OpponentLeaveBookPly)
+
+	times, err := repo.GetMoveTimes(user.ID, rep.ID, 20)
+	require.NoError(t, err)
+	require.Len(t, times, 1)
+	assert.Equal(t, 1, times[0].InBook)
+	assert.Equal(t, 4.5, times[0].AvgTimeSpent)
+
+	require.NoError(t, repo.DeleteGame(summary.ID, 0))
+	require.NoError(t, repo.DeleteGame(summary.ID, 1))
+	_, err = repo.GetByID(summary.ID)
+	assert.ErrorIs(t, err, ErrAnalysisNotFound)
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"najdorf", `("najdorf")`},
+		{`"poisoned pawn" -gambit`, `("poisoned pawn" NOT "gambit")`},
+		{"dragon or najdorf", `("dragon") OR ("najdorf")`},
+		{`say "hi""`, `("say" AND "hi")`},
+		{`a"b`, `("a" AND "b")`},
+		{"-gambit", ""},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ftsQuery(tt.query), tt.query)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build sqlite
+
+package repository
+
+import "strings"
+
+// ftsQuery translates a web-search style query, as PostgreSQL's websearch_to_tsquery reads
+// it, into an FTS5 query: words and "quoted phrases" must all match, -word excludes a word,
+// and "or" separates alternatives. It returns "" when the query has nothing to look for.
+func ftsQuery(query string) string {
+	var groups []string
+	for _, group := range splitSearchAlternatives(searchTokens(query)) {
+		var include, exclude []string
+		for _, tok := range group {
+			if tok.negated {
+				exclude = append(exclude, ftsString(tok.text))
+			} else {
+				include = append(include, ftsString(tok.text))
+			}
+		}
+		// FTS5 has no pure negation: a group needs something to match
+		if len(include) == 0 {
+			continue
+		}
+		expr := strings.Join(include, " AND ")
+		for _, term := range exclude {
+			expr += " NOT " + term
+		}
+		groups = append(groups, "("+expr+")")
+	}
+	return strings.Join(groups, " OR ")
+}
+
+type searchToken struct {
+	text    string
+	negated bool
+	or      bool
+}
+
+// searchTokens splits a query into words, phrases and "or" separators
+func searchTokens(query string) []searchToken {
+	var tokens []searchToken
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || (c == '-' && i+1 < len(query) && query[i+1] == '"'):
+			negated := c == '-'
+			if negated {
+				i++
+			}
+			end := strings.IndexByte(query[i+1:], '"')
+			var phrase string
+			if end < 0 {
+				phrase, i = query[i+1:], len(query)
+			} else {
+				phrase, i = query[i+1:i+1+end], i+2+end
+			}
+			if strings.TrimSpace(phrase) != "" {
+				tokens = append(tokens, searchToken{text: phrase, negated: negated})
+			}
+		default:
+			end := strings.IndexAny(query[i:], " \t\n\"")
+			if end < 0 {
+				end = len(query) - i
+			}
+			word := query[i : i+end]
+			i += end
+			switch {
+			case strings.EqualFold(word, "or"):
+				tokens = append(tokens, searchToken{or: true})
+			case strings.HasPrefix(word, "-"):
+				if word = strings.TrimLeft(word, "-"); word != "" {
+					tokens = append(tokens, searchToken{text: word, negated: true})
+				}
+			default:
+				tokens = append(tokens, searchToken{text: word})
+			}
+		}
+	}
+	return tokens
+}
+
+// splitSearchAlternatives groups tokens between "or" separators
+func splitSearchAlternatives(tokens []searchToken) [][]searchToken {
+	groups := [][]searchToken{nil}
+	for _, tok := range tokens {
+		if tok.or {
+			groups = append(groups, nil)
+			continue
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], tok)
+	}
+	return groups
+}
+
+// ftsString quotes text as an FTS5 string, which the tokenizer splits into a phrase
+func ftsString(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
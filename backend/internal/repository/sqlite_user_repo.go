@@ -0,0 +1,403 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	// Sync times are written as RFC 3339 so the JSON decodes into time.Time
+	sqliteLinkedAccountsColumn = `COALESCE((
+		SELECT json_group_array(json_object('id', la.id, 'provider', la.provider, 'username', la.username,
+				'lastSyncAt', strftime('%Y-%m-%dT%H:%M:%fZ', la.last_sync_at))
+			ORDER BY la.provider, la.created_at, la.username)
+		FROM linked_accounts la WHERE la.user_id = users.id
+	), '[]')`
+
+	sqliteUserColumns = `id, username, email, password_hash, oauth_provider, oauth_id, ` + sqliteLinkedAccountsColumn + `, lichess_access_token, last_lichess_sync_at, last_chesscom_sync_at, time_format_prefs, analysis_depth, weekly_digest, timezone, locale, created_at`
+
+	sqliteCreateUserSQL = `
+		INSERT INTO users (id, username, email, password_hash, created_at)
+		VALUES (?1, ?2, LOWER(?3), ?4, ?5)
+		RETURNING ` + sqliteUserColumns + `
+	`
+	sqliteGetUserByUsernameSQL = `
+		SELECT ` + sqliteUserColumns + `
+		FROM users WHERE username = ?1
+	`
+	sqliteGetUserByEmailSQL = `
+		SELECT ` + sqliteUserColumns + `
+		FROM users WHERE LOWER(email) = LOWER(?1)
+	`
+	sqliteGetUserByIDSQL = `
+		SELECT ` + sqliteUserColumns + `
+		FROM users WHERE id = ?1
+	`
+	sqliteCheckUserExistsSQL = `
+		SELECT EXISTS(SELECT 1 FROM users WHERE username = ?1)
+	`
+	sqliteCheckEmailExistsSQL = `
+		SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = LOWER(?1))
+	`
+	sqliteFindByOAuthSQL = `
+		SELECT ` + sqliteUserColumns + `
+		FROM users WHERE oauth_provider = ?1 AND oauth_id = ?2
+	`
+	sqliteCreateOAuthUserSQL = `
+		INSERT INTO users (id, username, oauth_provider, oauth_id, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+	`
+	sqliteUpdateProfileSQL = `
+		UPDATE users SET time_format_prefs = ?2, analysis_depth = COALESCE(?3, analysis_depth),
+			timezone = COALESCE(?4, timezone), locale = COALESCE(?5, locale)
+		WHERE id = ?1
+		RETURNING ` + sqliteUserColumns + `
+	`
+	sqliteInsertLinkedAccountSQL = `
+		INSERT INTO linked_accounts (id, user_id, provider, username, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		ON CONFLICT DO NOTHING
+	`
+	sqliteDeleteUnlistedAccountsSQL = `
+		DELETE FROM linked_accounts
+		WHERE user_id = ?1 AND provider || ':' || LOWER(username) NOT IN (SELECT value FROM json_each(?2))
+	`
+	sqliteUpdateLinkedAccountSyncSQL = `
+		UPDATE linked_accounts SET last_sync_at = ?2
+		WHERE id = ?1
+	`
+	sqliteUpdateSyncTimestampsSQL = `
+		UPDATE users SET last_lichess_sync_at = COALESCE(?2, last_lichess_sync_at), last_chesscom_sync_at = COALESCE(?3, last_chesscom_sync_at)
+		WHERE id = ?1
+	`
+	sqliteUpdateLichessTokenSQL = `
+		UPDATE users SET lichess_access_token = ?2
+		WHERE id = ?1
+	`
+	sqliteUpdateNotificationsSQL = `
+		UPDATE users SET weekly_digest = ?2
+		WHERE id = ?1
+		RETURNING ` + sqliteUserColumns + `
+	`
+	sqliteListDigestRecipientsSQL = `
+		SELECT ` + sqliteUserColumns + `
+		FROM users
+		WHERE weekly_digest AND email IS NOT NULL AND (last_digest_at IS NULL OR last_digest_at < ?1)
+		ORDER BY created_at
+	`
+	sqliteMarkDigestSentSQL = `
+		UPDATE users SET last_digest_at = ?2
+		WHERE id = ?1
+	`
+	sqliteUpdatePasswordSQL = `
+		UPDATE users SET password_hash = ?2
+		WHERE id = ?1
+	`
+)
+
+// SQLiteUserRepo implements UserRepository using SQLite
+type SQLiteUserRepo struct {
+	db *sql.DB
+}
+
+// NewSQLiteUserRepo creates a new SQLite user repository
+func NewSQLiteUserRepo(db *sql.DB) *SQLiteUserRepo {
+	return &SQLiteUserRepo{db: db}
+}
+
+func scanSQLiteUser(scan func(dest ...any) error) (*models.User, error) {
+	var prefsJSON string
+	var linkedAccountsJSON string
+	var passwordHash *string
+	var user models.User
+	err := scan(
+		&user.ID, &user.Username, &user.Email, &passwordHash, &user.OAuthProvider, &user.OAuthID,
+		&linkedAccountsJSON, &user.LichessAccessToken,
+		&user.LastLichessSyncAt, &user.LastChesscomSyncAt, &prefsJSON, &user.AnalysisDepth, &user.WeeklyDigest,
+		&user.Timezone, &user.Locale, &user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if passwordHash != nil {
+		user.PasswordHash = *passwordHash
+	}
+	if err := json.Unmarshal([]byte(prefsJSON), &user.TimeFormatPrefs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal time format preferences: %w", err)
+	}
+
+	var accounts []models.LinkedAccount
+	if err := json.Unmarshal([]byte(linkedAccountsJSON), &accounts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal linked accounts: %w", err)
+	}
+	user.SetLinkedAccounts(accounts)
+	return &user, nil
+}
+
+func (r *SQLiteUserRepo) Create(email, username, passwordHash string) (*models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	id := uuid.New().String()
+	user, err := scanSQLiteUser(r.db.QueryRowContext(ctx, sqliteCreateUserSQL, id, username, email, passwordHash, sqliteNow()).Scan)
+	if err != nil {
+		if isSQLiteUniqueError(err) {
+			if strings.Contains(err.Error(), "users.email") {
+				return nil, ErrEmailExists
+			}
+			return nil, ErrUsernameExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepo) GetByUsername(username string) (*models.User, error) {
+	return r.getUser(sqliteGetUserByUsernameSQL, "failed to get user by username", username)
+}
+
+func (r *SQLiteUserRepo) GetByEmail(email string) (*models.User, error) {
+	return r.getUser(sqliteGetUserByEmailSQL, "failed to get user by email", email)
+}
+
+func (r *SQLiteUserRepo) GetByID(id string) (*models.User, error) {
+	return r.getUser(sqliteGetUserByIDSQL, "failed to get user by ID", id)
+}
+
+func (r *SQLiteUserRepo) FindByOAuth(provider, oauthID string) (*models.User, error) {
+	return r.getUser(sqliteFindByOAuthSQL, "failed to find user by OAuth", provider, oauthID)
+}
+
+// getUser returns the user selected by query, ErrUserNotFound when there is none
+func (r *SQLiteUserRepo) getUser(query, failure string, args ...any) (*models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	user, err := scanSQLiteUser(r.db.QueryRowContext(ctx, query, args...).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", failure, err)
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepo) CreateOAuth(provider, oauthID, username string) (*models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id := uuid.New().String()
+	now := sqliteNow()
+	if _, err := tx.ExecContext(ctx, sqliteCreateOAuthUserSQL, id, username, provider, oauthID, now); err != nil {
+		if isSQLiteUniqueError(err) {
+			return nil, ErrUsernameExists
+		}
+		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
+	}
+
+	// For Lichess OAuth, auto-link the Lichess account
+	if provider == models.ProviderLichess {
+		if _, err := tx.ExecContext(ctx, sqliteInsertLinkedAccountSQL, uuid.New().String(), id, models.ProviderLichess, username, now); err != nil {
+			return nil, fmt.Errorf("failed to link Lichess account: %w", err)
+		}
+	}
+
+	user, err := scanSQLiteUser(tx.QueryRowContext(ctx, sqliteGetUserByIDSQL, id).Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get created OAuth user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateProfile replaces the user's linked accounts and time format preferences.
+// Accounts that are kept (same provider, case-insensitive username) retain their sync state.
+// The analysis depth, time zone and locale are only changed when given.
+func (r *SQLiteUserRepo) UpdateProfile(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	keep := make([]string, len(accounts))
+	for i, a := range accounts {
+		keep[i] = a.Provider + ":" + strings.ToLower(a.Username)
+	}
+	keepJSON, err := json.Marshal(keep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal linked accounts: %w", err)
+	}
+	if timeFormatPrefs == nil {
+		timeFormatPrefs = []string{}
+	}
+	prefsJSON, err := json.Marshal(timeFormatPrefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal time format preferences: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sqliteDeleteUnlistedAccountsSQL, userID, string(keepJSON)); err != nil {
+		return nil, fmt.Errorf("failed to remove linked accounts: %w", err)
+	}
+	now := sqliteNow()
+	for _, a := range accounts {
+		if _, err := tx.ExecContext(ctx, sqliteInsertLinkedAccountSQL, uuid.New().String(), userID, a.Provider, a.Username, now); err != nil {
+			return nil, fmt.Errorf("failed to add linked account: %w", err)
+		}
+	}
+
+	user, err := scanSQLiteUser(tx.QueryRowContext(ctx, sqliteUpdateProfileSQL, userID, string(prefsJSON), analysisDepth, timezone, locale).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepo) UpdateLinkedAccountSync(accountID string, syncedAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteUpdateLinkedAccountSyncSQL, accountID, syncedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to update linked account sync: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepo) UpdateSyncTimestamps(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteUpdateSyncTimestampsSQL, userID, sqliteOptionalTime(lichessSyncAt), sqliteOptionalTime(chesscomSyncAt)); err != nil {
+		return fmt.Errorf("failed to update sync timestamps: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepo) UpdateLichessToken(userID, token string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteUpdateLichessTokenSQL, userID, token); err != nil {
+		return fmt.Errorf("failed to update Lichess token: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepo) Exists(username string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, sqliteCheckUserExistsSQL, username).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *SQLiteUserRepo) EmailExists(email string) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, sqliteCheckEmailExistsSQL, email).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check email existence: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *SQLiteUserRepo) UpdatePassword(userID, passwordHash string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteUpdatePasswordSQL, userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// UpdateNotifications sets the email notifications a user receives
+func (r *SQLiteUserRepo) UpdateNotifications(userID string, weeklyDigest bool) (*models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	user, err := scanSQLiteUser(r.db.QueryRowContext(ctx, sqliteUpdateNotificationsSQL, userID, weeklyDigest).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update notifications: %w", err)
+	}
+	return user, nil
+}
+
+// ListDigestRecipients returns the users with an email who opted in to the weekly digest
+// and were last sent one before sentBefore, or never
+func (r *SQLiteUserRepo) ListDigestRecipients(sentBefore time.Time) ([]models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, sqliteListDigestRecipientsSQL, sentBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// MarkDigestSent records when the weekly digest was last sent to a user
+func (r *SQLiteUserRepo) MarkDigestSent(userID string, sentAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, sqliteMarkDigestSentSQL, userID, sentAt.UTC()); err != nil {
+		return fmt.Errorf("failed to mark digest sent: %w", err)
+	}
+	return nil
+}
+
+// sqliteOptionalTime is t in UTC, or NULL
+func sqliteOptionalTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
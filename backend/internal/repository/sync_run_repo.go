@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	syncRunColumns = `id, user_id, status, accounts, COALESCE(error, ''), started_at, finished_at`
+
+	createSyncRunSQL = `
+		INSERT INTO sync_runs (user_id, status)
+		VALUES ($1, 'running')
+		RETURNING ` + syncRunColumns
+	finishSyncRunSQL = `
+		UPDATE sync_runs
+		SET status = $2, accounts = $3, error = NULLIF($4, ''), finished_at = $5
+		WHERE id = $1
+	`
+	getSyncRunSQL = `
+		SELECT ` + syncRunColumns + `
+		FROM sync_runs
+		WHERE id = $1
+	`
+	listSyncRunsSQL = `
+		SELECT ` + syncRunColumns + `
+		FROM sync_runs
+		WHERE user_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`
+)
+
+// PostgresSyncRunRepo implements SyncRunRepository using PostgreSQL
+type PostgresSyncRunRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresSyncRunRepo creates a new PostgresSyncRunRepo
+func NewPostgresSyncRunRepo(pool *pgxpool.Pool) *PostgresSyncRunRepo {
+	return &PostgresSyncRunRepo{pool: pool}
+}
+
+func scanSyncRun(row pgx.Row) (*models.SyncRun, error) {
+	var run models.SyncRun
+	var accountsJSON []byte
+	if err := row.Scan(&run.ID, &run.UserID, &run.Status, &accountsJSON, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+		return nil, err
+	}
+
+	var accounts []models.SyncAccountResult
+	if err := json.Unmarshal(accountsJSON, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sync accounts: %w", err)
+	}
+	run.SetAccounts(accounts)
+	return &run, nil
+}
+
+// Create records the start of a sync run
+func (r *PostgresSyncRunRepo) Create(userID string) (*models.SyncRun, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	run, err := scanSyncRun(r.pool.QueryRow(ctx, createSyncRunSQL, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sync run: %w", err)
+	}
+	return run, nil
+}
+
+// Finish stores the final status and account results of a sync run
+func (r *PostgresSyncRunRepo) Finish(run *models.SyncRun) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	accounts := run.Accounts
+	if accounts == nil {
+		accounts = []models.SyncAccountResult{}
+	}
+	accountsJSON, err := json.Marshal(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync accounts: %w", err)
+	}
+
+	result, err := r.pool.Exec(ctx, finishSyncRunSQL, run.ID, run.Status, accountsJSON, run.Error, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to finish sync run: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSyncRunNotFound
+	}
+	return nil
+}
+
+// GetByID returns a sync run by ID
+func (r *PostgresSyncRunRepo) GetByID(id string) (*models.SyncRun, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	run, err := scanSyncRun(r.pool.QueryRow(ctx, getSyncRunSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrSyncRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get sync run: %w", err)
+	}
+	return run, nil
+}
+
+// ListByUser returns the most recent sync runs of a user, newest first
+func (r *PostgresSyncRunRepo) ListByUser(userID string, limit int) ([]models.SyncRun, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listSyncRunsSQL, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.SyncRun
+	for rows.Next() {
+		run, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync runs: %w", err)
+	}
+	return runs, nil
+}
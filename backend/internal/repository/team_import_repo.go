@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	teamImportColumns = `id, user_id, team_id, options, analysis_depth, status, members, results,
+		COALESCE(error, ''), next_attempt_at, created_at, updated_at`
+
+	createTeamImportSQL = `
+		INSERT INTO team_imports (user_id, team_id, options, analysis_depth)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + teamImportColumns
+	getTeamImportSQL = `
+		SELECT ` + teamImportColumns + `
+		FROM team_imports
+		WHERE id = $1
+	`
+	// Claimed imports are skipped by other workers until they are deferred again or finish
+	claimTeamImportsSQL = `
+		UPDATE team_imports SET status = 'processing', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM team_imports
+			WHERE status = 'pending' AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			ORDER BY created_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + teamImportColumns
+	setTeamImportMembersSQL = `
+		UPDATE team_imports SET members = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	recordTeamMemberSQL = `
+		UPDATE team_imports SET results = results || $2::jsonb, updated_at = NOW()
+		WHERE id = $1
+	`
+	deferTeamImportSQL = `
+		UPDATE team_imports SET status = 'pending', next_attempt_at = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+)
+
+// PostgresTeamImportRepo implements TeamImportRepository using PostgreSQL
+type PostgresTeamImportRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTeamImportRepo creates a new PostgreSQL team import repository
+func NewPostgresTeamImportRepo(pool *pgxpool.Pool) *PostgresTeamImportRepo {
+	return &PostgresTeamImportRepo{pool: pool}
+}
+
+func scanTeamImport(row pgx.Row) (*models.TeamImport, error) {
+	var t models.TeamImport
+	var optionsJSON, membersJSON, resultsJSON []byte
+	if err := row.Scan(&t.ID, &t.UserID, &t.TeamID, &optionsJSON, &t.AnalysisDepth, &t.Status,
+		&membersJSON, &resultsJSON, &t.Error, &t.NextAttemptAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(optionsJSON, &t.Options); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal team import options: %w", err)
+	}
+	if err := json.Unmarshal(membersJSON, &t.Members); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal team members: %w", err)
+	}
+	if err := json.Unmarshal(resultsJSON, &t.Results); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal team member results: %w", err)
+	}
+	return &t, nil
+}
+
+// Create queues a pending team import
+func (r *PostgresTeamImportRepo) Create(userID, teamID string, options models.LichessImportOptions, analysisDepth *int) (*models.TeamImport, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal team import options: %w", err)
+	}
+
+	created, err := scanTeamImport(r.pool.QueryRow(ctx, createTeamImportSQL, userID, teamID, optionsJSON, analysisDepth))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create team import: %w", err)
+	}
+	return created, nil
+}
+
+// GetByID returns a team import by ID
+func (r *PostgresTeamImportRepo) GetByID(id string) (*models.TeamImport, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	t, err := scanTeamImport(r.pool.QueryRow(ctx, getTeamImportSQL, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTeamImportNotFound
+		}
+		return nil, fmt.Errorf("failed to get team import: %w", err)
+	}
+	return t, nil
+}
+
+// ClaimPending marks up to limit due pending imports as processing and returns them, oldest first
+func (r *PostgresTeamImportRepo) ClaimPending(limit int) ([]models.TeamImport, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, claimTeamImportsSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim team imports: %w", err)
+	}
+	defer rows.Close()
+
+	var imports []models.TeamImport
+	for rows.Next() {
+		t, err := scanTeamImport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan team import: %w", err)
+		}
+		imports = append(imports, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating team imports: %w", err)
+	}
+	return imports, nil
+}
+
+// SetMembers stores the roster of the team, in the order members are imported
+func (r *PostgresTeamImportRepo) SetMembers(id string, members []string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	membersJSON, err := json.Marshal(members)
+	if err != nil {
+		return fmt.Errorf("failed to marshal team members: %w", err)
+	}
+	if _, err := r.pool.Exec(ctx, setTeamImportMembersSQL, id, membersJSON); err != nil {
+		return fmt.Errorf("failed to set team members: %w", err)
+	}
+	return nil
+}
+
+// RecordMember appends the outcome of one member's import
+func (r *PostgresTeamImportRepo) RecordMember(id string, result models.TeamMemberImport) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	resultJSON, err := json.Marshal([]models.TeamMemberImport{result})
+	if err != nil {
+		return fmt.Errorf("failed to marshal team member result: %w", err)
+	}
+	if _, err := r.pool.Exec(ctx, recordTeamMemberSQL, id, resultJSON); err != nil {
+		return fmt.Errorf("failed to record team member: %w", err)
+	}
+	return nil
+}
+
+// Defer puts a claimed import back in the queue until the given time
+func (r *PostgresTeamImportRepo) Defer(id string, until time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, deferTeamImportSQL, id, until); err != nil {
+		return fmt.Errorf("failed to defer team import: %w", err)
+	}
+	return nil
+}
+
+// MarkDone marks an import as done
+func (r *PostgresTeamImportRepo) MarkDone(id string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE team_imports SET status = 'done', next_attempt_at = NULL, updated_at = $2 WHERE id = $1`,
+		id, time.Now(),
+	)
+	return err
+}
+
+// MarkFailed marks an import as failed with the reason
+func (r *PostgresTeamImportRepo) MarkFailed(id string, message string) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`UPDATE team_imports SET status = 'failed', error = $2, next_attempt_at = NULL, updated_at = $3 WHERE id = $1`,
+		id, message, time.Now(),
+	)
+	return err
+}
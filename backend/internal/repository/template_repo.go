@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	templateColumns = `id, user_id, name, color, description, tags, featured, tree_data, created_at`
+
+	listTemplatesSQL = `
+		SELECT ` + templateColumns + `
+		FROM repertoire_templates
+		WHERE featured OR user_id = $1
+		ORDER BY featured DESC, user_id NULLS FIRST, created_at, name
+	`
+	getTemplateByIDSQL = `
+		SELECT ` + templateColumns + `
+		FROM repertoire_templates
+		WHERE id = $1
+	`
+	createTemplateSQL = `
+		INSERT INTO repertoire_templates (id, user_id, name, color, description, tags, featured, tree_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + templateColumns
+	ensureBuiltinTemplateSQL = `
+		INSERT INTO repertoire_templates (id, name, color, description, tags, featured, tree_data)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6)
+		ON CONFLICT (id) DO NOTHING
+	`
+	setTemplateFeaturedSQL = `
+		UPDATE repertoire_templates SET featured = $2
+		WHERE id = $1
+		RETURNING ` + templateColumns
+)
+
+// PostgresTemplateRepo implements TemplateRepository using PostgreSQL
+type PostgresTemplateRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTemplateRepo creates a new PostgresTemplateRepo
+func NewPostgresTemplateRepo(pool *pgxpool.Pool) *PostgresTemplateRepo {
+	return &PostgresTemplateRepo{pool: pool}
+}
+
+func scanTemplate(row pgx.Row) (*models.RepertoireTemplate, error) {
+	var t models.RepertoireTemplate
+	var treeDataJSON []byte
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Color, &t.Description, &t.Tags, &t.Featured, &treeDataJSON, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(treeDataJSON, &t.TreeData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree_data: %w", err)
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	return &t, nil
+}
+
+// List returns the featured templates followed by the user's own templates
+func (r *PostgresTemplateRepo) List(userID string) ([]models.RepertoireTemplate, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listTemplatesSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.RepertoireTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetByID returns a template by ID
+func (r *PostgresTemplateRepo) GetByID(id string) (*models.RepertoireTemplate, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	t, err := scanTemplate(r.pool.QueryRow(ctx, getTemplateByIDSQL, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return t, nil
+}
+
+// Create stores a new template
+func (r *PostgresTemplateRepo) Create(tmpl models.RepertoireTemplate) (*models.RepertoireTemplate, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	treeDataJSON, err := json.Marshal(tmpl.TreeData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree_data: %w", err)
+	}
+
+	t, err := scanTemplate(r.pool.QueryRow(ctx, createTemplateSQL,
+		tmpl.ID, tmpl.UserID, tmpl.Name, string(tmpl.Color), tmpl.Description, tmpl.Tags, tmpl.Featured, treeDataJSON,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return t, nil
+}
+
+// SetFeatured marks a template as featured or removes it from the featured list
+func (r *PostgresTemplateRepo) SetFeatured(id string, featured bool) (*models.RepertoireTemplate, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	t, err := scanTemplate(r.pool.QueryRow(ctx, setTemplateFeaturedSQL, id, featured))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return t, nil
+}
+
+// EnsureBuiltin inserts the built-in templates that are missing. Existing rows are left
+// untouched so that an admin's curation survives restarts.
+func (r *PostgresTemplateRepo) EnsureBuiltin(templates []models.RepertoireTemplate) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	for _, t := range templates {
+		treeDataJSON, err := json.Marshal(t.TreeData)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tree_data: %w", err)
+		}
+		batch.Queue(ensureBuiltinTemplateSQL, t.ID, t.Name, string(t.Color), t.Description, t.Tags, treeDataJSON)
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert built-in templates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	trainingSessionColumnsSQL = `id, repertoire_id, started_at, last_answer_at, reviews, correct`
+	extendTrainingSessionSQL  = `
+		UPDATE training_sessions
+		SET last_answer_at = $3, reviews = reviews + 1, correct = correct + $4
+		WHERE id = (
+			SELECT id FROM training_sessions
+			WHERE user_id = $1 AND repertoire_id = $2 AND last_answer_at >= $5
+			ORDER BY last_answer_at DESC
+			LIMIT 1
+		)
+		RETURNING ` + trainingSessionColumnsSQL
+	createTrainingSessionSQL = `
+		INSERT INTO training_sessions (user_id, repertoire_id, started_at, last_answer_at, reviews, correct)
+		VALUES ($1, $2, $3, $3, 1, $4)
+		RETURNING ` + trainingSessionColumnsSQL
+	recordTrainingDaySQL = `
+		INSERT INTO training_activity (user_id, day, reviews, correct)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (user_id, day) DO UPDATE
+		SET reviews = training_activity.reviews + 1, correct = training_activity.correct + EXCLUDED.correct
+	`
+	getTrainingDaysSQL = `
+		SELECT day, reviews, correct
+		FROM training_activity
+		WHERE user_id = $1
+		ORDER BY day
+	`
+	getTrainingRecallSQL = `
+		SELECT COALESCE(SUM(reviews) FILTER (WHERE last_answer_at >= $3), 0),
+			COALESCE(SUM(correct) FILTER (WHERE last_answer_at >= $3), 0),
+			MAX(last_answer_at)
+		FROM training_sessions
+		WHERE user_id = $1 AND repertoire_id = $2
+	`
+)
+
+// PostgresTrainingRepo implements TrainingRepository using PostgreSQL
+type PostgresTrainingRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTrainingRepo creates a new PostgreSQL training repository
+func NewPostgresTrainingRepo(pool *pgxpool.Pool) *PostgresTrainingRepo {
+	return &PostgresTrainingRepo{pool: pool}
+}
+
+// RecordAnswer adds an answer to the current session and to the day's totals in a single transaction.
+// The answer is counted on the day at falls on in its own location, the user's time zone.
+func (r *PostgresTrainingRepo) RecordAnswer(userID, repertoireID string, correct bool, at time.Time, sessionGap time.Duration) (*models.TrainingSession, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	correctCount := 0
+	if correct {
+		correctCount = 1
+	}
+
+	var session models.TrainingSession
+	scan := func(row pgx.Row) error {
+		return row.Scan(&session.ID, &session.RepertoireID, &session.StartedAt, &session.LastAnswerAt, &session.Reviews, &session.Correct)
+	}
+	err = scan(tx.QueryRow(ctx, extendTrainingSessionSQL, userID, repertoireID, at, correctCount, at.Add(-sessionGap)))
+	if err == pgx.ErrNoRows {
+		err = scan(tx.QueryRow(ctx, createTrainingSessionSQL, userID, repertoireID, at, correctCount))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record training session: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, recordTrainingDaySQL, userID, at.Format("2006-01-02"), correctCount); err != nil {
+		return nil, fmt.Errorf("failed to record training day: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit training answer: %w", err)
+	}
+	return &session, nil
+}
+
+// GetDays returns the daily training totals of a user, oldest first
+func (r *PostgresTrainingRepo) GetDays(userID string) ([]models.TrainingDay, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getTrainingDaysSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query training days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []models.TrainingDay
+	for rows.Next() {
+		var day models.TrainingDay
+		var date time.Time
+		if err := rows.Scan(&date, &day.Reviews, &day.Correct); err != nil {
+			return nil, fmt.Errorf("failed to scan training day: %w", err)
+		}
+		day.Date = date.Format("2006-01-02")
+		days = append(days, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating training days: %w", err)
+	}
+
+	return days, nil
+}
+
+// GetRecall sums the user's answers on a repertoire in sessions active since the given time
+func (r *PostgresTrainingRepo) GetRecall(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var recall models.TrainingRecall
+	err := r.pool.QueryRow(ctx, getTrainingRecallSQL, userID, repertoireID, since).Scan(&recall.Reviews, &recall.Correct, &recall.LastAnswerAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get training recall: %w", err)
+	}
+	return &recall, nil
+}
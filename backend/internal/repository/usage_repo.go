@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+const (
+	// The update is skipped once the counter reaches the limit, so concurrent calls never exceed it
+	incrementUsageSQL = `
+		INSERT INTO usage_counters (user_id, day, kind, count)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (user_id, day, kind) DO UPDATE SET count = usage_counters.count + 1
+		WHERE $4 = 0 OR usage_counters.count < $4
+		RETURNING count
+	`
+	getUsageDaySQL = `
+		SELECT kind, count
+		FROM usage_counters
+		WHERE user_id = $1 AND day = $2
+	`
+	getUsageTotalsSQL = `
+		SELECT kind, SUM(count), COUNT(*), MAX(count)
+		FROM usage_counters
+		WHERE day = $1
+		GROUP BY kind
+		ORDER BY kind
+	`
+)
+
+// PostgresUsageRepo implements UsageRepository using PostgreSQL
+type PostgresUsageRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresUsageRepo creates a new PostgreSQL usage counter repository
+func NewPostgresUsageRepo(pool *pgxpool.Pool) *PostgresUsageRepo {
+	return &PostgresUsageRepo{pool: pool}
+}
+
+// Increment counts a call of a user to a service on a day. It reports false, counting nothing,
+// when the counter already reached limit; a limit of 0 is not applied.
+func (r *PostgresUsageRepo) Increment(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	var count int
+	err := r.pool.QueryRow(ctx, incrementUsageSQL, userID, day, string(kind), limit).Scan(&count)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to increment usage counter: %w", err)
+	}
+	return true, nil
+}
+
+// GetDay returns the counters of a user on a day, by service
+func (r *PostgresUsageRepo) GetDay(userID string, day time.Time) (map[models.UsageKind]int, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getUsageDaySQL, userID, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage counters: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.UsageKind]int)
+	for rows.Next() {
+		var kind string
+		var count int
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan usage counter: %w", err)
+		}
+		counts[models.UsageKind(kind)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage counters: %w", err)
+	}
+	return counts, nil
+}
+
+// Totals sums the counters of all users on a day, by service
+func (r *PostgresUsageRepo) Totals(day time.Time) ([]models.UsageTotal, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, getUsageTotalsSQL, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []models.UsageTotal
+	for rows.Next() {
+		var total models.UsageTotal
+		var kind string
+		if err := rows.Scan(&kind, &total.Used, &total.Users, &total.MaxUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan usage total: %w", err)
+		}
+		total.Kind = models.UsageKind(kind)
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage totals: %w", err)
+	}
+	return totals, nil
+}
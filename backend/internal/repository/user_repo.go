@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,7 +15,13 @@ import (
 )
 
 const (
-	userColumns = `id, username, email, password_hash, oauth_provider, oauth_id, lichess_username, chesscom_username, lichess_access_token, last_lichess_sync_at, last_chesscom_sync_at, time_format_prefs, created_at`
+	linkedAccountsColumn = `COALESCE((
+		SELECT json_agg(json_build_object('id', la.id, 'provider', la.provider, 'username', la.username, 'lastSyncAt', la.last_sync_at)
+			ORDER BY la.provider, la.created_at, la.username)
+		FROM linked_accounts la WHERE la.user_id = users.id
+	), '[]'::json)`
+
+	userColumns = `id, username, email, password_hash, oauth_provider, oauth_id, ` + linkedAccountsColumn + `, lichess_access_token, last_lichess_sync_at, last_chesscom_sync_at, time_format_prefs, analysis_depth, weekly_digest, timezone, locale, created_at`
 
 	createUserSQL = `
 		INSERT INTO users (id, username, email, password_hash)
@@ -44,15 +51,28 @@ const (
 		FROM users WHERE oauth_provider = $1 AND oauth_id = $2
 	`
 	createOAuthUserSQL = `
-		INSERT INTO users (id, username, oauth_provider, oauth_id, lichess_username)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING ` + userColumns + `
+		INSERT INTO users (id, username, oauth_provider, oauth_id)
+		VALUES ($1, $2, $3, $4)
 	`
 	updateProfileSQL = `
-		UPDATE users SET lichess_username = $2, chesscom_username = $3, time_format_prefs = $4
+		UPDATE users SET time_format_prefs = $2, analysis_depth = COALESCE($3, analysis_depth),
+			timezone = COALESCE($4, timezone), locale = COALESCE($5, locale)
 		WHERE id = $1
 		RETURNING ` + userColumns + `
 	`
+	insertLinkedAccountSQL = `
+		INSERT INTO linked_accounts (user_id, provider, username)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, provider, (LOWER(username))) DO NOTHING
+	`
+	deleteUnlistedAccountsSQL = `
+		DELETE FROM linked_accounts
+		WHERE user_id = $1 AND NOT (provider || ':' || LOWER(username) = ANY($2))
+	`
+	updateLinkedAccountSyncSQL = `
+		UPDATE linked_accounts SET last_sync_at = $2
+		WHERE id = $1
+	`
 	updateSyncTimestampsSQL = `
 		UPDATE users SET last_lichess_sync_at = COALESCE($2, last_lichess_sync_at), last_chesscom_sync_at = COALESCE($3, last_chesscom_sync_at)
 		WHERE id = $1
@@ -62,6 +82,22 @@ const (
 		WHERE id = $1
 	`
 
+	updateNotificationsSQL = `
+		UPDATE users SET weekly_digest = $2
+		WHERE id = $1
+		RETURNING ` + userColumns + `
+	`
+	listDigestRecipientsSQL = `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE weekly_digest AND email IS NOT NULL AND (last_digest_at IS NULL OR last_digest_at < $1)
+		ORDER BY created_at
+	`
+	markDigestSentSQL = `
+		UPDATE users SET last_digest_at = $2
+		WHERE id = $1
+	`
+
 	updatePasswordSQL = `
 		UPDATE users SET password_hash = $2
 		WHERE id = $1
@@ -79,10 +115,12 @@ func NewPostgresUserRepo(pool *pgxpool.Pool) *PostgresUserRepo {
 func scanUser(scan func(dest ...any) error) (*models.User, error) {
 	var user models.User
 	var passwordHash *string
+	var linkedAccountsJSON []byte
 	err := scan(
 		&user.ID, &user.Username, &user.Email, &passwordHash, &user.OAuthProvider, &user.OAuthID,
-		&user.LichessUsername, &user.ChesscomUsername, &user.LichessAccessToken,
-		&user.LastLichessSyncAt, &user.LastChesscomSyncAt, &user.TimeFormatPrefs, &user.CreatedAt,
+		&linkedAccountsJSON, &user.LichessAccessToken,
+		&user.LastLichessSyncAt, &user.LastChesscomSyncAt, &user.TimeFormatPrefs, &user.AnalysisDepth, &user.WeeklyDigest,
+		&user.Timezone, &user.Locale, &user.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -90,6 +128,12 @@ func scanUser(scan func(dest ...any) error) (*models.User, error) {
 	if passwordHash != nil {
 		user.PasswordHash = *passwordHash
 	}
+
+	var accounts []models.LinkedAccount
+	if err := json.Unmarshal(linkedAccountsJSON, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal linked accounts: %w", err)
+	}
+	user.SetLinkedAccounts(accounts)
 	return &user, nil
 }
 
@@ -171,34 +215,89 @@ func (r *PostgresUserRepo) CreateOAuth(provider, oauthID, username string) (*mod
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	// For Lichess OAuth, auto-populate lichess_username
-	var lichessUsername *string
-	if provider == "lichess" {
-		lichessUsername = &username
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
 	id := uuid.New().String()
-	user, err := scanUser(r.pool.QueryRow(ctx, createOAuthUserSQL, id, username, provider, oauthID, lichessUsername).Scan)
-	if err != nil {
+	if _, err := tx.Exec(ctx, createOAuthUserSQL, id, username, provider, oauthID); err != nil {
 		if isDuplicateKeyError(err) {
 			return nil, ErrUsernameExists
 		}
 		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
 	}
+
+	// For Lichess OAuth, auto-link the Lichess account
+	if provider == models.ProviderLichess {
+		if _, err := tx.Exec(ctx, insertLinkedAccountSQL, id, models.ProviderLichess, username); err != nil {
+			return nil, fmt.Errorf("failed to link Lichess account: %w", err)
+		}
+	}
+
+	user, err := scanUser(tx.QueryRow(ctx, getUserByIDSQL, id).Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get created OAuth user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return user, nil
 }
 
-func (r *PostgresUserRepo) UpdateProfile(userID string, lichess, chesscom *string, timeFormatPrefs []string) (*models.User, error) {
+// UpdateProfile replaces the user's linked accounts and time format preferences.
+// Accounts that are kept (same provider, case-insensitive username) retain their sync state.
+// The analysis depth, time zone and locale are only changed when given.
+func (r *PostgresUserRepo) UpdateProfile(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
 	ctx, cancel := dbContext()
 	defer cancel()
 
-	user, err := scanUser(r.pool.QueryRow(ctx, updateProfileSQL, userID, lichess, chesscom, timeFormatPrefs).Scan)
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	keep := make([]string, len(accounts))
+	for i, a := range accounts {
+		keep[i] = a.Provider + ":" + strings.ToLower(a.Username)
+	}
+	if _, err := tx.Exec(ctx, deleteUnlistedAccountsSQL, userID, keep); err != nil {
+		return nil, fmt.Errorf("failed to remove linked accounts: %w", err)
+	}
+	for _, a := range accounts {
+		if _, err := tx.Exec(ctx, insertLinkedAccountSQL, userID, a.Provider, a.Username); err != nil {
+			return nil, fmt.Errorf("failed to add linked account: %w", err)
+		}
+	}
+
+	user, err := scanUser(tx.QueryRow(ctx, updateProfileSQL, userID, timeFormatPrefs, analysisDepth, timezone, locale).Scan)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
 		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return user, nil
 }
 
+func (r *PostgresUserRepo) UpdateLinkedAccountSync(accountID string, syncedAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, updateLinkedAccountSyncSQL, accountID, syncedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update linked account sync: %w", err)
+	}
+	return nil
+}
+
 func (r *PostgresUserRepo) UpdateSyncTimestamps(userID string, lichessSyncAt, chesscomSyncAt *time.Time) error {
 	ctx, cancel := dbContext()
 	defer cancel()
@@ -273,3 +372,52 @@ func isDuplicateEmailError(err error) bool {
 	errStr := err.Error()
 	return strings.Contains(errStr, "idx_users_email") || strings.Contains(errStr, "email")
 }
+
+// UpdateNotifications sets the email notifications a user receives
+func (r *PostgresUserRepo) UpdateNotifications(userID string, weeklyDigest bool) (*models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	user, err := scanUser(r.pool.QueryRow(ctx, updateNotificationsSQL, userID, weeklyDigest).Scan)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update notifications: %w", err)
+	}
+	return user, nil
+}
+
+// ListDigestRecipients returns the users with an email who opted in to the weekly digest
+// and were last sent one before sentBefore, or never
+func (r *PostgresUserRepo) ListDigestRecipients(sentBefore time.Time) ([]models.User, error) {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, listDigestRecipientsSQL, sentBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// MarkDigestSent records when the weekly digest was last sent to a user
+func (r *PostgresUserRepo) MarkDigestSent(userID string, sentAt time.Time) error {
+	ctx, cancel := dbContext()
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, markDigestSentSQL, userID, sentAt); err != nil {
+		return fmt.Errorf("failed to mark digest sent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/services"
+)
+
+// ErrSQLiteUnavailable is returned when DB_DRIVER=sqlite on a binary built without SQLite support
+var ErrSQLiteUnavailable = errors.New("DB_DRIVER=sqlite needs a binary built with -tags sqlite")
+
+// Deps are the services the routes are wired to. NewDeps builds them on PostgreSQL and
+// OpenSQLiteDeps on SQLite; other entrypoints may build their own or replace some before
+// calling New. Routes are only registered for the services that are set.
+type Deps struct {
+	Users           repository.UserRepository
+	Auth            *services.AuthService
+	OAuth           *services.OAuthService
+	Notifications   *services.NotificationService
+	Usage           *services.UsageService
+	CoachLinks      *services.CoachLinkService
+	Repertoires     *services.RepertoireService
+	Collab          *services.CollabHub
+	Categories      *services.CategoryService
+	Training        *services.TrainingService
+	Sparring        *services.SparringService
+	Goals           *services.GoalService
+	Prep            *services.PrepService
+	Engine          *services.EngineService
+	Imports         *services.ImportService
+	Lichess         *services.LichessService
+	Chesscom        *services.ChesscomService
+	Sync            *services.SyncService
+	StudyImport     *services.StudyImportService
+	Recommendations *services.RecommendationService
+
+	// Workers run in the background for as long as the server, see RunWorkers
+	Workers []func(context.Context)
+}
+
+// NewDeps wires repositories on the database and the services on them
+func NewDeps(cfg config.Config, db *repository.DB) (*Deps, error) {
+	// Initialize repositories
+	userRepo := repository.NewPostgresUserRepo(db.Pool)
+	repertoireRepo := repository.NewPostgresRepertoireRepo(db.Pool)
+	categoryRepo := repository.NewPostgresCategoryRepo(db.Pool)
+	analysisRepo := repository.NewPostgresAnalysisRepo(db.Pool)
+	fingerprintRepo := repository.NewPostgresFingerprintRepo(db.Pool, cfg.FingerprintBatchSize)
+	engineEvalRepo := repository.NewPostgresEngineEvalRepo(db.Pool)
+	dismissedMistakeRepo := repository.NewDismissedMistakeRepo(db.Pool)
+	passwordResetRepo := repository.NewPostgresPasswordResetRepo(db.Pool)
+	goalRepo := repository.NewPostgresGoalRepo(db.Pool)
+	trainingRepo := repository.NewPostgresTrainingRepo(db.Pool)
+	reanalysisJobRepo := repository.NewPostgresReanalysisJobRepo(db.Pool)
+	gameResultRepo := repository.NewPostgresGameResultRepo(db.Pool)
+	opponentReplyRepo := repository.NewPostgresOpponentReplyRepo(db.Pool)
+	importJobRepo := repository.NewPostgresImportJobRepo(db.Pool)
+	teamImportRepo := repository.NewPostgresTeamImportRepo(db.Pool)
+	importSummaryRepo := repository.NewPostgresImportSummaryRepo(db.Pool)
+	syncRunRepo := repository.NewPostgresSyncRunRepo(db.Pool)
+	templateRepo := repository.NewPostgresTemplateRepo(db.Pool)
+	collaboratorRepo := repository.NewPostgresCollaboratorRepo(db.Pool)
+	sessionRepo := repository.NewPostgresSessionRepo(db.Pool)
+	apiTokenRepo := repository.NewPostgresAPITokenRepo(db.Pool)
+	healthRepo := repository.NewPostgresHealthRepo(db.Pool)
+	coachLinkRepo := repository.NewPostgresCoachLinkRepo(db.Pool)
+	insightSettingsRepo := repository.NewPostgresInsightSettingsRepo(db.Pool)
+	classificationRuleRepo := repository.NewPostgresClassificationRuleRepo(db.Pool)
+	prepRepo := repository.NewPostgresPrepRepo(db.Pool)
+	usageRepo := repository.NewPostgresUsageRepo(db.Pool)
+	notificationRepo := repository.NewPostgresNotificationRepo(db.Pool)
+	rawGameRepo := repository.NewPostgresRawGameRepo(db.Pool)
+	recomputeJobRepo := repository.NewPostgresRecomputeJobRepo(db.Pool)
+
+	// Initialize opening analysis service (uses Lichess Explorer API)
+	engineSvc := services.NewEngineService(engineEvalRepo, analysisRepo)
+	engineSvc.WithRetention(cfg.EvalRetention)
+	engineSvc.WithTablebase(services.NewTablebaseService())
+	usageSvc := services.NewUsageService(usageRepo, cfg.UsageQuotas)
+	engineSvc.WithUsage(usageSvc)
+	engineSvc.WithClassificationRules(classificationRuleRepo)
+
+	// Initialize services
+	authSvc := services.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTExpiry)
+	emailSvc := services.NewEmailService(cfg)
+	notificationSvc := services.NewNotificationService(notificationRepo, userRepo, emailSvc)
+	if cfg.VAPIDPrivateKey != "" {
+		pushNotifier, err := services.NewPushNotifier(notificationRepo, cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure push notifications: %w", err)
+		}
+		notificationSvc.WithPush(pushNotifier)
+	}
+	engineSvc.WithNotifications(notificationSvc)
+	authSvc.WithPasswordReset(passwordResetRepo, emailSvc, cfg.PasswordResetExpiryHours)
+	authSvc.WithSessions(sessionRepo)
+	authSvc.WithAPITokens(apiTokenRepo)
+	oauthSvc := services.NewOAuthService(userRepo, authSvc, cfg.LichessClientID, cfg.OAuthCallbackURL)
+	repertoireSvc := services.NewRepertoireService(repertoireRepo)
+	repertoireCache := services.NewRepertoireCache(config.RepertoireCacheTTL)
+	repertoireSvc.WithCache(repertoireCache)
+	repertoireSvc.WithTemplates(templateRepo)
+	collabHub := services.NewCollabHub()
+	repertoireSvc.WithCollabHub(collabHub)
+	repertoireSvc.WithCollaborators(collaboratorRepo, userRepo)
+	repertoireSvc.WithHealth(healthRepo)
+	repertoireSvc.WithOpeningLabels()
+	repertoireSvc.WithExplorer(engineSvc)
+	if err := repertoireSvc.SeedBuiltinTemplates(); err != nil {
+		return nil, fmt.Errorf("failed to seed repertoire templates: %w", err)
+	}
+	categorySvc := services.NewCategoryService(categoryRepo, repertoireRepo)
+	categorySvc.WithRepertoireCache(repertoireCache)
+	lichessSvc := services.NewLichessService()
+	importSvc := services.NewImportService(repertoireSvc, analysisRepo,
+		services.WithFingerprintRepo(fingerprintRepo),
+		services.WithEngineService(engineSvc),
+		services.WithDismissedMistakeRepo(dismissedMistakeRepo),
+		services.WithUserRepo(userRepo),
+		services.WithReanalysisJobRepo(reanalysisJobRepo),
+		services.WithInsightSettingsRepo(insightSettingsRepo),
+		services.WithClassificationRuleRepo(classificationRuleRepo),
+		services.WithGameResultRepo(gameResultRepo),
+		services.WithOpponentReplyRepo(opponentReplyRepo),
+		services.WithImportJobRepo(importJobRepo, cfg.ImportSpoolDir),
+		services.WithTeamImports(teamImportRepo, lichessSvc),
+		services.WithImportSummaries(importSummaryRepo),
+		services.WithAnalysisWorkers(cfg.AnalysisWorkers),
+		services.WithUsage(usageSvc),
+		services.WithNotifications(notificationSvc),
+		services.WithGameArchive(rawGameRepo, recomputeJobRepo),
+	)
+	chesscomSvc := services.NewChesscomService()
+	syncSvc := services.NewSyncService(userRepo, importSvc, lichessSvc, chesscomSvc)
+	syncSvc.WithRunHistory(syncRunRepo)
+	syncSvc.WithUsage(usageSvc)
+	syncSvc.WithNotifications(notificationSvc)
+	studyImportSvc := services.NewStudyImportService(lichessSvc, repertoireSvc, categoryRepo, userRepo)
+	goalSvc := services.NewGoalService(goalRepo, repertoireRepo, trainingRepo, engineSvc)
+	goalSvc.WithWeeklySummaries(userRepo, emailSvc)
+	trainingSvc := services.NewTrainingService(trainingRepo, repertoireSvc)
+	trainingSvc.WithUserRepo(userRepo)
+	sparringSvc := services.NewSparringService(repertoireSvc, engineSvc)
+	coachLinkSvc := services.NewCoachLinkService(coachLinkRepo, userRepo)
+	healthSvc := services.NewHealthService(healthRepo, repertoireRepo, trainingRepo, gameResultRepo, engineSvc)
+	prepSvc := services.NewPrepService(prepRepo, repertoireSvc, engineSvc)
+	digestSvc := services.NewDigestService(userRepo, analysisRepo, importSvc, repertoireSvc, emailSvc)
+	digestSvc.WithNotifications(notificationSvc)
+	recommendationSvc := services.NewRecommendationService(repertoireSvc, importSvc, trainingRepo)
+
+	return &Deps{
+		Users:           userRepo,
+		Auth:            authSvc,
+		OAuth:           oauthSvc,
+		Notifications:   notificationSvc,
+		Usage:           usageSvc,
+		CoachLinks:      coachLinkSvc,
+		Repertoires:     repertoireSvc,
+		Collab:          collabHub,
+		Categories:      categorySvc,
+		Training:        trainingSvc,
+		Sparring:        sparringSvc,
+		Goals:           goalSvc,
+		Prep:            prepSvc,
+		Engine:          engineSvc,
+		Imports:         importSvc,
+		Lichess:         lichessSvc,
+		Chesscom:        chesscomSvc,
+		Sync:            syncSvc,
+		StudyImport:     studyImportSvc,
+		Recommendations: recommendationSvc,
+		Workers: []func(context.Context){
+			engineSvc.RunWorker,
+			engineSvc.RunRetentionWorker,
+			engineSvc.RunExplorerWorker,
+			goalSvc.RunWorker,
+			importSvc.RunReanalysisWorker,
+			importSvc.RunRecomputeWorker,
+			importSvc.RunImportJobWorker,
+			importSvc.RunTeamImportWorker,
+			digestSvc.RunWorker,
+			healthSvc.RunWorker,
+			repertoireSvc.RunOpeningLabelWorker,
+			prepSvc.RunWorker,
+		},
+	}, nil
+}
+
+// RunWorkers starts the background workers, which stop when ctx is cancelled
+func (d *Deps) RunWorkers(ctx context.Context) {
+	for _, worker := range d.Workers {
+		go worker(ctx)
+	}
+}
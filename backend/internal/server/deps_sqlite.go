@@ -0,0 +1,76 @@
+//go:build sqlite
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/services"
+)
+
+// sqliteOffFeatures are turned off on SQLite, whose stores only have a PostgreSQL implementation
+const sqliteOffFeatures = "categories, training, sparring, goals, node preparation, engine analysis and insights, " +
+	"coach links, notifications, usage quotas, study imports, recommendations, sessions, API tokens, " +
+	"duplicate detection, background imports and the raw game archive"
+
+// OpenSQLiteDeps opens the SQLite database at cfg.SQLitePath, applying pending migrations, and
+// wires the services SQLite supports on it: accounts, repertoires, game imports and sync. The
+// others are left nil, so their routes are not registered. Settings that would need them are
+// rejected. The returned function closes the database.
+func OpenSQLiteDeps(cfg config.Config) (*Deps, func(), error) {
+	var errs []error
+	if cfg.VAPIDPrivateKey != "" {
+		errs = append(errs, errors.New("push notifications need PostgreSQL: unset VAPID_PRIVATE_KEY"))
+	}
+	if cfg.SMTPHost != "" {
+		errs = append(errs, errors.New("emails (password resets, digests) need PostgreSQL: unset SMTP_HOST"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, fmt.Errorf("unsupported with DB_DRIVER=sqlite: %w", err)
+	}
+
+	db, err := repository.NewSQLiteDB(cfg.SQLitePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("sqlite: storing data in %s; %s are off (they need PostgreSQL)", cfg.SQLitePath, sqliteOffFeatures)
+
+	userRepo := repository.NewSQLiteUserRepo(db.DB)
+	repertoireRepo := repository.NewSQLiteRepertoireRepo(db.DB)
+	analysisRepo := repository.NewSQLiteAnalysisRepo(db.DB)
+
+	authSvc := services.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTExpiry)
+	oauthSvc := services.NewOAuthService(userRepo, authSvc, cfg.LichessClientID, cfg.OAuthCallbackURL)
+	repertoireSvc := services.NewRepertoireService(repertoireRepo)
+	repertoireSvc.WithCache(services.NewRepertoireCache(config.RepertoireCacheTTL))
+	collabHub := services.NewCollabHub()
+	repertoireSvc.WithCollabHub(collabHub)
+	repertoireSvc.WithOpeningLabels()
+	lichessSvc := services.NewLichessService()
+	importSvc := services.NewImportService(repertoireSvc, analysisRepo,
+		services.WithUserRepo(userRepo),
+		services.WithAnalysisWorkers(cfg.AnalysisWorkers),
+	)
+	chesscomSvc := services.NewChesscomService()
+	syncSvc := services.NewSyncService(userRepo, importSvc, lichessSvc, chesscomSvc)
+
+	return &Deps{
+		Users:       userRepo,
+		Auth:        authSvc,
+		OAuth:       oauthSvc,
+		Repertoires: repertoireSvc,
+		Collab:      collabHub,
+		Imports:     importSvc,
+		Lichess:     lichessSvc,
+		Chesscom:    chesscomSvc,
+		Sync:        syncSvc,
+		Workers: []func(context.Context){
+			repertoireSvc.RunOpeningLabelWorker,
+		},
+	}, db.Close, nil
+}
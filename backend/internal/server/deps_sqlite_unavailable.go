@@ -0,0 +1,12 @@
+//go:build !sqlite
+
+package server
+
+import (
+	"github.com/treechess/backend/config"
+)
+
+// OpenSQLiteDeps fails: SQLite support is only compiled in with the sqlite build tag
+func OpenSQLiteDeps(cfg config.Config) (*Deps, func(), error) {
+	return nil, nil, ErrSQLiteUnavailable
+}
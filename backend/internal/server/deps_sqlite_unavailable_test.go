@@ -0,0 +1,17 @@
+//go:build !sqlite
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/treechess/backend/config"
+)
+
+func TestOpenSQLiteDeps_NeedsSQLiteBuild(t *testing.T) {
+	_, _, err := OpenSQLiteDeps(config.Config{DBDriver: config.DBDriverSQLite})
+
+	assert.ErrorIs(t, err, ErrSQLiteUnavailable)
+}
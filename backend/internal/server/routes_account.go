@@ -0,0 +1,61 @@
+package server
+
+import (
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/handlers"
+)
+
+// registerAccountRoutes registers authentication, the user's profile and settings, coach links,
+// notifications and external service quotas
+func registerAccountRoutes(r routes, cfg config.Config, deps *Deps) {
+	authHandler := handlers.NewAuthHandler(deps.Auth)
+	oauthHandler := handlers.NewOAuthHandler(deps.OAuth, deps.Users, cfg.FrontendURL, cfg.JWTSecret, cfg.SecureCookies)
+
+	r.auth.POST("/api/auth/register", authHandler.RegisterHandler)
+	r.auth.POST("/api/auth/login", authHandler.LoginHandler)
+	r.auth.POST("/api/auth/forgot-password", authHandler.ForgotPasswordHandler)
+	r.auth.POST("/api/auth/reset-password", authHandler.ResetPasswordHandler)
+	r.public.GET("/api/auth/lichess/login", oauthHandler.LoginRedirect)
+	r.public.GET("/api/auth/lichess/callback", oauthHandler.Callback)
+
+	// Auth - current user
+	r.protected.GET("/api/auth/me", authHandler.MeHandler)
+	r.protected.PUT("/api/auth/profile", authHandler.UpdateProfileHandler)
+	r.protected.PUT("/api/auth/notifications", authHandler.UpdateNotificationsHandler)
+	r.protected.GET("/api/auth/sessions", authHandler.ListSessionsHandler)
+	r.protected.DELETE("/api/auth/sessions/:id", authHandler.RevokeSessionHandler)
+	r.protected.POST("/api/auth/change-password", authHandler.ChangePasswordHandler)
+	r.protected.GET("/api/auth/has-password", authHandler.HasPasswordHandler)
+
+	// Personal API tokens
+	r.protected.POST("/api/tokens", authHandler.CreateAPITokenHandler)
+	r.protected.GET("/api/tokens", authHandler.ListAPITokensHandler)
+	r.protected.DELETE("/api/tokens/:id", authHandler.RevokeAPITokenHandler)
+
+	// Coach-student links
+	if deps.CoachLinks != nil {
+		coachLinkHandler := handlers.NewCoachLinkHandler(deps.CoachLinks)
+		r.protected.POST("/api/links/invite", coachLinkHandler.InviteHandler)
+		r.protected.GET("/api/links", coachLinkHandler.ListHandler)
+		r.protected.POST("/api/links/:id/accept", coachLinkHandler.AcceptHandler)
+		r.protected.DELETE("/api/links/:id", coachLinkHandler.RevokeHandler)
+	}
+
+	// Notifications
+	if deps.Notifications != nil {
+		notificationHandler := handlers.NewNotificationHandler(deps.Notifications)
+		r.protected.GET("/api/notifications/settings", notificationHandler.SettingsHandler)
+		r.protected.PUT("/api/notifications/preferences", notificationHandler.UpdatePreferencesHandler)
+		r.protected.PUT("/api/notifications/webhook", notificationHandler.SetWebhookHandler)
+		r.protected.DELETE("/api/notifications/webhook", notificationHandler.DeleteWebhookHandler)
+		r.protected.POST("/api/notifications/push", notificationHandler.SubscribePushHandler)
+		r.protected.DELETE("/api/notifications/push", notificationHandler.UnsubscribePushHandler)
+	}
+
+	// External service quotas
+	if deps.Usage != nil {
+		usageHandler := handlers.NewUsageHandler(deps.Usage)
+		r.protected.GET("/api/usage", usageHandler.GetHandler, r.onBehalfOf)
+		r.protected.GET("/api/admin/usage", usageHandler.TotalsHandler, r.admin)
+	}
+}
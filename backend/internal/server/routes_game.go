@@ -0,0 +1,48 @@
+package server
+
+import (
+	"github.com/treechess/backend/internal/handlers"
+)
+
+// registerGameRoutes registers the imported games, the insights drawn from them, their overlay on
+// repertoires, the dashboard and study recommendations
+func registerGameRoutes(r routes, deps *Deps) {
+	importHandler := handlers.NewImportHandler(deps.Imports, deps.Lichess, deps.Chesscom)
+	r.protected.GET("/api/games/insights", importHandler.GetInsightsHandler, r.onBehalfOf)
+	r.protected.POST("/api/games/insights/dismiss", importHandler.DismissMistakeHandler)
+	r.protected.GET("/api/insights/explain", importHandler.ExplainMistakeHandler)
+	r.protected.GET("/api/insights/mistakes/export.csv", importHandler.ExportMistakesCSVHandler, r.onBehalfOf)
+	r.protected.GET("/api/insights/dismissed", importHandler.ListDismissedMistakesHandler)
+	r.protected.DELETE("/api/insights/dismissed", importHandler.RestoreMistakeHandler)
+	r.protected.GET("/api/settings/insights", importHandler.GetInsightSettingsHandler)
+	r.protected.PUT("/api/settings/insights", importHandler.UpdateInsightSettingsHandler)
+	r.protected.GET("/api/settings/classification-rules", importHandler.ListClassificationRulesHandler)
+	r.protected.POST("/api/settings/classification-rules", importHandler.CreateClassificationRuleHandler)
+	r.protected.PUT("/api/settings/classification-rules/:id", importHandler.UpdateClassificationRuleHandler)
+	r.protected.DELETE("/api/settings/classification-rules/:id", importHandler.DeleteClassificationRuleHandler)
+	r.protected.GET("/api/games/repertoires", importHandler.GetDistinctRepertoiresHandler, r.onBehalfOf)
+	r.protected.GET("/api/games", importHandler.GetGamesHandler, r.onBehalfOf)
+	r.protected.GET("/api/games/export", importHandler.ExportGamesPGNHandler, r.onBehalfOf)
+	r.protected.GET("/api/games/export.csv", importHandler.ExportGamesCSVHandler, r.onBehalfOf)
+	r.protected.GET("/api/games/:analysisId/:gameIndex/pgn", importHandler.ExportGamePGNHandler, r.onBehalfOf)
+	r.protected.DELETE("/api/games/:analysisId/:gameIndex", importHandler.DeleteGameHandler)
+	r.protected.PATCH("/api/games/:analysisId/:gameIndex", importHandler.UpdateGameHandler)
+	r.protected.POST("/api/games/bulk-delete", importHandler.BulkDeleteGamesHandler)
+	r.protected.POST("/api/games/:analysisId/:gameIndex/reanalyze", importHandler.ReanalyzeGameHandler)
+	r.protected.GET("/api/games/:analysisId/:gameIndex/repertoire-snapshot", importHandler.GameRepertoireSnapshotHandler)
+	r.protected.POST("/api/games/:analysisId/:gameIndex/view", importHandler.MarkGameViewedHandler)
+	r.protected.POST("/api/games/:analysisId/:gameIndex/adopt", importHandler.AdoptGameLineHandler)
+	r.protected.POST("/api/repertoires/:id/reanalyze-games", importHandler.ReanalyzeRepertoireGamesHandler)
+	r.protected.GET("/api/repertoires/:id/results-overlay", importHandler.ResultsOverlayHandler, r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/book-depth", importHandler.BookDepthHandler, r.onBehalfOf)
+
+	// Dashboard API
+	dashboardHandler := handlers.NewDashboardHandler(deps.Imports)
+	r.protected.GET("/api/dashboard/stats", dashboardHandler.GetStats, r.onBehalfOf)
+
+	// Recommendations API
+	if deps.Recommendations != nil {
+		recommendationHandler := handlers.NewRecommendationHandler(deps.Recommendations)
+		r.protected.GET("/api/recommendations", recommendationHandler.ListHandler, r.onBehalfOf)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"github.com/treechess/backend/internal/handlers"
+)
+
+// registerImportRoutes registers game imports and their analyses, position lookups, Lichess
+// study imports and platform sync
+func registerImportRoutes(r routes, deps *Deps) {
+	importHandler := handlers.NewImportHandler(deps.Imports, deps.Lichess, deps.Chesscom)
+	r.protected.POST("/api/imports", importHandler.UploadHandler, r.importLimit)
+	r.protected.POST("/api/imports/lichess", importHandler.LichessImportHandler, r.importLimit)
+	r.protected.POST("/api/imports/chesscom", importHandler.ChesscomImportHandler, r.importLimit)
+	r.protected.POST("/api/imports/lichess-broadcast", importHandler.LichessBroadcastImportHandler, r.importLimit)
+	r.protected.POST("/api/imports/lichess-team", importHandler.LichessTeamImportHandler, r.importLimit)
+	r.protected.GET("/api/imports/lichess-team/:id", importHandler.GetTeamImportHandler)
+	r.protected.POST("/api/imports/database", importHandler.ImportDatabaseHandler, r.importLimit)
+	r.protected.POST("/api/imports/preview", importHandler.ImportPreviewHandler, r.importLimit)
+	r.protected.GET("/api/imports/jobs/:id", importHandler.GetImportJobHandler)
+	r.protected.GET("/api/imports/:id/summary", importHandler.ImportSummaryHandler, r.onBehalfOf)
+	r.protected.GET("/api/imports/stats", importHandler.ImportStatsHandler)
+	r.protected.POST("/api/imports/validate-pgn", importHandler.ValidatePGNHandler)
+	r.protected.POST("/api/imports/validate-move", importHandler.ValidateMoveHandler)
+	r.protected.GET("/api/imports/legal-moves", importHandler.GetLegalMovesHandler)
+
+	r.protected.GET("/api/analyses", importHandler.ListAnalysesHandler, r.onBehalfOf)
+	r.protected.GET("/api/analyses/reanalysis-jobs/:id", importHandler.GetReanalysisJobHandler)
+	r.protected.GET("/api/analyses/:id", importHandler.GetAnalysisHandler, r.onBehalfOf)
+	r.protected.DELETE("/api/analyses/:id", importHandler.DeleteAnalysisHandler)
+	r.protected.POST("/api/analyses/:id/recompute-evals", importHandler.RecomputeEvalsHandler)
+	r.protected.POST("/api/analyses/:id/evals/retry", importHandler.RetryEvalsHandler)
+	r.protected.POST("/api/analyses/:id/rerun", importHandler.RerunImportHandler, r.importLimit)
+	r.protected.POST("/api/admin/recompute", importHandler.RecomputeHandler, r.admin)
+	r.protected.GET("/api/admin/recompute/:id", importHandler.GetRecomputeJobHandler, r.admin)
+
+	// Position API
+	r.protected.GET("/api/positions/opponent-replies", importHandler.OpponentRepliesHandler, r.onBehalfOf)
+	if deps.Engine != nil {
+		positionHandler := handlers.NewPositionHandler(deps.Engine, deps.Repertoires)
+		r.protected.GET("/api/positions/model-games", positionHandler.GetModelGamesHandler)
+		r.protected.GET("/api/positions/lookup", positionHandler.LookupPositionHandler)
+		r.protected.GET("/api/explorer", positionHandler.ExplorerHandler)
+		r.protected.GET("/api/tablebase", positionHandler.TablebaseHandler)
+		r.protected.POST("/api/chess/normalize-fen", positionHandler.NormalizeFENHandler)
+	}
+
+	// Study Import API
+	if deps.StudyImport != nil {
+		studyImportHandler := handlers.NewStudyImportHandler(deps.StudyImport)
+		r.protected.GET("/api/studies/preview", studyImportHandler.PreviewStudyHandler)
+		r.protected.POST("/api/studies/import", studyImportHandler.ImportStudyHandler)
+	}
+
+	// Sync API
+	if deps.Sync != nil {
+		syncHandler := handlers.NewSyncHandler(deps.Sync)
+		r.protected.POST("/api/sync", syncHandler.HandleSync, r.syncLimit)
+		r.protected.GET("/api/sync/runs", syncHandler.ListRunsHandler)
+		r.protected.GET("/api/sync/runs/:id", syncHandler.GetRunHandler)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"github.com/treechess/backend/internal/handlers"
+)
+
+// registerRepertoireRoutes registers repertoire editing and study: templates, collaboration,
+// revisions, training, sparring, goals, node preparation and categories
+func registerRepertoireRoutes(r routes, deps *Deps) {
+	repertoireSvc := deps.Repertoires
+
+	r.protected.GET("/api/repertoires/templates", handlers.ListTemplatesHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/templates", handlers.PublishTemplateHandler(repertoireSvc))
+	r.protected.PUT("/api/repertoires/templates/:id/featured", handlers.SetTemplateFeaturedHandler(repertoireSvc), r.admin)
+	r.protected.POST("/api/repertoires/seed", handlers.SeedHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/shared", handlers.ListSharedRepertoiresHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/search", handlers.SearchRepertoiresHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires", handlers.ListRepertoiresHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.POST("/api/repertoires", handlers.CreateRepertoireHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/import-json", handlers.ImportJSONHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id", handlers.GetRepertoireHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.PATCH("/api/repertoires/:id", handlers.UpdateRepertoireHandler(repertoireSvc))
+	r.protected.DELETE("/api/repertoires/:id", handlers.DeleteRepertoireHandler(repertoireSvc))
+	r.protected.PATCH("/api/repertoires/:id/archive", handlers.SetRepertoireArchivedHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/:id/nodes", handlers.AddNodeHandler(repertoireSvc))
+	r.protected.DELETE("/api/repertoires/:id/nodes/:nodeId", handlers.DeleteNodeHandler(repertoireSvc))
+	r.protected.PATCH("/api/repertoires/:id/nodes/:nodeId/comment", handlers.UpdateNodeCommentHandler(repertoireSvc))
+	r.protected.PATCH("/api/repertoires/:id/nodes/:nodeId/branch-name", handlers.UpdateNodeBranchNameHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/:id/nodes/:nodeId/toggle-collapsed", handlers.ToggleNodeCollapsedHandler(repertoireSvc))
+	r.protected.PATCH("/api/repertoires/:id/nodes/:nodeId/tags", handlers.UpdateNodeTagsHandler(repertoireSvc))
+	r.protected.PATCH("/api/repertoires/:id/nodes/:nodeId/reorder", handlers.ReorderChildrenHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id/nodes/:nodeId/subtree", handlers.GetSubtreeHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id/lines", handlers.ListLinesHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/export", handlers.ExportStudySheetHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/export.json", handlers.ExportJSONHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/comments.csv", handlers.ExportCommentsCSVHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.POST("/api/repertoires/:id/comments", handlers.ImportCommentsHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id/metrics", handlers.RepertoireMetricsHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/ws", handlers.CollabHandler(repertoireSvc, deps.Collab))
+	r.protected.GET("/api/repertoires/:id/collaborators", handlers.ListCollaboratorsHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/:id/collaborators", handlers.InviteCollaboratorHandler(repertoireSvc))
+	r.protected.DELETE("/api/repertoires/:id/collaborators/:userId", handlers.RemoveCollaboratorHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/merge", handlers.MergeRepertoiresHandler(repertoireSvc), r.mergeLimit)
+	r.protected.POST("/api/repertoires/:id/extract", handlers.ExtractSubtreeHandler(repertoireSvc))
+	r.protected.POST("/api/repertoires/:id/mirror", handlers.MirrorRepertoireHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id/revisions", handlers.ListRevisionsHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/revisions/:revA/diff/:revB", handlers.DiffRevisionsHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.POST("/api/repertoires/:id/revisions/:rev/restore", handlers.RestoreRevisionHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id/notes", handlers.GetStudyNotesHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.PUT("/api/repertoires/:id/notes", handlers.SaveStudyNotesHandler(repertoireSvc))
+	r.protected.GET("/api/repertoires/:id/notes/revisions", handlers.ListStudyNotesRevisionsHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.GET("/api/repertoires/:id/notes/revisions/:rev", handlers.GetStudyNotesHandler(repertoireSvc), r.onBehalfOf)
+	r.protected.POST("/api/repertoires/:id/merge-transpositions", handlers.MergeTranspositionsHandler(repertoireSvc), r.mergeLimit)
+	r.protected.PATCH("/api/repertoires/:id/matching", handlers.SetRepertoireMatchActiveHandler(repertoireSvc))
+
+	// Training and sparring
+	r.protected.GET("/api/repertoires/:id/training", handlers.TrainingPositionsHandler(repertoireSvc), r.onBehalfOf)
+	if deps.Training != nil {
+		trainingHandler := handlers.NewTrainingHandler(deps.Training, repertoireSvc)
+		r.protected.POST("/api/repertoires/:id/training/answers", trainingHandler.AnswerHandler)
+		r.protected.GET("/api/training/activity", trainingHandler.ActivityHandler, r.onBehalfOf)
+	}
+	if deps.Sparring != nil {
+		sparringHandler := handlers.NewSparringHandler(deps.Sparring, repertoireSvc)
+		r.protected.POST("/api/sparring/start", sparringHandler.StartHandler)
+		r.protected.POST("/api/sparring/move", sparringHandler.MoveHandler)
+	}
+
+	// Goals API
+	if deps.Goals != nil {
+		goalHandler := handlers.NewGoalHandler(deps.Goals, repertoireSvc)
+		r.protected.POST("/api/repertoires/:id/goals", goalHandler.CreateGoalHandler)
+		r.protected.GET("/api/goals", goalHandler.ListGoalsHandler)
+		r.protected.DELETE("/api/goals/:id", goalHandler.DeleteGoalHandler)
+	}
+
+	// Node preparation API
+	if deps.Prep != nil {
+		prepHandler := handlers.NewPrepHandler(deps.Prep, repertoireSvc)
+		r.protected.POST("/api/repertoires/:id/nodes/:nodeId/prep", prepHandler.RequestPrepHandler)
+		r.protected.GET("/api/repertoires/:id/nodes/:nodeId/prep", prepHandler.ListPrepHandler)
+		r.protected.POST("/api/repertoires/:id/prep/:prepId/suggestions/:suggestionId/accept", prepHandler.AcceptSuggestionHandler)
+		r.protected.DELETE("/api/repertoires/:id/prep/:prepId/suggestions/:suggestionId", prepHandler.DiscardSuggestionHandler)
+	}
+
+	// Category API
+	if deps.Categories != nil {
+		categorySvc := deps.Categories
+		r.protected.GET("/api/categories", handlers.ListCategoriesHandler(categorySvc))
+		r.protected.POST("/api/categories", handlers.CreateCategoryHandler(categorySvc))
+		r.protected.GET("/api/categories/:id", handlers.GetCategoryHandler(categorySvc))
+		r.protected.PATCH("/api/categories/:id", handlers.UpdateCategoryHandler(categorySvc))
+		r.protected.PATCH("/api/categories/:id/matching", handlers.SetCategoryMatchActiveHandler(categorySvc))
+		r.protected.DELETE("/api/categories/:id", handlers.DeleteCategoryHandler(categorySvc))
+		r.protected.PATCH("/api/repertoires/:id/category", handlers.AssignCategoryHandler(repertoireSvc, categorySvc))
+	}
+}
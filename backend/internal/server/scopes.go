@@ -0,0 +1,26 @@
+package server
+
+import (
+	appMiddleware "github.com/treechess/backend/internal/middleware"
+	"github.com/treechess/backend/internal/models"
+)
+
+// routeScopes lists the protected routes needing another scope than the default of their
+// method. Admin routes need the admin scope through RequireAdmin.
+var routeScopes = appMiddleware.RouteScopes{
+	// Importing and syncing games, allowed to import tokens
+	"POST /api/imports":                   models.ScopeImport,
+	"POST /api/imports/lichess":           models.ScopeImport,
+	"POST /api/imports/chesscom":          models.ScopeImport,
+	"POST /api/imports/lichess-broadcast": models.ScopeImport,
+	"POST /api/imports/lichess-team":      models.ScopeImport,
+	"POST /api/imports/database":          models.ScopeImport,
+	"POST /api/imports/preview":           models.ScopeImport,
+	"POST /api/analyses/:id/rerun":        models.ScopeImport,
+	"POST /api/sync":                      models.ScopeImport,
+
+	// Checks posting their input, which change nothing
+	"POST /api/imports/validate-pgn":  models.ScopeRead,
+	"POST /api/imports/validate-move": models.ScopeRead,
+	"POST /api/chess/normalize-fen":   models.ScopeRead,
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/handlers"
+	appMiddleware "github.com/treechess/backend/internal/middleware"
+)
+
+// routes are the groups and shared middleware the route modules register on
+type routes struct {
+	public    *echo.Echo
+	auth      *echo.Group // public, with a stricter rate limit against credential guessing
+	protected *echo.Group // requires a login or API token
+
+	// Expensive endpoints are also limited per user, wherever their requests come from
+	importLimit echo.MiddlewareFunc
+	syncLimit   echo.MiddlewareFunc
+	mergeLimit  echo.MiddlewareFunc
+	// Read endpoints a linked coach may call for a student with ?onBehalfOf=<student ID>
+	onBehalfOf echo.MiddlewareFunc
+	admin      echo.MiddlewareFunc
+}
+
+// New builds the Echo application: global middleware, then every route module on deps
+func New(cfg config.Config, deps *Deps) *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+
+	// Client addresses, used by the rate limiters and sessions, only come from forwarding headers set by trusted proxies
+	e.IPExtractor = appMiddleware.IPExtractor(cfg.TrustedProxies)
+
+	// Middleware
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     cfg.AllowedOrigins,
+		AllowCredentials: cfg.AllowCredentials,
+		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "If-None-Match", echo.HeaderIfModifiedSince},
+		ExposeHeaders:    append([]string{"ETag", echo.HeaderLastModified}, appMiddleware.RateLimitHeaders...),
+	}))
+
+	// Security headers
+	e.Use(securityHeaders)
+
+	// Response compression (zstd or gzip, as the client accepts)
+	e.Use(appMiddleware.Compress())
+
+	// Global body size limit (10MB). PGN databases are streamed to disk, which enforces their own limit.
+	e.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Limit: "10M",
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/api/imports/database"
+		},
+	}))
+
+	// Rate limiting: 100 requests/minute per IP, monitoring probes excepted
+	e.Use(appMiddleware.IPRateLimit(100, time.Minute, 20, cfg.RateLimitExempt, "rate limit exceeded"))
+
+	r := routes{
+		public: e,
+		// Stricter rate limit for auth endpoints: 10 requests/minute per IP
+		auth: e.Group("", appMiddleware.IPRateLimit(10, time.Minute, 5, cfg.RateLimitExempt, "too many authentication attempts")),
+		// Tokens need the scope of the route; personal API tokens get their own request budget
+		// on top of the per-IP limit
+		protected: e.Group("", appMiddleware.JWTAuth(deps.Auth, routeScopes),
+			appMiddleware.APITokenRateLimit(config.APITokenRequestsPerHour, time.Hour, config.APITokenRequestBurst)),
+		importLimit: appMiddleware.UserRateLimit(config.ImportRequestsPerHour, time.Hour, config.ImportRequestBurst),
+		syncLimit:   appMiddleware.UserRateLimit(config.SyncRequestsPerHour, time.Hour, config.SyncRequestBurst),
+		mergeLimit:  appMiddleware.UserRateLimit(config.MergeRequestsPerHour, time.Hour, config.MergeRequestBurst),
+		onBehalfOf:  appMiddleware.OnBehalfOf(deps.CoachLinks),
+		admin:       appMiddleware.RequireAdmin(deps.Users, cfg.AdminUsernames),
+	}
+
+	// Public routes (no auth required)
+	e.GET("/api/health", handlers.HealthHandler)
+	e.GET("/api/board.svg", handlers.BoardSVGHandler)
+
+	registerAccountRoutes(r, cfg, deps)
+	registerRepertoireRoutes(r, deps)
+	registerImportRoutes(r, deps)
+	registerGameRoutes(r, deps)
+	return e
+}
+
+// securityHeaders adds standard security headers to all responses.
+func securityHeaders(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+		c.Response().Header().Set("X-Frame-Options", "DENY")
+		c.Response().Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		c.Response().Header().Set("X-XSS-Protection", "1; mode=block")
+		return next(c)
+	}
+}
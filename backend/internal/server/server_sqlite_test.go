@@ -0,0 +1,72 @@
+//go:build sqlite
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+func sqliteTestConfig(t *testing.T) config.Config {
+	return config.Config{
+		DBDriver:        config.DBDriverSQLite,
+		SQLitePath:      filepath.Join(t.TempDir(), "treechess.db"),
+		JWTSecret:       "test-secret",
+		JWTExpiry:       time.Hour,
+		AnalysisWorkers: 1,
+	}
+}
+
+func TestOpenSQLiteDeps_ServesRequests(t *testing.T) {
+	deps, closeDB, err := OpenSQLiteDeps(sqliteTestConfig(t))
+	require.NoError(t, err)
+	t.Cleanup(closeDB)
+	e := New(config.Config{}, deps)
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/api/auth/register", "", `{"email":"alice@example.com","username":"alice","password":"correct-horse"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var auth models.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	rec = serve(http.MethodPost, "/api/repertoires", auth.Token, `{"name":"Italian Game","color":"white"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/api/repertoires", auth.Token, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "Italian Game")
+
+	// Features that need PostgreSQL are not served
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/goals", auth.Token, "").Code)
+}
+
+func TestOpenSQLiteDeps_RejectsPostgresOnlySettings(t *testing.T) {
+	cfg := sqliteTestConfig(t)
+	cfg.VAPIDPrivateKey = "key"
+
+	_, _, err := OpenSQLiteDeps(cfg)
+
+	assert.ErrorContains(t, err, "VAPID_PRIVATE_KEY")
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/services"
+)
+
+// fullDeps sets every optional service, so that all route modules are registered
+func fullDeps() *Deps {
+	return &Deps{
+		Notifications:   &services.NotificationService{},
+		Usage:           &services.UsageService{},
+		CoachLinks:      &services.CoachLinkService{},
+		Categories:      &services.CategoryService{},
+		Training:        &services.TrainingService{},
+		Sparring:        &services.SparringService{},
+		Goals:           &services.GoalService{},
+		Prep:            &services.PrepService{},
+		Engine:          &services.EngineService{},
+		Sync:            &services.SyncService{},
+		StudyImport:     &services.StudyImportService{},
+		Recommendations: &services.RecommendationService{},
+	}
+}
+
+func registeredRoutes(deps *Deps) map[string]bool {
+	registered := map[string]bool{}
+	for _, route := range New(config.Config{}, deps).Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	return registered
+}
+
+func TestNew_RegistersRouteModules(t *testing.T) {
+	registered := registeredRoutes(fullDeps())
+	for _, route := range []string{
+		"GET /api/health",
+		"POST /api/auth/login",
+		"GET /api/auth/me",
+		"GET /api/repertoires/:id",
+		"POST /api/imports",
+		"GET /api/games",
+		"GET /api/recommendations",
+	} {
+		assert.True(t, registered[route], "%s is not registered", route)
+	}
+}
+
+func TestNew_SkipsRoutesOfMissingServices(t *testing.T) {
+	registered := registeredRoutes(&Deps{})
+
+	assert.True(t, registered["GET /api/repertoires/:id"])
+	assert.True(t, registered["GET /api/positions/opponent-replies"])
+	for _, route := range []string{
+		"GET /api/recommendations",
+		"GET /api/goals",
+		"GET /api/categories",
+		"POST /api/sync",
+		"GET /api/explorer",
+		"GET /api/links",
+	} {
+		assert.False(t, registered[route], "%s is registered", route)
+	}
+}
+
+func TestNew_AppliesGlobalMiddleware(t *testing.T) {
+	e := New(config.Config{}, &Deps{})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.NotEmpty(t, rec.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestNew_ProtectedRoutesRequireAuth(t *testing.T) {
+	e := New(config.Config{}, &Deps{})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/repertoires", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRouteScopes_AreRegisteredRoutes(t *testing.T) {
+	registered := registeredRoutes(fullDeps())
+	for route := range routeScopes {
+		assert.True(t, registered[route], "%s is not registered", route)
+	}
+}
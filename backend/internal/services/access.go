@@ -0,0 +1,20 @@
+package services
+
+import "fmt"
+
+// ErrForbidden is returned when a user can see a resource but their role does not allow the action.
+// Resources the user cannot see at all are reported as not found instead, so their existence is not disclosed.
+var ErrForbidden = fmt.Errorf("forbidden")
+
+// ownershipGuard turns the result of a BelongsToUser lookup into the error of an ownership check:
+// notFound when the resource is not the user's, a wrapped error when the lookup failed.
+// Every not-found error passed here wraps ErrNotFound so handlers can map all checks the same way.
+func ownershipGuard(belongs bool, err error, notFound error) error {
+	if err != nil {
+		return fmt.Errorf("failed to check ownership: %w", err)
+	}
+	if !belongs {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// ErrInvalidAdoptPly is returned when the plies to adopt are not within the game
+var ErrInvalidAdoptPly = fmt.Errorf("toPly must be between 1 and the number of plies of the game")
+
+// ErrGameCustomStart is returned when adopting a line from a game that does not start from the initial position
+var ErrGameCustomStart = fmt.Errorf("game uses a custom starting position and cannot be adopted into a repertoire")
+
+// AdoptGameLine grafts the first toPly plies of a game into a repertoire, following the moves
+// already in the tree and adding the rest below them. Nothing is saved when a move is illegal.
+// The game is then re-analyzed against the updated repertoire, so it no longer leaves the book
+// within the adopted plies.
+func (s *ImportService) AdoptGameLine(userID, analysisID string, gameIndex, toPly int, repertoireID string) (*models.AdoptLineResponse, error) {
+	if err := s.repertoireService.CheckOwnership(repertoireID, userID); err != nil {
+		return nil, err
+	}
+
+	game, err := s.analysisRepo.GetGame(analysisID, gameIndex)
+	if err != nil {
+		return nil, err
+	}
+	if fen := game.Headers["FEN"]; fen != "" && ensureFullFEN(fen) != standardStartFEN {
+		return nil, ErrGameCustomStart
+	}
+	if toPly < 1 || toPly > len(game.Moves) {
+		return nil, ErrInvalidAdoptPly
+	}
+
+	repertoire, err := s.repertoireService.GetRepertoire(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	if repertoire.Color != game.UserColor {
+		return nil, ErrColorMismatch
+	}
+
+	moves := make([]string, toPly)
+	for i, move := range game.Moves[:toPly] {
+		moves[i] = move.SAN
+	}
+	known := knownPlies(&repertoire.TreeData, moves)
+
+	saved, err := s.repertoireService.AddLine(repertoireID, repertoire.TreeData.ID, moves)
+	if err != nil {
+		return nil, err
+	}
+
+	reanalyzed := s.reanalyzeGameFromMoves(game, saved)
+	classifyGame(&reanalyzed, classificationRulesFor(s.classificationRuleRepo, userID))
+	if err := s.analysisRepo.UpdateGame(analysisID, reanalyzed); err != nil {
+		return nil, fmt.Errorf("failed to save reanalyzed game: %w", err)
+	}
+	s.replaceGameResults(analysisID, reanalyzed, saved)
+
+	return &models.AdoptLineResponse{
+		Repertoire: saved,
+		Game:       &reanalyzed,
+		AddedNodes: len(moves) - known,
+	}, nil
+}
+
+// knownPlies counts the leading moves of a line that the tree already has from its root
+func knownPlies(root *models.RepertoireNode, moves []string) int {
+	node := root
+	for i, move := range moves {
+		if node = childWithMove(node, move); node == nil {
+			return i
+		}
+	}
+	return len(moves)
+}
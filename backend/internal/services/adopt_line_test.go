@@ -0,0 +1,144 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const adoptStartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+
+func adoptFixture(t *testing.T, game *models.GameAnalysis) (*ImportService, *mocks.MockAnalysisRepo, *[]models.RepertoireNode) {
+	t.Helper()
+	e4 := "e4"
+	rootID := "root"
+	tree := models.RepertoireNode{
+		ID:          rootID,
+		FEN:         adoptStartFEN,
+		ColorToMove: models.ChessColorWhite,
+		Children: []*models.RepertoireNode{{
+			ID:          "n-e4",
+			FEN:         "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+			Move:        &e4,
+			MoveNumber:  1,
+			ColorToMove: models.ChessColorBlack,
+			ParentID:    &rootID,
+			Children:    []*models.RepertoireNode{},
+		}},
+	}
+
+	var saved []models.RepertoireNode
+	repRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Name: "King's Pawn", Color: models.ColorWhite, TreeData: tree, Version: 1}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			saved = append(saved, treeData)
+			return &models.Repertoire{ID: id, Name: "King's Pawn", Color: models.ColorWhite, TreeData: treeData, Metadata: metadata, Version: 2}, nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return game, nil
+		},
+	}
+	return NewImportService(NewRepertoireService(repRepo), analysisRepo), analysisRepo, &saved
+}
+
+func adoptGame(sans ...string) *models.GameAnalysis {
+	game := &models.GameAnalysis{GameIndex: 0, Headers: models.PGNHeaders{}, UserColor: models.ColorWhite}
+	for i, san := range sans {
+		game.Moves = append(game.Moves, models.MoveAnalysis{PlyNumber: i, SAN: san, Status: "out-of-repertoire", IsUserMove: i%2 == 0})
+	}
+	return game
+}
+
+func TestAdoptGameLine_GraftsMissingPlies(t *testing.T) {
+	svc, analysisRepo, saved := adoptFixture(t, adoptGame("e4", "e5", "Nf3", "Nc6"))
+	var updated *models.GameAnalysis
+	analysisRepo.UpdateGameFunc = func(analysisID string, game models.GameAnalysis) error {
+		updated = &game
+		return nil
+	}
+
+	resp, err := svc.AdoptGameLine("user-1", "analysis-1", 0, 3, "rep-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.AddedNodes)
+	require.Len(t, *saved, 1)
+	e4 := (*saved)[0].Children
+	require.Len(t, e4, 1, "the existing e4 node is followed, not duplicated")
+	require.Len(t, e4[0].Children, 1)
+	assert.Equal(t, "e5", *e4[0].Children[0].Move)
+	require.Len(t, e4[0].Children[0].Children, 1)
+	assert.Equal(t, "Nf3", *e4[0].Children[0].Children[0].Move)
+	assert.Empty(t, e4[0].Children[0].Children[0].Children, "plies beyond toPly are left out")
+
+	require.NotNil(t, updated)
+	require.NotNil(t, updated.MatchedRepertoire)
+	assert.Equal(t, "rep-1", updated.MatchedRepertoire.ID)
+	assert.Equal(t, resp.Game, updated)
+}
+
+func TestAdoptGameLine_AllKnownSavesNothing(t *testing.T) {
+	svc, _, saved := adoptFixture(t, adoptGame("e4", "e5"))
+
+	resp, err := svc.AdoptGameLine("user-1", "analysis-1", 0, 1, "rep-1")
+
+	require.NoError(t, err)
+	assert.Zero(t, resp.AddedNodes)
+	assert.Empty(t, *saved)
+}
+
+func TestAdoptGameLine_IllegalMoveSavesNothing(t *testing.T) {
+	svc, _, saved := adoptFixture(t, adoptGame("e4", "e5", "Ke3"))
+
+	_, err := svc.AdoptGameLine("user-1", "analysis-1", 0, 3, "rep-1")
+
+	assert.ErrorIs(t, err, ErrInvalidMove)
+	assert.Empty(t, *saved)
+}
+
+func TestAdoptGameLine_Validation(t *testing.T) {
+	black := adoptGame("e4", "e5")
+	black.UserColor = models.ColorBlack
+	custom := adoptGame("e5")
+	custom.Headers["FEN"] = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+	tests := []struct {
+		name  string
+		game  *models.GameAnalysis
+		toPly int
+		want  error
+	}{
+		{"zero plies", adoptGame("e4", "e5"), 0, ErrInvalidAdoptPly},
+		{"beyond the game", adoptGame("e4", "e5"), 3, ErrInvalidAdoptPly},
+		{"color mismatch", black, 2, ErrColorMismatch},
+		{"custom start", custom, 1, ErrGameCustomStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, saved := adoptFixture(t, tt.game)
+
+			_, err := svc.AdoptGameLine("user-1", "analysis-1", 0, tt.toPly, "rep-1")
+
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, *saved)
+		})
+	}
+}
+
+func TestAdoptGameLine_RequiresWriteAccess(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return false, nil },
+	})
+	svc := NewImportService(repSvc, &mocks.MockAnalysisRepo{})
+
+	_, err := svc.AdoptGameLine("user-1", "analysis-1", 0, 1, "rep-1")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// WithAnalysisWorkers sets how many games of an import are analyzed in parallel
+func WithAnalysisWorkers(workers int) ImportServiceOption {
+	return func(s *ImportService) {
+		s.analysisWorkers = workers
+	}
+}
+
+// nodeIndex maps a normalized FEN to the first repertoire node reaching it, in depth-first order.
+// It replaces walking the whole tree for every move; once built it is only read, so workers share it.
+type nodeIndex map[string]*models.RepertoireNode
+
+func newNodeIndex(root *models.RepertoireNode) nodeIndex {
+	index := make(nodeIndex)
+	var walk func(node *models.RepertoireNode)
+	walk = func(node *models.RepertoireNode) {
+		if _, seen := index[node.FEN]; !seen {
+			index[node.FEN] = node
+		}
+		for _, child := range node.Children {
+			if child != nil {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	return index
+}
+
+// runAnalysisPool calls analyze for every index in [0, n) on a bounded number of workers.
+// analyze must only write to its own slot of a preallocated result slice, which keeps
+// the results in input order whatever the scheduling.
+func (s *ImportService) runAnalysisPool(n int, analyze func(i int)) {
+	workers := s.analysisWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				analyze(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
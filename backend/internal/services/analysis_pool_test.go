@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestNewNodeIndex_FirstNodeInDepthFirstOrderWins(t *testing.T) {
+	transposed := &models.RepertoireNode{ID: "transposed", FEN: "shared"}
+	first := &models.RepertoireNode{ID: "first", FEN: "shared"}
+	root := &models.RepertoireNode{
+		ID:  "root",
+		FEN: "start",
+		Children: []*models.RepertoireNode{
+			{ID: "a", FEN: "a", Children: []*models.RepertoireNode{first}},
+			{ID: "b", FEN: "b", Children: []*models.RepertoireNode{transposed}},
+		},
+	}
+
+	index := newNodeIndex(root)
+
+	assert.Len(t, index, 4)
+	assert.Same(t, first, index["shared"])
+	assert.Same(t, root, index["start"])
+	// Same choice as the tree walk it replaces
+	assert.Same(t, (&ImportService{}).findNodeInRepertoire(*root, "shared"), index["shared"])
+}
+
+func TestRunAnalysisPool_VisitsEveryIndexOnce(t *testing.T) {
+	svc := NewImportService(nil, nil, WithAnalysisWorkers(3))
+	var calls int64
+	visited := make([]int, 50)
+
+	svc.runAnalysisPool(len(visited), func(i int) {
+		atomic.AddInt64(&calls, 1)
+		visited[i]++
+	})
+
+	assert.Equal(t, int64(50), calls)
+	for i, n := range visited {
+		assert.Equal(t, 1, n, "index %d", i)
+	}
+}
+
+func TestParseAndAnalyze_ParallelMatchesSequential(t *testing.T) {
+	openings := []string{"1. e4 e5 2. Nf3 Nc6", "1. d4 d5 2. c4 e6", "1. c4 e5 2. Nc3 Nf6", "1. Nf3 d5 2. g3 c6"}
+	var games []string
+	for i := 0; i < 24; i++ {
+		white, black := "me", fmt.Sprintf("opponent%d", i)
+		if i%2 == 1 {
+			white, black = black, white
+		}
+		games = append(games, fmt.Sprintf("[White %q]\n[Black %q]\n\n%s 1-0", white, black, openings[i%len(openings)]))
+	}
+	pgnData := strings.Join(games, "\n\n")
+
+	analyze := func(workers int) []models.GameAnalysis {
+		analysisRepo := &mocks.MockAnalysisRepo{
+			SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+				return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+			},
+		}
+		svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo, WithAnalysisWorkers(workers))
+		_, results, err := svc.ParseAndAnalyze("games.pgn", "me", "user-1", pgnData)
+		require.NoError(t, err)
+		return results
+	}
+
+	sequential := analyze(1)
+	parallel := analyze(8)
+
+	require.Len(t, parallel, len(games))
+	assert.Equal(t, sequential, parallel)
+	for i, game := range parallel {
+		assert.Equal(t, i, game.GameIndex)
+		opponent := game.Headers["Black"]
+		if game.UserColor == models.ColorBlack {
+			opponent = game.Headers["White"]
+		}
+		assert.Equal(t, fmt.Sprintf("opponent%d", i), opponent)
+	}
+}
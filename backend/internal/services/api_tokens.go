@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// apiTokenPrefix marks personal API tokens, telling them apart from login JWTs
+const apiTokenPrefix = "tc_"
+
+var (
+	ErrAPITokensUnavailable = fmt.Errorf("api tokens are not available")
+	ErrAPITokenNotFound     = fmt.Errorf("api token %w", ErrNotFound)
+	ErrInvalidAPITokenName  = fmt.Errorf("token name must be 1-%d characters", config.MaxAPITokenNameLen)
+	ErrInvalidAPITokenScope = fmt.Errorf("token scope must be read, import or write")
+	ErrTooManyAPITokens     = fmt.Errorf("too many api tokens")
+)
+
+// WithAPITokens lets users create personal API tokens, accepted wherever login tokens are
+func (s *AuthService) WithAPITokens(apiTokenRepo repository.APITokenRepository) {
+	s.apiTokenRepo = apiTokenRepo
+}
+
+// CreateAPIToken issues a personal API token. The raw token is returned once and only its hash is kept.
+func (s *AuthService) CreateAPIToken(userID string, req models.CreateAPITokenRequest) (*models.CreateAPITokenResponse, error) {
+	if s.apiTokenRepo == nil {
+		return nil, ErrAPITokensUnavailable
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > config.MaxAPITokenNameLen {
+		return nil, ErrInvalidAPITokenName
+	}
+	if apiTokenScopes(req.Scope) == nil {
+		return nil, ErrInvalidAPITokenScope
+	}
+
+	count, err := s.apiTokenRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= config.MaxAPITokensPerUser {
+		return nil, ErrTooManyAPITokens
+	}
+
+	secret, err := generateSecureToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api token: %w", err)
+	}
+	rawToken := apiTokenPrefix + secret
+
+	token, err := s.apiTokenRepo.Create(userID, name, req.Scope, rawToken[:len(apiTokenPrefix)+8], hashToken(rawToken))
+	if err != nil {
+		return nil, err
+	}
+	return &models.CreateAPITokenResponse{APIToken: *token, Token: rawToken}, nil
+}
+
+// ListAPITokens returns the active personal API tokens of a user
+func (s *AuthService) ListAPITokens(userID string) ([]models.APIToken, error) {
+	if s.apiTokenRepo == nil {
+		return []models.APIToken{}, nil
+	}
+	return s.apiTokenRepo.ListByUser(userID)
+}
+
+// RevokeAPIToken disables a personal API token of a user; it stops working immediately
+func (s *AuthService) RevokeAPIToken(userID, tokenID string) error {
+	if s.apiTokenRepo == nil {
+		return ErrAPITokenNotFound
+	}
+	err := s.apiTokenRepo.Revoke(tokenID, userID, time.Now())
+	if errors.Is(err, repository.ErrAPITokenNotFound) {
+		return ErrAPITokenNotFound
+	}
+	return err
+}
+
+// validateAPIToken looks a personal API token up by its hash and records its use
+func (s *AuthService) validateAPIToken(rawToken string) (TokenSubject, error) {
+	if s.apiTokenRepo == nil {
+		return TokenSubject{}, ErrUnauthorized
+	}
+	token, err := s.apiTokenRepo.Use(hashToken(rawToken), time.Now())
+	if err != nil {
+		return TokenSubject{}, ErrUnauthorized
+	}
+	return TokenSubject{UserID: token.UserID, TokenID: token.ID, Scopes: apiTokenScopes(token.Scope)}, nil
+}
+
+// apiTokenScopes returns the scopes granted by a personal API token of scope, nil for an
+// unknown scope. API tokens never reach admin routes.
+func apiTokenScopes(scope string) []string {
+	switch scope {
+	case models.APITokenScopeRead:
+		return []string{models.ScopeRead}
+	case models.APITokenScopeImport:
+		return []string{models.ScopeRead, models.ScopeImport}
+	case models.APITokenScopeWrite:
+		return []string{models.ScopeRead, models.ScopeWrite, models.ScopeImport}
+	}
+	return nil
+}
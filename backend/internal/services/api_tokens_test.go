@@ -0,0 +1,96 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestCreateAPIToken_StoresOnlyTheHash(t *testing.T) {
+	var storedHash, storedPrefix string
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithAPITokens(&mocks.MockAPITokenRepo{
+		CreateFunc: func(userID, name, scope, prefix, tokenHash string) (*models.APIToken, error) {
+			storedHash, storedPrefix = tokenHash, prefix
+			return &models.APIToken{ID: "token-1", UserID: userID, Name: name, Scope: scope, Prefix: prefix}, nil
+		},
+	})
+
+	resp, err := svc.CreateAPIToken("user-123", models.CreateAPITokenRequest{Name: " scripts ", Scope: models.APITokenScopeRead})
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.Token, apiTokenPrefix))
+	assert.Equal(t, "scripts", resp.Name)
+	assert.Equal(t, hashToken(resp.Token), storedHash)
+	assert.NotContains(t, storedHash, resp.Token)
+	assert.True(t, strings.HasPrefix(resp.Token, storedPrefix))
+}
+
+func TestCreateAPIToken_Validation(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithAPITokens(&mocks.MockAPITokenRepo{
+		CountByUserFunc: func(userID string) (int, error) {
+			return config.MaxAPITokensPerUser, nil
+		},
+	})
+
+	_, err := svc.CreateAPIToken("user-123", models.CreateAPITokenRequest{Name: "", Scope: models.APITokenScopeRead})
+	assert.ErrorIs(t, err, ErrInvalidAPITokenName)
+
+	_, err = svc.CreateAPIToken("user-123", models.CreateAPITokenRequest{Name: "scripts", Scope: "admin"})
+	assert.ErrorIs(t, err, ErrInvalidAPITokenScope)
+
+	_, err = svc.CreateAPIToken("user-123", models.CreateAPITokenRequest{Name: "scripts", Scope: models.APITokenScopeWrite})
+	assert.ErrorIs(t, err, ErrTooManyAPITokens)
+}
+
+func TestValidateToken_APIToken(t *testing.T) {
+	var usedHash string
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithAPITokens(&mocks.MockAPITokenRepo{
+		UseFunc: func(tokenHash string, usedAt time.Time) (*models.APIToken, error) {
+			usedHash = tokenHash
+			return &models.APIToken{ID: "token-1", UserID: "user-123", Scope: models.APITokenScopeWrite}, nil
+		},
+	})
+
+	subject, err := svc.ValidateToken("tc_secret")
+
+	require.NoError(t, err)
+	assert.Equal(t, TokenSubject{UserID: "user-123", TokenID: "token-1", Scopes: []string{models.ScopeRead, models.ScopeWrite, models.ScopeImport}}, subject)
+	assert.Equal(t, hashToken("tc_secret"), usedHash)
+}
+
+func TestValidateToken_RevokedAPIToken(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithAPITokens(&mocks.MockAPITokenRepo{})
+
+	_, err := svc.ValidateToken("tc_revoked")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	// Without API tokens configured, tc_ tokens are never accepted
+	_, err = newTestAuthService(&mocks.MockUserRepo{}).ValidateToken("tc_secret")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestRevokeAPIToken_NotFound(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithAPITokens(&mocks.MockAPITokenRepo{
+		RevokeFunc: func(id, userID string, revokedAt time.Time) error {
+			return repository.ErrAPITokenNotFound
+		},
+	})
+
+	err := svc.RevokeAPIToken("user-123", "token-1")
+
+	assert.ErrorIs(t, err, ErrAPITokenNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -4,43 +4,67 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/language"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 )
 
 var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,50}$`)
+var chessUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,50}$`)
 var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
 var (
-	ErrInvalidUsername      = fmt.Errorf("username must be 3-50 alphanumeric characters, hyphens or underscores")
-	ErrInvalidEmail         = fmt.Errorf("invalid email format")
-	ErrPasswordTooShort     = fmt.Errorf("password must be at least 8 characters")
-	ErrInvalidCredentials   = fmt.Errorf("invalid credentials")
-	ErrUnauthorized         = fmt.Errorf("unauthorized")
-	ErrOAuthOnly            = fmt.Errorf("this account uses Lichess login")
-	ErrResetTokenExpired    = fmt.Errorf("reset token has expired")
-	ErrResetTokenInvalid    = fmt.Errorf("reset token is invalid")
-	ErrResetTokenUsed       = fmt.Errorf("reset token has already been used")
-	ErrIncorrectPassword    = fmt.Errorf("current password is incorrect")
-	ErrNoPassword           = fmt.Errorf("this account does not have a password set")
-	ErrTooManyResetRequests = fmt.Errorf("too many password reset requests")
+	ErrInvalidUsername       = fmt.Errorf("username must be 3-50 alphanumeric characters, hyphens or underscores")
+	ErrInvalidEmail          = fmt.Errorf("invalid email format")
+	ErrPasswordTooShort      = fmt.Errorf("password must be at least 8 characters")
+	ErrInvalidCredentials    = fmt.Errorf("invalid credentials")
+	ErrUnauthorized          = fmt.Errorf("unauthorized")
+	ErrOAuthOnly             = fmt.Errorf("this account uses Lichess login")
+	ErrResetTokenExpired     = fmt.Errorf("reset token has expired")
+	ErrResetTokenInvalid     = fmt.Errorf("reset token is invalid")
+	ErrResetTokenUsed        = fmt.Errorf("reset token has already been used")
+	ErrIncorrectPassword     = fmt.Errorf("current password is incorrect")
+	ErrNoPassword            = fmt.Errorf("this account does not have a password set")
+	ErrTooManyResetRequests  = fmt.Errorf("too many password reset requests")
+	ErrInvalidLinkedAccount  = fmt.Errorf("linked accounts need a provider (lichess or chesscom) and a valid username")
+	ErrTooManyLinkedAccounts = fmt.Errorf("too many linked accounts")
+	ErrDigestNeedsEmail      = fmt.Errorf("an email address is required to receive the weekly digest")
+	ErrInvalidTimezone       = fmt.Errorf("timezone must be an IANA time zone name, such as Europe/Paris")
+	ErrInvalidLocale         = fmt.Errorf("locale must be a BCP 47 language tag, such as en-US")
+	ErrSessionNotFound       = fmt.Errorf("session %w", ErrNotFound)
 )
 
+// TokenSubject identifies who a valid token was issued to
+type TokenSubject struct {
+	UserID    string
+	SessionID string   // Empty for tokens issued without sessions
+	TokenID   string   // Personal API token the request used, empty for login tokens
+	Scopes    []string // Scopes the token grants
+}
+
+// sessionScopes are granted by login tokens, which can do everything their user can
+var sessionScopes = []string{models.ScopeRead, models.ScopeWrite, models.ScopeImport, models.ScopeAdmin}
+
 type AuthService struct {
-	userRepo          repository.UserRepository
-	resetRepo         repository.PasswordResetRepository
-	emailService      EmailSender
-	jwtSecret         []byte
-	jwtExpiry         time.Duration
-	resetTokenExpiry  time.Duration
-	maxResetPerHour   int
+	userRepo         repository.UserRepository
+	resetRepo        repository.PasswordResetRepository
+	sessionRepo      repository.SessionRepository
+	apiTokenRepo     repository.APITokenRepository
+	emailService     EmailSender
+	jwtSecret        []byte
+	jwtExpiry        time.Duration
+	resetTokenExpiry time.Duration
+	maxResetPerHour  int
 }
 
 func NewAuthService(userRepo repository.UserRepository, jwtSecret string, jwtExpiry time.Duration) *AuthService {
@@ -62,7 +86,12 @@ func (s *AuthService) WithPasswordReset(resetRepo repository.PasswordResetReposi
 	}
 }
 
-func (s *AuthService) Register(email, username, password string) (*models.AuthResponse, error) {
+// WithSessions ties every issued token to a session, which the user can list and revoke
+func (s *AuthService) WithSessions(sessionRepo repository.SessionRepository) {
+	s.sessionRepo = sessionRepo
+}
+
+func (s *AuthService) Register(email, username, password string, device models.SessionDevice) (*models.AuthResponse, error) {
 	if !emailPattern.MatchString(email) {
 		return nil, ErrInvalidEmail
 	}
@@ -83,7 +112,7 @@ func (s *AuthService) Register(email, username, password string) (*models.AuthRe
 		return nil, err
 	}
 
-	token, err := s.generateToken(user)
+	token, err := s.generateToken(user, device)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +120,7 @@ func (s *AuthService) Register(email, username, password string) (*models.AuthRe
 	return &models.AuthResponse{Token: token, User: *user}, nil
 }
 
-func (s *AuthService) Login(email, password string) (*models.AuthResponse, error) {
+func (s *AuthService) Login(email, password string, device models.SessionDevice) (*models.AuthResponse, error) {
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
@@ -108,7 +137,7 @@ func (s *AuthService) Login(email, password string) (*models.AuthResponse, error
 		return nil, ErrInvalidCredentials
 	}
 
-	token, err := s.generateToken(user)
+	token, err := s.generateToken(user, device)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +145,13 @@ func (s *AuthService) Login(email, password string) (*models.AuthResponse, error
 	return &models.AuthResponse{Token: token, User: *user}, nil
 }
 
-func (s *AuthService) ValidateToken(tokenStr string) (string, error) {
+// ValidateToken checks a token's signature and expiry and, with sessions, that its session is still active.
+// Personal API tokens are accepted as well.
+func (s *AuthService) ValidateToken(tokenStr string) (TokenSubject, error) {
+	if strings.HasPrefix(tokenStr, apiTokenPrefix) {
+		return s.validateAPIToken(tokenStr)
+	}
+
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -124,20 +159,34 @@ func (s *AuthService) ValidateToken(tokenStr string) (string, error) {
 		return s.jwtSecret, nil
 	})
 	if err != nil {
-		return "", ErrUnauthorized
+		return TokenSubject{}, ErrUnauthorized
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return "", ErrUnauthorized
+		return TokenSubject{}, ErrUnauthorized
 	}
 
 	sub, ok := claims["sub"].(string)
 	if !ok || sub == "" {
-		return "", ErrUnauthorized
+		return TokenSubject{}, ErrUnauthorized
 	}
 
-	return sub, nil
+	subject := TokenSubject{UserID: sub, Scopes: sessionScopes}
+	// Tokens issued before scopes existed keep the session scopes until they expire
+	if scope, ok := claims["scope"].(string); ok {
+		subject.Scopes = strings.Fields(scope)
+	}
+	if s.sessionRepo != nil {
+		// Tokens issued before sessions existed stay valid until they expire
+		if sid, _ := claims["sid"].(string); sid != "" {
+			if err := s.sessionRepo.Touch(sid, time.Now()); err != nil {
+				return TokenSubject{}, ErrUnauthorized
+			}
+			subject.SessionID = sid
+		}
+	}
+	return subject, nil
 }
 
 func (s *AuthService) GetUserByID(id string) (*models.User, error) {
@@ -145,20 +194,125 @@ func (s *AuthService) GetUserByID(id string) (*models.User, error) {
 }
 
 func (s *AuthService) UpdateProfile(userID string, req models.UpdateProfileRequest) (*models.User, error) {
-	return s.userRepo.UpdateProfile(userID, req.LichessUsername, req.ChesscomUsername, req.TimeFormatPrefs)
+	accounts, err := linkedAccountsFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.AnalysisDepth != nil && !ValidAnalysisDepth(*req.AnalysisDepth) {
+		return nil, ErrInvalidAnalysisDepth
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			return nil, ErrInvalidTimezone
+		}
+	}
+	var locale *string
+	if req.Locale != nil {
+		tag, err := language.Parse(*req.Locale)
+		if err != nil {
+			return nil, ErrInvalidLocale
+		}
+		canonical := tag.String()
+		locale = &canonical
+	}
+	return s.userRepo.UpdateProfile(userID, accounts, req.TimeFormatPrefs, req.AnalysisDepth, req.Timezone, locale)
 }
 
-func (s *AuthService) generateToken(user *models.User) (string, error) {
+// UpdateNotifications sets the email notifications of a user. Only users with an email can opt in.
+func (s *AuthService) UpdateNotifications(userID string, req models.UpdateNotificationsRequest) (*models.User, error) {
+	if req.WeeklyDigest {
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Email == nil || *user.Email == "" {
+			return nil, ErrDigestNeedsEmail
+		}
+	}
+	return s.userRepo.UpdateNotifications(userID, req.WeeklyDigest)
+}
+
+// linkedAccountsFromRequest validates and deduplicates the accounts of a profile update.
+// Requests without linkedAccounts fall back to the single lichessUsername/chesscomUsername fields.
+func linkedAccountsFromRequest(req models.UpdateProfileRequest) ([]models.LinkedAccountInput, error) {
+	requested := req.LinkedAccounts
+	if requested == nil {
+		if req.LichessUsername != nil && *req.LichessUsername != "" {
+			requested = append(requested, models.LinkedAccountInput{Provider: models.ProviderLichess, Username: *req.LichessUsername})
+		}
+		if req.ChesscomUsername != nil && *req.ChesscomUsername != "" {
+			requested = append(requested, models.LinkedAccountInput{Provider: models.ProviderChesscom, Username: *req.ChesscomUsername})
+		}
+	}
+
+	accounts := []models.LinkedAccountInput{}
+	seen := make(map[string]bool)
+	for _, a := range requested {
+		a.Username = strings.TrimSpace(a.Username)
+		if (a.Provider != models.ProviderLichess && a.Provider != models.ProviderChesscom) || !chessUsernamePattern.MatchString(a.Username) {
+			return nil, ErrInvalidLinkedAccount
+		}
+		key := a.Provider + ":" + strings.ToLower(a.Username)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		accounts = append(accounts, a)
+	}
+
+	if len(accounts) > config.MaxLinkedAccounts {
+		return nil, ErrTooManyLinkedAccounts
+	}
+	return accounts, nil
+}
+
+func (s *AuthService) generateToken(user *models.User, device models.SessionDevice) (string, error) {
+	expiresAt := time.Now().Add(s.jwtExpiry)
 	claims := jwt.MapClaims{
 		"sub":      user.ID,
 		"username": user.Username,
-		"exp":      time.Now().Add(s.jwtExpiry).Unix(),
+		"exp":      expiresAt.Unix(),
+		"scope":    strings.Join(sessionScopes, " "),
+	}
+	if s.sessionRepo != nil {
+		session, err := s.sessionRepo.Create(user.ID, device, expiresAt)
+		if err != nil {
+			return "", err
+		}
+		claims["sid"] = session.ID
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.jwtSecret)
 }
 
+// ListSessions returns the active sessions of a user, flagging the one making the request
+func (s *AuthService) ListSessions(userID, currentSessionID string) ([]models.Session, error) {
+	if s.sessionRepo == nil {
+		return []models.Session{}, nil
+	}
+	sessions, err := s.sessionRepo.ListActive(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs a user out of one of their sessions; its token stops working immediately
+func (s *AuthService) RevokeSession(userID, sessionID string) error {
+	if s.sessionRepo == nil {
+		return ErrSessionNotFound
+	}
+	err := s.sessionRepo.Revoke(sessionID, userID, time.Now())
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
 // RequestPasswordReset initiates the password reset flow
 // Always returns nil to prevent email enumeration
 func (s *AuthService) RequestPasswordReset(email string) error {
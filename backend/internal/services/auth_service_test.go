@@ -1,13 +1,16 @@
 package services
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 	"github.com/treechess/backend/internal/repository/mocks"
@@ -32,7 +35,7 @@ func TestAuthService_Register_Success(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	resp, err := svc.Register(email, "testuser", "password123")
+	resp, err := svc.Register(email, "testuser", "password123", models.SessionDevice{})
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
@@ -57,7 +60,7 @@ func TestAuthService_Register_InvalidEmail(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := svc.Register(tt.email, "testuser", "password123")
+			_, err := svc.Register(tt.email, "testuser", "password123", models.SessionDevice{})
 			assert.ErrorIs(t, err, ErrInvalidEmail)
 		})
 	}
@@ -78,7 +81,7 @@ func TestAuthService_Register_InvalidUsername(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := svc.Register("test@example.com", tt.username, "password123")
+			_, err := svc.Register("test@example.com", tt.username, "password123", models.SessionDevice{})
 			assert.ErrorIs(t, err, ErrInvalidUsername)
 		})
 	}
@@ -87,7 +90,7 @@ func TestAuthService_Register_InvalidUsername(t *testing.T) {
 func TestAuthService_Register_PasswordTooShort(t *testing.T) {
 	svc := newTestAuthService(&mocks.MockUserRepo{})
 
-	_, err := svc.Register("test@example.com", "validuser", "short")
+	_, err := svc.Register("test@example.com", "validuser", "short", models.SessionDevice{})
 
 	assert.ErrorIs(t, err, ErrPasswordTooShort)
 }
@@ -100,7 +103,7 @@ func TestAuthService_Register_UsernameExists(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	_, err := svc.Register("test@example.com", "existinguser", "password123")
+	_, err := svc.Register("test@example.com", "existinguser", "password123", models.SessionDevice{})
 
 	assert.ErrorIs(t, err, repository.ErrUsernameExists)
 }
@@ -113,7 +116,7 @@ func TestAuthService_Register_EmailExists(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	_, err := svc.Register("existing@example.com", "newuser", "password123")
+	_, err := svc.Register("existing@example.com", "newuser", "password123", models.SessionDevice{})
 
 	assert.ErrorIs(t, err, repository.ErrEmailExists)
 }
@@ -133,7 +136,7 @@ func TestAuthService_Login_Success(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	resp, err := svc.Login("test@example.com", "password123")
+	resp, err := svc.Login("test@example.com", "password123", models.SessionDevice{})
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
@@ -149,7 +152,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	_, err := svc.Login("nonexistent@example.com", "password123")
+	_, err := svc.Login("nonexistent@example.com", "password123", models.SessionDevice{})
 
 	assert.ErrorIs(t, err, ErrInvalidCredentials)
 }
@@ -167,7 +170,7 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	_, err := svc.Login("test@example.com", "wrongpassword")
+	_, err := svc.Login("test@example.com", "wrongpassword", models.SessionDevice{})
 
 	assert.ErrorIs(t, err, ErrInvalidCredentials)
 }
@@ -186,7 +189,7 @@ func TestAuthService_Login_OAuthOnly(t *testing.T) {
 	}
 	svc := newTestAuthService(mockRepo)
 
-	_, err := svc.Login("oauth@example.com", "anypassword")
+	_, err := svc.Login("oauth@example.com", "anypassword", models.SessionDevice{})
 
 	assert.ErrorIs(t, err, ErrOAuthOnly)
 }
@@ -196,13 +199,34 @@ func TestAuthService_ValidateToken_Roundtrip(t *testing.T) {
 	svc := newTestAuthService(mockRepo)
 
 	user := &models.User{ID: "user-123", Username: "testuser"}
-	token, err := svc.generateToken(user)
+	token, err := svc.generateToken(user, models.SessionDevice{})
 	require.NoError(t, err)
 
-	userID, err := svc.ValidateToken(token)
+	subject, err := svc.ValidateToken(token)
 
 	require.NoError(t, err)
-	assert.Equal(t, "user-123", userID)
+	assert.Equal(t, "user-123", subject.UserID)
+	assert.Empty(t, subject.SessionID)
+	assert.Equal(t, []string{models.ScopeRead, models.ScopeWrite, models.ScopeImport, models.ScopeAdmin}, subject.Scopes)
+}
+
+func TestAuthService_ValidateToken_ScopeClaim(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(svc.jwtSecret)
+		require.NoError(t, err)
+		return token
+	}
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	subject, err := svc.ValidateToken(sign(jwt.MapClaims{"sub": "user-123", "exp": expiresAt, "scope": "read"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ScopeRead}, subject.Scopes)
+
+	// Tokens issued before scopes existed keep the session scopes
+	subject, err = svc.ValidateToken(sign(jwt.MapClaims{"sub": "user-123", "exp": expiresAt}))
+	require.NoError(t, err)
+	assert.Equal(t, sessionScopes, subject.Scopes)
 }
 
 func TestAuthService_ValidateToken_InvalidString(t *testing.T) {
@@ -219,7 +243,7 @@ func TestAuthService_ValidateToken_Expired(t *testing.T) {
 	svc := NewAuthService(mockRepo, testJWTSecret, -1*time.Hour)
 
 	user := &models.User{ID: "user-123", Username: "testuser"}
-	token, err := svc.generateToken(user)
+	token, err := svc.generateToken(user, models.SessionDevice{})
 	require.NoError(t, err)
 
 	_, err = svc.ValidateToken(token)
@@ -256,24 +280,89 @@ func TestAuthService_GetUserByID_NotFound(t *testing.T) {
 
 func TestAuthService_UpdateProfile(t *testing.T) {
 	lichess := "lichessuser"
+	var saved []models.LinkedAccountInput
 	mockRepo := &mocks.MockUserRepo{
-		UpdateProfileFunc: func(userID string, l, c *string, timeFormatPrefs []string) (*models.User, error) {
-			return &models.User{
-				ID:               userID,
-				Username:         "testuser",
-				LichessUsername:  l,
-				ChesscomUsername: c,
-			}, nil
+		UpdateProfileFunc: func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
+			saved = accounts
+			return &models.User{ID: userID, Username: "testuser"}, nil
 		},
 	}
 	svc := newTestAuthService(mockRepo)
 
-	user, err := svc.UpdateProfile("user-123", models.UpdateProfileRequest{
+	_, err := svc.UpdateProfile("user-123", models.UpdateProfileRequest{
 		LichessUsername: &lichess,
 	})
 
 	require.NoError(t, err)
-	assert.Equal(t, &lichess, user.LichessUsername)
+	assert.Equal(t, []models.LinkedAccountInput{{Provider: models.ProviderLichess, Username: lichess}}, saved)
+}
+
+func TestAuthService_UpdateProfile_TimezoneAndLocale(t *testing.T) {
+	var savedTimezone, savedLocale *string
+	mockRepo := &mocks.MockUserRepo{
+		UpdateProfileFunc: func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
+			savedTimezone, savedLocale = timezone, locale
+			return &models.User{ID: userID}, nil
+		},
+	}
+	svc := newTestAuthService(mockRepo)
+
+	timezone, locale := "America/New_York", "en-us"
+	_, err := svc.UpdateProfile("user-123", models.UpdateProfileRequest{Timezone: &timezone, Locale: &locale})
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", *savedTimezone)
+	assert.Equal(t, "en-US", *savedLocale)
+
+	invalid := "Mars/Olympus_Mons"
+	_, err = svc.UpdateProfile("user-123", models.UpdateProfileRequest{Timezone: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidTimezone)
+
+	invalid = "not a locale"
+	_, err = svc.UpdateProfile("user-123", models.UpdateProfileRequest{Locale: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidLocale)
+}
+
+func TestAuthService_UpdateProfile_LinkedAccounts(t *testing.T) {
+	var saved []models.LinkedAccountInput
+	mockRepo := &mocks.MockUserRepo{
+		UpdateProfileFunc: func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
+			saved = accounts
+			return &models.User{ID: userID}, nil
+		},
+	}
+	svc := newTestAuthService(mockRepo)
+	ignored := "ignored"
+
+	_, err := svc.UpdateProfile("user-123", models.UpdateProfileRequest{
+		LinkedAccounts: []models.LinkedAccountInput{
+			{Provider: models.ProviderLichess, Username: "blitzalt"},
+			{Provider: models.ProviderLichess, Username: " BlitzAlt "},
+			{Provider: models.ProviderChesscom, Username: "blitzalt"},
+		},
+		LichessUsername: &ignored,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.LinkedAccountInput{
+		{Provider: models.ProviderLichess, Username: "blitzalt"},
+		{Provider: models.ProviderChesscom, Username: "blitzalt"},
+	}, saved)
+}
+
+func TestAuthService_UpdateProfile_InvalidLinkedAccounts(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+
+	_, err := svc.UpdateProfile("user-123", models.UpdateProfileRequest{
+		LinkedAccounts: []models.LinkedAccountInput{{Provider: "lichess", Username: "bad name!"}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidLinkedAccount)
+
+	tooMany := make([]models.LinkedAccountInput, config.MaxLinkedAccounts+1)
+	for i := range tooMany {
+		tooMany[i] = models.LinkedAccountInput{Provider: models.ProviderLichess, Username: fmt.Sprintf("alt%d", i)}
+	}
+	_, err = svc.UpdateProfile("user-123", models.UpdateProfileRequest{LinkedAccounts: tooMany})
+	assert.ErrorIs(t, err, ErrTooManyLinkedAccounts)
 }
 
 func TestAuthService_Register_ValidUsernames(t *testing.T) {
@@ -287,7 +376,7 @@ func TestAuthService_Register_ValidUsernames(t *testing.T) {
 	validNames := []string{"abc", "user_name", "user-name", "User123", "a_b-c"}
 	for _, name := range validNames {
 		t.Run(name, func(t *testing.T) {
-			resp, err := svc.Register("test@example.com", name, "password123")
+			resp, err := svc.Register("test@example.com", name, "password123", models.SessionDevice{})
 			require.NoError(t, err)
 			assert.NotNil(t, resp)
 		})
@@ -311,7 +400,7 @@ func TestAuthService_Register_ValidEmails(t *testing.T) {
 	}
 	for _, email := range validEmails {
 		t.Run(email, func(t *testing.T) {
-			resp, err := svc.Register(email, "testuser", "password123")
+			resp, err := svc.Register(email, "testuser", "password123", models.SessionDevice{})
 			require.NoError(t, err)
 			assert.NotNil(t, resp)
 		})
@@ -631,3 +720,123 @@ func TestAuthService_RequestPasswordReset_OAuthOnly(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestAuthService_UpdateProfile_AnalysisDepth(t *testing.T) {
+	var savedDepth *int
+	mockRepo := &mocks.MockUserRepo{
+		UpdateProfileFunc: func(userID string, accounts []models.LinkedAccountInput, timeFormatPrefs []string, analysisDepth *int, timezone, locale *string) (*models.User, error) {
+			savedDepth = analysisDepth
+			return &models.User{ID: userID}, nil
+		},
+	}
+	svc := newTestAuthService(mockRepo)
+
+	depth := 15
+	_, err := svc.UpdateProfile("user-123", models.UpdateProfileRequest{AnalysisDepth: &depth})
+	require.NoError(t, err)
+	require.NotNil(t, savedDepth)
+	assert.Equal(t, 15, *savedDepth)
+
+	negative := -1
+	_, err = svc.UpdateProfile("user-123", models.UpdateProfileRequest{AnalysisDepth: &negative})
+	assert.ErrorIs(t, err, ErrInvalidAnalysisDepth)
+}
+
+func TestAuthService_UpdateNotifications(t *testing.T) {
+	email := "me@example.com"
+	var saved *bool
+	mockRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			if id == "oauth-user" {
+				return &models.User{ID: id}, nil
+			}
+			return &models.User{ID: id, Email: &email}, nil
+		},
+		UpdateNotificationsFunc: func(userID string, weeklyDigest bool) (*models.User, error) {
+			saved = &weeklyDigest
+			return &models.User{ID: userID, WeeklyDigest: weeklyDigest}, nil
+		},
+	}
+	svc := newTestAuthService(mockRepo)
+
+	user, err := svc.UpdateNotifications("user-123", models.UpdateNotificationsRequest{WeeklyDigest: true})
+	require.NoError(t, err)
+	assert.True(t, user.WeeklyDigest)
+
+	_, err = svc.UpdateNotifications("oauth-user", models.UpdateNotificationsRequest{WeeklyDigest: true})
+	assert.ErrorIs(t, err, ErrDigestNeedsEmail)
+
+	// Opting out needs no email
+	saved = nil
+	_, err = svc.UpdateNotifications("oauth-user", models.UpdateNotificationsRequest{WeeklyDigest: false})
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.False(t, *saved)
+}
+
+func TestAuthService_Sessions_TokenCarriesSession(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	var touched string
+	svc.WithSessions(&mocks.MockSessionRepo{
+		TouchFunc: func(id string, seenAt time.Time) error {
+			touched = id
+			return nil
+		},
+	})
+
+	token, err := svc.generateToken(&models.User{ID: "user-123", Username: "testuser"}, models.SessionDevice{UserAgent: "Firefox"})
+	require.NoError(t, err)
+
+	subject, err := svc.ValidateToken(token)
+
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", subject.UserID)
+	assert.Equal(t, "session-1", subject.SessionID)
+	assert.Equal(t, "session-1", touched)
+}
+
+func TestAuthService_Sessions_RevokedSessionIsUnauthorized(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithSessions(&mocks.MockSessionRepo{
+		TouchFunc: func(id string, seenAt time.Time) error {
+			return repository.ErrSessionNotFound
+		},
+	})
+
+	token, err := svc.generateToken(&models.User{ID: "user-123", Username: "testuser"}, models.SessionDevice{})
+	require.NoError(t, err)
+
+	_, err = svc.ValidateToken(token)
+
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestAuthService_ListSessions_FlagsCurrent(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithSessions(&mocks.MockSessionRepo{
+		ListActiveFunc: func(userID string, now time.Time) ([]models.Session, error) {
+			return []models.Session{{ID: "session-1"}, {ID: "session-2"}}, nil
+		},
+	})
+
+	sessions, err := svc.ListSessions("user-123", "session-2")
+
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+}
+
+func TestAuthService_RevokeSession_NotFound(t *testing.T) {
+	svc := newTestAuthService(&mocks.MockUserRepo{})
+	svc.WithSessions(&mocks.MockSessionRepo{
+		RevokeFunc: func(id, userID string, revokedAt time.Time) error {
+			return repository.ErrSessionNotFound
+		},
+	})
+
+	err := svc.RevokeSession("user-123", "someone-elses-session")
+
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
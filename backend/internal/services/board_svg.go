@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	boardSquareSize  = 45
+	boardLightSquare = "#f0d9b5"
+	boardDarkSquare  = "#b58863"
+	boardHighlight   = "#cdd26a"
+)
+
+// ErrInvalidBoardMove is returned when the move to highlight is not a UCI move such as "e2e4"
+var ErrInvalidBoardMove = fmt.Errorf("last move must be in UCI notation, e.g. e2e4")
+
+// BoardOptions controls how RenderBoardSVG draws a position
+type BoardOptions struct {
+	// Flipped draws the board from Black's side
+	Flipped bool
+	// LastMove highlights the origin and destination squares of a UCI move, if set
+	LastMove string
+}
+
+// pieceGlyphs uses the filled glyphs for both sides; the fill colour tells them apart
+var pieceGlyphs = map[chess.PieceType]string{
+	chess.King:   "♚",
+	chess.Queen:  "♛",
+	chess.Rook:   "♜",
+	chess.Bishop: "♝",
+	chess.Knight: "♞",
+	chess.Pawn:   "♟",
+}
+
+// RenderBoardSVG draws a position as a standalone SVG diagram
+func RenderBoardSVG(fen string, opts BoardOptions) (string, error) {
+	opt, err := chess.FEN(ensureFullFEN(fen))
+	if err != nil {
+		return "", fmt.Errorf("invalid FEN: %w", err)
+	}
+	board := chess.NewGame(opt).Position().Board()
+	highlighted, err := lastMoveSquares(opts.LastMove)
+	if err != nil {
+		return "", err
+	}
+
+	size := 8 * boardSquareSize
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d">`, size, size, size, size)
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			file, rank := col, 7-row
+			if opts.Flipped {
+				file, rank = 7-col, row
+			}
+			x, y := col*boardSquareSize, row*boardSquareSize
+
+			square := chess.NewSquare(chess.File(file), chess.Rank(rank))
+			fill := boardDarkSquare
+			if (file+rank)%2 == 1 {
+				fill = boardLightSquare
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x, y, boardSquareSize, boardSquareSize, fill)
+			if highlighted[square] {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="0.6"/>`, x, y, boardSquareSize, boardSquareSize, boardHighlight)
+			}
+
+			piece := board.Piece(square)
+			if piece == chess.NoPiece {
+				continue
+			}
+			pieceFill, stroke := "#fff", "#000"
+			if piece.Color() == chess.Black {
+				pieceFill, stroke = "#000", "#fff"
+			}
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="%d" text-anchor="middle" dominant-baseline="central" fill="%s" stroke="%s" stroke-width="1">%s</text>`,
+				x+boardSquareSize/2, y+boardSquareSize/2, boardSquareSize*4/5, pieceFill, stroke, pieceGlyphs[piece.Type()])
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.String(), nil
+}
+
+// lastMoveSquares returns the origin and destination squares of a UCI move, or none for an empty move
+func lastMoveSquares(move string) (map[chess.Square]bool, error) {
+	if move == "" {
+		return nil, nil
+	}
+	if len(move) != 4 && len(move) != 5 {
+		return nil, ErrInvalidBoardMove
+	}
+	squares := make(map[chess.Square]bool, 2)
+	for _, name := range []string{move[0:2], move[2:4]} {
+		if name[0] < 'a' || name[0] > 'h' || name[1] < '1' || name[1] > '8' {
+			return nil, ErrInvalidBoardMove
+		}
+		squares[chess.NewSquare(chess.File(name[0]-'a'), chess.Rank(name[1]-'1'))] = true
+	}
+	if len(move) == 5 && !strings.ContainsRune("qrbn", rune(move[4])) {
+		return nil, ErrInvalidBoardMove
+	}
+	return squares, nil
+}
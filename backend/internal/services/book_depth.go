@@ -0,0 +1,46 @@
+package services
+
+import (
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+// BookDepth reports where the user's games in a repertoire leave the book, who leaves it, how that
+// evolves month by month, and how long the user thinks over the opening plies
+func (s *ImportService) BookDepth(userID, repertoireID string) (*models.BookDepth, error) {
+	if err := s.repertoireService.CheckReadAccess(repertoireID, userID); err != nil {
+		return nil, err
+	}
+
+	trend, err := s.analysisRepo.GetBookDepth(userID, repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	moveTimes, err := s.analysisRepo.GetMoveTimes(userID, repertoireID, config.BookDepthMaxPly)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := &models.BookDepth{RepertoireID: repertoireID, Trend: trend, MoveTimes: moveTimes}
+	var userPlies, opponentPlies float64
+	for _, p := range trend {
+		depth.Games += p.Games
+		if p.UserLeaveBookPly != nil {
+			depth.UserDeviations += p.UserDeviations
+			userPlies += *p.UserLeaveBookPly * float64(p.UserDeviations)
+		}
+		if p.OpponentLeaveBookPly != nil {
+			depth.OpponentDeviations += p.OpponentDeviations
+			opponentPlies += *p.OpponentLeaveBookPly * float64(p.OpponentDeviations)
+		}
+	}
+	if depth.UserDeviations > 0 {
+		avg := userPlies / float64(depth.UserDeviations)
+		depth.UserLeaveBookPly = &avg
+	}
+	if depth.OpponentDeviations > 0 {
+		avg := opponentPlies / float64(depth.OpponentDeviations)
+		depth.OpponentLeaveBookPly = &avg
+	}
+	return depth, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestBookDepth_AveragesAcrossMonths(t *testing.T) {
+	ply := func(v float64) *float64 { return &v }
+	var maxPly int
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetBookDepthFunc: func(userID, repertoireID string) ([]models.BookDepthPeriod, error) {
+			return []models.BookDepthPeriod{
+				{Month: "2024-04", Games: 5, UserDeviations: 1, UserLeaveBookPly: ply(6), OpponentDeviations: 2, OpponentLeaveBookPly: ply(9)},
+				{Month: "2024-05", Games: 6, UserDeviations: 3, UserLeaveBookPly: ply(10)},
+			}, nil
+		},
+		GetMoveTimesFunc: func(userID, repertoireID string, max int) ([]models.PlyMoveTime, error) {
+			maxPly = max
+			return []models.PlyMoveTime{{Ply: 0, Moves: 11, InBook: 11, AvgTimeSpent: 1.5}}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo)
+
+	depth, err := svc.BookDepth("user-1", "rep-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, config.BookDepthMaxPly, maxPly)
+	assert.Equal(t, 11, depth.Games)
+	assert.Equal(t, 4, depth.UserDeviations)
+	require.NotNil(t, depth.UserLeaveBookPly)
+	assert.InDelta(t, 9, *depth.UserLeaveBookPly, 0.0001)
+	assert.Equal(t, 2, depth.OpponentDeviations)
+	require.NotNil(t, depth.OpponentLeaveBookPly)
+	assert.InDelta(t, 9, *depth.OpponentLeaveBookPly, 0.0001)
+	assert.Len(t, depth.Trend, 2)
+	assert.Len(t, depth.MoveTimes, 1)
+}
+
+func TestBookDepth_NoDeviations(t *testing.T) {
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), &mocks.MockAnalysisRepo{})
+
+	depth, err := svc.BookDepth("user-1", "rep-1")
+
+	require.NoError(t, err)
+	assert.Zero(t, depth.Games)
+	assert.Nil(t, depth.UserLeaveBookPly)
+	assert.Nil(t, depth.OpponentLeaveBookPly)
+	assert.NotNil(t, depth.Trend)
+}
+
+func TestBookDepth_NotFound(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return false, nil },
+	})
+	svc := NewImportService(repSvc, &mocks.MockAnalysisRepo{})
+
+	_, err := svc.BookDepth("user-1", "rep-1")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
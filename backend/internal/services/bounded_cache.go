@@ -0,0 +1,65 @@
+package services
+
+import "time"
+
+// boundedCache keeps values for a limited time and holds at most max entries: when full, expired
+// entries are dropped first, then the oldest ones. Keys come from clients, so the bound is what
+// keeps memory in check. It is not safe for concurrent use; callers hold their own lock.
+type boundedCache[V any] struct {
+	ttl     time.Duration
+	max     int
+	entries map[string]boundedCacheEntry[V]
+}
+
+type boundedCacheEntry[V any] struct {
+	value     V
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+func newBoundedCache[V any](ttl time.Duration, max int) *boundedCache[V] {
+	return &boundedCache[V]{ttl: ttl, max: max, entries: make(map[string]boundedCacheEntry[V])}
+}
+
+// get returns the value cached for key, unless it expired
+func (c *boundedCache[V]) get(key string, now time.Time) (V, bool) {
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// set caches value for key, evicting entries when the cache is full
+func (c *boundedCache[V]) set(key string, value V, now time.Time) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.max {
+		c.evict(now)
+	}
+	c.entries[key] = boundedCacheEntry[V]{value: value, storedAt: now, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *boundedCache[V]) delete(key string) {
+	delete(c.entries, key)
+}
+
+func (c *boundedCache[V]) clear() {
+	c.entries = make(map[string]boundedCacheEntry[V])
+}
+
+// evict drops the expired entries, or the oldest one when none expired
+func (c *boundedCache[V]) evict(now time.Time) {
+	oldest := ""
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.storedAt.Before(c.entries[oldest].storedAt) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.max && oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
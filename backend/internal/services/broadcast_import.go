@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// broadcastFilenamePrefix marks analyses of broadcast games, imported for reference rather than played by the user
+const broadcastFilenamePrefix = "broadcast_"
+
+// lichessBroadcastRoundURLPattern matches Lichess broadcast round URLs.
+// Accepts: https://lichess.org/broadcast/tour-slug/round-slug/abcdef12, the same followed by a game ID, or a raw round ID.
+var lichessBroadcastRoundURLPattern = regexp.MustCompile(`^(?:https?://(?:www\.)?lichess\.org/broadcast/[^/\s]+/[^/\s]+/)?([a-zA-Z0-9]{8})(?:/[a-zA-Z0-9]{8})?/?$`)
+
+// ParseBroadcastRoundURL extracts the round ID from a Lichess broadcast round URL or raw ID.
+func ParseBroadcastRoundURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("broadcast round URL is required")
+	}
+
+	matches := lichessBroadcastRoundURLPattern.FindStringSubmatch(rawURL)
+	if matches == nil {
+		return "", fmt.Errorf("invalid Lichess broadcast round URL or ID: %s", rawURL)
+	}
+	return matches[1], nil
+}
+
+// BroadcastFilename is the filename recorded for the import of a broadcast round
+func BroadcastFilename(roundID string) string {
+	return fmt.Sprintf("%s%s.pgn", broadcastFilenamePrefix, roundID)
+}
+
+// isReferenceImport reports whether an analysis holds games the user did not play
+func isReferenceImport(filename string) bool {
+	return strings.HasPrefix(filename, broadcastFilenamePrefix) || strings.HasPrefix(filename, teamFilenamePrefix)
+}
+
+// ownGameAnalyses drops the reference imports, whose results say nothing about the user's play
+func ownGameAnalyses(analyses []models.RawAnalysis) []models.RawAnalysis {
+	var own []models.RawAnalysis
+	for _, a := range analyses {
+		if !isReferenceImport(a.Filename) {
+			own = append(own, a)
+		}
+	}
+	return own
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+func TestParseBroadcastRoundURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		roundID string
+		wantErr bool
+	}{
+		{"round URL", "https://lichess.org/broadcast/tata-steel-2025/round-1/abcdEF12", "abcdEF12", false},
+		{"round URL with game", "https://lichess.org/broadcast/tata-steel-2025/round-1/abcdEF12/ghijKL34", "abcdEF12", false},
+		{"raw round ID", "  abcdEF12  ", "abcdEF12", false},
+		{"tournament URL", "https://lichess.org/broadcast/tata-steel-2025/abcdEF12/extra/more", "", true},
+		{"study URL", "https://lichess.org/study/abcdEF12", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundID, err := ParseBroadcastRoundURL(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.roundID, roundID)
+		})
+	}
+}
+
+func TestOwnGameAnalyses(t *testing.T) {
+	analyses := []models.RawAnalysis{
+		{ID: "own", Filename: "lichess_me.pgn"},
+		{ID: "reference", Filename: BroadcastFilename("abcdef12")},
+	}
+
+	own := ownGameAnalyses(analyses)
+
+	require.Len(t, own, 1)
+	assert.Equal(t, "own", own[0].ID)
+}
@@ -11,7 +11,7 @@ import (
 
 // Category errors
 var (
-	ErrCategoryNotFound = fmt.Errorf("category not found")
+	ErrCategoryNotFound = fmt.Errorf("category %w", ErrNotFound)
 	ErrCategoryLimit    = fmt.Errorf("maximum category limit reached (50)")
 )
 
@@ -25,8 +25,9 @@ type ExtendedRepertoireRepository interface {
 
 // CategoryService handles category business logic
 type CategoryService struct {
-	repo           repository.CategoryRepository
-	repertoireRepo ExtendedRepertoireRepository
+	repo            repository.CategoryRepository
+	repertoireRepo  ExtendedRepertoireRepository
+	repertoireCache *RepertoireCache
 }
 
 // NewCategoryService creates a new category service
@@ -120,8 +121,37 @@ func (s *CategoryService) RenameCategory(id, name string) (*models.Category, err
 	return s.repo.UpdateName(id, name)
 }
 
+// SetMatchActive sets whether the repertoires of a category are matched against imported games
+func (s *CategoryService) SetMatchActive(id string, active bool) (*models.Category, error) {
+	cat, err := s.repo.SetMatchActive(id, active)
+	if err != nil {
+		if errors.Is(err, repository.ErrCategoryNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return cat, nil
+}
+
+// WithRepertoireCache invalidates cache when a deleted category takes its repertoires with it
+func (s *CategoryService) WithRepertoireCache(cache *RepertoireCache) {
+	s.repertoireCache = cache
+}
+
 // DeleteCategory deletes a category (and cascades to its repertoires)
 func (s *CategoryService) DeleteCategory(id string) error {
+	if s.repertoireCache != nil {
+		repertoires, err := s.repertoireRepo.GetByCategory(id)
+		if err != nil {
+			return fmt.Errorf("failed to get repertoires for category: %w", err)
+		}
+		defer func() {
+			for _, rep := range repertoires {
+				s.repertoireCache.InvalidateRepertoire(rep.ID)
+			}
+		}()
+	}
+
 	err := s.repo.Delete(id)
 	if err != nil {
 		if errors.Is(err, repository.ErrCategoryNotFound) {
@@ -135,13 +165,7 @@ func (s *CategoryService) DeleteCategory(id string) error {
 // CheckOwnership verifies that a category belongs to the given user
 func (s *CategoryService) CheckOwnership(id, userID string) error {
 	belongs, err := s.repo.BelongsToUser(id, userID)
-	if err != nil {
-		return fmt.Errorf("failed to check ownership: %w", err)
-	}
-	if !belongs {
-		return ErrCategoryNotFound
-	}
-	return nil
+	return ownershipGuard(belongs, err, ErrCategoryNotFound)
 }
 
 // GetRepertoireCountForCategory returns the number of repertoires in a category
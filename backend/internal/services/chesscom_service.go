@@ -22,9 +22,7 @@ type ChesscomService struct {
 
 func NewChesscomService() *ChesscomService {
 	return &ChesscomService{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient: newResilientHTTPClient(30 * time.Second),
 	}
 }
 
@@ -86,7 +84,7 @@ func (s *ChesscomService) FetchGames(username string, options models.ChesscomImp
 
 	for i := len(filteredArchives) - 1; i >= 0 && totalGames < maxGames; i-- {
 		pgnURL := filteredArchives[i] + "/pgn"
-		monthPGN, err := s.fetchMonthPGN(pgnURL, options.TimeClass)
+		monthPGN, err := s.fetchMonthPGN(pgnURL, options)
 		if err != nil {
 			// Skip months that fail (could be rate limited on individual month)
 			continue
@@ -129,7 +127,7 @@ func (s *ChesscomService) doRequest(url string) (*http.Response, error) {
 	return s.httpClient.Do(req)
 }
 
-func (s *ChesscomService) fetchMonthPGN(pgnURL string, timeClass string) (string, error) {
+func (s *ChesscomService) fetchMonthPGN(pgnURL string, options models.ChesscomImportOptions) (string, error) {
 	req, err := http.NewRequest(http.MethodGet, pgnURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
@@ -156,9 +154,12 @@ func (s *ChesscomService) fetchMonthPGN(pgnURL string, timeClass string) (string
 
 	pgnData := string(body)
 
-	// Filter by time class if specified
-	if timeClass != "" {
-		pgnData = filterByTimeClass(pgnData, timeClass)
+	// Filter by time class if specified; daily games share the monthly archives with live ones
+	switch {
+	case options.TimeClass != "":
+		pgnData = filterByTimeClass(pgnData, options.TimeClass)
+	case options.ExcludeDaily:
+		pgnData = filterOutDaily(pgnData)
 	}
 
 	return pgnData, nil
@@ -265,32 +266,47 @@ func splitPGNGames(pgn string) []string {
 // filterByTimeClass filters PGN games by their TimeControl header.
 // Chess.com uses TimeControl header; we map timeClass to expected ranges.
 func filterByTimeClass(pgn string, timeClass string) string {
-	games := splitPGNGames(pgn)
-	var filtered []string
+	return filterGames(pgn, func(game string) bool { return matchesTimeClass(game, timeClass) })
+}
 
-	for _, game := range games {
-		if matchesTimeClass(game, timeClass) {
+// filterOutDaily removes daily (correspondence) games
+func filterOutDaily(pgn string) string {
+	return filterGames(pgn, func(game string) bool {
+		tc, ok := gameTimeControl(game)
+		return !ok || models.ClassifyTimeControl(tc) != "daily"
+	})
+}
+
+func filterGames(pgn string, keep func(game string) bool) string {
+	var filtered []string
+	for _, game := range splitPGNGames(pgn) {
+		if keep(game) {
 			filtered = append(filtered, game)
 		}
 	}
-
 	return strings.Join(filtered, "\n\n")
 }
 
 // matchesTimeClass checks if a PGN game matches the desired time class.
-// Chess.com PGN includes [TimeControl "X"] header where X is base time in seconds
-// or base+increment format like "600+5".
+// Chess.com PGN includes [TimeControl "X"] header where X is base time in seconds,
+// base+increment format like "600+5", or "1/86400" (seconds per move) for daily games.
 func matchesTimeClass(game string, timeClass string) bool {
-	// Look for TimeControl header
+	tc, ok := gameTimeControl(game)
+	if !ok {
+		// If no TimeControl header, include the game (don't filter out)
+		return true
+	}
+	return models.ClassifyTimeControl(tc) == timeClass
+}
+
+// gameTimeControl returns the TimeControl header of a single PGN game
+func gameTimeControl(game string) (string, bool) {
 	for _, line := range strings.Split(game, "\n") {
 		trimmed := strings.TrimSpace(line)
 		if strings.HasPrefix(trimmed, "[TimeControl ") {
 			tc := strings.TrimPrefix(trimmed, "[TimeControl \"")
-			tc = strings.TrimSuffix(tc, "\"]")
-			return models.ClassifyTimeControl(tc) == timeClass
+			return strings.TrimSuffix(tc, "\"]"), true
 		}
 	}
-	// If no TimeControl header, include the game (don't filter out)
-	return true
+	return "", false
 }
-
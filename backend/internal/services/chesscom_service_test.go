@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const chesscomMonthPGN = `[Event "Live Chess"]
+[TimeControl "180+2"]
+[Result "1-0"]
+
+1. e4 e5 1-0
+
+[Event "Let's Play!"]
+[TimeControl "1/86400"]
+[Result "0-1"]
+
+1. d4 d5 0-1
+`
+
+func TestFilterByTimeClass_Daily(t *testing.T) {
+	daily := filterByTimeClass(chesscomMonthPGN, "daily")
+	assert.Contains(t, daily, "1/86400")
+	assert.NotContains(t, daily, "180+2")
+
+	bullet := filterByTimeClass(chesscomMonthPGN, "bullet")
+	assert.Empty(t, bullet)
+}
+
+func TestFilterOutDaily(t *testing.T) {
+	live := filterOutDaily(chesscomMonthPGN)
+
+	assert.Contains(t, live, "180+2")
+	assert.NotContains(t, live, "1/86400")
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var (
+	ErrClassificationRuleNotFound = fmt.Errorf("classification rule %w", ErrNotFound)
+	ErrInvalidClassificationRule  = fmt.Errorf("invalid classification rule: key must be a lowercase slug other than a built-in status, name at most %d characters, and at least one condition set within range",
+		config.MaxClassificationRuleNameLen)
+	ErrClassificationRuleLimit = fmt.Errorf("maximum classification rule limit reached (%d)", config.MaxClassificationRulesPerUser)
+)
+
+// classificationKeyPattern restricts rule keys to slugs usable as a query parameter
+var classificationKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+// moveStatuses are the fixed statuses of analyzed moves, which rules can require
+var moveStatuses = map[string]bool{
+	"in-repertoire":     true,
+	"out-of-repertoire": true,
+	"opponent-new":      true,
+	"out-of-book":       true,
+	"beyond-book":       true,
+}
+
+// gameStatuses are the fixed statuses of games; rule keys may shadow neither these nor the move statuses
+var gameStatuses = map[string]bool{
+	"ok":       true,
+	"error":    true,
+	"new-line": true,
+}
+
+// WithClassificationRuleRepo lets users classify analyzed moves with their own rules
+func WithClassificationRuleRepo(repo repository.ClassificationRuleRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.classificationRuleRepo = repo
+	}
+}
+
+// ListClassificationRules returns the user's classification rules, oldest first
+func (s *ImportService) ListClassificationRules(userID string) ([]models.ClassificationRule, error) {
+	if s.classificationRuleRepo == nil {
+		return []models.ClassificationRule{}, nil
+	}
+	rules, err := s.classificationRuleRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []models.ClassificationRule{}
+	}
+	return rules, nil
+}
+
+// CreateClassificationRule validates and stores a new classification rule. It applies to the games
+// analyzed or re-analyzed from then on.
+func (s *ImportService) CreateClassificationRule(userID string, req models.ClassificationRuleRequest) (*models.ClassificationRule, error) {
+	rule, err := validateClassificationRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if s.classificationRuleRepo == nil {
+		return nil, fmt.Errorf("classification rules are not configured")
+	}
+
+	count, err := s.classificationRuleRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= config.MaxClassificationRulesPerUser {
+		return nil, ErrClassificationRuleLimit
+	}
+
+	return s.classificationRuleRepo.Create(userID, rule)
+}
+
+// UpdateClassificationRule replaces the key, name and conditions of one of the user's rules
+func (s *ImportService) UpdateClassificationRule(userID, id string, req models.ClassificationRuleRequest) (*models.ClassificationRule, error) {
+	rule, err := validateClassificationRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkClassificationRuleOwnership(id, userID); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.classificationRuleRepo.Update(id, rule)
+	if errors.Is(err, repository.ErrClassificationRuleNotFound) {
+		return nil, ErrClassificationRuleNotFound
+	}
+	return updated, err
+}
+
+// DeleteClassificationRule deletes one of the user's rules. Games keep the key until re-analyzed.
+func (s *ImportService) DeleteClassificationRule(userID, id string) error {
+	if err := s.checkClassificationRuleOwnership(id, userID); err != nil {
+		return err
+	}
+
+	err := s.classificationRuleRepo.Delete(id)
+	if errors.Is(err, repository.ErrClassificationRuleNotFound) {
+		return ErrClassificationRuleNotFound
+	}
+	return err
+}
+
+func (s *ImportService) checkClassificationRuleOwnership(id, userID string) error {
+	if s.classificationRuleRepo == nil {
+		return ErrClassificationRuleNotFound
+	}
+	belongs, err := s.classificationRuleRepo.BelongsToUser(id, userID)
+	if err != nil {
+		return err
+	}
+	if !belongs {
+		return ErrClassificationRuleNotFound
+	}
+	return nil
+}
+
+// validateClassificationRule checks a rule request and returns the rule to store
+func validateClassificationRule(req models.ClassificationRuleRequest) (models.ClassificationRule, error) {
+	rule := models.ClassificationRule{
+		Key:            strings.TrimSpace(req.Key),
+		Name:           strings.TrimSpace(req.Name),
+		MoveStatus:     req.MoveStatus,
+		Mover:          req.Mover,
+		MinWinrateDrop: req.MinWinrateDrop,
+		MinTimeSpent:   req.MinTimeSpent,
+		MaxPly:         req.MaxPly,
+	}
+	if rule.Name == "" {
+		rule.Name = rule.Key
+	}
+
+	switch {
+	case !classificationKeyPattern.MatchString(rule.Key), moveStatuses[rule.Key], gameStatuses[rule.Key]:
+		return rule, ErrInvalidClassificationRule
+	case len(rule.Name) > config.MaxClassificationRuleNameLen:
+		return rule, ErrInvalidClassificationRule
+	case rule.MoveStatus != "" && !moveStatuses[rule.MoveStatus]:
+		return rule, ErrInvalidClassificationRule
+	case rule.Mover != "" && rule.Mover != models.ClassificationMoverUser && rule.Mover != models.ClassificationMoverOpponent:
+		return rule, ErrInvalidClassificationRule
+	case rule.MinWinrateDrop != nil && (*rule.MinWinrateDrop < 0 || *rule.MinWinrateDrop > 1):
+		return rule, ErrInvalidClassificationRule
+	case rule.MinTimeSpent != nil && *rule.MinTimeSpent < 0:
+		return rule, ErrInvalidClassificationRule
+	case rule.MaxPly != nil && (*rule.MaxPly < 1 || *rule.MaxPly > config.MaxAnalysisDepth):
+		return rule, ErrInvalidClassificationRule
+	case rule.MoveStatus == "" && rule.Mover == "" && rule.MinWinrateDrop == nil && rule.MinTimeSpent == nil && rule.MaxPly == nil:
+		return rule, ErrInvalidClassificationRule
+	}
+	return rule, nil
+}
+
+// classificationRulesFor returns the rules of a user. Classification is an extra on top of the
+// analysis, so a failure to load them is logged and the games are left unclassified.
+func classificationRulesFor(repo repository.ClassificationRuleRepository, userID string) []models.ClassificationRule {
+	if repo == nil {
+		return nil
+	}
+	rules, err := repo.GetByUser(userID)
+	if err != nil {
+		log.Printf("classification: failed to get rules of user %s: %v", userID, err)
+		return nil
+	}
+	return rules
+}
+
+// classifyGame replaces the classifications of every move of a game with the keys of the rules it matches
+func classifyGame(game *models.GameAnalysis, rules []models.ClassificationRule) {
+	for i := range game.Moves {
+		move := &game.Moves[i]
+		move.Classifications = nil
+		for _, rule := range rules {
+			if rule.Matches(*move) {
+				move.Classifications = append(move.Classifications, rule.Key)
+			}
+		}
+	}
+}
+
+// classifyGames applies the user's classification rules to freshly analyzed games
+func (s *ImportService) classifyGames(userID string, games []models.GameAnalysis) {
+	rules := classificationRulesFor(s.classificationRuleRepo, userID)
+	for i := range games {
+		classifyGame(&games[i], rules)
+	}
+}
+
+// copyWinrateDrops carries the winrate drops of an evaluated game over to its new analysis,
+// matching the moves by ply
+func copyWinrateDrops(from, to []models.MoveAnalysis) {
+	for i := range to {
+		if i < len(from) && from[i].PlyNumber == to[i].PlyNumber && from[i].SAN == to[i].SAN {
+			to[i].WinrateDrop = from[i].WinrateDrop
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func inaccuracyInBook() models.ClassificationRule {
+	drop := 0.05
+	return models.ClassificationRule{
+		Key:            "inaccuracy-in-book",
+		MoveStatus:     "in-repertoire",
+		Mover:          models.ClassificationMoverUser,
+		MinWinrateDrop: &drop,
+	}
+}
+
+func TestClassifyGame(t *testing.T) {
+	small, large, slow := 0.01, 0.08, 30.0
+	maxPly := 2
+	rules := []models.ClassificationRule{
+		inaccuracyInBook(),
+		{Key: "slow-opening", MinTimeSpent: &slow, MaxPly: &maxPly},
+	}
+	game := models.GameAnalysis{Moves: []models.MoveAnalysis{
+		{PlyNumber: 0, SAN: "e4", Status: "in-repertoire", IsUserMove: true, WinrateDrop: &large, TimeSpent: &slow},
+		{PlyNumber: 1, SAN: "c5", Status: "in-repertoire", TimeSpent: &slow},
+		{PlyNumber: 2, SAN: "Nf3", Status: "in-repertoire", IsUserMove: true, WinrateDrop: &small, TimeSpent: &slow},
+		{PlyNumber: 3, SAN: "d6", Status: "opponent-new", Classifications: []string{"stale"}},
+		{PlyNumber: 4, SAN: "Bb5", Status: "out-of-book", IsUserMove: true, WinrateDrop: &large},
+	}}
+
+	classifyGame(&game, rules)
+
+	assert.Equal(t, []string{"inaccuracy-in-book", "slow-opening"}, game.Moves[0].Classifications)
+	assert.Equal(t, []string{"slow-opening"}, game.Moves[1].Classifications)
+	assert.Empty(t, game.Moves[2].Classifications, "small drop and past maxPly")
+	assert.Empty(t, game.Moves[3].Classifications, "previous classifications are replaced")
+	assert.Empty(t, game.Moves[4].Classifications, "out of book")
+}
+
+func TestCreateClassificationRule(t *testing.T) {
+	var created models.ClassificationRule
+	repo := &mocks.MockClassificationRuleRepo{
+		CreateFunc: func(userID string, rule models.ClassificationRule) (*models.ClassificationRule, error) {
+			created = rule
+			return &rule, nil
+		},
+	}
+	svc := NewImportService(nil, nil, WithClassificationRuleRepo(repo))
+	drop := 0.05
+
+	t.Run("saves a valid rule", func(t *testing.T) {
+		_, err := svc.CreateClassificationRule("user-1", models.ClassificationRuleRequest{
+			Key: " inaccuracy-in-book ", MoveStatus: "in-repertoire", Mover: "user", MinWinrateDrop: &drop,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "inaccuracy-in-book", created.Key)
+		assert.Equal(t, "inaccuracy-in-book", created.Name, "the name defaults to the key")
+	})
+
+	tooDeep := config.MaxAnalysisDepth + 1
+	tooLarge := 1.5
+	for name, req := range map[string]models.ClassificationRuleRequest{
+		"uppercase key":     {Key: "Blunder", Mover: "user"},
+		"built-in key":      {Key: "out-of-book", Mover: "user"},
+		"unknown status":    {Key: "odd", MoveStatus: "error"},
+		"unknown mover":     {Key: "odd", Mover: "both"},
+		"drop above one":    {Key: "odd", MinWinrateDrop: &tooLarge},
+		"ply beyond depth":  {Key: "odd", MaxPly: &tooDeep},
+		"without condition": {Key: "odd", Name: "Odd"},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := svc.CreateClassificationRule("user-1", req)
+			assert.ErrorIs(t, err, ErrInvalidClassificationRule)
+		})
+	}
+
+	t.Run("enforces the limit", func(t *testing.T) {
+		repo.CountByUserFunc = func(userID string) (int, error) { return config.MaxClassificationRulesPerUser, nil }
+		defer func() { repo.CountByUserFunc = nil }()
+
+		_, err := svc.CreateClassificationRule("user-1", models.ClassificationRuleRequest{Key: "mine", Mover: "user"})
+		assert.ErrorIs(t, err, ErrClassificationRuleLimit)
+	})
+}
+
+func TestDeleteClassificationRule_OtherUser(t *testing.T) {
+	deleted := false
+	repo := &mocks.MockClassificationRuleRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return false, nil },
+		DeleteFunc: func(id string) error {
+			deleted = true
+			return nil
+		},
+	}
+	svc := NewImportService(nil, nil, WithClassificationRuleRepo(repo))
+
+	err := svc.DeleteClassificationRule("user-1", "rule-1")
+
+	assert.ErrorIs(t, err, ErrClassificationRuleNotFound)
+	assert.False(t, deleted)
+}
+
+func TestParseAndAnalyze_AppliesClassificationRules(t *testing.T) {
+	var saved []models.GameAnalysis
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			saved = results
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	rules := &mocks.MockClassificationRuleRepo{
+		GetByUserFunc: func(userID string) ([]models.ClassificationRule, error) {
+			return []models.ClassificationRule{{Key: "opponent-surprise", Mover: models.ClassificationMoverOpponent}}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo, WithClassificationRuleRepo(rules))
+
+	_, _, err := svc.ParseAndAnalyze("games.pgn", "me", "user-1", "[White \"me\"]\n[Black \"someone\"]\n\n1. e4 c5 1-0")
+
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.Len(t, saved[0].Moves, 2)
+	assert.Empty(t, saved[0].Moves[0].Classifications)
+	assert.Equal(t, []string{"opponent-surprise"}, saved[0].Moves[1].Classifications)
+}
+
+func TestClassifyEvaluatedGame(t *testing.T) {
+	var updated *models.GameAnalysis
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return &models.GameAnalysis{GameIndex: gameIndex, Moves: []models.MoveAnalysis{
+				{PlyNumber: 0, SAN: "e4", Status: "in-repertoire", IsUserMove: true},
+				{PlyNumber: 1, SAN: "e5", Status: "in-repertoire"},
+			}}, nil
+		},
+		UpdateGameFunc: func(analysisID string, game models.GameAnalysis) error {
+			updated = &game
+			return nil
+		},
+	}
+	svc := NewEngineService(&mocks.MockEngineEvalRepo{}, analysisRepo)
+	svc.WithClassificationRules(&mocks.MockClassificationRuleRepo{
+		GetByUserFunc: func(userID string) ([]models.ClassificationRule, error) {
+			assert.Equal(t, "user-1", userID)
+			return []models.ClassificationRule{inaccuracyInBook()}, nil
+		},
+	})
+
+	svc.classifyEvaluatedGame(models.EngineEval{UserID: "user-1", AnalysisID: "analysis-1", GameIndex: 3},
+		[]models.ExplorerMoveStats{{PlyNumber: 0, WinrateDrop: 0.1}})
+
+	require.NotNil(t, updated)
+	assert.Equal(t, 3, updated.GameIndex)
+	require.NotNil(t, updated.Moves[0].WinrateDrop)
+	assert.Equal(t, 0.1, *updated.Moves[0].WinrateDrop)
+	assert.Equal(t, []string{"inaccuracy-in-book"}, updated.Moves[0].Classifications)
+	assert.Nil(t, updated.Moves[1].WinrateDrop)
+}
+
+func TestClassifyEvaluatedGame_MissingGame(t *testing.T) {
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return nil, repository.ErrGameNotFound
+		},
+		UpdateGameFunc: func(analysisID string, game models.GameAnalysis) error {
+			t.Fatal("a missing game is not saved")
+			return nil
+		},
+	}
+	svc := NewEngineService(&mocks.MockEngineEvalRepo{}, analysisRepo)
+	svc.WithClassificationRules(&mocks.MockClassificationRuleRepo{})
+
+	svc.classifyEvaluatedGame(models.EngineEval{AnalysisID: "analysis-1"}, nil)
+}
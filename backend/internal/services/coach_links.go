@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var (
+	ErrCoachLinkNotFound = fmt.Errorf("coach link %w", ErrNotFound)
+	ErrInvalidLinkRole   = fmt.Errorf("role must be 'coach' or 'student'")
+	ErrCannotLinkSelf    = fmt.Errorf("cannot link with yourself")
+	ErrCoachLinkExists   = fmt.Errorf("a link with this user already exists")
+	ErrLinkAlreadyActive = fmt.Errorf("coach link is already active")
+)
+
+// CoachLinkService manages coach-student links, which let a coach read a student's games,
+// insights and repertoires once the invitee accepted
+type CoachLinkService struct {
+	linkRepo repository.CoachLinkRepository
+	userRepo repository.UserRepository
+}
+
+// NewCoachLinkService creates a new coach link service
+func NewCoachLinkService(linkRepo repository.CoachLinkRepository, userRepo repository.UserRepository) *CoachLinkService {
+	return &CoachLinkService{linkRepo: linkRepo, userRepo: userRepo}
+}
+
+// Invite creates a pending link between the user and the invitee, who takes the side given by the request's role
+func (s *CoachLinkService) Invite(userID string, req models.InviteCoachLinkRequest) (*models.CoachLink, error) {
+	if req.Role != models.CoachLinkRoleCoach && req.Role != models.CoachLinkRoleStudent {
+		return nil, ErrInvalidLinkRole
+	}
+
+	var invitee *models.User
+	var err error
+	switch {
+	case strings.TrimSpace(req.Email) != "":
+		invitee, err = s.userRepo.GetByEmail(strings.TrimSpace(req.Email))
+	case strings.TrimSpace(req.Username) != "":
+		invitee, err = s.userRepo.GetByUsername(strings.TrimSpace(req.Username))
+	default:
+		return nil, ErrInviteeRequired
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInviteeNotFound
+		}
+		return nil, fmt.Errorf("failed to find invitee: %w", err)
+	}
+	if invitee.ID == userID {
+		return nil, ErrCannotLinkSelf
+	}
+
+	coachID, studentID := userID, invitee.ID
+	if req.Role == models.CoachLinkRoleCoach {
+		coachID, studentID = invitee.ID, userID
+	}
+	link, err := s.linkRepo.Create(coachID, studentID, userID)
+	if errors.Is(err, repository.ErrCoachLinkExists) {
+		return nil, ErrCoachLinkExists
+	}
+	return link, err
+}
+
+// List returns the links the user is on either side of, pending ones included
+func (s *CoachLinkService) List(userID string) ([]models.CoachLink, error) {
+	return s.linkRepo.ListByUser(userID)
+}
+
+// Accept activates a pending link. Only the invitee may accept it.
+func (s *CoachLinkService) Accept(id, userID string) (*models.CoachLink, error) {
+	link, err := s.getForUser(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if link.InvitedBy == userID {
+		return nil, ErrForbidden
+	}
+	if link.Status == models.CoachLinkActive {
+		return nil, ErrLinkAlreadyActive
+	}
+
+	link, err = s.linkRepo.Accept(id, time.Now())
+	if errors.Is(err, repository.ErrCoachLinkNotFound) {
+		return nil, ErrCoachLinkNotFound
+	}
+	return link, err
+}
+
+// Revoke removes a link; either side may revoke it, or decline it while pending
+func (s *CoachLinkService) Revoke(id, userID string) error {
+	if _, err := s.getForUser(id, userID); err != nil {
+		return err
+	}
+	err := s.linkRepo.Delete(id)
+	if errors.Is(err, repository.ErrCoachLinkNotFound) {
+		return ErrCoachLinkNotFound
+	}
+	return err
+}
+
+// CheckCoachAccess returns ErrNotFound unless the coach has an accepted link with the student
+func (s *CoachLinkService) CheckCoachAccess(coachID, studentID string) error {
+	active, err := s.linkRepo.IsActive(coachID, studentID)
+	return ownershipGuard(active, err, ErrCoachLinkNotFound)
+}
+
+// getForUser loads a link the user is on, reporting links of other users as not found
+func (s *CoachLinkService) getForUser(id, userID string) (*models.CoachLink, error) {
+	link, err := s.linkRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrCoachLinkNotFound) {
+			return nil, ErrCoachLinkNotFound
+		}
+		return nil, err
+	}
+	if link.CoachID != userID && link.StudentID != userID {
+		return nil, ErrCoachLinkNotFound
+	}
+	return link, nil
+}
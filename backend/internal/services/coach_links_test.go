@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestCoachLinkInvite_InviteeSide(t *testing.T) {
+	var coach, student string
+	linkRepo := &mocks.MockCoachLinkRepo{
+		CreateFunc: func(coachID, studentID, invitedBy string) (*models.CoachLink, error) {
+			coach, student = coachID, studentID
+			assert.Equal(t, "user-1", invitedBy)
+			return &models.CoachLink{ID: "link-1"}, nil
+		},
+	}
+	userRepo := &mocks.MockUserRepo{
+		GetByUsernameFunc: func(username string) (*models.User, error) {
+			return &models.User{ID: "user-2", Username: username}, nil
+		},
+	}
+	svc := NewCoachLinkService(linkRepo, userRepo)
+
+	_, err := svc.Invite("user-1", models.InviteCoachLinkRequest{Username: "coachy", Role: models.CoachLinkRoleCoach})
+	require.NoError(t, err)
+	assert.Equal(t, "user-2", coach)
+	assert.Equal(t, "user-1", student)
+
+	_, err = svc.Invite("user-1", models.InviteCoachLinkRequest{Username: "pupil", Role: models.CoachLinkRoleStudent})
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", coach)
+	assert.Equal(t, "user-2", student)
+}
+
+func TestCoachLinkInvite_Errors(t *testing.T) {
+	userRepo := &mocks.MockUserRepo{
+		GetByUsernameFunc: func(username string) (*models.User, error) {
+			if username == "me" {
+				return &models.User{ID: "user-1"}, nil
+			}
+			return nil, repository.ErrUserNotFound
+		},
+	}
+	linkRepo := &mocks.MockCoachLinkRepo{
+		CreateFunc: func(coachID, studentID, invitedBy string) (*models.CoachLink, error) {
+			return nil, repository.ErrCoachLinkExists
+		},
+	}
+	svc := NewCoachLinkService(linkRepo, userRepo)
+
+	_, err := svc.Invite("user-1", models.InviteCoachLinkRequest{Username: "me", Role: "parent"})
+	assert.ErrorIs(t, err, ErrInvalidLinkRole)
+
+	_, err = svc.Invite("user-1", models.InviteCoachLinkRequest{Role: models.CoachLinkRoleCoach})
+	assert.ErrorIs(t, err, ErrInviteeRequired)
+
+	_, err = svc.Invite("user-1", models.InviteCoachLinkRequest{Username: "ghost", Role: models.CoachLinkRoleCoach})
+	assert.ErrorIs(t, err, ErrInviteeNotFound)
+
+	_, err = svc.Invite("user-1", models.InviteCoachLinkRequest{Username: "me", Role: models.CoachLinkRoleCoach})
+	assert.ErrorIs(t, err, ErrCannotLinkSelf)
+
+	userRepo.GetByUsernameFunc = func(username string) (*models.User, error) { return &models.User{ID: "user-2"}, nil }
+	_, err = svc.Invite("user-1", models.InviteCoachLinkRequest{Username: "coachy", Role: models.CoachLinkRoleCoach})
+	assert.ErrorIs(t, err, ErrCoachLinkExists)
+}
+
+func TestCoachLinkAccept(t *testing.T) {
+	pending := &models.CoachLink{ID: "link-1", CoachID: "coach", StudentID: "student", InvitedBy: "coach", Status: models.CoachLinkPending}
+	accepted := false
+	linkRepo := &mocks.MockCoachLinkRepo{
+		GetByIDFunc: func(id string) (*models.CoachLink, error) { return pending, nil },
+		AcceptFunc: func(id string, at time.Time) (*models.CoachLink, error) {
+			accepted = true
+			return &models.CoachLink{ID: id, Status: models.CoachLinkActive}, nil
+		},
+	}
+	svc := NewCoachLinkService(linkRepo, &mocks.MockUserRepo{})
+
+	_, err := svc.Accept("link-1", "coach")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = svc.Accept("link-1", "stranger")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	link, err := svc.Accept("link-1", "student")
+	require.NoError(t, err)
+	assert.True(t, accepted)
+	assert.Equal(t, models.CoachLinkActive, link.Status)
+}
+
+func TestCoachLinkRevoke_EitherSide(t *testing.T) {
+	deleted := 0
+	linkRepo := &mocks.MockCoachLinkRepo{
+		GetByIDFunc: func(id string) (*models.CoachLink, error) {
+			return &models.CoachLink{ID: id, CoachID: "coach", StudentID: "student", Status: models.CoachLinkActive}, nil
+		},
+		DeleteFunc: func(id string) error {
+			deleted++
+			return nil
+		},
+	}
+	svc := NewCoachLinkService(linkRepo, &mocks.MockUserRepo{})
+
+	require.NoError(t, svc.Revoke("link-1", "coach"))
+	require.NoError(t, svc.Revoke("link-1", "student"))
+	assert.ErrorIs(t, svc.Revoke("link-1", "stranger"), ErrNotFound)
+	assert.Equal(t, 2, deleted)
+}
+
+func TestCheckCoachAccess(t *testing.T) {
+	svc := NewCoachLinkService(&mocks.MockCoachLinkRepo{
+		IsActiveFunc: func(coachID, studentID string) (bool, error) {
+			return coachID == "coach" && studentID == "student", nil
+		},
+	}, &mocks.MockUserRepo{})
+
+	assert.NoError(t, svc.CheckCoachAccess("coach", "student"))
+	assert.ErrorIs(t, svc.CheckCoachAccess("student", "coach"), ErrNotFound)
+}
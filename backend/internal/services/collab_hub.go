@@ -0,0 +1,82 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// collabBufferSize is how many events a subscriber may lag behind before events are dropped.
+// Clients notice the version gap and reload the repertoire.
+const collabBufferSize = 32
+
+// CollabSubscription receives the events of one repertoire
+type CollabSubscription struct {
+	RepertoireID string
+	Events       chan models.RepertoireEvent
+}
+
+// CollabHub fans repertoire edit events out to the clients watching each repertoire
+type CollabHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*CollabSubscription]struct{}
+}
+
+// NewCollabHub creates an empty collaboration hub
+func NewCollabHub() *CollabHub {
+	return &CollabHub{subscribers: make(map[string]map[*CollabSubscription]struct{})}
+}
+
+// Subscribe starts receiving events for a repertoire
+func (h *CollabHub) Subscribe(repertoireID string) *CollabSubscription {
+	sub := &CollabSubscription{
+		RepertoireID: repertoireID,
+		Events:       make(chan models.RepertoireEvent, collabBufferSize),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[repertoireID] == nil {
+		h.subscribers[repertoireID] = make(map[*CollabSubscription]struct{})
+	}
+	h.subscribers[repertoireID][sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops a subscription and closes its channel
+func (h *CollabHub) Unsubscribe(sub *CollabSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs, ok := h.subscribers[sub.RepertoireID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.RepertoireID)
+	}
+	close(sub.Events)
+}
+
+// Publish delivers an event to every subscriber of its repertoire without blocking;
+// subscribers whose buffer is full miss the event
+func (h *CollabHub) Publish(event models.RepertoireEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers[event.RepertoireID] {
+		select {
+		case sub.Events <- event:
+		default:
+		}
+	}
+}
+
+// Watchers returns the number of clients watching a repertoire
+func (h *CollabHub) Watchers(repertoireID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[repertoireID])
+}
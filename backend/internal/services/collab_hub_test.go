@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestCollabHub_PublishReachesRepertoireSubscribers(t *testing.T) {
+	hub := NewCollabHub()
+	watcher := hub.Subscribe("rep-1")
+	other := hub.Subscribe("rep-2")
+
+	hub.Publish(models.RepertoireEvent{Type: models.RepertoireEventNodeDeleted, RepertoireID: "rep-1", Version: 3})
+
+	event := <-watcher.Events
+	assert.Equal(t, 3, event.Version)
+	assert.Empty(t, other.Events)
+}
+
+func TestCollabHub_SlowSubscriberMissesEvents(t *testing.T) {
+	hub := NewCollabHub()
+	sub := hub.Subscribe("rep-1")
+
+	for i := 0; i < collabBufferSize+5; i++ {
+		hub.Publish(models.RepertoireEvent{RepertoireID: "rep-1", Version: i})
+	}
+
+	assert.Len(t, sub.Events, collabBufferSize)
+}
+
+func TestCollabHub_Unsubscribe(t *testing.T) {
+	hub := NewCollabHub()
+	sub := hub.Subscribe("rep-1")
+	require.Equal(t, 1, hub.Watchers("rep-1"))
+
+	hub.Unsubscribe(sub)
+	hub.Unsubscribe(sub)
+
+	_, open := <-sub.Events
+	assert.False(t, open)
+	assert.Zero(t, hub.Watchers("rep-1"))
+	hub.Publish(models.RepertoireEvent{RepertoireID: "rep-1"})
+}
+
+func TestRepertoireService_AddNodePublishesEvent(t *testing.T) {
+	rep := &models.Repertoire{
+		ID:    "rep-1",
+		Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID:          "root",
+			FEN:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			ColorToMove: models.ChessColorWhite,
+		},
+	}
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: treeData, Version: 7}, nil
+		},
+	})
+	hub := NewCollabHub()
+	svc.WithCollabHub(hub)
+	sub := hub.Subscribe("rep-1")
+
+	_, err := svc.AddNode("rep-1", models.AddNodeRequest{ParentID: "root", Move: "e4", MoveNumber: 1})
+	require.NoError(t, err)
+
+	event := <-sub.Events
+	assert.Equal(t, models.RepertoireEventNodeAdded, event.Type)
+	assert.Equal(t, 7, event.Version)
+	assert.Equal(t, "root", *event.ParentID)
+	require.NotNil(t, event.Node)
+	assert.Equal(t, "e4", *event.Node.Move)
+}
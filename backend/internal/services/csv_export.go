@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+var gamesCSVHeader = []string{
+	"date", "white", "black", "user_color", "opponent", "opening", "repertoire", "time_class",
+	"source", "result", "outcome", "status", "out_of_book_ply", "max_winrate_drop",
+}
+
+var mistakesCSVHeader = []string{
+	"date", "white", "black", "user_color", "opponent", "opening", "result", "ply",
+	"fen", "played_move", "best_move", "winrate_drop", "frequency",
+}
+
+// ExportGamesCSV exports the user's games matching the same filters as the games list as CSV,
+// newest first and capped at config.MaxExportGames. The out-of-book ply is that of the first move
+// outside the repertoire, and the winrate drop the largest one of the user's evaluated moves.
+func (s *ImportService) ExportGamesCSV(userID, timeClass, repertoire, source, classification string, starred bool) ([]byte, int, error) {
+	list, err := s.analysisRepo.GetAllGames(userID, config.MaxExportGames, 0, timeClass, repertoire, source, classification, starred)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	drops, err := s.maxWinrateDrops(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows := [][]string{gamesCSVHeader}
+	for _, summary := range list.Games {
+		game, err := s.analysisRepo.GetGame(summary.AnalysisID, summary.GameIndex)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		outOfBook := ""
+		for _, move := range game.Moves {
+			if move.Status != "in-repertoire" {
+				outOfBook = strconv.Itoa(move.PlyNumber)
+				break
+			}
+		}
+		drop := ""
+		if d, ok := drops[gameKey(summary.AnalysisID, summary.GameIndex)]; ok {
+			drop = formatCSVFloat(d)
+		}
+
+		rows = append(rows, []string{
+			summary.Date, summary.White, summary.Black, string(summary.UserColor),
+			csvOpponent(summary.White, summary.Black, summary.UserColor), summary.Opening,
+			summary.RepertoireName, summary.TimeClass, summary.Source, summary.Result,
+			gameOutcome(summary.Result, summary.UserColor), summary.Status, outOfBook, drop,
+		})
+	}
+
+	data, err := writeCSV(rows)
+	return data, len(rows) - 1, err
+}
+
+// ExportMistakesCSV exports the insight mistakes matching the filter as CSV, one row per game
+// in which a mistake was played
+func (s *ImportService) ExportMistakesCSV(userID string, filter models.InsightsFilter) ([]byte, error) {
+	insights, err := s.GetInsights(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := [][]string{mistakesCSVHeader}
+	for _, mistake := range insights.WorstMistakes {
+		for _, game := range mistake.Games {
+			rows = append(rows, []string{
+				game.Date, game.White, game.Black, string(game.UserColor),
+				csvOpponent(game.White, game.Black, game.UserColor), game.Opening, game.Result,
+				strconv.Itoa(game.PlyNumber), mistake.FEN, mistake.PlayedMove, mistake.BestMove,
+				formatCSVFloat(mistake.WinrateDrop), strconv.Itoa(mistake.Frequency),
+			})
+		}
+	}
+	return writeCSV(rows)
+}
+
+// maxWinrateDrops returns the largest winrate drop of each evaluated game of the user
+func (s *ImportService) maxWinrateDrops(userID string) (map[string]float64, error) {
+	drops := make(map[string]float64)
+	if s.engineService == nil {
+		return drops, nil
+	}
+
+	data, err := s.engineService.GetInsightsData(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, eval := range data.Evals {
+		if eval.Status != "done" {
+			continue
+		}
+		key := gameKey(eval.AnalysisID, eval.GameIndex)
+		for _, stat := range eval.Evals {
+			if current, ok := drops[key]; !ok || stat.WinrateDrop > current {
+				drops[key] = stat.WinrateDrop
+			}
+		}
+	}
+	return drops, nil
+}
+
+func gameKey(analysisID string, gameIndex int) string {
+	return analysisID + "/" + strconv.Itoa(gameIndex)
+}
+
+func csvOpponent(white, black string, userColor models.Color) string {
+	if userColor == models.ColorBlack {
+		return white
+	}
+	return black
+}
+
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+// writeCSV encodes rows as CSV. Cells that a spreadsheet would read as a formula are prefixed
+// with a quote, since player names and openings come from imported PGN.
+func writeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, row := range rows {
+		for i, cell := range row {
+			if isCSVFormula(cell) {
+				row[i] = "'" + cell
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func isCSVFormula(cell string) bool {
+	if cell == "" || !strings.ContainsRune("=+-@", rune(cell[0])) {
+		return false
+	}
+	_, err := strconv.ParseFloat(cell, 64)
+	return err != nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestExportGamesCSV(t *testing.T) {
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
+			assert.Equal(t, "lichess", source)
+			return &models.GamesResponse{Games: []models.GameSummary{{
+				AnalysisID: "a-1", White: "me", Black: "=HYPERLINK(\"x\")", Result: "1-0", Date: "2024.05.01",
+				UserColor: models.ColorWhite, Status: "error", TimeClass: "blitz", Opening: "Italian Game",
+				RepertoireName: "Italian", Source: "lichess",
+			}}}, nil
+		},
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return exportTestGame(), nil
+		},
+	}
+	svc := NewImportService(nil, analysisRepo)
+
+	data, count, err := svc.ExportGamesCSV("user-1", "", "", "lichess", "", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, gamesCSVHeader, rows[0])
+	assert.Equal(t, []string{
+		"2024.05.01", "me", `'=HYPERLINK("x")`, "white", `'=HYPERLINK("x")`, "Italian Game", "Italian", "blitz",
+		"lichess", "1-0", "win", "error", "2", "",
+	}, rows[1])
+}
+
+func TestExportMistakesCSV_WithoutEngine(t *testing.T) {
+	svc := NewImportService(nil, &mocks.MockAnalysisRepo{})
+
+	data, err := svc.ExportMistakesCSV("user-1", DefaultInsightsFilter())
+
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join(mistakesCSVHeader, ",")+"\n", string(data))
+}
+
+func TestIsCSVFormula(t *testing.T) {
+	assert.True(t, isCSVFormula("=1+1"))
+	assert.True(t, isCSVFormula("@SUM(A1)"))
+	assert.False(t, isCSVFormula("-0.5000"))
+	assert.False(t, isCSVFormula("Magnus"))
+	assert.False(t, isCSVFormula(""))
+}
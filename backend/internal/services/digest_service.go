@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+const (
+	digestInterval      = 7 * 24 * time.Hour
+	digestCheckInterval = time.Hour
+	digestMistakes      = 3
+	digestBranches      = 3
+	digestMinGames      = 3 // Branches reached by fewer games say little about the user's results
+	digestLocalHour     = 8 // Digests wait for the morning in the user's time zone
+)
+
+// DigestService composes and sends the weekly email digest to users who opted in
+type DigestService struct {
+	userRepo          repository.UserRepository
+	analysisRepo      repository.AnalysisRepository
+	importService     *ImportService
+	repertoireService *RepertoireService
+	emailService      EmailSender
+	notifications     *NotificationService
+}
+
+// NewDigestService creates a new digest service
+func NewDigestService(userRepo repository.UserRepository, analysisRepo repository.AnalysisRepository, importService *ImportService, repertoireService *RepertoireService, emailService EmailSender) *DigestService {
+	return &DigestService{
+		userRepo:          userRepo,
+		analysisRepo:      analysisRepo,
+		importService:     importService,
+		repertoireService: repertoireService,
+		emailService:      emailService,
+	}
+}
+
+// RunWorker periodically sends the digest to users whose last one is a week old, once it is
+// morning in their time zone
+func (s *DigestService) RunWorker(ctx context.Context) {
+	log.Println("digest: worker started")
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("digest: worker stopped")
+			return
+		case <-ticker.C:
+			s.sendDue(time.Now())
+		}
+	}
+}
+
+// WithNotifications sends digests on the channels each user chose instead of by email only
+func (s *DigestService) WithNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+func (s *DigestService) sendDue(now time.Time) {
+	if s.notifications == nil && !s.emailService.Enabled() {
+		return
+	}
+
+	since := now.Add(-digestInterval)
+	users, err := s.userRepo.ListDigestRecipients(since)
+	if err != nil {
+		log.Printf("digest: failed to list recipients: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if now.In(user.Location()).Hour() < digestLocalHour {
+			continue
+		}
+		digest, err := s.Compose(user.ID, since)
+		if err != nil {
+			log.Printf("digest: failed to compose digest for user %s: %v", user.ID, err)
+			continue
+		}
+		digest.Timezone, digest.Locale = user.Timezone, user.Locale
+		// A quiet week is marked as sent too, so it is not recomposed every hour
+		if !digestEmpty(digest) {
+			if err := s.send(user, *digest); err != nil {
+				log.Printf("digest: failed to send digest to user %s: %v", user.ID, err)
+				continue
+			}
+		}
+		if err := s.userRepo.MarkDigestSent(user.ID, now); err != nil {
+			log.Printf("digest: failed to mark digest sent for user %s: %v", user.ID, err)
+		}
+	}
+}
+
+func (s *DigestService) send(user models.User, digest models.WeeklyDigest) error {
+	if s.notifications != nil {
+		return s.notifications.Notify(user.ID, digestNotification(digest))
+	}
+	return s.emailService.SendWeeklyDigestEmail(*user.Email, digest)
+}
+
+// Compose summarizes a user's activity since the given time
+func (s *DigestService) Compose(userID string, since time.Time) (*models.WeeklyDigest, error) {
+	digest := &models.WeeklyDigest{Since: since}
+
+	analyses, err := s.analysisRepo.GetAllGamesRaw(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analyses: %w", err)
+	}
+	viewed, err := s.analysisRepo.GetViewedGames(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get viewed games: %w", err)
+	}
+	for _, analysis := range analyses {
+		if analysis.UploadedAt.Before(since) {
+			continue
+		}
+		digest.GamesImported += len(analysis.Results)
+		for _, game := range analysis.Results {
+			if !viewed[fmt.Sprintf("%s-%d", analysis.ID, game.GameIndex)] {
+				digest.UnreviewedGames++
+			}
+		}
+	}
+
+	filter, err := s.importService.InsightsFilterFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = digestMistakes
+	filter.Since = since
+	insights, err := s.importService.GetInsights(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	digest.NewMistakes = insights.WorstMistakes
+
+	repertoires, err := s.repertoireService.ListRepertoires(userID, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repertoires: %w", err)
+	}
+	for _, rep := range repertoires {
+		positions, err := s.repertoireService.TrainingPositions(rep.ID, nil, config.MaxTrainingPositions)
+		if err != nil {
+			return nil, err
+		}
+		digest.TrainingDue += len(positions)
+
+		overlay, err := s.importService.ResultsOverlay(userID, rep.ID)
+		if err != nil {
+			return nil, err
+		}
+		digest.WorstBranches = append(digest.WorstBranches, branchResults(rep, overlay.Nodes)...)
+	}
+
+	sort.SliceStable(digest.WorstBranches, func(i, j int) bool {
+		a, b := digest.WorstBranches[i], digest.WorstBranches[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.Games > b.Games
+	})
+	if len(digest.WorstBranches) > digestBranches {
+		digest.WorstBranches = digest.WorstBranches[:digestBranches]
+	}
+	return digest, nil
+}
+
+// branchResults turns the overlay of a repertoire into results by line, leaving out the root
+// and positions reached by too few games
+func branchResults(rep models.Repertoire, nodes []models.NodeResult) []models.BranchResult {
+	paths := make(map[string][]string)
+	var walk func(node *models.RepertoireNode, moves []string)
+	walk = func(node *models.RepertoireNode, moves []string) {
+		if node.Move != nil {
+			moves = append(moves, *node.Move)
+			paths[node.ID] = append([]string{}, moves...)
+		}
+		for _, child := range node.Children {
+			walk(child, moves)
+		}
+	}
+	walk(&rep.TreeData, nil)
+
+	var results []models.BranchResult
+	for _, node := range nodes {
+		moves, ok := paths[node.NodeID]
+		if !ok || node.Games < digestMinGames {
+			continue
+		}
+		results = append(results, models.BranchResult{
+			RepertoireName: rep.Name,
+			Moves:          moves,
+			Games:          node.Games,
+			Score:          node.Score,
+		})
+	}
+	return results
+}
+
+// digestEmpty reports a week without new games: branches and training alone are not worth an email
+func digestEmpty(digest *models.WeeklyDigest) bool {
+	return digest.GamesImported == 0 && len(digest.NewMistakes) == 0
+}
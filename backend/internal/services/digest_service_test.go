@@ -0,0 +1,207 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func newTestDigestService(userRepo *mocks.MockUserRepo, emailSvc *mocks.MockEmailService, uploadedAt time.Time) *DigestService {
+	rep := newTaggedRepertoire()
+	repertoireSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetAllFunc:        func(userID string) ([]models.Repertoire, error) { return []models.Repertoire{*rep}, nil },
+		GetByIDFunc:       func(id string) (*models.Repertoire, error) { return rep, nil },
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+	})
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) {
+			return []models.RawAnalysis{
+				{ID: "old", UploadedAt: uploadedAt.Add(-30 * 24 * time.Hour), Results: []models.GameAnalysis{{GameIndex: 0}}},
+				{ID: "new", UploadedAt: uploadedAt, Results: []models.GameAnalysis{{GameIndex: 0}, {GameIndex: 1}, {GameIndex: 2}}},
+			}, nil
+		},
+		GetViewedGamesFunc: func(userID string) (map[string]bool, error) {
+			return map[string]bool{"new-1": true, "old-0": true}, nil
+		},
+	}
+	gameResultRepo := &mocks.MockGameResultRepo{
+		OverlayFunc: func(repertoireID, userID string) ([]models.NodeResult, error) {
+			return []models.NodeResult{
+				{NodeID: "root", Games: 10, Wins: 5, Losses: 5},
+				{NodeID: "e4", Games: 10, Wins: 5, Losses: 5},
+				{NodeID: "c5", Games: 4, Wins: 1, Losses: 3},
+				{NodeID: "d4", Games: 2, Losses: 2},
+			}, nil
+		},
+	}
+	importSvc := NewImportService(repertoireSvc, analysisRepo, WithGameResultRepo(gameResultRepo))
+	return NewDigestService(userRepo, analysisRepo, importSvc, repertoireSvc, emailSvc)
+}
+
+func TestDigestService_Compose(t *testing.T) {
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	svc := newTestDigestService(&mocks.MockUserRepo{}, &mocks.MockEmailService{}, now.Add(-24*time.Hour))
+
+	digest, err := svc.Compose("user-1", now.Add(-digestInterval))
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, digest.GamesImported)
+	assert.Equal(t, 2, digest.UnreviewedGames)
+	assert.Equal(t, 4, digest.TrainingDue)
+	// The root and branches reached by too few games are left out; the worst score comes first
+	require.Len(t, digest.WorstBranches, 2)
+	assert.Equal(t, []string{"e4", "c5"}, digest.WorstBranches[0].Moves)
+	assert.Equal(t, 0.25, digest.WorstBranches[0].Score)
+	assert.Equal(t, []string{"e4"}, digest.WorstBranches[1].Moves)
+}
+
+func TestDigestService_SendDue(t *testing.T) {
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	email := "me@example.com"
+	var sentBefore time.Time
+	marked := map[string]time.Time{}
+	userRepo := &mocks.MockUserRepo{
+		ListDigestRecipientsFunc: func(before time.Time) ([]models.User, error) {
+			sentBefore = before
+			return []models.User{{ID: "user-1", Email: &email}}, nil
+		},
+		MarkDigestSentFunc: func(userID string, sentAt time.Time) error {
+			marked[userID] = sentAt
+			return nil
+		},
+	}
+	var sentTo string
+	var sent models.WeeklyDigest
+	emailSvc := &mocks.MockEmailService{
+		SendWeeklyDigestEmailFunc: func(toEmail string, digest models.WeeklyDigest) error {
+			sentTo, sent = toEmail, digest
+			return nil
+		},
+	}
+
+	newTestDigestService(userRepo, emailSvc, now.Add(-24*time.Hour)).sendDue(now)
+
+	assert.Equal(t, now.Add(-digestInterval), sentBefore)
+	assert.Equal(t, email, sentTo)
+	assert.Equal(t, 3, sent.GamesImported)
+	assert.Equal(t, now, marked["user-1"])
+}
+
+func TestDigestService_SendDue_QuietWeek(t *testing.T) {
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	email := "me@example.com"
+	marked := false
+	userRepo := &mocks.MockUserRepo{
+		ListDigestRecipientsFunc: func(before time.Time) ([]models.User, error) {
+			return []models.User{{ID: "user-1", Email: &email}}, nil
+		},
+		MarkDigestSentFunc: func(userID string, sentAt time.Time) error {
+			marked = true
+			return nil
+		},
+	}
+	emailSvc := &mocks.MockEmailService{
+		SendWeeklyDigestEmailFunc: func(toEmail string, digest models.WeeklyDigest) error {
+			t.Fatal("no digest expected for a week without games")
+			return nil
+		},
+	}
+
+	newTestDigestService(userRepo, emailSvc, now.Add(-30*24*time.Hour)).sendDue(now)
+
+	assert.True(t, marked)
+}
+
+func TestDigestService_SendDue_WaitsForLocalMorning(t *testing.T) {
+	// 12:00 UTC is 5:00 in Los Angeles and 21:00 in Tokyo
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	email := "me@example.com"
+	marked := map[string]bool{}
+	userRepo := &mocks.MockUserRepo{
+		ListDigestRecipientsFunc: func(before time.Time) ([]models.User, error) {
+			return []models.User{
+				{ID: "west", Email: &email, Timezone: "America/Los_Angeles"},
+				{ID: "east", Email: &email, Timezone: "Asia/Tokyo", Locale: "ja"},
+			}, nil
+		},
+		MarkDigestSentFunc: func(userID string, sentAt time.Time) error {
+			marked[userID] = true
+			return nil
+		},
+	}
+	var sent models.WeeklyDigest
+	emailSvc := &mocks.MockEmailService{
+		SendWeeklyDigestEmailFunc: func(toEmail string, digest models.WeeklyDigest) error {
+			sent = digest
+			return nil
+		},
+	}
+
+	newTestDigestService(userRepo, emailSvc, now.Add(-24*time.Hour)).sendDue(now)
+
+	assert.Equal(t, map[string]bool{"east": true}, marked)
+	assert.Equal(t, "Asia/Tokyo", sent.Timezone)
+	assert.Equal(t, "ja", sent.Locale)
+}
+
+func TestFormatLocalDate(t *testing.T) {
+	// Late on June 1st in UTC is already June 2nd in Tokyo
+	at := time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "06/01/2024", formatLocalDate(at, "", "en-US"))
+	assert.Equal(t, "2024/06/02", formatLocalDate(at, "Asia/Tokyo", "ja"))
+	assert.Equal(t, "02.06.2024", formatLocalDate(at, "Europe/Berlin", "de-AT"))
+	assert.Equal(t, "01/06/2024", formatLocalDate(at, "Europe/London", "fr"))
+}
+
+func TestWeeklyDigestBody(t *testing.T) {
+	body := weeklyDigestBody(models.WeeklyDigest{
+		GamesImported:   5,
+		UnreviewedGames: 2,
+		TrainingDue:     12,
+		NewMistakes:     []models.OpeningMistake{{PlayedMove: "Nc3", BestMove: "Nf3", Frequency: 2, WinrateDrop: 0.08}},
+	}, "https://treechess.example")
+
+	assert.Contains(t, body, "Games imported: 5 (2 not reviewed yet)")
+	assert.Contains(t, body, "Positions to train: 12")
+	assert.Contains(t, body, "- played Nc3 instead of Nf3 (2 games, -8% winrate)")
+	assert.NotContains(t, body, "worst results")
+	assert.Contains(t, body, "https://treechess.example")
+}
+
+func TestDigestService_SendDue_Notifications(t *testing.T) {
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	email := "me@example.com"
+	userRepo := &mocks.MockUserRepo{
+		ListDigestRecipientsFunc: func(before time.Time) ([]models.User, error) {
+			return []models.User{{ID: "user-1", Email: &email}}, nil
+		},
+		GetByIDFunc: func(id string) (*models.User, error) {
+			return &models.User{ID: id, Email: &email}, nil
+		},
+	}
+	emailSvc := &mocks.MockEmailService{
+		SendWeeklyDigestEmailFunc: func(toEmail string, digest models.WeeklyDigest) error {
+			t.Fatal("the user turned digest emails off")
+			return nil
+		},
+	}
+	push := &fakeNotifier{channel: models.NotificationChannelPush}
+	notifications := newNotificationTestService(&mocks.MockNotificationRepo{
+		GetPreferencesFunc: func(userID string) ([]models.NotificationPreference, error) {
+			return []models.NotificationPreference{{Event: models.NotificationWeeklyDigest, Push: true}}, nil
+		},
+	}, NewEmailNotifier(emailSvc), push)
+	svc := newTestDigestService(userRepo, emailSvc, now.Add(-24*time.Hour))
+	svc.WithNotifications(notifications)
+
+	svc.sendDue(now)
+
+	require.Len(t, push.sent, 1)
+	assert.Equal(t, "3 games imported, 0 new mistakes and 4 positions to train this week.", push.sent[0].Body)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+//go:embed eco/openings.tsv
+var ecoTable string
+
+var (
+	ecoOnce      sync.Once
+	ecoPositions map[string]models.OpeningLabel
+)
+
+// resolveOpening names the opening of a position from the ECO table, nil when it has no entry.
+// Positions are compared on board, side to move and castling rights, so a FEN with or without
+// an en passant square or move counters resolves the same way.
+func resolveOpening(fen string) *models.OpeningLabel {
+	ecoOnce.Do(func() { ecoPositions = loadECOTable(ecoTable) })
+
+	label, ok := ecoPositions[ecoPositionKey(fen)]
+	if !ok {
+		return nil
+	}
+	return &label
+}
+
+func ecoPositionKey(fen string) string {
+	parts := strings.Fields(fen)
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, " ")
+}
+
+// loadECOTable replays the moves of every entry of the table. The table is embedded, so an
+// entry that does not replay is a programming error.
+func loadECOTable(table string) map[string]models.OpeningLabel {
+	positions := make(map[string]models.OpeningLabel)
+	scanner := bufio.NewScanner(strings.NewReader(table))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			panic(fmt.Sprintf("eco: malformed entry %q", line))
+		}
+
+		game := chess.NewGame(chess.UseNotation(chess.AlgebraicNotation{}))
+		for _, san := range strings.Fields(fields[2]) {
+			if err := game.MoveStr(san); err != nil {
+				panic(fmt.Sprintf("eco: invalid move %s in %q: %v", san, fields[1], err))
+			}
+		}
+
+		key := ecoPositionKey(game.Position().String())
+		if _, exists := positions[key]; !exists {
+			positions[key] = models.OpeningLabel{ECO: fields[0], Name: fields[1]}
+		}
+	}
+	return positions
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"net/smtp"
+	"strings"
 
 	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
 )
 
 // EmailService handles sending emails
@@ -22,6 +24,9 @@ type EmailService struct {
 // EmailSender is the interface for email sending
 type EmailSender interface {
 	SendPasswordResetEmail(toEmail, token string) error
+	SendGoalSummaryEmail(toEmail string, goals []models.Goal) error
+	SendWeeklyDigestEmail(toEmail string, digest models.WeeklyDigest) error
+	SendNotificationEmail(toEmail string, n models.Notification) error
 	Enabled() bool
 }
 
@@ -68,6 +73,116 @@ If you did not request this password reset, you can safely ignore this email.
 
 - The TreeChess Team`, resetURL)
 
+	if err := s.send(toEmail, subject, body); err != nil {
+		return err
+	}
+
+	log.Printf("[EMAIL] Password reset email sent to %s", toEmail)
+	return nil
+}
+
+// SendGoalSummaryEmail sends the weekly progress summary of a user's repertoire goals
+func (s *EmailService) SendGoalSummaryEmail(toEmail string, goals []models.Goal) error {
+	var lines []string
+	for _, g := range goals {
+		status := "in progress"
+		if g.Achieved {
+			status = "achieved"
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s %.0f / %.0f (%s)", g.RepertoireName, goalLabel(g), g.Progress, g.Target, status))
+	}
+
+	if !s.enabled {
+		log.Printf("[EMAIL] SMTP not configured. Goal summary for %s:\n%s", toEmail, strings.Join(lines, "\n"))
+		return nil
+	}
+
+	subject := "Your weekly TreeChess goals"
+	body := fmt.Sprintf(`Hello,
+
+Here is where your repertoire goals stand this week:
+
+%s
+
+Open %s to keep working on them.
+
+- The TreeChess Team`, strings.Join(lines, "\n"), s.frontendURL)
+
+	if err := s.send(toEmail, subject, body); err != nil {
+		return err
+	}
+
+	log.Printf("[EMAIL] Goal summary email sent to %s", toEmail)
+	return nil
+}
+
+// SendWeeklyDigestEmail sends a user the summary of their week: games, new mistakes, weak branches and training
+func (s *EmailService) SendWeeklyDigestEmail(toEmail string, digest models.WeeklyDigest) error {
+	body := weeklyDigestBody(digest, s.frontendURL)
+	if !s.enabled {
+		log.Printf("[EMAIL] SMTP not configured. Weekly digest for %s:\n%s", toEmail, body)
+		return nil
+	}
+
+	if err := s.send(toEmail, "Your week on TreeChess", body); err != nil {
+		return err
+	}
+
+	log.Printf("[EMAIL] Weekly digest email sent to %s", toEmail)
+	return nil
+}
+
+// SendNotificationEmail sends a notification of an event, such as a finished import
+func (s *EmailService) SendNotificationEmail(toEmail string, n models.Notification) error {
+	body := fmt.Sprintf(`Hello,
+
+%s
+
+Open %s%s to see it.
+
+You can choose which notifications you receive in your profile settings.
+
+- The TreeChess Team`, n.Body, s.frontendURL, n.URL)
+
+	if !s.enabled {
+		log.Printf("[EMAIL] SMTP not configured. Notification for %s: %s", toEmail, n.Title)
+		return nil
+	}
+
+	if err := s.send(toEmail, n.Title, body); err != nil {
+		return err
+	}
+
+	log.Printf("[EMAIL] %s notification sent to %s", n.Event, toEmail)
+	return nil
+}
+
+// weeklyDigestBody writes the plain-text digest, leaving out empty sections
+func weeklyDigestBody(digest models.WeeklyDigest, frontendURL string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello,\n\nHere is your week on TreeChess, since %s.\n\n", formatLocalDate(digest.Since, digest.Timezone, digest.Locale))
+	fmt.Fprintf(&b, "Games imported: %d (%d not reviewed yet)\n", digest.GamesImported, digest.UnreviewedGames)
+	fmt.Fprintf(&b, "Positions to train: %d\n", digest.TrainingDue)
+
+	if len(digest.NewMistakes) > 0 {
+		b.WriteString("\nYour costliest new mistakes:\n")
+		for _, m := range digest.NewMistakes {
+			fmt.Fprintf(&b, "- played %s instead of %s (%d games, -%.0f%% winrate)\n", m.PlayedMove, m.BestMove, m.Frequency, m.WinrateDrop*100)
+		}
+	}
+	if len(digest.WorstBranches) > 0 {
+		b.WriteString("\nBranches with your worst results:\n")
+		for _, br := range digest.WorstBranches {
+			fmt.Fprintf(&b, "- %s: %s (%d games, %.0f%% score)\n", br.RepertoireName, strings.Join(br.Moves, " "), br.Games, br.Score*100)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nOpen %s to review them.\n\nYou can turn this email off in your profile settings.\n\n- The TreeChess Team", frontendURL)
+	return b.String()
+}
+
+// send delivers a plain-text email through the configured SMTP server
+func (s *EmailService) send(toEmail, subject, body string) error {
 	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
 		s.fromAddress, toEmail, subject, body)
 
@@ -82,7 +197,17 @@ If you did not request this password reset, you can safely ignore this email.
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-
-	log.Printf("[EMAIL] Password reset email sent to %s", toEmail)
 	return nil
 }
+
+// goalLabel describes a goal in human terms for summary emails
+func goalLabel(g models.Goal) string {
+	switch g.Type {
+	case models.GoalTypeExplorerCoverage:
+		return fmt.Sprintf("explorer coverage to depth %d (%%)", g.Depth)
+	case models.GoalTypeWeeklyReviews:
+		return "training reviews this week"
+	default:
+		return string(g.Type)
+	}
+}
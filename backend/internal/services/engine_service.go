@@ -3,13 +3,18 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 )
@@ -22,8 +27,24 @@ const (
 	explorerRatings  = "1600,1800,2000,2200,2500"
 	minExplorerGames = 50  // minimum games for reliable stats
 	apiDelay         = 200 * time.Millisecond
+	mastersBaseURL   = "https://explorer.lichess.ovh/masters"
+	maxModelGames    = 10
+	lichessGameURL   = "https://lichess.org/"
+
+	maxCoveragePositions = 60 // cap explorer lookups per coverage computation
+
+	evalRetentionInterval = 6 * time.Hour
+
+	// Evals are leased to the worker processing them, which renews the lease while it works.
+	// A crashed worker's evals are claimed again once their lease expires.
+	evalClaimBatch    = 5
+	evalLeaseDuration = 2 * time.Minute
+	evalLeaseRenewal  = evalLeaseDuration / 3
 )
 
+// ErrInvalidFEN is returned when a position lookup receives an unparseable FEN
+var ErrInvalidFEN = errors.New("invalid FEN")
+
 // explorerResponse represents the Lichess Explorer API response
 type explorerResponse struct {
 	White int             `json:"white"`
@@ -32,6 +53,24 @@ type explorerResponse struct {
 	Moves []explorerMove  `json:"moves"`
 }
 
+// mastersResponse represents the subset of the Lichess Explorer masters response we use
+type mastersResponse struct {
+	TopGames []mastersGame `json:"topGames"`
+}
+
+type mastersGame struct {
+	ID     string        `json:"id"`
+	Winner string        `json:"winner"` // "white", "black" or empty for a draw
+	White  mastersPlayer `json:"white"`
+	Black  mastersPlayer `json:"black"`
+	Year   int           `json:"year"`
+}
+
+type mastersPlayer struct {
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+}
+
 type explorerMove struct {
 	UCI           string `json:"uci"`
 	SAN           string `json:"san"`
@@ -44,22 +83,70 @@ type explorerMove struct {
 // EngineService manages async opening analysis using the Lichess Explorer API
 type EngineService struct {
 	evalRepo     repository.EngineEvalRepository
+	workerID     string // Identifies this process in eval leases
 	analysisRepo repository.AnalysisRepository
 	httpClient   *http.Client
-	cache        map[string]*explorerResponse
+	cache        *boundedCache[*explorerResponse]
+	modelGames   *boundedCache[[]models.ModelGame]
 	cacheMu      sync.Mutex
+	retention    time.Duration
+	tablebase    *TablebaseService
+	usage        *UsageService
+
+	classificationRuleRepo repository.ClassificationRuleRepository
+
+	notifications *NotificationService
+
+	explorerQueue chan explorerLookup
+	lookupMu      sync.Mutex
+	queued        map[string]bool
+	budgets       map[string]*explorerBudget
 }
 
 // NewEngineService creates a new engine service
 func NewEngineService(evalRepo repository.EngineEvalRepository, analysisRepo repository.AnalysisRepository) *EngineService {
 	return &EngineService{
 		evalRepo:     evalRepo,
+		workerID:     newWorkerID(),
 		analysisRepo: analysisRepo,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		cache: make(map[string]*explorerResponse),
+		httpClient: newResilientHTTPClient(30 * time.Second),
+		cache:      newBoundedCache[*explorerResponse](config.ExplorerCacheTTL, config.ExplorerCacheSize),
+		modelGames: newBoundedCache[[]models.ModelGame](config.ExplorerCacheTTL, config.ExplorerCacheSize),
+
+		explorerQueue: make(chan explorerLookup, config.ExplorerQueueSize),
+		queued:        make(map[string]bool),
+		budgets:       make(map[string]*explorerBudget),
+	}
+}
+
+// WithRetention enables pruning of finished evals older than maxAge; zero keeps them forever
+func (s *EngineService) WithRetention(maxAge time.Duration) {
+	s.retention = maxAge
+}
+
+// WithUsage charges Explorer lookups missing the cache to the users' daily quota instead of the
+// in-memory budget
+func (s *EngineService) WithUsage(usage *UsageService) {
+	s.usage = usage
+}
+
+// WithTablebase makes game analysis use exact tablebase results for positions with few pieces
+func (s *EngineService) WithTablebase(tablebase *TablebaseService) {
+	s.tablebase = tablebase
+}
+
+// WithClassificationRules records the winrate drops on the moves of evaluated games and re-applies
+// their owner's classification rules, so rules with a winrate drop condition can match
+func (s *EngineService) WithClassificationRules(repo repository.ClassificationRuleRepository) {
+	s.classificationRuleRepo = repo
+}
+
+// TablebasePosition returns the tablebase verdict on a position with few pieces
+func (s *EngineService) TablebasePosition(fen string) (*models.TablebasePosition, error) {
+	if s.tablebase == nil {
+		return nil, ErrTablebaseUnavailable
 	}
+	return s.tablebase.Lookup(fen)
 }
 
 // EnqueueAnalysis creates pending eval rows for all games in an analysis
@@ -69,7 +156,8 @@ func (s *EngineService) EnqueueAnalysis(userID, analysisID string, gameCount int
 	}
 }
 
-// RunWorker polls for pending evals and processes them via the Lichess Explorer API
+// RunWorker polls for pending evals and processes them via the Lichess Explorer API.
+// Every backend instance can run it: evals are leased, so no two workers process the same one.
 func (s *EngineService) RunWorker(ctx context.Context) {
 	log.Println("opening-analysis: worker started")
 	ticker := time.NewTicker(pollInterval)
@@ -86,49 +174,230 @@ func (s *EngineService) RunWorker(ctx context.Context) {
 	}
 }
 
-func (s *EngineService) processPending() {
-	pending, err := s.evalRepo.GetPending(5)
+// RunRetentionWorker periodically prunes stale evals and drops cached explorer data,
+// so positions evaluated long ago are re-fetched against current explorer statistics
+func (s *EngineService) RunRetentionWorker(ctx context.Context) {
+	if s.retention <= 0 {
+		return
+	}
+	log.Printf("opening-analysis: retention worker started (max age %s)", s.retention)
+	ticker := time.NewTicker(evalRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("opening-analysis: retention worker stopped")
+			return
+		case <-ticker.C:
+			s.pruneStaleEvals(time.Now())
+		}
+	}
+}
+
+func (s *EngineService) pruneStaleEvals(now time.Time) {
+	deleted, err := s.evalRepo.DeleteOlderThan(now.Add(-s.retention))
 	if err != nil {
-		log.Printf("opening-analysis: failed to get pending evals: %v", err)
+		log.Printf("opening-analysis: failed to prune stale evals: %v", err)
 		return
 	}
 
-	for _, eval := range pending {
-		if err := s.evalRepo.MarkProcessing(eval.ID); err != nil {
-			log.Printf("opening-analysis: failed to mark processing %s: %v", eval.ID, err)
-			continue
+	s.cacheMu.Lock()
+	s.cache.clear()
+	s.modelGames.clear()
+	s.cacheMu.Unlock()
+
+	if deleted > 0 {
+		log.Printf("opening-analysis: pruned %d stale evals", deleted)
+	}
+}
+
+// RecomputeAnalysis discards the evals of an analysis and queues all of its games
+// for re-evaluation, bypassing any cached explorer data for their positions.
+// Returns the number of games queued.
+func (s *EngineService) RecomputeAnalysis(userID, analysisID string) (int, error) {
+	detail, err := s.analysisRepo.GetByID(analysisID)
+	if err != nil {
+		return 0, err
+	}
+
+	gameIndices := make([]int, 0, len(detail.Results))
+	s.cacheMu.Lock()
+	for _, game := range detail.Results {
+		gameIndices = append(gameIndices, game.GameIndex)
+		for i, move := range game.Moves {
+			if i >= maxPlies {
+				break
+			}
+			s.cache.delete(ensureFullFEN(move.FEN))
 		}
+	}
+	s.cacheMu.Unlock()
+
+	if err := s.evalRepo.ResetAnalysis(userID, analysisID, gameIndices); err != nil {
+		return 0, fmt.Errorf("failed to reset evals: %w", err)
+	}
+	return len(gameIndices), nil
+}
+
+// newWorkerID names this process uniquely among the instances sharing the eval queue
+func newWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	suffix, err := generateSecureToken(4)
+	if err != nil {
+		return fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), suffix)
+}
+
+func (s *EngineService) processPending() {
+	claimed, err := s.evalRepo.ClaimPending(s.workerID, evalClaimBatch, evalLeaseDuration)
+	if err != nil {
+		log.Printf("opening-analysis: failed to claim pending evals: %v", err)
+		return
+	}
+	if len(claimed) == 0 {
+		return
+	}
+
+	stopHeartbeat := s.renewLeases(claimed, evalLeaseRenewal)
+	defer stopHeartbeat()
 
+	for _, eval := range claimed {
 		stats, err := s.analyzeGameOpenings(eval.AnalysisID, eval.GameIndex)
 		if err != nil {
 			log.Printf("opening-analysis: failed to analyze game %s/%d: %v", eval.AnalysisID, eval.GameIndex, err)
-			_ = s.evalRepo.MarkFailed(eval.ID)
+			s.markFailed(eval.ID)
 			continue
 		}
 
-		if err := s.evalRepo.SaveEvals(eval.ID, stats); err != nil {
+		if err := s.evalRepo.SaveEvals(eval.ID, s.workerID, stats); err != nil {
+			if errors.Is(err, repository.ErrEvalLeaseLost) {
+				log.Printf("opening-analysis: lease on eval %s expired, another worker took it over", eval.ID)
+				continue
+			}
 			log.Printf("opening-analysis: failed to save evals %s: %v", eval.ID, err)
-			_ = s.evalRepo.MarkFailed(eval.ID)
+			s.markFailed(eval.ID)
 			continue
 		}
+		s.classifyEvaluatedGame(eval, stats)
 	}
+
+	s.notifyFinishedAnalyses(claimed)
 }
 
-func (s *EngineService) analyzeGameOpenings(analysisID string, gameIndex int) ([]models.ExplorerMoveStats, error) {
-	detail, err := s.analysisRepo.GetByID(analysisID)
+// classifyEvaluatedGame stores the winrate drops of an evaluated game on its moves and classifies
+// them again. The evals are already saved, so failures are only logged.
+func (s *EngineService) classifyEvaluatedGame(eval models.EngineEval, stats []models.ExplorerMoveStats) {
+	if s.classificationRuleRepo == nil {
+		return
+	}
+	game, err := s.analysisRepo.GetGame(eval.AnalysisID, eval.GameIndex)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get analysis: %w", err)
+		log.Printf("opening-analysis: failed to get game %s/%d to classify: %v", eval.AnalysisID, eval.GameIndex, err)
+		return
 	}
 
-	var game *models.GameAnalysis
-	for i := range detail.Results {
-		if detail.Results[i].GameIndex == gameIndex {
-			game = &detail.Results[i]
-			break
+	drops := make(map[int]float64, len(stats))
+	for _, stat := range stats {
+		drops[stat.PlyNumber] = stat.WinrateDrop
+	}
+	for i := range game.Moves {
+		if drop, ok := drops[game.Moves[i].PlyNumber]; ok {
+			game.Moves[i].WinrateDrop = &drop
 		}
 	}
-	if game == nil {
-		return nil, fmt.Errorf("game %d not found in analysis %s", gameIndex, analysisID)
+	classifyGame(game, classificationRulesFor(s.classificationRuleRepo, eval.UserID))
+
+	if err := s.analysisRepo.UpdateGame(eval.AnalysisID, *game); err != nil {
+		log.Printf("opening-analysis: failed to save classified game %s/%d: %v", eval.AnalysisID, eval.GameIndex, err)
+	}
+}
+
+// notifyFinishedAnalyses notifies the owners of the analyses whose last evals were in the batch
+func (s *EngineService) notifyFinishedAnalyses(claimed []models.EngineEval) {
+	if s.notifications == nil {
+		return
+	}
+	notified := make(map[string]bool)
+	for _, eval := range claimed {
+		if notified[eval.AnalysisID] {
+			continue
+		}
+		notified[eval.AnalysisID] = true
+		unfinished, err := s.evalRepo.CountUnfinished(eval.AnalysisID)
+		if err != nil {
+			log.Printf("opening-analysis: failed to count unfinished evals of %s: %v", eval.AnalysisID, err)
+			continue
+		}
+		if unfinished == 0 {
+			notifyAsync(s.notifications, eval.UserID, analysisCompletedNotification(eval.AnalysisID))
+		}
+	}
+}
+
+func (s *EngineService) markFailed(id string) {
+	retrying, err := s.evalRepo.MarkFailed(id, s.workerID, config.EvalMaxAttempts, config.EvalRetryBaseDelay, config.EvalRetryMaxDelay)
+	if err != nil {
+		if !errors.Is(err, repository.ErrEvalLeaseLost) {
+			log.Printf("opening-analysis: failed to mark eval %s as failed: %v", id, err)
+		}
+		return
+	}
+	if !retrying {
+		log.Printf("opening-analysis: eval %s failed %d times, giving up", id, config.EvalMaxAttempts)
+	}
+}
+
+// RetryFailed queues an analysis' failed evals again with a fresh attempt budget and returns how many it queued
+func (s *EngineService) RetryFailed(analysisID string) (int, error) {
+	queued, err := s.evalRepo.RetryFailed(analysisID)
+	if err != nil {
+		return 0, err
+	}
+	return int(queued), nil
+}
+
+// renewLeases keeps the leases on claimed evals alive every interval until the returned stop
+// function is called. Finished evals are no longer leased and are skipped by the renewal.
+func (s *EngineService) renewLeases(claimed []models.EngineEval, interval time.Duration) (stop func()) {
+	ids := make([]string, len(claimed))
+	for i, eval := range claimed {
+		ids[i] = eval.ID
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := s.evalRepo.ExtendLease(s.workerID, ids, evalLeaseDuration); err != nil {
+					log.Printf("opening-analysis: failed to renew eval leases: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (s *EngineService) analyzeGameOpenings(analysisID string, gameIndex int) ([]models.ExplorerMoveStats, error) {
+	game, err := s.analysisRepo.GetGame(analysisID, gameIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game: %w", err)
 	}
 
 	plyLimit := maxPlies
@@ -144,6 +413,16 @@ func (s *EngineService) analyzeGameOpenings(analysisID string, gameIndex int) ([
 		}
 
 		fen := ensureFullFEN(move.FEN)
+		if s.tablebase != nil && countPieces(fen) <= config.TablebaseMaxPieces {
+			position, err := s.tablebase.Lookup(fen)
+			if err != nil {
+				log.Printf("opening-analysis: tablebase error at ply %d: %v", i, err)
+			} else if stat, ok := tablebaseMoveStats(position, move); ok {
+				stats = append(stats, stat)
+				continue
+			}
+		}
+
 		resp, err := s.fetchExplorer(fen)
 		if err != nil {
 			log.Printf("opening-analysis: explorer error at ply %d: %v", i, err)
@@ -227,51 +506,175 @@ func calcWinrate(white, draws, black int, userColor models.Color) float64 {
 }
 
 func (s *EngineService) fetchExplorer(fen string) (*explorerResponse, error) {
+	return s.fetchExplorerWith(fen, explorerSpeeds, explorerRatings)
+}
+
+// fetchExplorerWith queries the explorer for the given speeds and ratings filters, through the cache
+func (s *EngineService) fetchExplorerWith(fen, speeds, ratings string) (*explorerResponse, error) {
+	key := explorerCacheKey(fen, speeds, ratings)
+
 	// Check cache first
 	s.cacheMu.Lock()
-	if cached, ok := s.cache[fen]; ok {
+	if cached, ok := s.cache.get(key, time.Now()); ok {
 		s.cacheMu.Unlock()
 		return cached, nil
 	}
 	s.cacheMu.Unlock()
 
+	u := fmt.Sprintf("%s?variant=standard&speeds=%s&ratings=%s&fen=%s",
+		explorerBaseURL, speeds, ratings, url.QueryEscape(fen))
+
+	var result explorerResponse
+	if err := s.getExplorerJSON(u, &result); err != nil {
+		return nil, err
+	}
+
+	// Cache the result
+	s.cacheMu.Lock()
+	s.cache.set(key, &result, time.Now())
+	s.cacheMu.Unlock()
+
+	return &result, nil
+}
+
+// explorerCacheKey keys cached explorer responses; the default filters are keyed by FEN alone
+func explorerCacheKey(fen, speeds, ratings string) string {
+	if speeds == explorerSpeeds && ratings == explorerRatings {
+		return fen
+	}
+	return fen + "|" + speeds + "|" + ratings
+}
+
+// getExplorerJSON performs a rate-limited GET against the explorer and decodes the JSON body into out
+func (s *EngineService) getExplorerJSON(u string, out interface{}) error {
 	// Rate limit
 	time.Sleep(apiDelay)
 
-	u := fmt.Sprintf("%s?variant=standard&speeds=%s&ratings=%s&fen=%s",
-		explorerBaseURL, explorerSpeeds, explorerRatings, url.QueryEscape(fen))
-
 	resp, err := s.httpClient.Get(u)
 	if err != nil {
-		return nil, fmt.Errorf("explorer request failed: %w", err)
+		return fmt.Errorf("explorer request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		// Back off and retry once
-		time.Sleep(2 * time.Second)
-		resp, err = s.httpClient.Get(u)
-		if err != nil {
-			return nil, fmt.Errorf("explorer retry failed: %w", err)
-		}
-		defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("explorer returned status %d", resp.StatusCode)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("explorer returned status %d", resp.StatusCode)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode explorer response: %w", err)
 	}
+	return nil
+}
 
-	var result explorerResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode explorer response: %w", err)
+// GetModelGames returns top master games reaching the given position, cached per FEN
+func (s *EngineService) GetModelGames(fen string) ([]models.ModelGame, error) {
+	fullFEN := ensureFullFEN(fen)
+	if _, err := chess.FEN(fullFEN); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
 	}
 
-	// Cache the result
 	s.cacheMu.Lock()
-	s.cache[fen] = &result
+	if cached, ok := s.modelGames.get(fullFEN, time.Now()); ok {
+		s.cacheMu.Unlock()
+		return cached, nil
+	}
 	s.cacheMu.Unlock()
 
-	return &result, nil
+	u := fmt.Sprintf("%s?moves=0&topGames=%d&fen=%s",
+		mastersBaseURL, maxModelGames, url.QueryEscape(fullFEN))
+
+	var resp mastersResponse
+	if err := s.getExplorerJSON(u, &resp); err != nil {
+		return nil, err
+	}
+
+	games := toModelGames(resp.TopGames)
+
+	s.cacheMu.Lock()
+	s.modelGames.set(fullFEN, games, time.Now())
+	s.cacheMu.Unlock()
+
+	return games, nil
+}
+
+// toModelGames converts explorer top games into API models
+func toModelGames(top []mastersGame) []models.ModelGame {
+	games := make([]models.ModelGame, 0, len(top))
+	for _, g := range top {
+		result := "1/2-1/2"
+		switch g.Winner {
+		case "white":
+			result = "1-0"
+		case "black":
+			result = "0-1"
+		}
+		games = append(games, models.ModelGame{
+			ID:          g.ID,
+			White:       g.White.Name,
+			WhiteRating: g.White.Rating,
+			Black:       g.Black.Name,
+			BlackRating: g.Black.Rating,
+			Year:        g.Year,
+			Result:      result,
+			URL:         lichessGameURL + g.ID,
+		})
+	}
+	return games
+}
+
+// ExplorerCoverage returns the percentage (0-100) of explorer games, over positions where the
+// opponent is to move within maxDepth plies, whose reply is covered by a child in the tree.
+func (s *EngineService) ExplorerCoverage(root models.RepertoireNode, color models.Color, maxDepth int) (float64, error) {
+	userToMove := models.ChessColorWhite
+	if color == models.ColorBlack {
+		userToMove = models.ChessColorBlack
+	}
+
+	type queued struct {
+		node  *models.RepertoireNode
+		depth int
+	}
+	queue := []queued{{node: &root, depth: 0}}
+	var covered, total, lookups int
+
+	for len(queue) > 0 && lookups < maxCoveragePositions {
+		item := queue[0]
+		queue = queue[1:]
+		if item.depth >= maxDepth {
+			continue
+		}
+
+		if item.node.ColorToMove != userToMove {
+			lookups++
+			resp, err := s.fetchExplorer(ensureFullFEN(item.node.FEN))
+			if err != nil {
+				log.Printf("opening-analysis: coverage lookup failed at depth %d: %v", item.depth, err)
+			} else if resp.White+resp.Draws+resp.Black >= minExplorerGames {
+				children := make(map[string]bool, len(item.node.Children))
+				for _, child := range item.node.Children {
+					if child.Move != nil {
+						children[*child.Move] = true
+					}
+				}
+				for _, m := range resp.Moves {
+					games := m.White + m.Draws + m.Black
+					total += games
+					if children[m.SAN] {
+						covered += games
+					}
+				}
+			}
+		}
+
+		for _, child := range item.node.Children {
+			queue = append(queue, queued{node: child, depth: item.depth + 1})
+		}
+	}
+
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(covered) / float64(total) * 100, nil
 }
 
 // EngineInsightsData holds opening analysis results with progress counters
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestToModelGames(t *testing.T) {
+	top := []mastersGame{
+		{ID: "abc", Winner: "white", White: mastersPlayer{Name: "Carlsen", Rating: 2850}, Black: mastersPlayer{Name: "Caruana", Rating: 2800}, Year: 2019},
+		{ID: "def", Winner: "black", Year: 2020},
+		{ID: "ghi", Winner: "", Year: 2021},
+	}
+
+	games := toModelGames(top)
+
+	require.Len(t, games, 3)
+	assert.Equal(t, "1-0", games[0].Result)
+	assert.Equal(t, "Carlsen", games[0].White)
+	assert.Equal(t, 2800, games[0].BlackRating)
+	assert.Equal(t, "https://lichess.org/abc", games[0].URL)
+	assert.Equal(t, "0-1", games[1].Result)
+	assert.Equal(t, "1/2-1/2", games[2].Result)
+}
+
+func TestGetModelGames_InvalidFEN(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+
+	_, err := svc.GetModelGames("not a fen")
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidFEN))
+}
+
+func TestGetModelGames_UsesCache(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	cached := []models.ModelGame{{ID: "cached", Result: "1-0"}}
+	svc.modelGames.set(fen, cached, time.Now())
+
+	games, err := svc.GetModelGames(fen)
+
+	require.NoError(t, err)
+	assert.Equal(t, cached, games)
+}
+
+func TestGetModelGames_EvictsOldEntries(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	now := time.Now()
+
+	// Entries past the TTL are misses
+	svc.modelGames.set(fen, []models.ModelGame{{ID: "stale"}}, now.Add(-config.ExplorerCacheTTL))
+	_, ok := svc.modelGames.get(fen, now)
+	assert.False(t, ok)
+
+	// A full cache drops the oldest entry
+	svc.modelGames.clear()
+	for i := 0; i < config.ExplorerCacheSize; i++ {
+		svc.modelGames.set(fmt.Sprintf("fen-%d", i), nil, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	svc.modelGames.set(fen, []models.ModelGame{{ID: "fresh"}}, now.Add(time.Second))
+
+	assert.Len(t, svc.modelGames.entries, config.ExplorerCacheSize)
+	assert.NotContains(t, svc.modelGames.entries, "fen-0")
+	assert.Contains(t, svc.modelGames.entries, "fen-1")
+	games, ok := svc.modelGames.get(fen, now.Add(time.Second))
+	require.True(t, ok)
+	assert.Equal(t, "fresh", games[0].ID)
+}
+
+func TestExplorerCoverage_WeightsRepliesByGames(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+	e4 := "e4"
+	c5 := "c5"
+	rootFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	afterE4 := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	svc.cache.set(afterE4, &explorerResponse{
+		White: 60, Draws: 10, Black: 30,
+		Moves: []explorerMove{
+			{SAN: "c5", White: 30, Draws: 5, Black: 15},
+			{SAN: "e5", White: 30, Draws: 5, Black: 15},
+		},
+	}, time.Now())
+
+	root := models.RepertoireNode{
+		FEN:         rootFEN,
+		ColorToMove: models.ChessColorWhite,
+		Children: []*models.RepertoireNode{{
+			FEN:         afterE4,
+			Move:        &e4,
+			ColorToMove: models.ChessColorBlack,
+			Children: []*models.RepertoireNode{{
+				FEN:         "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+				Move:        &c5,
+				ColorToMove: models.ChessColorWhite,
+			}},
+		}},
+	}
+
+	coverage, err := svc.ExplorerCoverage(root, models.ColorWhite, 2)
+
+	require.NoError(t, err)
+	assert.InDelta(t, 50.0, coverage, 0.001)
+}
+
+func TestPruneStaleEvals(t *testing.T) {
+	var cutoff time.Time
+	evalRepo := &mocks.MockEngineEvalRepo{
+		DeleteOlderThanFunc: func(before time.Time) (int64, error) {
+			cutoff = before
+			return 3, nil
+		},
+	}
+	svc := NewEngineService(evalRepo, nil)
+	svc.WithRetention(30 * 24 * time.Hour)
+	svc.cache.set("some-fen", &explorerResponse{White: 1}, time.Now())
+	now := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	svc.pruneStaleEvals(now)
+
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), cutoff)
+	assert.Empty(t, svc.cache.entries)
+}
+
+func TestRecomputeAnalysis(t *testing.T) {
+	afterE4 := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	otherFEN := "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq - 0 1"
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetByIDFunc: func(id string) (*models.AnalysisDetail, error) {
+			return &models.AnalysisDetail{ID: id, Results: []models.GameAnalysis{
+				{GameIndex: 0, Moves: []models.MoveAnalysis{{SAN: "e4", FEN: afterE4}}},
+				{GameIndex: 2},
+			}}, nil
+		},
+	}
+	var gotIndices []int
+	evalRepo := &mocks.MockEngineEvalRepo{
+		ResetAnalysisFunc: func(userID, analysisID string, gameIndices []int) error {
+			gotIndices = gameIndices
+			return nil
+		},
+	}
+	svc := NewEngineService(evalRepo, analysisRepo)
+	svc.cache.set(afterE4, &explorerResponse{White: 1}, time.Now())
+	svc.cache.set(otherFEN, &explorerResponse{White: 1}, time.Now())
+
+	queued, err := svc.RecomputeAnalysis("user-1", "analysis-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued)
+	assert.Equal(t, []int{0, 2}, gotIndices)
+	assert.NotContains(t, svc.cache.entries, afterE4)
+	assert.Contains(t, svc.cache.entries, otherFEN)
+}
+
+func TestProcessPending_CompletesUnderLease(t *testing.T) {
+	var claimedBy string
+	var saved, failed []string
+	evalRepo := &mocks.MockEngineEvalRepo{
+		ClaimPendingFunc: func(workerID string, limit int, lease time.Duration) ([]models.EngineEval, error) {
+			claimedBy = workerID
+			return []models.EngineEval{
+				{ID: "eval-1", AnalysisID: "analysis-1", GameIndex: 0},
+				{ID: "eval-2", AnalysisID: "analysis-1", GameIndex: 1},
+				{ID: "eval-3", AnalysisID: "missing", GameIndex: 0},
+			}, nil
+		},
+		SaveEvalsFunc: func(id, workerID string, evals []models.ExplorerMoveStats) error {
+			assert.Equal(t, claimedBy, workerID)
+			if id == "eval-2" {
+				return repository.ErrEvalLeaseLost
+			}
+			saved = append(saved, id)
+			return nil
+		},
+		MarkFailedFunc: func(id, workerID string, maxAttempts int, baseDelay, maxDelay time.Duration) (bool, error) {
+			assert.Equal(t, claimedBy, workerID)
+			assert.Equal(t, config.EvalMaxAttempts, maxAttempts)
+			failed = append(failed, id)
+			return true, nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			if analysisID == "missing" {
+				return nil, repository.ErrGameNotFound
+			}
+			return &models.GameAnalysis{GameIndex: gameIndex}, nil
+		},
+	}
+	svc := NewEngineService(evalRepo, analysisRepo)
+
+	svc.processPending()
+
+	assert.NotEmpty(t, claimedBy)
+	assert.Equal(t, []string{"eval-1"}, saved)
+	// An eval whose lease was lost belongs to another worker and is not marked failed
+	assert.Equal(t, []string{"eval-3"}, failed)
+}
+
+func TestRetryFailed(t *testing.T) {
+	var gotAnalysis string
+	evalRepo := &mocks.MockEngineEvalRepo{
+		RetryFailedFunc: func(analysisID string) (int64, error) {
+			gotAnalysis = analysisID
+			return 3, nil
+		},
+	}
+	svc := NewEngineService(evalRepo, nil)
+
+	queued, err := svc.RetryFailed("analysis-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, queued)
+	assert.Equal(t, "analysis-1", gotAnalysis)
+}
+
+func TestRenewLeases(t *testing.T) {
+	renewed := make(chan []string, 10)
+	evalRepo := &mocks.MockEngineEvalRepo{
+		ExtendLeaseFunc: func(workerID string, ids []string, lease time.Duration) (int64, error) {
+			renewed <- ids
+			return int64(len(ids)), nil
+		},
+	}
+	svc := NewEngineService(evalRepo, nil)
+
+	stop := svc.renewLeases([]models.EngineEval{{ID: "eval-1"}, {ID: "eval-2"}}, time.Millisecond)
+	ids := <-renewed
+	stop()
+
+	assert.Equal(t, []string{"eval-1", "eval-2"}, ids)
+}
+
+func TestNewWorkerID_Unique(t *testing.T) {
+	assert.NotEqual(t, newWorkerID(), newWorkerID())
+}
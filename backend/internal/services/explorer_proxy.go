@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+var (
+	ErrInvalidExplorerFilter  = errors.New("invalid explorer filter")
+	ErrExplorerBudgetExceeded = errors.New("daily explorer lookup budget exhausted")
+	ErrExplorerBusy           = errors.New("explorer lookups are backed up, try again later")
+)
+
+// Filters accepted by the Lichess Explorer, in the order they are sent
+var (
+	explorerSpeedOptions  = []string{"ultraBullet", "bullet", "blitz", "rapid", "classical", "correspondence"}
+	explorerRatingOptions = []string{"0", "1000", "1200", "1400", "1600", "1800", "2000", "2200", "2500"}
+)
+
+type explorerLookup struct {
+	fen, speeds, ratings string
+}
+
+type explorerBudget struct {
+	day  string
+	used int
+}
+
+// ExplorerPosition serves Explorer stats for any position from the shared cache. On a miss the
+// lookup is queued for the explorer worker and charged to the user's daily budget; the
+// returned position is then pending until the worker has fetched it.
+func (s *EngineService) ExplorerPosition(userID, fen, speeds, ratings string) (*models.ExplorerPosition, error) {
+	fullFEN := ensureFullFEN(fen)
+	if _, err := chess.FEN(fullFEN); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+	speeds, err := normalizeExplorerFilter("speeds", speeds, explorerSpeeds, explorerSpeedOptions)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err = normalizeExplorerFilter("ratings", ratings, explorerRatings, explorerRatingOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	position := &models.ExplorerPosition{
+		FEN:     fullFEN,
+		Speeds:  speeds,
+		Ratings: ratings,
+		Status:  models.ExplorerPending,
+		Moves:   []models.ExplorerMove{},
+	}
+
+	key := explorerCacheKey(fullFEN, speeds, ratings)
+	s.cacheMu.Lock()
+	cached, ok := s.cache.get(key, time.Now())
+	s.cacheMu.Unlock()
+	if ok {
+		fillExplorerPosition(position, cached)
+		return position, nil
+	}
+
+	if err := s.queueExplorerLookup(userID, explorerLookup{fen: fullFEN, speeds: speeds, ratings: ratings}, time.Now()); err != nil {
+		return nil, err
+	}
+	return position, nil
+}
+
+// queueExplorerLookup hands a cache miss to the explorer worker, charging the user's budget
+// unless the same lookup is already queued
+func (s *EngineService) queueExplorerLookup(userID string, lookup explorerLookup, now time.Time) error {
+	key := explorerCacheKey(lookup.fen, lookup.speeds, lookup.ratings)
+
+	s.lookupMu.Lock()
+	defer s.lookupMu.Unlock()
+	if s.queued[key] {
+		return nil
+	}
+	// Only this function sends to the queue, so with room left the send below cannot block
+	if len(s.explorerQueue) == cap(s.explorerQueue) {
+		return ErrExplorerBusy
+	}
+	if err := s.chargeExplorer(userID, now); err != nil {
+		return err
+	}
+
+	s.explorerQueue <- lookup
+	s.queued[key] = true
+	return nil
+}
+
+// chargeExplorer charges a lookup to the user's daily quota, or to the in-memory budget when
+// quotas are not enforced. The caller holds lookupMu.
+func (s *EngineService) chargeExplorer(userID string, now time.Time) error {
+	if s.usage != nil {
+		if err := s.usage.Charge(userID, models.UsageExplorer); err != nil {
+			if errors.Is(err, ErrUsageQuotaExceeded) {
+				return ErrExplorerBudgetExceeded
+			}
+			return err
+		}
+		return nil
+	}
+
+	day := now.UTC().Format("2006-01-02")
+	budget := s.budgets[userID]
+	if budget == nil || budget.day != day {
+		budget = &explorerBudget{day: day}
+		s.budgets[userID] = budget
+	}
+	if budget.used >= config.ExplorerDailyBudget {
+		return ErrExplorerBudgetExceeded
+	}
+	budget.used++
+	return nil
+}
+
+// RunExplorerWorker fetches queued explorer lookups into the shared cache
+func (s *EngineService) RunExplorerWorker(ctx context.Context) {
+	log.Println("explorer: worker started")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("explorer: worker stopped")
+			return
+		case lookup := <-s.explorerQueue:
+			if _, err := s.fetchExplorerWith(lookup.fen, lookup.speeds, lookup.ratings); err != nil {
+				log.Printf("explorer: lookup failed for %s: %v", lookup.fen, err)
+			}
+			s.lookupMu.Lock()
+			delete(s.queued, explorerCacheKey(lookup.fen, lookup.speeds, lookup.ratings))
+			s.lookupMu.Unlock()
+		}
+	}
+}
+
+// normalizeExplorerFilter validates a comma-separated filter and rewrites it in canonical
+// order so equivalent requests share a cache entry
+func normalizeExplorerFilter(name, value, defaultValue string, options []string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return defaultValue, nil
+	}
+
+	requested := make(map[string]bool)
+	for _, v := range strings.Split(value, ",") {
+		requested[strings.TrimSpace(v)] = true
+	}
+
+	var selected []string
+	for _, option := range options {
+		if requested[option] {
+			selected = append(selected, option)
+			delete(requested, option)
+		}
+	}
+	delete(requested, "")
+	if len(requested) > 0 || len(selected) == 0 {
+		return "", fmt.Errorf("%w: %s must be among %s", ErrInvalidExplorerFilter, name, strings.Join(options, ","))
+	}
+	return strings.Join(selected, ","), nil
+}
+
+func fillExplorerPosition(position *models.ExplorerPosition, resp *explorerResponse) {
+	position.Status = models.ExplorerReady
+	position.White = resp.White
+	position.Draws = resp.Draws
+	position.Black = resp.Black
+	for _, m := range resp.Moves {
+		position.Moves = append(position.Moves, models.ExplorerMove{
+			SAN:           m.SAN,
+			UCI:           m.UCI,
+			White:         m.White,
+			Draws:         m.Draws,
+			Black:         m.Black,
+			AverageRating: m.AverageRating,
+		})
+	}
+}
+
+// ExplorerMoves fetches the moves played from a position with the default filters, most
+// played first. Unlike ExplorerPosition it waits for Lichess on a cache miss, so it is
+// meant for workers rather than request handlers.
+func (s *EngineService) ExplorerMoves(fen string) ([]models.ExplorerMove, error) {
+	return s.ExplorerMovesFor("", fen, explorerRatings)
+}
+
+// ExplorerMovesFor is ExplorerMoves for games between players of the given rating bands, on
+// behalf of a user whose daily quota is charged when the position is not cached. An empty
+// userID charges nothing.
+func (s *EngineService) ExplorerMovesFor(userID, fen, ratings string) ([]models.ExplorerMove, error) {
+	fullFEN := ensureFullFEN(fen)
+	if userID != "" {
+		s.cacheMu.Lock()
+		_, cached := s.cache.get(explorerCacheKey(fullFEN, explorerSpeeds, ratings), time.Now())
+		s.cacheMu.Unlock()
+		if !cached {
+			s.lookupMu.Lock()
+			err := s.chargeExplorer(userID, time.Now())
+			s.lookupMu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := s.fetchExplorerWith(fullFEN, explorerSpeeds, ratings)
+	if err != nil {
+		return nil, err
+	}
+	position := &models.ExplorerPosition{Moves: []models.ExplorerMove{}}
+	fillExplorerPosition(position, resp)
+	return position.Moves, nil
+}
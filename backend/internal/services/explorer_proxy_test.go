@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const afterE4FEN = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+func TestExplorerPosition_ServesFromCache(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+	svc.cache.set(explorerCacheKey(afterE4FEN, "blitz,rapid", "2000"), &explorerResponse{
+		White: 10, Draws: 5, Black: 8,
+		Moves: []explorerMove{{SAN: "c5", UCI: "c7c5", White: 4, Draws: 2, Black: 4}},
+	}, time.Now())
+
+	// Filters are canonicalized, so reordered input hits the same entry
+	position, err := svc.ExplorerPosition("user-1", afterE4FEN, "rapid, blitz", "2000")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ExplorerReady, position.Status)
+	assert.Equal(t, "blitz,rapid", position.Speeds)
+	assert.Equal(t, 10, position.White)
+	require.Len(t, position.Moves, 1)
+	assert.Equal(t, "c5", position.Moves[0].SAN)
+	assert.Empty(t, svc.explorerQueue)
+}
+
+func TestExplorerPosition_QueuesCacheMissOnce(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+
+	first, err := svc.ExplorerPosition("user-1", afterE4FEN, "", "")
+	require.NoError(t, err)
+	second, err := svc.ExplorerPosition("user-2", afterE4FEN, "", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, models.ExplorerPending, first.Status)
+	assert.Equal(t, models.ExplorerPending, second.Status)
+	assert.Len(t, svc.explorerQueue, 1)
+	assert.Equal(t, 1, svc.budgets["user-1"].used)
+	assert.Nil(t, svc.budgets["user-2"])
+}
+
+func TestQueueExplorerLookup_DailyBudget(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.budgets["user-1"] = &explorerBudget{day: "2026-05-01", used: config.ExplorerDailyBudget}
+
+	err := svc.queueExplorerLookup("user-1", explorerLookup{fen: afterE4FEN, speeds: explorerSpeeds, ratings: explorerRatings}, now)
+	assert.ErrorIs(t, err, ErrExplorerBudgetExceeded)
+
+	// The budget resets the next day
+	err = svc.queueExplorerLookup("user-1", explorerLookup{fen: afterE4FEN, speeds: explorerSpeeds, ratings: explorerRatings}, now.Add(24*time.Hour))
+	assert.NoError(t, err)
+}
+
+func TestExplorerPosition_InvalidInput(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+
+	_, err := svc.ExplorerPosition("user-1", "garbage", "", "")
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+
+	_, err = svc.ExplorerPosition("user-1", afterE4FEN, "blitz,hyperbullet", "")
+	assert.ErrorIs(t, err, ErrInvalidExplorerFilter)
+
+	_, err = svc.ExplorerPosition("user-1", afterE4FEN, "", "1750")
+	assert.ErrorIs(t, err, ErrInvalidExplorerFilter)
+}
+
+func TestQueueExplorerLookup_UsageQuota(t *testing.T) {
+	svc := NewEngineService(nil, nil)
+	svc.WithUsage(NewUsageService(&mocks.MockUsageRepo{
+		IncrementFunc: func(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error) {
+			return false, nil
+		},
+	}, config.UsageQuotas{Explorer: 1}))
+
+	err := svc.queueExplorerLookup("user-1", explorerLookup{fen: afterE4FEN, speeds: explorerSpeeds, ratings: explorerRatings}, time.Now())
+
+	assert.ErrorIs(t, err, ErrExplorerBudgetExceeded)
+	assert.Empty(t, svc.explorerQueue)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// NormalizeFEN strips half-move and full-move counters from a FEN string,
+// keeping only board, side to move, castling, and en passant fields.
+func NormalizeFEN(fen string) string {
+	parts := strings.Fields(fen)
+	if len(parts) >= 4 {
+		return strings.Join(parts[:4], " ")
+	}
+	return fen
+}
+
+// normalizeFEN is the package-internal alias kept for existing callers.
+func normalizeFEN(fen string) string { return NormalizeFEN(fen) }
+
+// fenDefaults are the fields a FEN may leave out, after the board: white to move, no castling,
+// no en passant square and the counters of a fresh position
+var fenDefaults = []string{"", "w", "-", "-", "0", "1"}
+
+// ensureFullFEN completes a FEN missing its trailing fields with their defaults
+func ensureFullFEN(fen string) string {
+	parts := strings.Fields(fen)
+	if len(parts) == 0 || len(parts) >= len(fenDefaults) {
+		return fen
+	}
+	return strings.Join(append(parts, fenDefaults[len(parts):]...), " ")
+}
+
+// DescribeFEN validates a FEN, possibly missing its trailing fields, and returns it in both the
+// normalized and the full form along with the side to move, the number of legal moves and
+// whether the game is over
+func DescribeFEN(fen string) (*models.FENDetails, error) {
+	fenFn, err := chess.FEN(ensureFullFEN(strings.TrimSpace(fen)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+	game := chess.NewGame(fenFn)
+	pos := game.Position()
+
+	details := &models.FENDetails{
+		FEN:        NormalizeFEN(pos.String()),
+		FullFEN:    pos.String(),
+		SideToMove: models.ColorWhite,
+		LegalMoves: len(game.ValidMoves()),
+	}
+	if pos.Turn() == chess.Black {
+		details.SideToMove = models.ColorBlack
+	}
+	if game.Outcome() != chess.NoOutcome {
+		details.GameOver = true
+		details.Reason = gameOverReason(game.Method())
+	}
+	return details, nil
+}
+
+func gameOverReason(method chess.Method) string {
+	switch method {
+	case chess.Checkmate:
+		return "checkmate"
+	case chess.Stalemate:
+		return "stalemate"
+	case chess.InsufficientMaterial:
+		return "insufficient_material"
+	case chess.SeventyFiveMoveRule:
+		return "seventy_five_move_rule"
+	}
+	return ""
+}
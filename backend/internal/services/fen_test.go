@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+func TestEnsureFullFEN_FillsMissingFields(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want string
+	}{
+		{"8/8/8/8/8/8/8/K6k", "8/8/8/8/8/8/8/K6k w - - 0 1"},
+		{"8/8/8/8/8/8/8/K6k b", "8/8/8/8/8/8/8/K6k b - - 0 1"},
+		{"8/8/8/8/8/8/8/K6k b - - 12", "8/8/8/8/8/8/8/K6k b - - 12 1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ensureFullFEN(tt.fen), tt.fen)
+	}
+}
+
+func TestDescribeFEN(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want models.FENDetails
+	}{
+		{
+			name: "normalized start",
+			fen:  "  rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -  ",
+			want: models.FENDetails{
+				FEN:        "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+				FullFEN:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+				SideToMove: models.ColorWhite,
+				LegalMoves: 20,
+			},
+		},
+		{
+			name: "checkmate",
+			fen:  "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+			want: models.FENDetails{
+				FEN:        "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq -",
+				FullFEN:    "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+				SideToMove: models.ColorWhite,
+				GameOver:   true,
+				Reason:     "checkmate",
+			},
+		},
+		{
+			name: "stalemate",
+			fen:  "7k/5Q2/6K1/8/8/8/8/8 b",
+			want: models.FENDetails{
+				FEN:        "7k/5Q2/6K1/8/8/8/8/8 b - -",
+				FullFEN:    "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+				SideToMove: models.ColorBlack,
+				GameOver:   true,
+				Reason:     "stalemate",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := DescribeFEN(tt.fen)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *details)
+		})
+	}
+}
+
+func TestDescribeFEN_Invalid(t *testing.T) {
+	_, err := DescribeFEN("not a fen")
+
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+}
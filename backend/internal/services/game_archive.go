@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrRecomputeUnavailable is returned when the raw game archive is not configured
+var ErrRecomputeUnavailable = fmt.Errorf("analysis recompute is not available")
+
+// recomputeBatchSize is how many archived games a recompute job reads at a time
+const recomputeBatchSize = 100
+
+// WithGameArchive archives the PGN of every imported game and enables recomputing the stored
+// analyses from the archive
+func WithGameArchive(rawGames repository.RawGameRepository, jobs repository.RecomputeJobRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.rawGameRepo = rawGames
+		s.recomputeJobRepo = jobs
+	}
+}
+
+// archiveRawGames archives the PGN of analyzed games under their fingerprint; rawPGNs follows
+// results. Failures are logged and do not fail the import.
+func (s *ImportService) archiveRawGames(userID, source string, results []models.GameAnalysis, rawPGNs []string) {
+	if s.rawGameRepo == nil {
+		return
+	}
+	entries := make([]repository.RawGameEntry, len(results))
+	for i, r := range results {
+		entries[i] = repository.RawGameEntry{
+			Fingerprint: ComputeFingerprint(r.Headers, r.Moves),
+			PGN:         rawPGNs[i],
+		}
+	}
+	if err := s.rawGameRepo.Append(userID, source, entries); err != nil {
+		log.Printf("import: %v", err)
+	}
+}
+
+// QueueRecompute queues a background job recomputing the stored analyses of a user's archived
+// games, or of every user's when userID is empty
+func (s *ImportService) QueueRecompute(userID string) (*models.RecomputeJob, error) {
+	if s.recomputeJobRepo == nil || s.rawGameRepo == nil || s.fingerprintRepo == nil {
+		return nil, ErrRecomputeUnavailable
+	}
+	if userID != "" && s.userRepo != nil {
+		if _, err := s.userRepo.GetByID(userID); err != nil {
+			return nil, err
+		}
+	}
+	return s.recomputeJobRepo.Create(userID)
+}
+
+// GetRecomputeJob returns a recompute job
+func (s *ImportService) GetRecomputeJob(id string) (*models.RecomputeJob, error) {
+	if s.recomputeJobRepo == nil {
+		return nil, repository.ErrRecomputeJobNotFound
+	}
+	return s.recomputeJobRepo.GetByID(id)
+}
+
+// RunRecomputeWorker polls for pending recompute jobs and processes them one at a time
+func (s *ImportService) RunRecomputeWorker(ctx context.Context) {
+	log.Println("recompute: worker started")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("recompute: worker stopped")
+			return
+		case <-ticker.C:
+			s.processRecomputeJobs()
+		}
+	}
+}
+
+func (s *ImportService) processRecomputeJobs() {
+	jobs, err := s.recomputeJobRepo.GetPending(1)
+	if err != nil {
+		log.Printf("recompute: failed to get pending jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := s.runRecomputeJob(job); err != nil {
+			log.Printf("recompute: job %s failed: %v", job.ID, err)
+			if markErr := s.recomputeJobRepo.MarkFailed(job.ID, err.Error()); markErr != nil {
+				log.Printf("recompute: failed to mark job %s as failed: %v", job.ID, markErr)
+			}
+		}
+	}
+}
+
+// runRecomputeJob walks the archive in batches and replaces the stored analysis of every archived
+// game still stored, reporting progress after each batch
+func (s *ImportService) runRecomputeJob(job models.RecomputeJob) error {
+	total, err := s.rawGameRepo.Count(job.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.recomputeJobRepo.MarkProcessing(job.ID, total); err != nil {
+		return fmt.Errorf("failed to mark job as processing: %w", err)
+	}
+
+	processed, recomputed := 0, 0
+	var after repository.RawGameKey
+	for {
+		games, err := s.rawGameRepo.ListAfter(job.UserID, after, recomputeBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(games) == 0 {
+			break
+		}
+
+		// The archive is ordered by user, so each user's games of the batch are contiguous
+		for start := 0; start < len(games); {
+			end := start + 1
+			for end < len(games) && games[end].UserID == games[start].UserID {
+				end++
+			}
+			count, err := s.recomputeUserGames(games[start].UserID, games[start:end])
+			if err != nil {
+				return err
+			}
+			recomputed += count
+			start = end
+		}
+
+		processed += len(games)
+		last := games[len(games)-1]
+		after = repository.RawGameKey{UserID: last.UserID, Fingerprint: last.Fingerprint}
+		if err := s.recomputeJobRepo.UpdateProgress(job.ID, processed, recomputed); err != nil {
+			log.Printf("recompute: failed to update progress of job %s: %v", job.ID, err)
+		}
+	}
+
+	if err := s.recomputeJobRepo.MarkDone(job.ID); err != nil {
+		return fmt.Errorf("failed to mark job as done: %w", err)
+	}
+	return nil
+}
+
+// archivedGame is an archived game being recomputed, with where its analysis is stored
+type archivedGame struct {
+	location    repository.GameLocation
+	game        *chess.Game
+	annotations []moveAnnotation
+	userColor   models.Color
+	stored      []models.MoveAnalysis // Moves of the stored analysis, for their winrate drops
+}
+
+// recomputeUserGames re-analyzes archived games of one user against the user's current
+// repertoires and replaces their stored analyses. Games deleted by the user are skipped, and
+// each game keeps the side the user played. It returns how many games were recomputed.
+func (s *ImportService) recomputeUserGames(userID string, raws []models.RawGame) (int, error) {
+	fingerprints := make([]string, len(raws))
+	for i, raw := range raws {
+		fingerprints[i] = raw.Fingerprint
+	}
+	locations, err := s.checkExistingFingerprints(userID, fingerprints, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var games []archivedGame
+	var userFENs []string
+	for _, raw := range raws {
+		loc, ok := locations[raw.Fingerprint]
+		if !ok {
+			continue
+		}
+		stored, err := s.analysisRepo.GetGame(loc.AnalysisID, loc.GameIndex)
+		if errors.Is(err, repository.ErrGameNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		parsed := s.readPGN(raw.PGN)
+		if len(parsed.games) != 1 {
+			log.Printf("recompute: archived game %s of user %s could not be read", raw.Fingerprint, userID)
+			continue
+		}
+		games = append(games, archivedGame{
+			location:    loc,
+			game:        parsed.games[0],
+			annotations: parsed.annotations[0],
+			userColor:   stored.UserColor,
+			stored:      stored.Moves,
+		})
+	}
+	if len(games) == 0 {
+		return 0, nil
+	}
+
+	maxPlies, err := s.analysisPlies(userID, nil)
+	if err != nil {
+		return 0, err
+	}
+	repertoires, err := s.repertoireService.CachedRepertoires(userID, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get repertoires: %w", err)
+	}
+	scope, err := s.newMatchScope(userID, nil)
+	if err != nil {
+		return 0, err
+	}
+	repertoires = scope.filter(repertoires)
+	byColor := make(map[models.Color][]models.Repertoire)
+	repertoiresByID := make(map[string]*models.Repertoire, len(repertoires))
+	indexes := make(map[string]nodeIndex, len(repertoires))
+	for i := range repertoires {
+		byColor[repertoires[i].Color] = append(byColor[repertoires[i].Color], repertoires[i])
+		repertoiresByID[repertoires[i].ID] = &repertoires[i]
+		indexes[repertoires[i].ID] = newNodeIndex(&repertoires[i].TreeData)
+	}
+	for _, g := range games {
+		userFENs = append(userFENs, userMovePositions(g.game, g.userColor, maxPlies)...)
+	}
+	matcher, err := s.newRepertoireMatcher(userID, userFENs, repertoires)
+	if err != nil {
+		return 0, err
+	}
+
+	rules := classificationRulesFor(s.classificationRuleRepo, userID)
+	hashes := make(map[string]string)
+	for _, g := range games {
+		var best *models.Repertoire
+		var score int
+		if gameVariant(g.game) == "" {
+			best, score = matcher.findBestMatchingRepertoire(g.game, byColor[g.userColor], g.userColor, maxPlies)
+		}
+		analysis := s.analyzeAgainst(g.location.GameIndex, g.game, g.annotations, g.userColor, best, score, indexes, maxPlies)
+		stampRepertoireSnapshot(&analysis, repertoiresByID, hashes)
+		copyWinrateDrops(g.stored, analysis.Moves)
+		classifyGame(&analysis, rules)
+		if err := s.analysisRepo.UpdateGame(g.location.AnalysisID, analysis); err != nil {
+			return 0, fmt.Errorf("failed to save recomputed game: %w", err)
+		}
+		var matched *models.Repertoire
+		if best != nil {
+			matched = repertoiresByID[best.ID]
+		}
+		s.replaceGameResults(g.location.AnalysisID, analysis, matched)
+	}
+	return len(games), nil
+}
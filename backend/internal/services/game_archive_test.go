@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const archiveTestPGN = `[Site "https://lichess.org/abcd1234"]
+[White "alice"]
+[Black "bob"]
+
+1. e4 e5 2. Nf3 Nc6 1-0`
+
+func TestParseAndAnalyze_ArchivesRawGames(t *testing.T) {
+	var archived []repository.RawGameEntry
+	var source string
+	rawGameRepo := &mocks.MockRawGameRepo{
+		AppendFunc: func(userID, src string, games []repository.RawGameEntry) error {
+			source = src
+			archived = games
+			return nil
+		},
+	}
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+			return map[string]repository.GameLocation{"https://lichess.org/abcd1234": {AnalysisID: "analysis-0"}}, nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo,
+		WithFingerprintRepo(fingerprintRepo), WithGameArchive(rawGameRepo, &mocks.MockRecomputeJobRepo{}))
+
+	_, _, err := svc.ParseAndAnalyze("lichess_alice.pgn", "alice", "user-1", archiveTestPGN)
+
+	assert.ErrorIs(t, err, ErrAllGamesDuplicate)
+	assert.Equal(t, models.ImportSourceLichess, source)
+	require.Len(t, archived, 1, "duplicates are archived too")
+	assert.Equal(t, "https://lichess.org/abcd1234", archived[0].Fingerprint)
+	assert.Equal(t, archiveTestPGN, archived[0].PGN)
+}
+
+func TestRunRecomputeJob(t *testing.T) {
+	e4, e5, nf3 := "e4", "e5", "Nf3"
+	startFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+	e4FEN := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"
+	e5FEN := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6"
+	repertoire := models.Repertoire{ID: "rep-e4", Name: "1.e4", Color: models.ColorWhite, TreeData: models.RepertoireNode{
+		ID: "root", FEN: startFEN,
+		Children: []*models.RepertoireNode{{ID: "e4", Move: &e4, FEN: e4FEN,
+			Children: []*models.RepertoireNode{{ID: "e5", Move: &e5, FEN: e5FEN,
+				Children: []*models.RepertoireNode{{ID: "nf3", Move: &nf3}},
+			}},
+		}},
+	}}
+	repertoireRepo := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) { return []models.Repertoire{repertoire}, nil },
+	}
+
+	rawGameRepo := &mocks.MockRawGameRepo{
+		CountFunc: func(userID string) (int, error) { return 2, nil },
+		ListAfterFunc: func(userID string, after repository.RawGameKey, limit int) ([]models.RawGame, error) {
+			if after.Fingerprint != "" {
+				return nil, nil
+			}
+			return []models.RawGame{
+				{UserID: "user-1", Fingerprint: "https://lichess.org/abcd1234", PGN: archiveTestPGN},
+				{UserID: "user-1", Fingerprint: "deleted", PGN: archiveTestPGN},
+			}, nil
+		},
+	}
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+			return map[string]repository.GameLocation{"https://lichess.org/abcd1234": {AnalysisID: "analysis-1", GameIndex: 3}}, nil
+		},
+	}
+	var updated []models.GameAnalysis
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return &models.GameAnalysis{GameIndex: gameIndex, UserColor: models.ColorWhite}, nil
+		},
+		UpdateGameFunc: func(analysisID string, game models.GameAnalysis) error {
+			assert.Equal(t, "analysis-1", analysisID)
+			updated = append(updated, game)
+			return nil
+		},
+	}
+	var processed, recomputed int
+	done := false
+	jobRepo := &mocks.MockRecomputeJobRepo{
+		UpdateProgressFunc: func(id string, p, r int) error {
+			processed, recomputed = p, r
+			return nil
+		},
+		MarkDoneFunc: func(id string) error {
+			done = true
+			return nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(repertoireRepo), analysisRepo,
+		WithFingerprintRepo(fingerprintRepo), WithGameArchive(rawGameRepo, jobRepo))
+
+	err := svc.runRecomputeJob(models.RecomputeJob{ID: "job-1"})
+
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 2, processed)
+	assert.Equal(t, 1, recomputed, "games no longer stored are skipped")
+	require.Len(t, updated, 1)
+	game := updated[0]
+	assert.Equal(t, 3, game.GameIndex)
+	assert.Equal(t, models.ColorWhite, game.UserColor)
+	require.NotNil(t, game.MatchedRepertoire)
+	assert.Equal(t, "rep-e4", game.MatchedRepertoire.ID)
+	assert.Equal(t, 2, game.MatchScore)
+	require.Len(t, game.Moves, 4)
+	assert.Equal(t, "in-repertoire", game.Moves[2].Status)
+	assert.Equal(t, "out-of-book", game.Moves[3].Status)
+}
+
+func TestQueueRecompute_Unavailable(t *testing.T) {
+	_, err := NewImportService(nil, nil).QueueRecompute("")
+
+	assert.ErrorIs(t, err, ErrRecomputeUnavailable)
+}
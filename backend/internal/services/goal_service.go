@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var (
+	ErrGoalNotFound     = fmt.Errorf("goal %w", ErrNotFound)
+	ErrInvalidGoalType  = fmt.Errorf("invalid goal type. must be 'explorer_coverage' or 'weekly_reviews'")
+	ErrInvalidGoalValue = fmt.Errorf("invalid goal target or depth")
+	ErrGoalLimit        = fmt.Errorf("maximum goal limit reached (%d)", config.MaxGoalsPerUser)
+)
+
+const (
+	goalRefreshInterval = time.Hour
+	goalSummaryInterval = 7 * 24 * time.Hour
+	reviewWindow        = 7 * 24 * time.Hour
+)
+
+// GoalService manages repertoire training goals and their progress
+type GoalService struct {
+	goalRepo       repository.GoalRepository
+	repertoireRepo repository.RepertoireRepository
+	trainingRepo   repository.TrainingRepository
+	coverage       CoverageCalculator
+	userRepo       repository.UserRepository
+	emailService   EmailSender
+}
+
+// NewGoalService creates a new goal service
+func NewGoalService(goalRepo repository.GoalRepository, repertoireRepo repository.RepertoireRepository, trainingRepo repository.TrainingRepository, coverage CoverageCalculator) *GoalService {
+	return &GoalService{
+		goalRepo:       goalRepo,
+		repertoireRepo: repertoireRepo,
+		trainingRepo:   trainingRepo,
+		coverage:       coverage,
+	}
+}
+
+// WithWeeklySummaries enables weekly goal summary emails
+func (s *GoalService) WithWeeklySummaries(userRepo repository.UserRepository, emailService EmailSender) {
+	s.userRepo = userRepo
+	s.emailService = emailService
+}
+
+// CreateGoal validates and creates a goal on a repertoire
+func (s *GoalService) CreateGoal(userID, repertoireID string, req models.CreateGoalRequest) (*models.Goal, error) {
+	switch req.Type {
+	case models.GoalTypeExplorerCoverage:
+		if req.Depth == 0 {
+			req.Depth = config.DefaultGoalDepth
+		}
+		if req.Target <= 0 || req.Target > 100 || req.Depth < 1 || req.Depth > config.MaxGoalDepth {
+			return nil, ErrInvalidGoalValue
+		}
+	case models.GoalTypeWeeklyReviews:
+		if req.Target < 1 {
+			return nil, ErrInvalidGoalValue
+		}
+		req.Depth = 0
+	default:
+		return nil, ErrInvalidGoalType
+	}
+
+	count, err := s.goalRepo.CountByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count goals: %w", err)
+	}
+	if count >= config.MaxGoalsPerUser {
+		return nil, ErrGoalLimit
+	}
+
+	return s.goalRepo.Create(userID, repertoireID, req.Type, req.Target, req.Depth)
+}
+
+// ListGoals returns all goals of a user
+func (s *GoalService) ListGoals(userID string) ([]models.Goal, error) {
+	return s.goalRepo.GetByUser(userID)
+}
+
+// CheckOwnership verifies that a goal belongs to the given user
+func (s *GoalService) CheckOwnership(id, userID string) error {
+	belongs, err := s.goalRepo.BelongsToUser(id, userID)
+	return ownershipGuard(belongs, err, ErrGoalNotFound)
+}
+
+// DeleteGoal deletes a goal
+func (s *GoalService) DeleteGoal(id string) error {
+	err := s.goalRepo.Delete(id)
+	if err == repository.ErrGoalNotFound {
+		return ErrGoalNotFound
+	}
+	return err
+}
+
+// RunWorker periodically recomputes goal progress and sends weekly summaries
+func (s *GoalService) RunWorker(ctx context.Context) {
+	log.Println("goals: worker started")
+	ticker := time.NewTicker(goalRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("goals: worker stopped")
+			return
+		case <-ticker.C:
+			s.refreshAll(time.Now())
+		}
+	}
+}
+
+func (s *GoalService) refreshAll(now time.Time) {
+	goals, err := s.goalRepo.GetAll()
+	if err != nil {
+		log.Printf("goals: failed to load goals: %v", err)
+		return
+	}
+
+	byUser := make(map[string][]models.Goal)
+	var userOrder []string
+	for _, goal := range goals {
+		progress, err := s.computeProgress(goal, now)
+		if err != nil {
+			log.Printf("goals: failed to compute progress for %s: %v", goal.ID, err)
+		} else {
+			goal.Progress = progress
+			goal.Achieved = progress >= goal.Target
+			if err := s.goalRepo.UpdateProgress(goal.ID, goal.Progress, goal.Achieved); err != nil {
+				log.Printf("goals: failed to save progress for %s: %v", goal.ID, err)
+			}
+		}
+
+		if _, ok := byUser[goal.UserID]; !ok {
+			userOrder = append(userOrder, goal.UserID)
+		}
+		byUser[goal.UserID] = append(byUser[goal.UserID], goal)
+	}
+
+	for _, userID := range userOrder {
+		s.maybeSendSummary(userID, byUser[userID], now)
+	}
+}
+
+// computeProgress measures a goal against current data
+func (s *GoalService) computeProgress(goal models.Goal, now time.Time) (float64, error) {
+	switch goal.Type {
+	case models.GoalTypeExplorerCoverage:
+		rep, err := s.repertoireRepo.GetByID(goal.RepertoireID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get repertoire: %w", err)
+		}
+		return s.coverage.ExplorerCoverage(rep.TreeData, rep.Color, goal.Depth)
+	case models.GoalTypeWeeklyReviews:
+		recall, err := s.trainingRepo.GetRecall(goal.UserID, goal.RepertoireID, now.Add(-reviewWindow))
+		if err != nil {
+			return 0, err
+		}
+		return float64(recall.Reviews), nil
+	default:
+		return 0, ErrInvalidGoalType
+	}
+}
+
+// maybeSendSummary emails a user their goals if the last summary is older than a week
+func (s *GoalService) maybeSendSummary(userID string, goals []models.Goal, now time.Time) {
+	if s.emailService == nil || s.userRepo == nil || !s.emailService.Enabled() {
+		return
+	}
+
+	var lastSent *time.Time
+	for _, g := range goals {
+		if g.LastSummaryAt != nil && (lastSent == nil || g.LastSummaryAt.After(*lastSent)) {
+			lastSent = g.LastSummaryAt
+		}
+	}
+	if lastSent != nil && now.Sub(*lastSent) < goalSummaryInterval {
+		return
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || user.Email == nil || *user.Email == "" {
+		return
+	}
+
+	if err := s.emailService.SendGoalSummaryEmail(*user.Email, goals); err != nil {
+		log.Printf("goals: failed to send summary to user %s: %v", userID, err)
+		return
+	}
+	if err := s.goalRepo.MarkSummarySent(userID, now); err != nil {
+		log.Printf("goals: failed to mark summary sent for user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+type fakeCoverage struct {
+	value float64
+	depth int
+}
+
+func (f *fakeCoverage) ExplorerCoverage(root models.RepertoireNode, color models.Color, maxDepth int) (float64, error) {
+	f.depth = maxDepth
+	return f.value, nil
+}
+
+func TestCreateGoal_Validation(t *testing.T) {
+	svc := NewGoalService(&mocks.MockGoalRepo{}, &mocks.MockRepertoireRepo{}, &mocks.MockTrainingRepo{}, &fakeCoverage{})
+
+	_, err := svc.CreateGoal("user-1", "rep-1", models.CreateGoalRequest{Type: "unknown", Target: 1})
+	assert.ErrorIs(t, err, ErrInvalidGoalType)
+
+	_, err = svc.CreateGoal("user-1", "rep-1", models.CreateGoalRequest{Type: models.GoalTypeExplorerCoverage, Target: 120})
+	assert.ErrorIs(t, err, ErrInvalidGoalValue)
+
+	_, err = svc.CreateGoal("user-1", "rep-1", models.CreateGoalRequest{Type: models.GoalTypeWeeklyReviews, Target: 0})
+	assert.ErrorIs(t, err, ErrInvalidGoalValue)
+}
+
+func TestCreateGoal_DefaultDepth(t *testing.T) {
+	svc := NewGoalService(&mocks.MockGoalRepo{}, &mocks.MockRepertoireRepo{}, &mocks.MockTrainingRepo{}, &fakeCoverage{})
+
+	goal, err := svc.CreateGoal("user-1", "rep-1", models.CreateGoalRequest{Type: models.GoalTypeExplorerCoverage, Target: 95})
+
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultGoalDepth, goal.Depth)
+}
+
+func TestCreateGoal_LimitReached(t *testing.T) {
+	goalRepo := &mocks.MockGoalRepo{
+		CountByUserFunc: func(userID string) (int, error) { return config.MaxGoalsPerUser, nil },
+	}
+	svc := NewGoalService(goalRepo, &mocks.MockRepertoireRepo{}, &mocks.MockTrainingRepo{}, &fakeCoverage{})
+
+	_, err := svc.CreateGoal("user-1", "rep-1", models.CreateGoalRequest{Type: models.GoalTypeWeeklyReviews, Target: 20})
+
+	assert.ErrorIs(t, err, ErrGoalLimit)
+}
+
+func TestRefreshAll_UpdatesProgressAndSendsSummary(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	goals := []models.Goal{
+		{ID: "g1", UserID: "user-1", RepertoireID: "rep-1", Type: models.GoalTypeExplorerCoverage, Target: 90, Depth: 6},
+		{ID: "g2", UserID: "user-1", RepertoireID: "rep-1", Type: models.GoalTypeWeeklyReviews, Target: 5},
+	}
+	progress := map[string]float64{}
+	achieved := map[string]bool{}
+	var summarySentAt time.Time
+	goalRepo := &mocks.MockGoalRepo{
+		GetAllFunc: func() ([]models.Goal, error) { return goals, nil },
+		UpdateProgressFunc: func(id string, p float64, a bool) error {
+			progress[id] = p
+			achieved[id] = a
+			return nil
+		},
+		MarkSummarySentFunc: func(userID string, sentAt time.Time) error {
+			summarySentAt = sentAt
+			return nil
+		},
+	}
+	repertoireRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite}, nil
+		},
+	}
+	var reviewSince time.Time
+	trainingRepo := &mocks.MockTrainingRepo{
+		GetRecallFunc: func(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error) {
+			reviewSince = since
+			return &models.TrainingRecall{Reviews: 7, Correct: 5}, nil
+		},
+	}
+	coverage := &fakeCoverage{value: 80}
+	email := "me@example.com"
+	var sentGoals []models.Goal
+	emailSvc := &mocks.MockEmailService{
+		SendGoalSummaryEmailFunc: func(toEmail string, g []models.Goal) error {
+			sentGoals = g
+			return nil
+		},
+	}
+	userRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) { return &models.User{ID: id, Email: &email}, nil },
+	}
+
+	svc := NewGoalService(goalRepo, repertoireRepo, trainingRepo, coverage)
+	svc.WithWeeklySummaries(userRepo, emailSvc)
+	svc.refreshAll(now)
+
+	assert.Equal(t, 6, coverage.depth)
+	assert.Equal(t, 80.0, progress["g1"])
+	assert.False(t, achieved["g1"])
+	assert.Equal(t, 7.0, progress["g2"])
+	assert.True(t, achieved["g2"])
+	assert.Equal(t, now.Add(-7*24*time.Hour), reviewSince)
+	assert.Len(t, sentGoals, 2)
+	assert.Equal(t, now, summarySentAt)
+}
+
+func TestRefreshAll_SkipsRecentSummary(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	lastSent := now.Add(-24 * time.Hour)
+	goalRepo := &mocks.MockGoalRepo{
+		GetAllFunc: func() ([]models.Goal, error) {
+			return []models.Goal{{ID: "g1", UserID: "user-1", Type: models.GoalTypeWeeklyReviews, Target: 5, LastSummaryAt: &lastSent}}, nil
+		},
+	}
+	sent := false
+	emailSvc := &mocks.MockEmailService{
+		SendGoalSummaryEmailFunc: func(toEmail string, g []models.Goal) error {
+			sent = true
+			return nil
+		},
+	}
+
+	svc := NewGoalService(goalRepo, &mocks.MockRepertoireRepo{}, &mocks.MockTrainingRepo{}, &fakeCoverage{})
+	svc.WithWeeklySummaries(&mocks.MockUserRepo{}, emailSvc)
+	svc.refreshAll(now)
+
+	assert.False(t, sent)
+}
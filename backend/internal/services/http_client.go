@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/treechess/backend/config"
+)
+
+// ErrCircuitOpen is returned when an upstream host failed repeatedly and requests to it are paused
+var ErrCircuitOpen = errors.New("upstream temporarily unavailable")
+
+// sharedTransport is used by every outgoing client so that concurrency caps and
+// circuit breakers apply per host across the Lichess, Chess.com and Explorer clients.
+var sharedTransport = newResilientTransport(http.DefaultTransport)
+
+// newResilientHTTPClient returns an HTTP client backed by the shared resilient transport
+func newResilientHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: sharedTransport,
+	}
+}
+
+// resilientTransport retries transient failures with jittered backoff, honours Retry-After,
+// caps concurrent requests per host and stops calling hosts that keep failing.
+type resilientTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	maxPerHost int
+	threshold  int
+	cooldown   time.Duration
+	idle       time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+	now        func() time.Time
+
+	mu        sync.Mutex
+	hosts     map[string]*hostState
+	lastSweep time.Time
+}
+
+// hostState holds the concurrency slots and circuit breaker of a single host
+type hostState struct {
+	slots     chan struct{}
+	failures  int
+	openUntil time.Time
+	lastUsed  time.Time
+}
+
+func newResilientTransport(base http.RoundTripper) *resilientTransport {
+	return &resilientTransport{
+		base:       base,
+		maxRetries: config.MaxHTTPRetries,
+		baseDelay:  config.HTTPRetryBaseDelay,
+		maxDelay:   config.MaxHTTPRetryDelay,
+		maxPerHost: config.MaxHTTPRequestsPerHost,
+		threshold:  config.CircuitBreakerThreshold,
+		cooldown:   config.CircuitBreakerCooldown,
+		idle:       config.HTTPHostIdleTimeout,
+		sleep:      sleepContext,
+		now:        time.Now,
+		hosts:      make(map[string]*hostState),
+	}
+}
+
+func (t *resilientTransport) host(name string) *hostState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if now.Sub(t.lastSweep) >= t.idle {
+		t.evictIdle(now)
+	}
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, t.maxPerHost)}
+		t.hosts[name] = h
+	}
+	h.lastUsed = now
+	return h
+}
+
+// evictIdle drops the state of hosts unused for the idle timeout, so that calls to arbitrary
+// hosts do not grow the map forever. Hosts with requests in flight or an open circuit are kept.
+// t.mu must be held.
+func (t *resilientTransport) evictIdle(now time.Time) {
+	for name, h := range t.hosts {
+		if len(h.slots) == 0 && !now.Before(h.openUntil) && now.Sub(h.lastUsed) >= t.idle {
+			delete(t.hosts, name)
+		}
+	}
+	t.lastSweep = now
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.host(req.URL.Host)
+	ctx := req.Context()
+
+	// Requests with a body can only be replayed when it can be recreated
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if err := t.allow(h); err != nil {
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, err)
+		}
+
+		// A RoundTripper must not modify the request, so retries send a copy with a fresh body
+		attemptReq := req
+		if attempt > 0 {
+			attemptReq = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := t.send(ctx, h, attemptReq)
+		t.record(h, err == nil && resp.StatusCode < http.StatusInternalServerError)
+
+		if !isRetryable(resp, err) || !replayable || attempt >= t.maxRetries {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send performs one attempt while holding a concurrency slot of the host.
+// The slot is released once the response body is closed.
+func (t *resilientTransport) send(ctx context.Context, h *hostState, req *http.Request) (*http.Response, error) {
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-h.slots
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() { <-h.slots }}
+	return resp, nil
+}
+
+// allow rejects requests while the host's circuit is open
+func (t *resilientTransport) allow(h *hostState) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.now().Before(h.openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record updates the circuit breaker: consecutive failures open the circuit for the cooldown period
+func (t *resilientTransport) record(h *hostState, success bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if success {
+		h.failures = 0
+		return
+	}
+	h.failures++
+	if h.failures >= t.threshold {
+		h.openUntil = t.now().Add(t.cooldown)
+		h.failures = 0
+	}
+}
+
+// backoff returns how long to wait before the next attempt, preferring the server's Retry-After
+func (t *resilientTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.now()); ok {
+			if d > t.maxDelay {
+				d = t.maxDelay
+			}
+			return d
+		}
+	}
+
+	d := t.baseDelay << attempt
+	if d > t.maxDelay || d <= 0 {
+		d = t.maxDelay
+	}
+	// Full jitter spreads retries from concurrent syncs
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// isRetryable reports whether an attempt failed transiently
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := at.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseOnClose frees a host concurrency slot when the response body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
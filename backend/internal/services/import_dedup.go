@@ -0,0 +1,30 @@
+package services
+
+import (
+	"maps"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// DedupProgress reports how many of the fingerprints of an import were checked against the
+// games already imported, out of total
+type DedupProgress func(checked, total int)
+
+// checkExistingFingerprints looks up the stored games matching fingerprints in batches of
+// config.FingerprintProgressBatch, reporting progress after each batch when progress is set
+func (s *ImportService) checkExistingFingerprints(userID string, fingerprints []string, progress DedupProgress) (map[string]repository.GameLocation, error) {
+	existing := make(map[string]repository.GameLocation)
+	for start := 0; start < len(fingerprints); start += config.FingerprintProgressBatch {
+		end := min(start+config.FingerprintProgressBatch, len(fingerprints))
+		batch, err := s.fingerprintRepo.CheckExisting(userID, fingerprints[start:end])
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(existing, batch)
+		if progress != nil {
+			progress(end, len(fingerprints))
+		}
+	}
+	return existing, nil
+}
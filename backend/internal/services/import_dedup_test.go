@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestCheckExistingFingerprints_Batches(t *testing.T) {
+	fingerprints := make([]string, config.FingerprintProgressBatch+1)
+	for i := range fingerprints {
+		fingerprints[i] = fmt.Sprintf("fp-%d", i)
+	}
+
+	var batches []int
+	svc := NewImportService(nil, &mocks.MockAnalysisRepo{}, WithFingerprintRepo(&mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, batch []string) (map[string]repository.GameLocation, error) {
+			batches = append(batches, len(batch))
+			if batch[0] == "fp-0" {
+				return map[string]repository.GameLocation{"fp-0": {AnalysisID: "analysis-1"}}, nil
+			}
+			return map[string]repository.GameLocation{batch[0]: {AnalysisID: "analysis-2", GameIndex: 3}}, nil
+		},
+	}))
+
+	var progress [][2]int
+	existing, err := svc.checkExistingFingerprints("user-1", fingerprints, func(checked, total int) {
+		progress = append(progress, [2]int{checked, total})
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{config.FingerprintProgressBatch, 1}, batches)
+	assert.Equal(t, map[string]repository.GameLocation{
+		"fp-0": {AnalysisID: "analysis-1"},
+		fingerprints[config.FingerprintProgressBatch]: {AnalysisID: "analysis-2", GameIndex: 3},
+	}, existing)
+	assert.Equal(t, [][2]int{
+		{config.FingerprintProgressBatch, len(fingerprints)},
+		{len(fingerprints), len(fingerprints)},
+	}, progress)
+}
+
+func TestCheckExistingFingerprints_Error(t *testing.T) {
+	svc := NewImportService(nil, &mocks.MockAnalysisRepo{}, WithFingerprintRepo(&mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, batch []string) (map[string]repository.GameLocation, error) {
+			return nil, fmt.Errorf("db down")
+		},
+	}))
+
+	_, err := svc.checkExistingFingerprints("user-1", []string{"fp-0"}, func(checked, total int) {
+		t.Fatal("progress reported for a failed batch")
+	})
+
+	assert.EqualError(t, err, "db down")
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrImportJobsUnavailable is returned when background database imports are not configured
+var ErrImportJobsUnavailable = fmt.Errorf("database imports are not available")
+
+// ErrImportTooLarge is returned when an uploaded PGN database exceeds config.MaxPGNDatabaseSize
+var ErrImportTooLarge = fmt.Errorf("PGN database exceeds maximum allowed size")
+
+// WithImportJobRepo enables background imports of large PGN databases, spooled to spoolDir
+func WithImportJobRepo(repo repository.ImportJobRepository, spoolDir string) ImportServiceOption {
+	return func(s *ImportService) {
+		s.importJobRepo = repo
+		s.importSpoolDir = spoolDir
+	}
+}
+
+// SpoolPGN streams an uploaded PGN database to the spool directory and returns the file path
+func (s *ImportService) SpoolPGN(src io.Reader) (string, error) {
+	if s.importJobRepo == nil {
+		return "", ErrImportJobsUnavailable
+	}
+	if err := os.MkdirAll(s.importSpoolDir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(s.importSpoolDir, "import-*.pgn")
+	if err != nil {
+		return "", fmt.Errorf("failed to create spool file: %w", err)
+	}
+	written, copyErr := io.Copy(f, io.LimitReader(src, config.MaxPGNDatabaseSize+1))
+	closeErr := f.Close()
+
+	switch {
+	case copyErr != nil:
+		s.DiscardSpooledPGN(f.Name())
+		return "", fmt.Errorf("failed to spool PGN: %w", copyErr)
+	case closeErr != nil:
+		s.DiscardSpooledPGN(f.Name())
+		return "", fmt.Errorf("failed to spool PGN: %w", closeErr)
+	case written > config.MaxPGNDatabaseSize:
+		s.DiscardSpooledPGN(f.Name())
+		return "", ErrImportTooLarge
+	}
+	return f.Name(), nil
+}
+
+// DiscardSpooledPGN removes a spooled file that will not be imported
+func (s *ImportService) DiscardSpooledPGN(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("import: failed to remove spooled file %s: %v", path, err)
+	}
+}
+
+// QueueDatabaseImport queues a background import of a spooled PGN database
+func (s *ImportService) QueueDatabaseImport(userID, username, filename string, policy models.DuplicatePolicy, path string) (*models.ImportJob, error) {
+	if s.importJobRepo == nil {
+		return nil, ErrImportJobsUnavailable
+	}
+	job, err := s.importJobRepo.Create(models.ImportJob{
+		UserID:          userID,
+		Username:        username,
+		Filename:        filename,
+		DuplicatePolicy: policy,
+		FilePath:        path,
+	})
+	if err != nil {
+		s.DiscardSpooledPGN(path)
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetImportJob returns an import job, hiding jobs of other users
+func (s *ImportService) GetImportJob(id, userID string) (*models.ImportJob, error) {
+	if s.importJobRepo == nil {
+		return nil, repository.ErrImportJobNotFound
+	}
+	job, err := s.importJobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, repository.ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+// RunImportJobWorker polls for pending database imports and processes them one at a time
+func (s *ImportService) RunImportJobWorker(ctx context.Context) {
+	log.Println("import: worker started")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("import: worker stopped")
+			return
+		case <-ticker.C:
+			s.processImportJobs()
+		}
+	}
+}
+
+func (s *ImportService) processImportJobs() {
+	jobs, err := s.importJobRepo.GetPending(1)
+	if err != nil {
+		log.Printf("import: failed to get pending jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := s.runImportJob(job); err != nil {
+			log.Printf("import: job %s failed: %v", job.ID, err)
+			if markErr := s.importJobRepo.MarkFailed(job.ID, err.Error()); markErr != nil {
+				log.Printf("import: failed to mark job %s as failed: %v", job.ID, markErr)
+			}
+		}
+	}
+}
+
+// runImportJob imports the spooled file chunk by chunk, recording progress after each chunk
+// and as the games of a chunk are checked for duplicates.
+// Unparseable games are reported by index and do not stop the import. The spooled file is
+// removed once the job finishes, whatever the outcome.
+func (s *ImportService) runImportJob(job models.ImportJob) error {
+	defer s.DiscardSpooledPGN(job.FilePath)
+
+	total, err := countSpooledGames(job.FilePath)
+	if err != nil {
+		return err
+	}
+	chunks := (total + config.ImportChunkSize - 1) / config.ImportChunkSize
+	if err := s.importJobRepo.MarkProcessing(job.ID, total, chunks); err != nil {
+		return fmt.Errorf("failed to mark job as processing: %w", err)
+	}
+
+	f, err := os.Open(job.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open spooled file: %w", err)
+	}
+	defer f.Close()
+
+	var chunk []string
+	firstIndex, imported, checked := 0, 0, 0
+	flush := func() error {
+		chunkChecked := 0
+		result := s.importChunk(job, chunk, firstIndex, func(done, _ int) {
+			chunkChecked = done
+			if err := s.importJobRepo.RecordDedupProgress(job.ID, checked+done); err != nil {
+				log.Printf("import: failed to record dedup progress of job %s: %v", job.ID, err)
+			}
+		})
+		if err := s.importJobRepo.RecordChunk(job.ID, result); err != nil {
+			return err
+		}
+		imported += result.Imported
+		checked += chunkChecked
+		firstIndex += len(chunk)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	err = scanRawPGNGames(f, func(game string) error {
+		chunk = append(chunk, game)
+		if len(chunk) < config.ImportChunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.importJobRepo.MarkDone(job.ID); err != nil {
+		return fmt.Errorf("failed to mark job as done: %w", err)
+	}
+	notifyAsync(s.notifications, job.UserID, importCompletedNotification(job.Filename, imported))
+	return nil
+}
+
+// importChunk imports the parseable games of a chunk as one analysis. firstIndex is the
+// position of the chunk's first game in the file, used to report failures.
+func (s *ImportService) importChunk(job models.ImportJob, games []string, firstIndex int, progress DedupProgress) models.ImportChunkResult {
+	var result models.ImportChunkResult
+	var valid []string
+	var validIndices []int
+	for i, game := range games {
+		if err := checkRawGame(game); err != nil {
+			result.Failed = append(result.Failed, models.ImportFailure{GameIndex: firstIndex + i, Error: err.Error()})
+			continue
+		}
+		valid = append(valid, game)
+		validIndices = append(validIndices, firstIndex+i)
+	}
+	if len(valid) == 0 {
+		return result
+	}
+
+	summary, _, err := s.ParseAndAnalyzeWithOptions(job.Filename, job.Username, job.UserID, strings.Join(valid, "\n\n"), ImportOptions{
+		DuplicatePolicy: job.DuplicatePolicy,
+		Provenance: models.ImportProvenance{
+			Source: models.ImportSourcePGN,
+			SourceParams: models.ImportSourceParams{
+				Username:        job.Username,
+				Filename:        job.Filename,
+				DuplicatePolicy: job.DuplicatePolicy,
+			},
+		},
+		DedupProgress: progress,
+	})
+	switch {
+	case errors.Is(err, ErrAllGamesDuplicate), errors.Is(err, ErrNoUserGames), errors.Is(err, ErrOnlyVariantGames):
+		result.Skipped = len(valid)
+	case err != nil:
+		for _, index := range validIndices {
+			result.Failed = append(result.Failed, models.ImportFailure{GameIndex: index, Error: err.Error()})
+		}
+	default:
+		result.AnalysisID = summary.ID
+		result.Imported = summary.GameCount
+		result.Skipped = len(valid) - summary.GameCount
+	}
+	return result
+}
+
+// checkRawGame reports why a single-game PGN cannot be imported, or nil if it can
+func checkRawGame(rawGame string) error {
+	err := fmt.Errorf("game has no moves")
+	for _, candidate := range localizedPGNCandidates(rawGame) {
+		parsed, parseErr := chess.GamesFromPGN(strings.NewReader(candidate))
+		if parseErr != nil {
+			err = parseErr
+			continue
+		}
+		for _, game := range parsed {
+			if len(game.Moves()) > 0 {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+func countSpooledGames(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open spooled file: %w", err)
+	}
+	defer f.Close()
+
+	count := 0
+	err = scanRawPGNGames(f, func(string) error {
+		count++
+		return nil
+	})
+	return count, err
+}
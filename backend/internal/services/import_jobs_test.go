@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const databasePGN = `[White "me"]
+[Black "opponent"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 1-0
+
+[White "opponent"]
+[Black "me"]
+[Result "0-1"]
+
+0-1
+
+[White "opponent"]
+[Black "me"]
+[Result "1/2-1/2"]
+
+1. d4 d5 1/2-1/2
+`
+
+func TestScanRawPGNGames_StopsOnCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	seen := 0
+
+	err := scanRawPGNGames(strings.NewReader(databasePGN), func(string) error {
+		seen++
+		return stop
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, seen)
+}
+
+func TestSpoolPGN(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	svc := NewImportService(nil, nil, WithImportJobRepo(&mocks.MockImportJobRepo{}, dir))
+
+	path, err := svc.SpoolPGN(strings.NewReader(databasePGN))
+
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, databasePGN, string(data))
+}
+
+func TestSpoolPGN_Unavailable(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	_, err := svc.SpoolPGN(strings.NewReader(databasePGN))
+
+	assert.ErrorIs(t, err, ErrImportJobsUnavailable)
+}
+
+func TestRunImportJob_ReportsUnparseableGames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.pgn")
+	require.NoError(t, os.WriteFile(path, []byte(databasePGN), 0o600))
+
+	var chunks []models.ImportChunkResult
+	var totalGames, totalChunks int
+	var checked []int
+	done := false
+	jobRepo := &mocks.MockImportJobRepo{
+		MarkProcessingFunc: func(id string, games, chunkCount int) error {
+			totalGames, totalChunks = games, chunkCount
+			return nil
+		},
+		RecordChunkFunc: func(id string, result models.ImportChunkResult) error {
+			chunks = append(chunks, result)
+			return nil
+		},
+		RecordDedupProgressFunc: func(id string, checkedGames int) error {
+			checked = append(checked, checkedGames)
+			return nil
+		},
+		MarkDoneFunc: func(id string) error {
+			done = true
+			return nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo,
+		WithImportJobRepo(jobRepo, t.TempDir()), WithFingerprintRepo(&mocks.MockFingerprintRepo{}))
+
+	err := svc.runImportJob(models.ImportJob{
+		ID: "job-1", UserID: "user-1", Username: "me", Filename: "db.pgn",
+		DuplicatePolicy: models.DuplicatePolicySkip, FilePath: path,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 3, totalGames)
+	assert.Equal(t, 1, totalChunks)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "analysis-1", chunks[0].AnalysisID)
+	assert.Equal(t, 2, chunks[0].Imported)
+	require.Len(t, chunks[0].Failed, 1)
+	assert.Equal(t, 1, chunks[0].Failed[0].GameIndex)
+	assert.Equal(t, []int{2}, checked)
+	assert.NoFileExists(t, path)
+}
+
+func TestGetImportJob_HidesOtherUsersJobs(t *testing.T) {
+	svc := NewImportService(nil, nil, WithImportJobRepo(&mocks.MockImportJobRepo{
+		GetByIDFunc: func(id string) (*models.ImportJob, error) {
+			return &models.ImportJob{ID: id, UserID: "owner"}, nil
+		},
+	}, t.TempDir()))
+
+	_, err := svc.GetImportJob("job-1", "someone-else")
+	assert.Error(t, err)
+
+	job, err := svc.GetImportJob("job-1", "owner")
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", job.ID)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// PreviewImport reports what importing pgnData would do, without saving anything: how many games
+// are new, were already imported, or were not played by the user. Games are not analyzed.
+func (s *ImportService) PreviewImport(userID, username, pgnData string) (*models.ImportPreview, error) {
+	games, err := s.parsePGN(pgnData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PGN: %w", err)
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("no games found in PGN")
+	}
+
+	usernames, err := s.playerUsernames(userID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.ImportPreview{TotalGames: len(games)}
+	var fingerprints []string
+	for _, game := range games {
+		switch s.determineUserColor(game, usernames...) {
+		case models.ColorWhite:
+			preview.AsWhite++
+		case models.ColorBlack:
+			preview.AsBlack++
+		default:
+			preview.NotPlayedByUser++
+			continue
+		}
+		fingerprints = append(fingerprints, ComputeFingerprint(s.extractHeaders(game), fingerprintMoves(game)))
+	}
+
+	if s.fingerprintRepo != nil && len(fingerprints) > 0 {
+		existing, err := s.checkExistingFingerprints(userID, fingerprints, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check fingerprints: %w", err)
+		}
+		for _, fingerprint := range fingerprints {
+			if _, ok := existing[fingerprint]; ok {
+				preview.Duplicates++
+			}
+		}
+	}
+	preview.NewGames = len(fingerprints) - preview.Duplicates
+	return preview, nil
+}
+
+// fingerprintMoves returns the opening moves ComputeFingerprint hashes, without analyzing the game
+func fingerprintMoves(game *chess.Game) []models.MoveAnalysis {
+	var moves []models.MoveAnalysis
+	position := gameStartPosition(game)
+	notation := chess.AlgebraicNotation{}
+	for ply, move := range game.Moves() {
+		if ply >= fingerprintPlies {
+			break
+		}
+		moves = append(moves, models.MoveAnalysis{PlyNumber: ply, SAN: notation.Encode(position, move)})
+		position = position.Update(move)
+	}
+	return moves
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const previewPGN = `[Site "https://lichess.org/newgame1"]
+[White "me"]
+[Black "opponent"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 1-0
+
+[Site "https://lichess.org/dupgame1"]
+[White "opponent"]
+[Black "me"]
+[Result "0-1"]
+
+1. d4 d5 0-1
+
+[Site "https://lichess.org/other1"]
+[White "someone"]
+[Black "else"]
+[Result "1/2-1/2"]
+
+1. c4 c5 1/2-1/2
+`
+
+func TestPreviewImport(t *testing.T) {
+	var checked []string
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+			checked = fingerprints
+			existing := make(map[string]repository.GameLocation)
+			for _, fp := range fingerprints {
+				if strings.Contains(fp, "dupgame1") {
+					existing[fp] = repository.GameLocation{AnalysisID: "old-analysis"}
+				}
+			}
+			return existing, nil
+		},
+		SaveBatchFunc: func(userID, analysisID string, entries []repository.FingerprintEntry) error {
+			t.Fatal("a preview must not save fingerprints")
+			return nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			t.Fatal("a preview must not save games")
+			return nil, nil
+		},
+	}
+	svc := NewImportService(nil, analysisRepo, WithFingerprintRepo(fingerprintRepo))
+
+	preview, err := svc.PreviewImport("user-1", "me", previewPGN)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportPreview{
+		TotalGames:      3,
+		NewGames:        1,
+		Duplicates:      1,
+		NotPlayedByUser: 1,
+		AsWhite:         1,
+		AsBlack:         1,
+	}, *preview)
+	assert.Len(t, checked, 2)
+}
+
+func TestFingerprintMoves_MatchesAnalyzedGame(t *testing.T) {
+	svc := NewImportService(nil, nil)
+	games, err := svc.parsePGN("[White \"me\"]\n[Black \"opponent\"]\n\n1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 1-0")
+	require.NoError(t, err)
+	game := games[0]
+
+	analysis := svc.analyzeGame(0, game, models.RepertoireNode{}, models.ColorWhite, 0)
+
+	assert.Len(t, fingerprintMoves(game), fingerprintPlies)
+	assert.Equal(t,
+		ComputeFingerprint(analysis.Headers, analysis.Moves),
+		ComputeFingerprint(svc.extractHeaders(game), fingerprintMoves(game)))
+}
@@ -1,12 +1,21 @@
 package services
 
 import (
+	"bufio"
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/notnil/chess"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 )
@@ -14,13 +23,42 @@ import (
 // ErrAllGamesDuplicate is returned when all games in an import already exist
 var ErrAllGamesDuplicate = fmt.Errorf("all games have already been imported")
 
+// ErrNoUserGames is returned when none of the imported games was played by the user
+var ErrNoUserGames = fmt.Errorf("no games found where the user was a player")
+
+// ErrEngineUnavailable is returned when opening analysis is not configured
+var ErrEngineUnavailable = fmt.Errorf("opening analysis is not available")
+
+// ErrGameNoteTooLong is returned when a game note exceeds config.MaxGameNoteLen
+var ErrGameNoteTooLong = fmt.Errorf("note must be at most %d characters", config.MaxGameNoteLen)
+
+// ErrEmptyGameUpdate is returned when a game update sets neither the note nor the starred flag
+var ErrEmptyGameUpdate = fmt.Errorf("note or starred is required")
+
 // ImportService handles game import and analysis business logic
 type ImportService struct {
-	repertoireService    *RepertoireService
-	analysisRepo         repository.AnalysisRepository
-	fingerprintRepo      repository.GameFingerprintRepository
-	engineService        *EngineService
-	dismissedMistakeRepo repository.DismissedMistakeRepository
+	repertoireService      *RepertoireService
+	analysisRepo           repository.AnalysisRepository
+	fingerprintRepo        repository.GameFingerprintRepository
+	engineService          *EngineService
+	dismissedMistakeRepo   repository.DismissedMistakeRepository
+	userRepo               repository.UserRepository
+	reanalysisJobRepo      repository.ReanalysisJobRepository
+	gameResultRepo         repository.GameResultRepository
+	opponentReplyRepo      repository.OpponentReplyRepository
+	importJobRepo          repository.ImportJobRepository
+	insightSettingsRepo    repository.InsightSettingsRepository
+	classificationRuleRepo repository.ClassificationRuleRepository
+	teamImportRepo         repository.TeamImportRepository
+	importSummaryRepo      repository.ImportSummaryRepository
+	rawGameRepo            repository.RawGameRepository
+	recomputeJobRepo       repository.RecomputeJobRepository
+	usage                  *UsageService
+	notifications          *NotificationService
+	teamFetcher            LichessTeamFetcher
+	teamMemberDelay        time.Duration
+	importSpoolDir         string
+	analysisWorkers        int
 }
 
 // NewImportService creates a new import service with the given dependencies
@@ -59,92 +97,274 @@ func WithDismissedMistakeRepo(repo repository.DismissedMistakeRepository) Import
 	}
 }
 
-// ParseAndAnalyze parses PGN data and analyzes games against repertoires
-func (s *ImportService) ParseAndAnalyze(filename string, username string, userID string, pgnData string) (*models.AnalysisSummary, []models.GameAnalysis, error) {
-	games, err := s.parsePGN(pgnData)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse PGN: %w", err)
+// WithUserRepo lets the ImportService recognise games played on any of the user's linked accounts
+func WithUserRepo(repo repository.UserRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.userRepo = repo
+	}
+}
+
+// WithReanalysisJobRepo enables background re-analysis of all games matched to a repertoire
+func WithReanalysisJobRepo(repo repository.ReanalysisJobRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.reanalysisJobRepo = repo
 	}
+}
 
+// ParseAndAnalyze parses PGN data and analyzes games against repertoires, skipping games that were already imported
+func (s *ImportService) ParseAndAnalyze(filename string, username string, userID string, pgnData string) (*models.AnalysisSummary, []models.GameAnalysis, error) {
+	return s.ParseAndAnalyzeWithPolicy(filename, username, userID, pgnData, models.DuplicatePolicySkip)
+}
+
+// ImportOptions tunes how the games of one import are analyzed
+type ImportOptions struct {
+	DuplicatePolicy models.DuplicatePolicy
+	// AnalysisDepth, in moves, overrides the user's analysis depth for this import when set
+	AnalysisDepth *int
+	// Reference imports games the user did not play, e.g. a broadcast round. Every game is kept and
+	// seen from the side of the repertoire it follows best; duplicate detection and result tracking are skipped.
+	Reference bool
+	// Provenance is recorded on the analysis; when its source is empty it is derived from the filename
+	Provenance models.ImportProvenance
+	// MatchRepertoireIDs, when set, are the only repertoires the games are matched against,
+	// whether or not they are active for matching
+	MatchRepertoireIDs []string
+	// IncludeVariants keeps the games of chess variants, e.g. Lichess Atomic or Crazyhouse games,
+	// without matching them against the repertoires. By default they are skipped.
+	IncludeVariants bool
+	// DedupProgress, when set, is called as the games are checked against the already imported ones
+	DedupProgress DedupProgress
+}
+
+// filenameProvenance derives the provenance of an import from the filename its caller chose,
+// for the imports that do not record one
+func filenameProvenance(filename, username string) models.ImportProvenance {
+	params := models.ImportSourceParams{Username: username, Sync: strings.HasPrefix(filename, "sync_")}
+	name := strings.TrimPrefix(filename, "sync_")
+	switch {
+	case strings.HasPrefix(name, "lichess_"):
+		return models.ImportProvenance{Source: models.ImportSourceLichess, SourceParams: params}
+	case strings.HasPrefix(name, "chesscom_"):
+		return models.ImportProvenance{Source: models.ImportSourceChesscom, SourceParams: params}
+	case strings.HasPrefix(filename, teamFilenamePrefix):
+		return models.ImportProvenance{Source: models.ImportSourceTeam, SourceParams: params}
+	case strings.HasPrefix(filename, broadcastFilenamePrefix):
+		roundID := strings.TrimSuffix(strings.TrimPrefix(filename, broadcastFilenamePrefix), ".pgn")
+		return models.ImportProvenance{Source: models.ImportSourceBroadcast, SourceParams: models.ImportSourceParams{RoundID: roundID}}
+	}
+	params.Filename = filename
+	return models.ImportProvenance{Source: models.ImportSourcePGN, SourceParams: params}
+}
+
+// ParseAndAnalyzeWithPolicy is ParseAndAnalyze with a configurable treatment of already imported games.
+// With DuplicatePolicyReplace the stored copy is re-analyzed in place; when every game was a replaced
+// duplicate no new analysis is created and the returned summary has an empty ID.
+func (s *ImportService) ParseAndAnalyzeWithPolicy(filename string, username string, userID string, pgnData string, policy models.DuplicatePolicy) (*models.AnalysisSummary, []models.GameAnalysis, error) {
+	return s.ParseAndAnalyzeWithOptions(filename, username, userID, pgnData, ImportOptions{DuplicatePolicy: policy})
+}
+
+// ParseAndAnalyzeWithOptions is ParseAndAnalyzeWithPolicy with a per-import analysis depth.
+// Moves past the depth are marked beyond book without being matched against the repertoires.
+func (s *ImportService) ParseAndAnalyzeWithOptions(filename string, username string, userID string, pgnData string, opts ImportOptions) (*models.AnalysisSummary, []models.GameAnalysis, error) {
+	policy := opts.DuplicatePolicy
+	parsed := s.readPGN(pgnData)
+	if !opts.IncludeVariants {
+		parsed = s.withoutVariants(parsed)
+	}
+	games, annotations := parsed.games, parsed.annotations
 	if len(games) == 0 {
+		if parsed.skippedVariants() > 0 {
+			return nil, nil, ErrOnlyVariantGames
+		}
 		return nil, nil, fmt.Errorf("no games found in PGN")
 	}
+	warnings := parsed.warnings
 
 	// Get all repertoires upfront
 	whiteColor := models.ColorWhite
 	blackColor := models.ColorBlack
-	whiteRepertoires, err := s.repertoireService.ListRepertoires(userID, &whiteColor)
+	whiteRepertoires, err := s.repertoireService.CachedRepertoires(userID, &whiteColor)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get white repertoires: %w", err)
 	}
-	blackRepertoires, err := s.repertoireService.ListRepertoires(userID, &blackColor)
+	blackRepertoires, err := s.repertoireService.CachedRepertoires(userID, &blackColor)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get black repertoires: %w", err)
 	}
+	scope, err := s.newMatchScope(userID, opts.MatchRepertoireIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	whiteRepertoires, blackRepertoires = scope.filter(whiteRepertoires), scope.filter(blackRepertoires)
+
+	usernames, err := s.playerUsernames(userID, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	maxPlies, err := s.analysisPlies(userID, opts.AnalysisDepth)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	var results []models.GameAnalysis
-	resultIndex := 0
-	for _, game := range games {
-		userColor := s.determineUserColor(game, username)
-		if userColor == "" {
+	userColors := make([]models.Color, len(games))
+	var userFENs []string
+	for i, game := range games {
+		if opts.Reference {
+			// The side is settled once the game is compared with the repertoires of both colors
+			userColors[i] = models.ColorWhite
+			userFENs = append(userFENs, userMovePositions(game, models.ColorWhite, maxPlies)...)
+			userFENs = append(userFENs, userMovePositions(game, models.ColorBlack, maxPlies)...)
 			continue
 		}
-
-		var repertoires []models.Repertoire
-		if userColor == models.ColorWhite {
-			repertoires = whiteRepertoires
-		} else {
-			repertoires = blackRepertoires
+		userColors[i] = s.determineUserColor(game, usernames...)
+		if userColors[i] != "" {
+			userFENs = append(userFENs, userMovePositions(game, userColors[i], maxPlies)...)
 		}
+	}
 
-		bestRepertoire, matchScore := s.findBestMatchingRepertoire(game, repertoires, userColor)
+	// One position index query covers every game instead of scanning each tree per move
+	allRepertoires := append(whiteRepertoires, blackRepertoires...)
+	matcher, err := s.newRepertoireMatcher(userID, userFENs, allRepertoires)
+	if err != nil {
+		return nil, nil, err
+	}
+	repertoiresByID := make(map[string]*models.Repertoire, len(allRepertoires))
+	indexes := make(map[string]nodeIndex, len(allRepertoires))
+	for i := range allRepertoires {
+		repertoiresByID[allRepertoires[i].ID] = &allRepertoires[i]
+		indexes[allRepertoires[i].ID] = newNodeIndex(&allRepertoires[i].TreeData)
+	}
 
-		var analysis models.GameAnalysis
-		if bestRepertoire == nil {
-			emptyTree := models.RepertoireNode{}
-			analysis = s.analyzeGame(resultIndex, game, emptyTree, userColor)
-			analysis.MatchedRepertoire = nil
-			analysis.MatchScore = 0
-		} else {
-			analysis = s.analyzeGame(resultIndex, game, bestRepertoire.TreeData, userColor)
-			analysis.MatchedRepertoire = &models.RepertoireRef{
-				ID:   bestRepertoire.ID,
-				Name: bestRepertoire.Name,
+	// Games the user did not play are dropped; the others are analyzed in parallel, each into its own slot
+	var userGames []int
+	for i := range games {
+		if userColors[i] != "" {
+			userGames = append(userGames, i)
+			continue
+		}
+		warnings = append(warnings, gameWarning(models.ImportWarningUserNotFound, parsed.positions[i], s.extractHeaders(games[i]), ""))
+	}
+	results := make([]models.GameAnalysis, len(userGames))
+	s.runAnalysisPool(len(userGames), func(resultIndex int) {
+		i := userGames[resultIndex]
+		game := games[i]
+		userColor := userColors[i]
+
+		var bestRepertoire *models.Repertoire
+		var matchScore int
+		switch {
+		case gameVariant(game) != "":
+			// Kept for the record only: repertoires are standard chess
+		case opts.Reference:
+			userColor, bestRepertoire, matchScore = matcher.findBestReferenceMatch(game, whiteRepertoires, blackRepertoires, maxPlies)
+		default:
+			repertoires := blackRepertoires
+			if userColor == models.ColorWhite {
+				repertoires = whiteRepertoires
 			}
-			analysis.MatchScore = matchScore
+			bestRepertoire, matchScore = matcher.findBestMatchingRepertoire(game, repertoires, userColor, maxPlies)
 		}
-		analysis.UserColor = userColor
-		results = append(results, analysis)
-		resultIndex++
-	}
+
+		results[resultIndex] = s.analyzeAgainst(resultIndex, game, annotations[i], userColor, bestRepertoire, matchScore, indexes, maxPlies)
+	})
 
 	if len(results) == 0 {
-		return nil, nil, fmt.Errorf("no games found where '%s' was a player", username)
+		return nil, nil, fmt.Errorf("%w: '%s'", ErrNoUserGames, username)
+	}
+	hashes := make(map[string]string)
+	for i := range results {
+		stampRepertoireSnapshot(&results[i], repertoiresByID, hashes)
+	}
+	s.classifyGames(userID, results)
+
+	provenance := opts.Provenance
+	if provenance.Source == "" {
+		provenance = filenameProvenance(filename, username)
+	}
+
+	// positions follows results through deduplication, for the warnings of the kept games
+	positions := make([]int, len(userGames))
+	for resultIndex, i := range userGames {
+		positions[resultIndex] = parsed.positions[i]
+	}
+
+	// Duplicates are archived too, for games imported before the archive existed
+	if !opts.Reference {
+		rawPGNs := make([]string, len(userGames))
+		for resultIndex, i := range userGames {
+			rawPGNs[resultIndex] = parsed.raws[i]
+		}
+		s.archiveRawGames(userID, provenance.Source, results, rawPGNs)
 	}
 
 	// Deduplicate using fingerprints
+	var duplicates []models.DuplicateGame
 	skippedDuplicates := 0
-	if s.fingerprintRepo != nil {
+	if s.fingerprintRepo != nil && !opts.Reference {
 		fingerprints := make([]string, len(results))
 		for i, r := range results {
 			fingerprints[i] = ComputeFingerprint(r.Headers, r.Moves)
 		}
 
-		existing, err := s.fingerprintRepo.CheckExisting(userID, fingerprints)
+		existing, err := s.checkExistingFingerprints(userID, fingerprints, opts.DedupProgress)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to check fingerprints: %w", err)
 		}
 
 		var filtered []models.GameAnalysis
+		var filteredPositions []int
 		for i, r := range results {
-			if !existing[fingerprints[i]] {
+			loc, isDuplicate := existing[fingerprints[i]]
+			if !isDuplicate {
+				filtered = append(filtered, r)
+				filteredPositions = append(filteredPositions, positions[i])
+				continue
+			}
+
+			duplicate := models.DuplicateGame{
+				White:      r.Headers["White"],
+				Black:      r.Headers["Black"],
+				Date:       r.Headers["Date"],
+				AnalysisID: loc.AnalysisID,
+				GameIndex:  loc.GameIndex,
+			}
+			switch policy {
+			case models.DuplicatePolicyReplace:
+				r.GameIndex = loc.GameIndex
+				if err := s.analysisRepo.UpdateGame(loc.AnalysisID, r); err != nil {
+					return nil, nil, fmt.Errorf("failed to replace duplicate game: %w", err)
+				}
+				var matched *models.Repertoire
+				if r.MatchedRepertoire != nil {
+					matched = repertoiresByID[r.MatchedRepertoire.ID]
+				}
+				s.replaceGameResults(loc.AnalysisID, r, matched)
+				duplicate.Action = "replaced"
+			case models.DuplicatePolicyKeepBoth:
 				filtered = append(filtered, r)
+				filteredPositions = append(filteredPositions, positions[i])
+				duplicate.Action = "kept"
+			default:
+				skippedDuplicates++
+				duplicate.Action = "skipped"
 			}
+			duplicates = append(duplicates, duplicate)
+			warnings = append(warnings, gameWarning(models.ImportWarningDuplicate, positions[i], r.Headers, duplicate.Action))
 		}
-		skippedDuplicates = len(results) - len(filtered)
+
+		s.recordSkippedDuplicates(userID, filename, skippedDuplicates)
 
 		if len(filtered) == 0 {
-			return nil, nil, ErrAllGamesDuplicate
+			if policy != models.DuplicatePolicyReplace {
+				return nil, nil, ErrAllGamesDuplicate
+			}
+			return &models.AnalysisSummary{
+				Username:         username,
+				Filename:         filename,
+				UploadedAt:       time.Now(),
+				ImportProvenance: provenance,
+				Duplicates:       duplicates,
+			}, nil, nil
 		}
 
 		// Re-index filtered games
@@ -152,16 +372,30 @@ func (s *ImportService) ParseAndAnalyze(filename string, username string, userID
 			filtered[i].GameIndex = i
 		}
 		results = filtered
+		positions = filteredPositions
+	}
+
+	for i, r := range results {
+		if variant := chessVariant(r.Headers["Variant"]); variant != "" {
+			warnings = append(warnings, gameWarning(models.ImportWarningVariant, positions[i], r.Headers,
+				fmt.Sprintf("imported without matching: %s is a chess variant", variant)))
+		} else if r.MatchedRepertoire == nil {
+			warnings = append(warnings, gameWarning(models.ImportWarningNoRepertoire, positions[i], r.Headers, ""))
+		}
 	}
+	sortImportWarnings(warnings)
 
-	summary, err := s.analysisRepo.Save(userID, username, filename, len(results), results)
+	summary, err := s.analysisRepo.Save(userID, username, filename, provenance, len(results), results)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to save analysis: %w", err)
 	}
 	summary.SkippedDuplicates = skippedDuplicates
+	summary.Duplicates = duplicates
+	summary.Warnings = warnings
+	s.saveImportSummary(userID, parsed.found, summary, len(results))
 
-	// Save fingerprints for the newly imported games
-	if s.fingerprintRepo != nil {
+	// Save fingerprints for the newly imported games; reference games must not block later imports of the user's own
+	if s.fingerprintRepo != nil && !opts.Reference {
 		entries := make([]repository.FingerprintEntry, len(results))
 		for i, r := range results {
 			entries[i] = repository.FingerprintEntry{
@@ -175,6 +409,11 @@ func (s *ImportService) ParseAndAnalyze(filename string, username string, userID
 		}
 	}
 
+	if !opts.Reference {
+		s.recordGameResults(summary.ID, results, repertoiresByID)
+		s.recordOpponentReplies(summary.ID, results)
+	}
+
 	// Enqueue engine analysis if available
 	if s.engineService != nil {
 		s.engineService.EnqueueAnalysis(userID, summary.ID, len(results))
@@ -183,8 +422,109 @@ func (s *ImportService) ParseAndAnalyze(filename string, username string, userID
 	return summary, results, nil
 }
 
+// recordSkippedDuplicates keeps the number of skipped duplicates for the import statistics.
+// Failures are logged and do not fail the import.
+func (s *ImportService) recordSkippedDuplicates(userID, filename string, count int) {
+	if count == 0 {
+		return
+	}
+	if err := s.analysisRepo.RecordSkippedDuplicates(userID, filename, count); err != nil {
+		log.Printf("import: %v", err)
+	}
+}
+
+// playerUsernames returns the username given for an import followed by the user's linked accounts
+func (s *ImportService) playerUsernames(userID, username string) ([]string, error) {
+	usernames := []string{username}
+	if s.userRepo == nil {
+		return usernames, nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked accounts: %w", err)
+	}
+	for _, account := range user.LinkedAccounts {
+		usernames = append(usernames, account.Username)
+	}
+	return usernames, nil
+}
+
+// ValidAnalysisDepth reports whether depth is an accepted analysis depth: 0 for whole games, or a number of moves
+func ValidAnalysisDepth(depth int) bool {
+	return depth >= 0 && depth <= config.MaxAnalysisDepth
+}
+
+// analysisPlies returns how many plies of each game are matched against repertoires, 0 meaning all.
+// The override of a single import wins over the user's setting.
+func (s *ImportService) analysisPlies(userID string, override *int) (int, error) {
+	if override != nil {
+		if !ValidAnalysisDepth(*override) {
+			return 0, ErrInvalidAnalysisDepth
+		}
+		return *override * 2, nil
+	}
+	if s.userRepo == nil {
+		return 0, nil
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get analysis depth: %w", err)
+	}
+	return user.AnalysisDepth * 2, nil
+}
+
+// withinDepth reports whether a ply is matched against repertoires given the maximum from analysisPlies
+func withinDepth(ply, maxPlies int) bool {
+	return maxPlies == 0 || ply < maxPlies
+}
+
+// repertoireMatcher scores games against repertoires using the nodes found in the position index
+type repertoireMatcher struct {
+	// nodes maps a position index key to the nodes reaching it, per repertoire ID
+	nodes map[string]map[string][]*models.RepertoireNode
+}
+
+// newRepertoireMatcher looks up the given positions in the user's position index. Only the
+// trees of repertoires with hits are walked, once, to resolve node IDs.
+func (s *ImportService) newRepertoireMatcher(userID string, fens []string, repertoires []models.Repertoire) (*repertoireMatcher, error) {
+	m := &repertoireMatcher{nodes: make(map[string]map[string][]*models.RepertoireNode)}
+	if len(fens) == 0 || len(repertoires) == 0 {
+		return m, nil
+	}
+
+	positions, err := s.repertoireService.FindPositions(userID, fens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up repertoire positions: %w", err)
+	}
+
+	byRepertoire := make(map[string][]models.RepertoirePosition)
+	for _, p := range positions {
+		byRepertoire[p.RepertoireID] = append(byRepertoire[p.RepertoireID], p)
+	}
+
+	for i := range repertoires {
+		hits := byRepertoire[repertoires[i].ID]
+		if len(hits) == 0 {
+			continue
+		}
+		nodesByID := indexNodesByID(&repertoires[i].TreeData)
+		for _, hit := range hits {
+			node := nodesByID[hit.NodeID]
+			if node == nil {
+				continue
+			}
+			if m.nodes[hit.FEN] == nil {
+				m.nodes[hit.FEN] = make(map[string][]*models.RepertoireNode)
+			}
+			m.nodes[hit.FEN][repertoires[i].ID] = append(m.nodes[hit.FEN][repertoires[i].ID], node)
+		}
+	}
+	return m, nil
+}
+
 // findBestMatchingRepertoire finds the repertoire with the most matching moves
-func (s *ImportService) findBestMatchingRepertoire(game *chess.Game, repertoires []models.Repertoire, userColor models.Color) (*models.Repertoire, int) {
+func (m *repertoireMatcher) findBestMatchingRepertoire(game *chess.Game, repertoires []models.Repertoire, userColor models.Color, maxPlies int) (*models.Repertoire, int) {
 	if len(repertoires) == 0 {
 		return nil, 0
 	}
@@ -193,7 +533,7 @@ func (s *ImportService) findBestMatchingRepertoire(game *chess.Game, repertoires
 	bestScore := -1
 
 	for i := range repertoires {
-		score := s.countMatchingMoves(game, repertoires[i].TreeData, userColor)
+		score := m.countMatchingMoves(game, repertoires[i].ID, userColor, maxPlies)
 		if score > bestScore {
 			bestScore = score
 			bestRepertoire = &repertoires[i]
@@ -203,27 +543,33 @@ func (s *ImportService) findBestMatchingRepertoire(game *chess.Game, repertoires
 	return bestRepertoire, bestScore
 }
 
-// countMatchingMoves counts how many of the user's moves are in the repertoire
-func (s *ImportService) countMatchingMoves(game *chess.Game, repertoireRoot models.RepertoireNode, userColor models.Color) int {
+// findBestReferenceMatch picks the side of a game the user did not play, from the repertoire that
+// follows it longest. Games followed by no repertoire are seen from White.
+func (m *repertoireMatcher) findBestReferenceMatch(game *chess.Game, whiteRepertoires, blackRepertoires []models.Repertoire, maxPlies int) (models.Color, *models.Repertoire, int) {
+	white, whiteScore := m.findBestMatchingRepertoire(game, whiteRepertoires, models.ColorWhite, maxPlies)
+	black, blackScore := m.findBestMatchingRepertoire(game, blackRepertoires, models.ColorBlack, maxPlies)
+	if black != nil && (white == nil || blackScore > whiteScore) {
+		return models.ColorBlack, black, blackScore
+	}
+	return models.ColorWhite, white, whiteScore
+}
+
+// countMatchingMoves counts how many of the user's moves within the analysis depth are in the repertoire
+func (m *repertoireMatcher) countMatchingMoves(game *chess.Game, repertoireID string, userColor models.Color, maxPlies int) int {
 	moves := game.Moves()
-	position := chess.StartingPosition()
+	position := gameStartPosition(game)
 	notation := chess.AlgebraicNotation{}
 	matchCount := 0
 
 	for ply, move := range moves {
-		san := notation.Encode(position, move)
-		currentFEN := normalizeFEN(position.String())
-		isUserMove := (ply%2 == 0 && userColor == models.ColorWhite) || (ply%2 == 1 && userColor == models.ColorBlack)
-
-		if isUserMove {
-			node := s.findNodeInRepertoire(repertoireRoot, currentFEN)
-			if node != nil {
-				for _, child := range node.Children {
-					if child.Move != nil && *child.Move == san {
-						matchCount++
-						break
-					}
-				}
+		if !withinDepth(ply, maxPlies) {
+			break
+		}
+
+		if isUserTurn(position, userColor) {
+			san := notation.Encode(position, move)
+			if nodesHaveChildMove(m.nodes[repository.PositionIndexKey(position.String())][repertoireID], san) {
+				matchCount++
 			}
 		}
 
@@ -233,47 +579,145 @@ func (s *ImportService) countMatchingMoves(game *chess.Game, repertoireRoot mode
 	return matchCount
 }
 
-func (s *ImportService) determineUserColor(game *chess.Game, username string) models.Color {
-	headers := s.extractHeaders(game)
-	white := headers["White"]
-	black := headers["Black"]
+// userMovePositions returns the positions within the analysis depth in which the user had to move
+func userMovePositions(game *chess.Game, userColor models.Color, maxPlies int) []string {
+	var fens []string
+	position := gameStartPosition(game)
+	for ply, move := range game.Moves() {
+		if !withinDepth(ply, maxPlies) {
+			break
+		}
+		if isUserTurn(position, userColor) {
+			fens = append(fens, normalizeFEN(position.String()))
+		}
+		position = position.Update(move)
+	}
+	return fens
+}
+
+// gameStartPosition returns the position a game starts from: the one in its FEN header for
+// games played from a custom position, the standard starting position otherwise
+func gameStartPosition(game *chess.Game) *chess.Position {
+	return game.Positions()[0]
+}
 
-	usernameLower := strings.ToLower(username)
-	if strings.ToLower(white) == usernameLower {
-		return models.ColorWhite
+// isUserTurn reports whether the user is to move in a position. It goes by the side to move
+// rather than by ply parity, as games from a custom position may start with Black to move.
+func isUserTurn(position *chess.Position, userColor models.Color) bool {
+	return (position.Turn() == chess.White) == (userColor == models.ColorWhite)
+}
+
+func nodesHaveChildMove(nodes []*models.RepertoireNode, san string) bool {
+	for _, node := range nodes {
+		for _, child := range node.Children {
+			if child.Move != nil && *child.Move == san {
+				return true
+			}
+		}
 	}
-	if strings.ToLower(black) == usernameLower {
-		return models.ColorBlack
+	return false
+}
+
+// indexNodesByID maps every node of a tree by its ID
+func indexNodesByID(root *models.RepertoireNode) map[string]*models.RepertoireNode {
+	nodes := make(map[string]*models.RepertoireNode)
+	var walk func(node *models.RepertoireNode)
+	walk = func(node *models.RepertoireNode) {
+		nodes[node.ID] = node
+		for _, child := range node.Children {
+			if child != nil {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	return nodes
+}
+
+func (s *ImportService) determineUserColor(game *chess.Game, usernames ...string) models.Color {
+	headers := s.extractHeaders(game)
+	white := strings.ToLower(headers["White"])
+	black := strings.ToLower(headers["Black"])
+
+	for _, username := range usernames {
+		usernameLower := strings.ToLower(username)
+		if white == usernameLower {
+			return models.ColorWhite
+		}
+		if black == usernameLower {
+			return models.ColorBlack
+		}
 	}
 	return ""
 }
 
 func (s *ImportService) parsePGN(pgnData string) ([]*chess.Game, error) {
+	games, _, err := s.parsePGNWithAnnotations(pgnData)
+	return games, err
+}
+
+// parsePGNWithAnnotations parses PGN data like parsePGN and also returns, for each
+// game, the comments and NAGs of its mainline moves (which notnil/chess discards).
+func (s *ImportService) parsePGNWithAnnotations(pgnData string) ([]*chess.Game, [][]moveAnnotation, error) {
+	parsed := s.readPGN(pgnData)
+	return parsed.games, parsed.annotations, nil
+}
+
+// parsedPGN holds the games read from PGN data. For each game it keeps its mainline
+// annotations and its 1-based position among the games of the data; the games that
+// could not be read are reported in warnings.
+type parsedPGN struct {
+	games       []*chess.Game
+	annotations [][]moveAnnotation
+	raws        []string // PGN text of each game as it was received
+	positions   []int
+	warnings    []models.ImportWarning
+	found       int
+}
+
+func (s *ImportService) readPGN(pgnData string) parsedPGN {
 	// Split multi-game PGN into individual games first, then parse each one
 	// separately to work around notnil/chess GamesFromPGN splitting games
 	// incorrectly when there are blank lines between headers and moves.
 	rawGames := splitRawPGNGames(pgnData)
 
-	var validGames []*chess.Game
+	var parsed parsedPGN
 	for _, rawGame := range rawGames {
 		rawGame = strings.TrimSpace(rawGame)
 		if rawGame == "" {
 			continue
 		}
-		reader := strings.NewReader(rawGame)
-		parsed, err := chess.GamesFromPGN(reader)
-		if err != nil {
-			// Skip individual games that fail to parse
-			continue
+		parsed.found++
+		// Localized piece letters and figurines are read as English SAN when the original does not parse
+		read := false
+		for _, candidate := range localizedPGNCandidates(rawGame) {
+			games, err := chess.GamesFromPGN(strings.NewReader(candidate))
+			if err != nil {
+				continue
+			}
+			for _, game := range games {
+				if len(game.Moves()) > 0 {
+					parsed.games = append(parsed.games, game)
+					parsed.annotations = append(parsed.annotations, extractMainlineAnnotations(candidate))
+					parsed.raws = append(parsed.raws, rawGame)
+					parsed.positions = append(parsed.positions, parsed.found)
+					read = true
+				}
+			}
+			break
 		}
-		for _, game := range parsed {
-			if len(game.Moves()) > 0 {
-				validGames = append(validGames, game)
+		if !read {
+			headers, _ := splitPGNHeadersAndMovetext(rawGame)
+			if variant := chessVariant(headers["Variant"]); variant != "" {
+				parsed.warnings = append(parsed.warnings, gameWarning(models.ImportWarningVariant, parsed.found, headers,
+					fmt.Sprintf("skipped: %s moves cannot be read as standard chess", variant)))
+				continue
 			}
+			parsed.warnings = append(parsed.warnings, gameWarning(models.ImportWarningParseFailed, parsed.found, headers, "no legal moves could be read"))
 		}
 	}
 
-	return validGames, nil
+	return parsed
 }
 
 // splitRawPGNGames splits a multi-game PGN string into individual game strings.
@@ -281,18 +725,35 @@ func (s *ImportService) parsePGN(pgnData string) ([]*chess.Game, error) {
 // line or blank line following move text.
 func splitRawPGNGames(pgn string) []string {
 	var games []string
+	// Reading from a string cannot fail and the callback never returns an error
+	_ = scanRawPGNGames(strings.NewReader(pgn), func(game string) error {
+		games = append(games, game)
+		return nil
+	})
+	return games
+}
+
+// maxPGNLineLength bounds a single line of PGN; exports put each game's moves on one line
+const maxPGNLineLength = 1024 * 1024
+
+// scanRawPGNGames streams the games of a PGN file to fn, one at a time, using the same
+// splitting rules as splitRawPGNGames. It stops at the first error returned by fn.
+func scanRawPGNGames(r io.Reader, fn func(game string) error) error {
 	var current strings.Builder
 	seenMoves := false
 
-	lines := strings.Split(pgn, "\n")
-	for _, line := range lines {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPGNLineLength)
+	for scanner.Scan() {
+		line := scanner.Text()
 		trimmed := strings.TrimSpace(line)
 
 		if strings.HasPrefix(trimmed, "[") && seenMoves {
-			// Start of a new game: save current and reset
-			game := strings.TrimSpace(current.String())
-			if game != "" {
-				games = append(games, game)
+			// Start of a new game: emit current and reset
+			if game := strings.TrimSpace(current.String()); game != "" {
+				if err := fn(game); err != nil {
+					return err
+				}
 			}
 			current.Reset()
 			seenMoves = false
@@ -305,17 +766,43 @@ func splitRawPGNGames(pgn string) []string {
 		current.WriteString(line)
 		current.WriteString("\n")
 	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read PGN: %w", err)
+	}
 
 	// Don't forget the last game
-	game := strings.TrimSpace(current.String())
-	if game != "" {
-		games = append(games, game)
+	if game := strings.TrimSpace(current.String()); game != "" {
+		return fn(game)
 	}
+	return nil
+}
 
-	return games
+// analyzeAgainst analyzes a game against the repertoire it was matched to, nil for none, and
+// adds the comments, NAGs and clock times of its PGN
+func (s *ImportService) analyzeAgainst(gameIndex int, game *chess.Game, annotations []moveAnnotation, userColor models.Color, repertoire *models.Repertoire, matchScore int, indexes map[string]nodeIndex, maxPlies int) models.GameAnalysis {
+	var analysis models.GameAnalysis
+	if repertoire == nil {
+		analysis = s.analyzeGameIndexed(gameIndex, game, nodeIndex{}, userColor, maxPlies)
+	} else {
+		analysis = s.analyzeGameIndexed(gameIndex, game, indexes[repertoire.ID], userColor, maxPlies)
+		analysis.MatchedRepertoire = &models.RepertoireRef{
+			ID:   repertoire.ID,
+			Name: repertoire.Name,
+		}
+		analysis.MatchScore = matchScore
+	}
+	analysis.UserColor = userColor
+	applyAnnotations(analysis.Moves, annotations)
+	applyTimeSpent(analysis.Moves, analysis.Headers["TimeControl"])
+	return analysis
+}
+
+func (s *ImportService) analyzeGame(gameIndex int, game *chess.Game, repertoireRoot models.RepertoireNode, userColor models.Color, maxPlies int) models.GameAnalysis {
+	return s.analyzeGameIndexed(gameIndex, game, newNodeIndex(&repertoireRoot), userColor, maxPlies)
 }
 
-func (s *ImportService) analyzeGame(gameIndex int, game *chess.Game, repertoireRoot models.RepertoireNode, userColor models.Color) models.GameAnalysis {
+// analyzeGameIndexed is analyzeGame with the repertoire's node index built once by the caller
+func (s *ImportService) analyzeGameIndexed(gameIndex int, game *chess.Game, index nodeIndex, userColor models.Color, maxPlies int) models.GameAnalysis {
 	analysis := models.GameAnalysis{
 		GameIndex: gameIndex,
 		Headers:   s.extractHeaders(game),
@@ -323,19 +810,21 @@ func (s *ImportService) analyzeGame(gameIndex int, game *chess.Game, repertoireR
 	}
 
 	moves := game.Moves()
-	position := chess.StartingPosition()
+	position := gameStartPosition(game)
 	notation := chess.AlgebraicNotation{}
 
 	for ply, move := range moves {
 		san := notation.Encode(position, move)
 		currentFEN := normalizeFEN(position.String())
-		isUserMove := (ply%2 == 0 && userColor == models.ColorWhite) || (ply%2 == 1 && userColor == models.ColorBlack)
+		isUserMove := isUserTurn(position, userColor)
 
 		var status string
 		var expectedMove string
 
-		node := s.findNodeInRepertoire(repertoireRoot, currentFEN)
-		if node == nil || len(node.Children) == 0 {
+		if !withinDepth(ply, maxPlies) {
+			// Past the analysis depth — kept for replaying the game but not matched
+			status = "beyond-book"
+		} else if node := index[currentFEN]; node == nil || len(node.Children) == 0 {
 			// Position not in tree or is a leaf — repertoire has ended
 			status = "out-of-book"
 		} else {
@@ -376,19 +865,6 @@ func (s *ImportService) analyzeGame(gameIndex int, game *chess.Game, repertoireR
 	return analysis
 }
 
-// NormalizeFEN strips half-move and full-move counters from a FEN string,
-// keeping only board, side to move, castling, and en passant fields.
-func NormalizeFEN(fen string) string {
-	parts := strings.Fields(fen)
-	if len(parts) >= 4 {
-		return strings.Join(parts[:4], " ")
-	}
-	return fen
-}
-
-// normalizeFEN is the package-internal alias kept for existing callers.
-func normalizeFEN(fen string) string { return NormalizeFEN(fen) }
-
 func (s *ImportService) extractHeaders(game *chess.Game) models.PGNHeaders {
 	headers := make(models.PGNHeaders)
 
@@ -444,17 +920,31 @@ func (s *ImportService) ValidatePGN(pgnData string) error {
 
 // ValidateMove validates a chess move
 func (s *ImportService) ValidateMove(fen, san string) error {
+	_, err := s.NormalizeMove(fen, san)
+	return err
+}
+
+// NormalizeMove validates a move given in English, localized or figurine SAN and returns it in English SAN
+func (s *ImportService) NormalizeMove(fen, san string) (string, error) {
 	fullFEN := ensureFullFEN(fen)
 	fenFn, err := chess.FEN(fullFEN)
 	if err != nil {
-		return fmt.Errorf("invalid FEN: %w", err)
+		return "", fmt.Errorf("invalid FEN: %w", err)
 	}
-	game := chess.NewGame(fenFn)
-	err = game.MoveStr(san)
-	if err != nil {
-		return fmt.Errorf("invalid move %s: %w", san, err)
+
+	var firstErr error
+	for _, candidate := range localizedSANCandidates(san) {
+		game := chess.NewGame(fenFn)
+		if err := game.MoveStr(candidate); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		moves := game.Moves()
+		return chess.AlgebraicNotation{}.Encode(game.Positions()[0], moves[0]), nil
 	}
-	return nil
+	return "", fmt.Errorf("invalid move %s: %w", san, firstErr)
 }
 
 // GetLegalMoves returns legal moves for a position
@@ -473,9 +963,9 @@ func (s *ImportService) GetLegalMoves(fen string) ([]string, error) {
 	return sanMoves, nil
 }
 
-// GetAnalyses returns all analyses summaries for a user
-func (s *ImportService) GetAnalyses(userID string) ([]models.AnalysisSummary, error) {
-	analyses, err := s.analysisRepo.GetAll(userID)
+// GetAnalyses returns all analyses summaries for a user, only those of one import source when source is set
+func (s *ImportService) GetAnalyses(userID, source string) ([]models.AnalysisSummary, error) {
+	analyses, err := s.analysisRepo.GetAll(userID, source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get analyses: %w", err)
 	}
@@ -492,9 +982,30 @@ func (s *ImportService) DeleteAnalysis(id string) error {
 	return s.analysisRepo.Delete(id)
 }
 
+// RecomputeEvals forces re-evaluation of every game in an analysis
+func (s *ImportService) RecomputeEvals(userID, analysisID string) (int, error) {
+	if s.engineService == nil {
+		return 0, ErrEngineUnavailable
+	}
+	return s.engineService.RecomputeAnalysis(userID, analysisID)
+}
+
+// RetryFailedEvals requeues the engine evals of an analysis that ran out of retries
+func (s *ImportService) RetryFailedEvals(analysisID string) (int, error) {
+	if s.engineService == nil {
+		return 0, ErrEngineUnavailable
+	}
+	return s.engineService.RetryFailed(analysisID)
+}
+
+// GetImportStats returns the user's import statistics per source and month
+func (s *ImportService) GetImportStats(userID string) ([]models.ImportSourceStats, error) {
+	return s.analysisRepo.GetImportStats(userID)
+}
+
 // GetAllGames returns all games from all analyses with pagination for a user
-func (s *ImportService) GetAllGames(userID string, limit, offset int, timeClass, repertoire, source string) (*models.GamesResponse, error) {
-	response, err := s.analysisRepo.GetAllGames(userID, limit, offset, timeClass, repertoire, source)
+func (s *ImportService) GetAllGames(userID string, limit, offset int, timeClass, repertoire, source, classification string, starred bool) (*models.GamesResponse, error) {
+	response, err := s.analysisRepo.GetAllGames(userID, limit, offset, timeClass, repertoire, source, classification, starred)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get games: %w", err)
 	}
@@ -511,16 +1022,54 @@ func (s *ImportService) MarkGameViewed(userID, analysisID string, gameIndex int)
 	return s.analysisRepo.MarkGameViewed(userID, analysisID, gameIndex)
 }
 
+// UpdateGameNotes sets the user's note and/or starred flag on a game
+func (s *ImportService) UpdateGameNotes(analysisID string, gameIndex int, req models.UpdateGameRequest) (*models.GameNotes, error) {
+	if req.Note == nil && req.Starred == nil {
+		return nil, ErrEmptyGameUpdate
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		if utf8.RuneCountInString(note) > config.MaxGameNoteLen {
+			return nil, ErrGameNoteTooLong
+		}
+		req.Note = &note
+	}
+	return s.analysisRepo.UpdateGameNotes(analysisID, gameIndex, req.Note, req.Starred)
+}
+
 // CheckOwnership verifies that an analysis belongs to the given user
 func (s *ImportService) CheckOwnership(id string, userID string) error {
 	belongs, err := s.analysisRepo.BelongsToUser(id, userID)
+	return ownershipGuard(belongs, err, ErrNotFound)
+}
+
+// BackfillFingerprints records the fingerprints of a user's stored games, e.g. games imported
+// before duplicate detection existed. Existing fingerprints are kept. It returns how many games were processed.
+func (s *ImportService) BackfillFingerprints(userID string) (int, error) {
+	if s.fingerprintRepo == nil {
+		return 0, fmt.Errorf("fingerprints are not configured")
+	}
+
+	analyses, err := s.analysisRepo.GetAllGamesRaw(userID)
 	if err != nil {
-		return fmt.Errorf("failed to check ownership: %w", err)
+		return 0, err
 	}
-	if !belongs {
-		return ErrNotFound
+
+	processed := 0
+	for _, analysis := range ownGameAnalyses(analyses) {
+		entries := make([]repository.FingerprintEntry, len(analysis.Results))
+		for i, game := range analysis.Results {
+			entries[i] = repository.FingerprintEntry{
+				Fingerprint: ComputeFingerprint(game.Headers, game.Moves),
+				GameIndex:   game.GameIndex,
+			}
+		}
+		if err := s.fingerprintRepo.SaveBatch(userID, analysis.ID, entries); err != nil {
+			return processed, err
+		}
+		processed += len(entries)
 	}
-	return nil
+	return processed, nil
 }
 
 // DeleteGame removes a single game from an analysis and its fingerprint
@@ -533,26 +1082,13 @@ func (s *ImportService) DeleteGame(analysisID string, gameIndex int) error {
 	return s.analysisRepo.DeleteGame(analysisID, gameIndex)
 }
 
-// ReanalyzeGame re-analyzes a specific game against a different repertoire
-func (s *ImportService) ReanalyzeGame(analysisID string, gameIndex int, repertoireID string) (*models.GameAnalysis, error) {
-	detail, err := s.analysisRepo.GetByID(analysisID)
+// ReanalyzeGame re-analyzes a specific game of the user against a different repertoire
+func (s *ImportService) ReanalyzeGame(userID, analysisID string, gameIndex int, repertoireID string) (*models.GameAnalysis, error) {
+	targetGame, err := s.analysisRepo.GetGame(analysisID, gameIndex)
 	if err != nil {
 		return nil, err
 	}
 
-	var targetGame *models.GameAnalysis
-	var targetIdx int
-	for i := range detail.Results {
-		if detail.Results[i].GameIndex == gameIndex {
-			targetGame = &detail.Results[i]
-			targetIdx = i
-			break
-		}
-	}
-	if targetGame == nil {
-		return nil, repository.ErrGameNotFound
-	}
-
 	repertoire, err := s.repertoireService.GetRepertoire(repertoireID)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrRepertoireNotFound, err)
@@ -563,35 +1099,142 @@ func (s *ImportService) ReanalyzeGame(analysisID string, gameIndex int, repertoi
 	}
 
 	reanalyzedGame := s.reanalyzeGameFromMoves(targetGame, repertoire)
+	classifyGame(&reanalyzedGame, classificationRulesFor(s.classificationRuleRepo, userID))
 
-	detail.Results[targetIdx] = reanalyzedGame
-	err = s.analysisRepo.UpdateResults(analysisID, detail.Results)
+	err = s.analysisRepo.UpdateGame(analysisID, reanalyzedGame)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save reanalyzed game: %w", err)
 	}
+	s.replaceGameResults(analysisID, reanalyzedGame, repertoire)
 
 	return &reanalyzedGame, nil
 }
 
+// EnqueueRepertoireReanalysis queues a background job re-analyzing every game of the user matched to the repertoire
+func (s *ImportService) EnqueueRepertoireReanalysis(userID, repertoireID string) (*models.ReanalysisJob, error) {
+	if s.reanalysisJobRepo == nil {
+		return nil, fmt.Errorf("reanalysis jobs are not configured")
+	}
+	if err := s.repertoireService.CheckOwner(repertoireID, userID); err != nil {
+		return nil, err
+	}
+	return s.reanalysisJobRepo.Create(userID, repertoireID)
+}
+
+// GetReanalysisJob returns a re-analysis job, hiding jobs of other users
+func (s *ImportService) GetReanalysisJob(id, userID string) (*models.ReanalysisJob, error) {
+	if s.reanalysisJobRepo == nil {
+		return nil, repository.ErrReanalysisJobNotFound
+	}
+	job, err := s.reanalysisJobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, repository.ErrReanalysisJobNotFound
+	}
+	return job, nil
+}
+
+// RunReanalysisWorker polls for pending re-analysis jobs and processes them one at a time
+func (s *ImportService) RunReanalysisWorker(ctx context.Context) {
+	log.Println("reanalysis: worker started")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("reanalysis: worker stopped")
+			return
+		case <-ticker.C:
+			s.processReanalysisJobs()
+		}
+	}
+}
+
+func (s *ImportService) processReanalysisJobs() {
+	jobs, err := s.reanalysisJobRepo.GetPending(5)
+	if err != nil {
+		log.Printf("reanalysis: failed to get pending jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := s.runReanalysisJob(job); err != nil {
+			log.Printf("reanalysis: job %s failed: %v", job.ID, err)
+			if markErr := s.reanalysisJobRepo.MarkFailed(job.ID, err.Error()); markErr != nil {
+				log.Printf("reanalysis: failed to mark job %s as failed: %v", job.ID, markErr)
+			}
+		}
+	}
+}
+
+// runReanalysisJob re-analyzes every game of the job's repertoire, reporting progress after each game.
+// Games deleted while the job runs are skipped.
+func (s *ImportService) runReanalysisJob(job models.ReanalysisJob) error {
+	repertoire, err := s.repertoireService.GetRepertoire(job.RepertoireID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRepertoireNotFound, err)
+	}
+
+	locations, err := s.analysisRepo.GetGameLocationsByRepertoire(job.UserID, job.RepertoireID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reanalysisJobRepo.MarkProcessing(job.ID, len(locations)); err != nil {
+		return fmt.Errorf("failed to mark job as processing: %w", err)
+	}
+	rules := classificationRulesFor(s.classificationRuleRepo, job.UserID)
+
+	for i, loc := range locations {
+		game, err := s.analysisRepo.GetGame(loc.AnalysisID, loc.GameIndex)
+		if err != nil && !errors.Is(err, repository.ErrGameNotFound) {
+			return err
+		}
+		if err == nil && game.UserColor == repertoire.Color {
+			reanalyzed := s.reanalyzeGameFromMoves(game, repertoire)
+			classifyGame(&reanalyzed, rules)
+			if err := s.analysisRepo.UpdateGame(loc.AnalysisID, reanalyzed); err != nil {
+				return fmt.Errorf("failed to save reanalyzed game: %w", err)
+			}
+			s.replaceGameResults(loc.AnalysisID, reanalyzed, repertoire)
+		}
+
+		if err := s.reanalysisJobRepo.UpdateProgress(job.ID, i+1); err != nil {
+			log.Printf("reanalysis: failed to update progress of job %s: %v", job.ID, err)
+		}
+	}
+
+	if err := s.reanalysisJobRepo.MarkDone(job.ID); err != nil {
+		return fmt.Errorf("failed to mark job as done: %w", err)
+	}
+	return nil
+}
+
 // reanalyzeGameFromMoves re-analyzes a game using its stored moves against a new repertoire
 func (s *ImportService) reanalyzeGameFromMoves(game *models.GameAnalysis, repertoire *models.Repertoire) models.GameAnalysis {
 	result := models.GameAnalysis{
-		GameIndex: game.GameIndex,
-		Headers:   game.Headers,
-		Moves:     make([]models.MoveAnalysis, len(game.Moves)),
-		UserColor: game.UserColor,
-		MatchedRepertoire: &models.RepertoireRef{
-			ID:   repertoire.ID,
-			Name: repertoire.Name,
-		},
-		MatchScore: 0,
+		GameIndex:         game.GameIndex,
+		Headers:           game.Headers,
+		Moves:             make([]models.MoveAnalysis, len(game.Moves)),
+		UserColor:         game.UserColor,
+		MatchedRepertoire: repertoireSnapshot(repertoire),
+		MatchScore:        0,
 	}
 
+	index := newNodeIndex(&repertoire.TreeData)
 	for i, move := range game.Moves {
 		var status string
 		var expectedMove string
 
-		node := s.findNodeInRepertoire(repertoire.TreeData, move.FEN)
+		if move.Status == "beyond-book" {
+			// Keep the analysis depth the game was imported with
+			result.Moves[i] = move
+			continue
+		}
+		node := index[move.FEN]
 		if node == nil || len(node.Children) == 0 {
 			status = "out-of-book"
 		} else {
@@ -624,12 +1267,20 @@ func (s *ImportService) reanalyzeGameFromMoves(game *models.GameAnalysis, repert
 			Status:       status,
 			ExpectedMove: expectedMove,
 			IsUserMove:   move.IsUserMove,
+			Comment:      move.Comment,
+			NAGs:         move.NAGs,
+			Clock:        move.Clock,
+			TimeSpent:    move.TimeSpent,
+			WinrateDrop:  move.WinrateDrop,
 		}
 	}
 
 	return result
 }
 
+// fingerprintPlies is how many opening moves the fallback fingerprint hashes
+const fingerprintPlies = 10
+
 // ComputeFingerprint generates a unique fingerprint for a game.
 // For Lichess games, uses the Site header (game URL).
 // For Chess.com games, uses the Link header (game URL).
@@ -657,7 +1308,7 @@ func ComputeFingerprint(headers models.PGNHeaders, moves []models.MoveAnalysis)
 	b.WriteString(headers["Event"])
 	b.WriteByte('|')
 
-	limit := 10
+	limit := fingerprintPlies
 	if len(moves) < limit {
 		limit = len(moves)
 	}
@@ -680,6 +1331,22 @@ func (s *ImportService) DismissMistake(userID, fen, playedMove string) error {
 	return s.dismissedMistakeRepo.Dismiss(userID, fen, playedMove)
 }
 
+// ListDismissedMistakes returns the mistakes a user has dismissed
+func (s *ImportService) ListDismissedMistakes(userID string) ([]models.DismissedMistake, error) {
+	if s.dismissedMistakeRepo == nil {
+		return nil, fmt.Errorf("dismissed mistake repository not configured")
+	}
+	return s.dismissedMistakeRepo.List(userID)
+}
+
+// RestoreMistake undoes a dismissal so the mistake is reported by insights again
+func (s *ImportService) RestoreMistake(userID, fen, playedMove string) error {
+	if s.dismissedMistakeRepo == nil {
+		return fmt.Errorf("dismissed mistake repository not configured")
+	}
+	return s.dismissedMistakeRepo.Restore(userID, fen, playedMove)
+}
+
 // collectRepertoireMoves extracts all parent FEN + child move combinations from a repertoire tree
 // The key format is "parentFEN|childMove" to identify moves that exist in the repertoire
 func collectRepertoireMoves(node *models.RepertoireNode, moves map[string]bool) {
@@ -694,10 +1361,32 @@ func collectRepertoireMoves(node *models.RepertoireNode, moves map[string]bool)
 	}
 }
 
+// DefaultInsightsFilter returns the filter used by the dashboard for users without insight settings:
+// top 2 mistakes recurring in 2 games, 2% cutoff, from ply 3, all games
+func DefaultInsightsFilter() models.InsightsFilter {
+	return models.InsightsFilter{
+		Limit:        config.DefaultInsightsLimit,
+		MinDrop:      config.DefaultInsightsMinDrop,
+		MinPly:       config.DefaultInsightsMinPly,
+		MinFrequency: config.DefaultInsightsMinFrequency,
+	}
+}
+
 // GetInsights computes worst opening mistakes using engine evaluations
-func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, error) {
+func (s *ImportService) GetInsights(userID string, filter models.InsightsFilter) (*models.InsightsResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = config.DefaultInsightsLimit
+	}
+	if filter.MinPly <= 0 {
+		filter.MinPly = config.DefaultInsightsMinPly
+	}
+	if filter.MinFrequency <= 0 {
+		filter.MinFrequency = config.DefaultInsightsMinFrequency
+	}
+
 	response := &models.InsightsResponse{
 		WorstMistakes:      []models.OpeningMistake{},
+		TimeTrouble:        []models.TimeTroubleSignal{},
 		EngineAnalysisDone: true,
 	}
 
@@ -719,7 +1408,7 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 	// Get repertoire moves to filter them out (moves in repertoire are intentional, not mistakes)
 	repertoireMoves := make(map[string]bool)
 	if s.repertoireService != nil {
-		repertoires, err := s.repertoireService.ListRepertoires(userID, nil)
+		repertoires, err := s.repertoireService.CachedRepertoires(userID, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get repertoires: %w", err)
 		}
@@ -743,6 +1432,9 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 		return nil, fmt.Errorf("failed to get analyses: %w", err)
 	}
 
+	analyses = filterInsightsAnalyses(ownGameAnalyses(analyses), filter)
+	response.TimeTrouble = computeTimeTrouble(analyses)
+
 	// Build lookup: analysisID+gameIndex -> explorer stats
 	type evalKey struct {
 		AnalysisID string
@@ -766,6 +1458,7 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 		earliestPly int
 		games       []models.GameRef
 		seen        map[string]bool
+		timeClasses map[string]int
 	}
 	mistakeGroups := make(map[mistakeKey]*mistakeData)
 
@@ -777,12 +1470,12 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 			}
 
 			for _, stat := range stats {
-				// Skip the very first move (ply 1-2) - opening choice, not a mistake
-				if stat.PlyNumber <= 2 {
+				// Skip the first moves (ply 1-2 by default) - opening choice, not a mistake
+				if stat.PlyNumber < filter.MinPly {
 					continue
 				}
-				// Only count as mistake if winrate drop >= 2%
-				if stat.WinrateDrop < 0.02 {
+				// Only count as mistake if winrate drop reaches the cutoff
+				if stat.WinrateDrop < filter.MinDrop {
 					continue
 				}
 
@@ -796,12 +1489,16 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 						winrateDrop: stat.WinrateDrop,
 						earliestPly: stat.PlyNumber,
 						seen:        make(map[string]bool),
+						timeClasses: make(map[string]int),
 					}
 					mistakeGroups[key] = data
 				}
 
 				if !data.seen[dedup] {
 					data.seen[dedup] = true
+					if timeClass := models.ClassifyTimeControl(game.Headers["TimeControl"]); timeClass != "" {
+						data.timeClasses[timeClass]++
+					}
 					if stat.WinrateDrop > data.winrateDrop {
 						data.winrateDrop = stat.WinrateDrop
 						data.bestMove = stat.BestMove
@@ -815,6 +1512,8 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 							Black:      game.Headers["Black"],
 							Result:     game.Headers["Result"],
 							Date:       game.Headers["Date"],
+							UserColor:  game.UserColor,
+							Opening:    game.Headers["Opening"],
 						})
 					}
 				}
@@ -823,7 +1522,7 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 	}
 
 	// Convert to slice, filter, and score: winrateDrop * frequency²
-	// Only keep mistakes that appeared in at least 2 games (recurring patterns)
+	// Only keep mistakes that appeared in at least filter.MinFrequency games (recurring patterns)
 	for key, data := range mistakeGroups {
 		// Skip dismissed mistakes and moves that exist in repertoires
 		moveKey := key.FEN + "|" + key.PlayedMove
@@ -832,7 +1531,7 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 		}
 
 		freq := len(data.seen)
-		if freq < 2 {
+		if freq < filter.MinFrequency {
 			continue
 		}
 		score := data.winrateDrop * float64(freq) * float64(freq)
@@ -844,35 +1543,166 @@ func (s *ImportService) GetInsights(userID string) (*models.InsightsResponse, er
 			Frequency:   freq,
 			Score:       score,
 			Games:       data.games,
+			TimeClasses: data.timeClasses,
 		})
 	}
 
-	// Sort by score desc, take top 2
+	// Sort by score desc, take top N
 	sortMistakes(response.WorstMistakes)
-	if len(response.WorstMistakes) > 2 {
-		response.WorstMistakes = response.WorstMistakes[:2]
+	if len(response.WorstMistakes) > filter.Limit {
+		response.WorstMistakes = response.WorstMistakes[:filter.Limit]
 	}
 
 	return response, nil
 }
 
-func sortMistakes(mistakes []models.OpeningMistake) {
-	for i := 1; i < len(mistakes); i++ {
-		for j := i; j > 0 && mistakes[j].Score > mistakes[j-1].Score; j-- {
-			mistakes[j], mistakes[j-1] = mistakes[j-1], mistakes[j]
+// filterInsightsAnalyses keeps the analyses uploaded since filter.Since and, within them,
+// the games matched to filter.RepertoireID and of filter.TimeClass. Analyses left without
+// games are dropped.
+func filterInsightsAnalyses(analyses []models.RawAnalysis, filter models.InsightsFilter) []models.RawAnalysis {
+	if filter.Since.IsZero() && filter.RepertoireID == "" && filter.TimeClass == "" {
+		return analyses
+	}
+
+	var filtered []models.RawAnalysis
+	for _, a := range analyses {
+		if !filter.Since.IsZero() && a.UploadedAt.Before(filter.Since) {
+			continue
+		}
+		if filter.RepertoireID != "" || filter.TimeClass != "" {
+			var games []models.GameAnalysis
+			for _, game := range a.Results {
+				if filter.RepertoireID != "" && (game.MatchedRepertoire == nil || game.MatchedRepertoire.ID != filter.RepertoireID) {
+					continue
+				}
+				if filter.TimeClass != "" && models.ClassifyTimeControl(game.Headers["TimeControl"]) != filter.TimeClass {
+					continue
+				}
+				games = append(games, game)
+			}
+			if len(games) == 0 {
+				continue
+			}
+			a.Results = games
+		}
+		filtered = append(filtered, a)
+	}
+	return filtered
+}
+
+const (
+	// timeTroubleWindow is the number of user moves, starting at the first one out of book, whose time is summed
+	timeTroubleWindow = 5
+	// timeTroubleMinShare is the share of the base time above which a repertoire is flagged
+	timeTroubleMinShare = 0.15
+	// timeTroubleMinGames is the minimum number of games before a repertoire is flagged
+	timeTroubleMinGames = 2
+)
+
+// computeTimeTrouble flags repertoires where the user, on average, spends a large
+// share of their clock on the first moves after leaving book. Games without clock
+// data, without a matched repertoire, or that never leave book are ignored.
+func computeTimeTrouble(analyses []models.RawAnalysis) []models.TimeTroubleSignal {
+	type timeData struct {
+		name          string
+		games         int
+		leaveBookMove float64
+		timeSpent     float64
+		clockShare    float64
+	}
+	groups := make(map[string]*timeData)
+	var order []string
+
+	for _, a := range analyses {
+		for _, game := range a.Results {
+			if game.MatchedRepertoire == nil {
+				continue
+			}
+			base, _, ok := models.ParseTimeControl(game.Headers["TimeControl"])
+			if !ok || base <= 0 || models.ClassifyTimeControl(game.Headers["TimeControl"]) == "daily" {
+				continue
+			}
+
+			leaveBook := leaveBookIndex(game.Moves)
+			if leaveBook < 0 {
+				continue
+			}
+
+			spent := 0.0
+			counted := 0
+			for _, move := range game.Moves[leaveBook:] {
+				if !move.IsUserMove {
+					continue
+				}
+				if move.TimeSpent == nil {
+					break
+				}
+				spent += *move.TimeSpent
+				counted++
+				if counted == timeTroubleWindow {
+					break
+				}
+			}
+			if counted == 0 {
+				continue
+			}
+
+			data, exists := groups[game.MatchedRepertoire.ID]
+			if !exists {
+				data = &timeData{name: game.MatchedRepertoire.Name}
+				groups[game.MatchedRepertoire.ID] = data
+				order = append(order, game.MatchedRepertoire.ID)
+			}
+			data.games++
+			data.leaveBookMove += float64(game.Moves[leaveBook].PlyNumber/2 + 1)
+			data.timeSpent += spent
+			data.clockShare += spent / float64(base)
 		}
 	}
+
+	signals := []models.TimeTroubleSignal{}
+	for _, id := range order {
+		data := groups[id]
+		if data.games < timeTroubleMinGames {
+			continue
+		}
+		n := float64(data.games)
+		if data.clockShare/n < timeTroubleMinShare {
+			continue
+		}
+		signals = append(signals, models.TimeTroubleSignal{
+			RepertoireID:     id,
+			RepertoireName:   data.name,
+			Games:            data.games,
+			AvgLeaveBookMove: data.leaveBookMove / n,
+			AvgTimeSpent:     data.timeSpent / n,
+			AvgClockShare:    data.clockShare / n,
+		})
+	}
+
+	sort.Slice(signals, func(i, j int) bool {
+		return signals[i].AvgClockShare > signals[j].AvgClockShare
+	})
+	return signals
 }
 
-func ensureFullFEN(fen string) string {
-	parts := strings.Fields(fen)
-	if len(parts) >= 6 {
-		return fen
+// leaveBookIndex returns the index of the user's first move out of book, or -1 when the
+// game never leaves it
+func leaveBookIndex(moves []models.MoveAnalysis) int {
+	for i, move := range moves {
+		if move.IsUserMove && move.Status != "in-repertoire" {
+			return i
+		}
 	}
-	if len(parts) == 4 {
-		return fen + " 0 1"
+	return -1
+}
+
+func sortMistakes(mistakes []models.OpeningMistake) {
+	for i := 1; i < len(mistakes); i++ {
+		for j := i; j > 0 && mistakes[j].Score > mistakes[j-1].Score; j-- {
+			mistakes[j], mistakes[j-1] = mistakes[j-1], mistakes[j]
+		}
 	}
-	return fen + " 0 1"
 }
 
 // classifyOutcome returns "win", "loss", or "draw" based on the PGN Result header and user's color.
@@ -914,6 +1744,7 @@ func (s *ImportService) GetDashboardStats(userID string) (*models.DashboardStats
 	if err != nil {
 		return nil, fmt.Errorf("failed to get analyses: %w", err)
 	}
+	analyses = ownGameAnalyses(analyses)
 
 	resp := &models.DashboardStatsResponse{
 		Repertoires: []models.RepertoireStats{},
@@ -931,6 +1762,7 @@ func (s *ImportService) GetDashboardStats(userID string) (*models.DashboardStats
 		outRepWins  int
 	}
 	repMap := make(map[string]*repAccum)
+	timeClasses := make(map[string]*timeClassAccum)
 
 	for _, a := range analyses {
 		for _, game := range a.Results {
@@ -958,6 +1790,15 @@ func (s *ImportService) GetDashboardStats(userID string) (*models.DashboardStats
 				resp.OutRepCount++
 			}
 
+			if timeClass := models.ClassifyTimeControl(game.Headers["TimeControl"]); timeClass != "" {
+				acc, ok := timeClasses[timeClass]
+				if !ok {
+					acc = &timeClassAccum{}
+					timeClasses[timeClass] = acc
+				}
+				acc.add(game, inRep, outcome == "win")
+			}
+
 			// Per-repertoire tracking
 			if game.MatchedRepertoire != nil {
 				repID := game.MatchedRepertoire.ID
@@ -1049,5 +1890,51 @@ func (s *ImportService) GetDashboardStats(userID string) (*models.DashboardStats
 		}
 	}
 
+	resp.TimeClasses = []models.TimeClassStats{}
+	for _, timeClass := range models.TimeClasses {
+		if acc, ok := timeClasses[timeClass]; ok {
+			resp.TimeClasses = append(resp.TimeClasses, acc.stats(timeClass))
+		}
+	}
+
 	return resp, nil
 }
+
+// timeClassAccum accumulates the dashboard statistics of the games of one time class
+type timeClassAccum struct {
+	gameCount     int
+	wins          int
+	inRepCount    int
+	leftBookCount int
+	leaveBookMove int
+}
+
+func (acc *timeClassAccum) add(game models.GameAnalysis, inRep, win bool) {
+	acc.gameCount++
+	if win {
+		acc.wins++
+	}
+	if inRep {
+		acc.inRepCount++
+	}
+	if leaveBook := leaveBookIndex(game.Moves); leaveBook >= 0 {
+		acc.leftBookCount++
+		acc.leaveBookMove += game.Moves[leaveBook].PlyNumber/2 + 1
+	}
+}
+
+func (acc *timeClassAccum) stats(timeClass string) models.TimeClassStats {
+	stats := models.TimeClassStats{
+		TimeClass:       timeClass,
+		GameCount:       acc.gameCount,
+		InRepCount:      acc.inRepCount,
+		OutRepCount:     acc.gameCount - acc.inRepCount,
+		CoveragePercent: float64(acc.inRepCount) / float64(acc.gameCount) * 100,
+		WinRate:         float64(acc.wins) / float64(acc.gameCount),
+		LeftBookCount:   acc.leftBookCount,
+	}
+	if acc.leftBookCount > 0 {
+		stats.AvgLeaveBookMove = float64(acc.leaveBookMove) / float64(acc.leftBookCount)
+	}
+	return stats
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -8,7 +9,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/notnil/chess"
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
 	"github.com/treechess/backend/internal/repository/mocks"
 )
 
@@ -269,7 +272,7 @@ func TestAnalyzeGame_CountMoves(t *testing.T) {
 		ColorToMove: models.ChessColorWhite,
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	assert.Len(t, analysis.Moves, 4)
 	assert.Equal(t, 0, analysis.Moves[0].PlyNumber)
@@ -297,13 +300,78 @@ func TestAnalyzeGame_WhiteMoveClassification(t *testing.T) {
 		ColorToMove: models.ChessColorWhite,
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	assert.Len(t, analysis.Moves, 2)
 	assert.True(t, analysis.Moves[0].IsUserMove)
 	assert.False(t, analysis.Moves[1].IsUserMove)
 }
 
+func TestAnalyzeGame_AnalysisDepth(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	games, err := svc.parsePGN(`[White "A"]
+[Black "B"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 1-0`)
+	require.NoError(t, err)
+	root := models.RepertoireNode{
+		ID:          "root",
+		FEN:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		ColorToMove: models.ChessColorWhite,
+	}
+
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 4)
+
+	require.Len(t, analysis.Moves, 6)
+	assert.Equal(t, "out-of-book", analysis.Moves[3].Status)
+	assert.Equal(t, "beyond-book", analysis.Moves[4].Status)
+	assert.Equal(t, "Bb5", analysis.Moves[4].SAN)
+	assert.Equal(t, "beyond-book", analysis.Moves[5].Status)
+}
+
+func TestReanalyzeGameFromMoves_KeepsBeyondBook(t *testing.T) {
+	svc := NewImportService(nil, nil)
+	game := &models.GameAnalysis{
+		Moves: []models.MoveAnalysis{
+			{PlyNumber: 0, SAN: "e4", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", Status: "out-of-book", IsUserMove: true},
+			{PlyNumber: 1, SAN: "e5", FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -", Status: "beyond-book"},
+		},
+	}
+	e4 := "e4"
+	repertoire := &models.Repertoire{ID: "rep-1", Name: "Main", TreeData: models.RepertoireNode{
+		FEN:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		Children: []*models.RepertoireNode{{Move: &e4, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -"}},
+	}}
+
+	result := svc.reanalyzeGameFromMoves(game, repertoire)
+
+	assert.Equal(t, "in-repertoire", result.Moves[0].Status)
+	assert.Equal(t, "beyond-book", result.Moves[1].Status)
+}
+
+func TestAnalysisPlies(t *testing.T) {
+	userRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			return &models.User{ID: id, AnalysisDepth: 12}, nil
+		},
+	}
+	svc := NewImportService(nil, nil, WithUserRepo(userRepo))
+
+	plies, err := svc.analysisPlies("user-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 24, plies)
+
+	override := 0
+	plies, err = svc.analysisPlies("user-1", &override)
+	require.NoError(t, err)
+	assert.Equal(t, 0, plies)
+
+	tooDeep := config.MaxAnalysisDepth + 1
+	_, err = svc.analysisPlies("user-1", &tooDeep)
+	assert.ErrorIs(t, err, ErrInvalidAnalysisDepth)
+}
+
 func TestAnalyzeGame_BlackRepertoire(t *testing.T) {
 	svc := NewImportService(nil, nil)
 
@@ -323,7 +391,7 @@ func TestAnalyzeGame_BlackRepertoire(t *testing.T) {
 		ColorToMove: models.ChessColorWhite,
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorBlack)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorBlack, 0)
 
 	assert.Len(t, analysis.Moves, 2)
 	assert.False(t, analysis.Moves[0].IsUserMove)
@@ -331,6 +399,62 @@ func TestAnalyzeGame_BlackRepertoire(t *testing.T) {
 	assert.True(t, analysis.Moves[1].IsUserMove)
 }
 
+func TestAnalyzeGame_FromPosition(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	games, err := svc.parsePGN(`[White "A"]
+[Black "B"]
+[Variant "From Position"]
+[SetUp "1"]
+[FEN "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"]
+
+1... c5 2. Nf3 d6 3. d4 *`)
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+
+	rootFEN := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -"
+	c5FEN, err := validateAndGetResultingFEN(rootFEN, "c5")
+	require.NoError(t, err)
+	nf3FEN, err := validateAndGetResultingFEN(c5FEN, "Nf3")
+	require.NoError(t, err)
+	d6FEN, err := validateAndGetResultingFEN(nf3FEN, "d6")
+	require.NoError(t, err)
+	c5, nf3, d6 := "c5", "Nf3", "d6"
+	root := models.RepertoireNode{
+		ID:          "root",
+		FEN:         rootFEN,
+		ColorToMove: models.ChessColorBlack,
+		Children: []*models.RepertoireNode{
+			{ID: "c5", FEN: c5FEN, Move: &c5, Children: []*models.RepertoireNode{
+				{ID: "nf3", FEN: nf3FEN, Move: &nf3, Children: []*models.RepertoireNode{
+					{ID: "d6", FEN: d6FEN, Move: &d6},
+				}},
+			}},
+		},
+	}
+
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorBlack, 0)
+
+	require.Len(t, analysis.Moves, 4)
+	assert.Equal(t, rootFEN, analysis.Moves[0].FEN, "the first move is played from the FEN header")
+	for i, want := range []struct {
+		san    string
+		user   bool
+		status string
+	}{
+		{"c5", true, "in-repertoire"},
+		{"Nf3", false, "in-repertoire"},
+		{"d6", true, "in-repertoire"},
+		{"d4", false, "out-of-book"},
+	} {
+		assert.Equal(t, want.san, analysis.Moves[i].SAN)
+		assert.Equal(t, want.user, analysis.Moves[i].IsUserMove, want.san)
+		assert.Equal(t, want.status, analysis.Moves[i].Status, want.san)
+	}
+
+	assert.Equal(t, []string{rootFEN, nf3FEN}, userMovePositions(games[0], models.ColorBlack, 0))
+}
+
 func TestAnalyzeGame_NoRepertoire(t *testing.T) {
 	svc := NewImportService(nil, nil)
 
@@ -350,7 +474,7 @@ func TestAnalyzeGame_NoRepertoire(t *testing.T) {
 		ColorToMove: models.ChessColorWhite,
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	assert.Len(t, analysis.Moves, 2)
 	assert.Equal(t, "out-of-book", analysis.Moves[0].Status) // Root has no children → out-of-book
@@ -449,6 +573,204 @@ func TestDetermineUserColor_LichessUsernameFormat(t *testing.T) {
 	assert.Equal(t, models.ColorBlack, color)
 }
 
+func TestParseAndAnalyze_MatchesLinkedAccounts(t *testing.T) {
+	userRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			user := &models.User{ID: id}
+			user.SetLinkedAccounts([]models.LinkedAccount{
+				{Provider: models.ProviderLichess, Username: "MainAccount"},
+				{Provider: models.ProviderChesscom, Username: "BlitzAlt"},
+			})
+			return user, nil
+		},
+	}
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(repSvc, analysisRepo, WithUserRepo(userRepo))
+
+	pgnData := `[White "someone"]
+[Black "blitzalt"]
+
+1. e4 c5 1-0
+
+[White "MainAccount"]
+[Black "someone"]
+
+1. d4 d5 0-1
+
+[White "stranger"]
+[Black "someone"]
+
+1. c4 e5 1/2-1/2`
+
+	_, results, err := svc.ParseAndAnalyze("mixed.pgn", "uploader", "user-1", pgnData)
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, models.ColorBlack, results[0].UserColor)
+	assert.Equal(t, models.ColorWhite, results[1].UserColor)
+}
+
+func TestParseAndAnalyze_RecordsProvenance(t *testing.T) {
+	var saved models.ImportProvenance
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			saved = provenance
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo)
+	pgnData := "[White \"me\"]\n[Black \"someone\"]\n\n1. e4 c5 1-0"
+
+	t.Run("explicit provenance is kept", func(t *testing.T) {
+		depth := 8
+		provenance := models.ImportProvenance{
+			Source: models.ImportSourceLichess,
+			SourceParams: models.ImportSourceParams{
+				Username:      "me",
+				AnalysisDepth: &depth,
+				Lichess:       &models.LichessImportOptions{Max: 50, PerfType: "blitz"},
+			},
+		}
+		_, _, err := svc.ParseAndAnalyzeWithOptions("lichess_me.pgn", "me", "user-1", pgnData, ImportOptions{Provenance: provenance})
+
+		require.NoError(t, err)
+		assert.Equal(t, provenance, saved)
+	})
+
+	for filename, want := range map[string]models.ImportProvenance{
+		"sync_chesscom_me.pgn": {Source: models.ImportSourceChesscom, SourceParams: models.ImportSourceParams{Username: "me", Sync: true}},
+		"games.pgn":            {Source: models.ImportSourcePGN, SourceParams: models.ImportSourceParams{Username: "me", Filename: "games.pgn"}},
+	} {
+		t.Run("derived from "+filename, func(t *testing.T) {
+			_, _, err := svc.ParseAndAnalyze(filename, "me", "user-1", pgnData)
+
+			require.NoError(t, err)
+			assert.Equal(t, want, saved)
+		})
+	}
+}
+
+func TestParseAndAnalyzeWithPolicy_Duplicates(t *testing.T) {
+	pgnData := `[Event "Casual"]
+[Site "https://lichess.org/dupgame1"]
+[White "me"]
+[Black "opponent"]
+
+1. e4 e5 1-0
+
+[Event "Casual"]
+[Site "https://lichess.org/newgame1"]
+[White "opponent"]
+[Black "me"]
+
+1. d4 d5 0-1`
+
+	newService := func(saved *[]models.GameAnalysis, updated *[]models.GameAnalysis) *ImportService {
+		fingerprintRepo := &mocks.MockFingerprintRepo{
+			CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+				existing := make(map[string]repository.GameLocation)
+				for _, fp := range fingerprints {
+					if strings.Contains(fp, "dupgame1") {
+						existing[fp] = repository.GameLocation{AnalysisID: "old-analysis", GameIndex: 7}
+					}
+				}
+				return existing, nil
+			},
+		}
+		analysisRepo := &mocks.MockAnalysisRepo{
+			SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+				*saved = results
+				return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+			},
+			UpdateGameFunc: func(analysisID string, game models.GameAnalysis) error {
+				assert.Equal(t, "old-analysis", analysisID)
+				*updated = append(*updated, game)
+				return nil
+			},
+		}
+		return NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo, WithFingerprintRepo(fingerprintRepo))
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		var saved, updated []models.GameAnalysis
+		summary, _, err := newService(&saved, &updated).ParseAndAnalyzeWithPolicy("f.pgn", "me", "user-1", pgnData, models.DuplicatePolicySkip)
+
+		require.NoError(t, err)
+		assert.Len(t, saved, 1)
+		assert.Empty(t, updated)
+		assert.Equal(t, 1, summary.SkippedDuplicates)
+		require.Len(t, summary.Duplicates, 1)
+		assert.Equal(t, "skipped", summary.Duplicates[0].Action)
+		assert.Equal(t, "old-analysis", summary.Duplicates[0].AnalysisID)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		var saved, updated []models.GameAnalysis
+		summary, _, err := newService(&saved, &updated).ParseAndAnalyzeWithPolicy("f.pgn", "me", "user-1", pgnData, models.DuplicatePolicyReplace)
+
+		require.NoError(t, err)
+		assert.Len(t, saved, 1)
+		require.Len(t, updated, 1)
+		assert.Equal(t, 7, updated[0].GameIndex)
+		assert.Equal(t, 0, summary.SkippedDuplicates)
+		require.Len(t, summary.Duplicates, 1)
+		assert.Equal(t, "replaced", summary.Duplicates[0].Action)
+	})
+
+	t.Run("keep-both", func(t *testing.T) {
+		var saved, updated []models.GameAnalysis
+		summary, _, err := newService(&saved, &updated).ParseAndAnalyzeWithPolicy("f.pgn", "me", "user-1", pgnData, models.DuplicatePolicyKeepBoth)
+
+		require.NoError(t, err)
+		assert.Len(t, saved, 2)
+		assert.Empty(t, updated)
+		require.Len(t, summary.Duplicates, 1)
+		assert.Equal(t, "kept", summary.Duplicates[0].Action)
+	})
+}
+
+func TestParseAndAnalyzeWithPolicy_AllReplaced(t *testing.T) {
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+			return map[string]repository.GameLocation{fingerprints[0]: {AnalysisID: "old-analysis", GameIndex: 0}}, nil
+		},
+	}
+	var recordedSkips []int
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			t.Fatal("no analysis should be created when every game was replaced")
+			return nil, nil
+		},
+		RecordSkippedDuplicatesFunc: func(userID, filename string, count int) error {
+			recordedSkips = append(recordedSkips, count)
+			return nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo, WithFingerprintRepo(fingerprintRepo))
+
+	pgnData := `[White "me"]
+[Black "opponent"]
+
+1. e4 e5 1-0`
+
+	summary, _, err := svc.ParseAndAnalyzeWithPolicy("f.pgn", "me", "user-1", pgnData, models.DuplicatePolicyReplace)
+	require.NoError(t, err)
+	assert.Empty(t, summary.ID)
+	require.Len(t, summary.Duplicates, 1)
+
+	assert.Empty(t, recordedSkips)
+
+	// Skipped games are counted for the import stats even though no analysis is stored
+	_, _, err = svc.ParseAndAnalyzeWithPolicy("f.pgn", "me", "user-1", pgnData, models.DuplicatePolicySkip)
+	assert.ErrorIs(t, err, ErrAllGamesDuplicate)
+	assert.Equal(t, []int{1}, recordedSkips)
+}
+
 // Additional tests for edge cases and better coverage
 
 func TestNewImportService(t *testing.T) {
@@ -715,7 +1037,7 @@ func TestAnalyzeGame_InRepertoire(t *testing.T) {
 		},
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	assert.Len(t, analysis.Moves, 2)
 	assert.Equal(t, "in-repertoire", analysis.Moves[0].Status)
@@ -751,7 +1073,7 @@ func TestAnalyzeGame_WithExpectedMove(t *testing.T) {
 		},
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	assert.Len(t, analysis.Moves, 2)
 	assert.Equal(t, "out-of-repertoire", analysis.Moves[0].Status)
@@ -1002,7 +1324,7 @@ func makeGameAnalysis(gameIndex int, headers models.PGNHeaders, moves []models.M
 func TestGetInsights_NoEngineService(t *testing.T) {
 	// Without engine service, GetInsights returns empty with engineAnalysisDone=true
 	svc := NewImportService(nil, nil)
-	insights, err := svc.GetInsights("user-1")
+	insights, err := svc.GetInsights("user-1", DefaultInsightsFilter())
 
 	require.NoError(t, err)
 	assert.NotNil(t, insights)
@@ -1055,7 +1377,7 @@ func TestGetInsights_WithExplorerStats(t *testing.T) {
 	engineSvc := NewEngineService(mockEvalRepo, mockAnalysisRepo)
 	svc := NewImportService(nil, mockAnalysisRepo, WithEngineService(engineSvc))
 
-	insights, err := svc.GetInsights("user-1")
+	insights, err := svc.GetInsights("user-1", DefaultInsightsFilter())
 
 	require.NoError(t, err)
 	assert.True(t, insights.EngineAnalysisDone)
@@ -1113,7 +1435,7 @@ func TestGetInsights_RecurringMistake(t *testing.T) {
 	engineSvc := NewEngineService(mockEvalRepo, mockAnalysisRepo)
 	svc := NewImportService(nil, mockAnalysisRepo, WithEngineService(engineSvc))
 
-	insights, err := svc.GetInsights("user-1")
+	insights, err := svc.GetInsights("user-1", DefaultInsightsFilter())
 
 	require.NoError(t, err)
 	assert.Len(t, insights.WorstMistakes, 1)
@@ -1124,6 +1446,139 @@ func TestGetInsights_RecurringMistake(t *testing.T) {
 	assert.Len(t, insights.WorstMistakes[0].Games, 2)
 }
 
+func TestGetInsights_Filters(t *testing.T) {
+	now := time.Now()
+	old := now.AddDate(0, -3, 0)
+	rep1 := &models.RepertoireRef{ID: "rep-1", Name: "London"}
+	rep2 := &models.RepertoireRef{ID: "rep-2", Name: "Sicilian"}
+	headers := models.PGNHeaders{"White": "A", "Black": "B", "TimeControl": "300+0"}
+	bulletHeaders := models.PGNHeaders{"White": "A", "Black": "B", "TimeControl": "60+0"}
+
+	analyses := []models.RawAnalysis{
+		makeRawAnalysis("a1", "recent.pgn", now, []models.GameAnalysis{
+			makeGameAnalysis(0, headers, nil, models.ColorWhite, rep1),
+			makeGameAnalysis(1, headers, nil, models.ColorWhite, rep1),
+			makeGameAnalysis(2, bulletHeaders, nil, models.ColorWhite, rep2),
+		}),
+		makeRawAnalysis("a2", "old.pgn", old, []models.GameAnalysis{
+			makeGameAnalysis(0, headers, nil, models.ColorWhite, rep1),
+		}),
+	}
+
+	stat := func(fen, played string, drop float64) models.ExplorerMoveStats {
+		return models.ExplorerMoveStats{PlyNumber: 4, FEN: fen, PlayedMove: played, BestMove: "Nc3", WinrateDrop: drop}
+	}
+	evals := func(analysisID string, gameIndex int) models.EngineEval {
+		return models.EngineEval{
+			AnalysisID: analysisID, GameIndex: gameIndex, Status: "done",
+			Evals: []models.ExplorerMoveStats{stat("fen-a", "Bf4", 0.08), stat("fen-b", "h3", 0.03)},
+		}
+	}
+	engineEvals := []models.EngineEval{evals("a1", 0), evals("a1", 1), evals("a1", 2), evals("a2", 0)}
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) {
+			return analyses, nil
+		},
+	}
+	mockEvalRepo := &mocks.MockEngineEvalRepo{
+		GetByUserFunc: func(userID string) ([]models.EngineEval, error) {
+			return engineEvals, nil
+		},
+	}
+	engineSvc := NewEngineService(mockEvalRepo, mockAnalysisRepo)
+	svc := NewImportService(nil, mockAnalysisRepo, WithEngineService(engineSvc))
+
+	t.Run("limit", func(t *testing.T) {
+		filter := DefaultInsightsFilter()
+		filter.Limit = 1
+		insights, err := svc.GetInsights("user-1", filter)
+		require.NoError(t, err)
+		require.Len(t, insights.WorstMistakes, 1)
+		assert.Equal(t, "Bf4", insights.WorstMistakes[0].PlayedMove)
+		assert.Equal(t, 4, insights.WorstMistakes[0].Frequency)
+		assert.Equal(t, map[string]int{"blitz": 3, "bullet": 1}, insights.WorstMistakes[0].TimeClasses)
+	})
+
+	t.Run("minDrop", func(t *testing.T) {
+		filter := DefaultInsightsFilter()
+		filter.MinDrop = 0.05
+		insights, err := svc.GetInsights("user-1", filter)
+		require.NoError(t, err)
+		require.Len(t, insights.WorstMistakes, 1)
+		assert.Equal(t, "Bf4", insights.WorstMistakes[0].PlayedMove)
+	})
+
+	t.Run("since and repertoire", func(t *testing.T) {
+		filter := DefaultInsightsFilter()
+		filter.Since = now.AddDate(0, -1, 0)
+		filter.RepertoireID = "rep-1"
+		insights, err := svc.GetInsights("user-1", filter)
+		require.NoError(t, err)
+		require.Len(t, insights.WorstMistakes, 2)
+		for _, m := range insights.WorstMistakes {
+			assert.Equal(t, 2, m.Frequency)
+		}
+	})
+
+	t.Run("timeClass", func(t *testing.T) {
+		filter := DefaultInsightsFilter()
+		filter.TimeClass = "bullet"
+		filter.MinFrequency = 1
+		insights, err := svc.GetInsights("user-1", filter)
+		require.NoError(t, err)
+		require.Len(t, insights.WorstMistakes, 2)
+		for _, m := range insights.WorstMistakes {
+			assert.Equal(t, 1, m.Frequency)
+			assert.Equal(t, map[string]int{"bullet": 1}, m.TimeClasses)
+		}
+	})
+}
+
+func TestGetDashboardStats_TimeClasses(t *testing.T) {
+	blitz := models.PGNHeaders{"Result": "1-0", "TimeControl": "180+2"}
+	rapid := models.PGNHeaders{"Result": "0-1", "TimeControl": "600+0"}
+	inBook := []models.MoveAnalysis{
+		{PlyNumber: 0, SAN: "e4", Status: "in-repertoire", IsUserMove: true},
+		{PlyNumber: 1, SAN: "e5", Status: "out-of-book"},
+	}
+	leftBook := []models.MoveAnalysis{
+		{PlyNumber: 0, SAN: "e4", Status: "in-repertoire", IsUserMove: true},
+		{PlyNumber: 1, SAN: "c5", Status: "in-repertoire"},
+		{PlyNumber: 2, SAN: "c3", Status: "out-of-repertoire", IsUserMove: true},
+	}
+	analyses := []models.RawAnalysis{
+		makeRawAnalysis("a1", "games.pgn", time.Now(), []models.GameAnalysis{
+			makeGameAnalysis(0, blitz, inBook, models.ColorWhite, nil),
+			makeGameAnalysis(1, blitz, leftBook, models.ColorWhite, nil),
+			makeGameAnalysis(2, rapid, leftBook, models.ColorWhite, nil),
+		}),
+	}
+	svc := NewImportService(nil, &mocks.MockAnalysisRepo{
+		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) {
+			return analyses, nil
+		},
+	})
+
+	stats, err := svc.GetDashboardStats("user-1")
+
+	require.NoError(t, err)
+	require.Len(t, stats.TimeClasses, 2)
+	assert.Equal(t, models.TimeClassStats{
+		TimeClass:        "blitz",
+		GameCount:        2,
+		InRepCount:       1,
+		OutRepCount:      1,
+		CoveragePercent:  50,
+		WinRate:          1,
+		LeftBookCount:    1,
+		AvgLeaveBookMove: 2,
+	}, stats.TimeClasses[0])
+	assert.Equal(t, "rapid", stats.TimeClasses[1].TimeClass)
+	assert.Equal(t, 1, stats.TimeClasses[1].OutRepCount)
+	assert.Zero(t, stats.TimeClasses[1].WinRate)
+}
+
 func TestGetInsights_Empty(t *testing.T) {
 	mockAnalysisRepo := &mocks.MockAnalysisRepo{
 		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) {
@@ -1138,7 +1593,7 @@ func TestGetInsights_Empty(t *testing.T) {
 
 	engineSvc := NewEngineService(mockEvalRepo, mockAnalysisRepo)
 	svc := NewImportService(nil, mockAnalysisRepo, WithEngineService(engineSvc))
-	insights, err := svc.GetInsights("user-1")
+	insights, err := svc.GetInsights("user-1", DefaultInsightsFilter())
 
 	require.NoError(t, err)
 	assert.NotNil(t, insights)
@@ -1146,6 +1601,62 @@ func TestGetInsights_Empty(t *testing.T) {
 	assert.True(t, insights.EngineAnalysisDone)
 }
 
+func TestGetInsights_TimeTrouble(t *testing.T) {
+	now := time.Now()
+	spent := func(v float64) *float64 { return &v }
+	rep := &models.RepertoireRef{ID: "rep-1", Name: "London"}
+	headers := models.PGNHeaders{"White": "A", "Black": "B", "TimeControl": "300+0"}
+
+	// Leaves book on move 3 (ply 4) and spends 30+20+10 seconds on the next user moves
+	gameMoves := []models.MoveAnalysis{
+		{PlyNumber: 0, SAN: "d4", Status: "in-repertoire", IsUserMove: true, TimeSpent: spent(1)},
+		{PlyNumber: 1, SAN: "d5", Status: "in-repertoire", TimeSpent: spent(1)},
+		{PlyNumber: 2, SAN: "Bf4", Status: "in-repertoire", IsUserMove: true, TimeSpent: spent(1)},
+		{PlyNumber: 3, SAN: "c5", Status: "opponent-new", TimeSpent: spent(5)},
+		{PlyNumber: 4, SAN: "e3", Status: "out-of-book", IsUserMove: true, TimeSpent: spent(30)},
+		{PlyNumber: 5, SAN: "Nc6", Status: "out-of-book", TimeSpent: spent(2)},
+		{PlyNumber: 6, SAN: "c3", Status: "out-of-book", IsUserMove: true, TimeSpent: spent(20)},
+		{PlyNumber: 7, SAN: "Qb6", Status: "out-of-book", TimeSpent: spent(2)},
+		{PlyNumber: 8, SAN: "Qb3", Status: "out-of-book", IsUserMove: true, TimeSpent: spent(10)},
+	}
+	noClock := []models.MoveAnalysis{
+		{PlyNumber: 0, SAN: "d4", Status: "out-of-book", IsUserMove: true},
+	}
+
+	analyses := []models.RawAnalysis{
+		makeRawAnalysis("a1", "lichess.pgn", now, []models.GameAnalysis{
+			makeGameAnalysis(0, headers, gameMoves, models.ColorWhite, rep),
+			makeGameAnalysis(1, headers, gameMoves, models.ColorWhite, rep),
+			makeGameAnalysis(2, headers, noClock, models.ColorWhite, rep),
+			makeGameAnalysis(3, headers, gameMoves, models.ColorWhite, nil),
+		}),
+	}
+
+	mockAnalysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) {
+			return analyses, nil
+		},
+	}
+	mockEvalRepo := &mocks.MockEngineEvalRepo{
+		GetByUserFunc: func(userID string) ([]models.EngineEval, error) {
+			return nil, nil
+		},
+	}
+
+	engineSvc := NewEngineService(mockEvalRepo, mockAnalysisRepo)
+	svc := NewImportService(nil, mockAnalysisRepo, WithEngineService(engineSvc))
+	insights, err := svc.GetInsights("user-1", DefaultInsightsFilter())
+
+	require.NoError(t, err)
+	require.Len(t, insights.TimeTrouble, 1)
+	signal := insights.TimeTrouble[0]
+	assert.Equal(t, "rep-1", signal.RepertoireID)
+	assert.Equal(t, 2, signal.Games)
+	assert.InDelta(t, 3, signal.AvgLeaveBookMove, 0.001)
+	assert.InDelta(t, 60, signal.AvgTimeSpent, 0.001)
+	assert.InDelta(t, 0.2, signal.AvgClockShare, 0.001)
+}
+
 func TestAnalyzeGame_RepertoireExhaustion(t *testing.T) {
 	// Game follows all prep, tree runs out, remaining moves are "out-of-book"
 	svc := NewImportService(nil, nil)
@@ -1187,7 +1698,7 @@ func TestAnalyzeGame_RepertoireExhaustion(t *testing.T) {
 		},
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	require.Len(t, analysis.Moves, 4)
 	// Move 0 (e4): root has child e4 → in-repertoire
@@ -1242,7 +1753,7 @@ func TestAnalyzeGame_TrueDeviation(t *testing.T) {
 		},
 	}
 
-	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite)
+	analysis := svc.analyzeGame(0, games[0], root, models.ColorWhite, 0)
 
 	require.Len(t, analysis.Moves, 2)
 	// Move 0 (d4): root has children but d4 not among them → out-of-repertoire
@@ -1290,3 +1801,301 @@ func TestGameStatusFromMoves_OpponentNewIsNewLine(t *testing.T) {
 	status := gameStatusFromMoves(moves)
 	assert.Equal(t, "new-line", status)
 }
+
+func TestEnqueueRepertoireReanalysis_NotOwned(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) {
+			return false, nil
+		},
+	})
+	jobRepo := &mocks.MockReanalysisJobRepo{
+		CreateFunc: func(userID, repertoireID string) (*models.ReanalysisJob, error) {
+			t.Fatal("job should not be created for a foreign repertoire")
+			return nil, nil
+		},
+	}
+	svc := NewImportService(repSvc, &mocks.MockAnalysisRepo{}, WithReanalysisJobRepo(jobRepo))
+
+	_, err := svc.EnqueueRepertoireReanalysis("user-1", "rep-1")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetReanalysisJob_OtherUser(t *testing.T) {
+	jobRepo := &mocks.MockReanalysisJobRepo{
+		GetByIDFunc: func(id string) (*models.ReanalysisJob, error) {
+			return &models.ReanalysisJob{ID: id, UserID: "someone-else"}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), &mocks.MockAnalysisRepo{}, WithReanalysisJobRepo(jobRepo))
+
+	_, err := svc.GetReanalysisJob("job-1", "user-1")
+
+	assert.ErrorIs(t, err, repository.ErrReanalysisJobNotFound)
+}
+
+func TestRunReanalysisJob(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{
+				ID:    id,
+				Name:  "Updated",
+				Color: models.ColorWhite,
+				TreeData: models.RepertoireNode{
+					ID:  "root",
+					FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+				},
+			}, nil
+		},
+	})
+
+	var updated []models.GameAnalysis
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameLocationsByRepertoireFunc: func(userID, repertoireID string) ([]repository.GameLocation, error) {
+			return []repository.GameLocation{
+				{AnalysisID: "analysis-1", GameIndex: 0},
+				{AnalysisID: "analysis-1", GameIndex: 1},
+			}, nil
+		},
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			if gameIndex == 1 {
+				return nil, repository.ErrGameNotFound
+			}
+			return &models.GameAnalysis{
+				GameIndex: gameIndex,
+				UserColor: models.ColorWhite,
+				Moves: []models.MoveAnalysis{
+					{PlyNumber: 0, SAN: "e4", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", IsUserMove: true},
+				},
+			}, nil
+		},
+		UpdateGameFunc: func(analysisID string, game models.GameAnalysis) error {
+			updated = append(updated, game)
+			return nil
+		},
+	}
+
+	var total int
+	var progress []int
+	done := false
+	jobRepo := &mocks.MockReanalysisJobRepo{
+		MarkProcessingFunc: func(id string, n int) error {
+			total = n
+			return nil
+		},
+		UpdateProgressFunc: func(id string, processed int) error {
+			progress = append(progress, processed)
+			return nil
+		},
+		MarkDoneFunc: func(id string) error {
+			done = true
+			return nil
+		},
+	}
+	svc := NewImportService(repSvc, analysisRepo, WithReanalysisJobRepo(jobRepo))
+
+	err := svc.runReanalysisJob(models.ReanalysisJob{ID: "job-1", UserID: "user-1", RepertoireID: "rep-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []int{1, 2}, progress)
+	assert.True(t, done)
+	require.Len(t, updated, 1)
+	require.NotNil(t, updated[0].MatchedRepertoire)
+	assert.Equal(t, "Updated", updated[0].MatchedRepertoire.Name)
+	assert.Equal(t, "out-of-book", updated[0].Moves[0].Status)
+}
+
+func TestRepertoireMatcher_UsesPositionIndex(t *testing.T) {
+	e4, d4, e5, nf3 := "e4", "d4", "e5", "Nf3"
+	startFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+	reps := []models.Repertoire{
+		{ID: "rep-d4", Name: "d4", Color: models.ColorWhite, TreeData: models.RepertoireNode{
+			ID: "root-d4", FEN: startFEN,
+			Children: []*models.RepertoireNode{{ID: "d4", Move: &d4, FEN: "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq -"}},
+		}},
+		{ID: "rep-e4", Name: "e4", Color: models.ColorWhite, TreeData: models.RepertoireNode{
+			ID: "root-e4", FEN: startFEN,
+			Children: []*models.RepertoireNode{{
+				ID: "e4", Move: &e4, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3",
+				Children: []*models.RepertoireNode{{
+					ID: "e5", Move: &e5, FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6",
+					Children: []*models.RepertoireNode{{ID: "nf3", Move: &nf3, FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq -"}},
+				}},
+			}},
+		}},
+	}
+	// The default mock FindPositions indexes the trees returned by GetAllFunc
+	index := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) { return reps, nil },
+	}
+	var queried []string
+	repo := &mocks.MockRepertoireRepo{
+		GetByColorFunc: func(userID string, color models.Color) ([]models.Repertoire, error) {
+			if color == models.ColorWhite {
+				return reps, nil
+			}
+			return nil, nil
+		},
+		FindPositionsFunc: func(userID string, fens []string) ([]models.RepertoirePosition, error) {
+			queried = append(queried, fens...)
+			return index.FindPositions(userID, fens)
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(repo), analysisRepo)
+
+	pgnData := `[White "me"]
+[Black "opponent"]
+
+1. e4 e5 2. Nf3 Nc6 1-0`
+
+	_, results, err := svc.ParseAndAnalyze("f.pgn", "me", "user-1", pgnData)
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NotNil(t, results[0].MatchedRepertoire)
+	assert.Equal(t, "rep-e4", results[0].MatchedRepertoire.ID)
+	assert.Equal(t, 2, results[0].MatchScore)
+	// The positions before each of White's moves are looked up in a single query
+	assert.Len(t, queried, 2)
+}
+
+func TestParseAndAnalyzeWithOptions_Reference(t *testing.T) {
+	e4, c5, d4 := "e4", "c5", "d4"
+	startFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+	white := []models.Repertoire{
+		{ID: "rep-d4", Name: "d4", Color: models.ColorWhite, TreeData: models.RepertoireNode{
+			ID: "root-d4", FEN: startFEN,
+			Children: []*models.RepertoireNode{{ID: "d4", Move: &d4, FEN: "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq -"}},
+		}},
+	}
+	black := []models.Repertoire{
+		{ID: "rep-sicilian", Name: "Sicilian", Color: models.ColorBlack, TreeData: models.RepertoireNode{
+			ID: "root-sicilian", FEN: startFEN,
+			Children: []*models.RepertoireNode{{
+				ID: "e4", Move: &e4, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3",
+				Children: []*models.RepertoireNode{{ID: "c5", Move: &c5, FEN: "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6"}},
+			}},
+		}},
+	}
+	index := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) { return append(white, black...), nil },
+	}
+	repo := &mocks.MockRepertoireRepo{
+		GetByColorFunc: func(userID string, color models.Color) ([]models.Repertoire, error) {
+			if color == models.ColorWhite {
+				return white, nil
+			}
+			return black, nil
+		},
+		FindPositionsFunc: index.FindPositions,
+	}
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+			t.Fatal("reference games must not be checked for duplicates")
+			return nil, nil
+		},
+		SaveBatchFunc: func(userID, analysisID string, entries []repository.FingerprintEntry) error {
+			t.Fatal("reference games must not be fingerprinted")
+			return nil
+		},
+	}
+	var savedFilename string
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			savedFilename = filename
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(repo), analysisRepo, WithFingerprintRepo(fingerprintRepo))
+
+	pgnData := `[White "Carlsen"]
+[Black "Nepomniachtchi"]
+
+1. e4 c5 2. Nf3 d6 1-0
+
+[White "Caruana"]
+[Black "Ding"]
+
+1. c4 e5 1/2-1/2`
+
+	summary, results, err := svc.ParseAndAnalyzeWithOptions(BroadcastFilename("abcdef12"), "broadcast", "user-1", pgnData,
+		ImportOptions{Reference: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.GameCount)
+	assert.Equal(t, "broadcast_abcdef12.pgn", savedFilename)
+	require.Len(t, results, 2)
+	assert.Equal(t, models.ColorBlack, results[0].UserColor)
+	require.NotNil(t, results[0].MatchedRepertoire)
+	assert.Equal(t, "rep-sicilian", results[0].MatchedRepertoire.ID)
+	assert.Equal(t, 1, results[0].MatchScore)
+	// A game no repertoire follows is still kept, seen from White
+	assert.Equal(t, models.ColorWhite, results[1].UserColor)
+	assert.Equal(t, 0, results[1].MatchScore)
+}
+
+func TestBackfillFingerprints(t *testing.T) {
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) {
+			return []models.RawAnalysis{
+				{ID: "analysis-1", Results: []models.GameAnalysis{
+					{GameIndex: 0, Headers: models.PGNHeaders{"Site": "https://lichess.org/abc"}},
+					{GameIndex: 2, Headers: models.PGNHeaders{"Site": "https://lichess.org/def"}},
+				}},
+				{ID: "analysis-2"},
+			}, nil
+		},
+	}
+	saved := map[string][]repository.FingerprintEntry{}
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		SaveBatchFunc: func(userID, analysisID string, entries []repository.FingerprintEntry) error {
+			saved[analysisID] = entries
+			return nil
+		},
+	}
+	svc := NewImportService(nil, analysisRepo, WithFingerprintRepo(fingerprintRepo))
+
+	processed, err := svc.BackfillFingerprints("user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	require.Len(t, saved["analysis-1"], 2)
+	assert.Equal(t, 2, saved["analysis-1"][1].GameIndex)
+	assert.NotEqual(t, saved["analysis-1"][0].Fingerprint, saved["analysis-1"][1].Fingerprint)
+}
+
+func TestUpdateGameNotes(t *testing.T) {
+	var savedNote *string
+	analysisRepo := &mocks.MockAnalysisRepo{
+		UpdateGameNotesFunc: func(analysisID string, gameIndex int, note *string, starred *bool) (*models.GameNotes, error) {
+			savedNote = note
+			return &models.GameNotes{AnalysisID: analysisID, GameIndex: gameIndex, Note: *note}, nil
+		},
+	}
+	svc := NewImportService(nil, analysisRepo)
+
+	note := "  missed Nxe5  "
+	notes, err := svc.UpdateGameNotes("analysis-1", 3, models.UpdateGameRequest{Note: &note})
+
+	require.NoError(t, err)
+	require.NotNil(t, savedNote)
+	assert.Equal(t, "missed Nxe5", *savedNote)
+	assert.Equal(t, 3, notes.GameIndex)
+}
+
+func TestUpdateGameNotes_Invalid(t *testing.T) {
+	svc := NewImportService(nil, &mocks.MockAnalysisRepo{})
+	long := strings.Repeat("x", config.MaxGameNoteLen+1)
+
+	_, err := svc.UpdateGameNotes("analysis-1", 0, models.UpdateGameRequest{})
+	assert.ErrorIs(t, err, ErrEmptyGameUpdate)
+
+	_, err = svc.UpdateGameNotes("analysis-1", 0, models.UpdateGameRequest{Note: &long})
+	assert.ErrorIs(t, err, ErrGameNoteTooLong)
+}
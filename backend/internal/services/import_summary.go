@@ -0,0 +1,60 @@
+package services
+
+import (
+	"log"
+	"sort"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// WithImportSummaries records a summary of every import run, with the games it dropped or
+// could not match, so the run can be audited after the fact
+func WithImportSummaries(repo repository.ImportSummaryRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.importSummaryRepo = repo
+	}
+}
+
+// GetImportSummary returns the summary of one of the user's import runs
+func (s *ImportService) GetImportSummary(analysisID, userID string) (*models.ImportSummary, error) {
+	if s.importSummaryRepo == nil {
+		return nil, repository.ErrImportSummaryNotFound
+	}
+	return s.importSummaryRepo.GetByAnalysisID(analysisID, userID)
+}
+
+// saveImportSummary records the summary of a saved import. A failure is logged: the games
+// are already imported and the summary only describes them.
+func (s *ImportService) saveImportSummary(userID string, gamesFound int, summary *models.AnalysisSummary, gamesImported int) {
+	if s.importSummaryRepo == nil {
+		return
+	}
+	err := s.importSummaryRepo.Save(userID, models.ImportSummary{
+		AnalysisID:    summary.ID,
+		GamesFound:    gamesFound,
+		GamesImported: gamesImported,
+		Warnings:      summary.Warnings,
+	})
+	if err != nil {
+		log.Printf("import: failed to save import summary for %s: %v", summary.ID, err)
+	}
+}
+
+func gameWarning(kind models.ImportWarningKind, game int, headers map[string]string, message string) models.ImportWarning {
+	return models.ImportWarning{
+		Kind:    kind,
+		Game:    game,
+		White:   headers["White"],
+		Black:   headers["Black"],
+		Date:    headers["Date"],
+		Message: message,
+	}
+}
+
+// sortImportWarnings orders warnings by the position of their game in the PGN
+func sortImportWarnings(warnings []models.ImportWarning) {
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Game < warnings[j].Game
+	})
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestParseAndAnalyze_SavesImportSummary(t *testing.T) {
+	pgnData := `[Site "https://lichess.org/dupgame1"]
+[White "me"]
+[Black "opponent"]
+
+1. e4 e5 1-0
+
+[White "someone"]
+[Black "else"]
+[Date "2024.01.02"]
+
+1. d4 d5 1/2-1/2
+
+[White "me"]
+[Black "broken"]
+
+1. e4 Ke7 1-0
+
+[White "opponent"]
+[Black "me"]
+
+1. c4 e5 0-1`
+
+	fingerprintRepo := &mocks.MockFingerprintRepo{
+		CheckExistingFunc: func(userID string, fingerprints []string) (map[string]repository.GameLocation, error) {
+			existing := make(map[string]repository.GameLocation)
+			for _, fp := range fingerprints {
+				if strings.Contains(fp, "dupgame1") {
+					existing[fp] = repository.GameLocation{AnalysisID: "old-analysis", GameIndex: 0}
+				}
+			}
+			return existing, nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	var savedUserID string
+	var saved models.ImportSummary
+	summaryRepo := &mocks.MockImportSummaryRepo{
+		SaveFunc: func(userID string, summary models.ImportSummary) error {
+			savedUserID = userID
+			saved = summary
+			return nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo,
+		WithFingerprintRepo(fingerprintRepo), WithImportSummaries(summaryRepo))
+
+	summary, _, err := svc.ParseAndAnalyze("f.pgn", "me", "user-1", pgnData)
+
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", savedUserID)
+	assert.Equal(t, "analysis-1", saved.AnalysisID)
+	assert.Equal(t, 4, saved.GamesFound)
+	assert.Equal(t, 1, saved.GamesImported)
+	assert.Equal(t, summary.Warnings, saved.Warnings)
+
+	require.Len(t, saved.Warnings, 4)
+	assert.Equal(t, models.ImportWarning{Kind: models.ImportWarningDuplicate, Game: 1, White: "me", Black: "opponent", Message: "skipped"}, saved.Warnings[0])
+	assert.Equal(t, models.ImportWarning{Kind: models.ImportWarningUserNotFound, Game: 2, White: "someone", Black: "else", Date: "2024.01.02"}, saved.Warnings[1])
+	assert.Equal(t, models.ImportWarningParseFailed, saved.Warnings[2].Kind)
+	assert.Equal(t, 3, saved.Warnings[2].Game)
+	assert.Equal(t, "broken", saved.Warnings[2].Black)
+	assert.Equal(t, models.ImportWarning{Kind: models.ImportWarningNoRepertoire, Game: 4, White: "opponent", Black: "me"}, saved.Warnings[3])
+}
+
+func TestParseAndAnalyze_ImportSummaryFailureKeepsImport(t *testing.T) {
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	summaryRepo := &mocks.MockImportSummaryRepo{
+		SaveFunc: func(userID string, summary models.ImportSummary) error {
+			return errors.New("db down")
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo, WithImportSummaries(summaryRepo))
+
+	summary, _, err := svc.ParseAndAnalyze("f.pgn", "me", "user-1", "[White \"me\"]\n[Black \"opponent\"]\n\n1. e4 e5 1-0")
+
+	require.NoError(t, err)
+	assert.Equal(t, "analysis-1", summary.ID)
+}
+
+func TestGetImportSummary_NotConfigured(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	_, err := svc.GetImportSummary("analysis-1", "user-1")
+
+	assert.ErrorIs(t, err, repository.ErrImportSummaryNotFound)
+}
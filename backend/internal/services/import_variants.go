@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+var ErrOnlyVariantGames = fmt.Errorf("only chess variant games were found; include variants in the import to keep the readable ones")
+
+// chessVariant returns the chess variant named by a Variant header, empty for standard chess.
+// Lichess writes "Standard" or "From Position" for standard games, and the variant name, e.g.
+// "Atomic" or "Crazyhouse", for the others.
+func chessVariant(header string) string {
+	variant := strings.TrimSpace(header)
+	switch strings.ToLower(variant) {
+	case "", "standard", "from position":
+		return ""
+	}
+	return variant
+}
+
+func gameVariant(game *chess.Game) string {
+	tag := game.GetTagPair("Variant")
+	if tag == nil {
+		return ""
+	}
+	return chessVariant(tag.Value)
+}
+
+// withoutVariants drops the games of chess variants, with a warning for each. Their moves may
+// happen to be legal in standard chess, but matching them against a repertoire means nothing.
+func (s *ImportService) withoutVariants(p parsedPGN) parsedPGN {
+	kept := parsedPGN{warnings: p.warnings, found: p.found}
+	for i, game := range p.games {
+		if variant := gameVariant(game); variant != "" {
+			kept.warnings = append(kept.warnings, gameWarning(models.ImportWarningVariant, p.positions[i], s.extractHeaders(game),
+				fmt.Sprintf("skipped: %s is a chess variant", variant)))
+			continue
+		}
+		kept.games = append(kept.games, game)
+		kept.annotations = append(kept.annotations, p.annotations[i])
+		kept.raws = append(kept.raws, p.raws[i])
+		kept.positions = append(kept.positions, p.positions[i])
+	}
+	return kept
+}
+
+// skippedVariants counts the games dropped by withoutVariants
+func (p parsedPGN) skippedVariants() int {
+	count := 0
+	for _, warning := range p.warnings {
+		if warning.Kind == models.ImportWarningVariant {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const variantTestPGN = `[White "me"]
+[Black "opponent"]
+[Variant "Atomic"]
+
+1. e4 e5 1-0
+
+[White "me"]
+[Black "other"]
+[Variant "Crazyhouse"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 Nf6 4. Nc3 Bc5 5. Bxf7+ Kxf7 6. N@g5+ 1-0
+
+[White "me"]
+[Black "third"]
+[Variant "Standard"]
+
+1. e4 c5 1-0`
+
+func newVariantTestService(t *testing.T) *ImportService {
+	t.Helper()
+	tree := newLabelTestTree(t, "e4 e5")
+	repertoireRepo := &mocks.MockRepertoireRepo{
+		GetByColorFunc: func(userID string, color models.Color) ([]models.Repertoire, error) {
+			if color != models.ColorWhite {
+				return nil, nil
+			}
+			return []models.Repertoire{{ID: "rep-1", Name: "1.e4", Color: models.ColorWhite, TreeData: tree}}, nil
+		},
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	return NewImportService(NewRepertoireService(repertoireRepo), analysisRepo)
+}
+
+func TestChessVariant(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"Standard", ""},
+		{"From Position", ""},
+		{" standard ", ""},
+		{"Atomic", "Atomic"},
+		{"Crazyhouse", "Crazyhouse"},
+		{"Antichess", "Antichess"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, chessVariant(tt.header), tt.header)
+	}
+}
+
+func TestParseAndAnalyze_SkipsVariantGames(t *testing.T) {
+	svc := newVariantTestService(t)
+
+	summary, results, err := svc.ParseAndAnalyzeWithOptions("f.pgn", "me", "user-1", variantTestPGN, ImportOptions{})
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "third", results[0].Headers["Black"])
+	require.NotNil(t, results[0].MatchedRepertoire)
+	assert.Equal(t, []models.ImportWarning{
+		{Kind: models.ImportWarningVariant, Game: 1, White: "me", Black: "opponent", Message: "skipped: Atomic is a chess variant"},
+		{Kind: models.ImportWarningVariant, Game: 2, White: "me", Black: "other", Message: "skipped: Crazyhouse moves cannot be read as standard chess"},
+	}, summary.Warnings)
+}
+
+func TestParseAndAnalyze_IncludesVariantGamesWithoutMatching(t *testing.T) {
+	svc := newVariantTestService(t)
+
+	summary, results, err := svc.ParseAndAnalyzeWithOptions("f.pgn", "me", "user-1", variantTestPGN, ImportOptions{IncludeVariants: true})
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Atomic", results[0].Headers["Variant"])
+	assert.Nil(t, results[0].MatchedRepertoire, "a variant game is not matched even when its moves are in a repertoire")
+	require.NotNil(t, results[1].MatchedRepertoire)
+	assert.Equal(t, []models.ImportWarning{
+		{Kind: models.ImportWarningVariant, Game: 1, White: "me", Black: "opponent", Message: "imported without matching: Atomic is a chess variant"},
+		{Kind: models.ImportWarningVariant, Game: 2, White: "me", Black: "other", Message: "skipped: Crazyhouse moves cannot be read as standard chess"},
+	}, summary.Warnings)
+}
+
+func TestParseAndAnalyze_OnlyVariantGames(t *testing.T) {
+	svc := newVariantTestService(t)
+	pgn := "[White \"me\"]\n[Black \"opponent\"]\n[Variant \"Antichess\"]\n\n1. e3 b5 2. Bxb5 1-0"
+
+	_, _, err := svc.ParseAndAnalyzeWithOptions("f.pgn", "me", "user-1", pgn, ImportOptions{})
+
+	assert.ErrorIs(t, err, ErrOnlyVariantGames)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrInvalidInsightSettings is returned when an insight threshold is out of range
+var ErrInvalidInsightSettings = fmt.Errorf("minDrop must be between 0 and 1, minPly between 1 and %d, minFrequency between 1 and %d and maxMistakes between 1 and %d",
+	config.MaxInsightsMinPly, config.MaxInsightsMinFrequency, config.MaxInsightsLimit)
+
+// WithInsightSettingsRepo lets users replace the default mistake thresholds of the insights with their own
+func WithInsightSettingsRepo(repo repository.InsightSettingsRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.insightSettingsRepo = repo
+	}
+}
+
+// DefaultInsightSettings returns the thresholds used for users who never saved their own
+func DefaultInsightSettings() models.InsightSettings {
+	return models.InsightSettings{
+		MinDrop:      config.DefaultInsightsMinDrop,
+		MinPly:       config.DefaultInsightsMinPly,
+		MinFrequency: config.DefaultInsightsMinFrequency,
+		MaxMistakes:  config.DefaultInsightsLimit,
+	}
+}
+
+// GetInsightSettings returns the user's insight thresholds, or the defaults when none were saved
+func (s *ImportService) GetInsightSettings(userID string) (*models.InsightSettings, error) {
+	defaults := DefaultInsightSettings()
+	if s.insightSettingsRepo == nil {
+		return &defaults, nil
+	}
+
+	settings, err := s.insightSettingsRepo.Get(userID)
+	if errors.Is(err, repository.ErrInsightSettingsNotFound) {
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateInsightSettings validates and saves the user's insight thresholds
+func (s *ImportService) UpdateInsightSettings(userID string, settings models.InsightSettings) (*models.InsightSettings, error) {
+	if settings.MinDrop < 0 || settings.MinDrop > 1 ||
+		settings.MinPly < 1 || settings.MinPly > config.MaxInsightsMinPly ||
+		settings.MinFrequency < 1 || settings.MinFrequency > config.MaxInsightsMinFrequency ||
+		settings.MaxMistakes < 1 || settings.MaxMistakes > config.MaxInsightsLimit {
+		return nil, ErrInvalidInsightSettings
+	}
+	if s.insightSettingsRepo == nil {
+		return nil, fmt.Errorf("insight settings are not configured")
+	}
+
+	if err := s.insightSettingsRepo.Save(userID, settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// InsightsFilterFor returns the insights filter built from the user's thresholds, to be narrowed by the caller
+func (s *ImportService) InsightsFilterFor(userID string) (models.InsightsFilter, error) {
+	settings, err := s.GetInsightSettings(userID)
+	if err != nil {
+		return models.InsightsFilter{}, err
+	}
+	return models.InsightsFilter{
+		Limit:        settings.MaxMistakes,
+		MinDrop:      settings.MinDrop,
+		MinPly:       settings.MinPly,
+		MinFrequency: settings.MinFrequency,
+		Location:     userLocation(s.userRepo, userID),
+	}, nil
+}
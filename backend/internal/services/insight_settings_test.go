@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestGetInsightSettings_DefaultsWhenNeverSaved(t *testing.T) {
+	svc := NewImportService(nil, nil, WithInsightSettingsRepo(&mocks.MockInsightSettingsRepo{}))
+
+	settings, err := svc.GetInsightSettings("user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, DefaultInsightSettings(), *settings)
+}
+
+func TestUpdateInsightSettings(t *testing.T) {
+	var saved models.InsightSettings
+	repo := &mocks.MockInsightSettingsRepo{
+		SaveFunc: func(userID string, settings models.InsightSettings) error {
+			saved = settings
+			return nil
+		},
+	}
+	svc := NewImportService(nil, nil, WithInsightSettingsRepo(repo))
+
+	t.Run("saves valid thresholds", func(t *testing.T) {
+		req := models.InsightSettings{MinDrop: 0.05, MinPly: 6, MinFrequency: 3, MaxMistakes: 10}
+		settings, err := svc.UpdateInsightSettings("user-1", req)
+
+		require.NoError(t, err)
+		assert.Equal(t, req, *settings)
+		assert.Equal(t, req, saved)
+	})
+
+	for name, req := range map[string]models.InsightSettings{
+		"drop above one":     {MinDrop: 1.5, MinPly: 3, MinFrequency: 2, MaxMistakes: 2},
+		"zero ply":           {MinDrop: 0.02, MinPly: 0, MinFrequency: 2, MaxMistakes: 2},
+		"frequency too high": {MinDrop: 0.02, MinPly: 3, MinFrequency: config.MaxInsightsMinFrequency + 1, MaxMistakes: 2},
+		"no mistakes":        {MinDrop: 0.02, MinPly: 3, MinFrequency: 2, MaxMistakes: 0},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := svc.UpdateInsightSettings("user-1", req)
+			assert.ErrorIs(t, err, ErrInvalidInsightSettings)
+		})
+	}
+}
+
+func TestGetInsights_UserThresholds(t *testing.T) {
+	analyses := []models.RawAnalysis{
+		makeRawAnalysis("a1", "lichess_user.pgn", time.Now(), []models.GameAnalysis{
+			makeGameAnalysis(0, models.PGNHeaders{"White": "A", "Black": "B", "Result": "1-0"}, nil, models.ColorWhite, nil),
+		}),
+	}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesRawFunc: func(userID string) ([]models.RawAnalysis, error) { return analyses, nil },
+	}
+	evalRepo := &mocks.MockEngineEvalRepo{
+		GetByUserFunc: func(userID string) ([]models.EngineEval, error) {
+			return []models.EngineEval{{
+				AnalysisID: "a1", GameIndex: 0, Status: "done",
+				Evals: []models.ExplorerMoveStats{
+					{PlyNumber: 4, FEN: "afterE6 w KQkq -", PlayedMove: "Bf4", BestMove: "Nc3", WinrateDrop: 0.08},
+				},
+			}}, nil
+		},
+	}
+	settings := &models.InsightSettings{MinDrop: 0.02, MinPly: 3, MinFrequency: 1, MaxMistakes: 5}
+	svc := NewImportService(nil, analysisRepo,
+		WithEngineService(NewEngineService(evalRepo, analysisRepo)),
+		WithInsightSettingsRepo(&mocks.MockInsightSettingsRepo{
+			GetFunc: func(userID string) (*models.InsightSettings, error) { return settings, nil },
+		}),
+	)
+
+	t.Run("single game mistakes are reported when the user allows them", func(t *testing.T) {
+		filter, err := svc.InsightsFilterFor("user-1")
+		require.NoError(t, err)
+		assert.Equal(t, 5, filter.Limit)
+
+		insights, err := svc.GetInsights("user-1", filter)
+		require.NoError(t, err)
+		require.Len(t, insights.WorstMistakes, 1)
+		assert.Equal(t, "Bf4", insights.WorstMistakes[0].PlayedMove)
+	})
+
+	t.Run("mistakes before the minimum ply are skipped", func(t *testing.T) {
+		settings.MinPly = 6
+		filter, err := svc.InsightsFilterFor("user-1")
+		require.NoError(t, err)
+
+		insights, err := svc.GetInsights("user-1", filter)
+		require.NoError(t, err)
+		assert.Empty(t, insights.WorstMistakes)
+	})
+}
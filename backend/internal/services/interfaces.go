@@ -10,6 +10,12 @@ type LichessGameFetcher interface {
 	FetchStudyPGN(studyID, authToken string) (string, error)
 }
 
+// LichessTeamFetcher abstracts the Lichess API for fetching a team roster and its members' games.
+type LichessTeamFetcher interface {
+	FetchTeamMembers(teamID string, limit int) ([]string, error)
+	FetchGames(username string, options models.LichessImportOptions) (string, error)
+}
+
 // ChesscomGameFetcher abstracts the Chess.com API for fetching games.
 type ChesscomGameFetcher interface {
 	FetchGames(username string, options models.ChesscomImportOptions) (string, error)
@@ -25,4 +31,35 @@ type RepertoireManager interface {
 	CreateRepertoire(userID, name string, color models.Color) (*models.Repertoire, error)
 	CreateRepertoireWithCategory(userID, name string, color models.Color, categoryID *string) (*models.Repertoire, error)
 	SaveTree(repertoireID string, treeData models.RepertoireNode) (*models.Repertoire, error)
+	GetRepertoire(id string) (*models.Repertoire, error)
+	CheckOwnership(id string, userID string) error
+}
+
+// CoverageCalculator abstracts the explorer-based repertoire coverage computation.
+type CoverageCalculator interface {
+	ExplorerCoverage(root models.RepertoireNode, color models.Color, maxDepth int) (float64, error)
+}
+
+// ExplorerFetcher abstracts Lichess Explorer lookups for background expansion of a position.
+type ExplorerFetcher interface {
+	ExplorerMoves(fen string) ([]models.ExplorerMove, error)
+}
+
+// SparringExplorer abstracts Lichess Explorer lookups at given rating bands for sparring replies,
+// charged to the sparring user.
+type SparringExplorer interface {
+	ExplorerMovesFor(userID, fen, ratings string) ([]models.ExplorerMove, error)
+}
+
+// PopularityExplorer abstracts Lichess Explorer lookups for the popularity of opponent moves
+// added to a repertoire, charged to the editing user.
+type PopularityExplorer interface {
+	ExplorerMovesFor(userID, fen, ratings string) ([]models.ExplorerMove, error)
+}
+
+// Notifier delivers notifications to a user over one channel. Users who did not set the
+// channel up are skipped without an error.
+type Notifier interface {
+	Channel() models.NotificationChannel
+	Notify(user *models.User, n models.Notification) error
 }
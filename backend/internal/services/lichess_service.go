@@ -1,6 +1,9 @@
 package services
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,9 +27,7 @@ type LichessService struct {
 
 func NewLichessService() *LichessService {
 	return &LichessService{
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		httpClient: newResilientHTTPClient(120 * time.Second),
 	}
 }
 
@@ -127,6 +128,50 @@ func (s *LichessService) FetchStudyChapterPGN(studyID, chapterID, authToken stri
 	return string(body), nil
 }
 
+// FetchBroadcastRoundPGN fetches the games of a Lichess broadcast round.
+func (s *LichessService) FetchBroadcastRoundPGN(roundID string) (string, error) {
+	if roundID == "" {
+		return "", fmt.Errorf("round ID is required")
+	}
+
+	reqURL := fmt.Sprintf("%s/broadcast/round/%s.pgn", lichessAPIBaseURL, url.PathEscape(roundID))
+
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-chess-pgn")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch broadcast round from Lichess: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// continue
+	case http.StatusNotFound:
+		return "", ErrLichessBroadcastNotFound
+	case http.StatusTooManyRequests:
+		return "", ErrLichessRateLimited
+	default:
+		return "", fmt.Errorf("Lichess API error: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	pgnData := string(body)
+	if pgnData == "" {
+		return "", fmt.Errorf("no games found in broadcast round '%s'", roundID)
+	}
+
+	return pgnData, nil
+}
+
 // FetchGames fetches games from Lichess for a given username and returns the PGN data
 func (s *LichessService) FetchGames(username string, options models.LichessImportOptions) (string, error) {
 	if username == "" {
@@ -204,3 +249,59 @@ func (s *LichessService) FetchGames(username string, options models.LichessImpor
 
 	return pgnData, nil
 }
+
+// FetchTeamMembers fetches the usernames of up to limit members of a Lichess team
+func (s *LichessService) FetchTeamMembers(teamID string, limit int) ([]string, error) {
+	if teamID == "" {
+		return nil, fmt.Errorf("team ID is required")
+	}
+
+	reqURL := fmt.Sprintf("%s/team/%s/users", lichessAPIBaseURL, url.PathEscape(teamID))
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch team members from Lichess: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// continue
+	case http.StatusNotFound:
+		return nil, ErrLichessTeamNotFound
+	case http.StatusTooManyRequests:
+		return nil, ErrLichessRateLimited
+	default:
+		return nil, fmt.Errorf("Lichess API error: %s", resp.Status)
+	}
+
+	// The roster is streamed as one JSON user per line
+	var members []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(members) < limit {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var member struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		}
+		if err := json.Unmarshal(line, &member); err != nil {
+			return nil, fmt.Errorf("failed to decode team member: %w", err)
+		}
+		if member.Username == "" {
+			member.Username = member.ID
+		}
+		members = append(members, member.Username)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read team members: %w", err)
+	}
+	return members, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// MatchExcluded returns the IDs of the repertoires of a user that imported games are not
+// matched against, because they or their category are inactive for matching
+func (s *RepertoireService) MatchExcluded(userID string) (map[string]bool, error) {
+	excluded, err := s.repo.GetMatchExcluded(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repertoires excluded from matching: %w", err)
+	}
+	return excluded, nil
+}
+
+// matchScope decides which repertoires the games of an import are matched against: the ones
+// the import names when it names any, whatever their flags, else the ones active for matching.
+// Archived repertoires are never matched.
+type matchScope struct {
+	only     map[string]bool
+	excluded map[string]bool
+}
+
+// newMatchScope loads the matching flags of a user, unless repertoireIDs overrides them
+func (s *ImportService) newMatchScope(userID string, repertoireIDs []string) (*matchScope, error) {
+	if len(repertoireIDs) > 0 {
+		only := make(map[string]bool, len(repertoireIDs))
+		for _, id := range repertoireIDs {
+			only[id] = true
+		}
+		return &matchScope{only: only}, nil
+	}
+	excluded, err := s.repertoireService.MatchExcluded(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &matchScope{excluded: excluded}, nil
+}
+
+// filter returns the repertoires in scope in a new slice, leaving the given one untouched as it
+// may be shared with the repertoire cache
+func (m *matchScope) filter(repertoires []models.Repertoire) []models.Repertoire {
+	kept := make([]models.Repertoire, 0, len(repertoires))
+	for _, rep := range repertoires {
+		if rep.Archived || (m.only != nil && !m.only[rep.ID]) || m.excluded[rep.ID] {
+			continue
+		}
+		kept = append(kept, rep)
+	}
+	return kept
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestParseAndAnalyzeWithOptions_MatchScope(t *testing.T) {
+	e4, e5, nf3 := "e4", "e5", "Nf3"
+	startFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+	e4FEN := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"
+	reps := []models.Repertoire{
+		{ID: "rep-main", Name: "Open games", Color: models.ColorWhite, TreeData: models.RepertoireNode{
+			ID: "root-main", FEN: startFEN,
+			Children: []*models.RepertoireNode{{
+				ID: "main-e4", Move: &e4, FEN: e4FEN,
+				Children: []*models.RepertoireNode{{
+					ID: "main-e5", Move: &e5, FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6",
+					Children: []*models.RepertoireNode{{ID: "main-nf3", Move: &nf3, FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq -"}},
+				}},
+			}},
+		}},
+		{ID: "rep-side", Name: "Side system", Color: models.ColorWhite, TreeData: models.RepertoireNode{
+			ID: "root-side", FEN: startFEN,
+			Children: []*models.RepertoireNode{{ID: "side-e4", Move: &e4, FEN: e4FEN}},
+		}},
+	}
+	// The default mock FindPositions indexes the trees returned by GetAllFunc
+	index := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) { return reps, nil },
+	}
+	pgnData := "[White \"me\"]\n[Black \"opponent\"]\n\n1. e4 e5 2. Nf3 Nc6 1-0"
+
+	tests := []struct {
+		name     string
+		excluded map[string]bool
+		ids      []string
+		want     string
+	}{
+		{"every repertoire active", nil, nil, "rep-main"},
+		{"inactive repertoire is skipped", map[string]bool{"rep-main": true}, nil, "rep-side"},
+		{"import names the repertoires", nil, []string{"rep-side"}, "rep-side"},
+		{"import overrides the flags", map[string]bool{"rep-main": true}, []string{"rep-main", "rep-side"}, "rep-main"},
+		{"nothing in scope", map[string]bool{"rep-main": true, "rep-side": true}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mocks.MockRepertoireRepo{
+				GetByColorFunc: func(userID string, color models.Color) ([]models.Repertoire, error) {
+					if color == models.ColorWhite {
+						return reps, nil
+					}
+					return nil, nil
+				},
+				FindPositionsFunc: index.FindPositions,
+				GetMatchExcludedFunc: func(userID string) (map[string]bool, error) {
+					if tt.ids != nil {
+						t.Fatal("the flags are not loaded when the import names the repertoires")
+					}
+					return tt.excluded, nil
+				},
+			}
+			analysisRepo := &mocks.MockAnalysisRepo{
+				SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+					return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+				},
+			}
+			svc := NewImportService(NewRepertoireService(repo), analysisRepo)
+
+			_, results, err := svc.ParseAndAnalyzeWithOptions("f.pgn", "me", "user-1", pgnData, ImportOptions{MatchRepertoireIDs: tt.ids})
+
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			if tt.want == "" {
+				assert.Nil(t, results[0].MatchedRepertoire)
+				return
+			}
+			require.NotNil(t, results[0].MatchedRepertoire)
+			assert.Equal(t, tt.want, results[0].MatchedRepertoire.ID)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"log"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// ExplainMistake assembles the context of a move played in a position: what the user's repertoires
+// prepare there, the Explorer stats of the played and best moves, and master games reaching it.
+// Explorer stats come from the shared cache; on a miss the lookup is queued and the explanation
+// is returned with a pending Explorer status.
+func (s *ImportService) ExplainMistake(userID, fen, playedMove string) (*models.MistakeExplanation, error) {
+	fen = strings.TrimSpace(fen)
+	explanation := &models.MistakeExplanation{
+		FEN:         NormalizeFEN(fen),
+		PlayedMove:  playedMove,
+		Repertoires: []models.RepertoireExpectation{},
+		ModelGames:  []models.ModelGame{},
+	}
+
+	expectations, err := s.repertoireService.ExpectedMoves(userID, fen, playedMove)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Repertoires = expectations
+
+	if s.engineService == nil {
+		return explanation, nil
+	}
+
+	position, err := s.engineService.ExplorerPosition(userID, fen, "", "")
+	if err != nil {
+		return nil, err
+	}
+	explanation.ExplorerStatus = position.Status
+	if position.Status == models.ExplorerReady {
+		explanation.TotalGames = position.White + position.Draws + position.Black
+		explanation.Played, explanation.Best = explainExplorerMoves(position, playedMove)
+	}
+
+	// Master games are an extra; the explanation stands without them
+	games, err := s.engineService.GetModelGames(fen)
+	if err != nil {
+		log.Printf("explain mistake: failed to get model games: %v", err)
+	} else if games != nil {
+		explanation.ModelGames = games
+	}
+
+	return explanation, nil
+}
+
+// explainExplorerMoves picks the played move and the move with the best expected score for the side to move
+func explainExplorerMoves(position *models.ExplorerPosition, playedMove string) (played, best *models.ExplainedMove) {
+	turn := models.ColorWhite
+	if fields := strings.Fields(position.FEN); len(fields) > 1 && fields[1] == "b" {
+		turn = models.ColorBlack
+	}
+
+	for _, m := range position.Moves {
+		if m.White+m.Draws+m.Black == 0 {
+			continue
+		}
+		move := &models.ExplainedMove{ExplorerMove: m, Winrate: calcWinrate(m.White, m.Draws, m.Black, turn)}
+		if best == nil || move.Winrate > best.Winrate {
+			best = move
+		}
+		if m.SAN == playedMove {
+			played = move
+		}
+	}
+	return played, best
+}
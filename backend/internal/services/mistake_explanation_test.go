@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestExplainMistake(t *testing.T) {
+	e4, c5, comment := "e4", "c5", "Fight for d4"
+	rep := models.Repertoire{ID: "rep-sicilian", Name: "Sicilian", Color: models.ColorBlack, TreeData: models.RepertoireNode{
+		ID: "root", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		Children: []*models.RepertoireNode{{
+			ID: "e4", Move: &e4, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3",
+			Children: []*models.RepertoireNode{{
+				ID: "c5", Move: &c5, Comment: &comment, FEN: "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6",
+			}},
+		}},
+	}}
+	repo := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) { return []models.Repertoire{rep}, nil },
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			copied := rep
+			return &copied, nil
+		},
+	}
+	engineSvc := NewEngineService(nil, nil)
+	engineSvc.cache.set(explorerCacheKey(afterE4FEN, explorerSpeeds, explorerRatings), &explorerResponse{
+		White: 100, Draws: 40, Black: 60,
+		Moves: []explorerMove{
+			{SAN: "e5", UCI: "e7e5", White: 50, Draws: 20, Black: 30},
+			{SAN: "c5", UCI: "c7c5", White: 40, Draws: 20, Black: 40},
+		},
+	}, time.Now())
+	engineSvc.modelGames.set(afterE4FEN, []models.ModelGame{{ID: "master-1", White: "Kasparov", Black: "Karpov"}}, time.Now())
+	svc := NewImportService(NewRepertoireService(repo), nil, WithEngineService(engineSvc))
+
+	explanation, err := svc.ExplainMistake("user-1", afterE4FEN, "e5")
+
+	require.NoError(t, err)
+	require.Len(t, explanation.Repertoires, 1)
+	assert.Equal(t, "c5", explanation.Repertoires[0].ExpectedMove)
+	assert.Equal(t, "Fight for d4", explanation.Repertoires[0].Comment)
+	assert.Equal(t, []string{"e4"}, explanation.Repertoires[0].Path)
+	assert.False(t, explanation.Repertoires[0].PlayedInRepertoire)
+
+	assert.Equal(t, models.ExplorerReady, explanation.ExplorerStatus)
+	assert.Equal(t, 200, explanation.TotalGames)
+	require.NotNil(t, explanation.Played)
+	require.NotNil(t, explanation.Best)
+	assert.Equal(t, "e5", explanation.Played.SAN)
+	assert.InDelta(t, 0.4, explanation.Played.Winrate, 0.001)
+	assert.Equal(t, "c5", explanation.Best.SAN)
+	assert.InDelta(t, 0.5, explanation.Best.Winrate, 0.001)
+	require.Len(t, explanation.ModelGames, 1)
+}
+
+func TestExplainMistake_ExplorerPending(t *testing.T) {
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), nil, WithEngineService(NewEngineService(nil, nil)))
+	svc.engineService.modelGames.set(afterE4FEN, []models.ModelGame{}, time.Now())
+
+	explanation, err := svc.ExplainMistake("user-1", afterE4FEN, "e5")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ExplorerPending, explanation.ExplorerStatus)
+	assert.Empty(t, explanation.Repertoires)
+	assert.Nil(t, explanation.Played)
+	assert.Nil(t, explanation.Best)
+}
+
+func TestExplainMistake_InvalidFEN(t *testing.T) {
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), nil)
+
+	_, err := svc.ExplainMistake("user-1", "not a fen", "e5")
+
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+}
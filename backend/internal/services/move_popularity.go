@@ -0,0 +1,57 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+// WithExplorer looks up in the Explorer how often the opponent moves added to repertoires are played
+func (s *RepertoireService) WithExplorer(explorer PopularityExplorer) {
+	s.explorer = explorer
+}
+
+// OpponentMovePopularity returns how often the move played from a node of a repertoire is
+// played in the Explorer games, charged to the user. It returns nil for the moves of the
+// repertoire's own side and when no Explorer is set.
+func (s *RepertoireService) OpponentMovePopularity(userID string, rep *models.Repertoire, parentID, move string) (*models.MovePopularity, error) {
+	if s.explorer == nil {
+		return nil, nil
+	}
+	parent := findNode(&rep.TreeData, parentID)
+	if parent == nil {
+		return nil, fmt.Errorf("%w: %s", ErrParentNotFound, parentID)
+	}
+	if parent.ColorToMove == userColorToMove(rep.Color) {
+		return nil, nil
+	}
+	fen, err := validateAndGetResultingFEN(parent.FEN, move)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s - %v", ErrInvalidMove, move, err)
+	}
+
+	moves, err := s.explorer.ExplorerMovesFor(userID, parent.FEN, explorerRatings)
+	if err != nil {
+		return nil, err
+	}
+	return movePopularity(parent.FEN, fen, move, moves), nil
+}
+
+// movePopularity finds the share of the Explorer games reaching a position among those played
+// from its parent. Moves are matched on the position they reach, whatever their notation.
+func movePopularity(parentFEN, fen, move string, moves []models.ExplorerMove) *models.MovePopularity {
+	popularity := &models.MovePopularity{Move: move}
+	for _, m := range moves {
+		games := m.White + m.Draws + m.Black
+		popularity.TotalGames += games
+		if reached, err := validateAndGetResultingFEN(parentFEN, m.SAN); err == nil && reached == fen {
+			popularity.Games += games
+		}
+	}
+	if popularity.TotalGames > 0 {
+		popularity.Share = float64(popularity.Games) / float64(popularity.TotalGames)
+		popularity.Rare = popularity.Share < config.RareMoveShare
+	}
+	return popularity
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+func TestOpponentMovePopularity(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5", "e4 Na6")
+	tree.ColorToMove = models.ChessColorWhite
+	tree.Children[0].ColorToMove = models.ChessColorBlack
+	rep := &models.Repertoire{ID: "rep-1", Color: models.ColorWhite, TreeData: tree}
+	explorer := &fakeSparringExplorer{moves: map[string][]models.ExplorerMove{
+		tree.Children[0].FEN: {
+			{SAN: "c5", White: 400, Draws: 200, Black: 390},
+			{SAN: "e5", White: 300, Draws: 200, Black: 300},
+			{SAN: "Na6", White: 5, Draws: 0, Black: 5},
+		},
+	}}
+	svc := NewRepertoireService(nil)
+	svc.WithExplorer(explorer)
+
+	popularity, err := svc.OpponentMovePopularity("user-1", rep, "root-e4", "Na6")
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.MovePopularity{Move: "Na6", Games: 10, TotalGames: 1800, Share: 10.0 / 1800, Rare: true}, popularity)
+	assert.Equal(t, []string{explorerRatings}, explorer.ratings)
+
+	popularity, err = svc.OpponentMovePopularity("user-1", rep, "root-e4", "e5")
+	require.NoError(t, err)
+	assert.Equal(t, 800, popularity.Games)
+	assert.False(t, popularity.Rare)
+}
+
+func TestOpponentMovePopularity_SkipsOwnMoves(t *testing.T) {
+	tree := newLabelTestTree(t, "e4")
+	tree.ColorToMove = models.ChessColorWhite
+	rep := &models.Repertoire{ID: "rep-1", Color: models.ColorWhite, TreeData: tree}
+	explorer := &fakeSparringExplorer{}
+	svc := NewRepertoireService(nil)
+	svc.WithExplorer(explorer)
+
+	popularity, err := svc.OpponentMovePopularity("user-1", rep, "root", "e4")
+
+	require.NoError(t, err)
+	assert.Nil(t, popularity)
+	assert.Empty(t, explorer.ratings, "the Explorer is not queried for the user's own moves")
+}
+
+func TestMovePopularity_EmptyPosition(t *testing.T) {
+	popularity := movePopularity("8/8/8/8/8/8/8/K6k w - -", "8/8/8/8/8/8/1K6/7k b - -", "Kb2", nil)
+
+	assert.Equal(t, &models.MovePopularity{Move: "Kb2"}, popularity)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// WithNotifications notifies users when their background imports complete
+func WithNotifications(notifications *NotificationService) ImportServiceOption {
+	return func(s *ImportService) {
+		s.notifications = notifications
+	}
+}
+
+// WithNotifications notifies users when a sync imported new games
+func (s *SyncService) WithNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// WithNotifications notifies users when every game of an import is analyzed
+func (s *EngineService) WithNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// importCompletedNotification announces a finished background import of a file, team or sync
+func importCompletedNotification(source string, imported int) models.Notification {
+	return models.Notification{
+		Event: models.NotificationImportCompleted,
+		Title: "Your import is complete",
+		Body:  fmt.Sprintf("%d games were imported from %s.", imported, source),
+		URL:   "/games",
+		Data:  map[string]any{"source": source, "gamesImported": imported},
+	}
+}
+
+// analysisCompletedNotification announces that the engine analyzed every game of an import
+func analysisCompletedNotification(analysisID string) models.Notification {
+	return models.Notification{
+		Event: models.NotificationAnalysisCompleted,
+		Title: "Your games are analyzed",
+		Body:  "The opening analysis of your imported games is ready.",
+		URL:   "/games",
+		Data:  map[string]any{"analysisId": analysisID},
+	}
+}
+
+// digestNotification carries the weekly digest; the email notifier renders it in full
+func digestNotification(digest models.WeeklyDigest) models.Notification {
+	return models.Notification{
+		Event: models.NotificationWeeklyDigest,
+		Title: "Your week on TreeChess",
+		Body: fmt.Sprintf("%d games imported, %d new mistakes and %d positions to train this week.",
+			digest.GamesImported, len(digest.NewMistakes), digest.TrainingDue),
+		URL:  "/dashboard",
+		Data: digest,
+	}
+}
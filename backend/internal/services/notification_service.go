@@ -0,0 +1,362 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var (
+	ErrInvalidNotificationEvent = errors.New("unknown notification event")
+	ErrInvalidWebhookURL        = errors.New("webhook URL must be an https URL")
+	ErrInvalidPushSubscription  = errors.New("invalid push subscription")
+	ErrPushUnavailable          = errors.New("push notifications are not configured")
+)
+
+const (
+	webhookTimeout   = 10 * time.Second
+	maxWebhookURLLen = 2048
+)
+
+// NotificationService delivers events to users on the channels they chose for each event
+type NotificationService struct {
+	repo      repository.NotificationRepository
+	userRepo  repository.UserRepository
+	email     EmailSender
+	notifiers []Notifier
+	push      *PushNotifier
+}
+
+// NewNotificationService creates a notification service delivering by email and webhook
+func NewNotificationService(repo repository.NotificationRepository, userRepo repository.UserRepository, email EmailSender) *NotificationService {
+	return &NotificationService{
+		repo:      repo,
+		userRepo:  userRepo,
+		email:     email,
+		notifiers: []Notifier{NewEmailNotifier(email), NewWebhookNotifier(repo)},
+	}
+}
+
+// WithPush enables Web Push delivery
+func (s *NotificationService) WithPush(push *PushNotifier) {
+	s.push = push
+	s.notifiers = append(s.notifiers, push)
+}
+
+// Notify delivers a notification to a user on every channel their preference for the event
+// enables. Failures on one channel do not stop the others; an error is only returned when
+// no channel could deliver.
+func (s *NotificationService) Notify(userID string, n models.Notification) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	pref, err := s.preference(userID, n.Event)
+	if err != nil {
+		return err
+	}
+	if n.SentAt.IsZero() {
+		n.SentAt = time.Now()
+	}
+
+	var errs []error
+	delivered := false
+	for _, notifier := range s.notifiers {
+		if !pref.Enabled(notifier.Channel()) {
+			continue
+		}
+		if err := notifier.Notify(user, n); err != nil {
+			log.Printf("notifications: failed to deliver %s to user %s by %s: %v", n.Event, userID, notifier.Channel(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Channel(), err))
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// notifyAsync delivers a notification in the background when notifications are enabled
+func notifyAsync(notifications *NotificationService, userID string, n models.Notification) {
+	if notifications == nil {
+		return
+	}
+	go func() {
+		if err := notifications.Notify(userID, n); err != nil {
+			log.Printf("notifications: %s for user %s not delivered: %v", n.Event, userID, err)
+		}
+	}()
+}
+
+// Settings returns a user's preferences for every event and the channels they set up
+func (s *NotificationService) Settings(userID string) (*models.NotificationSettings, error) {
+	prefs, err := s.preferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	settings := &models.NotificationSettings{Preferences: prefs, EmailEnabled: s.email.Enabled()}
+
+	webhook, err := s.repo.GetWebhook(userID)
+	if err != nil && !errors.Is(err, repository.ErrNotificationWebhookNotFound) {
+		return nil, err
+	}
+	if webhook != nil {
+		webhook.Secret = ""
+		settings.Webhook = webhook
+	}
+
+	if s.push != nil {
+		subs, err := s.repo.ListPushSubscriptions(userID)
+		if err != nil {
+			return nil, err
+		}
+		settings.PushSubscriptions = len(subs)
+		settings.PushPublicKey = s.push.PublicKey()
+	}
+	return settings, nil
+}
+
+// UpdatePreferences saves the preferences of the events listed, leaving the others as they are
+func (s *NotificationService) UpdatePreferences(userID string, prefs []models.NotificationPreference) (*models.NotificationSettings, error) {
+	for _, p := range prefs {
+		if !knownNotificationEvent(p.Event) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidNotificationEvent, p.Event)
+		}
+	}
+	if err := s.repo.SetPreferences(userID, prefs); err != nil {
+		return nil, err
+	}
+	return s.Settings(userID)
+}
+
+// SetWebhook sets the URL a user's notifications are posted to, with a new signing secret
+func (s *NotificationService) SetWebhook(userID, rawURL string) (*models.NotificationWebhook, error) {
+	if err := validateHTTPSURL(rawURL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return s.repo.SetWebhook(userID, rawURL, hex.EncodeToString(secret))
+}
+
+// DeleteWebhook stops posting a user's notifications to their webhook
+func (s *NotificationService) DeleteWebhook(userID string) error {
+	return s.repo.DeleteWebhook(userID)
+}
+
+// SubscribePush registers a browser for a user's push notifications
+func (s *NotificationService) SubscribePush(userID string, sub models.PushSubscription) error {
+	if s.push == nil {
+		return ErrPushUnavailable
+	}
+	if err := validateHTTPSURL(sub.Endpoint); err != nil {
+		return fmt.Errorf("%w: endpoint %v", ErrInvalidPushSubscription, err)
+	}
+	if _, _, err := decodePushKeys(sub); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPushSubscription, err)
+	}
+	return s.repo.AddPushSubscription(userID, sub)
+}
+
+// UnsubscribePush removes a browser's push subscription
+func (s *NotificationService) UnsubscribePush(userID, endpoint string) error {
+	return s.repo.DeletePushSubscription(userID, endpoint)
+}
+
+// preferences returns the preference of every event, using the default for events never set
+func (s *NotificationService) preferences(userID string) ([]models.NotificationPreference, error) {
+	saved, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	byEvent := make(map[models.NotificationEvent]models.NotificationPreference, len(saved))
+	for _, p := range saved {
+		byEvent[p.Event] = p
+	}
+
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationEvents))
+	for _, event := range models.NotificationEvents {
+		p, ok := byEvent[event]
+		if !ok {
+			p = defaultNotificationPreference(event)
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+func (s *NotificationService) preference(userID string, event models.NotificationEvent) (models.NotificationPreference, error) {
+	prefs, err := s.preferences(userID)
+	if err != nil {
+		return models.NotificationPreference{}, err
+	}
+	for _, p := range prefs {
+		if p.Event == event {
+			return p, nil
+		}
+	}
+	return models.NotificationPreference{}, fmt.Errorf("%w: %s", ErrInvalidNotificationEvent, event)
+}
+
+// defaultNotificationPreference delivers every event to the webhook and browsers a user set up,
+// and only the weekly digest by email
+func defaultNotificationPreference(event models.NotificationEvent) models.NotificationPreference {
+	return models.NotificationPreference{
+		Event:   event,
+		Email:   event == models.NotificationWeeklyDigest,
+		Webhook: true,
+		Push:    true,
+	}
+}
+
+func knownNotificationEvent(event models.NotificationEvent) bool {
+	for _, e := range models.NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func validateHTTPSURL(rawURL string) error {
+	if len(rawURL) > maxWebhookURLLen {
+		return fmt.Errorf("longer than %d characters", maxWebhookURLLen)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%q is not an https URL", rawURL)
+	}
+	// Hosts resolving to private addresses are refused when dialing; literal ones are caught here
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("%q is not a public address", rawURL)
+	}
+	return nil
+}
+
+// EmailNotifier delivers notifications by email
+type EmailNotifier struct {
+	email EmailSender
+}
+
+// NewEmailNotifier creates a notifier sending through an email sender
+func NewEmailNotifier(email EmailSender) *EmailNotifier {
+	return &EmailNotifier{email: email}
+}
+
+func (e *EmailNotifier) Channel() models.NotificationChannel {
+	return models.NotificationChannelEmail
+}
+
+// Notify emails a notification; the weekly digest keeps its own layout
+func (e *EmailNotifier) Notify(user *models.User, n models.Notification) error {
+	if user.Email == nil {
+		return nil
+	}
+	if digest, ok := n.Data.(models.WeeklyDigest); ok {
+		return e.email.SendWeeklyDigestEmail(*user.Email, digest)
+	}
+	return e.email.SendNotificationEmail(*user.Email, n)
+}
+
+// WebhookNotifier posts notifications as JSON to the URL a user set up. Each request carries an
+// X-TreeChess-Signature header: the hex HMAC-SHA256 of the body under the webhook's secret.
+type WebhookNotifier struct {
+	repo   repository.NotificationRepository
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier. Its client refuses to connect to private and
+// loopback addresses, so webhooks cannot reach the server's network.
+func NewWebhookNotifier(repo repository.NotificationRepository) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: rejectPrivateAddress}
+	return &WebhookNotifier{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: webhookTimeout},
+		},
+	}
+}
+
+func (w *WebhookNotifier) Channel() models.NotificationChannel {
+	return models.NotificationChannelWebhook
+}
+
+// Notify posts a notification to the user's webhook, if they set one up
+func (w *WebhookNotifier) Notify(user *models.User, n models.Notification) error {
+	webhook, err := w.repo.GetWebhook(user.ID)
+	if errors.Is(err, repository.ErrNotificationWebhookNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TreeChess-Webhook")
+	req.Header.Set("X-TreeChess-Event", string(n.Event))
+	req.Header.Set("X-TreeChess-Signature", "sha256="+webhookSignature(webhook.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// rejectPrivateAddress refuses connections to addresses that are not public, once DNS is resolved
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("address %s is not public", host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback()
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+type fakeNotifier struct {
+	channel models.NotificationChannel
+	err     error
+	sent    []models.Notification
+}
+
+func (f *fakeNotifier) Channel() models.NotificationChannel { return f.channel }
+
+func (f *fakeNotifier) Notify(user *models.User, n models.Notification) error {
+	f.sent = append(f.sent, n)
+	return f.err
+}
+
+func newNotificationTestService(repo *mocks.MockNotificationRepo, notifiers ...Notifier) *NotificationService {
+	userRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) { return &models.User{ID: id}, nil },
+	}
+	svc := NewNotificationService(repo, userRepo, &mocks.MockEmailService{})
+	svc.notifiers = notifiers
+	return svc
+}
+
+func TestNotify_DefaultPreferences(t *testing.T) {
+	email := &fakeNotifier{channel: models.NotificationChannelEmail}
+	webhook := &fakeNotifier{channel: models.NotificationChannelWebhook}
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{}, email, webhook)
+
+	require.NoError(t, svc.Notify("user-1", importCompletedNotification("games.pgn", 12)))
+	require.NoError(t, svc.Notify("user-1", digestNotification(models.WeeklyDigest{GamesImported: 3})))
+
+	// Only the digest goes out by email unless the user asks for more
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, models.NotificationWeeklyDigest, email.sent[0].Event)
+	assert.Len(t, webhook.sent, 2)
+	assert.False(t, webhook.sent[0].SentAt.IsZero())
+}
+
+func TestNotify_SavedPreferences(t *testing.T) {
+	email := &fakeNotifier{channel: models.NotificationChannelEmail}
+	webhook := &fakeNotifier{channel: models.NotificationChannelWebhook}
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{
+		GetPreferencesFunc: func(userID string) ([]models.NotificationPreference, error) {
+			return []models.NotificationPreference{{Event: models.NotificationAnalysisCompleted, Email: true}}, nil
+		},
+	}, email, webhook)
+
+	require.NoError(t, svc.Notify("user-1", analysisCompletedNotification("analysis-1")))
+
+	assert.Len(t, email.sent, 1)
+	assert.Empty(t, webhook.sent)
+}
+
+func TestNotify_FailsOnlyWhenNoChannelDelivers(t *testing.T) {
+	failing := &fakeNotifier{channel: models.NotificationChannelWebhook, err: errors.New("connection refused")}
+	push := &fakeNotifier{channel: models.NotificationChannelPush}
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{}, failing, push)
+
+	assert.NoError(t, svc.Notify("user-1", importCompletedNotification("sync", 4)))
+
+	push.err = errors.New("push service down")
+	assert.Error(t, svc.Notify("user-1", importCompletedNotification("sync", 4)))
+}
+
+func TestUpdatePreferences_RejectsUnknownEvent(t *testing.T) {
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{
+		SetPreferencesFunc: func(userID string, prefs []models.NotificationPreference) error {
+			t.Fatal("invalid preferences must not be saved")
+			return nil
+		},
+	})
+
+	_, err := svc.UpdatePreferences("user-1", []models.NotificationPreference{{Event: "video_ready", Push: true}})
+
+	assert.ErrorIs(t, err, ErrInvalidNotificationEvent)
+}
+
+func TestSettings_MergesDefaultsAndHidesSecret(t *testing.T) {
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{
+		GetPreferencesFunc: func(userID string) ([]models.NotificationPreference, error) {
+			return []models.NotificationPreference{{Event: models.NotificationWeeklyDigest, Push: true}}, nil
+		},
+		GetWebhookFunc: func(userID string) (*models.NotificationWebhook, error) {
+			return &models.NotificationWebhook{URL: "https://hooks.example.com/treechess", Secret: "s3cret"}, nil
+		},
+	})
+
+	settings, err := svc.Settings("user-1")
+
+	require.NoError(t, err)
+	require.Len(t, settings.Preferences, len(models.NotificationEvents))
+	assert.Equal(t, defaultNotificationPreference(models.NotificationImportCompleted), settings.Preferences[0])
+	assert.Equal(t, models.NotificationPreference{Event: models.NotificationWeeklyDigest, Push: true}, settings.Preferences[2])
+	assert.Equal(t, "https://hooks.example.com/treechess", settings.Webhook.URL)
+	assert.Empty(t, settings.Webhook.Secret)
+	assert.Empty(t, settings.PushPublicKey)
+}
+
+func TestSetWebhook(t *testing.T) {
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{})
+
+	webhook, err := svc.SetWebhook("user-1", "https://hooks.example.com/treechess")
+	require.NoError(t, err)
+	assert.Len(t, webhook.Secret, 64)
+
+	_, err = svc.SetWebhook("user-1", "http://hooks.example.com/treechess")
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+	_, err = svc.SetWebhook("user-1", "https://192.168.1.20/treechess")
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+}
+
+func TestSubscribePush_RequiresPush(t *testing.T) {
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{})
+
+	err := svc.SubscribePush("user-1", models.PushSubscription{Endpoint: "https://push.example.com/abc"})
+
+	assert.ErrorIs(t, err, ErrPushUnavailable)
+}
+
+func TestSubscribePush_RejectsPrivateEndpoints(t *testing.T) {
+	added := 0
+	svc := newNotificationTestService(&mocks.MockNotificationRepo{
+		AddPushSubscriptionFunc: func(userID string, sub models.PushSubscription) error {
+			added++
+			return nil
+		},
+	})
+	push, err := NewPushNotifier(&mocks.MockNotificationRepo{}, newTestVAPIDKey(t), "mailto:ops@example.com")
+	require.NoError(t, err)
+	svc.WithPush(push)
+
+	for _, endpoint := range []string{
+		"https://127.0.0.1:8443/push",
+		"https://localhost/push",
+		"https://[::1]/push",
+		"https://10.0.0.7/push",
+		"https://172.16.4.2/push",
+		"https://192.168.1.20/push",
+		"https://169.254.169.254/latest/meta-data",
+	} {
+		_, _, sub := newTestBrowser(t, endpoint)
+		assert.ErrorIs(t, svc.SubscribePush("user-1", sub), ErrInvalidPushSubscription, endpoint)
+	}
+	assert.Zero(t, added)
+
+	_, _, sub := newTestBrowser(t, "https://fcm.googleapis.com/fcm/send/abc")
+	require.NoError(t, svc.SubscribePush("user-1", sub))
+	assert.Equal(t, 1, added)
+}
+
+func TestPushNotifier_RefusesPrivateAddresses(t *testing.T) {
+	hits := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	_, _, sub := newTestBrowser(t, srv.URL+"/push")
+	notifier, err := NewPushNotifier(&mocks.MockNotificationRepo{
+		ListPushSubscriptionsFunc: func(userID string) ([]models.PushSubscription, error) {
+			return []models.PushSubscription{sub}, nil
+		},
+	}, newTestVAPIDKey(t), "mailto:ops@example.com")
+	require.NoError(t, err)
+
+	err = notifier.Notify(&models.User{ID: "user-1"}, importCompletedNotification("games.pgn", 1))
+
+	assert.ErrorContains(t, err, "is not public")
+	assert.Zero(t, hits)
+}
+
+func TestWebhookNotifier_SignsPayload(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhookNotifier(&mocks.MockNotificationRepo{
+		GetWebhookFunc: func(userID string) (*models.NotificationWebhook, error) {
+			return &models.NotificationWebhook{URL: srv.URL, Secret: "s3cret"}, nil
+		},
+	})
+	notifier.client = srv.Client()
+
+	err := notifier.Notify(&models.User{ID: "user-1"}, importCompletedNotification("games.pgn", 12))
+
+	require.NoError(t, err)
+	assert.Equal(t, "import_completed", header.Get("X-TreeChess-Event"))
+	assert.Equal(t, "sha256="+webhookSignature("s3cret", body), header.Get("X-TreeChess-Signature"))
+	var received models.Notification
+	require.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, "12 games were imported from games.pgn.", received.Body)
+}
+
+func TestWebhookNotifier_SkipsUsersWithoutWebhook(t *testing.T) {
+	notifier := NewWebhookNotifier(&mocks.MockNotificationRepo{})
+
+	assert.NoError(t, notifier.Notify(&models.User{ID: "user-1"}, importCompletedNotification("sync", 1)))
+}
+
+func TestRejectPrivateAddress(t *testing.T) {
+	assert.Error(t, rejectPrivateAddress("tcp", "127.0.0.1:443", nil))
+	assert.Error(t, rejectPrivateAddress("tcp", "10.0.0.7:443", nil))
+	assert.Error(t, rejectPrivateAddress("tcp", "[::1]:443", nil))
+	assert.Error(t, rejectPrivateAddress("tcp", "169.254.169.254:80", nil))
+	assert.NoError(t, rejectPrivateAddress("tcp", "93.184.216.34:443", nil))
+}
+
+func TestEmailNotifier_RendersDigest(t *testing.T) {
+	address := "me@example.com"
+	var digestSent, notificationSent bool
+	notifier := NewEmailNotifier(&mocks.MockEmailService{
+		SendWeeklyDigestEmailFunc: func(toEmail string, digest models.WeeklyDigest) error {
+			digestSent = true
+			return nil
+		},
+		SendNotificationEmailFunc: func(toEmail string, n models.Notification) error {
+			notificationSent = true
+			return nil
+		},
+	})
+
+	require.NoError(t, notifier.Notify(&models.User{ID: "user-1", Email: &address}, digestNotification(models.WeeklyDigest{})))
+	assert.True(t, digestSent)
+	assert.False(t, notificationSent)
+
+	require.NoError(t, notifier.Notify(&models.User{ID: "user-2"}, importCompletedNotification("sync", 1)))
+	assert.False(t, notificationSent, "users without an email address are skipped")
+}
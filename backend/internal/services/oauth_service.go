@@ -116,7 +116,7 @@ func (s *OAuthService) HandleCallback(ctx context.Context, code, codeVerifier st
 
 // FindOrCreateUser looks up an existing OAuth user or creates a new one, then returns a JWT.
 // The isNew return value indicates whether a new user was created.
-func (s *OAuthService) FindOrCreateUser(provider, oauthID, username string) (resp *models.AuthResponse, isNew bool, err error) {
+func (s *OAuthService) FindOrCreateUser(provider, oauthID, username string, device models.SessionDevice) (resp *models.AuthResponse, isNew bool, err error) {
 	user, err := s.userRepo.FindByOAuth(provider, oauthID)
 	if err != nil && err != repository.ErrUserNotFound {
 		return nil, false, fmt.Errorf("failed to find OAuth user: %w", err)
@@ -143,7 +143,7 @@ func (s *OAuthService) FindOrCreateUser(provider, oauthID, username string) (res
 		}
 	}
 
-	token, err := s.authService.generateToken(user)
+	token, err := s.authService.generateToken(user, device)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to generate token: %w", err)
 	}
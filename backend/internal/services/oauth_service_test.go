@@ -51,7 +51,7 @@ func TestOAuthService_FindOrCreateUser_ExistingUser(t *testing.T) {
 	}
 	oauthSvc, _ := newTestOAuthService(mockRepo)
 
-	resp, isNew, err := oauthSvc.FindOrCreateUser("lichess", "oauth-123", "lichessplayer")
+	resp, isNew, err := oauthSvc.FindOrCreateUser("lichess", "oauth-123", "lichessplayer", models.SessionDevice{})
 
 	require.NoError(t, err)
 	assert.False(t, isNew)
@@ -73,7 +73,7 @@ func TestOAuthService_FindOrCreateUser_NewUser(t *testing.T) {
 	}
 	oauthSvc, _ := newTestOAuthService(mockRepo)
 
-	resp, isNew, err := oauthSvc.FindOrCreateUser("lichess", "oauth-new", "newplayer")
+	resp, isNew, err := oauthSvc.FindOrCreateUser("lichess", "oauth-new", "newplayer", models.SessionDevice{})
 
 	require.NoError(t, err)
 	assert.True(t, isNew)
@@ -99,7 +99,7 @@ func TestOAuthService_FindOrCreateUser_UsernameCollision(t *testing.T) {
 	}
 	oauthSvc, _ := newTestOAuthService(mockRepo)
 
-	resp, isNew, err := oauthSvc.FindOrCreateUser("lichess", "oauth-new", "player")
+	resp, isNew, err := oauthSvc.FindOrCreateUser("lichess", "oauth-new", "player", models.SessionDevice{})
 
 	require.NoError(t, err)
 	assert.True(t, isNew)
@@ -114,7 +114,7 @@ func TestOAuthService_FindOrCreateUser_FindError(t *testing.T) {
 	}
 	oauthSvc, _ := newTestOAuthService(mockRepo)
 
-	_, _, err := oauthSvc.FindOrCreateUser("lichess", "oauth-123", "player")
+	_, _, err := oauthSvc.FindOrCreateUser("lichess", "oauth-123", "player", models.SessionDevice{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to find OAuth user")
@@ -131,7 +131,7 @@ func TestOAuthService_FindOrCreateUser_ExistsCheckError(t *testing.T) {
 	}
 	oauthSvc, _ := newTestOAuthService(mockRepo)
 
-	_, _, err := oauthSvc.FindOrCreateUser("lichess", "oauth-new", "player")
+	_, _, err := oauthSvc.FindOrCreateUser("lichess", "oauth-new", "player", models.SessionDevice{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to check username")
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+const openingLabelInterval = 10 * time.Minute
+
+// openingLabelLevels is how many levels of branches are labeled: the first moves of the
+// repertoire and the branches off them
+const openingLabelLevels = 2
+
+// labelOpenings names the first- and second-level branches of a tree after the opening they
+// reach. A branch runs from a child of the root or of a node with several children down to
+// the next fork or leaf, and takes the name of the deepest position of the ECO table on it,
+// or that of its parent branch when it reaches none. Labels found on other nodes are cleared.
+// It reports whether any label changed.
+func labelOpenings(root *models.RepertoireNode) bool {
+	changed := setOpening(root, nil)
+	for _, child := range root.Children {
+		if labelBranch(child, 1, nil) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func labelBranch(start *models.RepertoireNode, level int, inherited *models.OpeningLabel) bool {
+	changed := false
+	label := inherited
+	end := start
+	for {
+		if opening := resolveOpening(end.FEN); opening != nil {
+			label = opening
+		}
+		if end != start && setOpening(end, nil) {
+			changed = true
+		}
+		if len(end.Children) != 1 {
+			break
+		}
+		end = end.Children[0]
+	}
+
+	var startLabel *models.OpeningLabel
+	if level <= openingLabelLevels {
+		startLabel = label
+	}
+	if setOpening(start, startLabel) {
+		changed = true
+	}
+
+	for _, child := range end.Children {
+		if labelBranch(child, level+1, label) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func setOpening(node *models.RepertoireNode, label *models.OpeningLabel) bool {
+	if label == nil && node.Opening == nil {
+		return false
+	}
+	if label != nil && node.Opening != nil && *label == *node.Opening {
+		return false
+	}
+	node.Opening = label
+	return true
+}
+
+// labelingRepertoireRepo labels the openings of every tree saved through it
+type labelingRepertoireRepo struct {
+	RepertoireRepository
+}
+
+func (r *labelingRepertoireRepo) Save(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+	labelOpenings(&treeData)
+	saved, err := r.RepertoireRepository.Save(id, treeData, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.MarkOpeningsLabeled(id, config.OpeningLabelsVersion); err != nil {
+		log.Printf("opening labels: failed to mark %s labeled: %v", id, err)
+	}
+	return saved, nil
+}
+
+// SaveNodes relabels the edited tree and writes the nodes whose label changed along with the edit
+func (r *labelingRepertoireRepo) SaveNodes(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+	before := make(map[string]*models.OpeningLabel)
+	forEachNode(&tree, func(node *models.RepertoireNode) { before[node.ID] = node.Opening })
+	if labelOpenings(&tree) {
+		changes.Updated = append(changes.Updated[:len(changes.Updated):len(changes.Updated)], relabeledNodes(&tree, before)...)
+	}
+	saved, err := r.RepertoireRepository.SaveNodes(id, version, tree, changes, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.MarkOpeningsLabeled(id, config.OpeningLabelsVersion); err != nil {
+		log.Printf("opening labels: failed to mark %s labeled: %v", id, err)
+	}
+	return saved, nil
+}
+
+// relabeledNodes returns the IDs of the nodes whose label differs from the one they had before
+func relabeledNodes(root *models.RepertoireNode, before map[string]*models.OpeningLabel) []string {
+	var ids []string
+	forEachNode(root, func(node *models.RepertoireNode) {
+		old := before[node.ID]
+		if (old == nil) != (node.Opening == nil) || (old != nil && *old != *node.Opening) {
+			ids = append(ids, node.ID)
+		}
+	})
+	return ids
+}
+
+// WithOpeningLabels labels the branches of every tree the service saves with their opening name
+func (s *RepertoireService) WithOpeningLabels() {
+	s.repo = &labelingRepertoireRepo{RepertoireRepository: s.repo}
+}
+
+// RunOpeningLabelWorker periodically labels the repertoires saved before the current version of
+// the labeling, such as those predating it or labeled with an older ECO table
+func (s *RepertoireService) RunOpeningLabelWorker(ctx context.Context) {
+	log.Println("opening labels: worker started")
+	ticker := time.NewTicker(openingLabelInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("opening labels: worker stopped")
+			return
+		case <-ticker.C:
+			s.labelStale()
+		}
+	}
+}
+
+func (s *RepertoireService) labelStale() {
+	ids, err := s.repo.ListUnlabeled(config.OpeningLabelsVersion, config.OpeningLabelsBatchSize)
+	if err != nil {
+		log.Printf("opening labels: failed to list repertoires: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := s.LabelOpenings(id); err != nil {
+			log.Printf("opening labels: failed to label %s: %v", id, err)
+		}
+	}
+}
+
+// LabelOpenings labels the branches of a repertoire, saving it only when a label changed.
+// Archived repertoires are read-only and left as they are.
+func (s *RepertoireService) LabelOpenings(id string) error {
+	rep, err := s.getEditableRepertoire(id)
+	if err != nil {
+		return err
+	}
+	if labelOpenings(&rep.TreeData) {
+		if _, err := s.repo.Save(id, rep.TreeData, rep.Metadata); err != nil {
+			return err
+		}
+	}
+	return s.repo.MarkOpeningsLabeled(id, config.OpeningLabelsVersion)
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+// newLabelTestTree builds a tree with real positions from space-separated lines of moves
+func newLabelTestTree(t *testing.T, lines ...string) models.RepertoireNode {
+	root := models.RepertoireNode{ID: "root", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"}
+	for _, line := range lines {
+		node := &root
+		for _, san := range strings.Fields(line) {
+			child := childWithMove(node, san)
+			if child == nil {
+				fen, err := validateAndGetResultingFEN(node.FEN, san)
+				require.NoError(t, err)
+				move := san
+				child = &models.RepertoireNode{ID: node.ID + "-" + san, FEN: fen, Move: &move}
+				node.Children = append(node.Children, child)
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+func TestECOTable_Loads(t *testing.T) {
+	positions := loadECOTable(ecoTable)
+
+	assert.Greater(t, len(positions), 100)
+}
+
+func TestResolveOpening(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3 Nc6 Bc4 Nf6")
+	node := tree.Children[0].Children[0].Children[0].Children[0].Children[0].Children[0]
+
+	assert.Equal(t, &models.OpeningLabel{ECO: "C55", Name: "Italian Game: Two Knights Defense"}, resolveOpening(node.FEN))
+	assert.Nil(t, resolveOpening(tree.FEN))
+}
+
+func TestResolveOpening_IgnoresEnPassantAndCounters(t *testing.T) {
+	label := resolveOpening("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+
+	require.NotNil(t, label)
+	assert.Equal(t, "King's Pawn Game", label.Name)
+}
+
+func TestLabelOpenings(t *testing.T) {
+	tree := newLabelTestTree(t,
+		"e4 e5 Nf3 Nc6 Bc4 Nf6",
+		"e4 e5 Nf3 Nc6 Bc4 Bc5 c3 Nf6 d4",
+		"e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6 Be3 e5",
+		"e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6 Bg5",
+	)
+
+	changed := labelOpenings(&tree)
+
+	require.True(t, changed)
+	e4 := tree.Children[0]
+	assert.Equal(t, "King's Pawn Game", e4.Opening.Name)
+
+	e5, c5 := e4.Children[0], e4.Children[1]
+	assert.Equal(t, "Italian Game", e5.Opening.Name, "branch ends at the fork after 3.Bc4")
+	assert.Equal(t, "Sicilian Defense: Najdorf Variation", c5.Opening.Name)
+
+	// Third-level branches are not labeled and neither are the nodes inside a branch
+	nf6 := e5.Children[0].Children[0].Children[0].Children[0]
+	assert.Nil(t, nf6.Opening)
+	assert.Nil(t, e5.Children[0].Opening)
+	assert.Nil(t, tree.Opening)
+}
+
+func TestLabelOpenings_InheritsParentBranchName(t *testing.T) {
+	tree := newLabelTestTree(t, "d4 d5 c4 e6", "d4 d5 c4 h6", "d4 Nf6")
+
+	labelOpenings(&tree)
+
+	d5 := tree.Children[0].Children[0]
+	assert.Equal(t, "Queen's Gambit", d5.Opening.Name)
+	nf6 := tree.Children[0].Children[1]
+	assert.Equal(t, "Indian Defense", nf6.Opening.Name)
+}
+
+func TestLabelOpenings_ClearsStaleLabels(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3")
+	stale := &models.OpeningLabel{ECO: "A00", Name: "Stale"}
+	tree.Children[0].Children[0].Opening = stale
+
+	assert.True(t, labelOpenings(&tree))
+	assert.Nil(t, tree.Children[0].Children[0].Opening)
+	assert.Equal(t, "King's Knight Opening", tree.Children[0].Opening.Name)
+	assert.False(t, labelOpenings(&tree), "labels are already up to date")
+}
+
+func TestLabelingRepertoireRepo_Save(t *testing.T) {
+	var savedTree models.RepertoireNode
+	var markedID string
+	var markedVersion int
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			savedTree = treeData
+			return &models.Repertoire{ID: id, TreeData: treeData}, nil
+		},
+		MarkOpeningsLabeledFunc: func(id string, labelsVersion int) error {
+			markedID = id
+			markedVersion = labelsVersion
+			return nil
+		},
+	})
+	svc.WithOpeningLabels()
+
+	_, err := svc.SaveTree("rep-1", newLabelTestTree(t, "c4 e5"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "English Opening: King's English Variation", savedTree.Children[0].Opening.Name)
+	assert.Equal(t, "rep-1", markedID)
+	assert.Equal(t, config.OpeningLabelsVersion, markedVersion)
+}
+
+func TestLabelOpenings_SavesOnlyChangedTrees(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 c6")
+	labelOpenings(&tree)
+	saves, marks := 0, 0
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			saves++
+			return &models.Repertoire{ID: id}, nil
+		},
+		MarkOpeningsLabeledFunc: func(id string, labelsVersion int) error {
+			marks++
+			return nil
+		},
+	})
+
+	require.NoError(t, svc.LabelOpenings("rep-1"))
+	assert.Equal(t, 0, saves)
+	assert.Equal(t, 1, marks)
+}
+
+func TestLabelOpenings_LeavesArchivedRepertoires(t *testing.T) {
+	saves, marks := 0, 0
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: newLabelTestTree(t, "c4 e5"), Archived: true}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			saves++
+			return &models.Repertoire{ID: id}, nil
+		},
+		MarkOpeningsLabeledFunc: func(id string, labelsVersion int) error {
+			marks++
+			return nil
+		},
+	})
+
+	err := svc.LabelOpenings("rep-1")
+
+	assert.ErrorIs(t, err, ErrRepertoireArchived)
+	assert.Equal(t, 0, saves)
+	assert.Equal(t, 0, marks)
+}
+
+func TestListLines_Opening(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e6 d4 d5 e5", "e4 e6 d4 d5 Nc3 Bb4")
+	labelOpenings(&tree)
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+	})
+
+	lines, err := svc.ListLines("rep-1", nil)
+
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "French Defense: Advance Variation", lines[0].Opening.Name)
+	assert.Equal(t, "French Defense: Winawer Variation", lines[1].Opening.Name)
+}
+
+func TestLabelingRepertoireRepo_SaveNodesWritesRelabeledNodes(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3")
+	labelOpenings(&tree)
+	var changes repository.NodeChanges
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+		SaveNodesFunc: func(id string, version int, treeData models.RepertoireNode, c repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+			changes = c
+			return &models.Repertoire{ID: id, TreeData: treeData}, nil
+		},
+	})
+	svc.WithOpeningLabels()
+
+	// Adding 1...c5 ends the 1.e4 branch and makes 1...e5 the start of a labeled branch
+	saved, err := svc.AddNode("rep-1", models.AddNodeRequest{ParentID: "root-e4", Move: "c5", MoveNumber: 1})
+
+	require.NoError(t, err)
+	c5 := saved.TreeData.Children[0].Children[1]
+	assert.Equal(t, []string{c5.ID}, changes.Added)
+	assert.Contains(t, changes.Updated, "root-e4")
+	assert.Contains(t, changes.Updated, "root-e4-e5")
+	assert.NotContains(t, changes.Updated, "root-e4-e5-Nf3")
+	assert.NotNil(t, saved.TreeData.Children[0].Children[0].Opening)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrInvalidRatingRange is returned when the minimum opponent rating exceeds the maximum
+var ErrInvalidRatingRange = fmt.Errorf("minRating must not exceed maxRating")
+
+// WithOpponentReplyRepo records, for every saved game, the moves the opponent played in the
+// opening so they can be aggregated per position
+func WithOpponentReplyRepo(repo repository.OpponentReplyRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.opponentReplyRepo = repo
+	}
+}
+
+// OpponentReplies returns what the user's opponents played in a position, optionally restricted
+// to opponents rated within [minRating, maxRating]. A bound of 0 is not applied.
+func (s *ImportService) OpponentReplies(userID, fen string, minRating, maxRating int) (*models.OpponentReplies, error) {
+	if _, err := chess.FEN(ensureFullFEN(fen)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+	if minRating > 0 && maxRating > 0 && minRating > maxRating {
+		return nil, ErrInvalidRatingRange
+	}
+
+	result := &models.OpponentReplies{
+		FEN:       normalizeFEN(fen),
+		MinRating: minRating,
+		MaxRating: maxRating,
+		Replies:   []models.OpponentReply{},
+	}
+	if s.opponentReplyRepo == nil {
+		return result, nil
+	}
+
+	replies, err := s.opponentReplyRepo.Aggregate(userID, result.FEN, minRating, maxRating)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		result.Games += reply.Games
+	}
+	for i := range replies {
+		replies[i].Frequency = float64(replies[i].Games) / float64(result.Games)
+		if finished := replies[i].Wins + replies[i].Draws + replies[i].Losses; finished > 0 {
+			replies[i].Score = (float64(replies[i].Wins) + float64(replies[i].Draws)/2) / float64(finished)
+		}
+	}
+	result.Replies = replies
+	return result, nil
+}
+
+// recordOpponentReplies stores the opponents' opening moves of freshly saved games. Failures are
+// logged, not returned: the replies are a derived view and must not fail an import.
+func (s *ImportService) recordOpponentReplies(analysisID string, games []models.GameAnalysis) {
+	if s.opponentReplyRepo == nil {
+		return
+	}
+
+	var entries []repository.OpponentReplyEntry
+	for _, game := range games {
+		entries = append(entries, opponentReplyEntries(game)...)
+	}
+	if err := s.opponentReplyRepo.SaveBatch(analysisID, entries); err != nil {
+		log.Printf("warning: failed to save opponent replies: %v", err)
+	}
+}
+
+// opponentReplyEntries lists the opponent's moves of a game within the first config.OpponentRepliesMaxPly plies
+func opponentReplyEntries(game models.GameAnalysis) []repository.OpponentReplyEntry {
+	ratingHeader := "WhiteElo"
+	if game.UserColor == models.ColorWhite {
+		ratingHeader = "BlackElo"
+	}
+	rating, err := strconv.Atoi(game.Headers[ratingHeader])
+	if err != nil || rating < 0 || rating > config.MaxOpponentRating {
+		rating = 0
+	}
+	outcome := gameOutcome(game.Headers["Result"], game.UserColor)
+
+	var entries []repository.OpponentReplyEntry
+	for _, move := range game.Moves {
+		if move.PlyNumber >= config.OpponentRepliesMaxPly {
+			break
+		}
+		if move.IsUserMove {
+			continue
+		}
+		entries = append(entries, repository.OpponentReplyEntry{
+			GameIndex:      game.GameIndex,
+			Ply:            move.PlyNumber,
+			FEN:            move.FEN,
+			Move:           move.SAN,
+			OpponentRating: rating,
+			Outcome:        outcome,
+		})
+	}
+	return entries
+}
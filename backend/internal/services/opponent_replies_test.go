@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestOpponentReplyEntries_OpponentMovesOnly(t *testing.T) {
+	game := models.GameAnalysis{
+		GameIndex: 3,
+		Headers:   models.PGNHeaders{"Result": "0-1", "WhiteElo": "1850", "BlackElo": "1900"},
+		UserColor: models.ColorBlack,
+		Moves: []models.MoveAnalysis{
+			{PlyNumber: 0, SAN: "e4", FEN: startFEN},
+			{PlyNumber: 1, SAN: "c5", FEN: "after-e4", IsUserMove: true},
+			{PlyNumber: 2, SAN: "Nf3", FEN: "after-c5"},
+		},
+	}
+
+	entries := opponentReplyEntries(game)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, repository.OpponentReplyEntry{GameIndex: 3, Ply: 0, FEN: startFEN, Move: "e4", OpponentRating: 1850, Outcome: "win"}, entries[0])
+	assert.Equal(t, "Nf3", entries[1].Move)
+}
+
+func TestOpponentReplyEntries_UnknownRatingAndDepth(t *testing.T) {
+	moves := make([]models.MoveAnalysis, 40)
+	for i := range moves {
+		moves[i] = models.MoveAnalysis{PlyNumber: i, SAN: "x", IsUserMove: i%2 == 0}
+	}
+	game := models.GameAnalysis{Headers: models.PGNHeaders{"BlackElo": "?"}, UserColor: models.ColorWhite, Moves: moves}
+
+	entries := opponentReplyEntries(game)
+
+	assert.Len(t, entries, 15)
+	assert.Zero(t, entries[0].OpponentRating)
+	assert.Empty(t, entries[0].Outcome)
+}
+
+func TestRecordOpponentReplies(t *testing.T) {
+	var saved []repository.OpponentReplyEntry
+	svc := NewImportService(nil, nil, WithOpponentReplyRepo(&mocks.MockOpponentReplyRepo{
+		SaveBatchFunc: func(analysisID string, entries []repository.OpponentReplyEntry) error {
+			assert.Equal(t, "analysis-1", analysisID)
+			saved = entries
+			return nil
+		},
+	}))
+	games := []models.GameAnalysis{
+		{GameIndex: 0, UserColor: models.ColorWhite, Moves: []models.MoveAnalysis{{PlyNumber: 0, SAN: "e4", IsUserMove: true}, {PlyNumber: 1, SAN: "e5"}}},
+		{GameIndex: 1, UserColor: models.ColorBlack, Moves: []models.MoveAnalysis{{PlyNumber: 0, SAN: "d4"}}},
+	}
+
+	svc.recordOpponentReplies("analysis-1", games)
+
+	require.Len(t, saved, 2)
+	assert.Equal(t, "e5", saved[0].Move)
+	assert.Equal(t, "d4", saved[1].Move)
+}
+
+func TestOpponentReplies_ComputesFrequencyAndScore(t *testing.T) {
+	var gotFEN string
+	svc := NewImportService(nil, nil, WithOpponentReplyRepo(&mocks.MockOpponentReplyRepo{
+		AggregateFunc: func(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error) {
+			gotFEN = fen
+			assert.Equal(t, 1600, minRating)
+			assert.Equal(t, 2000, maxRating)
+			return []models.OpponentReply{
+				{Move: "e4", Games: 3, Wins: 2, Draws: 0, Losses: 0},
+				{Move: "d4", Games: 1, Wins: 0, Draws: 1, Losses: 0},
+			}, nil
+		},
+	}))
+
+	result, err := svc.OpponentReplies("user-1", startFEN, 1600, 2000)
+
+	require.NoError(t, err)
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", gotFEN)
+	assert.Equal(t, 4, result.Games)
+	require.Len(t, result.Replies, 2)
+	assert.InDelta(t, 0.75, result.Replies[0].Frequency, 0.0001)
+	// The unfinished e4 game does not count towards the score
+	assert.InDelta(t, 1.0, result.Replies[0].Score, 0.0001)
+	assert.InDelta(t, 0.5, result.Replies[1].Score, 0.0001)
+}
+
+func TestOpponentReplies_Validation(t *testing.T) {
+	svc := NewImportService(nil, nil, WithOpponentReplyRepo(&mocks.MockOpponentReplyRepo{}))
+
+	_, err := svc.OpponentReplies("user-1", "not a fen", 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+
+	_, err = svc.OpponentReplies("user-1", startFEN, 2000, 1600)
+	assert.ErrorIs(t, err, ErrInvalidRatingRange)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// moveAnnotation holds the comment, NAGs and clock attached to a single mainline move
+type moveAnnotation struct {
+	comment string
+	nags    []string
+	clock   *float64 // seconds remaining, from [%clk h:mm:ss]
+}
+
+// symbolicNAGs maps move-suffix annotation symbols to their standard numeric NAG
+var symbolicNAGs = map[string]string{
+	"!":  "$1",
+	"?":  "$2",
+	"!!": "$3",
+	"??": "$4",
+	"!?": "$5",
+	"?!": "$6",
+}
+
+// commentCommandRe matches embedded commands such as [%clk 0:03:00] or [%eval 0.25]
+var commentCommandRe = regexp.MustCompile(`\[%[^\]]*\]`)
+
+// clockCommandRe captures the hours, minutes and seconds of a [%clk h:mm:ss(.f)] command
+var clockCommandRe = regexp.MustCompile(`\[%clk\s+(\d+):(\d{1,2}):(\d{1,2}(?:\.\d+)?)\]`)
+
+// extractMainlineAnnotations returns one annotation per mainline move of a single-game PGN.
+// Comments and NAGs inside variations are ignored, as are comments before the first move.
+func extractMainlineAnnotations(rawPGN string) []moveAnnotation {
+	_, movetext := splitPGNHeadersAndMovetext(rawPGN)
+
+	var annotations []moveAnnotation
+	depth := 0
+	for _, tok := range tokenizePGNMovetext(movetext) {
+		switch tok.typ {
+		case tokenVariationStart:
+			depth++
+		case tokenVariationEnd:
+			if depth > 0 {
+				depth--
+			}
+		case tokenMove:
+			if depth == 0 {
+				annotations = append(annotations, moveAnnotation{})
+			}
+		case tokenComment:
+			if depth == 0 && len(annotations) > 0 {
+				last := &annotations[len(annotations)-1]
+				if clock, ok := parseClockCommand(tok.value); ok {
+					last.clock = &clock
+				}
+				text := cleanPGNComment(tok.value)
+				if text != "" {
+					if last.comment != "" {
+						last.comment += " "
+					}
+					last.comment += text
+				}
+			}
+		case tokenNAG:
+			if depth == 0 && len(annotations) > 0 {
+				last := &annotations[len(annotations)-1]
+				last.nags = append(last.nags, normalizeNAG(tok.value))
+			}
+		}
+	}
+
+	return annotations
+}
+
+// applyAnnotations copies mainline annotations onto analysed moves.
+// Annotations are only applied when they line up one-to-one with the moves.
+func applyAnnotations(moves []models.MoveAnalysis, annotations []moveAnnotation) {
+	if len(annotations) != len(moves) {
+		return
+	}
+	for i := range moves {
+		moves[i].Comment = annotations[i].comment
+		moves[i].NAGs = annotations[i].nags
+		moves[i].Clock = annotations[i].clock
+	}
+}
+
+// applyTimeSpent derives the time spent on each move from consecutive clock
+// readings of the same side. The first move of each side is measured against
+// the base time of the TimeControl header; moves without a usable reading are left unset.
+func applyTimeSpent(moves []models.MoveAnalysis, timeControl string) {
+	base, increment, ok := models.ParseTimeControl(timeControl)
+	for i := range moves {
+		if moves[i].Clock == nil {
+			continue
+		}
+		var previous float64
+		switch {
+		case i >= 2 && moves[i-2].Clock != nil:
+			previous = *moves[i-2].Clock
+		case i < 2 && ok:
+			previous = float64(base)
+		default:
+			continue
+		}
+		spent := previous - *moves[i].Clock
+		if ok {
+			spent += float64(increment)
+		}
+		if spent < 0 {
+			spent = 0
+		}
+		moves[i].TimeSpent = &spent
+	}
+}
+
+// parseClockCommand extracts the remaining time in seconds from a comment holding [%clk ...]
+func parseClockCommand(comment string) (float64, bool) {
+	m := clockCommandRe.FindStringSubmatch(comment)
+	if m == nil {
+		return 0, false
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return 0, false
+	}
+	return float64(hours*3600+minutes*60) + seconds, true
+}
+
+// cleanPGNComment strips embedded [%...] commands and collapses whitespace
+func cleanPGNComment(comment string) string {
+	comment = commentCommandRe.ReplaceAllString(comment, "")
+	return strings.Join(strings.Fields(comment), " ")
+}
+
+// normalizeNAG converts symbolic annotations (!, ?!, ...) to numeric NAGs
+func normalizeNAG(nag string) string {
+	if numeric, ok := symbolicNAGs[nag]; ok {
+		return numeric
+	}
+	return nag
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestExtractMainlineAnnotations(t *testing.T) {
+	pgn := `[Event "Annotated"]
+[White "me"]
+[Black "them"]
+
+1. e4! { Best by test } 1... e5 $2 2. Nf3 { [%clk 0:02:58] developing } (2. f4 { gambit } $5) 2... Nc6?! 1-0`
+
+	annotations := extractMainlineAnnotations(pgn)
+
+	require.Len(t, annotations, 4)
+	assert.Equal(t, "Best by test", annotations[0].comment)
+	assert.Equal(t, []string{"$1"}, annotations[0].nags)
+	assert.Equal(t, []string{"$2"}, annotations[1].nags)
+	assert.Equal(t, "developing", annotations[2].comment)
+	assert.Empty(t, annotations[2].nags)
+	assert.Equal(t, []string{"$6"}, annotations[3].nags)
+}
+
+func TestApplyAnnotations_MismatchedLengthIgnored(t *testing.T) {
+	moves := []models.MoveAnalysis{{SAN: "e4"}, {SAN: "e5"}}
+
+	applyAnnotations(moves, []moveAnnotation{{comment: "only one"}})
+
+	assert.Empty(t, moves[0].Comment)
+}
+
+func TestParseAndAnalyze_KeepsAnnotations(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+	var saved []models.GameAnalysis
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			saved = results
+			return &models.AnalysisSummary{ID: "analysis-1", GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(repSvc, analysisRepo)
+
+	pgn := `[Event "Annotated"]
+[White "me"]
+[Black "them"]
+[Result "1-0"]
+
+1. e4 { king pawn } e5 2. Nf3!? 1-0`
+
+	_, results, err := svc.ParseAndAnalyze("test.pgn", "me", "user-1", pgn)
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Moves, 3)
+	assert.Equal(t, "king pawn", results[0].Moves[0].Comment)
+	assert.Equal(t, []string{"$5"}, results[0].Moves[2].NAGs)
+	assert.Equal(t, results, saved)
+}
+
+func TestExtractMainlineAnnotations_Clock(t *testing.T) {
+	pgn := `[TimeControl "180+2"]
+
+1. e4 { [%clk 0:03:01] } 1... e5 { [%clk 0:02:55.5] } 2. Nf3 { [%clk 0:02:40] } 1-0`
+
+	annotations := extractMainlineAnnotations(pgn)
+
+	require.Len(t, annotations, 3)
+	require.NotNil(t, annotations[1].clock)
+	assert.InDelta(t, 175.5, *annotations[1].clock, 0.001)
+	assert.Empty(t, annotations[0].comment)
+}
+
+func TestApplyTimeSpent(t *testing.T) {
+	clock := func(v float64) *float64 { return &v }
+	moves := []models.MoveAnalysis{
+		{SAN: "e4", Clock: clock(181)},
+		{SAN: "e5", Clock: clock(175)},
+		{SAN: "Nf3", Clock: clock(150)},
+		{SAN: "Nc6"},
+		{SAN: "Bb5", Clock: clock(140)},
+	}
+
+	applyTimeSpent(moves, "180+2")
+
+	require.NotNil(t, moves[0].TimeSpent)
+	assert.InDelta(t, 1, *moves[0].TimeSpent, 0.001)
+	assert.InDelta(t, 7, *moves[1].TimeSpent, 0.001)
+	assert.InDelta(t, 33, *moves[2].TimeSpent, 0.001)
+	assert.Nil(t, moves[3].TimeSpent)
+	assert.InDelta(t, 12, *moves[4].TimeSpent, 0.001)
+}
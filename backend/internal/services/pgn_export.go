@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+// sevenTagRoster lists the PGN tags that must come first, in this order
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// ExportGamePGN rebuilds the PGN of a stored game with its analysis embedded as comments
+func (s *ImportService) ExportGamePGN(analysisID string, gameIndex int) (string, error) {
+	game, err := s.analysisRepo.GetGame(analysisID, gameIndex)
+	if err != nil {
+		return "", err
+	}
+	return buildAnnotatedPGN(game), nil
+}
+
+// ExportGamesPGN exports the user's games matching the same filters as the games list,
+// newest first and capped at config.MaxExportGames.
+func (s *ImportService) ExportGamesPGN(userID, timeClass, repertoire, source, classification string, starred bool) (string, int, error) {
+	list, err := s.analysisRepo.GetAllGames(userID, config.MaxExportGames, 0, timeClass, repertoire, source, classification, starred)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var pgns []string
+	for _, summary := range list.Games {
+		game, err := s.analysisRepo.GetGame(summary.AnalysisID, summary.GameIndex)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to load game %s/%d: %w", summary.AnalysisID, summary.GameIndex, err)
+		}
+		pgns = append(pgns, buildAnnotatedPGN(game))
+	}
+	return strings.Join(pgns, "\n"), len(pgns), nil
+}
+
+// buildAnnotatedPGN writes a game as PGN. Original comments, NAGs and clocks are kept and the
+// repertoire analysis is added as comments on the moves where the game left the repertoire.
+func buildAnnotatedPGN(game *models.GameAnalysis) string {
+	var b strings.Builder
+
+	result := game.Headers["Result"]
+	if result == "" {
+		result = "*"
+	}
+	writePGNHeaders(&b, game.Headers, result)
+	b.WriteString("\n")
+
+	var tokens []string
+	bookEnded := false
+	afterComment := false
+	for i, move := range game.Moves {
+		// Black's move number is repeated when a comment separates it from White's move
+		if i%2 == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d.", i/2+1))
+		} else if afterComment {
+			tokens = append(tokens, fmt.Sprintf("%d...", i/2+1))
+		}
+		tokens = append(tokens, move.SAN)
+		tokens = append(tokens, move.NAGs...)
+
+		// The repertoire goes on the first move: a comment before it is not read back by notnil/chess
+		var comment []string
+		if i == 0 && game.MatchedRepertoire != nil {
+			comment = append(comment, "analyzed against repertoire "+game.MatchedRepertoire.Name)
+		}
+		if move.Comment != "" {
+			comment = append(comment, move.Comment)
+		}
+		if note := analysisNote(move, bookEnded); note != "" {
+			comment = append(comment, note)
+		}
+		if move.Status == "out-of-book" {
+			bookEnded = true
+		}
+		text := strings.Join(comment, "; ")
+		if move.Clock != nil {
+			text = strings.TrimSpace(text + " [%clk " + formatClock(*move.Clock) + "]")
+		}
+		afterComment = text != ""
+		if afterComment {
+			tokens = append(tokens, pgnComment(text))
+		}
+	}
+	tokens = append(tokens, result)
+
+	writeWrapped(&b, tokens, 80)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// analysisNote describes a move's repertoire status; only deviations and the end of the book are noted
+func analysisNote(move models.MoveAnalysis, bookEnded bool) string {
+	switch move.Status {
+	case "out-of-repertoire":
+		if move.ExpectedMove != "" {
+			return "out of repertoire, expected " + move.ExpectedMove
+		}
+		return "out of repertoire"
+	case "opponent-new":
+		return "opponent left the repertoire"
+	case "out-of-book":
+		if !bookEnded {
+			return "end of repertoire"
+		}
+	}
+	return ""
+}
+
+// writePGNHeaders writes the seven tag roster first, then the remaining tags alphabetically
+func writePGNHeaders(b *strings.Builder, headers models.PGNHeaders, result string) {
+	for _, tag := range sevenTagRoster {
+		value := headers[tag]
+		switch {
+		case tag == "Result":
+			value = result
+		case value == "":
+			value = "?"
+		}
+		fmt.Fprintf(b, "[%s \"%s\"]\n", tag, escapePGNTag(value))
+	}
+
+	var extra []string
+	for tag := range headers {
+		if !isSevenTagRoster(tag) {
+			extra = append(extra, tag)
+		}
+	}
+	sort.Strings(extra)
+	for _, tag := range extra {
+		fmt.Fprintf(b, "[%s \"%s\"]\n", tag, escapePGNTag(headers[tag]))
+	}
+}
+
+func isSevenTagRoster(tag string) bool {
+	for _, t := range sevenTagRoster {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func escapePGNTag(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// pgnComment wraps text in braces; a closing brace would end the comment early so it is replaced
+func pgnComment(text string) string {
+	return "{" + strings.ReplaceAll(text, "}", ")") + "}"
+}
+
+// formatClock renders seconds as h:mm:ss, the format used by [%clk]
+func formatClock(seconds float64) string {
+	total := int(seconds + 0.5)
+	return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+}
+
+// writeWrapped joins tokens with spaces, breaking lines before they exceed width
+func writeWrapped(b *strings.Builder, tokens []string, width int) {
+	lineLen := 0
+	for _, tok := range tokens {
+		if lineLen > 0 && lineLen+1+len(tok) > width {
+			b.WriteString("\n")
+			lineLen = 0
+		} else if lineLen > 0 {
+			b.WriteString(" ")
+			lineLen++
+		}
+		b.WriteString(tok)
+		lineLen += len(tok)
+	}
+}
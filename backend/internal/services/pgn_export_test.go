@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func exportTestGame() *models.GameAnalysis {
+	clock := 178.0
+	return &models.GameAnalysis{
+		Headers: models.PGNHeaders{
+			"White":       "me",
+			"Black":       "opponent",
+			"Result":      "1-0",
+			"TimeControl": "180+2",
+			"Event":       `Club "Open"`,
+		},
+		UserColor:         models.ColorWhite,
+		MatchedRepertoire: &models.RepertoireRef{ID: "rep-1", Name: "Italian"},
+		Moves: []models.MoveAnalysis{
+			{PlyNumber: 0, SAN: "e4", Status: "in-repertoire", IsUserMove: true, Clock: &clock},
+			{PlyNumber: 1, SAN: "e5", Status: "in-repertoire"},
+			{PlyNumber: 2, SAN: "Nc3", Status: "out-of-repertoire", ExpectedMove: "Nf3", IsUserMove: true, NAGs: []string{"$6"}},
+			{PlyNumber: 3, SAN: "Nc6", Status: "out-of-book", Comment: "solid"},
+			{PlyNumber: 4, SAN: "Bc4", Status: "out-of-book", IsUserMove: true},
+		},
+	}
+}
+
+func TestBuildAnnotatedPGN(t *testing.T) {
+	pgn := buildAnnotatedPGN(exportTestGame())
+
+	assert.True(t, strings.HasPrefix(pgn, "[Event \"Club \\\"Open\\\"\"]\n[Site \"?\"]\n"))
+	assert.Contains(t, pgn, "[Result \"1-0\"]\n[TimeControl \"180+2\"]\n")
+	movetext := strings.ReplaceAll(pgn[strings.Index(pgn, "\n\n")+2:], "\n", " ")
+	assert.Equal(t, "1. e4 {analyzed against repertoire Italian [%clk 0:02:58]} 1... e5 2. Nc3 $6 {out of repertoire, expected Nf3} 2... Nc6 {solid; end of repertoire} 3. Bc4 1-0 ", movetext)
+	assert.Equal(t, 1, strings.Count(pgn, "end of repertoire"))
+}
+
+func TestBuildAnnotatedPGN_IsReadable(t *testing.T) {
+	pgn := buildAnnotatedPGN(exportTestGame())
+
+	opt, err := chess.PGN(strings.NewReader(pgn))
+	require.NoError(t, err)
+	game := chess.NewGame(opt)
+
+	assert.Len(t, game.Moves(), 5)
+	assert.Equal(t, chess.WhiteWon, game.Outcome())
+}
+
+func TestBuildAnnotatedPGN_NoResult(t *testing.T) {
+	game := &models.GameAnalysis{
+		Headers: models.PGNHeaders{},
+		Moves:   []models.MoveAnalysis{{SAN: "d4", Status: "out-of-book"}},
+	}
+
+	pgn := buildAnnotatedPGN(game)
+
+	assert.Contains(t, pgn, "[Result \"*\"]")
+	assert.True(t, strings.HasSuffix(pgn, "1. d4 {end of repertoire} *\n"))
+}
+
+func TestExportGamesPGN(t *testing.T) {
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetAllGamesFunc: func(userID string, limit, offset int, timeClass, opening, source, classification string, starred bool) (*models.GamesResponse, error) {
+			assert.Equal(t, "blitz", timeClass)
+			return &models.GamesResponse{Games: []models.GameSummary{
+				{AnalysisID: "a-1", GameIndex: 0},
+				{AnalysisID: "a-1", GameIndex: 1},
+			}}, nil
+		},
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return exportTestGame(), nil
+		},
+	}
+	svc := NewImportService(nil, analysisRepo)
+
+	pgn, count, err := svc.ExportGamesPGN("user-1", "blitz", "", "", "", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, strings.Count(pgn, "[Event "))
+}
@@ -171,6 +171,9 @@ func stripTrailingNAG(move string) (string, string) {
 	return move, ""
 }
 
+// standardStartFEN is the initial position, as a full FEN
+const standardStartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 // ParsePGNToTree parses a single PGN game text (with headers) into a RepertoireNode tree.
 // Returns the root node, a map of PGN headers, and any error.
 func ParsePGNToTree(pgnText string) (models.RepertoireNode, map[string]string, error) {
@@ -179,8 +182,7 @@ func ParsePGNToTree(pgnText string) (models.RepertoireNode, map[string]string, e
 
 	// Reject custom starting positions — only standard openings are supported
 	if fenHeader, ok := headers["FEN"]; ok && fenHeader != "" {
-		standardFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
-		if ensureFullFEN(fenHeader) != standardFEN {
+		if ensureFullFEN(fenHeader) != standardStartFEN {
 			return models.RepertoireNode{}, nil, ErrCustomStartingPosition
 		}
 	}
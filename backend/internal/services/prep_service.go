@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrInvalidPrepDepth is returned when a preparation asks for too few or too many plies
+var ErrInvalidPrepDepth = fmt.Errorf("prep depth must be between 1 and %d", config.MaxPrepDepth)
+
+// PrepService prepares repertoire nodes in the background: it follows the Explorer deeper
+// below a node than an interactive lookup would, and keeps the lines it finds as
+// suggestions until the user accepts them into the tree or discards them.
+type PrepService struct {
+	prepRepo      repository.PrepRepository
+	repertoireSvc *RepertoireService
+	explorer      ExplorerFetcher
+}
+
+// NewPrepService creates a new node preparation service
+func NewPrepService(prepRepo repository.PrepRepository, repertoireSvc *RepertoireService, explorer ExplorerFetcher) *PrepService {
+	return &PrepService{
+		prepRepo:      prepRepo,
+		repertoireSvc: repertoireSvc,
+		explorer:      explorer,
+	}
+}
+
+// RequestPrep queues the preparation of a node, depth plies deep
+func (s *PrepService) RequestPrep(userID, repertoireID, nodeID string, depth int) (*models.PrepRequest, error) {
+	if depth < 1 || depth > config.MaxPrepDepth {
+		return nil, ErrInvalidPrepDepth
+	}
+
+	rep, err := s.repertoireSvc.GetRepertoire(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	node := findNode(&rep.TreeData, nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+	}
+
+	return s.prepRepo.Create(userID, repertoireID, nodeID, node.FEN, depth)
+}
+
+// ListPrep returns the preparations of a node, newest first
+func (s *PrepService) ListPrep(repertoireID, nodeID string) ([]models.PrepRequest, error) {
+	requests, err := s.prepRepo.ListByNode(repertoireID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if requests == nil {
+		requests = []models.PrepRequest{}
+	}
+	return requests, nil
+}
+
+// AcceptSuggestion adds a suggested line below its node and removes it from the suggestions
+func (s *PrepService) AcceptSuggestion(repertoireID, prepID, suggestionID string) (*models.Repertoire, error) {
+	req, err := s.getRequest(repertoireID, prepID)
+	if err != nil {
+		return nil, err
+	}
+	suggestion := findSuggestion(req, suggestionID)
+	if suggestion == nil {
+		return nil, repository.ErrPrepSuggestionNotFound
+	}
+
+	rep, err := s.repertoireSvc.AddLine(repertoireID, req.NodeID, suggestion.Moves)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepRepo.RemoveSuggestion(prepID, suggestionID); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// DiscardSuggestion removes a suggestion without touching the tree
+func (s *PrepService) DiscardSuggestion(repertoireID, prepID, suggestionID string) error {
+	if _, err := s.getRequest(repertoireID, prepID); err != nil {
+		return err
+	}
+	return s.prepRepo.RemoveSuggestion(prepID, suggestionID)
+}
+
+// getRequest returns a prep request, hiding requests of other repertoires
+func (s *PrepService) getRequest(repertoireID, prepID string) (*models.PrepRequest, error) {
+	req, err := s.prepRepo.GetByID(prepID)
+	if err != nil {
+		return nil, err
+	}
+	if req.RepertoireID != repertoireID {
+		return nil, repository.ErrPrepRequestNotFound
+	}
+	return req, nil
+}
+
+func findSuggestion(req *models.PrepRequest, id string) *models.PrepSuggestion {
+	for i := range req.Suggestions {
+		if req.Suggestions[i].ID == id {
+			return &req.Suggestions[i]
+		}
+	}
+	return nil
+}
+
+// RunWorker polls for pending preparations and runs them
+func (s *PrepService) RunWorker(ctx context.Context) {
+	log.Println("prep: worker started")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("prep: worker stopped")
+			return
+		case <-ticker.C:
+			s.processPending()
+		}
+	}
+}
+
+func (s *PrepService) processPending() {
+	requests, err := s.prepRepo.ClaimPending(config.PrepClaimBatch)
+	if err != nil {
+		log.Printf("prep: failed to claim requests: %v", err)
+		return
+	}
+
+	for _, req := range requests {
+		suggestions, err := s.prepare(req)
+		if err != nil {
+			log.Printf("prep: request %s failed: %v", req.ID, err)
+			if markErr := s.prepRepo.MarkFailed(req.ID, err.Error()); markErr != nil {
+				log.Printf("prep: failed to mark request %s as failed: %v", req.ID, markErr)
+			}
+			continue
+		}
+		if err := s.prepRepo.Complete(req.ID, suggestions); err != nil {
+			log.Printf("prep: failed to complete request %s: %v", req.ID, err)
+		}
+	}
+}
+
+// prepare expands the most played first moves from the node, each followed along its most
+// played continuation. Lines the tree already holds in full are left out.
+func (s *PrepService) prepare(req models.PrepRequest) ([]models.PrepSuggestion, error) {
+	rep, err := s.repertoireSvc.GetRepertoire(req.RepertoireID)
+	if err != nil {
+		return nil, err
+	}
+	node := findNode(&rep.TreeData, req.NodeID)
+	if node == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, req.NodeID)
+	}
+
+	moves, err := s.explorer.ExplorerMoves(node.FEN)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []models.PrepSuggestion{}
+	for _, move := range moves {
+		if len(suggestions) >= config.PrepCandidates {
+			break
+		}
+		games := move.White + move.Draws + move.Black
+		if games < config.PrepMinGames {
+			break
+		}
+
+		line, err := s.mainLine(node.FEN, move.SAN, req.Depth)
+		if err != nil {
+			return nil, err
+		}
+		if lineInTree(node, line) {
+			continue
+		}
+		suggestions = append(suggestions, models.PrepSuggestion{
+			ID:    uuid.New().String(),
+			Moves: line,
+			Games: games,
+			White: move.White,
+			Draws: move.Draws,
+			Black: move.Black,
+		})
+	}
+	return suggestions, nil
+}
+
+// mainLine plays first from fen, then the most played Explorer move of each position
+// until the line is depth plies long or too few games go on
+func (s *PrepService) mainLine(fen, first string, depth int) ([]string, error) {
+	var line []string
+	move := first
+	for {
+		next, err := validateAndGetResultingFEN(fen, move)
+		if err != nil {
+			return nil, fmt.Errorf("explorer move %s is illegal: %w", move, err)
+		}
+		line = append(line, move)
+		if len(line) >= depth {
+			return line, nil
+		}
+
+		fen = next
+		moves, err := s.explorer.ExplorerMoves(fen)
+		if err != nil {
+			return nil, err
+		}
+		if len(moves) == 0 || moves[0].White+moves[0].Draws+moves[0].Black < config.PrepMinGames {
+			return line, nil
+		}
+		move = moves[0].SAN
+	}
+}
+
+// lineInTree reports whether every move of line is already below node
+func lineInTree(node *models.RepertoireNode, line []string) bool {
+	for _, move := range line {
+		node = childWithMove(node, move)
+		if node == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+type fakeExplorer map[string][]models.ExplorerMove
+
+func (f fakeExplorer) ExplorerMoves(fen string) ([]models.ExplorerMove, error) {
+	return f[fen], nil
+}
+
+// fenAfter plays moves from the initial position and returns the normalized FEN
+func fenAfter(t *testing.T, moves ...string) string {
+	t.Helper()
+	fen := normalizeFEN(startFEN)
+	for _, move := range moves {
+		next, err := validateAndGetResultingFEN(fen, move)
+		require.NoError(t, err)
+		fen = next
+	}
+	return fen
+}
+
+func prepMove(san string, games int) models.ExplorerMove {
+	return models.ExplorerMove{SAN: san, White: games / 2, Draws: games / 4, Black: games - games/2 - games/4}
+}
+
+func prepTestExplorer(t *testing.T) fakeExplorer {
+	return fakeExplorer{
+		fenAfter(t):                    {prepMove("e4", 1000), prepMove("d4", 800), prepMove("c4", 10)},
+		fenAfter(t, "e4"):              {prepMove("c5", 500)},
+		fenAfter(t, "e4", "c5"):        {prepMove("Nf3", 400)},
+		fenAfter(t, "d4"):              {prepMove("d5", 5)},
+		fenAfter(t, "e4", "c5", "Nf3"): {prepMove("d6", 300)},
+	}
+}
+
+// prepTestRepertoire holds the initial position with 1.e4 and, optionally, 1...c5 2.Nf3
+func prepTestRepertoire(t *testing.T, full bool) *models.Repertoire {
+	e4, c5, nf3 := "e4", "c5", "Nf3"
+	e4Node := &models.RepertoireNode{ID: "n-e4", FEN: fenAfter(t, "e4"), Move: &e4, MoveNumber: 1,
+		ColorToMove: models.ChessColorBlack, Children: []*models.RepertoireNode{}}
+	if full {
+		nf3Node := &models.RepertoireNode{ID: "n-nf3", FEN: fenAfter(t, "e4", "c5", "Nf3"), Move: &nf3, MoveNumber: 2,
+			ColorToMove: models.ChessColorBlack, Children: []*models.RepertoireNode{}}
+		e4Node.Children = append(e4Node.Children, &models.RepertoireNode{ID: "n-c5", FEN: fenAfter(t, "e4", "c5"), Move: &c5,
+			MoveNumber: 1, ColorToMove: models.ChessColorWhite, Children: []*models.RepertoireNode{nf3Node}})
+	}
+	return &models.Repertoire{
+		ID:    "rep-1",
+		Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID:          "root",
+			FEN:         fenAfter(t),
+			ColorToMove: models.ChessColorWhite,
+			Children:    []*models.RepertoireNode{e4Node},
+		},
+	}
+}
+
+func newPrepTestService(t *testing.T, prepRepo *mocks.MockPrepRepo, rep *models.Repertoire) (*PrepService, *models.RepertoireNode) {
+	var saved *models.RepertoireNode
+	repRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			*saved = treeData
+			return &models.Repertoire{ID: id, TreeData: treeData, Metadata: metadata}, nil
+		},
+	}
+	saved = &models.RepertoireNode{}
+	return NewPrepService(prepRepo, NewRepertoireService(repRepo), prepTestExplorer(t)), saved
+}
+
+func TestRequestPrep_Validation(t *testing.T) {
+	svc, _ := newPrepTestService(t, &mocks.MockPrepRepo{}, prepTestRepertoire(t, false))
+
+	_, err := svc.RequestPrep("user-1", "rep-1", "root", 0)
+	assert.ErrorIs(t, err, ErrInvalidPrepDepth)
+
+	_, err = svc.RequestPrep("user-1", "rep-1", "missing", 4)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+
+	req, err := svc.RequestPrep("user-1", "rep-1", "n-e4", 4)
+	require.NoError(t, err)
+	assert.Equal(t, fenAfter(t, "e4"), req.FEN)
+	assert.Equal(t, models.PrepPending, req.Status)
+}
+
+func TestPrepWorker_ExpandsMostPlayedLines(t *testing.T) {
+	var completed []models.PrepSuggestion
+	prepRepo := &mocks.MockPrepRepo{
+		ClaimPendingFunc: func(limit int) ([]models.PrepRequest, error) {
+			return []models.PrepRequest{{ID: "prep-1", RepertoireID: "rep-1", NodeID: "root", Depth: 3}}, nil
+		},
+		CompleteFunc: func(id string, suggestions []models.PrepSuggestion) error {
+			completed = suggestions
+			return nil
+		},
+	}
+	svc, _ := newPrepTestService(t, prepRepo, prepTestRepertoire(t, false))
+
+	svc.processPending()
+
+	require.Len(t, completed, 2)
+	assert.Equal(t, []string{"e4", "c5", "Nf3"}, completed[0].Moves)
+	assert.Equal(t, 1000, completed[0].Games)
+	assert.Equal(t, []string{"d4"}, completed[1].Moves, "the line stops where too few games go on")
+	assert.NotEqual(t, completed[0].ID, completed[1].ID)
+}
+
+func TestPrepWorker_SkipsLinesAlreadyInTree(t *testing.T) {
+	var completed []models.PrepSuggestion
+	prepRepo := &mocks.MockPrepRepo{
+		ClaimPendingFunc: func(limit int) ([]models.PrepRequest, error) {
+			return []models.PrepRequest{{ID: "prep-1", RepertoireID: "rep-1", NodeID: "root", Depth: 3}}, nil
+		},
+		CompleteFunc: func(id string, suggestions []models.PrepSuggestion) error {
+			completed = suggestions
+			return nil
+		},
+	}
+	svc, _ := newPrepTestService(t, prepRepo, prepTestRepertoire(t, true))
+
+	svc.processPending()
+
+	require.Len(t, completed, 1)
+	assert.Equal(t, []string{"d4"}, completed[0].Moves)
+}
+
+func TestPrepWorker_FailsWhenNodeIsGone(t *testing.T) {
+	var failedID string
+	prepRepo := &mocks.MockPrepRepo{
+		ClaimPendingFunc: func(limit int) ([]models.PrepRequest, error) {
+			return []models.PrepRequest{{ID: "prep-1", RepertoireID: "rep-1", NodeID: "deleted", Depth: 3}}, nil
+		},
+		CompleteFunc: func(id string, suggestions []models.PrepSuggestion) error {
+			t.Fatal("a failed request must not complete")
+			return nil
+		},
+		MarkFailedFunc: func(id, message string) error {
+			failedID = id
+			return nil
+		},
+	}
+	svc, _ := newPrepTestService(t, prepRepo, prepTestRepertoire(t, false))
+
+	svc.processPending()
+
+	assert.Equal(t, "prep-1", failedID)
+}
+
+func TestAcceptSuggestion_AddsLineBelowNode(t *testing.T) {
+	var removed string
+	prepRepo := &mocks.MockPrepRepo{
+		GetByIDFunc: func(id string) (*models.PrepRequest, error) {
+			return &models.PrepRequest{ID: id, RepertoireID: "rep-1", NodeID: "root", Status: models.PrepDone,
+				Suggestions: []models.PrepSuggestion{{ID: "s-1", Moves: []string{"e4", "c5", "Nf3"}}}}, nil
+		},
+		RemoveSuggestionFunc: func(id, suggestionID string) error {
+			removed = suggestionID
+			return nil
+		},
+	}
+	svc, saved := newPrepTestService(t, prepRepo, prepTestRepertoire(t, false))
+
+	rep, err := svc.AcceptSuggestion("rep-1", "prep-1", "s-1")
+
+	require.NoError(t, err)
+	require.NotNil(t, rep)
+	assert.Equal(t, "s-1", removed)
+
+	require.Len(t, saved.Children, 1, "the existing 1.e4 is followed, not duplicated")
+	e4 := saved.Children[0]
+	assert.Equal(t, "n-e4", e4.ID)
+	require.Len(t, e4.Children, 1)
+	c5 := e4.Children[0]
+	assert.Equal(t, "c5", *c5.Move)
+	assert.Equal(t, 1, c5.MoveNumber)
+	require.Len(t, c5.Children, 1)
+	nf3 := c5.Children[0]
+	assert.Equal(t, "Nf3", *nf3.Move)
+	assert.Equal(t, 2, nf3.MoveNumber)
+	assert.Equal(t, fenAfter(t, "e4", "c5", "Nf3"), nf3.FEN)
+	assert.Equal(t, c5.ID, *nf3.ParentID)
+}
+
+func TestAcceptSuggestion_OtherRepertoire(t *testing.T) {
+	prepRepo := &mocks.MockPrepRepo{
+		GetByIDFunc: func(id string) (*models.PrepRequest, error) {
+			return &models.PrepRequest{ID: id, RepertoireID: "rep-2", NodeID: "root"}, nil
+		},
+	}
+	svc, _ := newPrepTestService(t, prepRepo, prepTestRepertoire(t, false))
+
+	_, err := svc.AcceptSuggestion("rep-1", "prep-1", "s-1")
+	assert.ErrorIs(t, err, repository.ErrPrepRequestNotFound)
+
+	err = svc.DiscardSuggestion("rep-1", "prep-1", "s-1")
+	assert.ErrorIs(t, err, repository.ErrPrepRequestNotFound)
+}
+
+func TestAcceptSuggestion_UnknownSuggestion(t *testing.T) {
+	prepRepo := &mocks.MockPrepRepo{
+		GetByIDFunc: func(id string) (*models.PrepRequest, error) {
+			return &models.PrepRequest{ID: id, RepertoireID: "rep-1", NodeID: "root"}, nil
+		},
+	}
+	svc, _ := newPrepTestService(t, prepRepo, prepTestRepertoire(t, false))
+
+	_, err := svc.AcceptSuggestion("rep-1", "prep-1", "s-1")
+	assert.ErrorIs(t, err, repository.ErrPrepSuggestionNotFound)
+}
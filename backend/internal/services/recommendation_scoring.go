@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// Recommendation priorities are in points lost in games, a loss counting 1 and a draw 1/2, so
+// that recommendations of different kinds rank against each other.
+const (
+	// Points added per game for an opponent move the repertoire has no answer to, even when won
+	recommendationUncoveredWeight = 0.5
+	// Recall under which a repertoire is worth reviewing, and the answers needed to trust it
+	recommendationReviewRecall  = 0.7
+	recommendationReviewAnswers = 10
+	// Points a fully forgotten repertoire is worth
+	recommendationReviewWeight = 10.0
+)
+
+// lineResult is the user's record in a set of games
+type lineResult struct {
+	games, wins, draws, losses int
+}
+
+// lostPoints is what the user dropped in the games
+func (r lineResult) lostPoints() float64 {
+	return float64(r.losses) + float64(r.draws)/2
+}
+
+// leftResult is the record of the games that reached a node but none of its children, that is
+// the games leaving the repertoire at the node
+func leftResult(node models.NodeResult, children []models.NodeResult) lineResult {
+	left := lineResult{games: node.Games, wins: node.Wins, draws: node.Draws, losses: node.Losses}
+	for _, child := range children {
+		left.games -= child.Games
+		left.wins -= child.Wins
+		left.draws -= child.Draws
+		left.losses -= child.Losses
+	}
+	return left
+}
+
+// recallFactor raises the priority of lines in a repertoire the user answers poorly in training,
+// up to twice for a repertoire never answered right. Without training data lines are left as is.
+func recallFactor(recall *float64) float64 {
+	if recall == nil {
+		return 1
+	}
+	return 2 - *recall
+}
+
+// linePriority weighs games leaving a line, past its end or where the user deviated from it.
+// Poor recall makes deviations likelier to be memory slips than deliberate choices.
+func linePriority(left lineResult, recall *float64) float64 {
+	return left.lostPoints() * recallFactor(recall)
+}
+
+// coverPriority weighs an opponent move the repertoire does not answer by what it cost and how
+// often it is met
+func coverPriority(reply models.OpponentReply) float64 {
+	return float64(reply.Losses) + float64(reply.Draws)/2 + float64(reply.Games)*recommendationUncoveredWeight
+}
+
+// reviewPriority weighs training a whole repertoire again, 0 when recall is good enough or rests
+// on too few answers
+func reviewPriority(recall models.TrainingRecall) float64 {
+	if recall.Reviews < recommendationReviewAnswers {
+		return 0
+	}
+	rate := float64(recall.Correct) / float64(recall.Reviews)
+	if rate >= recommendationReviewRecall {
+		return 0
+	}
+	return (1 - rate) * recommendationReviewWeight
+}
+
+// resultsPhrase describes a record as in "3 losses in 4 games"
+func resultsPhrase(games, losses int) string {
+	return fmt.Sprintf("%s in %s", countNoun(losses, "loss", "losses"), countNoun(games, "game", "games"))
+}
+
+func countNoun(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+func TestLeftResult(t *testing.T) {
+	node := models.NodeResult{Games: 10, Wins: 5, Draws: 1, Losses: 4}
+	children := []models.NodeResult{
+		{Games: 6, Wins: 4, Losses: 2},
+		{Games: 1, Draws: 1},
+	}
+
+	left := leftResult(node, children)
+
+	assert.Equal(t, lineResult{games: 3, wins: 1, draws: 0, losses: 2}, left)
+	assert.Equal(t, 2.0, left.lostPoints())
+}
+
+func TestLinePriority(t *testing.T) {
+	left := lineResult{games: 4, wins: 1, draws: 2, losses: 1}
+	half := 0.5
+
+	assert.Equal(t, 2.0, linePriority(left, nil))
+	// Poor recall makes the line weigh more
+	assert.Equal(t, 3.0, linePriority(left, &half))
+	assert.Equal(t, 0.0, linePriority(lineResult{games: 3, wins: 3}, &half))
+}
+
+func TestCoverPriority(t *testing.T) {
+	// Unanswered moves are worth studying even when the games were won
+	assert.Equal(t, 2.0, coverPriority(models.OpponentReply{Games: 4, Wins: 4}))
+	assert.Equal(t, 3.5, coverPriority(models.OpponentReply{Games: 3, Wins: 1, Losses: 2}))
+}
+
+func TestReviewPriority(t *testing.T) {
+	assert.Equal(t, 5.0, reviewPriority(models.TrainingRecall{Reviews: 20, Correct: 10}))
+	// Good recall, or too few answers to tell
+	assert.Equal(t, 0.0, reviewPriority(models.TrainingRecall{Reviews: 20, Correct: 16}))
+	assert.Equal(t, 0.0, reviewPriority(models.TrainingRecall{Reviews: 5, Correct: 0}))
+}
+
+func TestResultsPhrase(t *testing.T) {
+	assert.Equal(t, "3 losses in 4 games", resultsPhrase(4, 3))
+	assert.Equal(t, "1 loss in 1 game", resultsPhrase(1, 1))
+	assert.Equal(t, "0 losses in 2 games", resultsPhrase(2, 0))
+}
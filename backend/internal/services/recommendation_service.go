@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// RecommendationService ranks what a user should study next from the coverage of their
+// repertoires, their results and their opponents' moves in games, and their training recall
+type RecommendationService struct {
+	repertoireService *RepertoireService
+	importService     *ImportService
+	trainingRepo      repository.TrainingRepository
+}
+
+// NewRecommendationService creates a new recommendation service
+func NewRecommendationService(repertoireService *RepertoireService, importService *ImportService, trainingRepo repository.TrainingRepository) *RecommendationService {
+	return &RecommendationService{
+		repertoireService: repertoireService,
+		importService:     importService,
+		trainingRepo:      trainingRepo,
+	}
+}
+
+// Recommend lists up to config.MaxRecommendations things for the user to study, most valuable first
+func (s *RecommendationService) Recommend(userID string) ([]models.Recommendation, error) {
+	repertoires, err := s.repertoireService.ListRepertoires(userID, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repertoires: %w", err)
+	}
+
+	since := time.Now().Add(-config.RecommendationRecallWindow)
+	recommendations := []models.Recommendation{}
+	for _, rep := range repertoires {
+		recall, err := s.trainingRepo.GetRecall(userID, rep.ID, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get training recall: %w", err)
+		}
+		var rate *float64
+		if recall.Reviews > 0 {
+			value := float64(recall.Correct) / float64(recall.Reviews)
+			rate = &value
+		}
+		if priority := reviewPriority(*recall); priority > 0 {
+			recommendations = append(recommendations, models.Recommendation{
+				Kind:           models.RecommendationReview,
+				RepertoireID:   rep.ID,
+				RepertoireName: rep.Name,
+				Recall:         rate,
+				Priority:       priority,
+				Summary: fmt.Sprintf("Train %s again — %.0f%% of your answers right over the last %d days",
+					rep.Name, *rate*100, int(config.RecommendationRecallWindow.Hours()/24)),
+			})
+		}
+
+		overlay, err := s.importService.ResultsOverlay(userID, rep.ID)
+		if err != nil {
+			return nil, err
+		}
+		lines, err := s.lineRecommendations(userID, rep, overlay.Nodes, rate)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, lines...)
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Priority > recommendations[j].Priority
+	})
+	if len(recommendations) > config.MaxRecommendations {
+		recommendations = recommendations[:config.MaxRecommendations]
+	}
+	return recommendations, nil
+}
+
+// lineRecommendations looks for the positions of a repertoire where the user's games leave it:
+// past the end of a line, where an opponent played a move it does not answer, or where the user
+// deviated from their own preparation
+func (s *RecommendationService) lineRecommendations(userID string, rep models.Repertoire, nodes []models.NodeResult, recall *float64) ([]models.Recommendation, error) {
+	results := make(map[string]models.NodeResult, len(nodes))
+	for _, node := range nodes {
+		results[node.NodeID] = node
+	}
+	userToMove := userColorToMove(rep.Color)
+
+	var recommendations []models.Recommendation
+	var walk func(node *models.RepertoireNode, line []*models.RepertoireNode) error
+	walk = func(node *models.RepertoireNode, line []*models.RepertoireNode) error {
+		if node.Move != nil {
+			line = append(line, node)
+		}
+		result, reached := results[node.ID]
+		if !reached {
+			return nil
+		}
+
+		var children []models.NodeResult
+		for _, child := range node.Children {
+			if r, ok := results[child.ID]; ok {
+				children = append(children, r)
+			}
+		}
+		left := leftResult(result, children)
+		base := models.Recommendation{
+			RepertoireID:   rep.ID,
+			RepertoireName: rep.Name,
+			NodeID:         node.ID,
+			Line:           recommendationLine(line),
+			Recall:         recall,
+		}
+
+		switch {
+		case len(node.Children) == 0:
+			// A transposition continues in the line it points to
+			if node.Move == nil || node.TranspositionOf != nil || left.games < config.RecommendationMinGames {
+				break
+			}
+			if priority := linePriority(left, recall); priority > 0 {
+				rec := base
+				rec.Kind = models.RecommendationDeepen
+				rec.Games = left.games
+				rec.Losses = left.losses
+				rec.CoveredUntil = node.MoveNumber
+				rec.Priority = priority
+				rec.Summary = fmt.Sprintf("Deepen %s — %s, no coverage past move %d", rec.Line, resultsPhrase(left.games, left.losses), node.MoveNumber)
+				recommendations = append(recommendations, rec)
+			}
+		case node.ColorToMove == userToMove:
+			if node.Move == nil || left.games < config.RecommendationMinGames {
+				break
+			}
+			if priority := linePriority(left, recall); priority > 0 {
+				rec := base
+				rec.Kind = models.RecommendationReview
+				rec.Games = left.games
+				rec.Losses = left.losses
+				rec.Priority = priority
+				rec.Summary = fmt.Sprintf("Review %s — you left your preparation here: %s", rec.Line, resultsPhrase(left.games, left.losses))
+				recommendations = append(recommendations, rec)
+			}
+		default:
+			if left.games < config.RecommendationMinGames {
+				break
+			}
+			covers, err := s.coverRecommendations(userID, node, base)
+			if err != nil {
+				return err
+			}
+			recommendations = append(recommendations, covers...)
+		}
+
+		for _, child := range node.Children {
+			if err := walk(child, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(&rep.TreeData, nil); err != nil {
+		return nil, err
+	}
+	return recommendations, nil
+}
+
+// coverRecommendations lists the moves the user's opponents played in a position that the
+// repertoire does not answer
+func (s *RecommendationService) coverRecommendations(userID string, node *models.RepertoireNode, base models.Recommendation) ([]models.Recommendation, error) {
+	replies, err := s.importService.OpponentReplies(userID, node.FEN, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var recommendations []models.Recommendation
+	for _, reply := range replies.Replies {
+		if reply.Games < config.RecommendationMinGames || childWithMove(node, reply.Move) != nil {
+			continue
+		}
+		rec := base
+		rec.Kind = models.RecommendationCover
+		rec.Move = reply.Move
+		rec.Games = reply.Games
+		rec.Losses = reply.Losses
+		rec.Priority = coverPriority(reply)
+		position := "the starting position"
+		if rec.Line != "" {
+			position = rec.Line
+		}
+		rec.Summary = fmt.Sprintf("Prepare an answer to %s after %s — %s", reply.Move, position, resultsPhrase(reply.Games, reply.Losses))
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations, nil
+}
+
+// recommendationLine writes the moves to a position in move-number notation
+func recommendationLine(line []*models.RepertoireNode) string {
+	var tokens []string
+	for i, node := range line {
+		// ColorToMove is the side to move after the node's move
+		if node.ColorToMove == models.ChessColorBlack {
+			tokens = append(tokens, fmt.Sprintf("%d.", node.MoveNumber))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d...", node.MoveNumber))
+		}
+		tokens = append(tokens, *node.Move)
+	}
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+// newRecommendationRepertoire is 1.e4 e5 2.Nf3 and 1.e4 c5 2.Nf3 for white
+func newRecommendationRepertoire() *models.Repertoire {
+	move := func(san string) *string { return &san }
+	return &models.Repertoire{
+		ID: "rep-1", Name: "e4", Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID: "root", FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ColorToMove: models.ChessColorWhite,
+			Children: []*models.RepertoireNode{{
+				ID: "e4", Move: move("e4"), MoveNumber: 1, ColorToMove: models.ChessColorBlack,
+				FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+				Children: []*models.RepertoireNode{
+					{
+						ID: "e5", Move: move("e5"), MoveNumber: 1, ColorToMove: models.ChessColorWhite,
+						FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+						Children: []*models.RepertoireNode{{
+							ID: "nf3-e5", Move: move("Nf3"), MoveNumber: 2, ColorToMove: models.ChessColorBlack,
+							FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+						}},
+					},
+					{
+						ID: "c5", Move: move("c5"), MoveNumber: 1, ColorToMove: models.ChessColorWhite,
+						FEN: "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+						Children: []*models.RepertoireNode{{
+							ID: "nf3-c5", Move: move("Nf3"), MoveNumber: 2, ColorToMove: models.ChessColorBlack,
+							FEN: "rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func newTestRecommendationService(recall models.TrainingRecall) *RecommendationService {
+	rep := newRecommendationRepertoire()
+	repertoireSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetAllFunc:        func(userID string) ([]models.Repertoire, error) { return []models.Repertoire{*rep}, nil },
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return true, nil },
+	})
+	gameResultRepo := &mocks.MockGameResultRepo{
+		OverlayFunc: func(repertoireID, userID string) ([]models.NodeResult, error) {
+			return []models.NodeResult{
+				{NodeID: "root", Games: 10, Wins: 5, Draws: 1, Losses: 4},
+				{NodeID: "e4", Games: 10, Wins: 5, Draws: 1, Losses: 4},
+				{NodeID: "e5", Games: 3, Wins: 1, Losses: 2},
+				{NodeID: "nf3-e5", Games: 3, Wins: 1, Losses: 2},
+				{NodeID: "c5", Games: 4, Wins: 2, Draws: 1, Losses: 1},
+				{NodeID: "nf3-c5", Games: 2, Wins: 1, Draws: 1},
+			}, nil
+		},
+	}
+	opponentReplyRepo := &mocks.MockOpponentReplyRepo{
+		AggregateFunc: func(userID, fen string, minRating, maxRating int) ([]models.OpponentReply, error) {
+			return []models.OpponentReply{
+				{Move: "c5", Games: 4, Wins: 2, Draws: 1, Losses: 1},
+				{Move: "d5", Games: 3, Wins: 1, Losses: 2},
+				{Move: "c6", Games: 1, Losses: 1},
+			}, nil
+		},
+	}
+	importSvc := NewImportService(repertoireSvc, &mocks.MockAnalysisRepo{},
+		WithGameResultRepo(gameResultRepo),
+		WithOpponentReplyRepo(opponentReplyRepo),
+	)
+	trainingRepo := &mocks.MockTrainingRepo{
+		GetRecallFunc: func(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error) {
+			return &recall, nil
+		},
+	}
+	return NewRecommendationService(repertoireSvc, importSvc, trainingRepo)
+}
+
+func TestRecommendationService_Recommend(t *testing.T) {
+	svc := newTestRecommendationService(models.TrainingRecall{Reviews: 20, Correct: 10})
+
+	recommendations, err := svc.Recommend("user-1")
+
+	require.NoError(t, err)
+	require.Len(t, recommendations, 5)
+
+	// Half the training answers wrong: the repertoire is worth training again
+	assert.Equal(t, models.RecommendationReview, recommendations[0].Kind)
+	assert.Empty(t, recommendations[0].NodeID)
+	assert.Equal(t, 5.0, recommendations[0].Priority)
+
+	// d5 is not answered; c6 was met too rarely and c5 is covered
+	cover := recommendations[1]
+	assert.Equal(t, models.RecommendationCover, cover.Kind)
+	assert.Equal(t, "e4", cover.NodeID)
+	assert.Equal(t, "d5", cover.Move)
+	assert.Equal(t, "Prepare an answer to d5 after 1. e4 — 2 losses in 3 games", cover.Summary)
+
+	deepen := recommendations[2]
+	assert.Equal(t, models.RecommendationDeepen, deepen.Kind)
+	assert.Equal(t, "nf3-e5", deepen.NodeID)
+	assert.Equal(t, 2, deepen.CoveredUntil)
+	assert.Equal(t, 3.0, deepen.Priority)
+	assert.Equal(t, "Deepen 1. e4 e5 2. Nf3 — 2 losses in 3 games, no coverage past move 2", deepen.Summary)
+
+	// Two of the c5 games left the repertoire on the user's move
+	review := recommendations[3]
+	assert.Equal(t, models.RecommendationReview, review.Kind)
+	assert.Equal(t, "c5", review.NodeID)
+	assert.Equal(t, 2, review.Games)
+	assert.Equal(t, 1.5, review.Priority)
+
+	assert.Equal(t, "nf3-c5", recommendations[4].NodeID)
+}
+
+func TestRecommendationService_Recommend_GoodRecall(t *testing.T) {
+	svc := newTestRecommendationService(models.TrainingRecall{Reviews: 20, Correct: 20})
+
+	recommendations, err := svc.Recommend("user-1")
+
+	require.NoError(t, err)
+	for _, rec := range recommendations {
+		assert.NotEmpty(t, rec.NodeID, "no repertoire-wide review with perfect recall")
+	}
+	// Perfect recall leaves line priorities as lost points
+	assert.Equal(t, "d5", recommendations[0].Move)
+	assert.Equal(t, 2.0, recommendations[1].Priority)
+}
+
+func TestRecommendationLine(t *testing.T) {
+	move := func(san string) *string { return &san }
+	line := []*models.RepertoireNode{
+		{Move: move("c5"), MoveNumber: 1, ColorToMove: models.ChessColorWhite},
+		{Move: move("Nf3"), MoveNumber: 2, ColorToMove: models.ChessColorBlack},
+		{Move: move("d6"), MoveNumber: 2, ColorToMove: models.ChessColorWhite},
+	}
+
+	assert.Equal(t, "1... c5 2. Nf3 d6", recommendationLine(line))
+	assert.Equal(t, "", recommendationLine(nil))
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var ErrRepertoireArchived = fmt.Errorf("repertoire is archived: unarchive it to edit its tree")
+
+// SetArchived archives or unarchives a repertoire. An archived repertoire keeps its tree, games
+// and history, but its tree cannot be edited, games are not matched against it, its health is
+// no longer refreshed and it is left out of repertoire lists unless asked for.
+func (s *RepertoireService) SetArchived(repertoireID string, archived bool) (*models.Repertoire, error) {
+	rep, err := s.repo.SetArchived(repertoireID, archived)
+	if err != nil {
+		if errors.Is(err, repository.ErrRepertoireNotFound) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return rep, nil
+}
+
+// getEditableRepertoire loads a repertoire whose tree is about to be edited
+func (s *RepertoireService) getEditableRepertoire(repertoireID string) (*models.Repertoire, error) {
+	rep, err := s.getRepertoireTree(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	if rep.Archived {
+		return nil, ErrRepertoireArchived
+	}
+	return rep, nil
+}
+
+// withoutArchived drops the archived repertoires of a list, keeping its order
+func withoutArchived(repertoires []models.Repertoire) []models.Repertoire {
+	kept := make([]models.Repertoire, 0, len(repertoires))
+	for _, rep := range repertoires {
+		if !rep.Archived {
+			kept = append(kept, rep)
+		}
+	}
+	return kept
+}
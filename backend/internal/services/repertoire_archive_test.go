@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestArchivedRepertoire_IsReadOnly(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5")
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, Archived: true, TreeData: tree}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			t.Fatal("an archived tree is never saved")
+			return nil, nil
+		},
+		SaveNodesFunc: func(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+			t.Fatal("an archived tree is never saved")
+			return nil, nil
+		},
+	})
+	e4 := tree.Children[0]
+
+	_, err := svc.AddNode("rep-1", models.AddNodeRequest{ParentID: e4.Children[0].ID, Move: "Nf3"})
+	assert.ErrorIs(t, err, ErrRepertoireArchived)
+	_, err = svc.DeleteNode("rep-1", e4.Children[0].ID)
+	assert.ErrorIs(t, err, ErrRepertoireArchived)
+	_, err = svc.SaveTree("rep-1", tree)
+	assert.ErrorIs(t, err, ErrRepertoireArchived)
+	_, err = svc.ReorderChildren("rep-1", e4.ID, []string{e4.Children[0].ID})
+	assert.ErrorIs(t, err, ErrRepertoireArchived)
+	_, err = svc.RestoreRevision("rep-1", 1)
+	assert.ErrorIs(t, err, ErrRepertoireArchived)
+
+	rep, err := svc.GetRepertoire("rep-1")
+	require.NoError(t, err, "an archived repertoire can still be read")
+	assert.True(t, rep.Archived)
+}
+
+func TestSetArchived(t *testing.T) {
+	var archivedID string
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		SetArchivedFunc: func(id string, archived bool) (*models.Repertoire, error) {
+			if id == "missing" {
+				return nil, repository.ErrRepertoireNotFound
+			}
+			archivedID = id
+			return &models.Repertoire{ID: id, Archived: archived}, nil
+		},
+	})
+
+	rep, err := svc.SetArchived("rep-1", true)
+	require.NoError(t, err)
+	assert.True(t, rep.Archived)
+	assert.Equal(t, "rep-1", archivedID)
+
+	_, err = svc.SetArchived("missing", true)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListRepertoires_HidesArchived(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) {
+			return []models.Repertoire{
+				{ID: "rep-current", Name: "2026 prep"},
+				{ID: "rep-old", Name: "2025 prep", Archived: true},
+			}, nil
+		},
+	})
+
+	reps, err := svc.ListRepertoires("user-1", nil, false)
+	require.NoError(t, err)
+	require.Len(t, reps, 1)
+	assert.Equal(t, "rep-current", reps[0].ID)
+
+	reps, err = svc.ListRepertoires("user-1", nil, true)
+	require.NoError(t, err)
+	assert.Len(t, reps, 2)
+}
+
+func TestMatchScope_SkipsArchived(t *testing.T) {
+	reps := []models.Repertoire{{ID: "rep-current"}, {ID: "rep-old", Archived: true}}
+
+	kept := (&matchScope{}).filter(reps)
+	require.Len(t, kept, 1)
+	assert.Equal(t, "rep-current", kept[0].ID)
+
+	named := (&matchScope{only: map[string]bool{"rep-old": true}}).filter(reps)
+	assert.Empty(t, named, "naming an archived repertoire does not match against it")
+}
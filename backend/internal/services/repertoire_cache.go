@@ -0,0 +1,184 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// RepertoireCache keeps the repertoires of each user in memory for a short time, so imports
+// and insights running several analyses in a row decode the trees once. Entries are dropped
+// on every write to one of their repertoires; the TTL bounds staleness from writes made
+// around the service, such as cascading deletes.
+type RepertoireCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]repertoireCacheEntry
+	// generation counts invalidations, so a read racing a write does not cache what it read
+	generation uint64
+}
+
+type repertoireCacheEntry struct {
+	repertoires []models.Repertoire
+	expiresAt   time.Time
+}
+
+// NewRepertoireCache creates a cache whose entries live for ttl
+func NewRepertoireCache(ttl time.Duration) *RepertoireCache {
+	return &RepertoireCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]repertoireCacheEntry),
+	}
+}
+
+// get returns the cached repertoires of a user. On a miss it returns the generation to pass
+// to set along with the repertoires read.
+func (c *RepertoireCache) get(userID string) ([]models.Repertoire, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok {
+		return nil, c.generation, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, userID)
+		return nil, c.generation, false
+	}
+	return entry.repertoires, c.generation, true
+}
+
+// set caches the repertoires of a user read at generation, unless a write happened since
+func (c *RepertoireCache) set(userID string, repertoires []models.Repertoire, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = repertoireCacheEntry{repertoires: repertoires, expiresAt: now.Add(c.ttl)}
+}
+
+// InvalidateUser drops the cached repertoires of a user
+func (c *RepertoireCache) InvalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	delete(c.entries, userID)
+}
+
+// InvalidateRepertoire drops the cached repertoires of the user owning a repertoire. A user
+// whose repertoires are cached has all of them cached, so looking the ID up is enough.
+func (c *RepertoireCache) InvalidateRepertoire(repertoireID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for userID, entry := range c.entries {
+		for _, rep := range entry.repertoires {
+			if rep.ID == repertoireID {
+				delete(c.entries, userID)
+				break
+			}
+		}
+	}
+}
+
+// cachingRepertoireRepo invalidates the cache on every repertoire write going through it
+type cachingRepertoireRepo struct {
+	RepertoireRepository
+	cache *RepertoireCache
+}
+
+func (r *cachingRepertoireRepo) Create(userID string, name string, color models.Color) (*models.Repertoire, error) {
+	defer r.cache.InvalidateUser(userID)
+	return r.RepertoireRepository.Create(userID, name, color)
+}
+
+func (r *cachingRepertoireRepo) CreateWithCategory(userID, name string, color models.Color, categoryID *string) (*models.Repertoire, error) {
+	defer r.cache.InvalidateUser(userID)
+	return r.RepertoireRepository.CreateWithCategory(userID, name, color, categoryID)
+}
+
+func (r *cachingRepertoireRepo) Save(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.Save(id, treeData, metadata)
+}
+
+func (r *cachingRepertoireRepo) SaveNodes(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.SaveNodes(id, version, tree, changes, metadata)
+}
+
+func (r *cachingRepertoireRepo) UpdateName(id string, name string) (*models.Repertoire, error) {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.UpdateName(id, name)
+}
+
+func (r *cachingRepertoireRepo) UpdateCategory(id string, categoryID *string) (*models.Repertoire, error) {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.UpdateCategory(id, categoryID)
+}
+
+func (r *cachingRepertoireRepo) SetMatchActive(id string, active bool) (*models.Repertoire, error) {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.SetMatchActive(id, active)
+}
+
+func (r *cachingRepertoireRepo) SetArchived(id string, archived bool) (*models.Repertoire, error) {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.SetArchived(id, archived)
+}
+
+func (r *cachingRepertoireRepo) Delete(id string) error {
+	defer r.cache.InvalidateRepertoire(id)
+	return r.RepertoireRepository.Delete(id)
+}
+
+// WithCache serves CachedRepertoires from cache and invalidates it on every write of the service
+func (s *RepertoireService) WithCache(cache *RepertoireCache) {
+	s.cache = cache
+	s.repo = &cachingRepertoireRepo{RepertoireRepository: s.repo, cache: cache}
+}
+
+// CachedRepertoires returns the repertoires of a user, optionally of one color, from the cache
+// when one is set. The trees are shared with other callers and must not be modified; health
+// is not attached.
+func (s *RepertoireService) CachedRepertoires(userID string, color *models.Color) ([]models.Repertoire, error) {
+	if color != nil && *color != models.ColorWhite && *color != models.ColorBlack {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidColor, *color)
+	}
+	if s.cache == nil {
+		return s.ListRepertoires(userID, color, true)
+	}
+
+	all, generation, ok := s.cache.get(userID)
+	if !ok {
+		var err error
+		all, err = s.repo.GetAll(userID)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.set(userID, all, generation)
+	}
+
+	repertoires := make([]models.Repertoire, 0, len(all))
+	for _, rep := range all {
+		if color == nil || rep.Color == *color {
+			repertoires = append(repertoires, rep)
+		}
+	}
+	return repertoires, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func newCachedRepertoireService(t *testing.T) (*RepertoireService, *RepertoireCache, *int) {
+	t.Helper()
+	loads := 0
+	repo := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) {
+			loads++
+			return []models.Repertoire{
+				{ID: "rep-white", Color: models.ColorWhite},
+				{ID: "rep-black", Color: models.ColorBlack},
+			}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id}, nil
+		},
+	}
+	svc := NewRepertoireService(repo)
+	cache := NewRepertoireCache(time.Minute)
+	svc.WithCache(cache)
+	return svc, cache, &loads
+}
+
+func TestCachedRepertoires_ReusesLoadedRepertoires(t *testing.T) {
+	svc, _, loads := newCachedRepertoireService(t)
+	white := models.ColorWhite
+
+	all, err := svc.CachedRepertoires("user-1", nil)
+	require.NoError(t, err)
+	whites, err := svc.CachedRepertoires("user-1", &white)
+	require.NoError(t, err)
+
+	assert.Len(t, all, 2)
+	require.Len(t, whites, 1)
+	assert.Equal(t, "rep-white", whites[0].ID)
+	assert.Equal(t, 1, *loads)
+}
+
+func TestCachedRepertoires_InvalidatedOnSave(t *testing.T) {
+	svc, _, loads := newCachedRepertoireService(t)
+
+	_, err := svc.CachedRepertoires("user-1", nil)
+	require.NoError(t, err)
+	_, err = svc.repo.Save("rep-black", models.RepertoireNode{}, models.Metadata{})
+	require.NoError(t, err)
+	_, err = svc.CachedRepertoires("user-1", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, *loads)
+}
+
+func TestCachedRepertoires_Expires(t *testing.T) {
+	svc, cache, loads := newCachedRepertoireService(t)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, err := svc.CachedRepertoires("user-1", nil)
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = svc.CachedRepertoires("user-1", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, *loads)
+}
+
+func TestRepertoireCache_SkipsReadsRacingWrites(t *testing.T) {
+	cache := NewRepertoireCache(time.Minute)
+
+	_, generation, ok := cache.get("user-1")
+	require.False(t, ok)
+	cache.InvalidateRepertoire("rep-1")
+	cache.set("user-1", []models.Repertoire{{ID: "rep-1"}}, generation)
+
+	_, _, ok = cache.get("user-1")
+	assert.False(t, ok)
+}
+
+func TestCachedRepertoires_InvalidColor(t *testing.T) {
+	svc, _, _ := newCachedRepertoireService(t)
+	color := models.Color("green")
+
+	_, err := svc.CachedRepertoires("user-1", &color)
+
+	assert.ErrorIs(t, err, ErrInvalidColor)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// WithCollaborators enables sharing repertoires with other users
+func (s *RepertoireService) WithCollaborators(collaboratorRepo repository.CollaboratorRepository, userRepo repository.UserRepository) {
+	s.collaboratorRepo = collaboratorRepo
+	s.userRepo = userRepo
+}
+
+// CheckOwner returns ErrNotFound unless the user owns the repertoire, or ErrForbidden if it is only shared with them.
+// Deleting, renaming, sharing and anything creating or removing whole repertoires is owner-only.
+func (s *RepertoireService) CheckOwner(id string, userID string) error {
+	return s.checkAccess(id, userID)
+}
+
+// CheckReadAccess returns ErrNotFound unless the user owns the repertoire or it is shared with them
+func (s *RepertoireService) CheckReadAccess(id string, userID string) error {
+	return s.checkAccess(id, userID, models.CollaboratorViewer, models.CollaboratorEditor)
+}
+
+// checkAccess lets through the owner and collaborators with one of roles. Other collaborators get
+// ErrForbidden; users the repertoire is not shared with get ErrNotFound.
+func (s *RepertoireService) checkAccess(id, userID string, roles ...models.CollaboratorRole) error {
+	belongs, err := s.repo.BelongsToUser(id, userID)
+	ownerErr := ownershipGuard(belongs, err, ErrNotFound)
+	if ownerErr == nil || !errors.Is(ownerErr, ErrNotFound) || s.collaboratorRepo == nil {
+		return ownerErr
+	}
+
+	collaborator, err := s.collaboratorRepo.Get(id, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrCollaboratorNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to check collaborator: %w", err)
+	}
+	for _, role := range roles {
+		if collaborator.Role == role {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// InviteCollaborator shares a repertoire with the user matching the request's email or username
+func (s *RepertoireService) InviteCollaborator(repertoireID, ownerID string, req models.InviteCollaboratorRequest) (*models.RepertoireCollaborator, error) {
+	if s.collaboratorRepo == nil {
+		return nil, ErrCollaboratorsUnavailable
+	}
+	if req.Role != models.CollaboratorViewer && req.Role != models.CollaboratorEditor {
+		return nil, ErrInvalidRole
+	}
+
+	var invitee *models.User
+	var err error
+	switch {
+	case strings.TrimSpace(req.Email) != "":
+		invitee, err = s.userRepo.GetByEmail(strings.TrimSpace(req.Email))
+	case strings.TrimSpace(req.Username) != "":
+		invitee, err = s.userRepo.GetByUsername(strings.TrimSpace(req.Username))
+	default:
+		return nil, ErrInviteeRequired
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInviteeNotFound
+		}
+		return nil, fmt.Errorf("failed to find invitee: %w", err)
+	}
+	if invitee.ID == ownerID {
+		return nil, ErrCannotInviteSelf
+	}
+
+	return s.collaboratorRepo.Upsert(repertoireID, invitee.ID, req.Role, ownerID)
+}
+
+// ListCollaborators returns the users a repertoire is shared with
+func (s *RepertoireService) ListCollaborators(repertoireID string) ([]models.RepertoireCollaborator, error) {
+	if s.collaboratorRepo == nil {
+		return []models.RepertoireCollaborator{}, nil
+	}
+	return s.collaboratorRepo.ListByRepertoire(repertoireID)
+}
+
+// RemoveCollaborator revokes a user's access. The owner can remove anyone and
+// collaborators can remove themselves.
+func (s *RepertoireService) RemoveCollaborator(repertoireID, requesterID, collaboratorID string) error {
+	if s.collaboratorRepo == nil {
+		return repository.ErrCollaboratorNotFound
+	}
+	if requesterID != collaboratorID {
+		if err := s.CheckOwner(repertoireID, requesterID); err != nil {
+			return err
+		}
+	}
+	return s.collaboratorRepo.Delete(repertoireID, collaboratorID)
+}
+
+// ListSharedRepertoires returns the repertoires other users shared with the user
+func (s *RepertoireService) ListSharedRepertoires(userID string) ([]models.SharedRepertoire, error) {
+	shared := []models.SharedRepertoire{}
+	if s.collaboratorRepo == nil {
+		return shared, nil
+	}
+
+	collaborations, err := s.collaboratorRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range collaborations {
+		rep, err := s.repo.GetByID(c.RepertoireID)
+		if err != nil {
+			if errors.Is(err, repository.ErrRepertoireNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		shared = append(shared, models.SharedRepertoire{Repertoire: *rep, Role: c.Role})
+	}
+	return shared, nil
+}
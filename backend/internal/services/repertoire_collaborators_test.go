@@ -0,0 +1,139 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func newSharingService(roles map[string]models.CollaboratorRole) (*RepertoireService, *mocks.MockCollaboratorRepo) {
+	repo := &mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id string, userID string) (bool, error) { return userID == "owner", nil },
+	}
+	collaborators := &mocks.MockCollaboratorRepo{
+		GetFunc: func(repertoireID, userID string) (*models.RepertoireCollaborator, error) {
+			role, ok := roles[userID]
+			if !ok {
+				return nil, repository.ErrCollaboratorNotFound
+			}
+			return &models.RepertoireCollaborator{RepertoireID: repertoireID, UserID: userID, Role: role}, nil
+		},
+	}
+	users := &mocks.MockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			if email == "student@example.com" {
+				return &models.User{ID: "student", Username: "student"}, nil
+			}
+			return nil, repository.ErrUserNotFound
+		},
+		GetByUsernameFunc: func(username string) (*models.User, error) {
+			if username == "owner" {
+				return &models.User{ID: "owner", Username: "owner"}, nil
+			}
+			return nil, repository.ErrUserNotFound
+		},
+	}
+	svc := NewRepertoireService(repo)
+	svc.WithCollaborators(collaborators, users)
+	return svc, collaborators
+}
+
+func TestRepertoireService_AccessChecks(t *testing.T) {
+	svc, _ := newSharingService(map[string]models.CollaboratorRole{
+		"editor": models.CollaboratorEditor,
+		"viewer": models.CollaboratorViewer,
+	})
+
+	assert.NoError(t, svc.CheckOwnership("rep-1", "owner"))
+	assert.NoError(t, svc.CheckOwnership("rep-1", "editor"))
+	assert.ErrorIs(t, svc.CheckOwnership("rep-1", "viewer"), ErrForbidden)
+	assert.ErrorIs(t, svc.CheckOwnership("rep-1", "stranger"), ErrNotFound)
+
+	assert.NoError(t, svc.CheckReadAccess("rep-1", "viewer"))
+	assert.NoError(t, svc.CheckReadAccess("rep-1", "editor"))
+	assert.ErrorIs(t, svc.CheckReadAccess("rep-1", "stranger"), ErrNotFound)
+
+	assert.ErrorIs(t, svc.CheckOwner("rep-1", "editor"), ErrForbidden)
+	assert.ErrorIs(t, svc.CheckOwner("rep-1", "stranger"), ErrNotFound)
+}
+
+func TestRepertoireService_InviteCollaborator(t *testing.T) {
+	svc, collaborators := newSharingService(nil)
+	var invitedBy string
+	collaborators.UpsertFunc = func(repertoireID, userID string, role models.CollaboratorRole, by string) (*models.RepertoireCollaborator, error) {
+		invitedBy = by
+		return &models.RepertoireCollaborator{RepertoireID: repertoireID, UserID: userID, Role: role}, nil
+	}
+
+	collaborator, err := svc.InviteCollaborator("rep-1", "owner", models.InviteCollaboratorRequest{
+		Email: " student@example.com ",
+		Role:  models.CollaboratorEditor,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "student", collaborator.UserID)
+	assert.Equal(t, models.CollaboratorEditor, collaborator.Role)
+	assert.Equal(t, "owner", invitedBy)
+}
+
+func TestRepertoireService_InviteCollaborator_Errors(t *testing.T) {
+	svc, _ := newSharingService(nil)
+
+	_, err := svc.InviteCollaborator("rep-1", "owner", models.InviteCollaboratorRequest{Email: "student@example.com", Role: "admin"})
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	_, err = svc.InviteCollaborator("rep-1", "owner", models.InviteCollaboratorRequest{Role: models.CollaboratorViewer})
+	assert.ErrorIs(t, err, ErrInviteeRequired)
+
+	_, err = svc.InviteCollaborator("rep-1", "owner", models.InviteCollaboratorRequest{Username: "nobody", Role: models.CollaboratorViewer})
+	assert.ErrorIs(t, err, ErrInviteeNotFound)
+
+	_, err = svc.InviteCollaborator("rep-1", "owner", models.InviteCollaboratorRequest{Username: "owner", Role: models.CollaboratorViewer})
+	assert.ErrorIs(t, err, ErrCannotInviteSelf)
+}
+
+func TestRepertoireService_RemoveCollaborator(t *testing.T) {
+	svc, collaborators := newSharingService(nil)
+	var removed []string
+	collaborators.DeleteFunc = func(repertoireID, userID string) error {
+		removed = append(removed, userID)
+		return nil
+	}
+
+	require.NoError(t, svc.RemoveCollaborator("rep-1", "owner", "student"))
+	require.NoError(t, svc.RemoveCollaborator("rep-1", "student", "student"))
+	assert.ErrorIs(t, svc.RemoveCollaborator("rep-1", "student", "coach"), ErrNotFound)
+	assert.Equal(t, []string{"student", "student"}, removed)
+}
+
+func TestRepertoireService_ListSharedRepertoires(t *testing.T) {
+	repo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			if id == "gone" {
+				return nil, repository.ErrRepertoireNotFound
+			}
+			return &models.Repertoire{ID: id, Name: "Shared"}, nil
+		},
+	}
+	svc := NewRepertoireService(repo)
+	svc.WithCollaborators(&mocks.MockCollaboratorRepo{
+		ListByUserFunc: func(userID string) ([]models.RepertoireCollaborator, error) {
+			return []models.RepertoireCollaborator{
+				{RepertoireID: "rep-1", UserID: userID, Role: models.CollaboratorViewer},
+				{RepertoireID: "gone", UserID: userID, Role: models.CollaboratorEditor},
+			}, nil
+		},
+	}, nil)
+
+	shared, err := svc.ListSharedRepertoires("student")
+
+	require.NoError(t, err)
+	require.Len(t, shared, 1)
+	assert.Equal(t, "rep-1", shared[0].ID)
+	assert.Equal(t, models.CollaboratorViewer, shared[0].Role)
+}
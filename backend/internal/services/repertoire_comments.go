@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var ErrInvalidCommentsFile = fmt.Errorf("invalid comments file: a CSV with path and comment columns is expected")
+
+var commentsCSVHeader = []string{"path", "comment"}
+
+// moveNumberPrefix matches the move numbers other tools write in lines, e.g. "1." or "12..."
+var moveNumberPrefix = regexp.MustCompile(`^[0-9]+\.+`)
+
+// ExportCommentsCSV exports the comments of a repertoire as CSV, one row per node in display
+// order. The path is the SAN moves from the root separated by spaces, empty for the root itself;
+// nodes without a comment are exported too so they can be filled in from a spreadsheet.
+func (s *RepertoireService) ExportCommentsCSV(repertoireID string) ([]byte, error) {
+	rep, err := s.getRepertoireTree(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := [][]string{commentsCSVHeader}
+	var walk func(node *models.RepertoireNode, path []string)
+	walk = func(node *models.RepertoireNode, path []string) {
+		if node.Move != nil {
+			path = append(path, *node.Move)
+		}
+		comment := ""
+		if node.Comment != nil {
+			comment = *node.Comment
+		}
+		rows = append(rows, []string{strings.Join(path, " "), comment})
+		for _, child := range node.Children {
+			walk(child, path[:len(path):len(path)])
+		}
+	}
+	walk(&rep.TreeData, nil)
+
+	return writeCSV(rows)
+}
+
+// ImportCommentsCSV sets the comments of a repertoire from a CSV file in the export format. Only
+// the path and comment columns are read, in any order; an empty comment clears the node's. Paths
+// may carry move numbers. Nothing is written when the file is invalid.
+func (s *RepertoireService) ImportCommentsCSV(repertoireID string, data []byte) (*models.CommentsImportResult, error) {
+	comments, order, err := parseCommentsCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *models.CommentsImportResult
+	saved, err := s.editTree(repertoireID, func(tree *models.RepertoireNode) (repository.NodeChanges, error) {
+		result = &models.CommentsImportResult{NotFound: []string{}}
+		var changes repository.NodeChanges
+		for _, path := range order {
+			node := findNodeByPath(tree, strings.Fields(path))
+			if node == nil {
+				result.NotFound = append(result.NotFound, path)
+				continue
+			}
+
+			comment := comments[path]
+			current := ""
+			if node.Comment != nil {
+				current = *node.Comment
+			}
+			if comment == current {
+				result.Unchanged++
+				continue
+			}
+
+			if comment == "" {
+				node.Comment = nil
+			} else {
+				node.Comment = &comment
+			}
+			node.EditedAt = editedNow()
+			changes.Updated = append(changes.Updated, node.ID)
+			result.Updated++
+		}
+		return changes, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Updated > 0 {
+		s.publish(saved, models.RepertoireEvent{Type: models.RepertoireEventCommentsImported})
+	}
+	return result, nil
+}
+
+// parseCommentsCSV reads the comment of every path of a comments file, returning the normalized
+// paths in file order. A path listed twice keeps its last comment.
+func parseCommentsCSV(data []byte) (map[string]string, []string, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, ErrInvalidCommentsFile
+	}
+	pathCol, commentCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "path":
+			pathCol = i
+		case "comment":
+			commentCol = i
+		}
+	}
+	if pathCol < 0 || commentCol < 0 {
+		return nil, nil, ErrInvalidCommentsFile
+	}
+
+	comments := make(map[string]string)
+	var order []string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCommentsFile, err)
+		}
+		if len(comments) >= config.MaxImportedRepertoireNodes {
+			return nil, nil, fmt.Errorf("%w: more than %d rows", ErrInvalidCommentsFile, config.MaxImportedRepertoireNodes)
+		}
+
+		path, comment := "", ""
+		if pathCol < len(record) {
+			path = normalizeMovePath(record[pathCol])
+		}
+		if commentCol < len(record) {
+			comment = strings.TrimSpace(unescapeCSVFormula(record[commentCol]))
+		}
+		if _, seen := comments[path]; !seen {
+			order = append(order, path)
+		}
+		comments[path] = comment
+	}
+	return comments, order, nil
+}
+
+// normalizeMovePath drops the move numbers and extra spaces of a path
+func normalizeMovePath(path string) string {
+	var moves []string
+	for _, field := range strings.Fields(unescapeCSVFormula(path)) {
+		if move := moveNumberPrefix.ReplaceAllString(field, ""); move != "" {
+			moves = append(moves, move)
+		}
+	}
+	return strings.Join(moves, " ")
+}
+
+// unescapeCSVFormula removes the quote writeCSV puts before cells a spreadsheet would read as a formula
+func unescapeCSVFormula(cell string) string {
+	if strings.HasPrefix(cell, "'") && isCSVFormula(cell[1:]) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// findNodeByPath follows SAN moves from the root, returning nil when one of them is not in the tree
+func findNodeByPath(root *models.RepertoireNode, moves []string) *models.RepertoireNode {
+	node := root
+	for _, move := range moves {
+		var next *models.RepertoireNode
+		for _, child := range node.Children {
+			if child.Move != nil && *child.Move == move {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
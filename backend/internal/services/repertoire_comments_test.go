@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestExportCommentsCSV(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3", "e4 c5")
+	main, formula := "Main line", "=+ for Black"
+	tree.Children[0].Children[0].Comment = &main
+	tree.Children[0].Children[1].Comment = &formula
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+	})
+
+	data, err := svc.ExportCommentsCSV("rep-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "path,comment\n,\ne4,\ne4 e5,Main line\ne4 e5 Nf3,\ne4 c5,'=+ for Black\n", string(data))
+}
+
+func TestImportCommentsCSV(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3", "e4 c5")
+	old, kept := "Old", "Kept"
+	tree.Children[0].Children[0].Comment = &old
+	tree.Children[0].Children[1].Comment = &kept
+	var changes repository.NodeChanges
+	var saved models.RepertoireNode
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+		SaveNodesFunc: func(id string, version int, tree models.RepertoireNode, c repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+			changes, saved = c, tree
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+	})
+
+	csv := "\xef\xbb\xbfComment,Path,Source\n" +
+		"\"Play for d5, then Nc6\",1. e4 e5 2. Nf3,elsewhere\n" +
+		"'=+ for Black,e4 c5\n" +
+		",1.e4 e5\n" +
+		"Kept,e4 c5\n" +
+		"Lost,e4 d5\n"
+
+	result, err := svc.ImportCommentsCSV("rep-1", []byte(csv))
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, 1, result.Unchanged, "the last row of e4 c5 wins")
+	assert.Equal(t, []string{"e4 d5"}, result.NotFound)
+	nf3 := saved.Children[0].Children[0].Children[0]
+	assert.Equal(t, "Play for d5, then Nc6", *nf3.Comment)
+	assert.Nil(t, saved.Children[0].Children[0].Comment, "an empty comment clears it")
+	assert.Equal(t, "Kept", *saved.Children[0].Children[1].Comment)
+	assert.ElementsMatch(t, []string{nf3.ID, saved.Children[0].Children[0].ID}, changes.Updated)
+}
+
+func TestImportCommentsCSV_RoundTrip(t *testing.T) {
+	tree := newLabelTestTree(t, "d4 d5 c4")
+	comment := "-+ if Black grabs c4"
+	tree.Children[0].Children[0].Comment = &comment
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: tree}, nil
+		},
+		SaveNodesFunc: func(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+			t.Fatal("an unchanged file writes nothing")
+			return nil, nil
+		},
+	})
+
+	data, err := svc.ExportCommentsCSV("rep-1")
+	require.NoError(t, err)
+	result, err := svc.ImportCommentsCSV("rep-1", data)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Updated)
+	assert.Equal(t, 4, result.Unchanged)
+	assert.Empty(t, result.NotFound)
+}
+
+func TestImportCommentsCSV_Invalid(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			t.Fatal("an invalid file is rejected before loading the repertoire")
+			return nil, nil
+		},
+	})
+
+	for name, data := range map[string]string{
+		"empty":          "",
+		"missing column": "path,note\ne4,Best by test\n",
+		"bad quoting":    "path,comment\ne4,\"unterminated\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.ImportCommentsCSV("rep-1", []byte(data))
+			assert.ErrorIs(t, err, ErrInvalidCommentsFile)
+		})
+	}
+}
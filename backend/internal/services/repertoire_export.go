@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+var (
+	ErrInvalidRepertoireFile     = fmt.Errorf("invalid repertoire file")
+	ErrUnsupportedRepertoireFile = fmt.Errorf("unsupported repertoire file version, versions 1 to %d are supported", models.RepertoireExportVersion)
+)
+
+// ExportJSON returns a repertoire with its study notes in the versioned JSON export format
+func (s *RepertoireService) ExportJSON(repertoireID string) (*models.RepertoireExport, error) {
+	rep, err := s.GetRepertoire(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.GetStudyNotes(repertoireID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get study notes: %w", err)
+	}
+
+	tree := exportNode(&rep.TreeData)
+	tree.FEN = rep.TreeData.FEN
+	tree.MoveNumber = rep.TreeData.MoveNumber
+	return &models.RepertoireExport{
+		Format:     models.RepertoireExportFormat,
+		Version:    models.RepertoireExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Name:       rep.Name,
+		Color:      rep.Color,
+		Metadata:   rep.Metadata,
+		Notes:      notes.Content,
+		Tree:       tree,
+	}, nil
+}
+
+func exportNode(node *models.RepertoireNode) models.ExportedNode {
+	exported := models.ExportedNode{
+		Tags:      node.Tags,
+		Collapsed: node.Collapsed,
+	}
+	if node.Move != nil {
+		exported.Move = *node.Move
+	}
+	if node.Comment != nil {
+		exported.Comment = *node.Comment
+	}
+	if node.BranchName != nil {
+		exported.BranchName = *node.BranchName
+	}
+	for _, child := range node.Children {
+		exported.Children = append(exported.Children, exportNode(child))
+	}
+	return exported
+}
+
+// ImportJSON creates a repertoire for a user from a JSON export. The whole file is validated
+// before anything is written: every move is replayed from the root position, so trees edited
+// by hand cannot store illegal moves, and every node gets a new ID.
+func (s *RepertoireService) ImportJSON(userID string, export *models.RepertoireExport) (*models.Repertoire, error) {
+	if export.Format != models.RepertoireExportFormat {
+		return nil, fmt.Errorf("%w: format must be %q", ErrInvalidRepertoireFile, models.RepertoireExportFormat)
+	}
+	if export.Version < 1 || export.Version > models.RepertoireExportVersion {
+		return nil, fmt.Errorf("%w: got %d", ErrUnsupportedRepertoireFile, export.Version)
+	}
+	if export.Color != models.ColorWhite && export.Color != models.ColorBlack {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidColor, export.Color)
+	}
+	name := strings.TrimSpace(export.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	if len(name) > config.MaxRepertoireNameLen {
+		return nil, ErrNameTooLong
+	}
+	if utf8.RuneCountInString(export.Notes) > config.MaxStudyNotesLen {
+		return nil, ErrStudyNotesTooLong
+	}
+
+	tree, err := importTree(export.Tree)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.Count(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check repertoire count: %w", err)
+	}
+	if count >= config.MaxRepertoires {
+		return nil, ErrLimitReached
+	}
+
+	rep, err := s.repo.Create(userID, name, export.Color)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create imported repertoire: %w", err)
+	}
+	saved, err := s.repo.Save(rep.ID, *tree, calculateMetadata(*tree))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save imported repertoire: %w", err)
+	}
+	if export.Notes != "" {
+		if _, err := s.repo.SaveNotes(rep.ID, userID, export.Notes); err != nil {
+			return nil, fmt.Errorf("failed to save imported study notes: %w", err)
+		}
+	}
+	return saved, nil
+}
+
+// importTree rebuilds a repertoire tree from an exported one, replaying its moves
+func importTree(root models.ExportedNode) (*models.RepertoireNode, error) {
+	if root.Move != "" {
+		return nil, fmt.Errorf("%w: the root node cannot have a move", ErrInvalidRepertoireFile)
+	}
+	if root.FEN == "" {
+		return nil, fmt.Errorf("%w: the root node has no fen", ErrInvalidRepertoireFile)
+	}
+	details, err := DescribeFEN(root.FEN)
+	if err != nil {
+		return nil, fmt.Errorf("%w: root position: %w", ErrInvalidRepertoireFile, err)
+	}
+
+	tree := &models.RepertoireNode{
+		ID:          uuid.New().String(),
+		FEN:         details.FEN,
+		MoveNumber:  root.MoveNumber,
+		ColorToMove: getColorToMoveFromFEN(details.FEN),
+		Children:    []*models.RepertoireNode{},
+	}
+	if err := applyExportedAnnotations(tree, root); err != nil {
+		return nil, err
+	}
+	nodes := 1
+	if err := importChildren(tree, root.Children, nil, &nodes); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func importChildren(parent *models.RepertoireNode, children []models.ExportedNode, line []string, nodes *int) error {
+	for _, exported := range children {
+		*nodes++
+		if *nodes > config.MaxImportedRepertoireNodes {
+			return fmt.Errorf("%w: more than %d nodes", ErrInvalidRepertoireFile, config.MaxImportedRepertoireNodes)
+		}
+
+		move := strings.TrimSpace(exported.Move)
+		childLine := append(line[:len(line):len(line)], move)
+		if move == "" {
+			return fmt.Errorf("%w: missing move after %s", ErrInvalidRepertoireFile, describeLine(line))
+		}
+		fen, err := validateAndGetResultingFEN(parent.FEN, move)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidRepertoireFile, describeLine(childLine), err)
+		}
+		if childWithFEN(parent, fen) != nil {
+			return fmt.Errorf("%w: %s appears twice", ErrInvalidRepertoireFile, describeLine(childLine))
+		}
+
+		moveNumber := parent.MoveNumber
+		if parent.ColorToMove == models.ChessColorWhite {
+			moveNumber++
+		}
+		parentID := parent.ID
+		child := &models.RepertoireNode{
+			ID:          uuid.New().String(),
+			FEN:         fen,
+			Move:        &move,
+			MoveNumber:  moveNumber,
+			ColorToMove: getColorToMoveFromFEN(fen),
+			ParentID:    &parentID,
+			Children:    []*models.RepertoireNode{},
+		}
+		if err := applyExportedAnnotations(child, exported); err != nil {
+			return err
+		}
+		parent.Children = append(parent.Children, child)
+
+		if err := importChildren(child, exported.Children, childLine, nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyExportedAnnotations copies the comment, branch name, tags and collapsed state of an
+// exported node, normalized as the editor stores them
+func applyExportedAnnotations(node *models.RepertoireNode, exported models.ExportedNode) error {
+	if comment := strings.TrimSpace(exported.Comment); comment != "" {
+		node.Comment = &comment
+	}
+	if branchName := strings.TrimSpace(exported.BranchName); branchName != "" {
+		node.BranchName = &branchName
+	}
+	tags, err := normalizeTags(exported.Tags, config.MaxNodeTags)
+	if err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		node.Tags = tags
+	}
+	node.Collapsed = exported.Collapsed
+	return nil
+}
+
+// describeLine names a node of an imported tree by the moves leading to it
+func describeLine(line []string) string {
+	if len(line) == 0 {
+		return "the root position"
+	}
+	return "line " + strings.Join(line, " ")
+}
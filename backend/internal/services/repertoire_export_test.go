@@ -0,0 +1,179 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+// newExportTestRepo serves a repertoire and records the one created by an import
+func newExportTestRepo(rep *models.Repertoire, saved *models.RepertoireNode, notes *string) *mocks.MockRepertoireRepo {
+	return &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return rep, nil
+		},
+		CreateFunc: func(userID, name string, color models.Color) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: "imported", Name: name, Color: color}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			*saved = treeData
+			return &models.Repertoire{ID: id, TreeData: treeData, Metadata: metadata}, nil
+		},
+		GetNotesFunc: func(repertoireID string, version int) (*models.StudyNotes, error) {
+			return &models.StudyNotes{RepertoireID: repertoireID, Version: 1, Content: "Play for d5"}, nil
+		},
+		SaveNotesFunc: func(repertoireID, userID, content string) (*models.StudyNotes, error) {
+			*notes = content
+			return &models.StudyNotes{RepertoireID: repertoireID, Version: 1, Content: content}, nil
+		},
+	}
+}
+
+func TestExportImportJSON_RoundTrip(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3 Nc6", "e4 c5 Nf3")
+	comment, branch := "Main line", "Open games"
+	e5 := tree.Children[0].Children[0]
+	e5.Comment = &comment
+	e5.BranchName = &branch
+	e5.Tags = []string{"critical"}
+	tree.Children[0].Children[1].Collapsed = true
+	rep := &models.Repertoire{ID: "rep-1", Name: "1.e4", Color: models.ColorWhite, TreeData: tree, Metadata: calculateMetadata(tree)}
+
+	var saved models.RepertoireNode
+	var notes string
+	svc := NewRepertoireService(newExportTestRepo(rep, &saved, &notes))
+
+	export, err := svc.ExportJSON("rep-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RepertoireExportFormat, export.Format)
+	assert.Equal(t, models.RepertoireExportVersion, export.Version)
+	assert.Equal(t, tree.FEN, export.Tree.FEN)
+	assert.Equal(t, "Play for d5", export.Notes)
+
+	// Through JSON, as the file travels between instances
+	data, err := json.Marshal(export)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"id"`)
+	var decoded models.RepertoireExport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	imported, err := svc.ImportJSON("user-2", &decoded)
+	require.NoError(t, err)
+	assert.Equal(t, "imported", imported.ID)
+	assert.Equal(t, rep.Metadata, imported.Metadata)
+	assert.Equal(t, "Play for d5", notes)
+
+	assert.NotEqual(t, tree.ID, saved.ID)
+	require.Len(t, saved.Children, 1)
+	importedE5 := saved.Children[0].Children[0]
+	assert.Equal(t, "e5", *importedE5.Move)
+	assert.Equal(t, e5.FEN, importedE5.FEN)
+	assert.Equal(t, 1, importedE5.MoveNumber)
+	assert.Equal(t, models.ChessColorWhite, importedE5.ColorToMove)
+	assert.Equal(t, saved.Children[0].ID, *importedE5.ParentID)
+	assert.Equal(t, &comment, importedE5.Comment)
+	assert.Equal(t, &branch, importedE5.BranchName)
+	assert.Equal(t, []string{"critical"}, importedE5.Tags)
+	assert.True(t, saved.Children[0].Children[1].Collapsed)
+	assert.NotEqual(t, e5.ID, importedE5.ID)
+}
+
+func TestImportJSON_Validation(t *testing.T) {
+	valid := func() *models.RepertoireExport {
+		return &models.RepertoireExport{
+			Format:  models.RepertoireExportFormat,
+			Version: models.RepertoireExportVersion,
+			Name:    "Sicilian",
+			Color:   models.ColorBlack,
+			Tree: models.ExportedNode{
+				FEN:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+				Children: []models.ExportedNode{{Move: "e4", Children: []models.ExportedNode{{Move: "c5"}}}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *models.RepertoireExport)
+		err    error
+	}{
+		{"wrong format", func(e *models.RepertoireExport) { e.Format = "pgn" }, ErrInvalidRepertoireFile},
+		{"future version", func(e *models.RepertoireExport) { e.Version = models.RepertoireExportVersion + 1 }, ErrUnsupportedRepertoireFile},
+		{"missing version", func(e *models.RepertoireExport) { e.Version = 0 }, ErrUnsupportedRepertoireFile},
+		{"bad color", func(e *models.RepertoireExport) { e.Color = "green" }, ErrInvalidColor},
+		{"blank name", func(e *models.RepertoireExport) { e.Name = "  " }, ErrNameRequired},
+		{"missing root position", func(e *models.RepertoireExport) { e.Tree.FEN = "" }, ErrInvalidRepertoireFile},
+		{"bad root position", func(e *models.RepertoireExport) { e.Tree.FEN = "not a fen" }, ErrInvalidRepertoireFile},
+		{"illegal move", func(e *models.RepertoireExport) { e.Tree.Children[0].Children[0].Move = "c4" }, ErrInvalidRepertoireFile},
+		{"missing move", func(e *models.RepertoireExport) { e.Tree.Children[0].Children[0].Move = "" }, ErrInvalidRepertoireFile},
+		{"duplicate move", func(e *models.RepertoireExport) {
+			e.Tree.Children = append(e.Tree.Children, models.ExportedNode{Move: "e4"})
+		}, ErrInvalidRepertoireFile},
+		{"too many tags", func(e *models.RepertoireExport) {
+			e.Tree.Children[0].Tags = make([]string, config.MaxNodeTags+1)
+			for i := range e.Tree.Children[0].Tags {
+				e.Tree.Children[0].Tags[i] = string(rune('a' + i))
+			}
+		}, ErrTooManyTags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			repo := &mocks.MockRepertoireRepo{
+				CreateFunc: func(userID, name string, color models.Color) (*models.Repertoire, error) {
+					created = true
+					return &models.Repertoire{ID: "imported"}, nil
+				},
+			}
+			export := valid()
+			tt.modify(export)
+
+			_, err := NewRepertoireService(repo).ImportJSON("user-1", export)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.False(t, created, "nothing is written for an invalid file")
+		})
+	}
+}
+
+func TestImportJSON_ErrorNamesLine(t *testing.T) {
+	export := &models.RepertoireExport{
+		Format:  models.RepertoireExportFormat,
+		Version: models.RepertoireExportVersion,
+		Name:    "Italian",
+		Color:   models.ColorWhite,
+		Tree: models.ExportedNode{
+			FEN:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+			Children: []models.ExportedNode{{Move: "e4", Children: []models.ExportedNode{{Move: "e5", Children: []models.ExportedNode{{Move: "Bb7"}}}}}},
+		},
+	}
+
+	_, err := NewRepertoireService(&mocks.MockRepertoireRepo{}).ImportJSON("user-1", export)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line e4 e5 Bb7")
+}
+
+func TestImportJSON_LimitReached(t *testing.T) {
+	repo := &mocks.MockRepertoireRepo{
+		CountFunc: func(userID string) (int, error) { return config.MaxRepertoires, nil },
+	}
+	export := &models.RepertoireExport{
+		Format:  models.RepertoireExportFormat,
+		Version: models.RepertoireExportVersion,
+		Name:    "Full",
+		Color:   models.ColorWhite,
+		Tree:    models.ExportedNode{FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"},
+	}
+
+	_, err := NewRepertoireService(repo).ImportJSON("user-1", export)
+
+	assert.ErrorIs(t, err, ErrLimitReached)
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+const healthCheckInterval = time.Hour
+
+// Weights of the health components; components without data are left out and the others rescaled
+const (
+	healthCoverageWeight  = 0.3
+	healthResultsWeight   = 0.2
+	healthRecallWeight    = 0.3
+	healthFreshnessWeight = 0.2
+)
+
+// HealthService scores how well each repertoire is maintained so the dashboard can rank what needs attention
+type HealthService struct {
+	healthRepo     repository.HealthRepository
+	repertoireRepo repository.RepertoireRepository
+	trainingRepo   repository.TrainingRepository
+	gameResultRepo repository.GameResultRepository
+	coverage       CoverageCalculator
+}
+
+// NewHealthService creates a new repertoire health service
+func NewHealthService(healthRepo repository.HealthRepository, repertoireRepo repository.RepertoireRepository, trainingRepo repository.TrainingRepository, gameResultRepo repository.GameResultRepository, coverage CoverageCalculator) *HealthService {
+	return &HealthService{
+		healthRepo:     healthRepo,
+		repertoireRepo: repertoireRepo,
+		trainingRepo:   trainingRepo,
+		gameResultRepo: gameResultRepo,
+		coverage:       coverage,
+	}
+}
+
+// RunWorker periodically recomputes the health of repertoires whose score is a day old
+func (s *HealthService) RunWorker(ctx context.Context) {
+	log.Println("health: worker started")
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("health: worker stopped")
+			return
+		case <-ticker.C:
+			s.refreshStale(time.Now())
+		}
+	}
+}
+
+func (s *HealthService) refreshStale(now time.Time) {
+	owners, err := s.healthRepo.ListStale(now.Add(-config.HealthRefreshAge), config.HealthBatchSize)
+	if err != nil {
+		log.Printf("health: failed to list repertoires: %v", err)
+		return
+	}
+
+	for _, owner := range owners {
+		health, err := s.compute(owner, now)
+		if err != nil {
+			log.Printf("health: failed to compute health of %s: %v", owner.RepertoireID, err)
+			continue
+		}
+		if err := s.healthRepo.Save(owner.RepertoireID, *health); err != nil {
+			log.Printf("health: failed to save health of %s: %v", owner.RepertoireID, err)
+		}
+	}
+}
+
+// compute measures a repertoire against the owner's games and training
+func (s *HealthService) compute(owner repository.RepertoireOwner, now time.Time) (*models.RepertoireHealth, error) {
+	rep, err := s.repertoireRepo.GetByID(owner.RepertoireID)
+	if err != nil {
+		return nil, err
+	}
+	health := &models.RepertoireHealth{ComputedAt: now}
+
+	if s.coverage != nil {
+		coverage, err := s.coverage.ExplorerCoverage(rep.TreeData, rep.Color, config.DefaultGoalDepth)
+		if err != nil {
+			return nil, err
+		}
+		health.Coverage = &coverage
+	}
+
+	if s.gameResultRepo != nil {
+		nodes, err := s.gameResultRepo.Overlay(owner.RepertoireID, owner.UserID)
+		if err != nil {
+			return nil, err
+		}
+		// Every game reaching the repertoire passes through the root
+		for _, node := range nodes {
+			if node.NodeID == rep.TreeData.ID && node.Games > 0 {
+				results := (float64(node.Wins) + float64(node.Draws)/2) / float64(node.Games) * 100
+				health.Results = &results
+			}
+		}
+	}
+
+	lastActivity := rep.UpdatedAt
+	recall, err := s.trainingRepo.GetRecall(owner.UserID, owner.RepertoireID, now.Add(-config.HealthRecallWindow))
+	if err != nil {
+		return nil, err
+	}
+	if recall.Reviews > 0 {
+		value := float64(recall.Correct) / float64(recall.Reviews) * 100
+		health.Recall = &value
+	}
+	if recall.LastAnswerAt != nil && recall.LastAnswerAt.After(lastActivity) {
+		lastActivity = *recall.LastAnswerAt
+	}
+	health.Freshness = healthFreshness(now.Sub(lastActivity))
+
+	health.Score = healthScore(health)
+	return health, nil
+}
+
+// healthFreshness falls linearly from 100 to 0 over config.HealthStaleAfter
+func healthFreshness(age time.Duration) float64 {
+	freshness := 100 * (1 - float64(age)/float64(config.HealthStaleAfter))
+	return math.Max(0, math.Min(100, freshness))
+}
+
+// healthScore is the weighted average of the components that have data
+func healthScore(health *models.RepertoireHealth) int {
+	total := health.Freshness * healthFreshnessWeight
+	weights := healthFreshnessWeight
+	for _, c := range []struct {
+		value  *float64
+		weight float64
+	}{
+		{health.Coverage, healthCoverageWeight},
+		{health.Results, healthResultsWeight},
+		{health.Recall, healthRecallWeight},
+	} {
+		if c.value != nil {
+			total += *c.value * c.weight
+			weights += c.weight
+		}
+	}
+	return int(math.Round(total / weights))
+}
+
+// WithHealth attaches the latest health scores to listed repertoires
+func (s *RepertoireService) WithHealth(repo repository.HealthRepository) {
+	s.healthRepo = repo
+}
+
+// attachHealth sets the health of the listed repertoires. The score is a derived view, so a
+// failure to load it is logged and the list returned without it.
+func (s *RepertoireService) attachHealth(userID string, repertoires []models.Repertoire) {
+	if s.healthRepo == nil || len(repertoires) == 0 {
+		return
+	}
+	health, err := s.healthRepo.GetByUser(userID)
+	if err != nil {
+		log.Printf("warning: failed to load repertoire health: %v", err)
+		return
+	}
+	for i := range repertoires {
+		if h, ok := health[repertoires[i].ID]; ok {
+			repertoires[i].Health = &h
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestHealthRefreshStale_ComputesAndSaves(t *testing.T) {
+	now := time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC)
+	lastAnswer := now.Add(-9 * 24 * time.Hour)
+	saved := map[string]models.RepertoireHealth{}
+	var staleBefore time.Time
+	healthRepo := &mocks.MockHealthRepo{
+		ListStaleFunc: func(before time.Time, limit int) ([]repository.RepertoireOwner, error) {
+			staleBefore = before
+			return []repository.RepertoireOwner{{RepertoireID: "rep-1", UserID: "user-1"}}, nil
+		},
+		SaveFunc: func(repertoireID string, health models.RepertoireHealth) error {
+			saved[repertoireID] = health
+			return nil
+		},
+	}
+	repertoireRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, TreeData: models.RepertoireNode{ID: "root"}, UpdatedAt: now.Add(-45 * 24 * time.Hour)}, nil
+		},
+	}
+	trainingRepo := &mocks.MockTrainingRepo{
+		GetRecallFunc: func(userID, repertoireID string, since time.Time) (*models.TrainingRecall, error) {
+			assert.Equal(t, now.Add(-config.HealthRecallWindow), since)
+			return &models.TrainingRecall{Reviews: 10, Correct: 7, LastAnswerAt: &lastAnswer}, nil
+		},
+	}
+	gameResultRepo := &mocks.MockGameResultRepo{
+		OverlayFunc: func(repertoireID, userID string) ([]models.NodeResult, error) {
+			return []models.NodeResult{
+				{NodeID: "root", Games: 4, Wins: 2, Draws: 1, Losses: 1},
+				{NodeID: "n1", Games: 2, Wins: 2},
+			}, nil
+		},
+	}
+	svc := NewHealthService(healthRepo, repertoireRepo, trainingRepo, gameResultRepo, &fakeCoverage{value: 80})
+
+	svc.refreshStale(now)
+
+	assert.Equal(t, now.Add(-config.HealthRefreshAge), staleBefore)
+	require.Contains(t, saved, "rep-1")
+	health := saved["rep-1"]
+	require.NotNil(t, health.Coverage)
+	require.NotNil(t, health.Results)
+	require.NotNil(t, health.Recall)
+	assert.InDelta(t, 80, *health.Coverage, 0.001)
+	assert.InDelta(t, 62.5, *health.Results, 0.001)
+	assert.InDelta(t, 70, *health.Recall, 0.001)
+	// The last training is more recent than the last edit
+	assert.InDelta(t, 90, health.Freshness, 0.001)
+	// 0.3*80 + 0.2*62.5 + 0.3*70 + 0.2*90 = 75.5
+	assert.Equal(t, 76, health.Score)
+	assert.Equal(t, now, health.ComputedAt)
+}
+
+func TestHealthScore_SkipsComponentsWithoutData(t *testing.T) {
+	coverage := 50.0
+	health := &models.RepertoireHealth{Coverage: &coverage, Freshness: 100}
+
+	// (0.3*50 + 0.2*100) / 0.5
+	assert.Equal(t, 70, healthScore(health))
+}
+
+func TestHealthFreshness(t *testing.T) {
+	assert.Equal(t, 100.0, healthFreshness(0))
+	assert.InDelta(t, 50, healthFreshness(config.HealthStaleAfter/2), 0.001)
+	assert.Equal(t, 0.0, healthFreshness(2*config.HealthStaleAfter))
+}
+
+func TestRepertoireService_ListRepertoires_AttachesHealth(t *testing.T) {
+	mockRepo := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) {
+			return []models.Repertoire{{ID: "rep-1"}, {ID: "rep-2"}}, nil
+		},
+	}
+	svc := NewRepertoireService(mockRepo)
+	svc.WithHealth(&mocks.MockHealthRepo{
+		GetByUserFunc: func(userID string) (map[string]models.RepertoireHealth, error) {
+			return map[string]models.RepertoireHealth{"rep-2": {Score: 42}}, nil
+		},
+	})
+
+	reps, err := svc.ListRepertoires("user-1", nil, false)
+
+	require.NoError(t, err)
+	require.Len(t, reps, 2)
+	assert.Nil(t, reps[0].Health)
+	require.NotNil(t, reps[1].Health)
+	assert.Equal(t, 42, reps[1].Health.Score)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ListLines enumerates the lines of a repertoire, from the root to every leaf.
+// With tags, only lines going through a node carrying one of them are returned.
+func (s *RepertoireService) ListLines(repertoireID string, tags []string) ([]models.RepertoireLine, error) {
+	rep, err := s.getRepertoireTree(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := tagSet(tags)
+
+	lines := []models.RepertoireLine{}
+	var walk func(node *models.RepertoireNode, moves, lineTags []string, opening *models.OpeningLabel)
+	walk = func(node *models.RepertoireNode, moves, lineTags []string, opening *models.OpeningLabel) {
+		if node.Move != nil {
+			moves = append(moves, *node.Move)
+		}
+		lineTags = mergeTags(lineTags, node.Tags)
+		if node.Opening != nil {
+			opening = node.Opening
+		}
+
+		if len(node.Children) == 0 {
+			if node.Move != nil && (len(wanted) == 0 || hasAnyTag(lineTags, wanted)) {
+				lines = append(lines, models.RepertoireLine{
+					LeafID:  node.ID,
+					Moves:   append([]string{}, moves...),
+					Tags:    lineTags,
+					Opening: opening,
+				})
+			}
+			return
+		}
+		for _, child := range node.Children {
+			walk(child, moves, lineTags, opening)
+		}
+	}
+	walk(&rep.TreeData, nil, []string{}, nil)
+	return lines, nil
+}
+
+// TrainingPositions selects the positions where the user plays a repertoire move, in tree order.
+// With tags, only positions tagged themselves or whose repertoire move is tagged are drilled.
+func (s *RepertoireService) TrainingPositions(repertoireID string, tags []string, limit int) ([]models.TrainingPosition, error) {
+	rep, err := s.getRepertoireTree(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := tagSet(tags)
+	userToMove := models.ChessColorWhite
+	if rep.Color == models.ColorBlack {
+		userToMove = models.ChessColorBlack
+	}
+
+	positions := []models.TrainingPosition{}
+	var walk func(node *models.RepertoireNode, path []string)
+	walk = func(node *models.RepertoireNode, path []string) {
+		if len(positions) >= limit {
+			return
+		}
+		if node.Move != nil {
+			path = append(path, *node.Move)
+		}
+
+		if node.ColorToMove == userToMove && len(node.Children) > 0 {
+			var expected []string
+			positionTags := node.Tags
+			for _, child := range node.Children {
+				if child.Move != nil {
+					expected = append(expected, *child.Move)
+				}
+				positionTags = mergeTags(positionTags, child.Tags)
+			}
+			if len(expected) > 0 && (len(wanted) == 0 || hasAnyTag(positionTags, wanted)) {
+				positions = append(positions, models.TrainingPosition{
+					NodeID:        node.ID,
+					FEN:           node.FEN,
+					Path:          append([]string{}, path...),
+					ExpectedMoves: expected,
+					Tags:          mergeTags(nil, positionTags),
+				})
+			}
+		}
+
+		for _, child := range node.Children {
+			walk(child, path)
+		}
+	}
+	walk(&rep.TreeData, nil)
+	return positions, nil
+}
+
+func (s *RepertoireService) getRepertoireTree(repertoireID string) (*models.Repertoire, error) {
+	rep, err := s.repo.GetByID(repertoireID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRepertoireNotFound) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return rep, nil
+}
+
+// ParseTagsParam splits a comma-separated tags query parameter
+func ParseTagsParam(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func tagSet(tags []string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[strings.ToLower(strings.TrimSpace(tag))] = true
+	}
+	return set
+}
+
+func hasAnyTag(tags []string, wanted map[string]bool) bool {
+	for _, tag := range tags {
+		if wanted[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTags returns the tags of a followed by those of b it lacks, without modifying a
+func mergeTags(a, b []string) []string {
+	merged := append([]string{}, a...)
+	for _, tag := range b {
+		found := false
+		for _, existing := range merged {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
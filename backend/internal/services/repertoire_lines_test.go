@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+// newTaggedRepertoire builds 1.e4 e5 2.Nf3 and 1.e4 c5 2.Nf3 d6 3.d4 (tagged critical)
+func newTaggedRepertoire() *models.Repertoire {
+	move := func(san string) *string { return &san }
+	return &models.Repertoire{
+		ID: "rep-1", Name: "e4", Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID: "root", FEN: "start", ColorToMove: models.ChessColorWhite,
+			Children: []*models.RepertoireNode{{
+				ID: "e4", Move: move("e4"), FEN: "after-e4", ColorToMove: models.ChessColorBlack,
+				Children: []*models.RepertoireNode{
+					{
+						ID: "e5", Move: move("e5"), FEN: "after-e5", ColorToMove: models.ChessColorWhite,
+						Children: []*models.RepertoireNode{
+							{ID: "nf3-a", Move: move("Nf3"), FEN: "after-nf3-a", ColorToMove: models.ChessColorBlack},
+						},
+					},
+					{
+						ID: "c5", Move: move("c5"), FEN: "after-c5", ColorToMove: models.ChessColorWhite,
+						Children: []*models.RepertoireNode{{
+							ID: "nf3-b", Move: move("Nf3"), FEN: "after-nf3-b", ColorToMove: models.ChessColorBlack,
+							Children: []*models.RepertoireNode{{
+								ID: "d6", Move: move("d6"), FEN: "after-d6", ColorToMove: models.ChessColorWhite,
+								Children: []*models.RepertoireNode{
+									{ID: "d4", Move: move("d4"), FEN: "after-d4", ColorToMove: models.ChessColorBlack, Tags: []string{"critical"}},
+								},
+							}},
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func newLinesService() *RepertoireService {
+	rep := newTaggedRepertoire()
+	return NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+	})
+}
+
+func TestListLines(t *testing.T) {
+	lines, err := newLinesService().ListLines("rep-1", nil)
+
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"e4", "e5", "Nf3"}, lines[0].Moves)
+	assert.Equal(t, []string{"e4", "c5", "Nf3", "d6", "d4"}, lines[1].Moves)
+	assert.Equal(t, []string{"critical"}, lines[1].Tags)
+}
+
+func TestListLines_FilteredByTag(t *testing.T) {
+	lines, err := newLinesService().ListLines("rep-1", []string{"Critical"})
+
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "d4", lines[0].LeafID)
+}
+
+func TestTrainingPositions(t *testing.T) {
+	positions, err := newLinesService().TrainingPositions("rep-1", nil, 10)
+
+	require.NoError(t, err)
+	var ids []string
+	for _, p := range positions {
+		ids = append(ids, p.NodeID)
+	}
+	assert.Equal(t, []string{"root", "e5", "c5", "d6"}, ids)
+	assert.Equal(t, []string{"e4"}, positions[0].ExpectedMoves)
+}
+
+func TestTrainingPositions_OnlyTagged(t *testing.T) {
+	positions, err := newLinesService().TrainingPositions("rep-1", []string{"critical"}, 10)
+
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "d6", positions[0].NodeID)
+	assert.Equal(t, []string{"e4", "c5", "Nf3", "d6"}, positions[0].Path)
+	assert.Equal(t, []string{"d4"}, positions[0].ExpectedMoves)
+}
+
+func TestTrainingPositions_Limit(t *testing.T) {
+	positions, err := newLinesService().TrainingPositions("rep-1", nil, 2)
+
+	require.NoError(t, err)
+	assert.Len(t, positions, 2)
+}
+
+func TestParseTagsParam(t *testing.T) {
+	assert.Equal(t, []string{"critical", "gambit"}, ParseTagsParam(" Critical,,gambit "))
+	assert.Nil(t, ParseTagsParam(""))
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// Metrics computes per-depth, branching and annotation statistics for a repertoire
+func (s *RepertoireService) Metrics(repertoireID string) (*models.RepertoireMetrics, error) {
+	rep, err := s.getRepertoireTree(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	userToMove := models.ChessColorWhite
+	if rep.Color == models.ColorBlack {
+		userToMove = models.ChessColorBlack
+	}
+
+	metrics := &models.RepertoireMetrics{
+		RepertoireID:  rep.ID,
+		NodesPerDepth: []int{},
+		Branches:      []models.BranchMetrics{},
+	}
+	var myPositions, myReplies, opponentPositions, opponentReplies, moves, annotated int
+
+	var walk func(node *models.RepertoireNode, depth int, branch *models.BranchMetrics)
+	walk = func(node *models.RepertoireNode, depth int, branch *models.BranchMetrics) {
+		metrics.TotalNodes++
+		if depth >= len(metrics.NodesPerDepth) {
+			metrics.NodesPerDepth = append(metrics.NodesPerDepth, 0)
+		}
+		metrics.NodesPerDepth[depth]++
+		if depth > metrics.MaxDepth {
+			metrics.MaxDepth = depth
+		}
+
+		if node.Move != nil {
+			moves++
+			if node.Comment != nil && strings.TrimSpace(*node.Comment) != "" {
+				annotated++
+			}
+		}
+		if branch != nil {
+			branch.Nodes++
+			branch.LastEditedAt = latest(branch.LastEditedAt, node.EditedAt)
+		}
+
+		if len(node.Children) == 0 {
+			if node.Move != nil {
+				metrics.LeafCount++
+			}
+			return
+		}
+		if node.ColorToMove == userToMove {
+			myPositions++
+			myReplies += len(node.Children)
+		} else {
+			opponentPositions++
+			opponentReplies += len(node.Children)
+		}
+
+		for _, child := range node.Children {
+			if branch != nil {
+				walk(child, depth+1, branch)
+				continue
+			}
+			metrics.Branches = append(metrics.Branches, models.BranchMetrics{
+				NodeID:     child.ID,
+				Move:       derefMove(child.Move),
+				BranchName: child.BranchName,
+			})
+			walk(child, depth+1, &metrics.Branches[len(metrics.Branches)-1])
+		}
+	}
+	walk(&rep.TreeData, 0, nil)
+
+	metrics.MyBranching = ratio(myReplies, myPositions)
+	metrics.OpponentBranching = ratio(opponentReplies, opponentPositions)
+	metrics.AnnotatedPercent = ratio(annotated*100, moves)
+	return metrics, nil
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if b == nil || (a != nil && !b.After(*a)) {
+		return a
+	}
+	return b
+}
+
+func derefMove(move *string) string {
+	if move == nil {
+		return ""
+	}
+	return *move
+}
+
+func ratio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestMetrics(t *testing.T) {
+	rep := newTaggedRepertoire()
+	comment := "main line"
+	e4 := rep.TreeData.Children[0]
+	e4.Comment = &comment
+	earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	e4.EditedAt = &earlier
+	e4.Children[1].Children[0].EditedAt = &later
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+	})
+
+	metrics, err := svc.Metrics("rep-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 8, metrics.TotalNodes)
+	assert.Equal(t, 2, metrics.LeafCount)
+	assert.Equal(t, 5, metrics.MaxDepth)
+	assert.Equal(t, []int{1, 1, 2, 2, 1, 1}, metrics.NodesPerDepth)
+	// White prepares one move everywhere; after 1.e4 Black has two replies
+	assert.Equal(t, 1.0, metrics.MyBranching)
+	assert.InDelta(t, 1.5, metrics.OpponentBranching, 1e-9)
+	assert.InDelta(t, 100.0/7.0, metrics.AnnotatedPercent, 1e-9)
+	require.Len(t, metrics.Branches, 1)
+	assert.Equal(t, "e4", metrics.Branches[0].Move)
+	assert.Equal(t, 7, metrics.Branches[0].Nodes)
+	assert.Equal(t, &later, metrics.Branches[0].LastEditedAt)
+}
+
+func TestMetrics_EmptyRepertoire(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, TreeData: models.RepertoireNode{ID: "root"}}, nil
+		},
+	})
+
+	metrics, err := svc.Metrics("rep-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, metrics.TotalNodes)
+	assert.Zero(t, metrics.LeafCount)
+	assert.Zero(t, metrics.AnnotatedPercent)
+	assert.Empty(t, metrics.Branches)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// MirrorRepertoire creates a copy of a repertoire seen from the other side of the board:
+// every position is flipped rank-wise with the colors swapped, so a White London becomes
+// a Black reversed London. The mirrored tree starts from the initial position with Black
+// to move, since the side that moved first in the original now moves second.
+func (s *RepertoireService) MirrorRepertoire(userID, repertoireID, name string) (*models.Repertoire, error) {
+	rep, err := s.repo.GetByID(repertoireID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRepertoireNotFound) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = rep.Name + " (mirrored)"
+	}
+	if len(name) > config.MaxRepertoireNameLen {
+		return nil, ErrNameTooLong
+	}
+
+	count, err := s.repo.Count(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check repertoire count: %w", err)
+	}
+	if count >= config.MaxRepertoires {
+		return nil, ErrLimitReached
+	}
+
+	newTree, err := mirrorTree(&rep.TreeData)
+	if err != nil {
+		return nil, err
+	}
+
+	color := models.ColorBlack
+	if rep.Color == models.ColorBlack {
+		color = models.ColorWhite
+	}
+
+	newRep, err := s.repo.Create(userID, name, color)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mirrored repertoire: %w", err)
+	}
+
+	saved, err := s.repo.Save(newRep.ID, *newTree, calculateMetadata(*newTree))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save mirrored repertoire: %w", err)
+	}
+	return saved, nil
+}
+
+// mirrorTree rebuilds a tree with mirrored positions and moves and fresh node IDs.
+// Every mirrored move is replayed from its mirrored parent, so the stored FENs come
+// from the chess library rather than from string manipulation alone.
+func mirrorTree(root *models.RepertoireNode) (*models.RepertoireNode, error) {
+	rootFEN, err := MirrorFEN(root.FEN)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	mirrored := mirrorNode(root, rootFEN, nil, ids)
+	if err := mirrorChildren(root, mirrored, ids); err != nil {
+		return nil, err
+	}
+	remapTranspositions(mirrored, ids)
+	return mirrored, nil
+}
+
+func mirrorChildren(src, dst *models.RepertoireNode, ids map[string]string) error {
+	for _, child := range src.Children {
+		if child.Move == nil {
+			continue
+		}
+		move := MirrorSAN(*child.Move)
+		fen, err := validateAndGetResultingFEN(dst.FEN, move)
+		if err != nil {
+			return fmt.Errorf("failed to mirror move %s: %w", *child.Move, err)
+		}
+
+		parentID := dst.ID
+		mirroredChild := mirrorNode(child, fen, &parentID, ids)
+		mirroredChild.Move = &move
+		dst.Children = append(dst.Children, mirroredChild)
+
+		if err := mirrorChildren(child, mirroredChild, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mirrorNode(node *models.RepertoireNode, fen string, parentID *string, ids map[string]string) *models.RepertoireNode {
+	newID := uuid.New().String()
+	ids[node.ID] = newID
+	return &models.RepertoireNode{
+		ID:              newID,
+		FEN:             fen,
+		MoveNumber:      node.MoveNumber,
+		ColorToMove:     getColorToMoveFromFEN(fen),
+		ParentID:        parentID,
+		Comment:         node.Comment,
+		BranchName:      node.BranchName,
+		Collapsed:       node.Collapsed,
+		TranspositionOf: node.TranspositionOf,
+		Tags:            node.Tags,
+		EditedAt:        node.EditedAt,
+		Children:        []*models.RepertoireNode{},
+	}
+}
+
+// remapTranspositions points transposition links at the mirrored nodes, dropping links
+// whose target was not copied
+func remapTranspositions(node *models.RepertoireNode, ids map[string]string) {
+	if node.TranspositionOf != nil {
+		if newID, ok := ids[*node.TranspositionOf]; ok {
+			node.TranspositionOf = &newID
+		} else {
+			node.TranspositionOf = nil
+		}
+	}
+	for _, child := range node.Children {
+		remapTranspositions(child, ids)
+	}
+}
+
+// MirrorFEN flips a position top to bottom and swaps the colors of every piece, the side
+// to move, the castling rights and the en passant square. The result keeps the number of
+// fields of the input, so normalized FENs stay normalized.
+func MirrorFEN(fen string) (string, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return "", fmt.Errorf("%w: %s", ErrInvalidFEN, fen)
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return "", fmt.Errorf("%w: %s", ErrInvalidFEN, fen)
+	}
+	mirroredRanks := make([]string, 8)
+	for i, rank := range ranks {
+		mirroredRanks[7-i] = swapCase(rank)
+	}
+	fields[0] = strings.Join(mirroredRanks, "/")
+
+	switch fields[1] {
+	case "w":
+		fields[1] = "b"
+	case "b":
+		fields[1] = "w"
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidFEN, fen)
+	}
+
+	if fields[2] != "-" {
+		swapped := swapCase(fields[2])
+		castling := ""
+		for _, right := range "KQkq" {
+			if strings.ContainsRune(swapped, right) {
+				castling += string(right)
+			}
+		}
+		fields[2] = castling
+	}
+
+	if fields[3] != "-" {
+		fields[3] = mirrorSquares(fields[3])
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// MirrorSAN maps a move in standard algebraic notation onto the mirrored board.
+// Files and piece letters are unchanged; only rank digits flip.
+func MirrorSAN(san string) string {
+	return mirrorSquares(san)
+}
+
+func mirrorSquares(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '1' && r <= '8' {
+			return '1' + '8' - r
+		}
+		return r
+	}, s)
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
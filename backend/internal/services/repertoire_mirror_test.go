@@ -0,0 +1,144 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestMirrorFEN(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want string
+	}{
+		{
+			name: "start position",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+			want: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq -",
+		},
+		{
+			name: "en passant square and full FEN",
+			fen:  "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1",
+			want: "rnbqkbnr/ppp1pppp/8/3p4/8/8/PPPPPPPP/RNBQKBNR w KQkq d6 0 1",
+		},
+		{
+			name: "partial castling rights",
+			fen:  "r3k2r/8/8/8/8/8/8/4K2R w Kq -",
+			want: "4k2r/8/8/8/8/8/8/R3K2R b Qk -",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MirrorFEN(tt.fen)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMirrorFEN_Invalid(t *testing.T) {
+	_, err := MirrorFEN("not a fen")
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+}
+
+func TestMirrorSAN(t *testing.T) {
+	assert.Equal(t, "d5", MirrorSAN("d4"))
+	assert.Equal(t, "Nf6", MirrorSAN("Nf3"))
+	assert.Equal(t, "R1a3", MirrorSAN("R8a6"))
+	assert.Equal(t, "exd1=Q+", MirrorSAN("exd8=Q+"))
+	assert.Equal(t, "O-O-O", MirrorSAN("O-O-O"))
+}
+
+func mirrorSourceRepertoire() *models.Repertoire {
+	d4, d5, bf4 := "d4", "d5", "Bf4"
+	return &models.Repertoire{
+		ID:    "rep-1",
+		Name:  "London",
+		Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID:          "root",
+			FEN:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+			ColorToMove: models.ChessColorWhite,
+			Children: []*models.RepertoireNode{{
+				ID:          "n-d4",
+				FEN:         "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq -",
+				Move:        &d4,
+				MoveNumber:  1,
+				ColorToMove: models.ChessColorBlack,
+				Children: []*models.RepertoireNode{{
+					ID:          "n-d5",
+					FEN:         "rnbqkbnr/ppp1pppp/8/3p4/3P4/8/PPP1PPPP/RNBQKBNR w KQkq -",
+					Move:        &d5,
+					MoveNumber:  1,
+					ColorToMove: models.ChessColorWhite,
+					Children: []*models.RepertoireNode{{
+						ID:          "n-bf4",
+						FEN:         "rn1qkbnr/ppp1pppp/8/3p4/3P1B2/8/PPP1PPPP/RN1QKBNR b KQkq -",
+						Move:        &bf4,
+						MoveNumber:  2,
+						ColorToMove: models.ChessColorBlack,
+						Children:    []*models.RepertoireNode{},
+					}},
+				}},
+			}},
+		},
+	}
+}
+
+func TestRepertoireService_MirrorRepertoire(t *testing.T) {
+	var createdColor models.Color
+	var createdName string
+	mockRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return mirrorSourceRepertoire(), nil },
+		CountFunc:   func(userID string) (int, error) { return 1, nil },
+		CreateFunc: func(userID, name string, color models.Color) (*models.Repertoire, error) {
+			createdName, createdColor = name, color
+			return &models.Repertoire{ID: "new-rep", Name: name, Color: color}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: treeData, Metadata: metadata}, nil
+		},
+	}
+	svc := NewRepertoireService(mockRepo)
+
+	result, err := svc.MirrorRepertoire("user-1", "rep-1", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ColorBlack, createdColor)
+	assert.Equal(t, "London (mirrored)", createdName)
+
+	root := result.TreeData
+	assert.NotEqual(t, "root", root.ID)
+	assert.Equal(t, models.ChessColorBlack, root.ColorToMove)
+	require.Len(t, root.Children, 1)
+
+	first := root.Children[0]
+	assert.Equal(t, "d5", *first.Move)
+	assert.Equal(t, root.ID, *first.ParentID)
+	assert.Equal(t, models.ChessColorWhite, first.ColorToMove)
+	require.Len(t, first.Children, 1)
+
+	bf5 := first.Children[0].Children[0]
+	assert.Equal(t, "Bf5", *bf5.Move)
+	assert.Equal(t, 2, bf5.MoveNumber)
+	assert.Equal(t, "rn1qkbnr/ppp1pppp/8/3p1b2/3P4/8/PPP1PPPP/RNBQKBNR w KQkq -", bf5.FEN)
+	assert.Equal(t, 3, result.Metadata.TotalMoves)
+}
+
+func TestRepertoireService_MirrorRepertoire_LimitReached(t *testing.T) {
+	mockRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return mirrorSourceRepertoire(), nil },
+		CountFunc:   func(userID string) (int, error) { return config.MaxRepertoires, nil },
+	}
+	svc := NewRepertoireService(mockRepo)
+
+	_, err := svc.MirrorRepertoire("user-1", "rep-1", "")
+
+	assert.ErrorIs(t, err, ErrLimitReached)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// ListRevisions returns the kept revisions of a repertoire, newest first
+func (s *RepertoireService) ListRevisions(repertoireID string) ([]models.RepertoireRevision, error) {
+	return s.repo.ListRevisions(repertoireID)
+}
+
+// GetRevision returns one kept revision of a repertoire with its tree
+func (s *RepertoireService) GetRevision(repertoireID string, version int) (*models.RepertoireRevision, error) {
+	return s.repo.GetRevision(repertoireID, version)
+}
+
+// DiffRevisions compares the trees of two revisions of a repertoire
+func (s *RepertoireService) DiffRevisions(repertoireID string, from, to int) (*models.RevisionDiff, error) {
+	before, err := s.repo.GetRevision(repertoireID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.repo.GetRevision(repertoireID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffTrees(before.TreeData, after.TreeData)
+	diff.RepertoireID = repertoireID
+	diff.From = from
+	diff.To = to
+	return diff, nil
+}
+
+// RestoreRevision saves the tree of an earlier revision as the newest version, so the
+// restore itself shows up in the history and can be undone like any other edit
+func (s *RepertoireService) RestoreRevision(repertoireID string, version int) (*models.Repertoire, error) {
+	if _, err := s.getEditableRepertoire(repertoireID); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.repo.GetRevision(repertoireID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.Save(repertoireID, *rev.TreeData, calculateMetadata(*rev.TreeData))
+	if err != nil {
+		return nil, err
+	}
+	s.publish(saved, models.RepertoireEvent{Type: models.RepertoireEventRevisionRestored})
+	return saved, nil
+}
+
+// diffTrees matches the nodes of two trees by the moves leading to them and reports the
+// lines only one of them has, and the annotations that differ on the nodes both have
+func diffTrees(before, after *models.RepertoireNode) *models.RevisionDiff {
+	diff := &models.RevisionDiff{
+		Added:   []models.DiffNode{},
+		Removed: []models.DiffNode{},
+		Changed: []models.NodeChange{},
+	}
+	diffNodes(before, after, nil, diff)
+	return diff
+}
+
+func diffNodes(before, after *models.RepertoireNode, path []string, diff *models.RevisionDiff) {
+	if changes := annotationChanges(before, after); len(changes) > 0 {
+		diff.Changed = append(diff.Changed, models.NodeChange{
+			NodeID:  after.ID,
+			Path:    path,
+			FEN:     after.FEN,
+			Changes: changes,
+		})
+	}
+
+	for _, child := range before.Children {
+		if child.Move == nil || childWithMove(after, *child.Move) != nil {
+			continue
+		}
+		diff.Removed = append(diff.Removed, diffNode(child, path))
+	}
+	for _, child := range after.Children {
+		if child.Move == nil {
+			continue
+		}
+		beforeChild := childWithMove(before, *child.Move)
+		if beforeChild == nil {
+			diff.Added = append(diff.Added, diffNode(child, path))
+			continue
+		}
+		diffNodes(beforeChild, child, appendMove(path, *child.Move), diff)
+	}
+}
+
+func diffNode(node *models.RepertoireNode, parentPath []string) models.DiffNode {
+	return models.DiffNode{
+		NodeID: node.ID,
+		Path:   appendMove(parentPath, *node.Move),
+		FEN:    node.FEN,
+		Nodes:  countNodes(node),
+	}
+}
+
+// appendMove returns path followed by move without sharing the backing array of path
+func appendMove(path []string, move string) []string {
+	return append(path[:len(path):len(path)], move)
+}
+
+func countNodes(node *models.RepertoireNode) int {
+	count := 1
+	for _, child := range node.Children {
+		count += countNodes(child)
+	}
+	return count
+}
+
+func annotationChanges(before, after *models.RepertoireNode) []models.FieldChange {
+	var changes []models.FieldChange
+	add := func(field, b, a string) {
+		if b != a {
+			changes = append(changes, models.FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	add("comment", stringValue(before.Comment), stringValue(after.Comment))
+	add("branchName", stringValue(before.BranchName), stringValue(after.BranchName))
+	add("tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", "))
+	return changes
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func revisionNode(id, move string, children ...*models.RepertoireNode) *models.RepertoireNode {
+	node := &models.RepertoireNode{ID: id, FEN: "fen-" + id, Children: children}
+	if move != "" {
+		node.Move = &move
+	}
+	if node.Children == nil {
+		node.Children = []*models.RepertoireNode{}
+	}
+	return node
+}
+
+func TestDiffTrees(t *testing.T) {
+	comment := "main line"
+	before := revisionNode("root", "",
+		revisionNode("a-e4", "e4",
+			revisionNode("a-e5", "e5"),
+			revisionNode("a-c5", "c5", revisionNode("a-nf3", "Nf3")),
+		),
+	)
+	afterE5 := revisionNode("b-e5", "e5", revisionNode("b-nf3", "Nf3"))
+	afterE5.Comment = &comment
+	afterE4 := revisionNode("b-e4", "e4", afterE5)
+	afterE4.Tags = []string{"sharp"}
+	after := revisionNode("root", "", afterE4, revisionNode("b-d4", "d4"))
+
+	diff := diffTrees(before, after)
+
+	require.Len(t, diff.Added, 2)
+	assert.Equal(t, []string{"e4", "e5", "Nf3"}, diff.Added[0].Path)
+	assert.Equal(t, "b-nf3", diff.Added[0].NodeID)
+	assert.Equal(t, []string{"d4"}, diff.Added[1].Path)
+
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, []string{"e4", "c5"}, diff.Removed[0].Path)
+	assert.Equal(t, 2, diff.Removed[0].Nodes)
+
+	require.Len(t, diff.Changed, 2)
+	assert.Equal(t, "b-e4", diff.Changed[0].NodeID)
+	assert.Equal(t, []models.FieldChange{{Field: "tags", Before: "", After: "sharp"}}, diff.Changed[0].Changes)
+	assert.Equal(t, []string{"e4", "e5"}, diff.Changed[1].Path)
+	assert.Equal(t, []models.FieldChange{{Field: "comment", Before: "", After: "main line"}}, diff.Changed[1].Changes)
+}
+
+func TestDiffTrees_Identical(t *testing.T) {
+	tree := revisionNode("root", "", revisionNode("e4", "e4"))
+
+	diff := diffTrees(tree, tree)
+
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Empty(t, diff.Changed)
+}
+
+func TestDiffRevisions_NotFound(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+
+	_, err := svc.DiffRevisions("rep-1", 1, 2)
+
+	assert.ErrorIs(t, err, repository.ErrRevisionNotFound)
+}
+
+func TestRestoreRevision_SavesRevisionTree(t *testing.T) {
+	old := revisionNode("root", "", revisionNode("e4", "e4"))
+	var saved *models.RepertoireNode
+	repo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Version: 5, TreeData: *revisionNode("root", "")}, nil
+		},
+		GetRevisionFunc: func(repertoireID string, version int) (*models.RepertoireRevision, error) {
+			assert.Equal(t, 3, version)
+			return &models.RepertoireRevision{Version: 3, TreeData: old}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			saved = &treeData
+			return &models.Repertoire{ID: id, Version: 6, TreeData: treeData, Metadata: metadata}, nil
+		},
+	}
+	svc := NewRepertoireService(repo)
+
+	rep, err := svc.RestoreRevision("rep-1", 3)
+
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Len(t, saved.Children, 1)
+	assert.Equal(t, 6, rep.Version)
+	assert.Equal(t, 2, rep.Metadata.TotalNodes)
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+// ErrInvalidSearchQuery is returned when a search query is empty or too long
+var ErrInvalidSearchQuery = fmt.Errorf("q must be 1-%d characters", config.MaxSearchQueryLen)
+
+// Search finds the user's node comments and repertoire names matching a query. The query
+// follows web search syntax: quoted phrases, "or" and -excluded words.
+func (s *RepertoireService) Search(userID, query string) ([]models.RepertoireSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || len(query) > config.MaxSearchQueryLen {
+		return nil, ErrInvalidSearchQuery
+	}
+	return s.repo.Search(userID, query, config.MaxSearchResults)
+}
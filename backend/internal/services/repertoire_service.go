@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/notnil/chess"
@@ -26,15 +27,32 @@ var (
 	ErrCannotDeleteRoot   = fmt.Errorf("cannot delete root node")
 	ErrCannotExtractRoot  = fmt.Errorf("cannot extract root node")
 	ErrNodeNotFound       = fmt.Errorf("node not found")
+	ErrInvalidChildOrder  = fmt.Errorf("order must list each child of the node exactly once")
 	ErrLimitReached       = fmt.Errorf("maximum repertoire limit reached (50)")
 	ErrNameRequired       = fmt.Errorf("name is required")
 	ErrNameTooLong        = fmt.Errorf("name must be 100 characters or less")
 	ErrMergeMinimumTwo    = fmt.Errorf("at least two repertoires are required to merge")
 	ErrMergeColorMismatch = fmt.Errorf("cannot merge repertoires of different colors")
 	ErrMergeDuplicateIDs  = fmt.Errorf("duplicate repertoire IDs")
+	ErrConcurrentEdit     = fmt.Errorf("the repertoire was changed by another edit, try again")
+
+	// Template errors
+	ErrTemplateNotFound     = fmt.Errorf("unknown template")
+	ErrTemplatesUnavailable = fmt.Errorf("custom templates are not available")
+	ErrDescriptionTooLong   = fmt.Errorf("description must be 500 characters or less")
+	ErrTooManyTags          = fmt.Errorf("at most 10 tags are allowed")
+	ErrTagTooLong           = fmt.Errorf("tags must be 30 characters or less")
+
+	// Collaborator errors
+	ErrCollaboratorsUnavailable = fmt.Errorf("repertoire sharing is not available")
+	ErrInvalidRole              = fmt.Errorf("role must be 'viewer' or 'editor'")
+	ErrInviteeRequired          = fmt.Errorf("email or username is required")
+	ErrInviteeNotFound          = fmt.Errorf("no user matches this email or username")
+	ErrCannotInviteSelf         = fmt.Errorf("cannot share a repertoire with yourself")
 
 	// Game analysis errors
-	ErrColorMismatch = fmt.Errorf("repertoire color does not match user color in game")
+	ErrColorMismatch        = fmt.Errorf("repertoire color does not match user color in game")
+	ErrInvalidAnalysisDepth = fmt.Errorf("analysisDepth must be between 0 and %d", config.MaxAnalysisDepth)
 
 	// Lichess errors
 	ErrLichessUserNotFound = fmt.Errorf("Lichess user not found")
@@ -50,6 +68,12 @@ var (
 	// Lichess study errors
 	ErrLichessStudyNotFound  = fmt.Errorf("Lichess study not found")
 	ErrLichessStudyForbidden = fmt.Errorf("Lichess study is private, authentication required")
+
+	// Lichess broadcast errors
+	ErrLichessBroadcastNotFound = fmt.Errorf("Lichess broadcast round not found")
+
+	// Lichess team errors
+	ErrLichessTeamNotFound = fmt.Errorf("Lichess team not found")
 )
 
 // RepertoireRepository interface for repository operations
@@ -57,13 +81,24 @@ type RepertoireRepository interface {
 	repository.RepertoireRepository
 	CreateWithCategory(userID, name string, color models.Color, categoryID *string) (*models.Repertoire, error)
 	UpdateCategory(id string, categoryID *string) (*models.Repertoire, error)
+	SetMatchActive(id string, active bool) (*models.Repertoire, error)
+	SetArchived(id string, archived bool) (*models.Repertoire, error)
+	// GetMatchExcluded returns the IDs of the repertoires of a user left out of game matching
+	GetMatchExcluded(userID string) (map[string]bool, error)
 	GetByCategory(categoryID string) ([]models.Repertoire, error)
 	GetUncategorized(userID string, color models.Color) ([]models.Repertoire, error)
 }
 
 // RepertoireService handles repertoire business logic
 type RepertoireService struct {
-	repo RepertoireRepository
+	repo             RepertoireRepository
+	templateRepo     repository.TemplateRepository
+	collabHub        *CollabHub
+	collaboratorRepo repository.CollaboratorRepository
+	userRepo         repository.UserRepository
+	healthRepo       repository.HealthRepository
+	cache            *RepertoireCache
+	explorer         PopularityExplorer
 }
 
 // NewRepertoireService creates a new repertoire service with the given repository
@@ -71,6 +106,21 @@ func NewRepertoireService(repo RepertoireRepository) *RepertoireService {
 	return &RepertoireService{repo: repo}
 }
 
+// WithCollabHub broadcasts node edits to the clients watching a repertoire
+func (s *RepertoireService) WithCollabHub(hub *CollabHub) {
+	s.collabHub = hub
+}
+
+// publish broadcasts an edit once it has been saved
+func (s *RepertoireService) publish(saved *models.Repertoire, event models.RepertoireEvent) {
+	if s.collabHub == nil {
+		return
+	}
+	event.RepertoireID = saved.ID
+	event.Version = saved.Version
+	s.collabHub.Publish(event)
+}
+
 // CreateRepertoire creates a new repertoire with the given name and color for a user
 func (s *RepertoireService) CreateRepertoire(userID string, name string, color models.Color) (*models.Repertoire, error) {
 	if color != models.ColorWhite && color != models.ColorBlack {
@@ -137,6 +187,18 @@ func (s *RepertoireService) AssignToCategory(repertoireID string, categoryID *st
 	return s.repo.UpdateCategory(repertoireID, categoryID)
 }
 
+// SetMatchActive sets whether imported games are matched against a repertoire
+func (s *RepertoireService) SetMatchActive(repertoireID string, active bool) (*models.Repertoire, error) {
+	rep, err := s.repo.SetMatchActive(repertoireID, active)
+	if err != nil {
+		if errors.Is(err, repository.ErrRepertoireNotFound) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return rep, nil
+}
+
 // GetRepertoire retrieves a repertoire by its ID
 func (s *RepertoireService) GetRepertoire(id string) (*models.Repertoire, error) {
 	rep, err := s.repo.GetByID(id)
@@ -150,27 +212,55 @@ func (s *RepertoireService) GetRepertoire(id string) (*models.Repertoire, error)
 	return rep, nil
 }
 
-// ListRepertoires returns all repertoires for a user, optionally filtered by color
-func (s *RepertoireService) ListRepertoires(userID string, color *models.Color) ([]models.Repertoire, error) {
+// RecomputeMetadata recalculates the stored node and depth counts of a repertoire from its tree.
+// It reports whether the stored metadata was stale and has been rewritten.
+func (s *RepertoireService) RecomputeMetadata(id string) (bool, error) {
+	rep, err := s.GetRepertoire(id)
+	if err != nil {
+		return false, err
+	}
+
+	metadata := calculateMetadata(rep.TreeData)
+	if metadata == rep.Metadata {
+		return false, nil
+	}
+	if _, err := s.repo.Save(id, rep.TreeData, metadata); err != nil {
+		return false, fmt.Errorf("failed to save repertoire: %w", err)
+	}
+	return true, nil
+}
+
+// ListRepertoires returns all repertoires for a user, optionally filtered by color. Archived
+// repertoires are left out unless includeArchived is set.
+func (s *RepertoireService) ListRepertoires(userID string, color *models.Color, includeArchived bool) ([]models.Repertoire, error) {
+	var repertoires []models.Repertoire
+	var err error
 	if color != nil {
 		if *color != models.ColorWhite && *color != models.ColorBlack {
 			return nil, fmt.Errorf("%w: %s", ErrInvalidColor, *color)
 		}
-		return s.repo.GetByColor(userID, *color)
+		repertoires, err = s.repo.GetByColor(userID, *color)
+	} else {
+		repertoires, err = s.repo.GetAll(userID)
 	}
-	return s.repo.GetAll(userID)
-}
-
-// CheckOwnership verifies that a repertoire belongs to the given user
-func (s *RepertoireService) CheckOwnership(id string, userID string) error {
-	belongs, err := s.repo.BelongsToUser(id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to check ownership: %w", err)
+		return nil, err
 	}
-	if !belongs {
-		return ErrNotFound
+	if !includeArchived {
+		repertoires = withoutArchived(repertoires)
 	}
-	return nil
+	s.attachHealth(userID, repertoires)
+	return repertoires, nil
+}
+
+// FindPositions returns the nodes of the user's repertoires reaching any of the given positions
+func (s *RepertoireService) FindPositions(userID string, fens []string) ([]models.RepertoirePosition, error) {
+	return s.repo.FindPositions(userID, fens)
+}
+
+// CheckOwnership verifies that the user may edit a repertoire: they own it or are an editor on it
+func (s *RepertoireService) CheckOwnership(id string, userID string) error {
+	return s.checkAccess(id, userID, models.CollaboratorEditor)
 }
 
 // RenameRepertoire updates the name of a repertoire
@@ -209,57 +299,120 @@ func (s *RepertoireService) DeleteRepertoire(id string) error {
 
 // AddNode adds a new node to a repertoire
 func (s *RepertoireService) AddNode(repertoireID string, req models.AddNodeRequest) (*models.Repertoire, error) {
-	rep, err := s.repo.GetByID(repertoireID)
-	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	var newNode *models.RepertoireNode
+	saved, err := s.editTree(repertoireID, func(tree *models.RepertoireNode) (repository.NodeChanges, error) {
+		parentNode := findNode(tree, req.ParentID)
+		if parentNode == nil {
+			return repository.NodeChanges{}, fmt.Errorf("%w: %s", ErrParentNotFound, req.ParentID)
 		}
-		return nil, err
-	}
 
-	parentNode := findNode(&rep.TreeData, req.ParentID)
-	if parentNode == nil {
-		return nil, fmt.Errorf("%w: %s", ErrParentNotFound, req.ParentID)
-	}
+		// Check if move already exists as child
+		if moveExistsAsChild(parentNode, req.Move) {
+			return repository.NodeChanges{}, fmt.Errorf("%w: %s", ErrMoveExists, req.Move)
+		}
 
-	// Check if move already exists as child
-	if moveExistsAsChild(parentNode, req.Move) {
-		return nil, fmt.Errorf("%w: %s", ErrMoveExists, req.Move)
-	}
+		// Validate move legality using chess library
+		resultingFEN, err := validateAndGetResultingFEN(parentNode.FEN, req.Move)
+		if err != nil {
+			return repository.NodeChanges{}, fmt.Errorf("%w: %s - %v", ErrInvalidMove, req.Move, err)
+		}
 
-	// Validate move legality using chess library
-	resultingFEN, err := validateAndGetResultingFEN(parentNode.FEN, req.Move)
+		// Calculate colorToMove from resulting FEN
+		colorToMove := getColorToMoveFromFEN(resultingFEN)
+
+		newNode = &models.RepertoireNode{
+			ID:          uuid.New().String(),
+			FEN:         resultingFEN,
+			Move:        &req.Move,
+			MoveNumber:  req.MoveNumber,
+			ColorToMove: colorToMove,
+			ParentID:    &req.ParentID,
+			EditedAt:    editedNow(),
+			Children:    []*models.RepertoireNode{},
+		}
+
+		parentNode.Children = append(parentNode.Children, newNode)
+		return repository.NodeChanges{Added: []string{newNode.ID}}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s - %v", ErrInvalidMove, req.Move, err)
+		return nil, err
 	}
+	s.publish(saved, models.RepertoireEvent{
+		Type:     models.RepertoireEventNodeAdded,
+		NodeID:   newNode.ID,
+		ParentID: newNode.ParentID,
+		Node:     newNode,
+	})
+	return saved, nil
+}
 
-	// Calculate colorToMove from resulting FEN
-	colorToMove := getColorToMoveFromFEN(resultingFEN)
+// AddLine adds a sequence of moves below a node, following the moves already in the tree.
+// Collaborators receive the first new node with the rest of the line below it.
+func (s *RepertoireService) AddLine(repertoireID, nodeID string, moves []string) (*models.Repertoire, error) {
+	var first *models.RepertoireNode
+	saved, err := s.editTree(repertoireID, func(tree *models.RepertoireNode) (repository.NodeChanges, error) {
+		node := findNode(tree, nodeID)
+		if node == nil {
+			return repository.NodeChanges{}, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+		}
 
-	newNode := &models.RepertoireNode{
-		ID:          uuid.New().String(),
-		FEN:         resultingFEN,
-		Move:        &req.Move,
-		MoveNumber:  req.MoveNumber,
-		ColorToMove: colorToMove,
-		ParentID:    &req.ParentID,
-		Children:    []*models.RepertoireNode{},
-	}
+		first = nil
+		for _, move := range moves {
+			if child := childWithMove(node, move); child != nil {
+				node = child
+				continue
+			}
 
-	parentNode.Children = append(parentNode.Children, newNode)
+			resultingFEN, err := validateAndGetResultingFEN(node.FEN, move)
+			if err != nil {
+				return repository.NodeChanges{}, fmt.Errorf("%w: %s - %v", ErrInvalidMove, move, err)
+			}
+			moveNumber := node.MoveNumber
+			if node.ColorToMove == models.ChessColorWhite {
+				moveNumber++
+			}
 
-	newMetadata := calculateMetadata(rep.TreeData)
+			san := move
+			parentID := node.ID
+			child := &models.RepertoireNode{
+				ID:          uuid.New().String(),
+				FEN:         resultingFEN,
+				Move:        &san,
+				MoveNumber:  moveNumber,
+				ColorToMove: getColorToMoveFromFEN(resultingFEN),
+				ParentID:    &parentID,
+				EditedAt:    editedNow(),
+				Children:    []*models.RepertoireNode{},
+			}
+			node.Children = append(node.Children, child)
+			if first == nil {
+				first = child
+			}
+			node = child
+		}
 
-	return s.repo.Save(repertoireID, rep.TreeData, newMetadata)
+		if first == nil {
+			return repository.NodeChanges{}, nil
+		}
+		return repository.NodeChanges{Added: []string{first.ID}}, nil
+	})
+	if err != nil || first == nil {
+		return saved, err
+	}
+
+	s.publish(saved, models.RepertoireEvent{
+		Type:     models.RepertoireEventNodeAdded,
+		NodeID:   first.ID,
+		ParentID: first.ParentID,
+		Node:     first,
+	})
+	return saved, nil
 }
 
 // SaveTree saves a complete tree to a repertoire, replacing the existing tree data
 func (s *RepertoireService) SaveTree(repertoireID string, treeData models.RepertoireNode) (*models.Repertoire, error) {
-	_, err := s.repo.GetByID(repertoireID)
+	_, err := s.getEditableRepertoire(repertoireID)
 	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
-		}
 		return nil, err
 	}
 
@@ -267,28 +420,86 @@ func (s *RepertoireService) SaveTree(repertoireID string, treeData models.Repert
 	return s.repo.Save(repertoireID, treeData, metadata)
 }
 
-// DeleteNode removes a node and its children from a repertoire
-func (s *RepertoireService) DeleteNode(repertoireID string, nodeID string) (*models.Repertoire, error) {
-	rep, err := s.repo.GetByID(repertoireID)
-	if err != nil {
+// editTree applies an edit to the tree of a repertoire and writes only the nodes it reports as
+// changed. When another save gets in between, the edit is replayed on the new tree, up to
+// config.MaxTreeEditAttempts times. An edit changing nothing saves nothing.
+func (s *RepertoireService) editTree(repertoireID string, edit func(tree *models.RepertoireNode) (repository.NodeChanges, error)) (*models.Repertoire, error) {
+	for attempt := 1; ; attempt++ {
+		rep, err := s.getEditableRepertoire(repertoireID)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := edit(&rep.TreeData)
+		if err != nil {
+			return nil, err
+		}
+		if changes.IsEmpty() {
+			return rep, nil
+		}
+
+		saved, err := s.repo.SaveNodes(repertoireID, rep.Version, rep.TreeData, changes, calculateMetadata(rep.TreeData))
+		if errors.Is(err, repository.ErrRepertoireVersionConflict) {
+			if attempt < config.MaxTreeEditAttempts {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %w", ErrConcurrentEdit, err)
+		}
 		if errors.Is(err, repository.ErrRepertoireNotFound) {
 			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
 		}
-		return nil, err
+		return saved, err
 	}
+}
 
-	if rep.TreeData.ID == nodeID {
-		return nil, ErrCannotDeleteRoot
-	}
+// DeleteNode removes a node and its children from a repertoire
+func (s *RepertoireService) DeleteNode(repertoireID string, nodeID string) (*models.Repertoire, error) {
+	var parentID *string
+	saved, err := s.editTree(repertoireID, func(tree *models.RepertoireNode) (repository.NodeChanges, error) {
+		if tree.ID == nodeID {
+			return repository.NodeChanges{}, ErrCannotDeleteRoot
+		}
 
-	newTreeData := deleteNodeRecursive(rep.TreeData, nodeID)
-	if newTreeData == nil {
-		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+		parent := findParentInTree(tree, nodeID)
+		if parent == nil {
+			return repository.NodeChanges{}, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+		}
+		index := 0
+		for i, child := range parent.Children {
+			if child.ID == nodeID {
+				index = i
+			}
+		}
+		var removed []string
+		forEachNode(parent.Children[index], func(node *models.RepertoireNode) { removed = append(removed, node.ID) })
+
+		parent.Children = append(parent.Children[:index:index], parent.Children[index+1:]...)
+		parent.EditedAt = editedNow()
+		parentID = &parent.ID
+		return repository.NodeChanges{
+			Updated: []string{parent.ID},
+			Deleted: &repository.DeletedNode{ParentID: parent.ID, Index: index, NodeIDs: removed},
+		}, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.publish(saved, models.RepertoireEvent{
+		Type:     models.RepertoireEventNodeDeleted,
+		NodeID:   nodeID,
+		ParentID: parentID,
+	})
+	return saved, nil
+}
 
-	newMetadata := calculateMetadata(*newTreeData)
-
-	return s.repo.Save(repertoireID, *newTreeData, newMetadata)
+// forEachNode calls fn on every node of a tree, parents before their children
+func forEachNode(root *models.RepertoireNode, fn func(node *models.RepertoireNode)) {
+	fn(root)
+	for _, child := range root.Children {
+		if child != nil {
+			forEachNode(child, fn)
+		}
+	}
 }
 
 // SeedRepertoires creates starter repertoires from templates for the given user
@@ -296,16 +507,14 @@ func (s *RepertoireService) SeedRepertoires(userID string, templateIDs []string)
 	var created []models.Repertoire
 
 	for _, tmplID := range templateIDs {
-		tmpl := GetTemplate(tmplID)
-		if tmpl == nil {
-			return nil, fmt.Errorf("unknown template: %s", tmplID)
-		}
-
-		tree, err := BuildTemplateTree(tmpl)
+		tmpl, err := s.getTemplate(userID, tmplID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build template %s: %w", tmplID, err)
+			return nil, err
 		}
 
+		// Fresh node IDs keep repertoires seeded from the same template independent
+		tree := *deepCloneSubtree(&tmpl.TreeData, nil)
+
 		// Check repertoire limit before creating
 		count, err := s.repo.Count(userID)
 		if err != nil {
@@ -379,12 +588,17 @@ func FindNode(root *models.RepertoireNode, id string) *models.RepertoireNode {
 
 // moveExistsAsChild checks if a move already exists as a child of the parent node
 func moveExistsAsChild(parent *models.RepertoireNode, moveSAN string) bool {
+	return childWithMove(parent, moveSAN) != nil
+}
+
+// childWithMove returns the child of parent reached by moveSAN, or nil
+func childWithMove(parent *models.RepertoireNode, moveSAN string) *models.RepertoireNode {
 	for _, child := range parent.Children {
 		if child.Move != nil && *child.Move == moveSAN {
-			return true
+			return child
 		}
 	}
-	return false
+	return nil
 }
 
 func deleteNodeRecursive(root models.RepertoireNode, idToDelete string) *models.RepertoireNode {
@@ -406,11 +620,8 @@ func deleteNodeRecursive(root models.RepertoireNode, idToDelete string) *models.
 // The subtree is removed from the original.
 func (s *RepertoireService) ExtractSubtree(userID, repertoireID, nodeID, name string) (*models.ExtractSubtreeResponse, error) {
 	// Fetch repertoire
-	rep, err := s.repo.GetByID(repertoireID)
+	rep, err := s.getEditableRepertoire(repertoireID)
 	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
-		}
 		return nil, err
 	}
 
@@ -497,6 +708,8 @@ func deepCloneSubtree(node *models.RepertoireNode, parentID *string) *models.Rep
 		BranchName:      node.BranchName,
 		Collapsed:       node.Collapsed,
 		TranspositionOf: node.TranspositionOf,
+		Tags:            node.Tags,
+		EditedAt:        node.EditedAt,
 		Children:        make([]*models.RepertoireNode, 0, len(node.Children)),
 	}
 	for _, child := range node.Children {
@@ -527,6 +740,8 @@ func buildSpineWithSubtree(path []*models.RepertoireNode) models.RepertoireNode
 			ColorToMove: node.ColorToMove,
 			ParentID:    parentID,
 			Comment:     node.Comment,
+			Tags:        node.Tags,
+			EditedAt:    node.EditedAt,
 			Children:    []*models.RepertoireNode{},
 		}
 	}
@@ -624,11 +839,8 @@ func (s *RepertoireService) MergeRepertoires(userID string, ids []string, name s
 // at the same move number and merges them. The first node encountered (BFS order)
 // becomes the canonical node; duplicates become transposition pointers.
 func (s *RepertoireService) MergeTranspositions(repertoireID string) (*models.Repertoire, error) {
-	rep, err := s.repo.GetByID(repertoireID)
+	rep, err := s.getEditableRepertoire(repertoireID)
 	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
-		}
 		return nil, err
 	}
 
@@ -737,6 +949,8 @@ func mergeNodes(target, source *models.RepertoireNode) {
 			if matched.Comment == nil && srcChild.Comment != nil {
 				matched.Comment = srcChild.Comment
 			}
+			matched.Tags = mergeTags(matched.Tags, srcChild.Tags)
+			matched.EditedAt = latest(matched.EditedAt, srcChild.EditedAt)
 			mergeNodes(matched, srcChild)
 		} else {
 			target.Children = append(target.Children, deepCloneSubtree(srcChild, &target.ID))
@@ -744,6 +958,12 @@ func mergeNodes(target, source *models.RepertoireNode) {
 	}
 }
 
+// editedNow returns the timestamp recorded on nodes when their content changes
+func editedNow() *time.Time {
+	now := time.Now().UTC()
+	return &now
+}
+
 func calculateMetadata(root models.RepertoireNode) models.Metadata {
 	var totalNodes, totalMoves, maxDepth int
 
@@ -758,37 +978,77 @@ func calculateMetadata(root models.RepertoireNode) models.Metadata {
 
 // UpdateNodeComment updates the comment on a specific node in a repertoire
 func (s *RepertoireService) UpdateNodeComment(repertoireID, nodeID, comment string) (*models.Repertoire, error) {
-	rep, err := s.repo.GetByID(repertoireID)
-	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	comment = strings.TrimSpace(comment)
+	saved, node, err := s.editNode(repertoireID, nodeID, func(node *models.RepertoireNode) {
+		if comment == "" {
+			node.Comment = nil
+		} else {
+			node.Comment = &comment
 		}
+		node.EditedAt = editedNow()
+	})
+	if err != nil {
 		return nil, err
 	}
+	s.publish(saved, models.RepertoireEvent{
+		Type:    models.RepertoireEventCommentUpdated,
+		NodeID:  nodeID,
+		Comment: node.Comment,
+	})
+	return saved, nil
+}
 
-	node := findNode(&rep.TreeData, nodeID)
-	if node == nil {
-		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
-	}
-
-	comment = strings.TrimSpace(comment)
-	if comment == "" {
-		node.Comment = nil
-	} else {
-		node.Comment = &comment
-	}
-
-	metadata := calculateMetadata(rep.TreeData)
-	return s.repo.Save(repertoireID, rep.TreeData, metadata)
+// editNode changes the fields of one node of a repertoire, writing only that node
+func (s *RepertoireService) editNode(repertoireID, nodeID string, edit func(node *models.RepertoireNode)) (*models.Repertoire, *models.RepertoireNode, error) {
+	var node *models.RepertoireNode
+	saved, err := s.editTree(repertoireID, func(tree *models.RepertoireNode) (repository.NodeChanges, error) {
+		node = findNode(tree, nodeID)
+		if node == nil {
+			return repository.NodeChanges{}, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+		}
+		edit(node)
+		return repository.NodeChanges{Updated: []string{nodeID}}, nil
+	})
+	return saved, node, err
 }
 
 // UpdateNodeBranchName updates the branch name on a specific node in a repertoire
 func (s *RepertoireService) UpdateNodeBranchName(repertoireID, nodeID, branchName string) (*models.Repertoire, error) {
-	rep, err := s.repo.GetByID(repertoireID)
+	branchName = strings.TrimSpace(branchName)
+	saved, _, err := s.editNode(repertoireID, nodeID, func(node *models.RepertoireNode) {
+		if branchName == "" {
+			node.BranchName = nil
+		} else {
+			node.BranchName = &branchName
+		}
+		node.EditedAt = editedNow()
+	})
+	return saved, err
+}
+
+// UpdateNodeTags replaces the tags of a specific node in a repertoire
+func (s *RepertoireService) UpdateNodeTags(repertoireID, nodeID string, tags []string) (*models.Repertoire, error) {
+	normalized, err := normalizeTags(tags, config.MaxNodeTags)
 	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		return nil, err
+	}
+
+	saved, _, err := s.editNode(repertoireID, nodeID, func(node *models.RepertoireNode) {
+		if len(normalized) == 0 {
+			node.Tags = nil
+		} else {
+			node.Tags = normalized
 		}
+		node.EditedAt = editedNow()
+	})
+	return saved, err
+}
+
+// ReorderChildren sets the order of a node's children. The first child is the main line:
+// it is expected when checking games and comes first in lines, training and exports.
+func (s *RepertoireService) ReorderChildren(repertoireID, nodeID string, childIDs []string) (*models.Repertoire, error) {
+	rep, err := s.getEditableRepertoire(repertoireID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -797,36 +1057,182 @@ func (s *RepertoireService) UpdateNodeBranchName(repertoireID, nodeID, branchNam
 		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
 	}
 
-	branchName = strings.TrimSpace(branchName)
-	if branchName == "" {
-		node.BranchName = nil
-	} else {
-		node.BranchName = &branchName
+	reordered, err := orderChildren(node.Children, childIDs)
+	if err != nil {
+		return nil, err
 	}
+	node.Children = reordered
+	node.EditedAt = editedNow()
 
 	metadata := calculateMetadata(rep.TreeData)
-	return s.repo.Save(repertoireID, rep.TreeData, metadata)
+	saved, err := s.repo.Save(repertoireID, rep.TreeData, metadata)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(saved, models.RepertoireEvent{
+		Type:     models.RepertoireEventChildrenReordered,
+		NodeID:   nodeID,
+		ChildIDs: childIDs,
+	})
+	return saved, nil
+}
+
+// orderChildren returns children in the order of ids, which must be a permutation of their IDs
+func orderChildren(children []*models.RepertoireNode, ids []string) ([]*models.RepertoireNode, error) {
+	if len(ids) != len(children) {
+		return nil, ErrInvalidChildOrder
+	}
+	byID := make(map[string]*models.RepertoireNode, len(children))
+	for _, child := range children {
+		byID[child.ID] = child
+	}
+	ordered := make([]*models.RepertoireNode, 0, len(ids))
+	for _, id := range ids {
+		child, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidChildOrder
+		}
+		delete(byID, id)
+		ordered = append(ordered, child)
+	}
+	return ordered, nil
 }
 
 // ToggleNodeCollapsed toggles the collapsed state on a specific node in a repertoire
 func (s *RepertoireService) ToggleNodeCollapsed(repertoireID, nodeID string) (*models.Repertoire, error) {
-	rep, err := s.repo.GetByID(repertoireID)
+	saved, _, err := s.editNode(repertoireID, nodeID, func(node *models.RepertoireNode) {
+		node.Collapsed = !node.Collapsed
+	})
+	return saved, err
+}
+
+// LookupPosition returns every node of the user's repertoires reaching the given position,
+// with the moves leading to it. Candidates come from the position index and only the
+// repertoires containing them are loaded.
+func (s *RepertoireService) LookupPosition(userID, fen string) ([]models.PositionMatch, error) {
+	matches := []models.PositionMatch{}
+	err := s.forEachPositionMatch(userID, fen, func(rep *models.Repertoire, node *models.RepertoireNode, path []string) {
+		matches = append(matches, models.PositionMatch{
+			RepertoireID:   rep.ID,
+			RepertoireName: rep.Name,
+			Color:          rep.Color,
+			NodeID:         node.ID,
+			Path:           path,
+		})
+	})
 	if err != nil {
-		if errors.Is(err, repository.ErrRepertoireNotFound) {
-			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		return nil, err
+	}
+	return matches, nil
+}
+
+// ExpectedMoves returns what each of the user's repertoires plays in the given position, when it
+// is the repertoire's side to move. playedMove, in SAN, is checked against the prepared moves.
+func (s *RepertoireService) ExpectedMoves(userID, fen, playedMove string) ([]models.RepertoireExpectation, error) {
+	turn := models.ColorWhite
+	if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+		turn = models.ColorBlack
+	}
+
+	expectations := []models.RepertoireExpectation{}
+	err := s.forEachPositionMatch(userID, fen, func(rep *models.Repertoire, node *models.RepertoireNode, path []string) {
+		if rep.Color != turn {
+			return
+		}
+		expectation := models.RepertoireExpectation{
+			RepertoireID:   rep.ID,
+			RepertoireName: rep.Name,
+			NodeID:         node.ID,
+			Path:           path,
 		}
+		if len(node.Children) > 0 {
+			expected := node.Children[0]
+			if expected.Move != nil {
+				expectation.ExpectedMove = *expected.Move
+			}
+			if expected.Comment != nil {
+				expectation.Comment = *expected.Comment
+			}
+		}
+		for _, child := range node.Children {
+			if child.Move != nil && *child.Move == playedMove {
+				expectation.PlayedInRepertoire = true
+			}
+		}
+		expectations = append(expectations, expectation)
+	})
+	if err != nil {
 		return nil, err
 	}
+	return expectations, nil
+}
 
-	node := findNode(&rep.TreeData, nodeID)
-	if node == nil {
-		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+// forEachPositionMatch calls found for every node of the user's repertoires reaching the given position
+func (s *RepertoireService) forEachPositionMatch(userID, fen string, found func(*models.Repertoire, *models.RepertoireNode, []string)) error {
+	if _, err := chess.FEN(ensureFullFEN(fen)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFEN, err)
 	}
+	target := positionLookupKey(fen)
 
-	node.Collapsed = !node.Collapsed
+	positions, err := s.repo.FindPositions(userID, []string{fen})
+	if err != nil {
+		return err
+	}
 
-	metadata := calculateMetadata(rep.TreeData)
-	return s.repo.Save(repertoireID, rep.TreeData, metadata)
+	// The index ignores en passant squares, so candidates are checked again on the tree
+	var repertoireIDs []string
+	seen := make(map[string]bool)
+	for _, p := range positions {
+		if !seen[p.RepertoireID] {
+			seen[p.RepertoireID] = true
+			repertoireIDs = append(repertoireIDs, p.RepertoireID)
+		}
+	}
+
+	for _, id := range repertoireIDs {
+		rep, err := s.repo.GetByID(id)
+		if err != nil {
+			if errors.Is(err, repository.ErrRepertoireNotFound) {
+				continue
+			}
+			return err
+		}
+		collectPositionMatches(&rep.TreeData, target, nil, func(node *models.RepertoireNode, path []string) {
+			found(rep, node, path)
+		})
+	}
+	return nil
+}
+
+// collectPositionMatches walks the tree depth-first, calling found for each node whose position is target
+func collectPositionMatches(node *models.RepertoireNode, target string, path []string, found func(*models.RepertoireNode, []string)) {
+	if node.Move != nil {
+		path = append(path, *node.Move)
+	}
+	if positionLookupKey(node.FEN) == target {
+		found(node, append([]string{}, path...))
+	}
+	for _, child := range node.Children {
+		collectPositionMatches(child, target, path, found)
+	}
+}
+
+// positionLookupKey normalizes a FEN and drops its en passant square unless the capture is legal,
+// since some generators always record the square after a double pawn push and others never do.
+func positionLookupKey(fen string) string {
+	parts := strings.Fields(NormalizeFEN(fen))
+	if len(parts) != 4 || parts[3] == "-" {
+		return strings.Join(parts, " ")
+	}
+	if fenFn, err := chess.FEN(ensureFullFEN(fen)); err == nil {
+		for _, move := range chess.NewGame(fenFn).ValidMoves() {
+			if move.HasTag(chess.EnPassant) {
+				return strings.Join(parts, " ")
+			}
+		}
+	}
+	parts[3] = "-"
+	return strings.Join(parts, " ")
 }
 
 func walkTree(node *models.RepertoireNode, currentDepth int, totalNodes, totalMoves, maxDepth *int) {
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 	"github.com/treechess/backend/internal/repository/mocks"
@@ -612,7 +613,7 @@ func TestListRepertoires_InvalidColor(t *testing.T) {
 	svc := NewRepertoireService(mockRepo)
 	invalidColor := models.Color("invalid")
 
-	_, err := svc.ListRepertoires("user-1", &invalidColor)
+	_, err := svc.ListRepertoires("user-1", &invalidColor, false)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid color")
@@ -1421,7 +1422,7 @@ func TestRepertoireService_ListRepertoires_WithColor(t *testing.T) {
 	svc := NewRepertoireService(mockRepo)
 	white := models.ColorWhite
 
-	reps, err := svc.ListRepertoires("user-1", &white)
+	reps, err := svc.ListRepertoires("user-1", &white, false)
 
 	require.NoError(t, err)
 	assert.Len(t, reps, 1)
@@ -1435,7 +1436,7 @@ func TestRepertoireService_ListRepertoires_All(t *testing.T) {
 	}
 	svc := NewRepertoireService(mockRepo)
 
-	reps, err := svc.ListRepertoires("user-1", nil)
+	reps, err := svc.ListRepertoires("user-1", nil, false)
 
 	require.NoError(t, err)
 	assert.Len(t, reps, 2)
@@ -1679,3 +1680,400 @@ func TestMergeTranspositions_SameFENDifferentMoveNumber(t *testing.T) {
 	assert.Nil(t, findNode(&savedTree, "n1").TranspositionOf)
 	assert.Nil(t, findNode(&savedTree, "n2").TranspositionOf)
 }
+
+func TestRepertoireService_LookupPosition(t *testing.T) {
+	d4, d5, c4, nf6 := "d4", "d5", "c4", "Nf6"
+	target := "rnbqkb1r/pppppppp/5n2/8/3P4/8/PPP1PPPP/RNBQKBNR w KQkq -"
+	reps := []models.Repertoire{
+		{
+			ID: "rep-1", Name: "Queen's Gambit", Color: models.ColorWhite,
+			TreeData: models.RepertoireNode{
+				ID:  "root",
+				FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+				Children: []*models.RepertoireNode{{
+					ID: "a1", Move: &d4,
+					FEN: "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq -",
+					Children: []*models.RepertoireNode{
+						{ID: "a2", Move: &d5, FEN: "rnbqkbnr/ppp1pppp/8/3p4/3P4/8/PPP1PPPP/RNBQKBNR w KQkq -"},
+						{ID: "a3", Move: &nf6, FEN: target},
+					},
+				}},
+			},
+		},
+		{
+			ID: "rep-2", Name: "Other", Color: models.ColorBlack,
+			TreeData: models.RepertoireNode{
+				ID:  "root",
+				FEN: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+				Children: []*models.RepertoireNode{{
+					ID: "b1", Move: &c4,
+					FEN: "rnbqkbnr/pppppppp/8/8/2P5/8/PP1PPPPP/RNBQKBNR b KQkq -",
+				}},
+			},
+		},
+	}
+	var loaded []string
+	mockRepo := &mocks.MockRepertoireRepo{
+		GetAllFunc: func(userID string) ([]models.Repertoire, error) {
+			return reps, nil
+		},
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			loaded = append(loaded, id)
+			for i := range reps {
+				if reps[i].ID == id {
+					return &reps[i], nil
+				}
+			}
+			return nil, repository.ErrRepertoireNotFound
+		},
+	}
+	svc := NewRepertoireService(mockRepo)
+
+	matches, err := svc.LookupPosition("user-1", target+" 2 2")
+
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "rep-1", matches[0].RepertoireID)
+	assert.Equal(t, "Queen's Gambit", matches[0].RepertoireName)
+	assert.Equal(t, "a3", matches[0].NodeID)
+	assert.Equal(t, []string{"d4", "Nf6"}, matches[0].Path)
+	// Only repertoires found in the position index are loaded
+	assert.Equal(t, []string{"rep-1"}, loaded)
+}
+
+func TestRepertoireService_LookupPosition_InvalidFEN(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+
+	_, err := svc.LookupPosition("user-1", "not a fen")
+
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+}
+
+func TestPositionLookupKey_IgnoresUnplayableEnPassant(t *testing.T) {
+	assert.Equal(t,
+		positionLookupKey("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -"),
+		positionLookupKey("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"))
+
+	// The square is kept when the capture is available
+	assert.Equal(t,
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6",
+		positionLookupKey("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3"))
+}
+
+func TestRepertoireService_PublishTemplate(t *testing.T) {
+	e4 := "e4"
+	rep := &models.Repertoire{
+		ID: "rep-1", Name: "My e4", Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID:       "root",
+			FEN:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+			Children: []*models.RepertoireNode{{ID: "n1", Move: &e4, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -"}},
+		},
+	}
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+	})
+	var created models.RepertoireTemplate
+	svc.WithTemplates(&mocks.MockTemplateRepo{
+		CreateFunc: func(tmpl models.RepertoireTemplate) (*models.RepertoireTemplate, error) {
+			created = tmpl
+			return &tmpl, nil
+		},
+	})
+
+	tmpl, err := svc.PublishTemplate("user-1", models.PublishTemplateRequest{
+		RepertoireID: "rep-1",
+		Description:  "Sharp lines",
+		Tags:         []string{" Aggressive", "aggressive", "", "e4"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "My e4", tmpl.Name)
+	assert.Equal(t, models.ColorWhite, tmpl.Color)
+	assert.Equal(t, []string{"aggressive", "e4"}, created.Tags)
+	assert.False(t, created.Featured)
+	require.NotNil(t, created.UserID)
+	assert.Equal(t, "user-1", *created.UserID)
+	// The template owns a copy of the tree
+	require.Len(t, created.TreeData.Children, 1)
+	assert.NotEqual(t, "n1", created.TreeData.Children[0].ID)
+}
+
+func TestRepertoireService_PublishTemplate_NotOwner(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return false, nil },
+	})
+	svc.WithTemplates(&mocks.MockTemplateRepo{})
+
+	_, err := svc.PublishTemplate("user-1", models.PublishTemplateRequest{RepertoireID: "rep-1"})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRepertoireService_PublishTemplate_TooManyTags(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Name: "Rep", Color: models.ColorBlack}, nil
+		},
+	})
+	svc.WithTemplates(&mocks.MockTemplateRepo{})
+
+	tags := make([]string, 11)
+	for i := range tags {
+		tags[i] = string(rune('a' + i))
+	}
+	_, err := svc.PublishTemplate("user-1", models.PublishTemplateRequest{RepertoireID: "rep-1", Tags: tags})
+
+	assert.ErrorIs(t, err, ErrTooManyTags)
+}
+
+func TestRepertoireService_SeedRepertoires_TemplateVisibility(t *testing.T) {
+	owner := "user-2"
+	templates := map[string]*models.RepertoireTemplate{
+		"private":  {ID: "private", UserID: &owner, Name: "Private", Color: models.ColorWhite},
+		"featured": {ID: "featured", UserID: &owner, Name: "Featured", Color: models.ColorWhite, Featured: true},
+	}
+	var savedRoots []string
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		CreateFunc: func(userID, name string, color models.Color) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: "new-" + name, Name: name, Color: color}, nil
+		},
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			savedRoots = append(savedRoots, treeData.ID)
+			return &models.Repertoire{ID: id, TreeData: treeData}, nil
+		},
+	})
+	svc.WithTemplates(&mocks.MockTemplateRepo{
+		GetByIDFunc: func(id string) (*models.RepertoireTemplate, error) {
+			if tmpl, ok := templates[id]; ok {
+				return tmpl, nil
+			}
+			return nil, repository.ErrTemplateNotFound
+		},
+	})
+
+	_, err := svc.SeedRepertoires("user-1", []string{"private"})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	created, err := svc.SeedRepertoires("user-1", []string{"featured"})
+	require.NoError(t, err)
+	assert.Len(t, created, 1)
+
+	// Owners can seed from their own unfeatured templates
+	_, err = svc.SeedRepertoires("user-2", []string{"private"})
+	require.NoError(t, err)
+	assert.Len(t, savedRoots, 2)
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	templates, err := BuiltinTemplates()
+
+	require.NoError(t, err)
+	assert.Len(t, templates, len(starterTemplates))
+	for _, tmpl := range templates {
+		assert.True(t, tmpl.Featured)
+		assert.NotEmpty(t, tmpl.TreeData.Children, tmpl.ID)
+	}
+}
+
+func TestRepertoireService_UpdateNodeTags(t *testing.T) {
+	e4 := "e4"
+	rep := &models.Repertoire{
+		ID: "rep-1",
+		TreeData: models.RepertoireNode{
+			ID:       "root",
+			Children: []*models.RepertoireNode{{ID: "n1", Move: &e4}},
+		},
+	}
+	mockRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: treeData}, nil
+		},
+	}
+	svc := NewRepertoireService(mockRepo)
+
+	saved, err := svc.UpdateNodeTags("rep-1", "n1", []string{"Critical", " needs-review ", "critical"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"critical", "needs-review"}, saved.TreeData.Children[0].Tags)
+
+	saved, err = svc.UpdateNodeTags("rep-1", "n1", nil)
+	require.NoError(t, err)
+	assert.Nil(t, saved.TreeData.Children[0].Tags)
+
+	_, err = svc.UpdateNodeTags("rep-1", "missing", []string{"critical"})
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+}
+
+func TestRepertoireService_ReorderChildren(t *testing.T) {
+	e4, d4, c4 := "e4", "d4", "c4"
+	rep := &models.Repertoire{
+		ID: "rep-1",
+		TreeData: models.RepertoireNode{
+			ID: "root",
+			Children: []*models.RepertoireNode{
+				{ID: "n-e4", Move: &e4},
+				{ID: "n-d4", Move: &d4},
+				{ID: "n-c4", Move: &c4},
+			},
+		},
+	}
+	mockRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, TreeData: treeData}, nil
+		},
+	}
+	svc := NewRepertoireService(mockRepo)
+
+	saved, err := svc.ReorderChildren("rep-1", "root", []string{"n-d4", "n-c4", "n-e4"})
+
+	require.NoError(t, err)
+	require.Len(t, saved.TreeData.Children, 3)
+	assert.Equal(t, "n-d4", saved.TreeData.Children[0].ID)
+	assert.Equal(t, "n-c4", saved.TreeData.Children[1].ID)
+	assert.Equal(t, "n-e4", saved.TreeData.Children[2].ID)
+
+	lines, err := svc.ListLines("rep-1", nil)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"d4"}, lines[0].Moves)
+}
+
+func TestRepertoireService_ReorderChildren_InvalidOrder(t *testing.T) {
+	rep := &models.Repertoire{
+		ID: "rep-1",
+		TreeData: models.RepertoireNode{
+			ID:       "root",
+			Children: []*models.RepertoireNode{{ID: "a"}, {ID: "b"}},
+		},
+	}
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+	})
+
+	for _, order := range [][]string{{"a"}, {"a", "a"}, {"a", "c"}, {"a", "b", "c"}} {
+		_, err := svc.ReorderChildren("rep-1", "root", order)
+		assert.ErrorIs(t, err, ErrInvalidChildOrder, "order %v", order)
+	}
+
+	_, err := svc.ReorderChildren("rep-1", "missing", []string{})
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+}
+
+func TestRepertoireService_RecomputeMetadata(t *testing.T) {
+	rep := newTaggedRepertoire()
+	rep.Metadata = models.Metadata{TotalNodes: 1}
+	var saved *models.Metadata
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+		SaveFunc: func(id string, treeData models.RepertoireNode, metadata models.Metadata) (*models.Repertoire, error) {
+			saved = &metadata
+			return rep, nil
+		},
+	})
+
+	changed, err := svc.RecomputeMetadata("rep-1")
+
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.NotNil(t, saved)
+	assert.Equal(t, models.Metadata{TotalNodes: 8, TotalMoves: 7, DeepestDepth: 5}, *saved)
+
+	rep.Metadata = *saved
+	saved = nil
+	changed, err = svc.RecomputeMetadata("rep-1")
+
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Nil(t, saved)
+}
+
+// newNodeEditTestRepo serves a fresh copy of a tree at each read and records the node-level saves
+func newNodeEditTestRepo(t *testing.T, saves *[]repository.NodeChanges, conflicts int, lines ...string) *mocks.MockRepertoireRepo {
+	return &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Version: 7, TreeData: newLabelTestTree(t, lines...)}, nil
+		},
+		SaveNodesFunc: func(id string, version int, tree models.RepertoireNode, changes repository.NodeChanges, metadata models.Metadata) (*models.Repertoire, error) {
+			assert.Equal(t, 7, version)
+			*saves = append(*saves, changes)
+			if len(*saves) <= conflicts {
+				return nil, repository.ErrRepertoireVersionConflict
+			}
+			return &models.Repertoire{ID: id, Version: version + 1, TreeData: tree, Metadata: metadata}, nil
+		},
+	}
+}
+
+func TestAddNode_SavesOnlyNewNode(t *testing.T) {
+	var saves []repository.NodeChanges
+	svc := NewRepertoireService(newNodeEditTestRepo(t, &saves, 0, "e4 e5"))
+
+	saved, err := svc.AddNode("rep-1", models.AddNodeRequest{ParentID: "root-e4", Move: "c5", MoveNumber: 1})
+
+	require.NoError(t, err)
+	require.Len(t, saves, 1)
+	added := saved.TreeData.Children[0].Children[1]
+	assert.Equal(t, repository.NodeChanges{Added: []string{added.ID}}, saves[0])
+	assert.Equal(t, 4, saved.Metadata.TotalNodes)
+}
+
+func TestDeleteNode_SavesRemovalAndParent(t *testing.T) {
+	var saves []repository.NodeChanges
+	svc := NewRepertoireService(newNodeEditTestRepo(t, &saves, 0, "e4 e5 Nf3", "e4 c5"))
+
+	saved, err := svc.DeleteNode("rep-1", "root-e4-e5")
+
+	require.NoError(t, err)
+	require.Len(t, saves, 1)
+	assert.Equal(t, []string{"root-e4"}, saves[0].Updated)
+	assert.Equal(t, &repository.DeletedNode{
+		ParentID: "root-e4",
+		Index:    0,
+		NodeIDs:  []string{"root-e4-e5", "root-e4-e5-Nf3"},
+	}, saves[0].Deleted)
+	require.Len(t, saved.TreeData.Children[0].Children, 1)
+	assert.NotNil(t, saved.TreeData.Children[0].EditedAt)
+
+	_, err = svc.DeleteNode("rep-1", "root")
+	assert.ErrorIs(t, err, ErrCannotDeleteRoot)
+	_, err = svc.DeleteNode("rep-1", "missing")
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+}
+
+func TestUpdateNodeComment_RetriesOnConflict(t *testing.T) {
+	var saves []repository.NodeChanges
+	svc := NewRepertoireService(newNodeEditTestRepo(t, &saves, 1, "d4 d5"))
+
+	saved, err := svc.UpdateNodeComment("rep-1", "root-d4", "  Queen's pawn ")
+
+	require.NoError(t, err)
+	assert.Len(t, saves, 2, "the edit is replayed on a fresh read")
+	assert.Equal(t, repository.NodeChanges{Updated: []string{"root-d4"}}, saves[1])
+	assert.Equal(t, "Queen's pawn", *saved.TreeData.Children[0].Comment)
+}
+
+func TestEditTree_GivesUpAfterRepeatedConflicts(t *testing.T) {
+	var saves []repository.NodeChanges
+	svc := NewRepertoireService(newNodeEditTestRepo(t, &saves, config.MaxTreeEditAttempts, "d4 d5"))
+
+	_, err := svc.ToggleNodeCollapsed("rep-1", "root-d4")
+
+	assert.ErrorIs(t, err, ErrConcurrentEdit)
+	assert.Len(t, saves, config.MaxTreeEditAttempts)
+}
+
+func TestAddLine_ExistingMovesSaveNothing(t *testing.T) {
+	var saves []repository.NodeChanges
+	svc := NewRepertoireService(newNodeEditTestRepo(t, &saves, 0, "e4 e5 Nf3"))
+
+	_, err := svc.AddLine("rep-1", "root", []string{"e4", "e5"})
+	require.NoError(t, err)
+	assert.Empty(t, saves)
+
+	_, err = svc.AddLine("rep-1", "root", []string{"e4", "e5", "Bc4", "Nf6"})
+	require.NoError(t, err)
+	require.Len(t, saves, 1)
+	assert.Len(t, saves[0].Added, 1, "the new line is written as one subtree")
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+var (
+	ErrNoRepertoireSnapshot          = fmt.Errorf("%w: the game was not analyzed against a recorded repertoire version", ErrNotFound)
+	ErrRepertoireSnapshotUnavailable = fmt.Errorf("%w: the repertoire version the game was analyzed against is no longer kept", ErrNotFound)
+)
+
+// repertoireSnapshot references a repertoire as it is now, with the version and tree hash a
+// game analyzed against it records
+func repertoireSnapshot(repertoire *models.Repertoire) *models.RepertoireRef {
+	return &models.RepertoireRef{
+		ID:       repertoire.ID,
+		Name:     repertoire.Name,
+		Version:  repertoire.Version,
+		TreeHash: repertoireTreeHash(&repertoire.TreeData),
+	}
+}
+
+// stampRepertoireSnapshot records on a game the version and tree hash of the repertoire it was
+// matched to. hashes keeps the hash of each tree across the games of one import.
+func stampRepertoireSnapshot(game *models.GameAnalysis, repertoires map[string]*models.Repertoire, hashes map[string]string) {
+	if game.MatchedRepertoire == nil {
+		return
+	}
+	repertoire, ok := repertoires[game.MatchedRepertoire.ID]
+	if !ok {
+		return
+	}
+	hash, ok := hashes[repertoire.ID]
+	if !ok {
+		hash = repertoireTreeHash(&repertoire.TreeData)
+		hashes[repertoire.ID] = hash
+	}
+	game.MatchedRepertoire.Version = repertoire.Version
+	game.MatchedRepertoire.TreeHash = hash
+}
+
+// repertoireTreeHash hashes the moves of a tree in display order. Comments, tags and other
+// annotations are left out: only the moves and their order decide how a game is matched.
+func repertoireTreeHash(root *models.RepertoireNode) string {
+	var b strings.Builder
+	b.WriteString(root.FEN)
+	var walk func(node *models.RepertoireNode)
+	walk = func(node *models.RepertoireNode) {
+		for _, child := range node.Children {
+			if child == nil || child.Move == nil {
+				continue
+			}
+			b.WriteByte('(')
+			b.WriteString(*child.Move)
+			walk(child)
+			b.WriteByte(')')
+		}
+	}
+	walk(root)
+
+	hash := sha256.Sum256([]byte(b.String()))
+	return fmt.Sprintf("sha256:%x", hash)
+}
+
+// GetGameRepertoireSnapshot returns a game with the tree of the repertoire version it was
+// analyzed against: the current tree when the repertoire has not been saved since, else the
+// kept revision of that version
+func (s *ImportService) GetGameRepertoireSnapshot(analysisID string, gameIndex int) (*models.GameRepertoireSnapshot, error) {
+	game, err := s.analysisRepo.GetGame(analysisID, gameIndex)
+	if err != nil {
+		return nil, err
+	}
+	ref := game.MatchedRepertoire
+	if ref == nil || ref.Version == 0 {
+		return nil, ErrNoRepertoireSnapshot
+	}
+
+	snapshot := &models.GameRepertoireSnapshot{Game: *game, Repertoire: *ref, Changed: true}
+	current, err := s.repertoireService.GetRepertoire(ref.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if current != nil {
+		snapshot.CurrentVersion = current.Version
+		snapshot.Changed = repertoireTreeHash(&current.TreeData) != ref.TreeHash
+		if current.Version == ref.Version {
+			snapshot.TreeData = &current.TreeData
+			return snapshot, nil
+		}
+	}
+
+	rev, err := s.repertoireService.GetRevision(ref.ID, ref.Version)
+	if errors.Is(err, repository.ErrRevisionNotFound) {
+		return nil, ErrRepertoireSnapshotUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot.TreeData = rev.TreeData
+	return snapshot, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestRepertoireTreeHash(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5 Nf3", "e4 c5")
+	hash := repertoireTreeHash(&tree)
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, hash)
+
+	comment := "Open games"
+	tree.Children[0].Children[0].Comment = &comment
+	tree.Children[0].Children[0].Tags = []string{"critical"}
+	assert.Equal(t, hash, repertoireTreeHash(&tree), "annotations do not change matching")
+
+	e4 := tree.Children[0]
+	e4.Children[0], e4.Children[1] = e4.Children[1], e4.Children[0]
+	assert.NotEqual(t, hash, repertoireTreeHash(&tree), "the main line decides the expected move")
+
+	other := newLabelTestTree(t, "e4 e5", "e4 c5 Nf3")
+	assert.NotEqual(t, hash, repertoireTreeHash(&other))
+}
+
+func TestReanalyzeGame_RecordsRepertoireSnapshot(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5")
+	rep := &models.Repertoire{ID: "rep-1", Name: "1.e4", Color: models.ColorWhite, Version: 7, TreeData: tree}
+	var saved models.GameAnalysis
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) {
+			return &models.GameAnalysis{UserColor: models.ColorWhite, Moves: []models.MoveAnalysis{
+				{PlyNumber: 0, SAN: "e4", FEN: tree.FEN, IsUserMove: true},
+			}}, nil
+		},
+		UpdateGameFunc: func(analysisID string, game models.GameAnalysis) error {
+			saved = game
+			return nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+	}), analysisRepo)
+
+	_, err := svc.ReanalyzeGame("user-1", "analysis-1", 0, "rep-1")
+
+	require.NoError(t, err)
+	require.NotNil(t, saved.MatchedRepertoire)
+	assert.Equal(t, 7, saved.MatchedRepertoire.Version)
+	assert.Equal(t, repertoireTreeHash(&tree), saved.MatchedRepertoire.TreeHash)
+}
+
+func TestGetGameRepertoireSnapshot(t *testing.T) {
+	oldTree := newLabelTestTree(t, "e4 e5")
+	newTree := newLabelTestTree(t, "e4 e5", "e4 c5")
+	game := &models.GameAnalysis{MatchedRepertoire: &models.RepertoireRef{
+		ID: "rep-1", Name: "1.e4", Version: 4, TreeHash: repertoireTreeHash(&oldTree),
+	}}
+	analysisRepo := &mocks.MockAnalysisRepo{
+		GetGameFunc: func(analysisID string, gameIndex int) (*models.GameAnalysis, error) { return game, nil },
+	}
+	current := &models.Repertoire{ID: "rep-1", Version: 6, TreeData: newTree}
+	repertoireRepo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return current, nil },
+		GetRevisionFunc: func(repertoireID string, version int) (*models.RepertoireRevision, error) {
+			assert.Equal(t, 4, version)
+			return &models.RepertoireRevision{Version: version, TreeData: &oldTree}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(repertoireRepo), analysisRepo)
+
+	t.Run("edited since", func(t *testing.T) {
+		snapshot, err := svc.GetGameRepertoireSnapshot("analysis-1", 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, &oldTree, snapshot.TreeData)
+		assert.Equal(t, 6, snapshot.CurrentVersion)
+		assert.True(t, snapshot.Changed)
+	})
+
+	t.Run("saved since with the same moves", func(t *testing.T) {
+		comment := "Open games"
+		sameMoves := newLabelTestTree(t, "e4 e5")
+		sameMoves.Children[0].Comment = &comment
+		current = &models.Repertoire{ID: "rep-1", Version: 5, TreeData: sameMoves}
+		defer func() { current = &models.Repertoire{ID: "rep-1", Version: 6, TreeData: newTree} }()
+
+		snapshot, err := svc.GetGameRepertoireSnapshot("analysis-1", 0)
+
+		require.NoError(t, err)
+		assert.False(t, snapshot.Changed)
+	})
+
+	t.Run("revision pruned", func(t *testing.T) {
+		repertoireRepo.GetRevisionFunc = nil
+
+		_, err := svc.GetGameRepertoireSnapshot("analysis-1", 0)
+
+		assert.ErrorIs(t, err, ErrRepertoireSnapshotUnavailable)
+	})
+
+	t.Run("repertoire deleted", func(t *testing.T) {
+		repertoireRepo.GetByIDFunc = func(id string) (*models.Repertoire, error) {
+			return nil, repository.ErrRepertoireNotFound
+		}
+		repertoireRepo.GetRevisionFunc = func(repertoireID string, version int) (*models.RepertoireRevision, error) {
+			return &models.RepertoireRevision{Version: version, TreeData: &oldTree}, nil
+		}
+
+		snapshot, err := svc.GetGameRepertoireSnapshot("analysis-1", 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, snapshot.CurrentVersion)
+		assert.True(t, snapshot.Changed)
+	})
+
+	t.Run("analyzed before snapshots", func(t *testing.T) {
+		game = &models.GameAnalysis{MatchedRepertoire: &models.RepertoireRef{ID: "rep-1", Name: "1.e4"}}
+
+		_, err := svc.GetGameRepertoireSnapshot("analysis-1", 0)
+
+		assert.ErrorIs(t, err, ErrNoRepertoireSnapshot)
+	})
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// GetRepertoireToDepth returns a repertoire with its tree cut depth moves below the root.
+// Nodes whose children were cut are marked Truncated; a negative depth returns the whole tree.
+func (s *RepertoireService) GetRepertoireToDepth(id string, depth int) (*models.Repertoire, error) {
+	rep, err := s.GetRepertoire(id)
+	if err != nil {
+		return nil, err
+	}
+	if depth >= 0 {
+		rep.TreeData = *pruneTree(&rep.TreeData, depth)
+	}
+	return rep, nil
+}
+
+// GetSubtree returns the subtree rooted at a node, cut depth moves below it like GetRepertoireToDepth
+func (s *RepertoireService) GetSubtree(repertoireID, nodeID string, depth int) (*models.RepertoireNode, error) {
+	rep, err := s.GetRepertoire(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+
+	node := findNode(&rep.TreeData, nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+	}
+	return pruneTree(node, depth), nil
+}
+
+// pruneTree copies a tree down to depth levels below node, leaving the original untouched.
+// A negative depth copies the whole tree.
+func pruneTree(node *models.RepertoireNode, depth int) *models.RepertoireNode {
+	pruned := *node
+	pruned.Children = []*models.RepertoireNode{}
+	if depth == 0 {
+		pruned.Truncated = len(node.Children) > 0
+		return &pruned
+	}
+	for _, child := range node.Children {
+		pruned.Children = append(pruned.Children, pruneTree(child, depth-1))
+	}
+	return &pruned
+}
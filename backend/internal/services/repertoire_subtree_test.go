@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestPruneTree_LeavesOriginalUntouched(t *testing.T) {
+	rep := newTaggedRepertoire()
+
+	pruned := pruneTree(&rep.TreeData, 1)
+
+	require.Len(t, pruned.Children, 1)
+	e4 := pruned.Children[0]
+	assert.True(t, e4.Truncated)
+	assert.Empty(t, e4.Children)
+	assert.False(t, pruned.Truncated)
+
+	original := rep.TreeData.Children[0]
+	assert.False(t, original.Truncated)
+	assert.Len(t, original.Children, 2)
+}
+
+func TestPruneTree_LeafIsNotTruncated(t *testing.T) {
+	rep := newTaggedRepertoire()
+
+	pruned := pruneTree(&rep.TreeData, 10)
+
+	d4 := findNode(pruned, "d4")
+	require.NotNil(t, d4)
+	assert.False(t, d4.Truncated)
+	assert.Equal(t, calculateMetadata(rep.TreeData), calculateMetadata(*pruned))
+}
+
+func TestRepertoireService_GetRepertoireToDepth(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return newTaggedRepertoire(), nil },
+	})
+
+	rep, err := svc.GetRepertoireToDepth("rep-1", 0)
+	require.NoError(t, err)
+	assert.True(t, rep.TreeData.Truncated)
+	assert.Empty(t, rep.TreeData.Children)
+
+	rep, err = svc.GetRepertoireToDepth("rep-1", -1)
+	require.NoError(t, err)
+	assert.NotNil(t, findNode(&rep.TreeData, "d4"))
+}
+
+func TestRepertoireService_GetSubtree(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return newTaggedRepertoire(), nil },
+	})
+
+	subtree, err := svc.GetSubtree("rep-1", "c5", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "c5", subtree.ID)
+	require.Len(t, subtree.Children, 1)
+	assert.Equal(t, "nf3-b", subtree.Children[0].ID)
+	assert.True(t, subtree.Children[0].Truncated)
+
+	_, err = svc.GetSubtree("rep-1", "missing", 1)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+}
@@ -1,17 +1,20 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/notnil/chess"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
 )
 
-// RepertoireTemplate represents a starter repertoire template
-type RepertoireTemplate struct {
+// StarterTemplate is a built-in template, stored in the template table on startup
+type StarterTemplate struct {
 	ID          string
 	Name        string
 	Color       models.Color
@@ -19,15 +22,7 @@ type RepertoireTemplate struct {
 	Moves       []string // SAN moves for the main line
 }
 
-// RepertoireTemplateSummary is the public-facing summary of a template
-type RepertoireTemplateSummary struct {
-	ID          string       `json:"id"`
-	Name        string       `json:"name"`
-	Color       models.Color `json:"color"`
-	Description string       `json:"description"`
-}
-
-var starterTemplates = []RepertoireTemplate{
+var starterTemplates = []StarterTemplate{
 	// White openings
 	{
 		ID:          "italian",
@@ -116,32 +111,29 @@ var starterTemplates = []RepertoireTemplate{
 	},
 }
 
-// GetTemplate returns a template by ID, or nil if not found
-func GetTemplate(id string) *RepertoireTemplate {
+// BuiltinTemplates returns the starter templates with their trees built
+func BuiltinTemplates() ([]models.RepertoireTemplate, error) {
+	templates := make([]models.RepertoireTemplate, 0, len(starterTemplates))
 	for i := range starterTemplates {
-		if starterTemplates[i].ID == id {
-			return &starterTemplates[i]
-		}
-	}
-	return nil
-}
-
-// ListTemplates returns summaries of all available templates
-func ListTemplates() []RepertoireTemplateSummary {
-	summaries := make([]RepertoireTemplateSummary, len(starterTemplates))
-	for i, t := range starterTemplates {
-		summaries[i] = RepertoireTemplateSummary{
-			ID:          t.ID,
-			Name:        t.Name,
-			Color:       t.Color,
-			Description: t.Description,
+		tree, err := BuildTemplateTree(&starterTemplates[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to build template %s: %w", starterTemplates[i].ID, err)
 		}
+		templates = append(templates, models.RepertoireTemplate{
+			ID:          starterTemplates[i].ID,
+			Name:        starterTemplates[i].Name,
+			Color:       starterTemplates[i].Color,
+			Description: starterTemplates[i].Description,
+			Tags:        []string{},
+			Featured:    true,
+			TreeData:    tree,
+		})
 	}
-	return summaries
+	return templates, nil
 }
 
 // BuildTemplateTree builds a valid RepertoireNode tree from the template moves
-func BuildTemplateTree(tmpl *RepertoireTemplate) (models.RepertoireNode, error) {
+func BuildTemplateTree(tmpl *StarterTemplate) (models.RepertoireNode, error) {
 	game := chess.NewGame()
 
 	startFEN := normalizeFEN(game.Position().String())
@@ -186,3 +178,134 @@ func BuildTemplateTree(tmpl *RepertoireTemplate) (models.RepertoireNode, error)
 
 	return root, nil
 }
+
+// WithTemplates stores templates in repo; without it only the built-in starters are served
+func (s *RepertoireService) WithTemplates(repo repository.TemplateRepository) {
+	s.templateRepo = repo
+}
+
+// SeedBuiltinTemplates stores the built-in starters that are not in the template table yet
+func (s *RepertoireService) SeedBuiltinTemplates() error {
+	if s.templateRepo == nil {
+		return nil
+	}
+	templates, err := BuiltinTemplates()
+	if err != nil {
+		return err
+	}
+	return s.templateRepo.EnsureBuiltin(templates)
+}
+
+// ListTemplates returns the featured templates and the user's own templates
+func (s *RepertoireService) ListTemplates(userID string) ([]models.RepertoireTemplate, error) {
+	if s.templateRepo == nil {
+		return BuiltinTemplates()
+	}
+	return s.templateRepo.List(userID)
+}
+
+// getTemplate returns a template the user may seed from: a featured one or one of their own
+func (s *RepertoireService) getTemplate(userID, id string) (*models.RepertoireTemplate, error) {
+	if s.templateRepo == nil {
+		templates, err := BuiltinTemplates()
+		if err != nil {
+			return nil, err
+		}
+		for i := range templates {
+			if templates[i].ID == id {
+				return &templates[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+
+	tmpl, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+		}
+		return nil, err
+	}
+	if !tmpl.Featured && (tmpl.UserID == nil || *tmpl.UserID != userID) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	return tmpl, nil
+}
+
+// PublishTemplate saves a copy of one of the user's repertoires as a template.
+// It is listed to the user only until an admin features it.
+func (s *RepertoireService) PublishTemplate(userID string, req models.PublishTemplateRequest) (*models.RepertoireTemplate, error) {
+	if s.templateRepo == nil {
+		return nil, ErrTemplatesUnavailable
+	}
+
+	if err := s.CheckOwnership(req.RepertoireID, userID); err != nil {
+		return nil, err
+	}
+	rep, err := s.GetRepertoire(req.RepertoireID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = rep.Name
+	}
+	if len(name) > config.MaxRepertoireNameLen {
+		return nil, ErrNameTooLong
+	}
+	description := strings.TrimSpace(req.Description)
+	if len(description) > config.MaxTemplateDescriptionLen {
+		return nil, ErrDescriptionTooLong
+	}
+	tags, err := normalizeTags(req.Tags, config.MaxTemplateTags)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templateRepo.Create(models.RepertoireTemplate{
+		ID:          uuid.New().String(),
+		UserID:      &userID,
+		Name:        name,
+		Color:       rep.Color,
+		Description: description,
+		Tags:        tags,
+		TreeData:    *deepCloneSubtree(&rep.TreeData, nil),
+	})
+}
+
+// SetTemplateFeatured adds a template to or removes it from the list served to every user
+func (s *RepertoireService) SetTemplateFeatured(id string, featured bool) (*models.RepertoireTemplate, error) {
+	if s.templateRepo == nil {
+		return nil, ErrTemplatesUnavailable
+	}
+	tmpl, err := s.templateRepo.SetFeatured(id, featured)
+	if err != nil {
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+		}
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// normalizeTags trims and lowercases tags, dropping empty and duplicate ones
+func normalizeTags(tags []string, maxTags int) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > config.MaxTagLen {
+			return nil, fmt.Errorf("%w: %s", ErrTagTooLong, tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, ErrTooManyTags
+	}
+	return normalized, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"log"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// WithGameResultRepo records, for every saved game, the repertoire nodes it reached so
+// results can be overlaid on the repertoire tree
+func WithGameResultRepo(repo repository.GameResultRepository) ImportServiceOption {
+	return func(s *ImportService) {
+		s.gameResultRepo = repo
+	}
+}
+
+// ResultsOverlay returns the user's score in their imported games for every node of a repertoire
+func (s *ImportService) ResultsOverlay(userID, repertoireID string) (*models.ResultsOverlay, error) {
+	if err := s.repertoireService.CheckReadAccess(repertoireID, userID); err != nil {
+		return nil, err
+	}
+
+	overlay := &models.ResultsOverlay{RepertoireID: repertoireID, Nodes: []models.NodeResult{}}
+	if s.gameResultRepo == nil {
+		return overlay, nil
+	}
+
+	nodes, err := s.gameResultRepo.Overlay(repertoireID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes {
+		if nodes[i].Games > 0 {
+			nodes[i].Score = (float64(nodes[i].Wins) + float64(nodes[i].Draws)/2) / float64(nodes[i].Games)
+		}
+	}
+	overlay.Nodes = nodes
+	return overlay, nil
+}
+
+// recordGameResults stores the nodes reached by freshly saved games. Failures are logged, not
+// returned: the overlay is a derived view and must not fail an import.
+func (s *ImportService) recordGameResults(analysisID string, games []models.GameAnalysis, repertoires map[string]*models.Repertoire) {
+	if s.gameResultRepo == nil {
+		return
+	}
+
+	var entries []repository.GameResultEntry
+	for _, game := range games {
+		if game.MatchedRepertoire == nil {
+			continue
+		}
+		entries = append(entries, gameResultEntries(game, repertoires[game.MatchedRepertoire.ID])...)
+	}
+	if err := s.gameResultRepo.SaveBatch(analysisID, entries); err != nil {
+		log.Printf("warning: failed to save game results: %v", err)
+	}
+}
+
+// replaceGameResults re-records the nodes reached by a game after it was re-analyzed
+func (s *ImportService) replaceGameResults(analysisID string, game models.GameAnalysis, repertoire *models.Repertoire) {
+	if s.gameResultRepo == nil {
+		return
+	}
+	if err := s.gameResultRepo.ReplaceGame(analysisID, game.GameIndex, gameResultEntries(game, repertoire)); err != nil {
+		log.Printf("warning: failed to replace game results: %v", err)
+	}
+}
+
+// gameResultEntries walks the game's moves down the repertoire tree, from the root until the
+// game leaves the repertoire. Transpositions into another branch are not followed.
+func gameResultEntries(game models.GameAnalysis, repertoire *models.Repertoire) []repository.GameResultEntry {
+	outcome := gameOutcome(game.Headers["Result"], game.UserColor)
+	if repertoire == nil || outcome == "" {
+		return nil
+	}
+
+	node := &repertoire.TreeData
+	entries := []repository.GameResultEntry{{
+		GameIndex:    game.GameIndex,
+		RepertoireID: repertoire.ID,
+		NodeID:       node.ID,
+		Outcome:      outcome,
+	}}
+	for _, move := range game.Moves {
+		var next *models.RepertoireNode
+		for _, child := range node.Children {
+			if child.Move != nil && *child.Move == move.SAN {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+		entries = append(entries, repository.GameResultEntry{
+			GameIndex:    game.GameIndex,
+			RepertoireID: repertoire.ID,
+			NodeID:       node.ID,
+			Outcome:      outcome,
+		})
+	}
+	return entries
+}
+
+// gameOutcome converts a PGN result to the user's point of view; unfinished games yield ""
+func gameOutcome(result string, userColor models.Color) string {
+	var winner models.Color
+	switch result {
+	case "1-0":
+		winner = models.ColorWhite
+	case "0-1":
+		winner = models.ColorBlack
+	case "1/2-1/2":
+		return "draw"
+	default:
+		return ""
+	}
+	if winner == userColor {
+		return "win"
+	}
+	return "loss"
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestGameOutcome(t *testing.T) {
+	tests := []struct {
+		result string
+		color  models.Color
+		want   string
+	}{
+		{"1-0", models.ColorWhite, "win"},
+		{"1-0", models.ColorBlack, "loss"},
+		{"0-1", models.ColorBlack, "win"},
+		{"1/2-1/2", models.ColorWhite, "draw"},
+		{"*", models.ColorWhite, ""},
+		{"", models.ColorBlack, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, gameOutcome(tt.result, tt.color), "%s as %s", tt.result, tt.color)
+	}
+}
+
+func TestGameResultEntries_StopsWhenLeavingRepertoire(t *testing.T) {
+	game := models.GameAnalysis{
+		GameIndex: 3,
+		Headers:   models.PGNHeaders{"Result": "0-1"},
+		UserColor: models.ColorWhite,
+		Moves:     []models.MoveAnalysis{{SAN: "e4"}, {SAN: "c5"}, {SAN: "Nc3"}, {SAN: "d6"}},
+	}
+
+	entries := gameResultEntries(game, newTaggedRepertoire())
+
+	require.Len(t, entries, 3)
+	nodeIDs := []string{entries[0].NodeID, entries[1].NodeID, entries[2].NodeID}
+	assert.Equal(t, []string{"root", "e4", "c5"}, nodeIDs)
+	for _, e := range entries {
+		assert.Equal(t, 3, e.GameIndex)
+		assert.Equal(t, "rep-1", e.RepertoireID)
+		assert.Equal(t, "loss", e.Outcome)
+	}
+}
+
+func TestGameResultEntries_SkipsUnfinishedGames(t *testing.T) {
+	game := models.GameAnalysis{
+		Headers:   models.PGNHeaders{"Result": "*"},
+		UserColor: models.ColorWhite,
+		Moves:     []models.MoveAnalysis{{SAN: "e4"}},
+	}
+
+	assert.Empty(t, gameResultEntries(game, newTaggedRepertoire()))
+	assert.Empty(t, gameResultEntries(game, nil))
+}
+
+func TestRecordGameResults_OnlyMatchedGames(t *testing.T) {
+	var saved []repository.GameResultEntry
+	svc := NewImportService(nil, nil, WithGameResultRepo(&mocks.MockGameResultRepo{
+		SaveBatchFunc: func(analysisID string, entries []repository.GameResultEntry) error {
+			assert.Equal(t, "analysis-1", analysisID)
+			saved = entries
+			return nil
+		},
+	}))
+	rep := newTaggedRepertoire()
+	games := []models.GameAnalysis{
+		{
+			GameIndex: 0, Headers: models.PGNHeaders{"Result": "1-0"}, UserColor: models.ColorWhite,
+			Moves:             []models.MoveAnalysis{{SAN: "e4"}, {SAN: "e5"}},
+			MatchedRepertoire: &models.RepertoireRef{ID: rep.ID, Name: rep.Name},
+		},
+		{
+			GameIndex: 1, Headers: models.PGNHeaders{"Result": "1-0"}, UserColor: models.ColorWhite,
+			Moves: []models.MoveAnalysis{{SAN: "d4"}},
+		},
+	}
+
+	svc.recordGameResults("analysis-1", games, map[string]*models.Repertoire{rep.ID: rep})
+
+	require.Len(t, saved, 3)
+	assert.Equal(t, "e5", saved[2].NodeID)
+	assert.Equal(t, "win", saved[2].Outcome)
+}
+
+func TestResultsOverlay_ComputesScore(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+	svc := NewImportService(repSvc, nil, WithGameResultRepo(&mocks.MockGameResultRepo{
+		OverlayFunc: func(repertoireID, userID string) ([]models.NodeResult, error) {
+			return []models.NodeResult{{NodeID: "root", Games: 4, Wins: 2, Draws: 1, Losses: 1}}, nil
+		},
+	}))
+
+	overlay, err := svc.ResultsOverlay("user-1", "rep-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "rep-1", overlay.RepertoireID)
+	require.Len(t, overlay.Nodes, 1)
+	assert.InDelta(t, 0.625, overlay.Nodes[0].Score, 0.0001)
+}
+
+func TestResultsOverlay_NotFound(t *testing.T) {
+	repSvc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		BelongsToUserFunc: func(id, userID string) (bool, error) { return false, nil },
+	})
+	svc := NewImportService(repSvc, nil, WithGameResultRepo(&mocks.MockGameResultRepo{}))
+
+	_, err := svc.ResultsOverlay("user-1", "rep-1")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// pieceNotation maps one language's piece letters to English SAN letters
+type pieceNotation struct {
+	name    string
+	letters map[rune]rune
+}
+
+// localizedNotations lists the piece letter sets accepted in uploads, English first.
+// Languages sharing a set (German, Swedish, Danish, Norwegian...) appear once.
+var localizedNotations = []pieceNotation{
+	{"english", map[rune]rune{'K': 'K', 'Q': 'Q', 'R': 'R', 'B': 'B', 'N': 'N'}},
+	{"german", map[rune]rune{'K': 'K', 'D': 'Q', 'T': 'R', 'L': 'B', 'S': 'N'}},
+	{"dutch", map[rune]rune{'K': 'K', 'D': 'Q', 'T': 'R', 'L': 'B', 'P': 'N'}},
+	{"french", map[rune]rune{'R': 'K', 'D': 'Q', 'T': 'R', 'F': 'B', 'C': 'N'}},
+	{"spanish", map[rune]rune{'R': 'K', 'D': 'Q', 'T': 'R', 'A': 'B', 'C': 'N'}},
+	{"polish", map[rune]rune{'K': 'K', 'H': 'Q', 'W': 'R', 'G': 'B', 'S': 'N'}},
+	{"czech", map[rune]rune{'K': 'K', 'D': 'Q', 'V': 'R', 'S': 'B', 'J': 'N'}},
+}
+
+// figurines maps figurine algebraic notation symbols to English piece letters; pawns have none
+var figurines = map[rune]string{
+	'♔': "K", '♕': "Q", '♖': "R", '♗': "B", '♘': "N", '♙': "",
+	'♚': "K", '♛': "Q", '♜': "R", '♝': "B", '♞': "N", '♟': "",
+}
+
+// castlingZeroRe matches short castling written with zeros, e.g. "5. 0-0" or "5...0-0+"
+var castlingZeroRe = regexp.MustCompile(`(^|[\s.(])0-0([\s+#!?)]|$)`)
+
+// localizedSANCandidates returns the English readings of a single SAN move, most likely first.
+// The move itself (with figurines and zero-castling normalized) is always the first candidate.
+func localizedSANCandidates(san string) []string {
+	san = normalizeFigurines(strings.TrimSpace(san))
+	return translateCandidates(san, sanPieceLetters(san), func(table map[rune]rune) string {
+		return translateSAN(san, table)
+	})
+}
+
+// localizedPGNCandidates returns the English readings of a single-game PGN, most likely first.
+// Only movetext outside comments is translated; tags and comments are kept as they are.
+func localizedPGNCandidates(rawGame string) []string {
+	rawGame = normalizeFigurines(rawGame)
+
+	seen := make(map[rune]bool)
+	forEachMovetextToken(rawGame, func(tok string) string {
+		for r := range sanPieceLetters(tok) {
+			seen[r] = true
+		}
+		return tok
+	})
+
+	return translateCandidates(rawGame, seen, func(table map[rune]rune) string {
+		return forEachMovetextToken(rawGame, func(tok string) string {
+			return translateSAN(tok, table)
+		})
+	})
+}
+
+// translateCandidates applies every notation covering all seen piece letters, skipping duplicates
+func translateCandidates(original string, seen map[rune]bool, translate func(map[rune]rune) string) []string {
+	candidates := []string{original}
+	for _, notation := range localizedNotations[1:] {
+		if !coversLetters(notation.letters, seen) {
+			continue
+		}
+		translated := translate(notation.letters)
+		duplicate := false
+		for _, c := range candidates {
+			if c == translated {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			candidates = append(candidates, translated)
+		}
+	}
+	return candidates
+}
+
+func coversLetters(table map[rune]rune, seen map[rune]bool) bool {
+	for r := range seen {
+		if _, ok := table[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeFigurines replaces figurine symbols by English letters and 0-0 castling by O-O
+func normalizeFigurines(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if letter, ok := figurines[r]; ok {
+			b.WriteString(letter)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	text = strings.ReplaceAll(b.String(), "0-0-0", "O-O-O")
+	// Replace castling but not the 1-0 / 0-1 results or move numbers such as 10.
+	return castlingZeroRe.ReplaceAllString(text, "${1}O-O${2}")
+}
+
+// sanPieceLetters returns the uppercase piece letters used by a SAN token (piece and promotion)
+func sanPieceLetters(tok string) map[rune]bool {
+	letters := make(map[rune]bool)
+	_, move := splitMoveNumber(tok)
+	if move == "" || strings.HasPrefix(move, "O-O") {
+		return letters
+	}
+	runes := []rune(move)
+	if unicode.IsUpper(runes[0]) {
+		letters[runes[0]] = true
+	}
+	if promo, ok := promotionIndex(runes); ok {
+		letters[runes[promo]] = true
+	}
+	return letters
+}
+
+// translateSAN rewrites the piece and promotion letters of a SAN token with table
+func translateSAN(tok string, table map[rune]rune) string {
+	number, move := splitMoveNumber(tok)
+	if move == "" || strings.HasPrefix(move, "O-O") {
+		return tok
+	}
+	runes := []rune(move)
+	if english, ok := table[runes[0]]; ok && unicode.IsUpper(runes[0]) {
+		runes[0] = english
+	}
+	if promo, ok := promotionIndex(runes); ok {
+		if english, ok := table[runes[promo]]; ok {
+			runes[promo] = english
+		}
+	}
+	return number + string(runes)
+}
+
+// promotionIndex finds the promotion piece of a pawn move such as e8=D or e8D
+func promotionIndex(runes []rune) (int, bool) {
+	for i := 1; i < len(runes); i++ {
+		if (runes[i-1] == '8' || runes[i-1] == '1' || runes[i-1] == '=') && unicode.IsUpper(runes[i]) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// splitMoveNumber separates a leading move number ("12." or "12...") from a token
+func splitMoveNumber(tok string) (string, string) {
+	i := 0
+	for i < len(tok) && tok[i] >= '0' && tok[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(tok) || tok[i] != '.' {
+		if i > 0 {
+			// Results and bare numbers are not moves
+			return tok, ""
+		}
+		return "", tok
+	}
+	for i < len(tok) && tok[i] == '.' {
+		i++
+	}
+	return tok[:i], tok[i:]
+}
+
+// forEachMovetextToken rebuilds text with every movetext token replaced by fn(token).
+// Tag pair lines, {comments}, ; comments and $NAGs are copied unchanged.
+func forEachMovetextToken(text string, fn func(string) string) string {
+	var b strings.Builder
+	lines := strings.SplitAfter(text, "\n")
+	inComment := false
+	for _, line := range lines {
+		if !inComment && strings.HasPrefix(strings.TrimSpace(line), "[") {
+			b.WriteString(line)
+			continue
+		}
+
+		var tok strings.Builder
+		flush := func() {
+			if tok.Len() > 0 {
+				b.WriteString(fn(tok.String()))
+				tok.Reset()
+			}
+		}
+		runes := []rune(line)
+		for i := 0; i < len(runes); i++ {
+			r := runes[i]
+			switch {
+			case inComment:
+				b.WriteRune(r)
+				if r == '}' {
+					inComment = false
+				}
+			case r == '{':
+				flush()
+				inComment = true
+				b.WriteRune(r)
+			case r == ';':
+				flush()
+				b.WriteString(string(runes[i:]))
+				i = len(runes)
+			case unicode.IsSpace(r) || r == '(' || r == ')':
+				flush()
+				b.WriteRune(r)
+			default:
+				tok.WriteRune(r)
+			}
+		}
+		flush()
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestNormalizeMove_LocalizedAndFigurine(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	tests := []struct {
+		name string
+		fen  string
+		san  string
+		want string
+	}{
+		{"english", startFEN, "Nf3", "Nf3"},
+		{"german knight", startFEN, "Sf3", "Nf3"},
+		{"french knight", startFEN, "Cf3", "Nf3"},
+		{"dutch knight", startFEN, "Pf3", "Nf3"},
+		{"figurine knight", startFEN, "♘f3", "Nf3"},
+		{"figurine pawn", startFEN, "♙e4", "e4"},
+		// S is a knight in German and a bishop in Czech; the legal reading wins
+		{"czech bishop", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "Sc4", "Bc4"},
+		{"zero castling", "r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", "0-0", "O-O"},
+		{"german promotion", "8/4P3/8/8/8/8/k7/7K w - - 0 1", "e8=D", "e8=Q"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.NormalizeMove(tt.fen, tt.san)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeMove_Invalid(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	_, err := svc.NormalizeMove(startFEN, "Xf3")
+	assert.Error(t, err)
+}
+
+func TestParsePGN_GermanNotation(t *testing.T) {
+	svc := NewImportService(nil, nil)
+	pgn := `[Event "Vereinsabend"]
+[White "Müller"]
+[Black "Schmidt"]
+[Result "1-0"]
+
+1. e4 e5 2. Sf3 Sc6 3. Lb5 a6 {Die Spanische Partie} 4. La4 Sf6 5. 0-0 Le7 6. Te1 b5 7. Lb3 d6 8. c3 0-0 9. h3 Dd7 1-0`
+
+	games, annotations, err := svc.parsePGNWithAnnotations(pgn)
+
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Len(t, games[0].Moves(), 18)
+	assert.Equal(t, "Die Spanische Partie", annotations[0][5].comment)
+}
+
+func TestParsePGN_FrenchNotation(t *testing.T) {
+	svc := NewImportService(nil, nil)
+	pgn := `[Event "Partie"]
+[Result "*"]
+
+1. e4 e5 2. Cf3 Cc6 3. Fc4 Fc5 4. Re2 *`
+
+	games, err := svc.parsePGN(pgn)
+
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Len(t, games[0].Moves(), 7)
+}
+
+func TestParsePGN_FigurineNotation(t *testing.T) {
+	svc := NewImportService(nil, nil)
+	pgn := `[Event "Figurine"]
+[Result "*"]
+
+1. e4 e5 2. ♘f3 ♞c6 3. ♗b5 *`
+
+	games, err := svc.parsePGN(pgn)
+
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Len(t, games[0].Moves(), 5)
+}
+
+func TestLocalizedPGNCandidates_KeepsTagsAndComments(t *testing.T) {
+	pgn := "[Site \"Sf3 Dd1\"]\n\n1. Sf3 {Springer nach f3, Dame bleibt} d5 1-0"
+
+	candidates := localizedPGNCandidates(pgn)
+
+	require.GreaterOrEqual(t, len(candidates), 2)
+	assert.Equal(t, pgn, candidates[0])
+	assert.Equal(t, "[Site \"Sf3 Dd1\"]\n\n1. Nf3 {Springer nach f3, Dame bleibt} d5 1-0", candidates[1])
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+var (
+	ErrSparringSessionNotFound = fmt.Errorf("sparring session %w", ErrNotFound)
+	ErrSparringOver            = fmt.Errorf("sparring game is over")
+	ErrInvalidSparringRating   = fmt.Errorf("rating must be between 0 and %d", config.MaxOpponentRating)
+)
+
+// SparringService plays the opponent's side of a repertoire: it answers the user's moves with
+// replies sampled from the Explorer and checks the user's moves against the repertoire.
+// Sessions live in memory and expire after config.SparringSessionTTL without a move.
+type SparringService struct {
+	repertoireService *RepertoireService
+	explorer          SparringExplorer
+	now               func() time.Time
+	intn              func(n int) int
+
+	mu       sync.Mutex
+	sessions map[string]*sparringSession
+}
+
+type sparringSession struct {
+	userID    string
+	startedAt time.Time
+	expiresAt time.Time // guarded by SparringService.mu
+
+	// mu serializes the moves of the session
+	mu sync.Mutex
+	// node is the position reached in the repertoire tree, nil once the game left it
+	node  *models.RepertoireNode
+	state models.SparringSession
+}
+
+// NewSparringService creates a new sparring service
+func NewSparringService(repertoireSvc *RepertoireService, explorer SparringExplorer) *SparringService {
+	return &SparringService{
+		repertoireService: repertoireSvc,
+		explorer:          explorer,
+		now:               time.Now,
+		intn:              rand.Intn,
+		sessions:          make(map[string]*sparringSession),
+	}
+}
+
+// Start begins a sparring game on a repertoire. When the user plays black, the opponent's first
+// move is already played in the returned session.
+func (s *SparringService) Start(userID string, req models.SparringStartRequest) (*models.SparringSession, error) {
+	if req.Rating < 0 || req.Rating > config.MaxOpponentRating {
+		return nil, ErrInvalidSparringRating
+	}
+	rep, err := s.repertoireService.getRepertoireTree(req.RepertoireID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	session := &sparringSession{
+		userID:    userID,
+		startedAt: now,
+		expiresAt: now.Add(config.SparringSessionTTL),
+		node:      &rep.TreeData,
+		state: models.SparringSession{
+			ID:           uuid.New().String(),
+			RepertoireID: rep.ID,
+			Color:        rep.Color,
+			Ratings:      sparringRatings(req.Rating),
+			FEN:          rep.TreeData.FEN,
+			Moves:        []string{},
+			Status:       models.SparringActive,
+			ExpiresAt:    now.Add(config.SparringSessionTTL),
+		},
+	}
+	if len(rep.TreeData.Children) == 0 {
+		session.state.Status = models.SparringCompleted
+	} else if rep.TreeData.ColorToMove != userColorToMove(rep.Color) {
+		s.playOpponent(session)
+	}
+
+	s.store(session, now)
+	return sparringSnapshot(session), nil
+}
+
+// Move plays the user's move in a sparring game and the opponent's reply. A move the repertoire
+// does not have ends the game and reveals the expected moves.
+func (s *SparringService) Move(userID string, req models.SparringMoveRequest) (*models.SparringSession, error) {
+	move := strings.TrimSpace(req.Move)
+	if move == "" {
+		return nil, fmt.Errorf("%w: move is required", ErrInvalidMove)
+	}
+	session, expiresAt, err := s.get(userID, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.state.Status != models.SparringActive {
+		return nil, ErrSparringOver
+	}
+	fen, err := validateAndGetResultingFEN(session.state.FEN, move)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMove, err)
+	}
+
+	state := &session.state
+	state.OpponentMove = nil
+	state.Moves = append(state.Moves, move)
+	state.FEN = fen
+	state.ExpiresAt = expiresAt
+
+	child := childWithFEN(session.node, fen)
+	if child == nil {
+		state.Score.Deviations++
+		state.Status = models.SparringDeviated
+		for _, c := range session.node.Children {
+			if c.Move != nil {
+				state.ExpectedMoves = append(state.ExpectedMoves, *c.Move)
+			}
+		}
+		session.node = nil
+	} else {
+		state.Score.RepertoireMoves++
+		session.node = child
+		if len(child.Children) == 0 {
+			state.Status = models.SparringCompleted
+		} else {
+			s.playOpponent(session)
+		}
+	}
+	state.Score.Accuracy = sparringAccuracy(state.Score)
+
+	return sparringSnapshot(session), nil
+}
+
+// playOpponent answers from the session's node with a move sampled from the Explorer by how
+// often it is played, or from the repertoire when the Explorer has no games for the position
+func (s *SparringService) playOpponent(session *sparringSession) {
+	state := &session.state
+	node := session.node
+
+	var candidates []string
+	var weights []int
+	moves, err := s.explorer.ExplorerMovesFor(session.userID, node.FEN, state.Ratings)
+	if err != nil {
+		log.Printf("sparring: explorer lookup failed for %s: %v", node.FEN, err)
+	}
+	for _, m := range moves {
+		if games := m.White + m.Draws + m.Black; games > 0 {
+			candidates = append(candidates, m.SAN)
+			weights = append(weights, games)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, child := range node.Children {
+			if child.Move != nil {
+				candidates = append(candidates, *child.Move)
+				weights = append(weights, 1)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		state.Status = models.SparringCompleted
+		return
+	}
+
+	reply := candidates[s.weightedIndex(weights)]
+	fen, err := validateAndGetResultingFEN(node.FEN, reply)
+	if err != nil {
+		log.Printf("sparring: explorer move %s is illegal in %s: %v", reply, node.FEN, err)
+		state.Status = models.SparringCompleted
+		return
+	}
+	state.OpponentMove = &reply
+	state.Moves = append(state.Moves, reply)
+	state.FEN = fen
+
+	child := childWithFEN(node, fen)
+	switch {
+	case child == nil:
+		state.Status = models.SparringOutOfBook
+		session.node = nil
+	case len(child.Children) == 0:
+		state.Status = models.SparringCompleted
+		session.node = child
+	default:
+		session.node = child
+	}
+}
+
+func (s *SparringService) weightedIndex(weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	pick := s.intn(total)
+	for i, w := range weights {
+		if pick < w {
+			return i
+		}
+		pick -= w
+	}
+	return len(weights) - 1
+}
+
+// store keeps a new session, dropping expired ones and the user's oldest beyond config.MaxSparringSessions
+func (s *SparringService) store(session *sparringSession, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var own []*sparringSession
+	for id, existing := range s.sessions {
+		if !now.Before(existing.expiresAt) {
+			delete(s.sessions, id)
+			continue
+		}
+		if existing.userID == session.userID {
+			own = append(own, existing)
+		}
+	}
+	if excess := len(own) - config.MaxSparringSessions + 1; excess > 0 {
+		sort.Slice(own, func(i, j int) bool { return own[i].startedAt.Before(own[j].startedAt) })
+		for _, old := range own[:excess] {
+			delete(s.sessions, old.state.ID)
+		}
+	}
+	s.sessions[session.state.ID] = session
+}
+
+// get returns a live session of the user and extends its life. Sessions of other users are
+// reported as not found.
+func (s *SparringService) get(userID, sessionID string) (*sparringSession, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.userID != userID {
+		return nil, time.Time{}, ErrSparringSessionNotFound
+	}
+	now := s.now()
+	if !now.Before(session.expiresAt) {
+		delete(s.sessions, sessionID)
+		return nil, time.Time{}, ErrSparringSessionNotFound
+	}
+	session.expiresAt = now.Add(config.SparringSessionTTL)
+	return session, session.expiresAt, nil
+}
+
+// sparringSnapshot copies the state of a session so it can be returned while the session goes on
+func sparringSnapshot(session *sparringSession) *models.SparringSession {
+	state := session.state
+	state.Moves = append([]string{}, state.Moves...)
+	state.ExpectedMoves = append([]string(nil), state.ExpectedMoves...)
+	return &state
+}
+
+// sparringRatings picks the Explorer rating bands around a rating: the band it falls in and the
+// next one up. A rating of 0 keeps the default bands.
+func sparringRatings(rating int) string {
+	if rating == 0 {
+		return explorerRatings
+	}
+	band := 0
+	for i, option := range explorerRatingOptions {
+		if lower, _ := strconv.Atoi(option); rating >= lower {
+			band = i
+		}
+	}
+	bands := explorerRatingOptions[band:]
+	if len(bands) > 2 {
+		bands = bands[:2]
+	}
+	return strings.Join(bands, ",")
+}
+
+func sparringAccuracy(score models.SparringScore) int {
+	played := score.RepertoireMoves + score.Deviations
+	if played == 0 {
+		return 0
+	}
+	return score.RepertoireMoves * 100 / played
+}
+
+func userColorToMove(color models.Color) models.ChessColor {
+	if color == models.ColorBlack {
+		return models.ChessColorBlack
+	}
+	return models.ChessColorWhite
+}
+
+// childWithFEN finds the child of a node reaching a position, whatever notation its move was stored in
+func childWithFEN(node *models.RepertoireNode, fen string) *models.RepertoireNode {
+	for _, child := range node.Children {
+		if child.FEN == fen {
+			return child
+		}
+	}
+	return nil
+}
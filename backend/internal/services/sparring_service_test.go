@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+type fakeSparringExplorer struct {
+	moves   map[string][]models.ExplorerMove
+	ratings []string
+}
+
+func (f *fakeSparringExplorer) ExplorerMovesFor(userID, fen, ratings string) ([]models.ExplorerMove, error) {
+	f.ratings = append(f.ratings, ratings)
+	moves, ok := f.moves[fen]
+	if !ok {
+		return nil, errors.New("no explorer data")
+	}
+	return moves, nil
+}
+
+func newSparringTestService(t *testing.T, color models.Color, explorer *fakeSparringExplorer, lines ...string) *SparringService {
+	tree := newLabelTestTree(t, lines...)
+	tree.ColorToMove = models.ChessColorWhite
+	repo := &mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: color, TreeData: tree}, nil
+		},
+	}
+	svc := NewSparringService(NewRepertoireService(repo), explorer)
+	svc.intn = func(n int) int { return 0 }
+	return svc
+}
+
+func TestSparring_FollowsRepertoire(t *testing.T) {
+	explorer := &fakeSparringExplorer{}
+	svc := newSparringTestService(t, models.ColorWhite, explorer, "e4 e5 Nf3 Nc6 Bb5")
+
+	session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1", Rating: 1500})
+	require.NoError(t, err)
+	assert.Equal(t, models.SparringActive, session.Status)
+	assert.Empty(t, session.Moves)
+
+	// Without Explorer data the opponent plays its repertoire moves
+	session, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "e4"})
+	require.NoError(t, err)
+	require.NotNil(t, session.OpponentMove)
+	assert.Equal(t, "e5", *session.OpponentMove)
+	assert.Equal(t, []string{"1400,1600"}, explorer.ratings)
+
+	session, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "Nf3"})
+	require.NoError(t, err)
+	session, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "Bb5"})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.SparringCompleted, session.Status)
+	assert.Equal(t, []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}, session.Moves)
+	assert.Equal(t, models.SparringScore{RepertoireMoves: 3, Accuracy: 100}, session.Score)
+
+	_, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "a3"})
+	assert.ErrorIs(t, err, ErrSparringOver)
+}
+
+func TestSparring_Deviation(t *testing.T) {
+	svc := newSparringTestService(t, models.ColorWhite, &fakeSparringExplorer{}, "e4 e5 Nf3", "e4 c5 Nc3")
+
+	session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1"})
+	require.NoError(t, err)
+	session, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "e4"})
+	require.NoError(t, err)
+	session, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "d4"})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.SparringDeviated, session.Status)
+	assert.Equal(t, []string{"Nf3"}, session.ExpectedMoves)
+	assert.Equal(t, models.SparringScore{RepertoireMoves: 1, Deviations: 1, Accuracy: 50}, session.Score)
+}
+
+func TestSparring_ExplorerReplyOutOfBook(t *testing.T) {
+	tree := newLabelTestTree(t, "e4 e5")
+	explorer := &fakeSparringExplorer{moves: map[string][]models.ExplorerMove{
+		tree.Children[0].FEN: {
+			{SAN: "c6", White: 3, Draws: 1, Black: 1},
+			{SAN: "e5", White: 50, Draws: 20, Black: 30},
+		},
+	}}
+	svc := newSparringTestService(t, models.ColorWhite, explorer, "e4 e5 Nf3", "e4 c5 Nf3")
+
+	session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1"})
+	require.NoError(t, err)
+	session, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "e4"})
+	require.NoError(t, err)
+
+	// c6 is sampled first with the lowest draw, though the repertoire has no answer to it
+	assert.Equal(t, "c6", *session.OpponentMove)
+	assert.Equal(t, models.SparringOutOfBook, session.Status)
+}
+
+func TestSparring_OpponentMovesFirstForBlack(t *testing.T) {
+	svc := newSparringTestService(t, models.ColorBlack, &fakeSparringExplorer{}, "d4 d5 c4 e6")
+
+	session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d4"}, session.Moves)
+	assert.Equal(t, "d4", *session.OpponentMove)
+}
+
+func TestSparring_InvalidMoves(t *testing.T) {
+	svc := newSparringTestService(t, models.ColorWhite, &fakeSparringExplorer{}, "e4 e5")
+	session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1"})
+	require.NoError(t, err)
+
+	_, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "e5"})
+	assert.ErrorIs(t, err, ErrInvalidMove)
+	_, err = svc.Move("user-2", models.SparringMoveRequest{SessionID: session.ID, Move: "e4"})
+	assert.ErrorIs(t, err, ErrSparringSessionNotFound)
+	_, err = svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1", Rating: -1})
+	assert.ErrorIs(t, err, ErrInvalidSparringRating)
+}
+
+func TestSparring_SessionsExpire(t *testing.T) {
+	svc := newSparringTestService(t, models.ColorWhite, &fakeSparringExplorer{}, "e4 e5 Nf3")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1"})
+	require.NoError(t, err)
+
+	now = now.Add(config.SparringSessionTTL)
+	_, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: session.ID, Move: "e4"})
+	assert.ErrorIs(t, err, ErrSparringSessionNotFound)
+}
+
+func TestSparring_DropsOldestSessionsOverLimit(t *testing.T) {
+	svc := newSparringTestService(t, models.ColorWhite, &fakeSparringExplorer{}, "e4 e5 Nf3")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	var ids []string
+	for i := 0; i <= config.MaxSparringSessions; i++ {
+		now = now.Add(time.Second)
+		session, err := svc.Start("user-1", models.SparringStartRequest{RepertoireID: "rep-1"})
+		require.NoError(t, err)
+		ids = append(ids, session.ID)
+	}
+
+	_, err := svc.Move("user-1", models.SparringMoveRequest{SessionID: ids[0], Move: "e4"})
+	assert.ErrorIs(t, err, ErrSparringSessionNotFound)
+	_, err = svc.Move("user-1", models.SparringMoveRequest{SessionID: ids[len(ids)-1], Move: "e4"})
+	assert.NoError(t, err)
+}
+
+func TestSparringRatings(t *testing.T) {
+	assert.Equal(t, explorerRatings, sparringRatings(0))
+	assert.Equal(t, "0,1000", sparringRatings(800))
+	assert.Equal(t, "1400,1600", sparringRatings(1500))
+	assert.Equal(t, "2200,2500", sparringRatings(2400))
+	assert.Equal(t, "2500", sparringRatings(2800))
+}
@@ -7,6 +7,8 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/google/uuid"
+
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository"
 )
@@ -55,6 +57,20 @@ func ParseStudyURL(rawURL string) (studyID, chapterID string, err error) {
 // PreviewStudy fetches a Lichess study and returns metadata about its chapters
 // without creating any repertoires.
 func (s *StudyImportService) PreviewStudy(studyID, authToken string) (*models.StudyInfo, error) {
+	return s.previewStudy(studyID, authToken, nil)
+}
+
+// PreviewStudyInto previews a study like PreviewStudy, also counting for each chapter the moves
+// it would add when merged into the user's repertoire targetID.
+func (s *StudyImportService) PreviewStudyInto(userID, studyID, authToken, targetID string) (*models.StudyInfo, error) {
+	target, err := s.mergeTarget(userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return s.previewStudy(studyID, authToken, target)
+}
+
+func (s *StudyImportService) previewStudy(studyID, authToken string, target *models.Repertoire) (*models.StudyInfo, error) {
 	pgnData, err := s.lichessService.FetchStudyPGN(studyID, authToken)
 	if err != nil {
 		return nil, err
@@ -103,12 +119,25 @@ func (s *StudyImportService) PreviewStudy(studyID, authToken string) (*models.St
 			}
 		}
 
-		chapterInfos = append(chapterInfos, models.StudyChapterInfo{
+		info := models.StudyChapterInfo{
 			Index:       i,
 			Name:        name,
 			Orientation: orientation,
 			MoveCount:   moveCount,
-		})
+		}
+		root, _, err := ParsePGNToTree(chapterPGN)
+		switch {
+		case errors.Is(err, ErrCustomStartingPosition):
+			info.Unsupported = true
+		case err == nil:
+			info.NodeCount = countNodes(&root) - 1
+			if target != nil {
+				tree := target.TreeData
+				added := mergeChapterByPosition(deepCloneSubtree(&tree, nil), &root)
+				info.NewNodeCount = &added
+			}
+		}
+		chapterInfos = append(chapterInfos, info)
 	}
 
 	return &models.StudyInfo{
@@ -192,17 +221,9 @@ func (s *StudyImportService) ImportStudyChaptersWithCategory(userID, studyID, au
 	var category *models.Category
 	var categoryID *string
 	if createCategory && s.categoryRepo != nil {
-		catName := categoryName
-		if catName == "" {
-			catName = studyName
-		}
-		if catName == "" {
-			catName = "Imported Study"
-		}
-
-		cat, err := s.categoryRepo.Create(userID, catName, detectedColor)
+		cat, err := s.createStudyCategory(userID, categoryName, studyName, detectedColor)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create category: %w", err)
+			return nil, err
 		}
 		category = cat
 		categoryID = &cat.ID
@@ -271,14 +292,128 @@ var ErrMixedColors = fmt.Errorf("cannot merge chapters with different colors")
 
 // ImportStudyChaptersMerged imports selected chapters from a Lichess study and merges them into a single repertoire.
 func (s *StudyImportService) ImportStudyChaptersMerged(userID, studyID, authToken string, chapterIndices []int, mergeName string) (*models.Repertoire, error) {
-	pgnData, err := s.lichessService.FetchStudyPGN(studyID, authToken)
+	result, err := s.ImportStudyChaptersMergedWithCategory(userID, studyID, authToken, chapterIndices, mergeName, false, "")
+	if err != nil {
+		return nil, err
+	}
+	return &result.Repertoires[0], nil
+}
+
+// ImportStudyChaptersMergedWithCategory merges selected chapters into a single new repertoire,
+// optionally placed in a new category named categoryName, or after the study when empty.
+func (s *StudyImportService) ImportStudyChaptersMergedWithCategory(userID, studyID, authToken string, chapterIndices []int, mergeName string, createCategory bool, categoryName string) (*StudyImportResult, error) {
+	parsedTrees, detectedColor, studyName, err := s.fetchChapterTrees(studyID, authToken, chapterIndices)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use provided name or fall back to study name
+	if mergeName == "" {
+		mergeName = studyName
+	}
+	if mergeName == "" {
+		mergeName = "Merged Study"
+	}
+
+	// Create one repertoire, in a new category if requested
+	var category *models.Category
+	var rep *models.Repertoire
+	if createCategory && s.categoryRepo != nil {
+		category, err = s.createStudyCategory(userID, categoryName, studyName, detectedColor)
+		if err != nil {
+			return nil, err
+		}
+		rep, err = s.repertoireService.CreateRepertoireWithCategory(userID, mergeName, detectedColor, &category.ID)
+	} else {
+		rep, err = s.repertoireService.CreateRepertoire(userID, mergeName, detectedColor)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repertoire: %w", err)
+	}
+
+	// Start with the first tree, merge the rest into it
+	merged := parsedTrees[0]
+	for i := 1; i < len(parsedTrees); i++ {
+		mergeNodes(&merged, &parsedTrees[i])
+	}
+
+	// Save the merged tree
+	saved, err := s.repertoireService.SaveTree(rep.ID, merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save merged tree: %w", err)
+	}
+
+	return &StudyImportResult{
+		Repertoires: []models.Repertoire{*saved},
+		Category:    category,
+	}, nil
+}
+
+// ImportStudyChaptersInto merges selected chapters into the user's existing repertoire targetID.
+// Chapters are matched against the repertoire by position, see mergeChapterByPosition, and must
+// all be played from the repertoire's color.
+func (s *StudyImportService) ImportStudyChaptersInto(userID, studyID, authToken string, chapterIndices []int, targetID string) (*models.Repertoire, error) {
+	target, err := s.mergeTarget(userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTrees, color, _, err := s.fetchChapterTrees(studyID, authToken, chapterIndices)
+	if err != nil {
+		return nil, err
+	}
+	if color != target.Color {
+		return nil, ErrMixedColors
+	}
+
+	tree := target.TreeData
+	for i := range parsedTrees {
+		mergeChapterByPosition(&tree, &parsedTrees[i])
+	}
+
+	saved, err := s.repertoireService.SaveTree(target.ID, tree)
 	if err != nil {
+		return nil, fmt.Errorf("failed to save merged tree: %w", err)
+	}
+	return saved, nil
+}
+
+// mergeTarget loads the repertoire chapters are merged into, checking the user may edit it
+func (s *StudyImportService) mergeTarget(userID, targetID string) (*models.Repertoire, error) {
+	if err := s.repertoireService.CheckOwnership(targetID, userID); err != nil {
 		return nil, err
 	}
+	return s.repertoireService.GetRepertoire(targetID)
+}
+
+// createStudyCategory creates the category of imported chapters, named categoryName or after the study
+func (s *StudyImportService) createStudyCategory(userID, categoryName, studyName string, color models.Color) (*models.Category, error) {
+	catName := categoryName
+	if catName == "" {
+		catName = studyName
+	}
+	if catName == "" {
+		catName = "Imported Study"
+	}
+
+	cat, err := s.categoryRepo.Create(userID, catName, color)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return cat, nil
+}
+
+// fetchChapterTrees fetches a study and parses the selected chapters, which must share a color.
+// Chapters starting from a custom position are skipped.
+func (s *StudyImportService) fetchChapterTrees(studyID, authToken string, chapterIndices []int) ([]models.RepertoireNode, models.Color, string, error) {
+	pgnData, err := s.lichessService.FetchStudyPGN(studyID, authToken)
+	if err != nil {
+		return nil, "", "", err
+	}
 
 	chapters := splitRawPGNGames(pgnData)
 	if len(chapters) == 0 {
-		return nil, fmt.Errorf("no chapters found in study")
+		return nil, "", "", fmt.Errorf("no chapters found in study")
 	}
 
 	// Build a set of requested indices for quick lookup
@@ -302,7 +437,7 @@ func (s *StudyImportService) ImportStudyChaptersMerged(userID, studyID, authToke
 				log.Printf("Skipping chapter %d: custom starting position", i)
 				continue
 			}
-			return nil, fmt.Errorf("failed to parse chapter %d: %w", i, err)
+			return nil, "", "", fmt.Errorf("failed to parse chapter %d: %w", i, err)
 		}
 
 		// Extract study name for fallback
@@ -326,43 +461,16 @@ func (s *StudyImportService) ImportStudyChaptersMerged(userID, studyID, authToke
 		if len(parsedTrees) == 0 {
 			detectedColor = color
 		} else if color != detectedColor {
-			return nil, ErrMixedColors
+			return nil, "", "", ErrMixedColors
 		}
 
 		parsedTrees = append(parsedTrees, root)
 	}
 
 	if len(parsedTrees) == 0 {
-		return nil, fmt.Errorf("no chapters could be parsed")
+		return nil, "", "", fmt.Errorf("no chapters could be parsed")
 	}
-
-	// Use provided name or fall back to study name
-	if mergeName == "" {
-		mergeName = studyName
-	}
-	if mergeName == "" {
-		mergeName = "Merged Study"
-	}
-
-	// Create one repertoire
-	rep, err := s.repertoireService.CreateRepertoire(userID, mergeName, detectedColor)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create repertoire: %w", err)
-	}
-
-	// Start with the first tree, merge the rest into it
-	merged := parsedTrees[0]
-	for i := 1; i < len(parsedTrees); i++ {
-		mergeNodes(&merged, &parsedTrees[i])
-	}
-
-	// Save the merged tree
-	saved, err := s.repertoireService.SaveTree(rep.ID, merged)
-	if err != nil {
-		return nil, fmt.Errorf("failed to save merged tree: %w", err)
-	}
-
-	return saved, nil
+	return parsedTrees, detectedColor, studyName, nil
 }
 
 // GetLichessTokenForUser retrieves the stored Lichess access token for a user.
@@ -377,3 +485,80 @@ func (s *StudyImportService) GetLichessTokenForUser(userID string) string {
 	}
 	return *user.LichessAccessToken
 }
+
+// mergeChapterByPosition merges a chapter tree into a repertoire tree and returns the number of
+// moves added. Chapter moves the tree already has are unified as mergeNodes does. A move reaching
+// a position the tree has through another move order is added as a transposition to it, and the
+// rest of the chapter line is merged there rather than duplicated.
+func mergeChapterByPosition(root, chapter *models.RepertoireNode) int {
+	positions := make(map[string]*models.RepertoireNode)
+	indexPositions(root, positions)
+	return mergeAtPositions(root, chapter, positions)
+}
+
+// indexPositions records the first node of the subtree reaching each position
+func indexPositions(node *models.RepertoireNode, positions map[string]*models.RepertoireNode) {
+	if node.TranspositionOf == nil {
+		key := NormalizeFEN(node.FEN)
+		if _, ok := positions[key]; !ok {
+			positions[key] = node
+		}
+	}
+	for _, child := range node.Children {
+		indexPositions(child, positions)
+	}
+}
+
+func mergeAtPositions(target, source *models.RepertoireNode, positions map[string]*models.RepertoireNode) int {
+	added := 0
+	for _, srcChild := range source.Children {
+		var matched *models.RepertoireNode
+		for _, tgtChild := range target.Children {
+			if tgtChild.Move != nil && srcChild.Move != nil && *tgtChild.Move == *srcChild.Move {
+				matched = tgtChild
+				break
+			}
+		}
+
+		if matched != nil {
+			if matched.Comment == nil && srcChild.Comment != nil {
+				matched.Comment = srcChild.Comment
+			}
+			matched.Tags = mergeTags(matched.Tags, srcChild.Tags)
+			if matched.TranspositionOf != nil {
+				// Transposition pointers have no children; the line goes on at the position they point to
+				if canonical, ok := positions[NormalizeFEN(matched.FEN)]; ok {
+					added += mergeAtPositions(canonical, srcChild, positions)
+				}
+				continue
+			}
+			added += mergeAtPositions(matched, srcChild, positions)
+			continue
+		}
+
+		// The move is new: add it alone, then merge its continuation so later moves can transpose too
+		node := &models.RepertoireNode{
+			ID:          uuid.New().String(),
+			FEN:         srcChild.FEN,
+			Move:        srcChild.Move,
+			MoveNumber:  srcChild.MoveNumber,
+			ColorToMove: srcChild.ColorToMove,
+			ParentID:    &target.ID,
+			Comment:     srcChild.Comment,
+			BranchName:  srcChild.BranchName,
+			Tags:        srcChild.Tags,
+			Children:    []*models.RepertoireNode{},
+		}
+		target.Children = append(target.Children, node)
+		added++
+
+		if canonical, ok := positions[NormalizeFEN(srcChild.FEN)]; ok {
+			node.TranspositionOf = &canonical.ID
+			added += mergeAtPositions(canonical, srcChild, positions)
+			continue
+		}
+		positions[NormalizeFEN(node.FEN)] = node
+		added += mergeAtPositions(node, srcChild, positions)
+	}
+	return added
+}
@@ -260,3 +260,123 @@ func TestStudyImportService_ImportStudyChapters_CreateError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to create repertoire")
 }
+
+// --- Merging into an existing repertoire ---
+
+func TestMergeChapterByPosition_Transposition(t *testing.T) {
+	// The repertoire reaches its position through 1.d4 Nf6 2.Nf3 e6
+	root, _, err := ParsePGNToTree("1. d4 Nf6 2. Nf3 e6 3. g3 *")
+	require.NoError(t, err)
+	// The chapter gets there through 1.Nf3 Nf6 2.d4 e6 and goes on with 3.c4
+	chapter, _, err := ParsePGNToTree("1. Nf3 Nf6 2. d4 e6 3. c4 *")
+	require.NoError(t, err)
+
+	added := mergeChapterByPosition(&root, &chapter)
+
+	// 1.Nf3, 1...Nf6 and 2.d4 are new; 2...e6 transposes; 3.c4 lands under the existing position
+	assert.Equal(t, 5, added)
+	require.Len(t, root.Children, 2)
+	nf3 := root.Children[1]
+	pointer := nf3.Children[0].Children[0].Children[0]
+	assert.Equal(t, "e6", *pointer.Move)
+	require.NotNil(t, pointer.TranspositionOf)
+	assert.Empty(t, pointer.Children)
+
+	existing := root.Children[0].Children[0].Children[0].Children[0]
+	assert.Equal(t, *pointer.TranspositionOf, existing.ID)
+	require.Len(t, existing.Children, 2)
+	assert.Equal(t, "c4", *existing.Children[1].Move)
+}
+
+func TestMergeChapterByPosition_ExistingLine(t *testing.T) {
+	root, _, err := ParsePGNToTree("1. e4 e5 2. Nf3 *")
+	require.NoError(t, err)
+	chapter, _, err := ParsePGNToTree("1. e4 e5 2. Nf3 Nc6 *")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, mergeChapterByPosition(&root, &chapter))
+	assert.Equal(t, 5, countNodes(&root))
+}
+
+func TestStudyImportService_ImportStudyChaptersInto(t *testing.T) {
+	pgnData := `[Event "Study: Italian"]
+[Orientation "White"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 *
+
+[Event "Study: French"]
+[Orientation "Black"]
+
+1. e4 e6 *
+`
+	mockLichess := &mocks.MockLichessService{
+		FetchStudyPGNFunc: func(studyID, authToken string) (string, error) {
+			return pgnData, nil
+		},
+	}
+	existing, _, err := ParsePGNToTree("1. e4 e5 2. Nf3 *")
+	require.NoError(t, err)
+
+	var saved models.RepertoireNode
+	mockRepSvc := &mocks.MockRepertoireService{
+		GetRepertoireFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, TreeData: existing}, nil
+		},
+		SaveTreeFunc: func(repertoireID string, treeData models.RepertoireNode) (*models.Repertoire, error) {
+			saved = treeData
+			return &models.Repertoire{ID: repertoireID, TreeData: treeData}, nil
+		},
+	}
+	svc := NewStudyImportService(mockLichess, mockRepSvc, nil, &mocks.MockUserRepo{})
+
+	rep, err := svc.ImportStudyChaptersInto("user-1", "testid01", "", []int{0}, "rep-1")
+	require.NoError(t, err)
+	assert.Equal(t, "rep-1", rep.ID)
+	assert.Equal(t, 6, countNodes(&saved))
+
+	// Chapters played from the other color do not fit the repertoire
+	_, err = svc.ImportStudyChaptersInto("user-1", "testid01", "", []int{1}, "rep-1")
+	assert.ErrorIs(t, err, ErrMixedColors)
+
+	mockRepSvc.CheckOwnershipFunc = func(id, userID string) error { return ErrNotFound }
+	_, err = svc.ImportStudyChaptersInto("user-2", "testid01", "", []int{0}, "rep-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStudyImportService_PreviewStudyInto_NodeCounts(t *testing.T) {
+	pgnData := `[Event "Study: Italian"]
+[Orientation "White"]
+
+1. e4 e5 2. Nf3 Nc6 (2... d6) 3. Bc4 *
+
+[Event "Study: Endgame"]
+[FEN "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1"]
+
+1. Kd2 *
+`
+	mockLichess := &mocks.MockLichessService{
+		FetchStudyPGNFunc: func(studyID, authToken string) (string, error) {
+			return pgnData, nil
+		},
+	}
+	existing, _, err := ParsePGNToTree("1. e4 e5 2. Nf3 Nc6 *")
+	require.NoError(t, err)
+	mockRepSvc := &mocks.MockRepertoireService{
+		GetRepertoireFunc: func(id string) (*models.Repertoire, error) {
+			return &models.Repertoire{ID: id, Color: models.ColorWhite, TreeData: existing}, nil
+		},
+	}
+	svc := NewStudyImportService(mockLichess, mockRepSvc, nil, &mocks.MockUserRepo{})
+
+	info, err := svc.PreviewStudyInto("user-1", "testid01", "", "rep-1")
+	require.NoError(t, err)
+	require.Len(t, info.Chapters, 2)
+
+	assert.Equal(t, 6, info.Chapters[0].NodeCount)
+	require.NotNil(t, info.Chapters[0].NewNodeCount)
+	assert.Equal(t, 2, *info.Chapters[0].NewNodeCount)
+	assert.True(t, info.Chapters[1].Unsupported)
+
+	// Previewing leaves the repertoire untouched
+	assert.Equal(t, 5, countNodes(&existing))
+}
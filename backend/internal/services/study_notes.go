@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrStudyNotesTooLong is returned when study notes exceed config.MaxStudyNotesLen
+var ErrStudyNotesTooLong = fmt.Errorf("study notes must be at most %d characters", config.MaxStudyNotesLen)
+
+// GetStudyNotes returns a version of the study notes of a repertoire, the current one when
+// version is 0. A repertoire without notes has empty notes at version 0.
+func (s *RepertoireService) GetStudyNotes(repertoireID string, version int) (*models.StudyNotes, error) {
+	notes, err := s.repo.GetNotes(repertoireID, version)
+	if errors.Is(err, repository.ErrStudyNotesNotFound) && version == 0 {
+		return &models.StudyNotes{RepertoireID: repertoireID}, nil
+	}
+	return notes, err
+}
+
+// SaveStudyNotes replaces the study notes of a repertoire, keeping the previous version in its
+// history. Saving the current content again adds no version.
+func (s *RepertoireService) SaveStudyNotes(repertoireID, userID, content string) (*models.StudyNotes, error) {
+	if utf8.RuneCountInString(content) > config.MaxStudyNotesLen {
+		return nil, ErrStudyNotesTooLong
+	}
+
+	current, err := s.GetStudyNotes(repertoireID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if current.Content == content {
+		return current, nil
+	}
+
+	notes, err := s.repo.SaveNotes(repertoireID, userID, content)
+	if errors.Is(err, repository.ErrRepertoireNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return notes, err
+}
+
+// ListStudyNotesRevisions returns the kept versions of the study notes of a repertoire, newest first
+func (s *RepertoireService) ListStudyNotesRevisions(repertoireID string) ([]models.StudyNotesRevision, error) {
+	return s.repo.ListNoteRevisions(repertoireID)
+}
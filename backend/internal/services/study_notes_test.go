@@ -0,0 +1,75 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestGetStudyNotes_EmptyWhenNoneSaved(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+
+	notes, err := svc.GetStudyNotes("rep-1", 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.StudyNotes{RepertoireID: "rep-1"}, notes)
+}
+
+func TestGetStudyNotes_MissingVersion(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+
+	_, err := svc.GetStudyNotes("rep-1", 3)
+
+	assert.ErrorIs(t, err, repository.ErrStudyNotesNotFound)
+}
+
+func TestSaveStudyNotes(t *testing.T) {
+	saves := 0
+	repo := &mocks.MockRepertoireRepo{
+		GetNotesFunc: func(repertoireID string, version int) (*models.StudyNotes, error) {
+			return &models.StudyNotes{RepertoireID: repertoireID, Version: 2, Content: "current"}, nil
+		},
+		SaveNotesFunc: func(repertoireID, userID, content string) (*models.StudyNotes, error) {
+			saves++
+			return &models.StudyNotes{RepertoireID: repertoireID, Version: 3, Content: content, UpdatedBy: &userID}, nil
+		},
+	}
+	svc := NewRepertoireService(repo)
+
+	notes, err := svc.SaveStudyNotes("rep-1", "user-1", "# Plans\nPlay d4.")
+	require.NoError(t, err)
+	assert.Equal(t, 3, notes.Version)
+	assert.Equal(t, "user-1", *notes.UpdatedBy)
+
+	notes, err = svc.SaveStudyNotes("rep-1", "user-1", "current")
+	require.NoError(t, err)
+	assert.Equal(t, 2, notes.Version)
+	assert.Equal(t, 1, saves)
+}
+
+func TestSaveStudyNotes_TooLong(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{})
+
+	_, err := svc.SaveStudyNotes("rep-1", "user-1", strings.Repeat("é", config.MaxStudyNotesLen+1))
+
+	assert.ErrorIs(t, err, ErrStudyNotesTooLong)
+}
+
+func TestSaveStudyNotes_RepertoireNotFound(t *testing.T) {
+	svc := NewRepertoireService(&mocks.MockRepertoireRepo{
+		SaveNotesFunc: func(repertoireID, userID, content string) (*models.StudyNotes, error) {
+			return nil, repository.ErrRepertoireNotFound
+		},
+	})
+
+	_, err := svc.SaveStudyNotes("rep-1", "user-1", "notes")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
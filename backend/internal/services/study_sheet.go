@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// Study sheet formats
+const (
+	StudySheetMarkdown = "markdown"
+	StudySheetHTML     = "html"
+)
+
+// ErrInvalidExportFormat is returned for a study sheet format other than markdown or html
+var ErrInvalidExportFormat = fmt.Errorf("export format must be markdown or html")
+
+// studyChapter gathers the lines of a repertoire going through a named branch, up to the next one
+type studyChapter struct {
+	title string
+	start *models.RepertoireNode
+	lines [][]*models.RepertoireNode
+}
+
+// ExportStudySheet writes a repertoire as a printable document: the study notes of the repertoire,
+// then one chapter per named branch, each opening with a diagram of its starting position, followed
+// by its lines with comments inline. Markdown diagrams link to the Lichess analysis board; HTML
+// embeds them as SVG.
+func (s *RepertoireService) ExportStudySheet(repertoireID, format string) (string, error) {
+	if format != StudySheetMarkdown && format != StudySheetHTML {
+		return "", ErrInvalidExportFormat
+	}
+	rep, err := s.getRepertoireTree(repertoireID)
+	if err != nil {
+		return "", err
+	}
+	notes, err := s.GetStudyNotes(repertoireID, 0)
+	if err != nil {
+		return "", err
+	}
+
+	chapters := studyChapters(&rep.TreeData)
+	if format == StudySheetMarkdown {
+		return markdownStudySheet(rep, notes.Content, chapters), nil
+	}
+	return htmlStudySheet(rep, notes.Content, chapters)
+}
+
+// studyChapters splits the lines of a tree by the deepest named branch they go through.
+// Lines before any named branch form the first chapter. Chapters without lines are dropped.
+func studyChapters(root *models.RepertoireNode) []*studyChapter {
+	first := &studyChapter{title: "Main lines", start: root}
+	if root.BranchName != nil && *root.BranchName != "" {
+		first.title = *root.BranchName
+	}
+	all := []*studyChapter{first}
+
+	var walk func(node *models.RepertoireNode, chapter *studyChapter, path []*models.RepertoireNode)
+	walk = func(node *models.RepertoireNode, chapter *studyChapter, path []*models.RepertoireNode) {
+		if node.Move != nil {
+			path = append(path, node)
+		}
+		if node != root && node.BranchName != nil && *node.BranchName != "" {
+			chapter = &studyChapter{title: *node.BranchName, start: node}
+			all = append(all, chapter)
+		}
+		if len(node.Children) == 0 {
+			if len(path) > 0 {
+				chapter.lines = append(chapter.lines, append([]*models.RepertoireNode{}, path...))
+			}
+			return
+		}
+		for _, child := range node.Children {
+			walk(child, chapter, path)
+		}
+	}
+	walk(root, first, nil)
+
+	var chapters []*studyChapter
+	for _, chapter := range all {
+		if len(chapter.lines) > 0 {
+			chapters = append(chapters, chapter)
+		}
+	}
+	return chapters
+}
+
+// studyLineText writes a line in move-number notation, passing comments through formatComment.
+// Black's move number is repeated after a comment, as in PGN.
+func studyLineText(line []*models.RepertoireNode, formatMove, formatComment func(string) string) string {
+	var tokens []string
+	afterComment := true
+	for _, node := range line {
+		// ColorToMove is the side to move after the node's move
+		if node.ColorToMove == models.ChessColorBlack {
+			tokens = append(tokens, fmt.Sprintf("%d.", node.MoveNumber))
+		} else if afterComment {
+			tokens = append(tokens, fmt.Sprintf("%d...", node.MoveNumber))
+		}
+		tokens = append(tokens, formatMove(*node.Move))
+
+		afterComment = node.Comment != nil && *node.Comment != ""
+		if afterComment {
+			tokens = append(tokens, formatComment(*node.Comment))
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// lichessAnalysisURL opens a position on the Lichess analysis board
+func lichessAnalysisURL(fen string, color models.Color) string {
+	url := "https://lichess.org/analysis/standard/" + strings.ReplaceAll(ensureFullFEN(fen), " ", "_")
+	if color == models.ColorBlack {
+		url += "?color=black"
+	}
+	return url
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`", "#", `\#`)
+
+// markdownStudySheet copies the notes as they are, since they are written in markdown
+func markdownStudySheet(rep *models.Repertoire, notes string, chapters []*studyChapter) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(rep.Name))
+	fmt.Fprintf(&b, "Repertoire for %s, %d lines.\n", rep.Color, countStudyLines(chapters))
+	if notes = strings.TrimSpace(notes); notes != "" {
+		fmt.Fprintf(&b, "\n## Study notes\n\n%s\n", notes)
+	}
+
+	identity := func(s string) string { return s }
+	comment := func(s string) string { return "*" + markdownEscaper.Replace(s) + "*" }
+	for _, chapter := range chapters {
+		fmt.Fprintf(&b, "\n## %s\n\n", markdownEscaper.Replace(chapter.title))
+		fmt.Fprintf(&b, "[Diagram](%s)\n", lichessAnalysisURL(chapter.start.FEN, rep.Color))
+		for i, line := range chapter.lines {
+			fmt.Fprintf(&b, "\n**Line %d:** %s\n", i+1, studyLineText(line, identity, comment))
+		}
+	}
+	return b.String()
+}
+
+const studySheetStyle = `body{font-family:Georgia,serif;max-width:48em;margin:2em auto;line-height:1.5}` +
+	`h2{border-bottom:1px solid #ccc}` +
+	`section{page-break-inside:avoid}` +
+	`.diagram svg{width:16em;height:16em}` +
+	`.notes{white-space:pre-wrap}`
+
+// htmlStudySheet shows the notes as plain text; their markdown is not rendered
+func htmlStudySheet(rep *models.Repertoire, notes string, chapters []*studyChapter) (string, error) {
+	var b strings.Builder
+	title := html.EscapeString(rep.Name)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", title, studySheetStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p>Repertoire for %s, %d lines.</p>\n", title, rep.Color, countStudyLines(chapters))
+	if notes = strings.TrimSpace(notes); notes != "" {
+		fmt.Fprintf(&b, "<section>\n<h2>Study notes</h2>\n<div class=\"notes\">%s</div>\n</section>\n", html.EscapeString(notes))
+	}
+
+	comment := func(s string) string { return "<em>" + html.EscapeString(s) + "</em>" }
+	for _, chapter := range chapters {
+		diagram, err := RenderBoardSVG(chapter.start.FEN, BoardOptions{Flipped: rep.Color == models.ColorBlack})
+		if err != nil {
+			return "", fmt.Errorf("failed to draw %q: %w", chapter.title, err)
+		}
+		fmt.Fprintf(&b, "<section>\n<h2>%s</h2>\n<div class=\"diagram\">%s</div>\n", html.EscapeString(chapter.title), diagram)
+		for i, line := range chapter.lines {
+			fmt.Fprintf(&b, "<p><strong>Line %d:</strong> %s</p>\n", i+1, studyLineText(line, html.EscapeString, comment))
+		}
+		b.WriteString("</section>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String(), nil
+}
+
+func countStudyLines(chapters []*studyChapter) int {
+	total := 0
+	for _, chapter := range chapters {
+		total += len(chapter.lines)
+	}
+	return total
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+const startingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+
+// newStudySheetService serves 1.e4 e5 2.Nf3 and 1.e4 c5 (branch "Sicilian", commented) 2.Nf3 d6,
+// with the given study notes when not empty
+func newStudySheetService(notes string) *RepertoireService {
+	str := func(s string) *string { return &s }
+	rep := &models.Repertoire{
+		ID: "rep-1", Name: "My e4 <main>", Color: models.ColorWhite,
+		TreeData: models.RepertoireNode{
+			ID: "root", FEN: startingFEN, ColorToMove: models.ChessColorWhite,
+			Children: []*models.RepertoireNode{{
+				ID: "e4", Move: str("e4"), MoveNumber: 1, FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -", ColorToMove: models.ChessColorBlack,
+				Children: []*models.RepertoireNode{
+					{
+						ID: "e5", Move: str("e5"), MoveNumber: 1, FEN: "after-e5", ColorToMove: models.ChessColorWhite,
+						Children: []*models.RepertoireNode{
+							{ID: "nf3-a", Move: str("Nf3"), MoveNumber: 2, FEN: "after-nf3-a", ColorToMove: models.ChessColorBlack},
+						},
+					},
+					{
+						ID: "c5", Move: str("c5"), MoveNumber: 1, ColorToMove: models.ChessColorWhite,
+						FEN:        "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq -",
+						BranchName: str("Sicilian"), Comment: str("the *sharpest* reply"),
+						Children: []*models.RepertoireNode{{
+							ID: "nf3-b", Move: str("Nf3"), MoveNumber: 2, FEN: "after-nf3-b", ColorToMove: models.ChessColorBlack,
+							Children: []*models.RepertoireNode{
+								{ID: "d6", Move: str("d6"), MoveNumber: 2, FEN: "after-d6", ColorToMove: models.ChessColorWhite},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	return NewRepertoireService(&mocks.MockRepertoireRepo{
+		GetByIDFunc: func(id string) (*models.Repertoire, error) { return rep, nil },
+		GetNotesFunc: func(repertoireID string, version int) (*models.StudyNotes, error) {
+			if notes == "" {
+				return nil, repository.ErrStudyNotesNotFound
+			}
+			return &models.StudyNotes{RepertoireID: repertoireID, Version: 1, Content: notes}, nil
+		},
+	})
+}
+
+func TestExportStudySheet_Markdown(t *testing.T) {
+	sheet, err := newStudySheetService("").ExportStudySheet("rep-1", StudySheetMarkdown)
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sheet, "# My e4 <main>\n"))
+	assert.Contains(t, sheet, "Repertoire for white, 2 lines.")
+	assert.Contains(t, sheet, "## Main lines\n\n[Diagram](https://lichess.org/analysis/standard/rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR_w_KQkq_-_0_1)")
+	assert.Contains(t, sheet, "**Line 1:** 1. e4 e5 2. Nf3\n")
+	assert.Contains(t, sheet, "## Sicilian\n")
+	assert.Contains(t, sheet, `**Line 1:** 1. e4 c5 *the \*sharpest\* reply* 2. Nf3 d6`)
+	assert.Less(t, strings.Index(sheet, "## Main lines"), strings.Index(sheet, "## Sicilian"))
+}
+
+func TestExportStudySheet_HTML(t *testing.T) {
+	sheet, err := newStudySheetService("").ExportStudySheet("rep-1", StudySheetHTML)
+
+	require.NoError(t, err)
+	assert.Contains(t, sheet, "<h1>My e4 &lt;main&gt;</h1>")
+	assert.Equal(t, 2, strings.Count(sheet, "<svg "))
+	assert.Contains(t, sheet, "<h2>Sicilian</h2>")
+	assert.Contains(t, sheet, "1. e4 c5 <em>the *sharpest* reply</em> 2. Nf3 d6")
+}
+
+func TestExportStudySheet_IncludesStudyNotes(t *testing.T) {
+	svc := newStudySheetService("Plan: **d4** break.\n\nModel game: Fischer <-> Spassky")
+
+	markdown, err := svc.ExportStudySheet("rep-1", StudySheetMarkdown)
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "## Study notes\n\nPlan: **d4** break.\n\nModel game: Fischer <-> Spassky\n")
+	assert.Less(t, strings.Index(markdown, "## Study notes"), strings.Index(markdown, "## Main lines"))
+
+	page, err := svc.ExportStudySheet("rep-1", StudySheetHTML)
+	require.NoError(t, err)
+	assert.Contains(t, page, "<div class=\"notes\">Plan: **d4** break.\n\nModel game: Fischer &lt;-&gt; Spassky</div>")
+}
+
+func TestExportStudySheet_WithoutStudyNotes(t *testing.T) {
+	sheet, err := newStudySheetService("").ExportStudySheet("rep-1", StudySheetMarkdown)
+
+	require.NoError(t, err)
+	assert.NotContains(t, sheet, "Study notes")
+}
+
+func TestExportStudySheet_InvalidFormat(t *testing.T) {
+	_, err := newStudySheetService("").ExportStudySheet("rep-1", "pdf")
+
+	assert.ErrorIs(t, err, ErrInvalidExportFormat)
+}
+
+func TestRenderBoardSVG(t *testing.T) {
+	svg, err := RenderBoardSVG(startingFEN, BoardOptions{})
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Equal(t, 64, strings.Count(svg, "<rect "))
+	assert.Equal(t, 32, strings.Count(svg, "<text "))
+
+	_, err = RenderBoardSVG("not a fen", BoardOptions{})
+	assert.Error(t, err)
+}
+
+func TestRenderBoardSVG_LastMove(t *testing.T) {
+	svg, err := RenderBoardSVG("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -", BoardOptions{LastMove: "e2e4"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(svg, "fill-opacity"))
+
+	for _, move := range []string{"e2", "e2e9", "i2e4", "e7e8k"} {
+		_, err = RenderBoardSVG(startingFEN, BoardOptions{LastMove: move})
+		assert.ErrorIs(t, err, ErrInvalidBoardMove, move)
+	}
+}
+
+func TestRenderBoardSVG_Flipped(t *testing.T) {
+	svg, err := RenderBoardSVG(startingFEN, BoardOptions{Flipped: true})
+
+	require.NoError(t, err)
+	// h1 is drawn in the top-left corner, holding White's rook
+	assert.Contains(t, svg, `<rect x="0" y="0" width="45" height="45" fill="#f0d9b5"/><text x="22" y="22" font-size="36" text-anchor="middle" dominant-baseline="central" fill="#fff"`)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -15,6 +16,7 @@ const (
 	syncFirstSyncLookbackDays  = 90
 	syncMaxGames               = 10
 	syncFirstSyncMaxGames      = 50
+	syncRunHistoryLimit        = 20
 )
 
 type SyncService struct {
@@ -22,6 +24,9 @@ type SyncService struct {
 	importService   GameImporter
 	lichessService  LichessGameFetcher
 	chesscomService ChesscomGameFetcher
+	runRepo         repository.SyncRunRepository
+	usage           *UsageService
+	notifications   *NotificationService
 }
 
 func NewSyncService(userRepo repository.UserRepository, importSvc GameImporter, lichessSvc LichessGameFetcher, chesscomSvc ChesscomGameFetcher) *SyncService {
@@ -33,53 +38,182 @@ func NewSyncService(userRepo repository.UserRepository, importSvc GameImporter,
 	}
 }
 
+// WithRunHistory enables persisted sync runs, used by StartSync and the run history
+func (s *SyncService) WithRunHistory(runRepo repository.SyncRunRepository) {
+	s.runRepo = runRepo
+}
+
+// WithUsage counts the game downloads of syncs against the users' daily quotas
+func (s *SyncService) WithUsage(usage *UsageService) {
+	s.usage = usage
+}
+
 func (s *SyncService) Sync(userID string) (*models.SyncResult, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	result := &models.SyncResult{}
+	run := &models.SyncRun{}
+	run.SetAccounts(s.syncUser(user))
+	return &run.SyncResult, nil
+}
+
+// StartSync records a sync run and syncs the user's accounts in the background.
+// The returned run is still running; its outcome is available through GetRun.
+func (s *SyncService) StartSync(userID string) (*models.SyncRun, error) {
+	if s.runRepo == nil {
+		return nil, fmt.Errorf("sync run history is not configured")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	run, err := s.runRepo.Create(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.completeRun(user, *run)
+	return run, nil
+}
+
+// completeRun performs the sync of a started run and persists its outcome
+func (s *SyncService) completeRun(user *models.User, run models.SyncRun) {
+	run.SetAccounts(s.syncUser(user))
+
+	run.Status = models.SyncRunDone
+	failed := 0
+	for _, a := range run.Accounts {
+		if a.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 && failed == len(run.Accounts) {
+		run.Status = models.SyncRunFailed
+		run.Error = "all accounts failed to sync"
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if err := s.runRepo.Finish(&run); err != nil {
+		log.Printf("Failed to record sync run %s for user %s: %v", run.ID, user.ID, err)
+	}
+	if imported := run.LichessGamesImported + run.ChesscomGamesImported; imported > 0 {
+		notifyAsync(s.notifications, user.ID, importCompletedNotification("sync", imported))
+	}
+}
+
+// GetRun returns a sync run, hiding runs of other users
+func (s *SyncService) GetRun(id, userID string) (*models.SyncRun, error) {
+	if s.runRepo == nil {
+		return nil, repository.ErrSyncRunNotFound
+	}
+	run, err := s.runRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.UserID != userID {
+		return nil, repository.ErrSyncRunNotFound
+	}
+	return run, nil
+}
+
+// ListRuns returns the user's most recent sync runs, newest first
+func (s *SyncService) ListRuns(userID string) ([]models.SyncRun, error) {
+	if s.runRepo == nil {
+		return nil, nil
+	}
+	return s.runRepo.ListByUser(userID, syncRunHistoryLimit)
+}
+
+// syncUser syncs every linked account of the user and returns one result per account
+func (s *SyncService) syncUser(user *models.User) []models.SyncAccountResult {
+	var results []models.SyncAccountResult
 	now := time.Now()
 
-	if user.LichessUsername != nil && *user.LichessUsername != "" {
-		imported, err := s.syncLichess(user, now)
-		if err != nil {
-			log.Printf("Lichess sync error for user %s: %v", userID, err)
-			result.LichessError = err.Error()
-		} else {
-			result.LichessGamesImported = imported
-			if err := s.userRepo.UpdateSyncTimestamps(userID, &now, nil); err != nil {
-				log.Printf("Failed to update Lichess sync timestamp for user %s: %v", userID, err)
+	lichessAccounts := user.AccountsFor(models.ProviderLichess)
+	if len(lichessAccounts) > 0 {
+		accountResults := s.syncAccounts(user, lichessAccounts, now, s.syncLichess)
+		results = append(results, accountResults...)
+		if anyAccountSynced(accountResults) {
+			if err := s.userRepo.UpdateSyncTimestamps(user.ID, &now, nil); err != nil {
+				log.Printf("Failed to update Lichess sync timestamp for user %s: %v", user.ID, err)
 			}
 		}
 	}
 
-	if user.ChesscomUsername != nil && *user.ChesscomUsername != "" {
-		imported, err := s.syncChesscom(user, now)
-		if err != nil {
-			log.Printf("Chess.com sync error for user %s: %v", userID, err)
-			result.ChesscomError = err.Error()
-		} else {
-			result.ChesscomGamesImported = imported
-			if err := s.userRepo.UpdateSyncTimestamps(userID, nil, &now); err != nil {
-				log.Printf("Failed to update Chess.com sync timestamp for user %s: %v", userID, err)
+	chesscomAccounts := user.AccountsFor(models.ProviderChesscom)
+	if len(chesscomAccounts) > 0 {
+		accountResults := s.syncAccounts(user, chesscomAccounts, now, s.syncChesscom)
+		results = append(results, accountResults...)
+		if anyAccountSynced(accountResults) {
+			if err := s.userRepo.UpdateSyncTimestamps(user.ID, nil, &now); err != nil {
+				log.Printf("Failed to update Chess.com sync timestamp for user %s: %v", user.ID, err)
 			}
 		}
 	}
 
+	return results
+}
+
+// syncAccounts syncs each account with syncFn and records per-account sync times
+func (s *SyncService) syncAccounts(user *models.User, accounts []models.LinkedAccount, now time.Time, syncFn func(*models.User, models.LinkedAccount, time.Time) (models.SyncAccountResult, error)) []models.SyncAccountResult {
+	results := make([]models.SyncAccountResult, 0, len(accounts))
+	for _, account := range accounts {
+		result, err := syncFn(user, account, now)
+		result.Provider = account.Provider
+		result.Username = account.Username
+		if err != nil {
+			log.Printf("%s sync error for user %s (%s): %v", account.Provider, user.ID, account.Username, err)
+			result.Error = err.Error()
+		} else if err := s.userRepo.UpdateLinkedAccountSync(account.ID, now); err != nil {
+			log.Printf("Failed to update sync timestamp for account %s: %v", account.ID, err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func anyAccountSynced(results []models.SyncAccountResult) bool {
+	for _, r := range results {
+		if r.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// importSyncedGames imports fetched PGN data and counts fetched, imported and skipped games.
+// A fetch made only of already imported games is not an error.
+func (s *SyncService) importSyncedGames(filename, username, userID, pgnData string) (models.SyncAccountResult, error) {
+	result := models.SyncAccountResult{GamesFetched: len(splitPGNGames(pgnData))}
+
+	summary, _, err := s.importService.ParseAndAnalyze(filename, username, userID, pgnData)
+	if errors.Is(err, ErrAllGamesDuplicate) {
+		result.GamesSkipped = result.GamesFetched
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.GamesImported = summary.GameCount
+	result.GamesSkipped = summary.SkippedDuplicates
 	return result, nil
 }
 
-func (s *SyncService) syncLichess(user *models.User, now time.Time) (int, error) {
-	since := s.computeSince(user.LastLichessSyncAt, now)
+func (s *SyncService) syncLichess(user *models.User, account models.LinkedAccount, now time.Time) (models.SyncAccountResult, error) {
+	since := s.computeSince(account.LastSyncAt, now)
 
 	max := syncMaxGames
-	if user.LastLichessSyncAt == nil {
+	if account.LastSyncAt == nil {
 		max = syncFirstSyncMaxGames
 	}
 
-	perfType := strings.Join(user.TimeFormatPrefs, ",")
+	perfType := lichessPerfTypes(user.TimeFormatPrefs)
 	if perfType == "" {
 		perfType = "bullet,blitz,rapid"
 	}
@@ -90,25 +224,28 @@ func (s *SyncService) syncLichess(user *models.User, now time.Time) (int, error)
 		PerfType: perfType,
 	}
 
-	pgnData, err := s.lichessService.FetchGames(*user.LichessUsername, options)
+	if err := chargeUsage(s.usage, user.ID, models.UsageLichessExport); err != nil {
+		return models.SyncAccountResult{}, err
+	}
+	pgnData, err := s.lichessService.FetchGames(account.Username, options)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch Lichess games: %w", err)
+		return models.SyncAccountResult{}, fmt.Errorf("failed to fetch Lichess games: %w", err)
 	}
 
-	filename := fmt.Sprintf("sync_lichess_%s.pgn", *user.LichessUsername)
-	summary, _, err := s.importService.ParseAndAnalyze(filename, *user.LichessUsername, user.ID, pgnData)
+	filename := fmt.Sprintf("sync_lichess_%s.pgn", account.Username)
+	result, err := s.importSyncedGames(filename, account.Username, user.ID, pgnData)
 	if err != nil {
-		return 0, fmt.Errorf("failed to analyze Lichess games: %w", err)
+		return result, fmt.Errorf("failed to analyze Lichess games: %w", err)
 	}
 
-	return summary.GameCount, nil
+	return result, nil
 }
 
-func (s *SyncService) syncChesscom(user *models.User, now time.Time) (int, error) {
-	since := s.computeSince(user.LastChesscomSyncAt, now)
+func (s *SyncService) syncChesscom(user *models.User, account models.LinkedAccount, now time.Time) (models.SyncAccountResult, error) {
+	since := s.computeSince(account.LastSyncAt, now)
 
 	max := syncMaxGames
-	if user.LastChesscomSyncAt == nil {
+	if account.LastSyncAt == nil {
 		max = syncFirstSyncMaxGames
 	}
 
@@ -125,7 +262,13 @@ func (s *SyncService) syncChesscom(user *models.User, now time.Time) (int, error
 			TimeClass: tc,
 		}
 
-		pgnData, err := s.chesscomService.FetchGames(*user.ChesscomUsername, options)
+		if err := chargeUsage(s.usage, user.ID, models.UsageChesscom); err != nil {
+			if allPgnData.Len() == 0 {
+				return models.SyncAccountResult{}, err
+			}
+			break
+		}
+		pgnData, err := s.chesscomService.FetchGames(account.Username, options)
 		if err != nil {
 			log.Printf("Chess.com sync error for time class %s: %v", tc, err)
 			continue
@@ -134,16 +277,29 @@ func (s *SyncService) syncChesscom(user *models.User, now time.Time) (int, error
 	}
 
 	if allPgnData.Len() == 0 {
-		return 0, nil
+		return models.SyncAccountResult{}, nil
 	}
 
-	filename := fmt.Sprintf("sync_chesscom_%s.pgn", *user.ChesscomUsername)
-	summary, _, err := s.importService.ParseAndAnalyze(filename, *user.ChesscomUsername, user.ID, allPgnData.String())
+	filename := fmt.Sprintf("sync_chesscom_%s.pgn", account.Username)
+	result, err := s.importSyncedGames(filename, account.Username, user.ID, allPgnData.String())
 	if err != nil {
-		return 0, fmt.Errorf("failed to analyze Chess.com games: %w", err)
+		return result, fmt.Errorf("failed to analyze Chess.com games: %w", err)
 	}
 
-	return summary.GameCount, nil
+	return result, nil
+}
+
+// lichessPerfTypes maps time format preferences to Lichess perf types, where daily games
+// are called correspondence
+func lichessPerfTypes(timeFormats []string) string {
+	perfTypes := make([]string, len(timeFormats))
+	for i, tf := range timeFormats {
+		if tf == "daily" {
+			tf = "correspondence"
+		}
+		perfTypes[i] = tf
+	}
+	return strings.Join(perfTypes, ",")
 }
 
 func (s *SyncService) computeSince(lastSync *time.Time, now time.Time) int64 {
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/treechess/backend/config"
 	"github.com/treechess/backend/internal/models"
 	"github.com/treechess/backend/internal/repository/mocks"
 )
@@ -15,11 +16,7 @@ import (
 func TestSyncService_Sync_BothPlatforms(t *testing.T) {
 	lichessUser := "lichessplayer"
 	chesscomUser := "chesscomuser"
-	user := &models.User{
-		ID:              "user-1",
-		LichessUsername:  &lichessUser,
-		ChesscomUsername: &chesscomUser,
-	}
+	user := newLinkedUser(&lichessUser, &chesscomUser, nil)
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -53,11 +50,7 @@ func TestSyncService_Sync_BothPlatforms(t *testing.T) {
 
 func TestSyncService_Sync_LichessOnly(t *testing.T) {
 	lichessUser := "lichessplayer"
-	user := &models.User{
-		ID:              "user-1",
-		LichessUsername:  &lichessUser,
-		ChesscomUsername: nil,
-	}
+	user := newLinkedUser(&lichessUser, nil, nil)
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -84,11 +77,7 @@ func TestSyncService_Sync_LichessOnly(t *testing.T) {
 
 func TestSyncService_Sync_ChesscomOnly(t *testing.T) {
 	chesscomUser := "chesscomuser"
-	user := &models.User{
-		ID:              "user-1",
-		LichessUsername:  nil,
-		ChesscomUsername: &chesscomUser,
-	}
+	user := newLinkedUser(nil, &chesscomUser, nil)
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -114,11 +103,7 @@ func TestSyncService_Sync_ChesscomOnly(t *testing.T) {
 }
 
 func TestSyncService_Sync_NeitherPlatform(t *testing.T) {
-	user := &models.User{
-		ID:              "user-1",
-		LichessUsername:  nil,
-		ChesscomUsername: nil,
-	}
+	user := newLinkedUser(nil, nil, nil)
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -135,11 +120,7 @@ func TestSyncService_Sync_NeitherPlatform(t *testing.T) {
 func TestSyncService_Sync_LichessError_ChesscomStillRuns(t *testing.T) {
 	lichessUser := "lichessplayer"
 	chesscomUser := "chesscomuser"
-	user := &models.User{
-		ID:              "user-1",
-		LichessUsername:  &lichessUser,
-		ChesscomUsername: &chesscomUser,
-	}
+	user := newLinkedUser(&lichessUser, &chesscomUser, nil)
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -206,13 +187,7 @@ func TestSyncService_ComputeSince_WithoutLastSync(t *testing.T) {
 func TestSyncService_FirstSync_Uses50Games(t *testing.T) {
 	lichessUser := "lichessplayer"
 	chesscomUser := "chesscomuser"
-	user := &models.User{
-		ID:               "user-1",
-		LichessUsername:   &lichessUser,
-		ChesscomUsername:  &chesscomUser,
-		LastLichessSyncAt: nil,
-		LastChesscomSyncAt: nil,
-	}
+	user := newLinkedUser(&lichessUser, &chesscomUser, nil)
 
 	var capturedLichessMax int
 	var capturedChesscomMax int
@@ -251,13 +226,7 @@ func TestSyncService_SubsequentSync_Uses10Games(t *testing.T) {
 	lichessUser := "lichessplayer"
 	chesscomUser := "chesscomuser"
 	lastSync := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
-	user := &models.User{
-		ID:                 "user-1",
-		LichessUsername:    &lichessUser,
-		ChesscomUsername:   &chesscomUser,
-		LastLichessSyncAt:  &lastSync,
-		LastChesscomSyncAt: &lastSync,
-	}
+	user := newLinkedUser(&lichessUser, &chesscomUser, &lastSync)
 
 	var capturedLichessMax int
 	var capturedChesscomMax int
@@ -295,11 +264,7 @@ func TestSyncService_SubsequentSync_Uses10Games(t *testing.T) {
 func TestSyncService_Sync_EmptyUsername(t *testing.T) {
 	emptyLichess := ""
 	emptyChesscom := ""
-	user := &models.User{
-		ID:              "user-1",
-		LichessUsername:  &emptyLichess,
-		ChesscomUsername: &emptyChesscom,
-	}
+	user := newLinkedUser(&emptyLichess, &emptyChesscom, nil)
 
 	mockUserRepo := &mocks.MockUserRepo{
 		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
@@ -312,3 +277,213 @@ func TestSyncService_Sync_EmptyUsername(t *testing.T) {
 	assert.Equal(t, 0, result.LichessGamesImported)
 	assert.Equal(t, 0, result.ChesscomGamesImported)
 }
+
+// newLinkedUser builds a user whose linked accounts mirror the given usernames; nil or empty ones are skipped
+func newLinkedUser(lichess, chesscom *string, lastSync *time.Time) *models.User {
+	var accounts []models.LinkedAccount
+	if lichess != nil && *lichess != "" {
+		accounts = append(accounts, models.LinkedAccount{ID: "acc-lichess", Provider: models.ProviderLichess, Username: *lichess, LastSyncAt: lastSync})
+	}
+	if chesscom != nil && *chesscom != "" {
+		accounts = append(accounts, models.LinkedAccount{ID: "acc-chesscom", Provider: models.ProviderChesscom, Username: *chesscom, LastSyncAt: lastSync})
+	}
+	user := &models.User{ID: "user-1"}
+	user.SetLinkedAccounts(accounts)
+	return user
+}
+
+func TestSyncService_Sync_MultipleAccounts(t *testing.T) {
+	lastSync := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	user := &models.User{ID: "user-1"}
+	user.SetLinkedAccounts([]models.LinkedAccount{
+		{ID: "acc-1", Provider: models.ProviderLichess, Username: "blitzalt", LastSyncAt: &lastSync},
+		{ID: "acc-2", Provider: models.ProviderLichess, Username: "classical"},
+		{ID: "acc-3", Provider: models.ProviderLichess, Username: "closed"},
+	})
+
+	var synced []string
+	mockUserRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
+		UpdateLinkedAccountSyncFunc: func(accountID string, syncedAt time.Time) error {
+			synced = append(synced, accountID)
+			return nil
+		},
+	}
+	maxByUser := make(map[string]int)
+	mockLichess := &mocks.MockLichessService{
+		FetchGamesFunc: func(username string, opts models.LichessImportOptions) (string, error) {
+			if username == "closed" {
+				return "", fmt.Errorf("account closed")
+			}
+			maxByUser[username] = opts.Max
+			return "pgn data", nil
+		},
+	}
+	var importedAs []string
+	mockImport := &mocks.MockImportService{
+		ParseAndAnalyzeFunc: func(filename, username, userID, pgnData string) (*models.AnalysisSummary, []models.GameAnalysis, error) {
+			importedAs = append(importedAs, username)
+			return &models.AnalysisSummary{GameCount: 2}, nil, nil
+		},
+	}
+
+	svc := NewSyncService(mockUserRepo, mockImport, mockLichess, &mocks.MockChesscomService{})
+	result, err := svc.Sync("user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.LichessGamesImported)
+	assert.Contains(t, result.LichessError, "closed")
+	assert.Equal(t, []string{"blitzalt", "classical"}, importedAs)
+	assert.Equal(t, []string{"acc-1", "acc-2"}, synced)
+	assert.Equal(t, syncMaxGames, maxByUser["blitzalt"])
+	assert.Equal(t, syncFirstSyncMaxGames, maxByUser["classical"])
+}
+
+func TestSyncService_CompleteRun_RecordsAccountResults(t *testing.T) {
+	user := &models.User{ID: "user-1"}
+	user.SetLinkedAccounts([]models.LinkedAccount{
+		{ID: "acc-1", Provider: models.ProviderLichess, Username: "fresh"},
+		{ID: "acc-2", Provider: models.ProviderLichess, Username: "seen"},
+		{ID: "acc-3", Provider: models.ProviderChesscom, Username: "closed"},
+	})
+
+	twoGames := "[Event \"A\"]\n\n1. e4 e5 1-0\n\n[Event \"B\"]\n\n1. d4 d5 0-1\n"
+	mockLichess := &mocks.MockLichessService{
+		FetchGamesFunc: func(username string, opts models.LichessImportOptions) (string, error) {
+			return twoGames, nil
+		},
+	}
+	mockChesscom := &mocks.MockChesscomService{
+		FetchGamesFunc: func(username string, opts models.ChesscomImportOptions) (string, error) {
+			return twoGames, nil
+		},
+	}
+	mockImport := &mocks.MockImportService{
+		ParseAndAnalyzeFunc: func(filename, username, userID, pgnData string) (*models.AnalysisSummary, []models.GameAnalysis, error) {
+			switch username {
+			case "seen":
+				return nil, nil, ErrAllGamesDuplicate
+			case "closed":
+				return nil, nil, fmt.Errorf("database error")
+			}
+			return &models.AnalysisSummary{GameCount: 1, SkippedDuplicates: 1}, nil, nil
+		},
+	}
+	var finished *models.SyncRun
+	mockRunRepo := &mocks.MockSyncRunRepo{
+		FinishFunc: func(run *models.SyncRun) error {
+			finished = run
+			return nil
+		},
+	}
+
+	svc := NewSyncService(&mocks.MockUserRepo{}, mockImport, mockLichess, mockChesscom)
+	svc.WithRunHistory(mockRunRepo)
+	svc.completeRun(user, models.SyncRun{ID: "run-1", UserID: "user-1", Status: models.SyncRunRunning})
+
+	require.NotNil(t, finished)
+	assert.Equal(t, models.SyncRunDone, finished.Status)
+	require.NotNil(t, finished.FinishedAt)
+	require.Len(t, finished.Accounts, 3)
+
+	assert.Equal(t, models.SyncAccountResult{Provider: models.ProviderLichess, Username: "fresh", GamesFetched: 2, GamesImported: 1, GamesSkipped: 1}, finished.Accounts[0])
+	assert.Equal(t, models.SyncAccountResult{Provider: models.ProviderLichess, Username: "seen", GamesFetched: 2, GamesSkipped: 2}, finished.Accounts[1])
+	assert.Equal(t, models.ProviderChesscom, finished.Accounts[2].Provider)
+	assert.Contains(t, finished.Accounts[2].Error, "database error")
+
+	assert.Equal(t, 1, finished.LichessGamesImported)
+	assert.Empty(t, finished.LichessError)
+	assert.Contains(t, finished.ChesscomError, "closed")
+}
+
+func TestSyncService_CompleteRun_AllAccountsFailed(t *testing.T) {
+	lichessUser := "lichessplayer"
+	user := newLinkedUser(&lichessUser, nil, nil)
+	mockLichess := &mocks.MockLichessService{
+		FetchGamesFunc: func(username string, opts models.LichessImportOptions) (string, error) {
+			return "", fmt.Errorf("rate limited")
+		},
+	}
+	var finished *models.SyncRun
+	mockRunRepo := &mocks.MockSyncRunRepo{
+		FinishFunc: func(run *models.SyncRun) error {
+			finished = run
+			return nil
+		},
+	}
+
+	svc := NewSyncService(&mocks.MockUserRepo{}, &mocks.MockImportService{}, mockLichess, &mocks.MockChesscomService{})
+	svc.WithRunHistory(mockRunRepo)
+	svc.completeRun(user, models.SyncRun{ID: "run-1", UserID: "user-1"})
+
+	require.NotNil(t, finished)
+	assert.Equal(t, models.SyncRunFailed, finished.Status)
+	assert.NotEmpty(t, finished.Error)
+}
+
+func TestSyncService_DailyPreference(t *testing.T) {
+	lichessUser := "lichessplayer"
+	chesscomUser := "chesscomuser"
+	user := newLinkedUser(&lichessUser, &chesscomUser, nil)
+	user.TimeFormatPrefs = []string{"blitz", "daily"}
+
+	var lichessPerfType string
+	var chesscomTimeClasses []string
+	mockUserRepo := &mocks.MockUserRepo{
+		GetByIDFunc:              func(id string) (*models.User, error) { return user, nil },
+		UpdateSyncTimestampsFunc: func(userID string, l, c *time.Time) error { return nil },
+	}
+	mockLichess := &mocks.MockLichessService{
+		FetchGamesFunc: func(username string, opts models.LichessImportOptions) (string, error) {
+			lichessPerfType = opts.PerfType
+			return "[Event \"Test\"]\n\n1. e4 e5 1-0\n", nil
+		},
+	}
+	mockChesscom := &mocks.MockChesscomService{
+		FetchGamesFunc: func(username string, opts models.ChesscomImportOptions) (string, error) {
+			chesscomTimeClasses = append(chesscomTimeClasses, opts.TimeClass)
+			return "[Event \"Test\"]\n\n1. d4 d5 0-1\n", nil
+		},
+	}
+	mockImport := &mocks.MockImportService{
+		ParseAndAnalyzeFunc: func(filename, username, userID, pgnData string) (*models.AnalysisSummary, []models.GameAnalysis, error) {
+			return &models.AnalysisSummary{GameCount: 1}, nil, nil
+		},
+	}
+
+	svc := NewSyncService(mockUserRepo, mockImport, mockLichess, mockChesscom)
+	_, err := svc.Sync("user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "blitz,correspondence", lichessPerfType)
+	assert.Equal(t, []string{"blitz", "daily"}, chesscomTimeClasses)
+}
+
+func TestSyncService_Sync_UsageQuotaExceeded(t *testing.T) {
+	lichessUser := "lichessplayer"
+	user := newLinkedUser(&lichessUser, nil, nil)
+
+	mockUserRepo := &mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) { return user, nil },
+	}
+	fetched := false
+	mockLichess := &mocks.MockLichessService{
+		FetchGamesFunc: func(username string, opts models.LichessImportOptions) (string, error) {
+			fetched = true
+			return "", nil
+		},
+	}
+	usage := NewUsageService(&mocks.MockUsageRepo{
+		IncrementFunc: func(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error) {
+			return false, nil
+		},
+	}, config.UsageQuotas{LichessExport: 1})
+
+	svc := NewSyncService(mockUserRepo, &mocks.MockImportService{}, mockLichess, &mocks.MockChesscomService{})
+	svc.WithUsage(usage)
+	result, err := svc.Sync("user-1")
+
+	require.NoError(t, err)
+	assert.False(t, fetched)
+	assert.NotEmpty(t, result.LichessError)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+)
+
+const tablebaseBaseURL = "https://tablebase.lichess.ovh/standard"
+
+var (
+	ErrTooManyPieces        = fmt.Errorf("tablebases cover positions with at most %d pieces", config.TablebaseMaxPieces)
+	ErrTablebaseUnavailable = errors.New("tablebase lookups are not available")
+)
+
+// TablebaseService looks positions up in the Lichess tablebase. Verdicts never change, so they
+// are cached per position.
+type TablebaseService struct {
+	httpClient *http.Client
+	baseURL    string
+	cache      map[string]*models.TablebasePosition
+	cacheMu    sync.Mutex
+}
+
+// NewTablebaseService creates a new tablebase service
+func NewTablebaseService() *TablebaseService {
+	return &TablebaseService{
+		httpClient: newResilientHTTPClient(10 * time.Second),
+		baseURL:    tablebaseBaseURL,
+		cache:      make(map[string]*models.TablebasePosition),
+	}
+}
+
+// Lookup returns the tablebase verdict on a position with at most config.TablebaseMaxPieces pieces
+func (s *TablebaseService) Lookup(fen string) (*models.TablebasePosition, error) {
+	fullFEN := ensureFullFEN(strings.TrimSpace(fen))
+	if _, err := chess.FEN(fullFEN); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+	if countPieces(fullFEN) > config.TablebaseMaxPieces {
+		return nil, ErrTooManyPieces
+	}
+
+	// Move counters do not change the verdict
+	key := NormalizeFEN(fullFEN)
+	s.cacheMu.Lock()
+	cached, ok := s.cache[key]
+	s.cacheMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s?fen=%s", s.baseURL, url.QueryEscape(fullFEN)))
+	if err != nil {
+		return nil, fmt.Errorf("tablebase request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tablebase returned status %d", resp.StatusCode)
+	}
+
+	var position models.TablebasePosition
+	if err := json.NewDecoder(resp.Body).Decode(&position); err != nil {
+		return nil, fmt.Errorf("failed to decode tablebase response: %w", err)
+	}
+	position.FEN = fullFEN
+	if position.Moves == nil {
+		position.Moves = []models.TablebaseMove{}
+	}
+
+	s.cacheMu.Lock()
+	if len(s.cache) >= config.TablebaseCacheSize {
+		// Make room by dropping an arbitrary entry; lookups are cheap to repeat
+		for k := range s.cache {
+			delete(s.cache, k)
+			break
+		}
+	}
+	s.cache[key] = &position
+	s.cacheMu.Unlock()
+
+	return &position, nil
+}
+
+// countPieces returns the number of pieces, kings included, on the board of a FEN
+func countPieces(fen string) int {
+	board, _, _ := strings.Cut(strings.TrimSpace(fen), " ")
+	count := 0
+	for _, r := range board {
+		if strings.ContainsRune("pnbrqkPNBRQK", r) {
+			count++
+		}
+	}
+	return count
+}
+
+// tablebaseMoveScore is the exact result of a tablebase move for the side playing it: 1 for a win,
+// 0.5 for a draw, including wins and losses spoiled by the fifty-move rule, and 0 for a loss.
+// ok is false when the tablebase cannot tell.
+func tablebaseMoveScore(move models.TablebaseMove) (score float64, ok bool) {
+	// The category is that of the opponent, who moves next
+	switch move.Category {
+	case "loss", "maybe-loss":
+		return 1, true
+	case "blessed-loss", "draw", "cursed-win":
+		return 0.5, true
+	case "win", "maybe-win":
+		return 0, true
+	}
+	return 0, false
+}
+
+// tablebaseMoveStats compares the played move of a tablebase position with the best one using exact
+// results. ok is false when the move is not found or the tablebase cannot tell its result.
+func tablebaseMoveStats(position *models.TablebasePosition, move models.MoveAnalysis) (models.ExplorerMoveStats, bool) {
+	var played, best float64
+	bestMove := ""
+	found := false
+	for _, m := range position.Moves {
+		score, ok := tablebaseMoveScore(m)
+		if !ok {
+			continue
+		}
+		if bestMove == "" || score > best {
+			best, bestMove = score, m.SAN
+		}
+		if m.SAN == move.SAN {
+			played, found = score, true
+		}
+	}
+	if !found {
+		return models.ExplorerMoveStats{}, false
+	}
+
+	return models.ExplorerMoveStats{
+		PlyNumber:     move.PlyNumber,
+		FEN:           move.FEN,
+		PlayedMove:    move.SAN,
+		PlayedWinrate: played,
+		BestMove:      bestMove,
+		BestWinrate:   best,
+		WinrateDrop:   best - played,
+		Tablebase:     true,
+	}, true
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+)
+
+// White to move wins with the pawn push, the king move only draws
+const kpkFEN = "8/8/8/8/8/4k3/4P3/4K3 w - - 0 1"
+
+func TestTablebaseLookup_CachesVerdicts(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, kpkFEN, r.URL.Query().Get("fen"))
+		w.Write([]byte(`{"category":"draw","dtz":0,"dtm":null,"checkmate":false,"stalemate":false,
+			"moves":[{"uci":"e1d1","san":"Kd1","category":"draw","dtz":0,"zeroing":false}]}`))
+	}))
+	defer server.Close()
+
+	svc := NewTablebaseService()
+	svc.baseURL = server.URL
+
+	first, err := svc.Lookup(kpkFEN)
+	require.NoError(t, err)
+	second, err := svc.Lookup("8/8/8/8/8/4k3/4P3/4K3 w - - 12 40")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, requests)
+	assert.Same(t, first, second)
+	assert.Equal(t, "draw", first.Category)
+	require.Len(t, first.Moves, 1)
+	assert.Equal(t, "Kd1", first.Moves[0].SAN)
+}
+
+func TestTablebaseLookup_RejectsLargePositions(t *testing.T) {
+	svc := NewTablebaseService()
+
+	_, err := svc.Lookup("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	assert.ErrorIs(t, err, ErrTooManyPieces)
+
+	_, err = svc.Lookup("not a fen")
+	assert.ErrorIs(t, err, ErrInvalidFEN)
+}
+
+func TestTablebaseMoveStats(t *testing.T) {
+	position := &models.TablebasePosition{Moves: []models.TablebaseMove{
+		{SAN: "Kd1", Category: "draw"},
+		{SAN: "e4", Category: "loss"},
+		{SAN: "Kf1", Category: "unknown"},
+	}}
+
+	stat, ok := tablebaseMoveStats(position, models.MoveAnalysis{PlyNumber: 60, SAN: "Kd1", FEN: kpkFEN})
+
+	require.True(t, ok)
+	assert.True(t, stat.Tablebase)
+	assert.Equal(t, "e4", stat.BestMove)
+	assert.Equal(t, 1.0, stat.BestWinrate)
+	assert.Equal(t, 0.5, stat.PlayedWinrate)
+	assert.Equal(t, 0.5, stat.WinrateDrop)
+
+	_, ok = tablebaseMoveStats(position, models.MoveAnalysis{SAN: "Kf1"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// teamFilenamePrefix marks analyses of a team member's games, imported for reference like broadcast rounds
+const teamFilenamePrefix = "team_"
+
+// ErrTeamImportsUnavailable is returned when background team imports are not configured
+var ErrTeamImportsUnavailable = fmt.Errorf("team imports are not available")
+
+// lichessTeamURLPattern matches Lichess team URLs.
+// Accepts: https://lichess.org/team/team-slug or a raw team ID.
+var lichessTeamURLPattern = regexp.MustCompile(`^(?:https?://(?:www\.)?lichess\.org/team/)?([a-z0-9][a-z0-9-]{1,99})/?$`)
+
+// ParseLichessTeamID extracts the team ID from a Lichess team URL or raw ID.
+func ParseLichessTeamID(rawURL string) (string, error) {
+	rawURL = strings.ToLower(strings.TrimSpace(rawURL))
+	if rawURL == "" {
+		return "", fmt.Errorf("team URL is required")
+	}
+
+	matches := lichessTeamURLPattern.FindStringSubmatch(rawURL)
+	if matches == nil {
+		return "", fmt.Errorf("invalid Lichess team URL or ID: %s", rawURL)
+	}
+	return matches[1], nil
+}
+
+// TeamMemberFilename is the filename recorded for the import of a team member's games
+func TeamMemberFilename(teamID, username string) string {
+	return fmt.Sprintf("%s%s_%s.pgn", teamFilenamePrefix, teamID, username)
+}
+
+// WithTeamImports enables background imports of Lichess teams, fetched through lichess
+func WithTeamImports(repo repository.TeamImportRepository, lichess LichessTeamFetcher) ImportServiceOption {
+	return func(s *ImportService) {
+		s.teamImportRepo = repo
+		s.teamFetcher = lichess
+		s.teamMemberDelay = config.TeamImportMemberDelay
+	}
+}
+
+// QueueTeamImport queues a background import of the games of every member of a Lichess team.
+// Without a start date, the games of the last config.TeamImportDefaultDays days are imported.
+func (s *ImportService) QueueTeamImport(userID, teamID string, options models.LichessImportOptions, analysisDepth *int) (*models.TeamImport, error) {
+	if analysisDepth != nil && !ValidAnalysisDepth(*analysisDepth) {
+		return nil, ErrInvalidAnalysisDepth
+	}
+	if s.teamImportRepo == nil {
+		return nil, ErrTeamImportsUnavailable
+	}
+	if options.Since == 0 {
+		options.Since = time.Now().AddDate(0, 0, -config.TeamImportDefaultDays).UnixMilli()
+	}
+	return s.teamImportRepo.Create(userID, teamID, options, analysisDepth)
+}
+
+// GetTeamImport returns a team import, hiding imports of other users
+func (s *ImportService) GetTeamImport(id, userID string) (*models.TeamImport, error) {
+	if s.teamImportRepo == nil {
+		return nil, repository.ErrTeamImportNotFound
+	}
+	t, err := s.teamImportRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserID != userID {
+		return nil, repository.ErrTeamImportNotFound
+	}
+	return t, nil
+}
+
+// ImportTeamMemberGames analyzes the games of one team member for reference. The games are matched
+// against the repertoires of both colors and recorded as part of the team import in params.
+func (s *ImportService) ImportTeamMemberGames(userID string, params models.ImportSourceParams, pgnData string) (*models.AnalysisSummary, error) {
+	if len(pgnData) > config.MaxPGNFileSize {
+		return nil, ErrImportTooLarge
+	}
+	summary, _, err := s.ParseAndAnalyzeWithOptions(TeamMemberFilename(params.TeamID, params.Username), params.Username, userID, pgnData,
+		ImportOptions{AnalysisDepth: params.AnalysisDepth, Reference: true,
+			Provenance: models.ImportProvenance{Source: models.ImportSourceTeam, SourceParams: params}})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// RunTeamImportWorker polls for pending team imports and processes them one at a time
+func (s *ImportService) RunTeamImportWorker(ctx context.Context) {
+	log.Println("team-import: worker started")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("team-import: worker stopped")
+			return
+		case <-ticker.C:
+			s.processTeamImports()
+		}
+	}
+}
+
+func (s *ImportService) processTeamImports() {
+	imports, err := s.teamImportRepo.ClaimPending(1)
+	if err != nil {
+		log.Printf("team-import: failed to claim imports: %v", err)
+		return
+	}
+
+	for _, t := range imports {
+		s.runTeamImport(t)
+	}
+}
+
+// runTeamImport imports the members not imported yet, spacing the Lichess requests out.
+// On a Lichess rate limit the import goes back to the queue and later resumes where it stopped.
+func (s *ImportService) runTeamImport(t models.TeamImport) {
+	members := t.Members
+	if len(members) == 0 {
+		fetched, err := s.teamFetcher.FetchTeamMembers(t.TeamID, config.MaxTeamImportMembers)
+		if err != nil {
+			s.stopTeamImport(t.ID, err)
+			return
+		}
+		if len(fetched) == 0 {
+			s.stopTeamImport(t.ID, fmt.Errorf("team %s has no members", t.TeamID))
+			return
+		}
+		if err := s.teamImportRepo.SetMembers(t.ID, fetched); err != nil {
+			s.stopTeamImport(t.ID, err)
+			return
+		}
+		members = fetched
+	}
+
+	games := 0
+	for _, r := range t.Results {
+		games += r.GameCount
+	}
+	for i := len(t.Results); i < len(members); i++ {
+		if i > len(t.Results) && s.teamMemberDelay > 0 {
+			time.Sleep(s.teamMemberDelay)
+		}
+		result, err := s.importTeamMember(t, members[i])
+		if err != nil {
+			s.stopTeamImport(t.ID, err)
+			return
+		}
+		if err := s.teamImportRepo.RecordMember(t.ID, result); err != nil {
+			s.stopTeamImport(t.ID, err)
+			return
+		}
+		games += result.GameCount
+	}
+
+	if err := s.teamImportRepo.MarkDone(t.ID); err != nil {
+		log.Printf("team-import: failed to mark import %s as done: %v", t.ID, err)
+		return
+	}
+	notifyAsync(s.notifications, t.UserID, importCompletedNotification("team "+t.TeamID, games))
+}
+
+// importTeamMember imports the games of one member. Failures specific to the member are
+// reported in the result; only a Lichess rate limit or an exhausted quota is returned as an error.
+func (s *ImportService) importTeamMember(t models.TeamImport, username string) (models.TeamMemberImport, error) {
+	result := models.TeamMemberImport{Username: username}
+
+	options := t.Options
+	if err := chargeUsage(s.usage, t.UserID, models.UsageLichessExport); err != nil {
+		return result, err
+	}
+	pgnData, err := s.teamFetcher.FetchGames(username, options)
+	if err != nil {
+		if errors.Is(err, ErrLichessRateLimited) {
+			return result, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	summary, err := s.ImportTeamMemberGames(t.UserID, models.ImportSourceParams{
+		Username:      username,
+		TeamID:        t.TeamID,
+		BatchID:       t.ID,
+		AnalysisDepth: t.AnalysisDepth,
+		Lichess:       &options,
+	}, pgnData)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.AnalysisID = summary.ID
+	result.GameCount = summary.GameCount
+	return result, nil
+}
+
+// stopTeamImport defers the import after a Lichess rate limit or until the owner's quota resets,
+// and fails it after any other error
+func (s *ImportService) stopTeamImport(id string, err error) {
+	if errors.Is(err, ErrLichessRateLimited) {
+		log.Printf("team-import: import %s rate limited, resuming in %s", id, config.TeamImportRateLimitBackoff)
+		if deferErr := s.teamImportRepo.Defer(id, time.Now().Add(config.TeamImportRateLimitBackoff)); deferErr != nil {
+			log.Printf("team-import: failed to defer import %s: %v", id, deferErr)
+		}
+		return
+	}
+	if errors.Is(err, ErrUsageQuotaExceeded) {
+		resumeAt := usageDay(time.Now()).AddDate(0, 0, 1)
+		log.Printf("team-import: import %s out of quota, resuming at %s", id, resumeAt)
+		if deferErr := s.teamImportRepo.Defer(id, resumeAt); deferErr != nil {
+			log.Printf("team-import: failed to defer import %s: %v", id, deferErr)
+		}
+		return
+	}
+
+	log.Printf("team-import: import %s failed: %v", id, err)
+	if markErr := s.teamImportRepo.MarkFailed(id, err.Error()); markErr != nil {
+		log.Printf("team-import: failed to mark import %s as failed: %v", id, markErr)
+	}
+}
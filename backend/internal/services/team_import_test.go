@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+type fakeTeamFetcher struct {
+	members    []string
+	membersErr error
+	games      map[string]string
+	gamesErr   map[string]error
+	fetched    []string
+}
+
+func (f *fakeTeamFetcher) FetchTeamMembers(teamID string, limit int) ([]string, error) {
+	return f.members, f.membersErr
+}
+
+func (f *fakeTeamFetcher) FetchGames(username string, options models.LichessImportOptions) (string, error) {
+	f.fetched = append(f.fetched, username)
+	if err := f.gamesErr[username]; err != nil {
+		return "", err
+	}
+	return f.games[username], nil
+}
+
+func newTeamImportService(repo *mocks.MockTeamImportRepo, fetcher *fakeTeamFetcher) *ImportService {
+	analysisRepo := &mocks.MockAnalysisRepo{
+		SaveFunc: func(userID string, username, filename string, provenance models.ImportProvenance, gameCount int, results []models.GameAnalysis) (*models.AnalysisSummary, error) {
+			return &models.AnalysisSummary{ID: "analysis-" + username, GameCount: gameCount}, nil
+		},
+	}
+	svc := NewImportService(NewRepertoireService(&mocks.MockRepertoireRepo{}), analysisRepo, WithTeamImports(repo, fetcher))
+	svc.teamMemberDelay = 0
+	return svc
+}
+
+func TestParseLichessTeamID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		teamID  string
+		wantErr bool
+	}{
+		{"team URL", "https://lichess.org/team/my-chess-club", "my-chess-club", false},
+		{"team URL with trailing slash", "https://lichess.org/team/my-chess-club/", "my-chess-club", false},
+		{"raw team ID", "  My-Chess-Club  ", "my-chess-club", false},
+		{"broadcast URL", "https://lichess.org/broadcast/tata-steel-2025/round-1/abcdEF12", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teamID, err := ParseLichessTeamID(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.teamID, teamID)
+		})
+	}
+}
+
+func TestQueueTeamImport_DefaultsSince(t *testing.T) {
+	var queued models.LichessImportOptions
+	repo := &mocks.MockTeamImportRepo{
+		CreateFunc: func(userID, teamID string, options models.LichessImportOptions, analysisDepth *int) (*models.TeamImport, error) {
+			queued = options
+			return &models.TeamImport{ID: "team-import-1", TeamID: teamID, Options: options}, nil
+		},
+	}
+	svc := newTeamImportService(repo, &fakeTeamFetcher{})
+
+	_, err := svc.QueueTeamImport("user-1", "my-club", models.LichessImportOptions{}, nil)
+
+	require.NoError(t, err)
+	expected := time.Now().AddDate(0, 0, -config.TeamImportDefaultDays).UnixMilli()
+	assert.InDelta(t, expected, queued.Since, float64(time.Minute.Milliseconds()))
+}
+
+func TestQueueTeamImport_Unavailable(t *testing.T) {
+	svc := NewImportService(nil, nil)
+
+	_, err := svc.QueueTeamImport("user-1", "my-club", models.LichessImportOptions{}, nil)
+
+	assert.ErrorIs(t, err, ErrTeamImportsUnavailable)
+}
+
+func TestGetTeamImport_OtherUser(t *testing.T) {
+	repo := &mocks.MockTeamImportRepo{
+		GetByIDFunc: func(id string) (*models.TeamImport, error) {
+			return &models.TeamImport{ID: id, UserID: "user-2"}, nil
+		},
+	}
+	svc := newTeamImportService(repo, &fakeTeamFetcher{})
+
+	_, err := svc.GetTeamImport("team-import-1", "user-1")
+
+	assert.ErrorIs(t, err, repository.ErrTeamImportNotFound)
+}
+
+func TestRunTeamImport_RecordsEveryMember(t *testing.T) {
+	var members []string
+	var recorded []models.TeamMemberImport
+	done := false
+	repo := &mocks.MockTeamImportRepo{
+		SetMembersFunc: func(id string, m []string) error {
+			members = m
+			return nil
+		},
+		RecordMemberFunc: func(id string, result models.TeamMemberImport) error {
+			recorded = append(recorded, result)
+			return nil
+		},
+		MarkDoneFunc: func(id string) error {
+			done = true
+			return nil
+		},
+	}
+	fetcher := &fakeTeamFetcher{
+		members: []string{"alice", "bob"},
+		games: map[string]string{"alice": `[White "alice"]
+[Black "someone"]
+
+1. e4 e5 1-0`},
+		gamesErr: map[string]error{"bob": fmt.Errorf("%w: bob", ErrLichessUserNotFound)},
+	}
+	svc := newTeamImportService(repo, fetcher)
+
+	svc.runTeamImport(models.TeamImport{ID: "team-import-1", UserID: "user-1", TeamID: "my-club"})
+
+	assert.Equal(t, []string{"alice", "bob"}, members)
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "analysis-alice", recorded[0].AnalysisID)
+	assert.Equal(t, 1, recorded[0].GameCount)
+	assert.Empty(t, recorded[1].AnalysisID)
+	assert.NotEmpty(t, recorded[1].Error)
+	assert.True(t, done)
+}
+
+func TestRunTeamImport_ResumesAfterRecordedMembers(t *testing.T) {
+	fetcher := &fakeTeamFetcher{gamesErr: map[string]error{}}
+	svc := newTeamImportService(&mocks.MockTeamImportRepo{}, fetcher)
+
+	svc.runTeamImport(models.TeamImport{
+		ID:      "team-import-1",
+		TeamID:  "my-club",
+		Members: []string{"alice", "bob"},
+		Results: []models.TeamMemberImport{{Username: "alice", AnalysisID: "analysis-alice"}},
+	})
+
+	assert.Equal(t, []string{"bob"}, fetcher.fetched)
+}
+
+func TestRunTeamImport_RateLimitDefers(t *testing.T) {
+	deferred, done := false, false
+	repo := &mocks.MockTeamImportRepo{
+		DeferFunc: func(id string, until time.Time) error {
+			deferred = true
+			return nil
+		},
+		MarkDoneFunc: func(id string) error {
+			done = true
+			return nil
+		},
+		RecordMemberFunc: func(id string, result models.TeamMemberImport) error {
+			t.Fatalf("member %s should not be recorded", result.Username)
+			return nil
+		},
+	}
+	fetcher := &fakeTeamFetcher{
+		members:  []string{"alice"},
+		gamesErr: map[string]error{"alice": ErrLichessRateLimited},
+	}
+	svc := newTeamImportService(repo, fetcher)
+
+	svc.runTeamImport(models.TeamImport{ID: "team-import-1", TeamID: "my-club"})
+
+	assert.True(t, deferred)
+	assert.False(t, done)
+}
+
+func TestRunTeamImport_TeamNotFound(t *testing.T) {
+	var failure string
+	repo := &mocks.MockTeamImportRepo{
+		MarkFailedFunc: func(id string, message string) error {
+			failure = message
+			return nil
+		},
+	}
+	fetcher := &fakeTeamFetcher{membersErr: fmt.Errorf("%w: my-club", ErrLichessTeamNotFound)}
+	svc := newTeamImportService(repo, fetcher)
+
+	svc.runTeamImport(models.TeamImport{ID: "team-import-1", TeamID: "my-club"})
+
+	assert.Contains(t, failure, "my-club")
+}
+
+func TestFilenameProvenance_TeamMember(t *testing.T) {
+	provenance := filenameProvenance(TeamMemberFilename("my-club", "alice"), "alice")
+
+	assert.Equal(t, models.ImportSourceTeam, provenance.Source)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrNotTrainingPosition is returned when an answer is given for a node where the user has no repertoire move
+var ErrNotTrainingPosition = fmt.Errorf("node is not a training position")
+
+const trainingDayLayout = "2006-01-02"
+
+// TrainingService checks training answers and keeps the user's training activity and streaks
+type TrainingService struct {
+	repo              repository.TrainingRepository
+	repertoireService *RepertoireService
+	userRepo          repository.UserRepository
+}
+
+// NewTrainingService creates a new training service
+func NewTrainingService(repo repository.TrainingRepository, repertoireSvc *RepertoireService) *TrainingService {
+	return &TrainingService{repo: repo, repertoireService: repertoireSvc}
+}
+
+// WithUserRepo counts training days in each user's time zone instead of UTC
+func (s *TrainingService) WithUserRepo(userRepo repository.UserRepository) {
+	s.userRepo = userRepo
+}
+
+// Answer checks a move played in a training position against the repertoire and records it
+func (s *TrainingService) Answer(userID, repertoireID string, req models.TrainingAnswerRequest) (*models.TrainingAnswerResult, error) {
+	move := strings.TrimSpace(req.Move)
+	if req.NodeID == "" || move == "" {
+		return nil, fmt.Errorf("%w: nodeId and move are required", ErrInvalidMove)
+	}
+
+	rep, err := s.repertoireService.getRepertoireTree(repertoireID)
+	if err != nil {
+		return nil, err
+	}
+	node := findNode(&rep.TreeData, req.NodeID)
+	if node == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, req.NodeID)
+	}
+
+	userToMove := models.ChessColorWhite
+	if rep.Color == models.ColorBlack {
+		userToMove = models.ChessColorBlack
+	}
+	result := &models.TrainingAnswerResult{ExpectedMoves: []string{}}
+	for _, child := range node.Children {
+		if child.Move == nil {
+			continue
+		}
+		result.ExpectedMoves = append(result.ExpectedMoves, *child.Move)
+		if *child.Move == move {
+			result.Correct = true
+		}
+	}
+	if node.ColorToMove != userToMove || len(result.ExpectedMoves) == 0 {
+		return nil, ErrNotTrainingPosition
+	}
+
+	now := time.Now().In(userLocation(s.userRepo, userID))
+	session, err := s.repo.RecordAnswer(userID, repertoireID, result.Correct, now, config.TrainingSessionGap)
+	if err != nil {
+		return nil, err
+	}
+	result.Session = *session
+	return result, nil
+}
+
+// Activity returns the user's training calendar over the last days, today included, with their streaks.
+// The longest streak covers all of the user's training, not only the requested days.
+func (s *TrainingService) Activity(userID string, days int) (*models.TrainingActivity, error) {
+	return s.activityAt(userID, days, time.Now().In(userLocation(s.userRepo, userID)))
+}
+
+func (s *TrainingService) activityAt(userID string, days int, now time.Time) (*models.TrainingActivity, error) {
+	all, err := s.repo.GetDays(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := localDay(now, now.Location())
+	since := today.AddDate(0, 0, -(days - 1)).Format(trainingDayLayout)
+	activity := &models.TrainingActivity{Days: []models.TrainingDay{}}
+	correct := 0
+	for _, day := range all {
+		if day.Date < since {
+			continue
+		}
+		activity.Days = append(activity.Days, day)
+		activity.TotalReviews += day.Reviews
+		correct += day.Correct
+	}
+	if activity.TotalReviews > 0 {
+		activity.Accuracy = float64(correct) / float64(activity.TotalReviews)
+	}
+
+	activity.CurrentStreak, activity.LongestStreak = trainingStreaks(all, today)
+	return activity, nil
+}
+
+// trainingStreaks counts runs of consecutive training days in days, sorted oldest first.
+// The current streak is still alive when the last training day is today or yesterday.
+func trainingStreaks(days []models.TrainingDay, today time.Time) (current, longest int) {
+	run := 0
+	var previous time.Time
+	for _, day := range days {
+		if day.Reviews == 0 {
+			continue
+		}
+		date, err := time.Parse(trainingDayLayout, day.Date)
+		if err != nil {
+			continue
+		}
+		if run > 0 && date.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = date
+		if run > longest {
+			longest = run
+		}
+	}
+
+	if run > 0 && !previous.Before(today.AddDate(0, 0, -1)) {
+		current = run
+	}
+	return current, longest
+}
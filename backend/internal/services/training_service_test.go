@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+func TestTrainingAnswer(t *testing.T) {
+	var recorded []bool
+	repo := &mocks.MockTrainingRepo{
+		RecordAnswerFunc: func(userID, repertoireID string, correct bool, at time.Time, sessionGap time.Duration) (*models.TrainingSession, error) {
+			recorded = append(recorded, correct)
+			return &models.TrainingSession{ID: "session-1", RepertoireID: repertoireID, Reviews: len(recorded)}, nil
+		},
+	}
+	svc := NewTrainingService(repo, newLinesService())
+
+	right, err := svc.Answer("user-1", "rep-1", models.TrainingAnswerRequest{NodeID: "d6", Move: "d4"})
+	require.NoError(t, err)
+	wrong, err := svc.Answer("user-1", "rep-1", models.TrainingAnswerRequest{NodeID: "d6", Move: "Be2"})
+	require.NoError(t, err)
+
+	assert.True(t, right.Correct)
+	assert.False(t, wrong.Correct)
+	assert.Equal(t, []string{"d4"}, wrong.ExpectedMoves)
+	assert.Equal(t, 2, wrong.Session.Reviews)
+	assert.Equal(t, []bool{true, false}, recorded)
+}
+
+func TestTrainingAnswer_Errors(t *testing.T) {
+	svc := NewTrainingService(&mocks.MockTrainingRepo{}, newLinesService())
+
+	// The opponent moves after 1.e4
+	_, err := svc.Answer("user-1", "rep-1", models.TrainingAnswerRequest{NodeID: "e4", Move: "e5"})
+	assert.ErrorIs(t, err, ErrNotTrainingPosition)
+
+	// Lines ending on the user's move have nothing to drill
+	_, err = svc.Answer("user-1", "rep-1", models.TrainingAnswerRequest{NodeID: "d4", Move: "e5"})
+	assert.ErrorIs(t, err, ErrNotTrainingPosition)
+
+	_, err = svc.Answer("user-1", "rep-1", models.TrainingAnswerRequest{NodeID: "missing", Move: "e4"})
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+
+	_, err = svc.Answer("user-1", "rep-1", models.TrainingAnswerRequest{NodeID: "root"})
+	assert.ErrorIs(t, err, ErrInvalidMove)
+}
+
+func TestTrainingActivity(t *testing.T) {
+	repo := &mocks.MockTrainingRepo{
+		GetDaysFunc: func(userID string) ([]models.TrainingDay, error) {
+			return []models.TrainingDay{
+				{Date: "2026-03-01", Reviews: 10, Correct: 5},
+				{Date: "2026-03-02", Reviews: 10, Correct: 5},
+				{Date: "2026-03-03", Reviews: 10, Correct: 5},
+				{Date: "2026-05-09", Reviews: 4, Correct: 3},
+				{Date: "2026-05-10", Reviews: 6, Correct: 5},
+			}, nil
+		},
+	}
+	svc := NewTrainingService(repo, nil)
+	now := time.Date(2026, 5, 11, 8, 0, 0, 0, time.UTC)
+
+	activity, err := svc.activityAt("user-1", 30, now)
+
+	require.NoError(t, err)
+	require.Len(t, activity.Days, 2)
+	assert.Equal(t, "2026-05-09", activity.Days[0].Date)
+	assert.Equal(t, 10, activity.TotalReviews)
+	assert.InDelta(t, 0.8, activity.Accuracy, 0.001)
+	// Not trained yet today, but yesterday keeps the streak alive
+	assert.Equal(t, 2, activity.CurrentStreak)
+	assert.Equal(t, 3, activity.LongestStreak)
+
+	// A missed day breaks it
+	activity, err = svc.activityAt("user-1", 30, now.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 0, activity.CurrentStreak)
+}
+
+func TestTrainingActivity_UserTimezone(t *testing.T) {
+	repo := &mocks.MockTrainingRepo{
+		GetDaysFunc: func(userID string) ([]models.TrainingDay, error) {
+			return []models.TrainingDay{{Date: "2026-05-11", Reviews: 4, Correct: 3}}, nil
+		},
+	}
+	svc := NewTrainingService(repo, nil)
+	svc.WithUserRepo(&mocks.MockUserRepo{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			return &models.User{ID: id, Timezone: "Asia/Tokyo"}, nil
+		},
+	})
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// 23:00 UTC on May 10th is already May 11th in Tokyo: the user trained today
+	now := time.Date(2026, 5, 10, 23, 0, 0, 0, time.UTC).In(userLocation(svc.userRepo, "user-1"))
+	assert.Equal(t, tokyo, now.Location())
+	activity, err := svc.activityAt("user-1", 1, now)
+	require.NoError(t, err)
+	require.Len(t, activity.Days, 1)
+	assert.Equal(t, 1, activity.CurrentStreak)
+}
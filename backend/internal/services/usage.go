@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+// ErrUsageQuotaExceeded is returned when a user has used up their daily quota of a service
+var ErrUsageQuotaExceeded = errors.New("daily usage quota exceeded")
+
+const usageDayLayout = "2006-01-02"
+
+// UsageService counts the calls each user makes to external services per UTC day and refuses
+// those beyond the configured quotas, so a single user cannot exhaust the provider rate limits
+type UsageService struct {
+	repo   repository.UsageRepository
+	limits map[models.UsageKind]int
+	now    func() time.Time
+}
+
+// NewUsageService creates a new usage service enforcing the given quotas
+func NewUsageService(repo repository.UsageRepository, quotas config.UsageQuotas) *UsageService {
+	return &UsageService{
+		repo: repo,
+		limits: map[models.UsageKind]int{
+			models.UsageExplorer:      quotas.Explorer,
+			models.UsageLichessExport: quotas.LichessExport,
+			models.UsageChesscom:      quotas.Chesscom,
+		},
+		now: time.Now,
+	}
+}
+
+// Charge counts a call of a user to a service, or returns ErrUsageQuotaExceeded when their
+// quota for the day is used up. Calls are let through when the counter cannot be written.
+func (s *UsageService) Charge(userID string, kind models.UsageKind) error {
+	allowed, err := s.repo.Increment(userID, kind, usageDay(s.now()), s.limits[kind])
+	if err != nil {
+		log.Printf("usage: failed to count %s call of user %s: %v", kind, userID, err)
+		return nil
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s", ErrUsageQuotaExceeded, kind)
+	}
+	return nil
+}
+
+// Usage returns a user's consumption of every counted service today
+func (s *UsageService) Usage(userID string) (*models.UsageReport, error) {
+	day := usageDay(s.now())
+	counts, err := s.repo.GetDay(userID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.UsageReport{
+		Day:      day.Format(usageDayLayout),
+		ResetsAt: day.AddDate(0, 0, 1),
+		Counters: make([]models.UsageCounter, 0, len(models.UsageKinds)),
+	}
+	for _, kind := range models.UsageKinds {
+		report.Counters = append(report.Counters, models.UsageCounter{Kind: kind, Used: counts[kind], Limit: s.limits[kind]})
+	}
+	return report, nil
+}
+
+// Totals returns the consumption of every counted service by all users today
+func (s *UsageService) Totals() (*models.UsageTotals, error) {
+	day := usageDay(s.now())
+	totals, err := s.repo.Totals(day)
+	if err != nil {
+		return nil, err
+	}
+
+	byKind := make(map[models.UsageKind]models.UsageTotal, len(totals))
+	for _, total := range totals {
+		byKind[total.Kind] = total
+	}
+	result := &models.UsageTotals{Day: day.Format(usageDayLayout), Totals: make([]models.UsageTotal, 0, len(models.UsageKinds))}
+	for _, kind := range models.UsageKinds {
+		total := byKind[kind]
+		total.Kind = kind
+		total.Limit = s.limits[kind]
+		result.Totals = append(result.Totals, total)
+	}
+	return result, nil
+}
+
+// usageDay truncates a time to the start of its UTC day, when quotas reset
+func usageDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// chargeUsage charges a call to usage when quotas are enforced
+func chargeUsage(usage *UsageService, userID string, kind models.UsageKind) error {
+	if usage == nil {
+		return nil
+	}
+	return usage.Charge(userID, kind)
+}
+
+// WithUsage counts the game downloads of imports against the users' daily quotas
+func WithUsage(usage *UsageService) ImportServiceOption {
+	return func(s *ImportService) {
+		s.usage = usage
+	}
+}
+
+// ChargeUsage counts a call of a user to a service against their daily quota when quotas are
+// enforced, for downloads started outside the import service
+func (s *ImportService) ChargeUsage(userID string, kind models.UsageKind) error {
+	return chargeUsage(s.usage, userID, kind)
+}
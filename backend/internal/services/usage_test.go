@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+var usageTestQuotas = config.UsageQuotas{Explorer: 100, LichessExport: 2, Chesscom: 0}
+
+func newUsageTestService(repo *mocks.MockUsageRepo) *UsageService {
+	svc := NewUsageService(repo, usageTestQuotas)
+	svc.now = func() time.Time { return time.Date(2026, 5, 1, 22, 30, 0, 0, time.FixedZone("UTC-3", -3*3600)) }
+	return svc
+}
+
+func TestUsageCharge(t *testing.T) {
+	counts := map[models.UsageKind]int{}
+	var days []time.Time
+	svc := newUsageTestService(&mocks.MockUsageRepo{
+		IncrementFunc: func(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error) {
+			days = append(days, day)
+			if limit > 0 && counts[kind] >= limit {
+				return false, nil
+			}
+			counts[kind]++
+			return true, nil
+		},
+	})
+
+	require.NoError(t, svc.Charge("user-1", models.UsageLichessExport))
+	require.NoError(t, svc.Charge("user-1", models.UsageLichessExport))
+	err := svc.Charge("user-1", models.UsageLichessExport)
+	assert.ErrorIs(t, err, ErrUsageQuotaExceeded)
+
+	// A quota of 0 lifts the cap
+	for i := 0; i < 5; i++ {
+		require.NoError(t, svc.Charge("user-1", models.UsageChesscom))
+	}
+
+	// Calls are counted on the UTC day
+	assert.Equal(t, time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC), days[0])
+}
+
+func TestUsageCharge_FailsOpen(t *testing.T) {
+	svc := newUsageTestService(&mocks.MockUsageRepo{
+		IncrementFunc: func(userID string, kind models.UsageKind, day time.Time, limit int) (bool, error) {
+			return false, errors.New("db down")
+		},
+	})
+
+	assert.NoError(t, svc.Charge("user-1", models.UsageExplorer))
+}
+
+func TestUsage_Report(t *testing.T) {
+	svc := newUsageTestService(&mocks.MockUsageRepo{
+		GetDayFunc: func(userID string, day time.Time) (map[models.UsageKind]int, error) {
+			return map[models.UsageKind]int{models.UsageExplorer: 12}, nil
+		},
+	})
+
+	report, err := svc.Usage("user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "2026-05-02", report.Day)
+	assert.Equal(t, time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC), report.ResetsAt)
+	assert.Equal(t, []models.UsageCounter{
+		{Kind: models.UsageExplorer, Used: 12, Limit: 100},
+		{Kind: models.UsageLichessExport, Used: 0, Limit: 2},
+		{Kind: models.UsageChesscom, Used: 0, Limit: 0},
+	}, report.Counters)
+}
+
+func TestUsage_Totals(t *testing.T) {
+	svc := newUsageTestService(&mocks.MockUsageRepo{
+		TotalsFunc: func(day time.Time) ([]models.UsageTotal, error) {
+			return []models.UsageTotal{{Kind: models.UsageChesscom, Used: 40, Users: 3, MaxUsed: 25}}, nil
+		},
+	})
+
+	totals, err := svc.Totals()
+
+	require.NoError(t, err)
+	require.Len(t, totals.Totals, len(models.UsageKinds))
+	assert.Equal(t, models.UsageTotal{Kind: models.UsageExplorer, Limit: 100}, totals.Totals[0])
+	assert.Equal(t, models.UsageTotal{Kind: models.UsageChesscom, Used: 40, Users: 3, MaxUsed: 25}, totals.Totals[2])
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/treechess/backend/internal/repository"
+)
+
+// userLocation returns the time zone a user's days are counted in, UTC when it cannot be read
+func userLocation(userRepo repository.UserRepository, userID string) *time.Location {
+	if userRepo == nil {
+		return time.UTC
+	}
+	user, err := userRepo.GetByID(userID)
+	if err != nil {
+		log.Printf("failed to get time zone of user %s: %v", userID, err)
+		return time.UTC
+	}
+	return user.Location()
+}
+
+// localDay returns the calendar day of t in loc, as midnight UTC so days compare and add up
+// like the dates stored in the database
+func localDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// dateLayouts are the numeric date formats of locales, by full tag or language.
+// Locales not listed write the day first.
+var dateLayouts = map[string]string{
+	"en":    "01/02/2006",
+	"en-US": "01/02/2006",
+	"en-GB": "02/01/2006",
+	"de":    "02.01.2006",
+	"ja":    "2006/01/02",
+	"ko":    "2006. 01. 02.",
+	"sv":    "2006-01-02",
+	"zh":    "2006-01-02",
+}
+
+// formatLocalDate writes the day of t in the user's time zone the way their locale does
+func formatLocalDate(t time.Time, timezone, locale string) string {
+	loc := time.UTC
+	if timezone != "" {
+		if l, err := time.LoadLocation(timezone); err == nil {
+			loc = l
+		}
+	}
+	layout, ok := dateLayouts[locale]
+	if !ok {
+		language, _, _ := strings.Cut(locale, "-")
+		if layout, ok = dateLayouts[language]; !ok {
+			layout = "02/01/2006"
+		}
+	}
+	return t.In(loc).Format(layout)
+}
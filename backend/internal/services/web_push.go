@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository"
+)
+
+const (
+	pushTimeout    = 10 * time.Second
+	pushTTL        = 24 * time.Hour // how long push services hold a message for an offline browser
+	pushRecordSize = 4096
+	vapidExpiry    = 12 * time.Hour
+)
+
+// PushNotifier delivers notifications to the browsers a user subscribed with the Web Push
+// protocol: payloads are encrypted for each browser (RFC 8291) and requests are signed with
+// the server's VAPID key (RFC 8292).
+type PushNotifier struct {
+	repo       repository.NotificationRepository
+	privateKey *ecdsa.PrivateKey
+	publicKey  string // uncompressed point, base64url encoded as browsers expect it
+	subject    string
+	client     *http.Client
+	now        func() time.Time
+}
+
+// NewPushNotifier creates a push notifier from a base64url VAPID private key. subject is a
+// mailto: or https: contact for push services.
+func NewPushNotifier(repo repository.NotificationRepository, privateKey, subject string) (*PushNotifier, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	// Endpoints come from browsers, so like webhooks they must not reach the server's own
+	// network; push services are expected to be up, so requests are not retried
+	dialer := &net.Dialer{Timeout: pushTimeout, Control: rejectPrivateAddress}
+	return &PushNotifier{
+		repo:       repo,
+		privateKey: key,
+		publicKey:  base64.RawURLEncoding.EncodeToString(ecdhKey.PublicKey().Bytes()),
+		subject:    subject,
+		now:        time.Now,
+		client: &http.Client{
+			Timeout:   pushTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: pushTimeout},
+		},
+	}, nil
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with
+func (p *PushNotifier) PublicKey() string {
+	return p.publicKey
+}
+
+func (p *PushNotifier) Channel() models.NotificationChannel {
+	return models.NotificationChannelPush
+}
+
+// pushPayload is what the service worker receives
+type pushPayload struct {
+	Event models.NotificationEvent `json:"event"`
+	Title string                   `json:"title"`
+	Body  string                   `json:"body"`
+	URL   string                   `json:"url,omitempty"`
+}
+
+// Notify pushes a notification to every browser of the user. Subscriptions the push service
+// reports as gone are removed.
+func (p *PushNotifier) Notify(user *models.User, n models.Notification) error {
+	subs, err := p.repo.ListPushSubscriptions(user.ID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(pushPayload{Event: n.Event, Title: n.Title, Body: n.Body, URL: n.URL})
+	if err != nil {
+		return fmt.Errorf("failed to encode push payload: %w", err)
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		gone, err := p.send(sub, payload)
+		if gone {
+			if err := p.repo.DeletePushSubscription(user.ID, sub.Endpoint); err != nil && !errors.Is(err, repository.ErrPushSubscriptionNotFound) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send delivers a payload to one subscription. gone reports a subscription the browser dropped.
+func (p *PushNotifier) send(sub models.PushSubscription, payload []byte) (gone bool, err error) {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return false, err
+	}
+	auth, err := p.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprintf("%d", int(pushTTL.Seconds())))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, nil
+	case resp.StatusCode/100 != 2:
+		return false, fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// vapidAuthorization signs a token for the push service of an endpoint
+func (p *PushNotifier) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": p.now().Add(vapidExpiry).Unix(),
+		"sub": p.subject,
+	})
+	signed, err := token.SignedString(p.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, p.publicKey), nil
+}
+
+// decodePushKeys decodes a subscription's P-256 public key and authentication secret
+func decodePushKeys(sub models.PushSubscription) (*ecdh.PublicKey, []byte, error) {
+	rawKey, err := base64.RawURLEncoding.DecodeString(trimBase64Padding(sub.Keys.P256dh))
+	if err != nil {
+		return nil, nil, fmt.Errorf("p256dh key: %w", err)
+	}
+	key, err := ecdh.P256().NewPublicKey(rawKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("p256dh key: %w", err)
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(trimBase64Padding(sub.Keys.Auth))
+	if err != nil {
+		return nil, nil, fmt.Errorf("auth secret: %w", err)
+	}
+	if len(secret) != 16 {
+		return nil, nil, fmt.Errorf("auth secret must be 16 bytes")
+	}
+	return key, secret, nil
+}
+
+func trimBase64Padding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// encryptPushPayload encrypts a payload for a subscription as a single aes128gcm record
+// (RFC 8188), with keys derived from an ephemeral ECDH exchange as RFC 8291 describes
+func encryptPushPayload(sub models.PushSubscription, payload []byte) ([]byte, error) {
+	uaPublic, authSecret, err := decodePushKeys(sub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPushSubscription, err)
+	}
+	if len(payload)+1+aes.BlockSize > pushRecordSize {
+		return nil, fmt.Errorf("push payload of %d bytes is too large", len(payload))
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate push key: %w", err)
+	}
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive push secret: %w", err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate push salt: %w", err)
+	}
+
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	keyInfo := "WebPush: info\x00" + string(uaPublic.Bytes()) + string(asPublicBytes)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key id length and the key id (the ephemeral public key)
+	var out bytes.Buffer
+	out.Write(salt)
+	binary.Write(&out, binary.BigEndian, uint32(pushRecordSize))
+	out.WriteByte(byte(len(asPublicBytes)))
+	out.Write(asPublicBytes)
+	// 0x02 delimits the last (and only) record
+	out.Write(gcm.Seal(nil, nonce, append(payload, 0x02), nil))
+	return out.Bytes(), nil
+}
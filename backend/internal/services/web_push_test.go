@@ -0,0 +1,158 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/repository/mocks"
+)
+
+// newTestBrowser generates the keys of a browser subscription to endpoint
+func newTestBrowser(t *testing.T, endpoint string) (*ecdh.PrivateKey, []byte, models.PushSubscription) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	secret := make([]byte, 16)
+	_, err = rand.Read(secret)
+	require.NoError(t, err)
+
+	var sub models.PushSubscription
+	sub.Endpoint = endpoint
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString(secret)
+	return key, secret, sub
+}
+
+// decryptPushPayload does what the browser does with a pushed message
+func decryptPushPayload(t *testing.T, key *ecdh.PrivateKey, authSecret, body []byte) []byte {
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	serverKey, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	require.NoError(t, err)
+	ciphertext := body[21+idLen:]
+	require.LessOrEqual(t, len(ciphertext), int(recordSize))
+
+	shared, err := key.ECDH(serverKey)
+	require.NoError(t, err)
+	keyInfo := "WebPush: info\x00" + string(key.PublicKey().Bytes()) + string(serverKey.Bytes())
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, keyInfo, 32)
+	require.NoError(t, err)
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	require.NoError(t, err)
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func newTestVAPIDKey(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := key.Bytes()
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func TestEncryptPushPayload_RoundTrip(t *testing.T) {
+	key, secret, sub := newTestBrowser(t, "https://push.example.com/abc")
+
+	body, err := encryptPushPayload(sub, []byte(`{"title":"hello"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, `{"title":"hello"}`, string(decryptPushPayload(t, key, secret, body)))
+}
+
+func TestEncryptPushPayload_InvalidKeys(t *testing.T) {
+	_, _, sub := newTestBrowser(t, "https://push.example.com/abc")
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString([]byte("short"))
+
+	_, err := encryptPushPayload(sub, []byte("{}"))
+
+	assert.ErrorIs(t, err, ErrInvalidPushSubscription)
+}
+
+func TestNewPushNotifier_InvalidKey(t *testing.T) {
+	_, err := NewPushNotifier(&mocks.MockNotificationRepo{}, "not a key", "mailto:ops@example.com")
+
+	assert.Error(t, err)
+}
+
+func TestPushNotifier_Notify(t *testing.T) {
+	var bodies [][]byte
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		authorization = r.Header.Get("Authorization")
+		assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key, secret, live := newTestBrowser(t, srv.URL+"/live")
+	_, _, gone := newTestBrowser(t, srv.URL+"/gone")
+	var deleted []string
+	notifier, err := NewPushNotifier(&mocks.MockNotificationRepo{
+		ListPushSubscriptionsFunc: func(userID string) ([]models.PushSubscription, error) {
+			return []models.PushSubscription{live, gone}, nil
+		},
+		DeletePushSubscriptionFunc: func(userID, endpoint string) error {
+			deleted = append(deleted, endpoint)
+			return nil
+		},
+	}, newTestVAPIDKey(t), "mailto:ops@example.com")
+	require.NoError(t, err)
+	notifier.client = srv.Client()
+
+	err = notifier.Notify(&models.User{ID: "user-1"}, importCompletedNotification("games.pgn", 5))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{gone.Endpoint}, deleted)
+	require.Len(t, bodies, 1)
+	var payload pushPayload
+	require.NoError(t, json.Unmarshal(decryptPushPayload(t, key, secret, bodies[0]), &payload))
+	assert.Equal(t, "5 games were imported from games.pgn.", payload.Body)
+
+	// The VAPID token is signed for the push service's origin with the advertised key
+	require.True(t, strings.HasPrefix(authorization, "vapid t="))
+	parts := strings.SplitN(strings.TrimPrefix(authorization, "vapid t="), ", k=", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, notifier.PublicKey(), parts[1])
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(parts[0], claims, func(token *jwt.Token) (any, error) {
+		return &notifier.privateKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL, claims["aud"])
+	assert.Equal(t, "mailto:ops@example.com", claims["sub"])
+}
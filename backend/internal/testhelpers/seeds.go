@@ -10,14 +10,20 @@ import (
 	"github.com/treechess/backend/internal/models"
 )
 
+// TestEmail is the address seeded users and users registered by the helpers get
+func TestEmail(username string) string {
+	return username + "@example.com"
+}
+
 // SeedUser creates a user with the given credentials using bcrypt.MinCost for speed.
+// The user's email is TestEmail(username).
 func SeedUser(t *testing.T, repos *Repos, username, password string) *models.User {
 	t.Helper()
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 	if err != nil {
 		t.Fatalf("SeedUser: bcrypt: %v", err)
 	}
-	user, err := repos.User.Create(username, string(hash))
+	user, err := repos.User.Create(TestEmail(username), username, string(hash))
 	if err != nil {
 		t.Fatalf("SeedUser: %v", err)
 	}
@@ -37,7 +43,7 @@ func SeedRepertoire(t *testing.T, repos *Repos, userID, name string, color model
 // SeedAnalysis saves an analysis for the given user.
 func SeedAnalysis(t *testing.T, repos *Repos, userID, username, filename string, results []models.GameAnalysis) *models.AnalysisSummary {
 	t.Helper()
-	summary, err := repos.Analysis.Save(userID, username, filename, len(results), results)
+	summary, err := repos.Analysis.Save(userID, username, filename, models.ImportProvenance{Source: models.ImportSourcePGN}, len(results), results)
 	if err != nil {
 		t.Fatalf("SeedAnalysis: %v", err)
 	}
@@ -4,6 +4,8 @@ package testhelpers
 
 import (
 	"bytes"
+	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,14 +13,15 @@ import (
 
 	"github.com/labstack/echo/v4"
 
-	"github.com/treechess/backend/internal/handlers"
-	appMiddleware "github.com/treechess/backend/internal/middleware"
+	"github.com/treechess/backend/config"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/server"
 	"github.com/treechess/backend/internal/services"
 )
 
 const testJWTSecret = "integration-test-secret-key-32chars!"
 
-// TestServer holds an Echo instance with all routes wired to real services.
+// TestServer holds the whole application, as main wires it, on the test database.
 type TestServer struct {
 	Echo      *echo.Echo
 	AuthSvc   *services.AuthService
@@ -26,76 +29,71 @@ type TestServer struct {
 	ImportSvc *services.ImportService
 }
 
-// SetupTestServer creates a full Echo server with real services and routes.
-func SetupTestServer(t *testing.T, repos *Repos) *TestServer {
+// TestConfig is the configuration test servers run with. Requests from httptest's default
+// client address are exempt from rate limiting, so tests can make as many as they need.
+func TestConfig(t *testing.T) config.Config {
 	t.Helper()
+	_, testNet, err := net.ParseCIDR("192.0.2.0/24")
+	if err != nil {
+		t.Fatalf("TestConfig: %v", err)
+	}
+	return config.Config{
+		Environment:              config.EnvDevelopment,
+		AllowedOrigins:           []string{"http://localhost:5173"},
+		RateLimitExempt:          []*net.IPNet{testNet},
+		JWTSecret:                testJWTSecret,
+		JWTExpiry:                168 * time.Hour,
+		FrontendURL:              "http://localhost:5173",
+		PasswordResetExpiryHours: 1,
+		EvalRetention:            90 * 24 * time.Hour,
+		ImportSpoolDir:           t.TempDir(),
+		AnalysisWorkers:          1,
+	}
+}
 
-	authSvc := services.NewAuthService(repos.User, testJWTSecret, 168*time.Hour)
-	repertoireSvc := services.NewRepertoireService(repos.Repertoire)
-	engineSvc := services.NewEngineService(repos.EngineEval, repos.Analysis)
-	importSvc := services.NewImportService(repertoireSvc, repos.Analysis,
-		services.WithFingerprintRepo(repos.Fingerprint),
-		services.WithEngineService(engineSvc),
-	)
-
-	e := echo.New()
-	e.HideBanner = true
-
-	authHandler := handlers.NewAuthHandler(authSvc)
-
-	// Public routes
-	e.POST("/api/auth/register", authHandler.RegisterHandler)
-	e.POST("/api/auth/login", authHandler.LoginHandler)
-
-	// Protected routes
-	protected := e.Group("", appMiddleware.JWTAuth(authSvc))
-
-	protected.GET("/api/auth/me", authHandler.MeHandler)
-
-	// Repertoire routes
-	protected.GET("/api/repertoires", handlers.ListRepertoiresHandler(repertoireSvc))
-	protected.POST("/api/repertoires", handlers.CreateRepertoireHandler(repertoireSvc))
-	protected.GET("/api/repertoires/:id", handlers.GetRepertoireHandler(repertoireSvc))
-	protected.PATCH("/api/repertoires/:id", handlers.UpdateRepertoireHandler(repertoireSvc))
-	protected.DELETE("/api/repertoires/:id", handlers.DeleteRepertoireHandler(repertoireSvc))
-	protected.POST("/api/repertoires/:id/nodes", handlers.AddNodeHandler(repertoireSvc))
-	protected.DELETE("/api/repertoires/:id/nodes/:nodeId", handlers.DeleteNodeHandler(repertoireSvc))
-	protected.POST("/api/repertoires/merge", handlers.MergeRepertoiresHandler(repertoireSvc))
-	protected.POST("/api/repertoires/:id/extract", handlers.ExtractSubtreeHandler(repertoireSvc))
-	protected.POST("/api/repertoires/:id/merge-transpositions", handlers.MergeTranspositionsHandler(repertoireSvc))
-
-	// Import routes
-	importHandler := handlers.NewImportHandler(importSvc, nil, nil)
-	protected.POST("/api/imports", importHandler.UploadHandler)
-	protected.GET("/api/analyses", importHandler.ListAnalysesHandler)
-	protected.GET("/api/analyses/:id", importHandler.GetAnalysisHandler)
-	protected.DELETE("/api/analyses/:id", importHandler.DeleteAnalysisHandler)
-
-	// Games routes
-	protected.GET("/api/games", importHandler.GetGamesHandler)
-	protected.DELETE("/api/games/:analysisId/:gameIndex", importHandler.DeleteGameHandler)
-	protected.POST("/api/games/:analysisId/:gameIndex/reanalyze", importHandler.ReanalyzeGameHandler)
-	protected.POST("/api/games/:analysisId/:gameIndex/view", importHandler.MarkGameViewedHandler)
-	protected.GET("/api/games/insights", importHandler.GetInsightsHandler)
+// SetupTestServer wires the full Echo application, every route and middleware included, on the
+// test database. Background workers are not started.
+func SetupTestServer(t *testing.T, tdb *TestDB) *TestServer {
+	t.Helper()
+	return SetupTestServerWithConfig(t, tdb, TestConfig(t))
+}
 
+// SetupTestServerWithConfig is SetupTestServer with a configuration of the test's own, for
+// tests of configurable behavior such as rate limiting.
+func SetupTestServerWithConfig(t *testing.T, tdb *TestDB, cfg config.Config) *TestServer {
+	t.Helper()
+	deps, err := server.NewDeps(cfg, tdb.serverDB(t))
+	if err != nil {
+		t.Fatalf("SetupTestServer: %v", err)
+	}
 	return &TestServer{
-		Echo:      e,
-		AuthSvc:   authSvc,
-		RepSvc:    repertoireSvc,
-		ImportSvc: importSvc,
+		Echo:      server.New(cfg, deps),
+		AuthSvc:   deps.Auth,
+		RepSvc:    deps.Repertoires,
+		ImportSvc: deps.Imports,
 	}
 }
 
 // AuthToken registers a user via the auth service and returns a JWT token.
+// The user's email is TestEmail(username).
 func (ts *TestServer) AuthToken(t *testing.T, username, password string) string {
 	t.Helper()
-	resp, err := ts.AuthSvc.Register(username, password)
+	resp, err := ts.AuthSvc.Register(TestEmail(username), username, password, models.SessionDevice{})
 	if err != nil {
 		t.Fatalf("AuthToken: %v", err)
 	}
 	return resp.Token
 }
 
+// Register registers a user through the HTTP API and returns the response, failing the test
+// unless the user was created. The user's email is TestEmail(username).
+func (ts *TestServer) Register(t *testing.T, username, password string) models.AuthResponse {
+	t.Helper()
+	var resp models.AuthResponse
+	ts.DoJSON(t, http.MethodPost, "/api/auth/register", models.RegisterRequest{Email: TestEmail(username), Username: username, Password: password}, "", http.StatusCreated, &resp)
+	return resp
+}
+
 // AuthRequest creates an authenticated HTTP request.
 func AuthRequest(method, path string, body []byte, token string) *http.Request {
 	var req *http.Request
@@ -117,3 +115,27 @@ func (ts *TestServer) DoRequest(req *http.Request) *httptest.ResponseRecorder {
 	ts.Echo.ServeHTTP(rec, req)
 	return rec
 }
+
+// DoJSON sends body as JSON, failing the test unless the response has the wanted status, and
+// decodes the response into out when it is not nil. A nil body sends no body.
+func (ts *TestServer) DoJSON(t *testing.T, method, path string, body interface{}, token string, wantStatus int, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			t.Fatalf("DoJSON: marshal: %v", err)
+		}
+	}
+	rec := ts.DoRequest(AuthRequest(method, path, payload, token))
+	if rec.Code != wantStatus {
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, rec.Code, wantStatus, rec.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return rec
+}
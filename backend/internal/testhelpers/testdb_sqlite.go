@@ -0,0 +1,116 @@
+//go:build integration && sqlite
+
+package testhelpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/treechess/backend/internal/repository"
+)
+
+// TestDB wraps a SQLite database file in a temporary directory with its repos.
+type TestDB struct {
+	SQLite *repository.SQLiteDB
+	dir    string
+	repos  *Repos
+}
+
+// SetupTestDB creates a SQLite database, runs migrations, and returns a TestDB.
+// It uses t.Fatal on error, so it's suitable for use in individual test functions.
+func SetupTestDB(t *testing.T) *TestDB {
+	t.Helper()
+	tdb, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("SetupTestDB: %v", err)
+	}
+	return tdb
+}
+
+// MustSetupTestDB creates a SQLite database for use in TestMain.
+// It panics on error instead of using t.Fatal.
+func MustSetupTestDB() *TestDB {
+	tdb, err := setupTestDB()
+	if err != nil {
+		panic(fmt.Sprintf("MustSetupTestDB: %v", err))
+	}
+	return tdb
+}
+
+func setupTestDB() (*TestDB, error) {
+	dir, err := os.MkdirTemp("", "treechess-test-")
+	if err != nil {
+		return nil, fmt.Errorf("creating database directory: %w", err)
+	}
+
+	db, err := repository.NewSQLiteDB(filepath.Join(dir, "treechess_test.db"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("initializing DB with migrations: %w", err)
+	}
+
+	return &TestDB{SQLite: db, dir: dir}, nil
+}
+
+// Teardown closes the database and removes its file.
+func (tdb *TestDB) Teardown() {
+	if tdb.SQLite != nil {
+		tdb.SQLite.Close()
+	}
+	_ = os.RemoveAll(tdb.dir)
+}
+
+// TruncateAll removes all data from all tables, preserving schema.
+func (tdb *TestDB) TruncateAll(t *testing.T) {
+	t.Helper()
+	// Children first: SQLite has no TRUNCATE ... CASCADE
+	_, err := tdb.SQLite.DB.Exec(`
+		DELETE FROM viewed_games;
+		DELETE FROM skipped_duplicates;
+		DELETE FROM games;
+		DELETE FROM analyses;
+		DELETE FROM repertoire_notes;
+		DELETE FROM repertoire_revisions;
+		DELETE FROM repertoire_positions;
+		DELETE FROM repertoire_search;
+		DELETE FROM repertoires;
+		DELETE FROM categories;
+		DELETE FROM linked_accounts;
+		DELETE FROM users;
+	`)
+	if err != nil {
+		t.Fatalf("TruncateAll: %v", err)
+	}
+}
+
+// Repos returns the SQLite repositories wired to the test database.
+func (tdb *TestDB) Repos() *Repos {
+	if tdb.repos == nil {
+		tdb.repos = &Repos{
+			User:       repository.NewSQLiteUserRepo(tdb.SQLite.DB),
+			Repertoire: repository.NewSQLiteRepertoireRepo(tdb.SQLite.DB),
+			Analysis:   repository.NewSQLiteAnalysisRepo(tdb.SQLite.DB),
+		}
+	}
+	return tdb.repos
+}
+
+// FingerprintRepo returns nil: fingerprints have no SQLite implementation.
+func (tdb *TestDB) FingerprintRepo(batchSize int) *repository.PostgresFingerprintRepo {
+	return nil
+}
+
+// RequirePostgres skips the test: it uses stores that have no SQLite implementation.
+func RequirePostgres(t *testing.T) {
+	t.Helper()
+	t.Skip("requires the PostgreSQL backend")
+}
+
+// serverDB skips the test: the full server is only wired on PostgreSQL.
+func (tdb *TestDB) serverDB(t *testing.T) *repository.DB {
+	t.Helper()
+	t.Skip("the server requires the PostgreSQL backend")
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 )
 
 func TestCascadeDelete_Analysis(t *testing.T) {
+	testhelpers.RequirePostgres(t)
 	testDB.TruncateAll(t)
 	repos := testDB.Repos()
 	user := testhelpers.SeedUser(t, repos, "cascadeuser", "password123")
@@ -67,6 +68,7 @@ func TestCascadeDelete_Analysis(t *testing.T) {
 }
 
 func TestUniqueFingerprint(t *testing.T) {
+	testhelpers.RequirePostgres(t)
 	testDB.TruncateAll(t)
 	repos := testDB.Repos()
 	user := testhelpers.SeedUser(t, repos, "uniquefp", "password123")
@@ -87,7 +89,29 @@ func TestUniqueFingerprint(t *testing.T) {
 	assert.ErrorIs(t, err, services.ErrAllGamesDuplicate)
 }
 
+func TestFingerprintRepo_Batches(t *testing.T) {
+	testhelpers.RequirePostgres(t)
+	testDB.TruncateAll(t)
+	repos := testDB.Repos()
+	user := testhelpers.SeedUser(t, repos, "fpbatches", "password123")
+
+	// One fingerprint per query, so two games take two queries
+	fingerprintRepo := testDB.FingerprintRepo(1)
+	importSvc := services.NewImportService(services.NewRepertoireService(repos.Repertoire), repos.Analysis,
+		services.WithFingerprintRepo(fingerprintRepo),
+	)
+
+	pgn := testhelpers.TwoGamePGN("fpbatches", "opponent")
+	summary, _, err := importSvc.ParseAndAnalyze("test.pgn", "fpbatches", user.ID, pgn)
+	require.NoError(t, err)
+	require.Equal(t, 2, summary.GameCount)
+
+	_, _, err = importSvc.ParseAndAnalyze("test2.pgn", "fpbatches", user.ID, pgn)
+	assert.ErrorIs(t, err, services.ErrAllGamesDuplicate)
+}
+
 func TestFingerprintUniquePerUser(t *testing.T) {
+	testhelpers.RequirePostgres(t)
 	testDB.TruncateAll(t)
 	repos := testDB.Repos()
 	userA := testhelpers.SeedUser(t, repos, "fpusera", "password123")
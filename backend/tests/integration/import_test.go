@@ -1,4 +1,4 @@
-//go:build integration
+//go:build integration && !sqlite
 
 package integration
 
@@ -226,7 +226,7 @@ func TestImportPipeline_DeleteLastGame_DeletesAnalysis(t *testing.T) {
 	assert.ErrorIs(t, err, repository.ErrAnalysisNotFound)
 
 	// No analyses should remain
-	analyses, err := importSvc.GetAnalyses(user.ID)
+	analyses, err := importSvc.GetAnalyses(user.ID, "")
 	require.NoError(t, err)
 	assert.Len(t, analyses, 0)
 }
@@ -252,7 +252,7 @@ func TestImportPipeline_DeleteAnalysis_CascadeAll(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify analysis is gone
-	analyses, err := importSvc.GetAnalyses(user.ID)
+	analyses, err := importSvc.GetAnalyses(user.ID, "")
 	require.NoError(t, err)
 	assert.Len(t, analyses, 0)
 
@@ -282,12 +282,12 @@ func TestImportPipeline_ListGames_Pagination(t *testing.T) {
 	}
 
 	// Get all games with limit/offset
-	page1, err := importSvc.GetAllGames(user.ID, 5, 0, "", "", "")
+	page1, err := importSvc.GetAllGames(user.ID, 5, 0, "", "", "", "", false)
 	require.NoError(t, err)
 	assert.Equal(t, 9, page1.Total)
 	assert.Len(t, page1.Games, 5)
 
-	page2, err := importSvc.GetAllGames(user.ID, 5, 5, "", "", "")
+	page2, err := importSvc.GetAllGames(user.ID, 5, 5, "", "", "", "", false)
 	require.NoError(t, err)
 	assert.Len(t, page2.Games, 4)
 }
@@ -320,17 +320,17 @@ func TestImportPipeline_ListGames_SourceFilter(t *testing.T) {
 	require.NoError(t, err)
 
 	// Filter by source=pgn
-	pgnGames, err := importSvc.GetAllGames(user.ID, 20, 0, "", "", "pgn")
+	pgnGames, err := importSvc.GetAllGames(user.ID, 20, 0, "", "", "pgn", "", false)
 	require.NoError(t, err)
 	assert.Equal(t, 1, pgnGames.Total)
 
 	// Filter by source=lichess
-	lichessGames, err := importSvc.GetAllGames(user.ID, 20, 0, "", "", "lichess")
+	lichessGames, err := importSvc.GetAllGames(user.ID, 20, 0, "", "", "lichess", "", false)
 	require.NoError(t, err)
 	assert.Equal(t, 1, lichessGames.Total)
 
 	// No filter returns all
-	allGames, err := importSvc.GetAllGames(user.ID, 20, 0, "", "", "")
+	allGames, err := importSvc.GetAllGames(user.ID, 20, 0, "", "", "", "", false)
 	require.NoError(t, err)
 	assert.Equal(t, 2, allGames.Total)
 }
@@ -402,14 +402,13 @@ func TestImportPipeline_ReanalyzeColorMismatch(t *testing.T) {
 	require.NoError(t, err)
 
 	// Reanalyze a white game against a black repertoire → color mismatch
-	_, err = importSvc.ReanalyzeGame(summary.ID, 0, blackRep.ID)
+	_, err = importSvc.ReanalyzeGame(user.ID, summary.ID, 0, blackRep.ID)
 	assert.ErrorIs(t, err, services.ErrColorMismatch)
 }
 
 func TestImportPipeline_HandlerLevel_Upload(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 	token := ts.AuthToken(t, "uploaduser", "password123")
 
 	// Create multipart form
@@ -16,11 +16,10 @@ import (
 
 func TestAuth_RegisterAndLogin(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	// Register
-	regBody, _ := json.Marshal(models.RegisterRequest{Username: "authuser", Password: "password123"})
+	regBody, _ := json.Marshal(models.RegisterRequest{Email: testhelpers.TestEmail("authuser"), Username: "authuser", Password: "password123"})
 	req := testhelpers.AuthRequest(http.MethodPost, "/api/auth/register", regBody, "")
 	rec := ts.DoRequest(req)
 	require.Equal(t, http.StatusCreated, rec.Code)
@@ -32,7 +31,7 @@ func TestAuth_RegisterAndLogin(t *testing.T) {
 	assert.Equal(t, "authuser", regResp.User.Username)
 
 	// Login with same credentials
-	loginBody, _ := json.Marshal(models.LoginRequest{Username: "authuser", Password: "password123"})
+	loginBody, _ := json.Marshal(models.LoginRequest{Email: testhelpers.TestEmail("authuser"), Password: "password123"})
 	req = testhelpers.AuthRequest(http.MethodPost, "/api/auth/login", loginBody, "")
 	rec = ts.DoRequest(req)
 	require.Equal(t, http.StatusOK, rec.Code)
@@ -50,10 +49,9 @@ func TestAuth_RegisterAndLogin(t *testing.T) {
 
 func TestAuth_DuplicateUsername(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
-	regBody, _ := json.Marshal(models.RegisterRequest{Username: "dupname", Password: "password123"})
+	regBody, _ := json.Marshal(models.RegisterRequest{Email: testhelpers.TestEmail("dupname"), Username: "dupname", Password: "password123"})
 
 	// First registration succeeds
 	req := testhelpers.AuthRequest(http.MethodPost, "/api/auth/register", regBody, "")
@@ -68,16 +66,15 @@ func TestAuth_DuplicateUsername(t *testing.T) {
 
 func TestAuth_InvalidCredentials(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	// Register
-	regBody, _ := json.Marshal(models.RegisterRequest{Username: "logintest", Password: "password123"})
+	regBody, _ := json.Marshal(models.RegisterRequest{Email: testhelpers.TestEmail("logintest"), Username: "logintest", Password: "password123"})
 	req := testhelpers.AuthRequest(http.MethodPost, "/api/auth/register", regBody, "")
 	ts.DoRequest(req)
 
 	// Login with wrong password
-	loginBody, _ := json.Marshal(models.LoginRequest{Username: "logintest", Password: "wrongpassword"})
+	loginBody, _ := json.Marshal(models.LoginRequest{Email: testhelpers.TestEmail("logintest"), Password: "wrongpassword"})
 	req = testhelpers.AuthRequest(http.MethodPost, "/api/auth/login", loginBody, "")
 	rec := ts.DoRequest(req)
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
@@ -85,8 +82,7 @@ func TestAuth_InvalidCredentials(t *testing.T) {
 
 func TestAuth_UnauthenticatedAccess(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	// No token → 401 on protected endpoints
 	endpoints := []struct {
@@ -112,8 +108,7 @@ func TestAuth_UnauthenticatedAccess(t *testing.T) {
 
 func TestUserIsolation_RepertoireAccess(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	tokenA := ts.AuthToken(t, "usera_rep", "password123")
 	tokenB := ts.AuthToken(t, "userb_rep", "password123")
@@ -155,8 +150,7 @@ func TestUserIsolation_RepertoireAccess(t *testing.T) {
 
 func TestUserIsolation_AnalysisAccess(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	tokenA := ts.AuthToken(t, "usera_ana", "password123")
 	tokenB := ts.AuthToken(t, "userb_ana", "password123")
@@ -184,8 +178,7 @@ func TestUserIsolation_AnalysisAccess(t *testing.T) {
 
 func TestUserIsolation_ListRepertoires(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	tokenA := ts.AuthToken(t, "usera_list", "password123")
 	tokenB := ts.AuthToken(t, "userb_list", "password123")
@@ -227,8 +220,7 @@ func TestUserIsolation_ListRepertoires(t *testing.T) {
 
 func TestUserIsolation_ListAnalyses(t *testing.T) {
 	testDB.TruncateAll(t)
-	repos := testDB.Repos()
-	ts := testhelpers.SetupTestServer(t, repos)
+	ts := testhelpers.SetupTestServer(t, testDB)
 
 	tokenA := ts.AuthToken(t, "usera_analist", "password123")
 	tokenB := ts.AuthToken(t, "userb_analist", "password123")
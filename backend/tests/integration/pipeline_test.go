@@ -1,9 +1,10 @@
-//go:build integration
+//go:build integration && !sqlite
 
 package integration
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,18 +31,14 @@ func TestEngineEvalPipeline(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, summary.ID)
 
-	// Import should have created pending engine evals
-	pending, err := repos.EngineEval.GetPending(10)
+	// Import should have created pending engine evals; a worker claims them
+	claimed, err := repos.EngineEval.ClaimPending("worker-1", 10, time.Minute)
 	require.NoError(t, err)
-	assert.GreaterOrEqual(t, len(pending), 1)
+	assert.GreaterOrEqual(t, len(claimed), 1)
 
-	// Mark one as processing
-	evalID := pending[0].ID
-	err = repos.EngineEval.MarkProcessing(evalID)
-	require.NoError(t, err)
-
-	// Verify it's no longer pending
-	stillPending, err := repos.EngineEval.GetPending(10)
+	// Claimed evals are leased: another worker does not get them
+	evalID := claimed[0].ID
+	stillPending, err := repos.EngineEval.ClaimPending("worker-2", 10, time.Minute)
 	require.NoError(t, err)
 	for _, p := range stillPending {
 		assert.NotEqual(t, evalID, p.ID)
@@ -133,7 +130,7 @@ func TestReanalyzeGame(t *testing.T) {
 	require.Len(t, results, 1)
 
 	// Reanalyze against d4 repertoire
-	reanalyzed, err := importSvc.ReanalyzeGame(summary.ID, 0, d4Rep.ID)
+	reanalyzed, err := importSvc.ReanalyzeGame(user.ID, summary.ID, 0, d4Rep.ID)
 	require.NoError(t, err)
 	require.NotNil(t, reanalyzed)
 
@@ -0,0 +1,99 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/treechess/backend/internal/middleware"
+	"github.com/treechess/backend/internal/models"
+	"github.com/treechess/backend/internal/testhelpers"
+)
+
+// uploadPGN posts a PGN file to the import endpoint as the frontend does
+func uploadPGN(t *testing.T, ts *testhelpers.TestServer, token, username, pgn string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("username", username))
+	part, err := writer.CreateFormFile("file", "games.pgn")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(pgn))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/imports", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return ts.DoRequest(req)
+}
+
+func TestServer_AuthRepertoireImportFlow(t *testing.T) {
+	testDB.TruncateAll(t)
+	ts := testhelpers.SetupTestServer(t, testDB)
+
+	// Register, then log in again as a returning user would
+	ts.Register(t, "flowuser", "password123")
+	var login models.AuthResponse
+	ts.DoJSON(t, http.MethodPost, "/api/auth/login",
+		models.LoginRequest{Email: testhelpers.TestEmail("flowuser"), Password: "password123"}, "", http.StatusOK, &login)
+	token := login.Token
+	require.NotEmpty(t, token)
+
+	// Build 1.e4 e5 2.Nf3 node by node
+	var rep models.Repertoire
+	ts.DoJSON(t, http.MethodPost, "/api/repertoires",
+		models.CreateRepertoireRequest{Name: "Open games", Color: models.ColorWhite}, token, http.StatusCreated, &rep)
+	parentID := rep.TreeData.ID
+	for i, move := range []string{"e4", "e5", "Nf3"} {
+		ts.DoJSON(t, http.MethodPost, fmt.Sprintf("/api/repertoires/%s/nodes", rep.ID),
+			models.AddNodeRequest{ParentID: parentID, Move: move, MoveNumber: i/2 + 1}, token, http.StatusOK, &rep)
+		node := rep.TreeData.Children[0]
+		for node.Move == nil || *node.Move != move {
+			node = node.Children[0]
+		}
+		parentID = node.ID
+	}
+
+	// Import a game following the line and find it matched to the repertoire
+	rec := uploadPGN(t, ts, token, "flowuser", testhelpers.SimplePGN("flowuser", "opponent"))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var games models.GamesResponse
+	ts.DoJSON(t, http.MethodGet, "/api/games", nil, token, http.StatusOK, &games)
+	require.Equal(t, 1, games.Total)
+	assert.Equal(t, models.ColorWhite, games.Games[0].UserColor)
+	assert.Equal(t, rep.ID, games.Games[0].RepertoireID)
+
+	// Another user sees neither the repertoire nor the game
+	other := ts.Register(t, "otheruser", "password123")
+	ts.DoJSON(t, http.MethodGet, "/api/repertoires/"+rep.ID, nil, other.Token, http.StatusNotFound, nil)
+	var otherGames models.GamesResponse
+	ts.DoJSON(t, http.MethodGet, "/api/games", nil, other.Token, http.StatusOK, &otherGames)
+	assert.Equal(t, 0, otherGames.Total)
+}
+
+func TestServer_AuthRateLimit(t *testing.T) {
+	testDB.TruncateAll(t)
+	// Without the test exemption, the auth endpoints allow bursts of 5 attempts per address
+	cfg := testhelpers.TestConfig(t)
+	cfg.RateLimitExempt = nil
+	ts := testhelpers.SetupTestServerWithConfig(t, testDB, cfg)
+
+	login := models.LoginRequest{Email: testhelpers.TestEmail("nobody"), Password: "password123"}
+	for i := 0; i < 5; i++ {
+		rec := ts.DoJSON(t, http.MethodPost, "/api/auth/login", login, "", http.StatusUnauthorized, nil)
+		assert.NotEmpty(t, rec.Header().Get(middleware.HeaderRateLimitRemaining))
+	}
+
+	rec := ts.DoJSON(t, http.MethodPost, "/api/auth/login", login, "", http.StatusTooManyRequests, nil)
+	assert.NotEmpty(t, rec.Header().Get(middleware.HeaderRetryAfter))
+}
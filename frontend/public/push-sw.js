@@ -0,0 +1,17 @@
+// Shows the push notifications sent by the server and opens their page on click
+self.addEventListener('push', (event) => {
+  const data = event.data ? event.data.json() : {};
+  event.waitUntil(
+    self.registration.showNotification(data.title || 'TreeChess', {
+      body: data.body,
+      icon: '/favicon.svg',
+      tag: data.event,
+      data: { url: data.url || '/' },
+    })
+  );
+});
+
+self.addEventListener('notificationclick', (event) => {
+  event.notification.close();
+  event.waitUntil(self.clients.openWindow(event.notification.data.url));
+});
@@ -12,7 +12,7 @@ export function useAnalyses() {
   const loadAnalyses = useCallback(async () => {
     const signal = getSignal();
     try {
-      const data = await importApi.list({ signal });
+      const data = await importApi.list(undefined, { signal });
       if (!signal.aborted) {
         setAnalyses(data || []);
       }
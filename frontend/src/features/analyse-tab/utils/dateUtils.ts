@@ -9,6 +9,8 @@ export function formatSource(source: string): string {
   switch (source) {
     case 'lichess': return 'Lichess';
     case 'chesscom': return 'Chess.com';
+    case 'broadcast': return 'Broadcast';
+    case 'lichess_team': return 'Lichess team';
     case 'pgn': return 'PGN';
     default: return source;
   }
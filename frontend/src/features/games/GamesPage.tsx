@@ -28,6 +28,8 @@ const SOURCE_FILTERS = [
   { value: '', label: 'All' },
   { value: 'lichess', label: 'Lichess' },
   { value: 'chesscom', label: 'Chess.com' },
+  { value: 'broadcast', label: 'Broadcast' },
+  { value: 'lichess_team', label: 'Lichess team' },
   { value: 'pgn', label: 'PGN' },
 ] as const;
 
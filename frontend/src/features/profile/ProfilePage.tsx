@@ -201,7 +201,7 @@ export function ProfilePage() {
             Select which time controls to sync from Lichess/Chess.com.
           </p>
           <div className="flex gap-2 flex-wrap">
-            {(['rapid', 'blitz', 'bullet'] as const).map((format) => (
+            {(['rapid', 'blitz', 'bullet', 'daily'] as const).map((format) => (
               <button
                 key={format}
                 type="button"
@@ -1,29 +1,85 @@
 import axios from 'axios';
 import type {
   Repertoire,
+  RepertoireNode,
+  SlimRepertoire,
   AddNodeRequest,
   Color,
   AnalysisSummary,
   AnalysisDetail,
   UploadResponse,
+  GameSource,
   GamesResponse,
   GameAnalysis,
+  GameRepertoireSnapshot,
   LichessImportOptions,
   ChesscomImportOptions,
+  ImportPreview,
   CreateRepertoireRequest,
   UpdateRepertoireRequest,
   AuthResponse,
   User,
   UpdateProfileRequest,
+  Session,
+  APIToken,
+  APITokenScope,
+  CreatedAPIToken,
   SyncResult,
+  SyncRun,
   StudyInfo,
   StudyImportResponse,
   InsightsResponse,
+  InsightSettings,
+  ClassificationRule,
+  ClassificationRuleRequest,
+  MistakeExplanation,
   DashboardStatsResponse,
+  Recommendation,
   Category,
   CategoryWithRepertoires,
   CreateCategoryRequest,
-  UpdateCategoryRequest
+  UpdateCategoryRequest,
+  RepertoireTemplate,
+  PublishTemplateRequest,
+  RepertoireLine,
+  TrainingPosition,
+  TrainingAnswerResult,
+  TrainingActivity,
+  RepertoireMetrics,
+  RepertoireSearchResult,
+  ResultsOverlay,
+  OpponentReplies,
+  BookDepth,
+  DuplicatePolicy,
+  ImportJob,
+  TeamImport,
+  ImportSummary,
+  ImportSourceStats,
+  RepertoireCollaborator,
+  InviteCollaboratorRequest,
+  SharedRepertoire,
+  ExplorerPosition,
+  TablebasePosition,
+  FENDetails,
+  UpdateGameRequest,
+  GameNotes,
+  AdoptLineResponse,
+  CoachLink,
+  InviteCoachLinkRequest,
+  PrepRequest,
+  RepertoireRevision,
+  RevisionDiff,
+  StudyNotes,
+  StudyNotesRevision,
+  SparringSession,
+  UsageReport,
+  NotificationPreference,
+  NotificationSettings,
+  NotificationWebhook,
+  RepertoireExport,
+  CommentsImportResult,
+  AddNodeResponse,
+  TimeClass
 } from '../types';
 
 const TOKEN_STORAGE_KEY = 'treechess_token';
@@ -92,6 +148,11 @@ export const authApi = {
     return response.data;
   },
 
+  updateNotifications: async (data: { weeklyDigest: boolean }): Promise<User> => {
+    const response = await api.put('/auth/notifications', data);
+    return response.data;
+  },
+
   forgotPassword: async (email: string): Promise<{ message: string }> => {
     const response = await api.post('/auth/forgot-password', { email });
     return response.data;
@@ -111,18 +172,57 @@ export const authApi = {
     const response = await api.get('/auth/has-password');
     return response.data;
   },
+
+  listSessions: async (): Promise<Session[]> => {
+    const response = await api.get('/auth/sessions');
+    return response.data.sessions;
+  },
+
+  revokeSession: async (id: string): Promise<void> => {
+    await api.delete(`/auth/sessions/${id}`);
+  },
+};
+
+// Personal API tokens
+export const apiTokenApi = {
+  list: async (): Promise<APIToken[]> => {
+    const response = await api.get('/tokens');
+    return response.data.tokens;
+  },
+
+  create: async (name: string, scope: APITokenScope): Promise<CreatedAPIToken> => {
+    const response = await api.post('/tokens', { name, scope });
+    return response.data;
+  },
+
+  revoke: async (id: string): Promise<void> => {
+    await api.delete(`/tokens/${id}`);
+  },
 };
 
 // Repertoire API
 export const repertoireApi = {
-  list: async (color?: Color): Promise<Repertoire[]> => {
-    const params = color ? { color } : {};
+  list: async (color?: Color, includeArchived?: boolean): Promise<Repertoire[]> => {
+    const params = { ...(color ? { color } : {}), ...(includeArchived ? { includeArchived } : {}) };
     const response = await api.get('/repertoires', { params });
     return response.data;
   },
 
-  get: async (id: string): Promise<Repertoire> => {
-    const response = await api.get(`/repertoires/${id}`);
+  get: async (id: string, depth?: number): Promise<Repertoire> => {
+    const params = depth !== undefined ? { depth } : {};
+    const response = await api.get(`/repertoires/${id}`, { params });
+    return response.data;
+  },
+
+  getSlim: async (id: string, depth?: number): Promise<SlimRepertoire> => {
+    const params = depth !== undefined ? { depth, fields: 'slim' } : { fields: 'slim' };
+    const response = await api.get(`/repertoires/${id}`, { params });
+    return response.data;
+  },
+
+  getSubtree: async (id: string, nodeId: string, depth?: number): Promise<RepertoireNode> => {
+    const params = depth !== undefined ? { depth } : {};
+    const response = await api.get(`/repertoires/${id}/nodes/${nodeId}/subtree`, { params });
     return response.data;
   },
 
@@ -141,8 +241,10 @@ export const repertoireApi = {
     await api.delete(`/repertoires/${id}`);
   },
 
-  addNode: async (id: string, data: AddNodeRequest): Promise<Repertoire> => {
-    const response = await api.post(`/repertoires/${id}/nodes`, data);
+  addNode: async (id: string, data: AddNodeRequest, includeStats = false): Promise<AddNodeResponse> => {
+    const response = await api.post(`/repertoires/${id}/nodes`, data, {
+      params: includeStats ? { includeStats: true } : undefined
+    });
     return response.data;
   },
 
@@ -151,11 +253,21 @@ export const repertoireApi = {
     return response.data;
   },
 
-  listTemplates: async (): Promise<{ id: string; name: string; color: string; description: string }[]> => {
+  listTemplates: async (): Promise<RepertoireTemplate[]> => {
     const response = await api.get('/repertoires/templates');
     return response.data;
   },
 
+  publishTemplate: async (data: PublishTemplateRequest): Promise<RepertoireTemplate> => {
+    const response = await api.post('/repertoires/templates', data);
+    return response.data;
+  },
+
+  setTemplateFeatured: async (id: string, featured: boolean): Promise<RepertoireTemplate> => {
+    const response = await api.put(`/repertoires/templates/${id}/featured`, { featured });
+    return response.data;
+  },
+
   seedFromTemplates: async (templateIds: string[]): Promise<Repertoire[]> => {
     const response = await api.post('/repertoires/seed', { templateIds });
     return response.data;
@@ -166,6 +278,43 @@ export const repertoireApi = {
     return response.data;
   },
 
+  mirror: async (id: string, name?: string): Promise<Repertoire> => {
+    const response = await api.post(`/repertoires/${id}/mirror`, { name });
+    return response.data;
+  },
+
+  listRevisions: async (id: string): Promise<RepertoireRevision[]> => {
+    const response = await api.get(`/repertoires/${id}/revisions`);
+    return response.data;
+  },
+
+  diffRevisions: async (id: string, from: number, to: number): Promise<RevisionDiff> => {
+    const response = await api.get(`/repertoires/${id}/revisions/${from}/diff/${to}`);
+    return response.data;
+  },
+
+  restoreRevision: async (id: string, version: number): Promise<Repertoire> => {
+    const response = await api.post(`/repertoires/${id}/revisions/${version}/restore`);
+    return response.data;
+  },
+
+  // The current study notes, or an earlier version of them
+  getNotes: async (id: string, version?: number): Promise<StudyNotes> => {
+    const path = version ? `/repertoires/${id}/notes/revisions/${version}` : `/repertoires/${id}/notes`;
+    const response = await api.get(path);
+    return response.data;
+  },
+
+  saveNotes: async (id: string, content: string): Promise<StudyNotes> => {
+    const response = await api.put(`/repertoires/${id}/notes`, { content });
+    return response.data;
+  },
+
+  listNotesRevisions: async (id: string): Promise<StudyNotesRevision[]> => {
+    const response = await api.get(`/repertoires/${id}/notes/revisions`);
+    return response.data;
+  },
+
   mergeRepertoires: async (ids: string[], name: string): Promise<{ merged: Repertoire }> => {
     const response = await api.post('/repertoires/merge', { ids, name });
     return response.data;
@@ -181,6 +330,115 @@ export const repertoireApi = {
     return response.data;
   },
 
+  updateNodeTags: async (id: string, nodeId: string, tags: string[]): Promise<Repertoire> => {
+    const response = await api.patch(`/repertoires/${id}/nodes/${nodeId}/tags`, { tags });
+    return response.data;
+  },
+
+  reorderChildren: async (id: string, nodeId: string, childIds: string[]): Promise<Repertoire> => {
+    const response = await api.patch(`/repertoires/${id}/nodes/${nodeId}/reorder`, { childIds });
+    return response.data;
+  },
+
+  listLines: async (id: string, tags?: string[]): Promise<RepertoireLine[]> => {
+    const params = tags?.length ? { tags: tags.join(',') } : {};
+    const response = await api.get(`/repertoires/${id}/lines`, { params });
+    return response.data.lines;
+  },
+
+  exportStudySheet: async (id: string, format: 'markdown' | 'html' = 'markdown'): Promise<Blob> => {
+    const response = await api.get(`/repertoires/${id}/export`, { params: { format }, responseType: 'blob' });
+    return response.data;
+  },
+
+  exportJSON: async (id: string): Promise<RepertoireExport> => {
+    const response = await api.get(`/repertoires/${id}/export.json`);
+    return response.data;
+  },
+
+  importJSON: async (file: RepertoireExport): Promise<Repertoire> => {
+    const response = await api.post('/repertoires/import-json', file);
+    return response.data;
+  },
+
+  // Node comments as CSV keyed by move path, for editing them in a spreadsheet
+  exportCommentsCsv: async (id: string): Promise<Blob> => {
+    const response = await api.get(`/repertoires/${id}/comments.csv`, { responseType: 'blob' });
+    return response.data;
+  },
+
+  importComments: async (id: string, file: File): Promise<CommentsImportResult> => {
+    const formData = new FormData();
+    formData.append('file', file);
+    const response = await api.post(`/repertoires/${id}/comments`, formData, {
+      headers: {
+        'Content-Type': 'multipart/form-data'
+      }
+    });
+    return response.data;
+  },
+
+  getTrainingPositions: async (id: string, tags?: string[], limit?: number): Promise<TrainingPosition[]> => {
+    const params: Record<string, string | number> = {};
+    if (tags?.length) params.tags = tags.join(',');
+    if (limit) params.limit = limit;
+    const response = await api.get(`/repertoires/${id}/training`, { params });
+    return response.data.positions;
+  },
+
+  answerTraining: async (id: string, nodeId: string, move: string): Promise<TrainingAnswerResult> => {
+    const response = await api.post(`/repertoires/${id}/training/answers`, { nodeId, move });
+    return response.data;
+  },
+
+  // Live edits of a repertoire; a gap in event versions means events were missed and the tree should be reloaded
+  openCollabSocket: (id: string): WebSocket => {
+    const url = new URL(`${API_BASE}/repertoires/${id}/ws`, window.location.href);
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+    url.searchParams.set('token', localStorage.getItem(TOKEN_STORAGE_KEY) ?? '');
+    return new WebSocket(url);
+  },
+
+  // Web search syntax: quoted phrases, "or" and -excluded words
+  search: async (q: string, options?: RequestOptions): Promise<RepertoireSearchResult[]> => {
+    const response = await api.get('/repertoires/search', { params: { q }, signal: options?.signal });
+    return response.data;
+  },
+
+  listShared: async (): Promise<SharedRepertoire[]> => {
+    const response = await api.get('/repertoires/shared');
+    return response.data;
+  },
+
+  listCollaborators: async (id: string): Promise<RepertoireCollaborator[]> => {
+    const response = await api.get(`/repertoires/${id}/collaborators`);
+    return response.data;
+  },
+
+  inviteCollaborator: async (id: string, data: InviteCollaboratorRequest): Promise<RepertoireCollaborator> => {
+    const response = await api.post(`/repertoires/${id}/collaborators`, data);
+    return response.data;
+  },
+
+  removeCollaborator: async (id: string, userId: string): Promise<void> => {
+    await api.delete(`/repertoires/${id}/collaborators/${userId}`);
+  },
+
+  getMetrics: async (id: string): Promise<RepertoireMetrics> => {
+    const response = await api.get(`/repertoires/${id}/metrics`);
+    return response.data;
+  },
+
+  getResultsOverlay: async (id: string): Promise<ResultsOverlay> => {
+    const response = await api.get(`/repertoires/${id}/results-overlay`);
+    return response.data;
+  },
+
+  getBookDepth: async (id: string): Promise<BookDepth> => {
+    const response = await api.get(`/repertoires/${id}/book-depth`);
+    return response.data;
+  },
+
   mergeTranspositions: async (id: string): Promise<Repertoire> => {
     const response = await api.post(`/repertoires/${id}/merge-transpositions`);
     return response.data;
@@ -194,6 +452,35 @@ export const repertoireApi = {
   assignCategory: async (id: string, categoryId: string | null): Promise<Repertoire> => {
     const response = await api.patch(`/repertoires/${id}/category`, { categoryId });
     return response.data;
+  },
+
+  setMatchActive: async (id: string, active: boolean): Promise<Repertoire> => {
+    const response = await api.patch(`/repertoires/${id}/matching`, { active });
+    return response.data;
+  },
+
+  setArchived: async (id: string, archived: boolean): Promise<Repertoire> => {
+    const response = await api.patch(`/repertoires/${id}/archive`, { archived });
+    return response.data;
+  },
+
+  requestPrep: async (id: string, nodeId: string, depth?: number): Promise<PrepRequest> => {
+    const response = await api.post(`/repertoires/${id}/nodes/${nodeId}/prep`, null, { params: { depth } });
+    return response.data;
+  },
+
+  listPrep: async (id: string, nodeId: string): Promise<PrepRequest[]> => {
+    const response = await api.get(`/repertoires/${id}/nodes/${nodeId}/prep`);
+    return response.data;
+  },
+
+  acceptPrepSuggestion: async (id: string, prepId: string, suggestionId: string): Promise<Repertoire> => {
+    const response = await api.post(`/repertoires/${id}/prep/${prepId}/suggestions/${suggestionId}/accept`);
+    return response.data;
+  },
+
+  discardPrepSuggestion: async (id: string, prepId: string, suggestionId: string): Promise<void> => {
+    await api.delete(`/repertoires/${id}/prep/${prepId}/suggestions/${suggestionId}`);
   }
 };
 
@@ -221,6 +508,11 @@ export const categoryApi = {
     return response.data;
   },
 
+  setMatchActive: async (id: string, active: boolean): Promise<Category> => {
+    const response = await api.patch(`/categories/${id}/matching`, { active });
+    return response.data;
+  },
+
   delete: async (id: string): Promise<void> => {
     await api.delete(`/categories/${id}`);
   }
@@ -228,10 +520,18 @@ export const categoryApi = {
 
 // Import/Analysis API
 export const importApi = {
-  upload: async (file: File, username: string): Promise<UploadResponse> => {
+  // matchRepertoireIds restricts matching to these repertoires instead of the active ones;
+  // includeVariants keeps chess variant games, unmatched, instead of skipping them
+  upload: async (file: File, username: string, matchRepertoireIds?: string[], includeVariants?: boolean): Promise<UploadResponse> => {
     const formData = new FormData();
     formData.append('file', file);
     formData.append('username', username);
+    if (matchRepertoireIds?.length) {
+      formData.append('matchRepertoireIds', matchRepertoireIds.join(','));
+    }
+    if (includeVariants) {
+      formData.append('includeVariants', 'true');
+    }
 
     const response = await api.post('/imports', formData, {
       headers: {
@@ -241,18 +541,90 @@ export const importApi = {
     return response.data;
   },
 
-  importFromLichess: async (username: string, options?: LichessImportOptions): Promise<UploadResponse> => {
-    const response = await api.post('/imports/lichess', { username, options });
+  importDatabase: async (file: File, username: string, duplicatePolicy?: DuplicatePolicy): Promise<ImportJob> => {
+    const formData = new FormData();
+    formData.append('username', username);
+    if (duplicatePolicy) {
+      formData.append('duplicatePolicy', duplicatePolicy);
+    }
+    formData.append('file', file);
+
+    const response = await api.post('/imports/database', formData, {
+      headers: {
+        'Content-Type': 'multipart/form-data'
+      }
+    });
+    return response.data;
+  },
+
+  getImportJob: async (id: string): Promise<ImportJob> => {
+    const response = await api.get(`/imports/jobs/${id}`);
+    return response.data;
+  },
+
+  getImportStats: async (): Promise<ImportSourceStats[]> => {
+    const response = await api.get('/imports/stats');
+    return response.data;
+  },
+
+  importFromLichess: async (username: string, options?: LichessImportOptions, matchRepertoireIds?: string[], includeVariants?: boolean): Promise<UploadResponse> => {
+    const response = await api.post('/imports/lichess', { username, options, matchRepertoireIds, includeVariants });
+    return response.data;
+  },
+
+  importFromChesscom: async (username: string, options?: ChesscomImportOptions, matchRepertoireIds?: string[], includeVariants?: boolean): Promise<UploadResponse> => {
+    const response = await api.post('/imports/chesscom', { username, options, matchRepertoireIds, includeVariants });
+    return response.data;
+  },
+
+  // Games of a Lichess broadcast round, imported for reference against the repertoires of both colors
+  importLichessBroadcast: async (url: string, analysisDepth?: number): Promise<UploadResponse> => {
+    const response = await api.post('/imports/lichess-broadcast', { url, analysisDepth });
+    return response.data;
+  },
+
+  // Recent games of every member of a Lichess team, imported in the background for reference
+  importLichessTeam: async (team: string, options?: LichessImportOptions, analysisDepth?: number): Promise<TeamImport> => {
+    const response = await api.post('/imports/lichess-team', { team, options, analysisDepth });
+    return response.data;
+  },
+
+  getTeamImport: async (id: string): Promise<TeamImport> => {
+    const response = await api.get(`/imports/lichess-team/${id}`);
+    return response.data;
+  },
+
+  // What an import run found, by the ID of the analysis it created
+  getImportSummary: async (analysisId: string): Promise<ImportSummary> => {
+    const response = await api.get(`/imports/${analysisId}/summary`);
+    return response.data;
+  },
+
+  previewUpload: async (file: File, username: string): Promise<ImportPreview> => {
+    const formData = new FormData();
+    formData.append('file', file);
+    formData.append('username', username);
+
+    const response = await api.post('/imports/preview', formData, {
+      headers: {
+        'Content-Type': 'multipart/form-data'
+      }
+    });
+    return response.data;
+  },
+
+  previewFromLichess: async (username: string, lichessOptions?: LichessImportOptions): Promise<ImportPreview> => {
+    const response = await api.post('/imports/preview', { source: 'lichess', username, lichessOptions });
     return response.data;
   },
 
-  importFromChesscom: async (username: string, options?: ChesscomImportOptions): Promise<UploadResponse> => {
-    const response = await api.post('/imports/chesscom', { username, options });
+  previewFromChesscom: async (username: string, chesscomOptions?: ChesscomImportOptions): Promise<ImportPreview> => {
+    const response = await api.post('/imports/preview', { source: 'chesscom', username, chesscomOptions });
     return response.data;
   },
 
-  list: async (options?: RequestOptions): Promise<AnalysisSummary[]> => {
-    const response = await api.get('/analyses', { signal: options?.signal });
+  list: async (source?: GameSource, options?: RequestOptions): Promise<AnalysisSummary[]> => {
+    const response = await api.get('/analyses', { params: source ? { source } : undefined, signal: options?.signal });
     return response.data;
   },
 
@@ -263,15 +635,50 @@ export const importApi = {
 
   delete: async (id: string): Promise<void> => {
     await api.delete(`/analyses/${id}`);
+  },
+
+  recomputeEvals: async (id: string): Promise<{ analysisId: string; queued: number }> => {
+    const response = await api.post(`/analyses/${id}/recompute-evals`);
+    return response.data;
+  },
+
+  // Requeues the engine evals that failed after every automatic retry
+  retryEvals: async (id: string): Promise<{ analysisId: string; queued: number }> => {
+    const response = await api.post(`/analyses/${id}/evals/retry`);
+    return response.data;
+  },
+
+  // Runs the Lichess, Chess.com or broadcast import that created the analysis again
+  rerun: async (id: string): Promise<UploadResponse> => {
+    const response = await api.post(`/analyses/${id}/rerun`);
+    return response.data;
   }
 };
 
 // Sync API
+const SYNC_POLL_INTERVAL_MS = 2000;
+
 export const syncApi = {
+  // Starts a sync and polls its run until it finishes
   sync: async (): Promise<SyncResult> => {
     const response = await api.post('/sync');
+    let run: SyncRun = response.data;
+    while (run.status === 'running') {
+      await new Promise((resolve) => setTimeout(resolve, SYNC_POLL_INTERVAL_MS));
+      run = await syncApi.getRun(run.id);
+    }
+    return run;
+  },
+
+  getRun: async (id: string): Promise<SyncRun> => {
+    const response = await api.get(`/sync/runs/${id}`);
     return response.data;
   },
+
+  listRuns: async (): Promise<SyncRun[]> => {
+    const response = await api.get('/sync/runs');
+    return response.data.runs;
+  },
 };
 
 // Health API
@@ -282,10 +689,21 @@ export const healthApi = {
   }
 };
 
+// Server-rendered board diagram, usable directly as an <img> src
+export const boardImageUrl = (fen: string, lastMove?: string, orientation?: 'white' | 'black'): string => {
+  const params = new URLSearchParams({ fen });
+  if (lastMove) params.set('lastMove', lastMove);
+  if (orientation) params.set('orientation', orientation);
+  return `${API_BASE}/board.svg?${params}`;
+};
+
 // Study Import API
 export const studyApi = {
-  preview: async (url: string): Promise<StudyInfo> => {
-    const response = await api.get('/studies/preview', { params: { url }, timeout: 120000 });
+  // With a target repertoire, each chapter also reports the moves it would add to it
+  preview: async (url: string, targetRepertoireId?: string): Promise<StudyInfo> => {
+    const params: Record<string, string> = { url };
+    if (targetRepertoireId) params.targetRepertoireId = targetRepertoireId;
+    const response = await api.get('/studies/preview', { params, timeout: 120000 });
     return response.data;
   },
 
@@ -295,15 +713,21 @@ export const studyApi = {
     mergeAsOne?: boolean,
     mergeName?: string,
     createCategory?: boolean,
-    categoryName?: string
+    categoryName?: string,
+    targetRepertoireId?: string
   ): Promise<StudyImportResponse> => {
     const body: Record<string, unknown> = { studyUrl, chapters };
-    if (mergeAsOne) {
-      body.mergeAsOne = true;
-      if (mergeName) body.mergeName = mergeName;
-    } else if (createCategory) {
-      body.createCategory = true;
-      if (categoryName) body.categoryName = categoryName;
+    if (targetRepertoireId) {
+      body.targetRepertoireId = targetRepertoireId;
+    } else {
+      if (mergeAsOne) {
+        body.mergeAsOne = true;
+        if (mergeName) body.mergeName = mergeName;
+      }
+      if (createCategory) {
+        body.createCategory = true;
+        if (categoryName) body.categoryName = categoryName;
+      }
     }
     const response = await api.post('/studies/import', body, { timeout: 120000 });
     return response.data;
@@ -312,8 +736,8 @@ export const studyApi = {
 
 // Games API
 export const gamesApi = {
-  list: async (limit = 20, offset = 0, timeClass?: string, repertoire?: string, source?: string, options?: RequestOptions): Promise<GamesResponse> => {
-    const params: Record<string, string | number> = { limit, offset };
+  list: async (limit = 20, offset = 0, timeClass?: string, repertoire?: string, source?: string, starred?: boolean, options?: RequestOptions, classification?: string): Promise<GamesResponse> => {
+    const params: Record<string, string | number | boolean> = { limit, offset };
     if (timeClass) {
       params.timeClass = timeClass;
     }
@@ -323,6 +747,12 @@ export const gamesApi = {
     if (source) {
       params.source = source;
     }
+    if (starred) {
+      params.starred = true;
+    }
+    if (classification) {
+      params.classification = classification;
+    }
     const response = await api.get('/games', {
       params,
       signal: options?.signal
@@ -330,6 +760,23 @@ export const gamesApi = {
     return response.data;
   },
 
+  // Spreadsheet export of the games matching the list filters
+  exportCsv: async (timeClass?: string, repertoire?: string, source?: string, starred?: boolean, classification?: string): Promise<Blob> => {
+    const params: Record<string, string | boolean> = {};
+    if (timeClass) params.timeClass = timeClass;
+    if (repertoire) params.repertoire = repertoire;
+    if (source) params.source = source;
+    if (starred) params.starred = true;
+    if (classification) params.classification = classification;
+    const response = await api.get('/games/export.csv', { params, responseType: 'blob' });
+    return response.data;
+  },
+
+  update: async (analysisId: string, gameIndex: number, data: UpdateGameRequest): Promise<GameNotes> => {
+    const response = await api.patch(`/games/${analysisId}/${gameIndex}`, data);
+    return response.data;
+  },
+
   delete: async (analysisId: string, gameIndex: number): Promise<void> => {
     await api.delete(`/games/${analysisId}/${gameIndex}`);
   },
@@ -349,17 +796,70 @@ export const gamesApi = {
     return response.data;
   },
 
+  // The game with the repertoire tree as it was when the game was analyzed
+  repertoireSnapshot: async (analysisId: string, gameIndex: number, options?: RequestOptions): Promise<GameRepertoireSnapshot> => {
+    const response = await api.get(`/games/${analysisId}/${gameIndex}/repertoire-snapshot`, { signal: options?.signal });
+    return response.data;
+  },
+
+  // Grafts the first toPly plies of the game into the repertoire, skipping the moves it already has
+  adoptLine: async (analysisId: string, gameIndex: number, toPly: number, repertoireId: string): Promise<AdoptLineResponse> => {
+    const response = await api.post(`/games/${analysisId}/${gameIndex}/adopt`, null, { params: { toPly, repertoireId } });
+    return response.data;
+  },
+
   markViewed: async (analysisId: string, gameIndex: number): Promise<void> => {
     await api.post(`/games/${analysisId}/${gameIndex}/view`);
   },
 
-  insights: async (options?: RequestOptions): Promise<InsightsResponse> => {
-    const response = await api.get('/games/insights', { signal: options?.signal });
+  insights: async (options?: RequestOptions & { timeClass?: TimeClass }): Promise<InsightsResponse> => {
+    const params = options?.timeClass ? { timeClass: options.timeClass } : undefined;
+    const response = await api.get('/games/insights', { params, signal: options?.signal });
+    return response.data;
+  },
+
+  // Spreadsheet export of the insight mistakes, one row per game where the mistake was played
+  exportMistakesCsv: async (filter?: { repertoireId?: string; timeClass?: TimeClass; minDrop?: number; since?: string; limit?: number }): Promise<Blob> => {
+    const response = await api.get('/insights/mistakes/export.csv', { params: filter, responseType: 'blob' });
     return response.data;
   },
 
   dismissMistake: async (fen: string, playedMove: string): Promise<void> => {
     await api.post('/games/insights/dismiss', { fen, playedMove });
+  },
+
+  explainMistake: async (fen: string, played: string, options?: RequestOptions): Promise<MistakeExplanation> => {
+    const response = await api.get('/insights/explain', { params: { fen, played }, signal: options?.signal });
+    return response.data;
+  },
+
+  getInsightSettings: async (options?: RequestOptions): Promise<InsightSettings> => {
+    const response = await api.get('/settings/insights', { signal: options?.signal });
+    return response.data;
+  },
+
+  updateInsightSettings: async (settings: InsightSettings): Promise<InsightSettings> => {
+    const response = await api.put('/settings/insights', settings);
+    return response.data;
+  },
+
+  classificationRules: async (options?: RequestOptions): Promise<ClassificationRule[]> => {
+    const response = await api.get('/settings/classification-rules', { signal: options?.signal });
+    return response.data;
+  },
+
+  createClassificationRule: async (rule: ClassificationRuleRequest): Promise<ClassificationRule> => {
+    const response = await api.post('/settings/classification-rules', rule);
+    return response.data;
+  },
+
+  updateClassificationRule: async (id: string, rule: ClassificationRuleRequest): Promise<ClassificationRule> => {
+    const response = await api.put(`/settings/classification-rules/${id}`, rule);
+    return response.data;
+  },
+
+  deleteClassificationRule: async (id: string): Promise<void> => {
+    await api.delete(`/settings/classification-rules/${id}`);
   }
 };
 
@@ -369,3 +869,126 @@ export const dashboardApi = {
     return response.data;
   },
 };
+
+export const recommendationApi = {
+  list: async (options?: RequestOptions): Promise<Recommendation[]> => {
+    const response = await api.get('/recommendations', { signal: options?.signal });
+    return response.data.recommendations;
+  },
+};
+
+export const explorerApi = {
+  getPosition: async (fen: string, speeds?: string[], ratings?: number[], options?: RequestOptions): Promise<ExplorerPosition> => {
+    const params: Record<string, string> = { fen };
+    if (speeds?.length) params.speeds = speeds.join(',');
+    if (ratings?.length) params.ratings = ratings.join(',');
+    const response = await api.get('/explorer', { params, signal: options?.signal });
+    return response.data;
+  },
+
+  // Exact results for positions with at most 7 pieces
+  getTablebase: async (fen: string, options?: RequestOptions): Promise<TablebasePosition> => {
+    const response = await api.get('/tablebase', { params: { fen }, signal: options?.signal });
+    return response.data;
+  },
+
+  // Validates a FEN, possibly missing its trailing fields, and returns its normalized and full forms
+  normalizeFen: async (fen: string): Promise<FENDetails> => {
+    const response = await api.post('/chess/normalize-fen', { fen });
+    return response.data;
+  },
+
+  // What opponents played against the user in their imported games, optionally within a rating range
+  getOpponentReplies: async (fen: string, minRating?: number, maxRating?: number, options?: RequestOptions): Promise<OpponentReplies> => {
+    const params: Record<string, string | number> = { fen };
+    if (minRating) params.minRating = minRating;
+    if (maxRating) params.maxRating = maxRating;
+    const response = await api.get('/positions/opponent-replies', { params, signal: options?.signal });
+    return response.data;
+  },
+};
+
+export const trainingApi = {
+  // Training calendar of the last days with the current and longest streaks
+  activity: async (days?: number, options?: RequestOptions): Promise<TrainingActivity> => {
+    const response = await api.get('/training/activity', { params: days ? { days } : undefined, signal: options?.signal });
+    return response.data;
+  },
+};
+
+export const sparringApi = {
+  // Starts a game on a repertoire; rating picks the Explorer bands the opponent plays from
+  start: async (repertoireId: string, rating?: number): Promise<SparringSession> => {
+    const response = await api.post('/sparring/start', { repertoireId, rating: rating ?? 0 });
+    return response.data;
+  },
+
+  move: async (sessionId: string, move: string): Promise<SparringSession> => {
+    const response = await api.post('/sparring/move', { sessionId, move });
+    return response.data;
+  },
+};
+
+export const usageApi = {
+  get: async (): Promise<UsageReport> => {
+    const response = await api.get('/usage');
+    return response.data;
+  },
+};
+
+export const notificationsApi = {
+  settings: async (): Promise<NotificationSettings> => {
+    const response = await api.get('/notifications/settings');
+    return response.data;
+  },
+
+  updatePreferences: async (preferences: NotificationPreference[]): Promise<NotificationSettings> => {
+    const response = await api.put('/notifications/preferences', { preferences });
+    return response.data;
+  },
+
+  setWebhook: async (url: string): Promise<NotificationWebhook> => {
+    const response = await api.put('/notifications/webhook', { url });
+    return response.data;
+  },
+
+  deleteWebhook: async (): Promise<void> => {
+    await api.delete('/notifications/webhook');
+  },
+
+  // Registers the push service worker and subscribes this browser with the server's VAPID key
+  subscribePush: async (publicKey: string): Promise<void> => {
+    const registration = await navigator.serviceWorker.register('/push-sw.js');
+    const padded = (publicKey + '='.repeat((4 - (publicKey.length % 4)) % 4)).replace(/-/g, '+').replace(/_/g, '/');
+    const subscription = await registration.pushManager.subscribe({
+      userVisibleOnly: true,
+      applicationServerKey: Uint8Array.from(atob(padded), (c) => c.charCodeAt(0)),
+    });
+    await api.post('/notifications/push', subscription.toJSON());
+  },
+
+  unsubscribePush: async (endpoint: string): Promise<void> => {
+    await api.delete('/notifications/push', { data: { endpoint } });
+  },
+};
+
+export const linksApi = {
+  invite: async (data: InviteCoachLinkRequest): Promise<CoachLink> => {
+    const response = await api.post('/links/invite', data);
+    return response.data;
+  },
+
+  list: async (options?: RequestOptions): Promise<CoachLink[]> => {
+    const response = await api.get('/links', { signal: options?.signal });
+    return response.data;
+  },
+
+  accept: async (id: string): Promise<CoachLink> => {
+    const response = await api.post(`/links/${id}/accept`);
+    return response.data;
+  },
+
+  revoke: async (id: string): Promise<void> => {
+    await api.delete(`/links/${id}`);
+  },
+};
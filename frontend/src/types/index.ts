@@ -1,17 +1,107 @@
+// Error responses: branch on code, show error
+export type ApiErrorCode =
+  | 'BAD_REQUEST'
+  | 'UNAUTHORIZED'
+  | 'FORBIDDEN'
+  | 'NOT_FOUND'
+  | 'METHOD_NOT_ALLOWED'
+  | 'CONFLICT'
+  | 'PAYLOAD_TOO_LARGE'
+  | 'RATE_LIMITED'
+  | 'INTERNAL_ERROR'
+  | 'BAD_GATEWAY'
+  | 'SERVICE_UNAVAILABLE'
+  | 'VALIDATION_FAILED'
+  | 'INVALID_FEN'
+  | 'INVALID_MOVE'
+  | 'COLOR_MISMATCH'
+  | 'REPERTOIRE_LIMIT_REACHED'
+  | 'CATEGORY_LIMIT_REACHED'
+  | 'GOAL_LIMIT_REACHED'
+  | 'API_TOKEN_LIMIT_REACHED'
+  | 'LINKED_ACCOUNT_LIMIT_REACHED'
+  | 'CLASSIFICATION_RULE_LIMIT_REACHED'
+  | 'MOVE_EXISTS'
+  | 'REPERTOIRE_ARCHIVED'
+  | 'ROOT_NODE'
+  | 'NODE_NOT_FOUND'
+  | 'DUPLICATE_GAME'
+  | 'IMPORT_TOO_LARGE'
+  | 'EMAIL_TAKEN'
+  | 'USERNAME_TAKEN'
+  | 'INVALID_CREDENTIALS'
+  | 'OAUTH_ONLY'
+  | 'INCORRECT_PASSWORD'
+  | 'RESET_TOKEN_INVALID'
+  | 'RESET_TOKEN_EXPIRED'
+  | 'RESET_TOKEN_USED'
+  | 'ALREADY_EXISTS'
+  | 'FEATURE_UNAVAILABLE'
+  | 'UPSTREAM_RATE_LIMITED'
+  | 'UPSTREAM_UNAVAILABLE'
+  | 'EXPLORER_BUDGET_EXCEEDED'
+  | 'EXPLORER_BUSY'
+  | 'PRIVATE_STUDY';
+
+export interface ApiError {
+  code: ApiErrorCode;
+  error: string;
+}
+
 // Auth types
-export type TimeFormat = 'bullet' | 'blitz' | 'rapid';
+export type TimeFormat = 'bullet' | 'blitz' | 'rapid' | 'daily';
 
 export interface User {
   id: string;
   username: string;
   email?: string;
   oauthProvider?: string;
+  linkedAccounts?: LinkedAccount[];
   lichessUsername?: string;
   chesscomUsername?: string;
   lastLichessSyncAt?: string;
   lastChesscomSyncAt?: string;
   timeFormatPrefs?: TimeFormat[];
+  analysisDepth: number; // moves matched per imported game, 0 for whole games
+  weeklyDigest: boolean; // opted in to the weekly email digest
+  timezone: string; // IANA time zone training days and date filters are counted in
+  locale: string; // BCP 47 tag dates are formatted for
+  createdAt: string;
+}
+
+export interface Session {
+  id: string;
+  userAgent: string;
+  ip: string;
   createdAt: string;
+  lastSeenAt: string;
+  expiresAt: string;
+  current: boolean; // the session making the request
+}
+
+export type APITokenScope = 'read' | 'import' | 'write';
+
+export interface APIToken {
+  id: string;
+  name: string;
+  scope: APITokenScope;
+  prefix: string; // first characters of the token
+  createdAt: string;
+  lastUsedAt?: string;
+}
+
+// The raw token is only returned on creation
+export interface CreatedAPIToken extends APIToken {
+  token: string;
+}
+
+export type AccountProvider = 'lichess' | 'chesscom';
+
+export interface LinkedAccount {
+  id: string;
+  provider: AccountProvider;
+  username: string;
+  lastSyncAt?: string;
 }
 
 export interface SyncResult {
@@ -21,10 +111,34 @@ export interface SyncResult {
   chesscomError?: string;
 }
 
+export type SyncRunStatus = 'running' | 'done' | 'failed';
+
+export interface SyncAccountResult {
+  provider: AccountProvider;
+  username: string;
+  gamesFetched: number;
+  gamesImported: number;
+  gamesSkipped: number;
+  error?: string;
+}
+
+export interface SyncRun extends SyncResult {
+  id: string;
+  status: SyncRunStatus;
+  accounts: SyncAccountResult[];
+  error?: string;
+  startedAt: string;
+  finishedAt?: string;
+}
+
 export interface UpdateProfileRequest {
+  linkedAccounts?: { provider: AccountProvider; username: string }[];
   lichessUsername?: string;
   chesscomUsername?: string;
   timeFormatPrefs?: TimeFormat[];
+  analysisDepth?: number;
+  timezone?: string;
+  locale?: string;
 }
 
 export interface LoginRequest {
@@ -52,6 +166,7 @@ export interface Category {
   id: string;
   name: string;
   color: Color;
+  matchActive: boolean; // its repertoires are matched against imported games
   createdAt: string;
   updatedAt: string;
 }
@@ -85,7 +200,17 @@ export interface RepertoireNode {
   branchName?: string | null;
   collapsed?: boolean;
   transpositionOf?: string | null;
+  tags?: string[];
+  editedAt?: string;
+  // Set on the first node of first- and second-level branches
+  opening?: OpeningLabel;
   children: RepertoireNode[];
+  truncated?: boolean;
+}
+
+export interface OpeningLabel {
+  eco: string;
+  name: string;
 }
 
 export interface RepertoireMetadata {
@@ -103,6 +228,459 @@ export interface Repertoire {
   metadata: RepertoireMetadata;
   createdAt: string;
   updatedAt: string;
+  version: number;
+  matchActive: boolean; // imported games are matched against it, unless its category is inactive
+  archived: boolean; // read-only, not matched against games and hidden from lists by default
+  health?: RepertoireHealth; // absent until the nightly computation has run
+}
+
+/** Repertoire file for backups and moving repertoires between instances (schema version 1) */
+export interface RepertoireExport {
+  format: 'treechess-repertoire';
+  version: number;
+  exportedAt: string;
+  name: string;
+  color: Color;
+  metadata: RepertoireMetadata;
+  notes?: string;
+  tree: ExportedNode;
+}
+
+/** Node of an exported tree: fen and moveNumber are only set on the root, move on the others */
+export interface ExportedNode {
+  fen?: string;
+  moveNumber?: number;
+  move?: string;
+  comment?: string;
+  branchName?: string;
+  tags?: string[];
+  collapsed?: boolean;
+  children?: ExportedNode[];
+}
+
+/** Outcome of a bulk comment import; paths are SAN moves from the root separated by spaces */
+export interface CommentsImportResult {
+  updated: number;
+  unchanged: number;
+  notFound: string[];
+}
+
+/** 0-100 maintenance score; components are 0-100 too, null when there is no data for them */
+export interface RepertoireHealth {
+  score: number;
+  coverage: number | null;
+  results: number | null;
+  recall: number | null;
+  freshness: number;
+  computedAt: string;
+}
+
+export type RecommendationKind = 'deepen' | 'cover' | 'review';
+
+/** Something worth studying next, most valuable first in GET /api/recommendations */
+export interface Recommendation {
+  kind: RecommendationKind;
+  repertoireId: string;
+  repertoireName: string;
+  nodeId?: string; // absent when the whole repertoire is concerned
+  line?: string;
+  move?: string; // opponent move to prepare an answer to, for cover
+  games: number;
+  losses: number;
+  coveredUntil?: number;
+  recall?: number;
+  priority: number;
+  summary: string;
+}
+
+/** Compact tree node returned with fields=slim: only the top node carries its FEN */
+export interface SlimRepertoireNode {
+  id: string;
+  fen?: string;
+  move?: string;
+  moveNumber?: number;
+  colorToMove?: ShortColor;
+  parentId?: string;
+  comment?: string;
+  branchName?: string;
+  collapsed?: boolean;
+  transpositionOf?: string;
+  tags?: string[];
+  editedAt?: string;
+  opening?: OpeningLabel;
+  truncated?: boolean;
+  children?: SlimRepertoireNode[];
+}
+
+export interface SlimRepertoire extends Omit<Repertoire, 'treeData'> {
+  treeData: SlimRepertoireNode;
+}
+
+export interface RepertoireTemplate {
+  id: string;
+  userId?: string;
+  name: string;
+  color: Color;
+  description: string;
+  tags: string[];
+  featured: boolean;
+  createdAt: string;
+}
+
+export interface RepertoireLine {
+  leafId: string;
+  moves: string[];
+  tags: string[];
+  opening?: OpeningLabel;
+}
+
+export interface TrainingPosition {
+  nodeId: string;
+  fen: string;
+  path: string[];
+  expectedMoves: string[];
+  tags: string[];
+}
+
+export interface TrainingSession {
+  id: string;
+  repertoireId: string;
+  startedAt: string;
+  lastAnswerAt: string;
+  reviews: number;
+  correct: number;
+}
+
+export interface TrainingAnswerResult {
+  correct: boolean;
+  expectedMoves: string[];
+  session: TrainingSession;
+}
+
+export interface TrainingDay {
+  date: string; // YYYY-MM-DD, UTC
+  reviews: number;
+  correct: number;
+}
+
+export interface TrainingActivity {
+  days: TrainingDay[];
+  currentStreak: number;
+  longestStreak: number;
+  totalReviews: number;
+  accuracy: number;
+}
+
+export type SparringStatus = 'active' | 'completed' | 'deviated' | 'out_of_book';
+
+export interface SparringScore {
+  repertoireMoves: number;
+  deviations: number;
+  accuracy: number;
+}
+
+// A sparring game where the server plays opponent replies sampled from the Explorer
+export interface SparringSession {
+  id: string;
+  repertoireId: string;
+  color: Color;
+  ratings: string;
+  fen: string;
+  moves: string[];
+  status: SparringStatus;
+  opponentMove?: string;
+  expectedMoves?: string[];
+  score: SparringScore;
+  expiresAt: string;
+}
+
+export type UsageKind = 'explorer' | 'lichess_export' | 'chesscom';
+
+// limit is 0 when the service is not capped
+export interface UsageCounter {
+  kind: UsageKind;
+  used: number;
+  limit: number;
+}
+
+// The user's calls to external services today; quotas reset at resetsAt (UTC midnight)
+export interface UsageReport {
+  day: string;
+  resetsAt: string;
+  counters: UsageCounter[];
+}
+
+export type NotificationEvent = 'import_completed' | 'analysis_completed' | 'weekly_digest';
+
+export interface NotificationPreference {
+  event: NotificationEvent;
+  email: boolean;
+  webhook: boolean;
+  push: boolean;
+}
+
+// secret is only returned when the webhook is set
+export interface NotificationWebhook {
+  url: string;
+  secret?: string;
+  createdAt: string;
+}
+
+export interface NotificationSettings {
+  preferences: NotificationPreference[];
+  webhook?: NotificationWebhook;
+  pushSubscriptions: number;
+  pushPublicKey?: string;
+  emailEnabled: boolean;
+}
+
+export interface BranchMetrics {
+  nodeId: string;
+  move: string;
+  branchName?: string;
+  nodes: number;
+  lastEditedAt: string | null;
+}
+
+// A node comment or repertoire name matching a search; name matches point at the root node
+export interface RepertoireSearchResult {
+  repertoireId: string;
+  repertoireName: string;
+  nodeId: string;
+  movePath: string[];
+  comment?: string;
+  rank: number;
+}
+
+export interface RepertoireMetrics {
+  repertoireId: string;
+  totalNodes: number;
+  leafCount: number;
+  maxDepth: number;
+  nodesPerDepth: number[];
+  myBranching: number;
+  opponentBranching: number;
+  annotatedPercent: number;
+  branches: BranchMetrics[];
+}
+
+export interface NodeResult {
+  nodeId: string;
+  games: number;
+  wins: number;
+  draws: number;
+  losses: number;
+  score: number;
+}
+
+export interface ResultsOverlay {
+  repertoireId: string;
+  nodes: NodeResult[];
+}
+
+/** A move the user's opponents played in a position, with the user's score after it */
+export interface OpponentReply {
+  move: string;
+  games: number;
+  frequency: number;
+  wins: number;
+  draws: number;
+  losses: number;
+  score: number;
+  averageRating: number;
+}
+
+export interface OpponentReplies {
+  fen: string;
+  games: number;
+  minRating?: number;
+  maxRating?: number;
+  replies: OpponentReply[];
+}
+
+/** Where games leave a repertoire; plies are numbered from 0 (White's first move) */
+export interface BookDepth {
+  repertoireId: string;
+  games: number;
+  userDeviations: number;
+  userLeaveBookPly: number | null;
+  opponentDeviations: number;
+  opponentLeaveBookPly: number | null;
+  trend: BookDepthPeriod[];
+  moveTimes: PlyMoveTime[];
+}
+
+export interface BookDepthPeriod {
+  month: string; // YYYY-MM
+  games: number;
+  userDeviations: number;
+  userLeaveBookPly: number | null;
+  opponentDeviations: number;
+  opponentLeaveBookPly: number | null;
+}
+
+export interface PlyMoveTime {
+  ply: number;
+  moves: number;
+  inBook: number;
+  avgTimeSpent: number; // seconds
+}
+
+export type RepertoireEventType = 'snapshot' | 'node_added' | 'node_deleted' | 'comment_updated' | 'children_reordered' | 'revision_restored' | 'comments_imported';
+
+export interface RepertoireEvent {
+  type: RepertoireEventType;
+  repertoireId: string;
+  version: number;
+  nodeId?: string;
+  parentId?: string;
+  node?: RepertoireNode;
+  comment?: string;
+  childIds?: string[];
+}
+
+export type CollaboratorRole = 'viewer' | 'editor';
+
+export interface RepertoireCollaborator {
+  repertoireId: string;
+  userId: string;
+  username: string;
+  role: CollaboratorRole;
+  createdAt: string;
+}
+
+export interface InviteCollaboratorRequest {
+  email?: string;
+  username?: string;
+  role: CollaboratorRole;
+}
+
+export type CoachLinkStatus = 'pending' | 'active';
+export type CoachLinkRole = 'coach' | 'student';
+
+// Once active, the coach may pass onBehalfOf=<studentId> to read endpoints
+export interface CoachLink {
+  id: string;
+  coachId: string;
+  coachUsername: string;
+  studentId: string;
+  studentUsername: string;
+  invitedBy: string;
+  status: CoachLinkStatus;
+  createdAt: string;
+  acceptedAt?: string;
+}
+
+// role is the side the invitee takes
+export interface InviteCoachLinkRequest {
+  email?: string;
+  username?: string;
+  role: CoachLinkRole;
+}
+
+export interface SharedRepertoire extends Repertoire {
+  role: CollaboratorRole;
+}
+
+export interface ExplorerMove {
+  san: string;
+  uci: string;
+  white: number;
+  draws: number;
+  black: number;
+  averageRating: number;
+}
+
+// status is 'pending' while the lookup waits in the server queue; poll again shortly
+export interface ExplorerPosition {
+  fen: string;
+  speeds: string;
+  ratings: string;
+  status: 'ready' | 'pending';
+  white: number;
+  draws: number;
+  black: number;
+  moves: ExplorerMove[];
+}
+
+export type PrepStatus = 'pending' | 'running' | 'done' | 'failed';
+
+// A candidate line below the prepared node; stats are those of its first move
+export interface PrepSuggestion {
+  id: string;
+  moves: string[];
+  games: number;
+  white: number;
+  draws: number;
+  black: number;
+}
+
+export interface PrepRequest {
+  id: string;
+  repertoireId: string;
+  nodeId: string;
+  fen: string;
+  depth: number;
+  status: PrepStatus;
+  suggestions: PrepSuggestion[];
+  error?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+// The tree of a repertoire as saved at one version; treeData is left out of lists
+export interface RepertoireRevision {
+  version: number;
+  metadata: RepertoireMetadata;
+  createdAt: string;
+  treeData?: RepertoireNode;
+}
+
+// Markdown notes of a repertoire for plans and model games; version 0 means none were saved yet
+export interface StudyNotes {
+  repertoireId: string;
+  version: number;
+  content: string;
+  updatedBy?: string;
+  updatedAt?: string;
+}
+
+export interface StudyNotesRevision {
+  version: number;
+  length: number; // in characters
+  updatedBy?: string;
+  updatedAt: string;
+}
+
+// Nodes are matched by the moves leading to them; added and removed hold the first node of each line
+export interface RevisionDiff {
+  repertoireId: string;
+  from: number;
+  to: number;
+  added: DiffNode[];
+  removed: DiffNode[];
+  changed: NodeChange[];
+}
+
+export interface DiffNode {
+  nodeId: string;
+  path: string[];
+  fen: string;
+  nodes: number;
+}
+
+export interface NodeChange {
+  nodeId: string;
+  path: string[];
+  fen: string;
+  changes: { field: 'comment' | 'branchName' | 'tags'; before: string; after: string }[];
+}
+
+export interface PublishTemplateRequest {
+  repertoireId: string;
+  name?: string;
+  description?: string;
+  tags?: string[];
 }
 
 // Request types for repertoire management
@@ -119,6 +697,18 @@ export interface UpdateRepertoireRequest {
 export interface RepertoireRef {
   id: string;
   name: string;
+  // Repertoire version and tree hash the game was analyzed against, absent on older games
+  version?: number;
+  treeHash?: string;
+}
+
+// A game with the repertoire tree it was analyzed against; currentVersion is 0 once the repertoire is deleted
+export interface GameRepertoireSnapshot {
+  game: GameAnalysis;
+  repertoire: RepertoireRef;
+  treeData: RepertoireNode;
+  currentVersion: number;
+  changed: boolean;
 }
 
 // Add node request
@@ -130,6 +720,20 @@ export interface AddNodeRequest {
   colorToMove: ShortColor;
 }
 
+// How often an opponent move is played in the Lichess Explorer at the parent position
+export interface MovePopularity {
+  move: string;
+  games: number;
+  totalGames: number;
+  share: number;
+  rare: boolean;
+}
+
+// Add node response; popularity is only set when stats were requested for an opponent move
+export interface AddNodeResponse extends Repertoire {
+  popularity?: MovePopularity;
+}
+
 // Analysis types
 export interface PGNHeaders {
   Event?: string;
@@ -144,7 +748,7 @@ export interface PGNHeaders {
   ECOUrl?: string;
 }
 
-export type MoveStatus = 'in-repertoire' | 'out-of-repertoire' | 'opponent-new' | 'out-of-book';
+export type MoveStatus = 'in-repertoire' | 'out-of-repertoire' | 'opponent-new' | 'out-of-book' | 'beyond-book';
 
 export interface MoveAnalysis {
   plyNumber: number;
@@ -153,6 +757,8 @@ export interface MoveAnalysis {
   status: MoveStatus;
   expectedMove?: string;
   isUserMove: boolean;
+  winrateDrop?: number;
+  classifications?: string[];
 }
 
 export interface GameAnalysis {
@@ -162,6 +768,22 @@ export interface GameAnalysis {
   userColor: Color;
   matchedRepertoire?: RepertoireRef | null;
   matchScore?: number;
+  note?: string;
+  starred?: boolean;
+}
+
+// How an import was requested, kept so it can be run again
+export interface ImportSourceParams {
+  username?: string;
+  filename?: string;
+  roundId?: string;
+  teamId?: string;
+  batchId?: string;
+  sync?: boolean;
+  duplicatePolicy?: DuplicatePolicy;
+  analysisDepth?: number;
+  lichess?: LichessImportOptions;
+  chesscom?: ChesscomImportOptions;
 }
 
 export interface AnalysisSummary {
@@ -170,6 +792,8 @@ export interface AnalysisSummary {
   filename: string;
   gameCount: number;
   uploadedAt: string;
+  source: GameSource;
+  sourceParams: ImportSourceParams;
 }
 
 export interface AnalysisDetail extends AnalysisSummary {
@@ -181,7 +805,7 @@ export type GameStatus = 'ok' | 'error' | 'new-line';
 
 export type TimeClass = 'bullet' | 'blitz' | 'rapid' | 'daily';
 
-export type GameSource = 'lichess' | 'chesscom' | 'pgn';
+export type GameSource = 'lichess' | 'chesscom' | 'broadcast' | 'lichess_team' | 'pgn';
 
 export interface GameSummary {
   analysisId: string;
@@ -199,6 +823,28 @@ export interface GameSummary {
   repertoireId?: string;
   source: GameSource;
   synced: boolean;
+  note?: string;
+  starred: boolean;
+  classifications?: string[];
+}
+
+export interface UpdateGameRequest {
+  note?: string;
+  starred?: boolean;
+}
+
+export interface GameNotes {
+  analysisId: string;
+  gameIndex: number;
+  note: string;
+  starred: boolean;
+}
+
+// Repertoire a game line was adopted into, and the game re-analyzed against it
+export interface AdoptLineResponse {
+  repertoire: Repertoire;
+  game: GameAnalysis;
+  addedNodes: number;
 }
 
 export interface GamesResponse {
@@ -217,6 +863,8 @@ export interface GameRef {
   black: string;
   result: string;
   date: string;
+  userColor?: Color;
+  opening?: string;
 }
 
 export interface OpeningMistake {
@@ -227,6 +875,7 @@ export interface OpeningMistake {
   frequency: number;
   score: number;
   games: GameRef[];
+  timeClasses: Partial<Record<TimeClass, number>>;
 }
 
 export interface InsightsResponse {
@@ -236,6 +885,101 @@ export interface InsightsResponse {
   engineAnalysisCompleted: number;
 }
 
+// Thresholds deciding which moves the insights report as mistakes
+export interface InsightSettings {
+  minDrop: number;
+  minPly: number;
+  minFrequency: number;
+  maxMistakes: number;
+}
+
+// User-defined label added to the analyzed moves meeting every condition that is set
+export interface ClassificationRule {
+  id: string;
+  key: string;
+  name: string;
+  moveStatus?: MoveStatus;
+  mover?: 'user' | 'opponent';
+  minWinrateDrop?: number;
+  minTimeSpent?: number;
+  maxPly?: number;
+  createdAt: string;
+}
+
+export type ClassificationRuleRequest = Omit<ClassificationRule, 'id' | 'createdAt'>;
+
+// Categories are from the side to move's point of view; a move's category is the opponent's after it
+export type TablebaseCategory =
+  | 'win' | 'maybe-win' | 'cursed-win' | 'draw' | 'blessed-loss' | 'maybe-loss' | 'loss' | 'unknown';
+
+export interface TablebaseMove {
+  uci: string;
+  san: string;
+  category: TablebaseCategory;
+  dtz: number | null;
+  dtm: number | null;
+  zeroing: boolean;
+  checkmate: boolean;
+  stalemate: boolean;
+}
+
+export interface TablebasePosition {
+  fen: string;
+  category: TablebaseCategory;
+  dtz: number | null;
+  dtm: number | null;
+  checkmate: boolean;
+  stalemate: boolean;
+  moves: TablebaseMove[];
+}
+
+// A FEN checked and completed by the server, so the board editor and video review share its chess logic
+export interface FENDetails {
+  fen: string; // board, side to move, castling and en passant
+  fullFen: string;
+  sideToMove: Color;
+  legalMoves: number;
+  gameOver: boolean;
+  reason?: 'checkmate' | 'stalemate' | 'insufficient_material' | 'seventy_five_move_rule';
+}
+
+export interface RepertoireExpectation {
+  repertoireId: string;
+  repertoireName: string;
+  nodeId: string;
+  path: string[];
+  expectedMove?: string;
+  comment?: string;
+  playedInRepertoire: boolean;
+}
+
+export interface ExplainedMove extends ExplorerMove {
+  winrate: number;
+}
+
+export interface ModelGame {
+  id: string;
+  white: string;
+  whiteRating: number;
+  black: string;
+  blackRating: number;
+  year: number;
+  result: string;
+  url: string;
+}
+
+// explorerStatus is 'pending' while the Explorer lookup waits in the server queue; poll again shortly
+export interface MistakeExplanation {
+  fen: string;
+  playedMove: string;
+  repertoires: RepertoireExpectation[];
+  explorerStatus?: 'ready' | 'pending';
+  totalGames: number;
+  played?: ExplainedMove;
+  best?: ExplainedMove;
+  modelGames: ModelGame[];
+}
+
 // Dashboard types
 export interface RepertoireStats {
   repertoireId: string;
@@ -262,6 +1006,19 @@ export interface DashboardStatsResponse {
   inRepCount: number;
   outRepCount: number;
   repertoires: RepertoireStats[];
+  timeClasses: TimeClassStats[];
+}
+
+// Out-of-book statistics of the games of one time class
+export interface TimeClassStats {
+  timeClass: TimeClass;
+  gameCount: number;
+  inRepCount: number;
+  outRepCount: number;
+  coveragePercent: number;
+  winRate: number;
+  leftBookCount: number;
+  avgLeaveBookMove: number;
 }
 
 // API types
@@ -275,7 +1032,87 @@ export interface UploadResponse {
   username: string;
   filename: string;
   gameCount: number;
-  source?: 'lichess' | 'chesscom' | 'pgn';
+  source: GameSource;
+  sourceParams: ImportSourceParams;
+  warnings?: ImportWarning[];
+}
+
+export type ImportWarningKind = 'parse_failed' | 'user_not_found' | 'duplicate' | 'no_repertoire' | 'variant';
+
+// A game of an import that was dropped or needs attention; game is its 1-based position in the PGN
+export interface ImportWarning {
+  kind: ImportWarningKind;
+  game: number;
+  white?: string;
+  black?: string;
+  date?: string;
+  message?: string;
+}
+
+export interface ImportSummary {
+  analysisId: string;
+  gamesFound: number;
+  gamesImported: number;
+  warnings: ImportWarning[];
+  createdAt: string;
+}
+
+export type DuplicatePolicy = 'skip' | 'replace' | 'keep-both';
+
+export interface ImportFailure {
+  gameIndex: number;
+  error: string;
+}
+
+export interface ImportJob {
+  id: string;
+  userId: string;
+  username: string;
+  filename: string;
+  duplicatePolicy: DuplicatePolicy;
+  status: 'pending' | 'processing' | 'done' | 'failed';
+  totalGames: number;
+  totalChunks: number;
+  processedChunks: number;
+  importedGames: number;
+  skippedGames: number;
+  checkedGames: number; // games checked against the already imported ones
+  failedGames: ImportFailure[];
+  analysisIds: string[];
+  error?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+// Background import of the recent games of every member of a Lichess team, one analysis per member
+export interface TeamImport {
+  id: string;
+  teamId: string;
+  options: LichessImportOptions;
+  analysisDepth?: number;
+  status: 'pending' | 'processing' | 'done' | 'failed';
+  members: string[];
+  results: TeamMemberImport[];
+  error?: string;
+  nextAttemptAt?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface TeamMemberImport {
+  username: string;
+  analysisId?: string;
+  gameCount: number;
+  error?: string;
+}
+
+export interface ImportSourceStats {
+  source: GameSource;
+  month: string; // YYYY-MM
+  gamesImported: number;
+  duplicatesSkipped: number;
+  outOfRepertoireRate: number;
+  evalCompletion: number;
 }
 
 // Lichess import types
@@ -293,6 +1130,17 @@ export interface ChesscomImportOptions {
   since?: number;
   until?: number;
   timeClass?: 'daily' | 'rapid' | 'blitz' | 'bullet';
+  excludeDaily?: boolean;
+}
+
+// Counts of what an import would do, before anything is saved
+export interface ImportPreview {
+  totalGames: number;
+  newGames: number;
+  duplicates: number; // already imported
+  notPlayedByUser: number; // the username was not found in the game
+  asWhite: number;
+  asBlack: number;
 }
 
 // Lichess Study import types
@@ -301,6 +1149,9 @@ export interface StudyChapterInfo {
   name: string;
   orientation: string;
   moveCount: number;
+  nodeCount: number;
+  newNodeCount?: number;
+  unsupported?: boolean;
 }
 
 export interface StudyInfo {
@@ -316,6 +1167,7 @@ export interface StudyImportRequest {
   mergeName?: string;
   createCategory?: boolean;
   categoryName?: string;
+  targetRepertoireId?: string;
 }
 
 export interface StudyImportResponse {